@@ -131,4 +131,12 @@ func init() {
 		printObject(router)
 		return nil
 	})
+	R(&options.RouterAgentConfigOptions{}, "router-agent-config", "Show config pulled by cloudnet agent on router", func(s *mcclient.ClientSession, opts *options.RouterAgentConfigOptions) error {
+		cfg, err := modules.Routers.GetSpecific(s, opts.ID, "agent-config", nil)
+		if err != nil {
+			return err
+		}
+		printObject(cfg)
+		return nil
+	})
 }
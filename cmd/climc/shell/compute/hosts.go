@@ -46,6 +46,10 @@ func init() {
 	cmd.Perform("start", &options.BaseIdOptions{})
 	cmd.Perform("stop", &options.BaseIdOptions{})
 	cmd.Perform("reset", &options.BaseIdOptions{})
+	cmd.Perform("power-standby", &options.BaseIdOptions{})
+	cmd.Perform("power-resume", &options.BaseIdOptions{})
+	cmd.Perform("enter-maintenance", &compute.HostEnterMaintenanceOptions{})
+	cmd.Perform("exit-maintenance", &compute.HostExitMaintenanceOptions{})
 	cmd.BatchDelete(&options.BaseIdsOptions{})
 	cmd.Perform("remove-all-netifs", &options.BaseIdOptions{})
 
@@ -58,6 +62,7 @@ func init() {
 
 	cmd.Get("ipmi", &options.BaseIdOptions{})
 	cmd.Get("vnc", &options.BaseIdOptions{})
+	cmd.Get("maintenance-readiness", &options.BaseIdOptions{})
 
 	R(&options.BaseIdOptions{}, "host-logininfo", "Get SSH login information of a host", func(s *mcclient.ClientSession, args *options.BaseIdOptions) error {
 		srvid, e := modules.Hosts.GetId(s, args.ID, nil)
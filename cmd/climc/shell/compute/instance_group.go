@@ -15,13 +15,51 @@
 package compute
 
 import (
+	"strconv"
+	"strings"
+
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/modules"
 	"yunion.io/x/onecloud/pkg/mcclient/options"
 )
 
+// parseTopologySpreads parses topology spread constraints in the form of
+// key=rack,max_skew=1,min_domains=3,mode=soft
+func parseTopologySpreads(strs []string) (jsonutils.JSONObject, error) {
+	constraints := api.TopologySpreadConstraints{}
+	for _, str := range strs {
+		constraint := api.TopologySpreadConstraint{}
+		for _, seg := range strings.Split(str, ",") {
+			kv := strings.SplitN(seg, "=", 2)
+			if len(kv) != 2 {
+				return nil, errors.Errorf("invalid topology spread %q", str)
+			}
+			var err error
+			switch kv[0] {
+			case "key":
+				constraint.TopologyKey = kv[1]
+			case "max_skew":
+				constraint.MaxSkew, err = strconv.Atoi(kv[1])
+			case "min_domains":
+				constraint.MinDomains, err = strconv.Atoi(kv[1])
+			case "mode":
+				constraint.Mode = kv[1]
+			default:
+				return nil, errors.Errorf("unknown field %q of topology spread", kv[0])
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse %s", seg)
+			}
+		}
+		constraints = append(constraints, constraint)
+	}
+	return jsonutils.Marshal(constraints), nil
+}
+
 func init() {
 	type InstanceGroupListOptions struct {
 		options.BaseListOptions
@@ -70,6 +108,8 @@ func init() {
 		SchedStrategy   string `help:"scheduler strategy"`
 		Granularity     string `help:"the upper limit number of guests with this group in a host"`
 		ForceDispersion bool   `help:"force to make guest dispersion"`
+
+		TopologySpread []string `help:"topology spread constraint, e.g. key=rack,max_skew=1,min_domains=3,mode=hard" json:"-"`
 	}
 
 	R(&InstanceGroupCreateOptions{}, "instance-group-create", "Create a instance group",
@@ -78,6 +118,13 @@ func init() {
 			if err != nil {
 				return err
 			}
+			if len(args.TopologySpread) > 0 {
+				spreads, err := parseTopologySpreads(args.TopologySpread)
+				if err != nil {
+					return err
+				}
+				params.Set("topology_spread_constraints", spreads)
+			}
 			result, err := modules.InstanceGroup.Create(s, params)
 			if err != nil {
 				return err
@@ -103,6 +150,9 @@ func init() {
 		Name            string `help:"New name to change"`
 		Granularity     string `help:"the upper limit number of guests with this group in a host"`
 		ForceDispersion string `help:"force to make guest dispersion" choices:"yes|no" json:"-"`
+
+		TopologySpread      []string `help:"topology spread constraint, e.g. key=rack,max_skew=1,min_domains=3,mode=hard" json:"-"`
+		ClearTopologySpread bool     `help:"remove all topology spread constraints" json:"-"`
 	}
 
 	R(&InstanceGroupUpdateOptions{}, "instance-group-update", "update a instance group",
@@ -116,6 +166,15 @@ func init() {
 			} else {
 				params.Set("force_dispersion", jsonutils.JSONFalse)
 			}
+			if len(args.TopologySpread) > 0 {
+				spreads, err := parseTopologySpreads(args.TopologySpread)
+				if err != nil {
+					return err
+				}
+				params.Set("topology_spread_constraints", spreads)
+			} else if args.ClearTopologySpread {
+				params.Set("topology_spread_constraints", jsonutils.NewArray())
+			}
 			ret, err := modules.InstanceGroup.Update(s, args.ID, params)
 			if err != nil {
 				return err
@@ -90,6 +90,9 @@ func init() {
 		ReservedCpu     *int     `help:"reserved cpu for isolated device"`
 		ReservedMem     *int     `help:"reserved mem for isolated device"`
 		ReservedStorage *int     `help:"reserved storage for isolated device"`
+		NumaNode        *int     `help:"numa node of isolated device, -1 for unknown"`
+		PcieSwitch      string   `help:"upstream port address of pcie switch of isolated device"`
+		PeerGroup       string   `help:"NVLink or alike peer group of isolated device"`
 	}
 	R(&DeviceUpdateOptions{}, "isolated-device-update", "Update a isolated device", func(s *mcclient.ClientSession, args *DeviceUpdateOptions) error {
 		res := modules.IsolatedDevices.BatchUpdate(s, args.ID, jsonutils.Marshal(args))
@@ -35,6 +35,26 @@ func init() {
 		printObject(lbcert)
 		return nil
 	})
+	R(&options.LoadbalancerCertificateAcmeCreateOptions{}, "lbcert-acme-create", "Create lbcert issued and renewed by acme", func(s *mcclient.ClientSession, opts *options.LoadbalancerCertificateAcmeCreateOptions) error {
+		params, err := opts.Params()
+		if err != nil {
+			return err
+		}
+		lbcert, err := modules.LoadbalancerCertificates.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(lbcert)
+		return nil
+	})
+	R(&options.LoadbalancerCertificateGetOptions{}, "lbcert-acme-renew", "Issue acme lbcert again right now", func(s *mcclient.ClientSession, opts *options.LoadbalancerCertificateGetOptions) error {
+		lbcert, err := modules.LoadbalancerCertificates.PerformAction(s, opts.ID, "acme-renew", nil)
+		if err != nil {
+			return err
+		}
+		printObject(lbcert)
+		return nil
+	})
 	R(&options.LoadbalancerCertificateGetOptions{}, "lbcert-show", "Show lbcert", func(s *mcclient.ClientSession, opts *options.LoadbalancerCertificateGetOptions) error {
 		lbcert, err := modules.LoadbalancerCertificates.Get(s, opts.ID, nil)
 		if err != nil {
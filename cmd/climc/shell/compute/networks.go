@@ -71,6 +71,12 @@ func init() {
 		IsAutoAlloc *bool  `help:"Auto allocation IP pool"`
 		BgpType     string `help:"Internet service provider name" positional:"false"`
 		Desc        string `help:"Description" metavar:"DESCRIPTION"`
+
+		Ip6Start string `help:"Start of IPv6 address range"`
+		Ip6End   string `help:"End of IPv6 address range"`
+		Ip6Mask  int64  `help:"Length of IPv6 network mask"`
+		Gateway6 string `help:"IPv6 default gateway"`
+		Dns6     string `help:"IPv6 DNS server"`
 	}
 	R(&NetworkCreateOptions{}, "network-create", "Create a virtual network", func(s *mcclient.ClientSession, args *NetworkCreateOptions) error {
 		params := jsonutils.NewDict()
@@ -96,6 +102,17 @@ func init() {
 		if len(args.Desc) > 0 {
 			params.Add(jsonutils.NewString(args.Desc), "description")
 		}
+		if len(args.Ip6Start) > 0 {
+			params.Add(jsonutils.NewString(args.Ip6Start), "guest_ip6_start")
+			params.Add(jsonutils.NewString(args.Ip6End), "guest_ip6_end")
+			params.Add(jsonutils.NewInt(args.Ip6Mask), "guest_ip6_mask")
+		}
+		if len(args.Gateway6) > 0 {
+			params.Add(jsonutils.NewString(args.Gateway6), "guest_gateway6")
+		}
+		if len(args.Dns6) > 0 {
+			params.Add(jsonutils.NewString(args.Dns6), "guest_dns6")
+		}
 		if len(args.BgpType) > 0 {
 			params.Add(jsonutils.NewString(args.BgpType), "bgp_type")
 		}
@@ -37,6 +37,10 @@ type ComputeQuotaOptions struct {
 	Memory         int64 `help:"Memory size in MB" json:"memory,omitzero"`
 	Storage        int64 `help:"Storage size in MB" json:"storage,omitzero"`
 	IsolatedDevice int64 `help:"Isolated device count" json:"isolated_device,omitzero"`
+
+	PreemptibleCount  int64 `help:"preemptible server count" json:"preemptible_count,omitzero"`
+	PreemptibleCpu    int64 `help:"preemptible server CPU count" json:"preemptible_cpu,omitzero"`
+	PreemptibleMemory int64 `help:"preemptible server memory size in MB" json:"preemptible_memory,omitzero"`
 }
 
 type RegionQuotaKeys struct {
@@ -16,6 +16,9 @@ package compute
 
 import (
 	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
 
 	"yunion.io/x/jsonutils"
 
@@ -58,6 +61,67 @@ func init() {
 			return nil
 		})
 
+	type SchedulerSimulateOptions struct {
+		WORKLOADS  string   `help:"JSON or YAML file of workloads, each is a schedule input with name and weight"`
+		Region     string   `help:"Cloud region ID or name"`
+		Zone       string   `help:"Zone ID or name"`
+		RemoveHost []string `help:"ID or name of host to drain"`
+		AddHost    []string `help:"Add empty hosts the same as template host, e.g. <TEMPLATE_HOST>:<COUNT>"`
+		MaxCount   int      `help:"Max count of guests to place"`
+	}
+	R(&SchedulerSimulateOptions{}, "scheduler-simulate", "Simulate how many guests of workloads fit in candidates",
+		func(s *mcclient.ClientSession, args *SchedulerSimulateOptions) error {
+			content, err := ioutil.ReadFile(args.WORKLOADS)
+			if err != nil {
+				return err
+			}
+			workloads, err := jsonutils.ParseYAML(string(content))
+			if err != nil {
+				return err
+			}
+			if _, ok := workloads.(*jsonutils.JSONArray); !ok {
+				return fmt.Errorf("workloads should be an array")
+			}
+			params := jsonutils.NewDict()
+			params.Add(workloads, "workloads")
+			if len(args.Region) > 0 {
+				params.Add(jsonutils.NewString(args.Region), "region")
+			}
+			if len(args.Zone) > 0 {
+				params.Add(jsonutils.NewString(args.Zone), "zone")
+			}
+			if len(args.RemoveHost) > 0 {
+				params.Add(jsonutils.NewStringArray(args.RemoveHost), "remove_hosts")
+			}
+			addHosts := jsonutils.NewArray()
+			for _, h := range args.AddHost {
+				parts := strings.Split(h, ":")
+				if len(parts) != 2 {
+					return fmt.Errorf("invalid add host %q, should be <TEMPLATE_HOST>:<COUNT>", h)
+				}
+				count, err := strconv.Atoi(parts[1])
+				if err != nil {
+					return fmt.Errorf("invalid count of add host %q: %v", h, err)
+				}
+				addHost := jsonutils.NewDict()
+				addHost.Add(jsonutils.NewString(parts[0]), "template_host")
+				addHost.Add(jsonutils.NewInt(int64(count)), "count")
+				addHosts.Add(addHost)
+			}
+			if addHosts.Length() > 0 {
+				params.Add(addHosts, "add_hosts")
+			}
+			if args.MaxCount > 0 {
+				params.Add(jsonutils.NewInt(int64(args.MaxCount)), "max_count")
+			}
+			result, err := modules.SchedManager.Simulate(s, params)
+			if err != nil {
+				return err
+			}
+			fmt.Println(result.YAMLString())
+			return nil
+		})
+
 	type SchedulerCandidateListOptions struct {
 		Type   string `help:"Sched type filter" choices:"baremetal|host"`
 		Region string `help:"Cloud region ID"`
@@ -125,6 +125,9 @@ func init() {
 		SERVER  string `help:"ID or Name of server"`
 		MACORIP string `help:"IP, Mac, or Index of NIC"`
 		BW      int64  `help:"Bandwidth in Mbps"`
+		Ingress *int64 `help:"Ingress bandwidth to the server in Mbps, vpc network only"`
+		Egress  *int64 `help:"Egress bandwidth from the server in Mbps, vpc network only"`
+		Burst   *int64 `help:"Burst size in kbits, vpc network only"`
 	}
 	R(&ServerNetworkBWOptions{}, "server-change-bandwidth", "Change server network bandwidth in Mbps", func(s *mcclient.ClientSession, args *ServerNetworkBWOptions) error {
 		params := jsonutils.NewDict()
@@ -142,6 +145,15 @@ func init() {
 			return fmt.Errorf("Please specify Ip or Mac")
 		}
 		params.Add(jsonutils.NewInt(args.BW), "bandwidth")
+		if args.Ingress != nil {
+			params.Add(jsonutils.NewInt(*args.Ingress), "ingress_bandwidth")
+		}
+		if args.Egress != nil {
+			params.Add(jsonutils.NewInt(*args.Egress), "egress_bandwidth")
+		}
+		if args.Burst != nil {
+			params.Add(jsonutils.NewInt(*args.Burst), "burst")
+		}
 		server, err := modules.Servers.PerformAction(s, args.SERVER, "change-bandwidth", params)
 		if err != nil {
 			return err
@@ -51,6 +51,36 @@ func init() {
 	},
 	)
 
+	type GuestImageImportOptions struct {
+		NAME      string   `help:"Name of guest image"`
+		FORMAT    string   `help:"Format of the package" choices:"ova|ovf"`
+		URL       string   `help:"URL of the ova package or the ovf descriptor"`
+		DiskUrl   []string `help:"URL of disk referenced by the ovf descriptor, default is relative to the descriptor"`
+		Protected bool     `help:"if guest image is protected"`
+	}
+
+	R(&GuestImageImportOptions{}, "guest-image-import", "Import guest image from an ova or ovf package", func(s *mcclient.ClientSession,
+		args *GuestImageImportOptions) error {
+
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(args.NAME), "name")
+		params.Add(jsonutils.NewString(args.FORMAT), "import_format")
+		params.Add(jsonutils.NewString(args.URL), "copy_from")
+		if len(args.DiskUrl) > 0 {
+			params.Add(jsonutils.NewStringArray(args.DiskUrl), "disk_urls")
+		}
+		if args.Protected {
+			params.Add(jsonutils.JSONTrue, "protected")
+		}
+		ret, err := modules.GuestImages.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	},
+	)
+
 	type GuestImageListOptions struct {
 		options.BaseListOptions
 
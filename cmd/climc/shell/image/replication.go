@@ -0,0 +1,138 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	type ImageReplicateOptions struct {
+		ID     string   `help:"ID or name of the image"`
+		REGION []string `help:"Target regions"`
+	}
+	R(&ImageReplicateOptions{}, "image-replicate", "Replicate image to other regions", func(s *mcclient.ClientSession, args *ImageReplicateOptions) error {
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewStringArray(args.REGION), "regions")
+		result, err := modules.Images.PerformAction(s, args.ID, "replicate", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type ImageReplicasOptions struct {
+		ID string `help:"ID or name of the image"`
+	}
+	R(&ImageReplicasOptions{}, "image-replicas", "Show replicas of image in other regions", func(s *mcclient.ClientSession, args *ImageReplicasOptions) error {
+		result, err := modules.Images.GetSpecific(s, args.ID, "replicas", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type ImageReplicationPolicyListOptions struct {
+		options.BaseListOptions
+
+		TargetRegion string `help:"Filter by target region"`
+	}
+	R(&ImageReplicationPolicyListOptions{}, "image-replication-policy-list", "List image replication policies", func(s *mcclient.ClientSession, args *ImageReplicationPolicyListOptions) error {
+		params, err := options.ListStructToParams(args)
+		if err != nil {
+			return err
+		}
+		result, err := modules.ImageReplicationPolicies.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.ImageReplicationPolicies.GetColumns(s))
+		return nil
+	})
+
+	type ImageReplicationPolicyCreateOptions struct {
+		NAME         string   `help:"Name of the policy"`
+		TargetRegion []string `help:"Target regions" required:"true"`
+		IsStandard   *bool    `help:"Replicate standard images"`
+		TagKey       string   `help:"Replicate images with the tag"`
+		TagValue     string   `help:"Value of the tag, any value if empty"`
+		Desc         string   `help:"Description"`
+	}
+	R(&ImageReplicationPolicyCreateOptions{}, "image-replication-policy-create", "Create image replication policy", func(s *mcclient.ClientSession, args *ImageReplicationPolicyCreateOptions) error {
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(args.NAME), "name")
+		params.Add(jsonutils.NewStringArray(args.TargetRegion), "target_regions")
+		if args.IsStandard != nil {
+			params.Add(jsonutils.NewBool(*args.IsStandard), "is_standard")
+		}
+		if len(args.TagKey) > 0 {
+			params.Add(jsonutils.NewString(args.TagKey), "tag_key")
+		}
+		if len(args.TagValue) > 0 {
+			params.Add(jsonutils.NewString(args.TagValue), "tag_value")
+		}
+		if len(args.Desc) > 0 {
+			params.Add(jsonutils.NewString(args.Desc), "description")
+		}
+		result, err := modules.ImageReplicationPolicies.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type ImageReplicationPolicyIdOptions struct {
+		ID string `help:"ID or name of the policy"`
+	}
+	R(&ImageReplicationPolicyIdOptions{}, "image-replication-policy-show", "Show image replication policy", func(s *mcclient.ClientSession, args *ImageReplicationPolicyIdOptions) error {
+		result, err := modules.ImageReplicationPolicies.Get(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&ImageReplicationPolicyIdOptions{}, "image-replication-policy-delete", "Delete image replication policy", func(s *mcclient.ClientSession, args *ImageReplicationPolicyIdOptions) error {
+		result, err := modules.ImageReplicationPolicies.Delete(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&ImageReplicationPolicyIdOptions{}, "image-replication-policy-enable", "Enable image replication policy", func(s *mcclient.ClientSession, args *ImageReplicationPolicyIdOptions) error {
+		result, err := modules.ImageReplicationPolicies.PerformAction(s, args.ID, "enable", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&ImageReplicationPolicyIdOptions{}, "image-replication-policy-disable", "Disable image replication policy", func(s *mcclient.ClientSession, args *ImageReplicationPolicyIdOptions) error {
+		result, err := modules.ImageReplicationPolicies.PerformAction(s, args.ID, "disable", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"io/ioutil"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	type ImageTrustedKeyListOptions struct {
+		options.BaseListOptions
+
+		Fingerprint []string `help:"Filter by fingerprint"`
+		KeyType     []string `help:"Filter by key type" choices:"rsa|ecdsa|ed25519"`
+	}
+	R(&ImageTrustedKeyListOptions{}, "image-trusted-key-list", "List trusted keys of image signature", func(s *mcclient.ClientSession, args *ImageTrustedKeyListOptions) error {
+		params, err := options.ListStructToParams(args)
+		if err != nil {
+			return err
+		}
+		result, err := modules.ImageTrustedKeys.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.ImageTrustedKeys.GetColumns(s))
+		return nil
+	})
+
+	type ImageTrustedKeyCreateOptions struct {
+		NAME   string `help:"Name of the trusted key"`
+		KEY    string `help:"Path of the PEM encoded public key file"`
+		Domain string `help:"Domain of the trusted key"`
+		Desc   string `help:"Description"`
+	}
+	R(&ImageTrustedKeyCreateOptions{}, "image-trusted-key-create", "Trust a public key to verify image signatures", func(s *mcclient.ClientSession, args *ImageTrustedKeyCreateOptions) error {
+		content, err := ioutil.ReadFile(args.KEY)
+		if err != nil {
+			return errors.Wrapf(err, "read %s", args.KEY)
+		}
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(args.NAME), "name")
+		params.Add(jsonutils.NewString(string(content)), "public_key")
+		if len(args.Domain) > 0 {
+			params.Add(jsonutils.NewString(args.Domain), "project_domain")
+		}
+		if len(args.Desc) > 0 {
+			params.Add(jsonutils.NewString(args.Desc), "description")
+		}
+		result, err := modules.ImageTrustedKeys.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type ImageTrustedKeyIdOptions struct {
+		ID string `help:"ID or name of the trusted key"`
+	}
+	R(&ImageTrustedKeyIdOptions{}, "image-trusted-key-show", "Show details of a trusted key", func(s *mcclient.ClientSession, args *ImageTrustedKeyIdOptions) error {
+		result, err := modules.ImageTrustedKeys.Get(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	R(&ImageTrustedKeyIdOptions{}, "image-trusted-key-delete", "Delete a trusted key", func(s *mcclient.ClientSession, args *ImageTrustedKeyIdOptions) error {
+		result, err := modules.ImageTrustedKeys.Delete(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type ImageVerifySignatureOptions struct {
+		ID string `help:"ID or name of the image"`
+	}
+	R(&ImageVerifySignatureOptions{}, "image-verify-signature", "Verify image signature with the trusted keys", func(s *mcclient.ClientSession, args *ImageVerifySignatureOptions) error {
+		result, err := modules.Images.PerformAction(s, args.ID, "verify-signature", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+}
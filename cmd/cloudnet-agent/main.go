@@ -0,0 +1,73 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/app"
+	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
+	"yunion.io/x/onecloud/pkg/cloudnet/agent"
+	"yunion.io/x/onecloud/pkg/util/atexit"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+func main() {
+	defer atexit.Handle()
+
+	opts := &agent.Options{}
+	commonOpts := &opts.CommonOptions
+	{
+		common_options.ParseOptions(opts, os.Args, "cloudnet-agent.conf", "cloudnet-agent")
+		app.InitAuth(commonOpts, func() {
+			log.Infof("auth finished ok")
+		})
+	}
+	if err := opts.ValidateThenInit(); err != nil {
+		log.Fatalf("opts validate: %s", err)
+	}
+
+	a, err := agent.NewAgent(opts)
+	if err != nil {
+		log.Fatalf("new agent: %v", err)
+	}
+
+	{
+		ctx := context.Background()
+		ctx, cancelFunc := context.WithCancel(ctx)
+		go procutils.WaitZombieLoop(ctx)
+
+		wg := &sync.WaitGroup{}
+		ctx = context.WithValue(ctx, "wg", wg)
+		wg.Add(1)
+		go a.Start(ctx)
+
+		go func() {
+			sigChan := make(chan os.Signal, 1)
+			signal.Notify(sigChan, syscall.SIGINT)
+			signal.Notify(sigChan, syscall.SIGTERM)
+			sig := <-sigChan
+			log.Infof("signal received: %s", sig)
+			cancelFunc()
+		}()
+		wg.Wait()
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudnet
+
+import "time"
+
+// RouterAgentConfig is the desired state of a router managed by cloudnet
+// agent
+type RouterAgentConfig struct {
+	RouterId string `json:"router_id"`
+
+	// Parts of router config to be realized
+	RealizeWgIfaces bool `json:"realize_wg_ifaces"`
+	RealizeRoutes   bool `json:"realize_routes"`
+	RealizeRules    bool `json:"realize_rules"`
+
+	// Digest changes whenever any part of the config changes
+	Digest string `json:"digest"`
+
+	Ifaces []RouterAgentIface `json:"ifaces"`
+
+	// Routes maps ifname to route lines, e.g. "10.0.0.0/24 via 192.168.0.1 dev eth0"
+	Routes map[string][]string `json:"routes"`
+
+	Rules []RouterAgentRule `json:"rules"`
+}
+
+type RouterAgentIface struct {
+	Ifname string `json:"ifname"`
+
+	PrivateKey string `json:"private_key"`
+
+	ListenPort int `json:"listen_port"`
+
+	Peers []RouterAgentIfacePeer `json:"peers"`
+}
+
+type RouterAgentIfacePeer struct {
+	Name string `json:"name"`
+
+	PublicKey string `json:"public_key"`
+
+	AllowedIPs string `json:"allowed_ips"`
+
+	Endpoint string `json:"endpoint"`
+
+	PersistentKeepalive int `json:"persistent_keepalive"`
+}
+
+type RouterAgentRule struct {
+	Priority int `json:"priority"`
+
+	Table string `json:"table"`
+
+	Chain string `json:"chain"`
+
+	Body string `json:"body"`
+}
+
+type RouterAgentReportStatusInput struct {
+	// Digest of the config that was applied
+	Digest string `json:"digest"`
+
+	Ifaces []RouterAgentIfaceStatus `json:"ifaces"`
+}
+
+type RouterAgentIfaceStatus struct {
+	Ifname string `json:"ifname"`
+
+	Peers []RouterAgentIfacePeerStatus `json:"peers"`
+}
+
+type RouterAgentIfacePeerStatus struct {
+	PublicKey string `json:"public_key"`
+
+	// Endpoint as seen by wireguard, it may differ from the configured
+	// one when peer is behind nat
+	Endpoint string `json:"endpoint"`
+
+	LatestHandshake time.Time `json:"latest_handshake"`
+
+	RxBytes int64 `json:"rx_bytes"`
+
+	TxBytes int64 `json:"tx_bytes"`
+
+	// LatencyMs is the average round trip time to endpoint, negative
+	// when unreachable
+	LatencyMs float64 `json:"latency_ms"`
+}
@@ -33,6 +33,8 @@ type RouterUpdateInput struct {
 
 	RealizeRules *bool `json:"realize_rules"`
 
+	AgentManaged *bool `json:"agent_managed"`
+
 	OldEndpoint string `json:"_old_endpoint"`
 }
 
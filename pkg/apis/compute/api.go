@@ -206,6 +206,10 @@ type DiskConfig struct {
 
 	//swagger:ignore
 	DiskId string `json:"disk_id"`
+
+	// 加密密钥ID, 指定后磁盘会以此密钥加密, 仅KVM支持
+	// required: false
+	EncryptKeyId string `json:"encrypt_key_id"`
 }
 
 type IsolatedDeviceConfig struct {
@@ -297,6 +301,12 @@ type ServerConfigs struct {
 	// required: false
 	Backup bool `json:"backup"`
 
+	// 抢占式实例, 仅KVM支持
+	// 宿主机资源不足时, 可被非抢占式实例抢占(关机或删除)
+	// default: false
+	// required: false
+	Preemptible bool `json:"preemptible"`
+
 	// 创建虚拟机数量
 	// default: 1
 	Count int `json:"count"`
@@ -383,6 +393,10 @@ type ServerCreateInput struct {
 	// required: false
 	Cdrom string `json:"cdrom"`
 
+	// 加密密钥ID, 指定后所有未指定密钥的磁盘都会以此密钥加密, 仅KVM平台支持
+	// required: false
+	EncryptKeyId string `json:"encrypt_key_id"`
+
 	// enum: cirros, vmware, qxl, std
 	// default: std
 	Vga string `json:"vga"`
@@ -418,6 +432,12 @@ type ServerCreateInput struct {
 	// default: stop
 	ShutdownBehavior string `json:"shutdown_behavior"`
 
+	// 抢占式实例被抢占时执行的操作
+	// stop: 关机, delete: 删除
+	// enum: stop, delete
+	// default: stop
+	PreemptAction string `json:"preempt_action"`
+
 	// 创建后自动启动
 	// 部分云创建后会自动启动例如: 腾讯云, AWS, OpenStack, ZStack, Ucloud, Huawei, Azure, 天翼云
 	// default: false
@@ -0,0 +1,100 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	// 未到预留开始时间
+	CAPACITY_RESERVATION_STATUS_PENDING = "pending"
+	// 预留生效中, 其他项目的虚拟机不能占用预留容量
+	CAPACITY_RESERVATION_STATUS_ACTIVE = "active"
+	// 已过预留结束时间
+	CAPACITY_RESERVATION_STATUS_EXPIRED = "expired"
+)
+
+type CapacityReservationCreateInput struct {
+	apis.VirtualResourceCreateInput
+	ZoneResourceInput
+
+	// 调度标签Id或名称, 与可用区同时指定时取交集
+	SchedtagId string `json:"schedtag_id"`
+
+	// 预留CPU核数
+	CpuCount int `json:"cpu_count"`
+	// 预留内存大小(MB)
+	MemorySize int `json:"memory_size"`
+	// 预留本地存储大小(MB)
+	StorageSize int `json:"storage_size"`
+
+	// 预留直通设备型号
+	IsolatedDeviceModel string `json:"isolated_device_model"`
+	// 预留直通设备数量
+	IsolatedDeviceCount int `json:"isolated_device_count"`
+
+	// 预留开始时间, 默认为当前时间
+	StartTime time.Time `json:"start_time"`
+	// 预留结束时间
+	// required: true
+	EndTime time.Time `json:"end_time"`
+}
+
+type CapacityReservationUpdateInput struct {
+	apis.VirtualResourceBaseUpdateInput
+
+	CpuCount            *int       `json:"cpu_count"`
+	MemorySize          *int       `json:"memory_size"`
+	StorageSize         *int       `json:"storage_size"`
+	IsolatedDeviceCount *int       `json:"isolated_device_count"`
+	StartTime           *time.Time `json:"start_time"`
+	EndTime             *time.Time `json:"end_time"`
+}
+
+type CapacityReservationListInput struct {
+	apis.VirtualResourceListInput
+	ZonalFilterListInput
+
+	// 按调度标签过滤
+	SchedtagId string `json:"schedtag_id"`
+	// 只列出当前生效的预留
+	Active *bool `json:"active"`
+}
+
+type CapacityReservationUsage struct {
+	// 已使用CPU核数
+	UsedCpuCount int `json:"used_cpu_count"`
+	// 已使用内存大小(MB)
+	UsedMemorySize int `json:"used_memory_size"`
+	// 已使用本地存储大小(MB)
+	UsedStorageSize int `json:"used_storage_size"`
+	// 已使用直通设备数量
+	UsedIsolatedDeviceCount int `json:"used_isolated_device_count"`
+	// 使用率(百分比), 各项预留资源使用率的最大值
+	Utilization float64 `json:"utilization"`
+}
+
+type CapacityReservationDetails struct {
+	apis.VirtualResourceDetails
+	ZoneResourceInfo
+
+	// 调度标签名称
+	Schedtag string `json:"schedtag"`
+
+	CapacityReservationUsage
+}
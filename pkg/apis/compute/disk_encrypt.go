@@ -0,0 +1,30 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+const (
+	DISK_ENCRYPT_FORMAT_LUKS = "luks"
+)
+
+// SEncryptInfo carries the unwrapped data key of an encrypted disk from region to host
+type SEncryptInfo struct {
+	// 加密密钥ID
+	Id string `json:"id"`
+	// base64编码的磁盘数据密钥
+	Key string `json:"key"`
+	// 加密格式
+	// enum: luks
+	Format string `json:"format"`
+}
@@ -14,7 +14,35 @@
 
 package compute
 
-import "yunion.io/x/onecloud/pkg/apis"
+import (
+	"net"
+	"reflect"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+	"yunion.io/x/pkg/util/sets"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	// 返回全部记录值
+	DNS_RECORD_POLICY_SIMPLE = "simple"
+	// 按权重每次返回一个记录值
+	DNS_RECORD_POLICY_WEIGHTED = "weighted"
+	// 按客户端子网(EDNS Client Subnet或源地址)返回对应的记录值
+	DNS_RECORD_POLICY_CLIENT_SUBNET = "client_subnet"
+	// 按记录值顺序返回第一个健康的记录值
+	DNS_RECORD_POLICY_FAILOVER = "failover"
+)
+
+var DNS_RECORD_POLICIES = sets.NewString(
+	DNS_RECORD_POLICY_SIMPLE,
+	DNS_RECORD_POLICY_WEIGHTED,
+	DNS_RECORD_POLICY_CLIENT_SUBNET,
+	DNS_RECORD_POLICY_FAILOVER,
+)
 
 type DnsRecordCreateInput struct {
 	apis.AdminSharableVirtualResourceBaseCreateInput
@@ -22,3 +50,83 @@ type DnsRecordCreateInput struct {
 
 type DnsRecordUpdateInput struct {
 }
+
+type SDnsRecordWeight struct {
+	// 记录值
+	Value string `json:"value"`
+	// 权重, 0表示不返回该记录值
+	Weight int `json:"weight"`
+}
+
+type SDnsRecordSubnet struct {
+	// 客户端网段
+	Cidr string `json:"cidr"`
+	// 该网段的客户端得到的记录值
+	Values []string `json:"values"`
+}
+
+type SDnsRecordPolicyOptions struct {
+	// weighted策略的记录值权重, 未列出的记录值权重为1
+	Weights []SDnsRecordWeight `json:"weights"`
+	// client_subnet策略的网段, 客户端匹配掩码最长的网段.  未匹配的客户端得到全部记录值
+	Subnets []SDnsRecordSubnet `json:"subnets"`
+}
+
+func (opts SDnsRecordPolicyOptions) String() string {
+	return jsonutils.Marshal(opts).String()
+}
+
+func (opts SDnsRecordPolicyOptions) IsZero() bool {
+	return len(opts.Weights) == 0 && len(opts.Subnets) == 0
+}
+
+// Validate checks options of the policy against record values, e.g.
+// "1.2.3.4", "::1"
+func (opts *SDnsRecordPolicyOptions) Validate(policy string, values []string) error {
+	valueSet := sets.NewString(values...)
+	switch policy {
+	case DNS_RECORD_POLICY_WEIGHTED:
+		for _, w := range opts.Weights {
+			if !valueSet.Has(w.Value) {
+				return httperrors.NewInputParameterError("weight of unknown record value %s", w.Value)
+			}
+			if w.Weight < 0 {
+				return httperrors.NewInputParameterError("negative weight %d of %s", w.Weight, w.Value)
+			}
+		}
+		opts.Subnets = nil
+	case DNS_RECORD_POLICY_CLIENT_SUBNET:
+		for i := range opts.Subnets {
+			subnet := &opts.Subnets[i]
+			_, ipNet, err := net.ParseCIDR(subnet.Cidr)
+			if err != nil {
+				return httperrors.NewInputParameterError("invalid subnet cidr %s", subnet.Cidr)
+			}
+			subnet.Cidr = ipNet.String()
+			if len(subnet.Values) == 0 {
+				return httperrors.NewInputParameterError("no record values for subnet %s", subnet.Cidr)
+			}
+			for _, v := range subnet.Values {
+				if !valueSet.Has(v) {
+					return httperrors.NewInputParameterError("unknown record value %s of subnet %s", v, subnet.Cidr)
+				}
+			}
+		}
+		opts.Weights = nil
+	default:
+		opts.Weights = nil
+		opts.Subnets = nil
+	}
+	return nil
+}
+
+type DnsRecordReportHealthInput struct {
+	// 健康检查失败的记录值
+	Unhealthy []string `json:"unhealthy"`
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&SDnsRecordPolicyOptions{}), func() gotypes.ISerializable {
+		return &SDnsRecordPolicyOptions{}
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+const (
+	FLOW_LOG_RESOURCE_TYPE_VPC     = "vpc"
+	FLOW_LOG_RESOURCE_TYPE_NETWORK = "network"
+	FLOW_LOG_RESOURCE_TYPE_GUEST   = "guest"
+
+	FLOW_LOG_TRAFFIC_TYPE_ALL    = "all"
+	FLOW_LOG_TRAFFIC_TYPE_ACCEPT = "accept"
+	FLOW_LOG_TRAFFIC_TYPE_DROP   = "drop"
+
+	FLOW_LOG_DELIVERY_LOGGER = "logger"
+	FLOW_LOG_DELIVERY_BUCKET = "bucket"
+
+	FLOW_LOG_STATUS_AVAILABLE = "available"
+
+	// 默认每秒记录的报文数
+	FLOW_LOG_DEFAULT_RATE_LIMIT = 100
+	// 默认保存天数
+	FLOW_LOG_DEFAULT_RETENTION_DAYS = 7
+)
+
+var (
+	FLOW_LOG_RESOURCE_TYPES = []string{
+		FLOW_LOG_RESOURCE_TYPE_VPC,
+		FLOW_LOG_RESOURCE_TYPE_NETWORK,
+		FLOW_LOG_RESOURCE_TYPE_GUEST,
+	}
+	FLOW_LOG_TRAFFIC_TYPES = []string{
+		FLOW_LOG_TRAFFIC_TYPE_ALL,
+		FLOW_LOG_TRAFFIC_TYPE_ACCEPT,
+		FLOW_LOG_TRAFFIC_TYPE_DROP,
+	}
+	FLOW_LOG_DELIVERY_TYPES = []string{
+		FLOW_LOG_DELIVERY_LOGGER,
+		FLOW_LOG_DELIVERY_BUCKET,
+	}
+)
+
+type FlowLogCreateInput struct {
+	apis.EnabledStatusInfrasResourceBaseCreateInput
+
+	// 采集对象类型
+	// enum: vpc, network, guest
+	ResourceType string `json:"resource_type"`
+	// 采集对象Id或名称
+	ResourceId string `json:"resource_id"`
+
+	// 采集的流量类型
+	// enum: all, accept, drop
+	// default: all
+	TrafficType string `json:"traffic_type"`
+	// 每秒最多记录的报文数
+	// default: 100
+	RateLimit int `json:"rate_limit"`
+
+	// 投递方式
+	// enum: logger, bucket
+	// default: logger
+	DeliveryType string `json:"delivery_type"`
+	// 投递的存储桶Id或名称, 投递方式为bucket时必须指定
+	BucketId string `json:"bucket_id"`
+	// 存储桶中对象的前缀
+	BucketPrefix string `json:"bucket_prefix"`
+	// 存储桶中记录的保存天数
+	// default: 7
+	RetentionDays int `json:"retention_days"`
+
+	// swagger:ignore
+	VpcId string `json:"vpc_id"`
+}
+
+type FlowLogUpdateInput struct {
+	apis.EnabledStatusInfrasResourceBaseUpdateInput
+
+	TrafficType   string `json:"traffic_type"`
+	RateLimit     *int   `json:"rate_limit"`
+	BucketPrefix  string `json:"bucket_prefix"`
+	RetentionDays *int   `json:"retention_days"`
+}
+
+type FlowLogListInput struct {
+	apis.EnabledStatusInfrasResourceBaseListInput
+	VpcFilterListInput
+
+	// 按采集对象类型过滤
+	ResourceType []string `json:"resource_type"`
+	// 按采集对象过滤
+	ResourceId string `json:"resource_id"`
+	// 按投递方式过滤
+	DeliveryType []string `json:"delivery_type"`
+}
+
+type FlowLogDetails struct {
+	apis.EnabledStatusInfrasResourceBaseDetails
+	VpcResourceInfo
+
+	// 采集对象名称
+	Resource string `json:"resource"`
+	// 存储桶名称
+	Bucket string `json:"bucket"`
+}
+
+// FlowLogRecord is one flow record parsed from ovn acl logs
+type FlowLogRecord struct {
+	FlowLogId string `json:"flow_log_id"`
+	HostId    string `json:"host_id"`
+	Timestamp string `json:"timestamp"`
+
+	// allow or drop
+	Verdict   string `json:"verdict"`
+	Severity  string `json:"severity,omitempty"`
+	Direction string `json:"direction,omitempty"`
+
+	Protocol string `json:"protocol"`
+	SrcMac   string `json:"src_mac,omitempty"`
+	DstMac   string `json:"dst_mac,omitempty"`
+	SrcIp    string `json:"src_ip,omitempty"`
+	DstIp    string `json:"dst_ip,omitempty"`
+	SrcPort  int    `json:"src_port,omitempty"`
+	DstPort  int    `json:"dst_port,omitempty"`
+	IcmpType int    `json:"icmp_type,omitempty"`
+	IcmpCode int    `json:"icmp_code,omitempty"`
+	TcpFlags string `json:"tcp_flags,omitempty"`
+}
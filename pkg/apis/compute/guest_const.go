@@ -146,6 +146,9 @@ const (
 	SHUTDOWN_STOP      = "stop"
 	SHUTDOWN_TERMINATE = "terminate"
 
+	PREEMPT_ACTION_STOP   = "stop"
+	PREEMPT_ACTION_DELETE = "delete"
+
 	HYPERVISOR_KVM       = "kvm"
 	HYPERVISOR_CONTAINER = "container"
 	HYPERVISOR_BAREMETAL = "baremetal"
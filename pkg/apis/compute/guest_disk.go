@@ -97,4 +97,6 @@ type GuestdiskJsonDesc struct {
 	} `json:"image_info"`
 
 	TargetStorageId string `json:"target_storage_id"`
+
+	EncryptInfo *SEncryptInfo `json:"encrypt_info,omitempty"`
 }
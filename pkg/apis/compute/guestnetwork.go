@@ -95,6 +95,11 @@ type GuestnetworkJsonDesc struct {
 	TeamWith   string               `json:"team_with"`
 	Manual     *bool                `json:"manual"`
 
+	Ip6      string `json:"ip6"`
+	Masklen6 int8   `json:"masklen6"`
+	Gateway6 string `json:"gateway6"`
+	Dns6     string `json:"dns6"`
+
 	Vpc struct {
 		Id           string `json:"id"`
 		Provider     string `json:"provider"`
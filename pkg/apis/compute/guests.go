@@ -621,6 +621,10 @@ type ServerUpdateInput struct {
 	SrcMacCheck *bool `json:"src_mac_check"`
 
 	SshPort int `json:"ssh_port"`
+
+	// 抢占式实例被抢占时执行的操作
+	// enum: stop, delete
+	PreemptAction *string `json:"preempt_action"`
 }
 
 type GuestJsonDesc struct {
@@ -112,6 +112,8 @@ type HostListInput struct {
 	OvnVersion []string `json:"ovn_version"`
 	// 是否处于维护状态
 	IsMaintenance *bool `json:"is_maintenance"`
+	// 按节能状态过滤
+	PowerState []string `json:"power_state"`
 	// 是否为导入的宿主机
 	IsImport *bool `json:"is_import"`
 	// 是否允许PXE启动
@@ -0,0 +1,83 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+const (
+	// 虚拟机元数据, 指定宿主机进入维护模式时虚拟机的迁移方式
+	GUEST_METADATA_MAINTENANCE_POLICY = "__maintenance_policy"
+
+	// 运行中的虚拟机热迁移, 其它虚拟机冷迁移
+	HOST_MAINTENANCE_POLICY_LIVE = "live"
+	// 关机后冷迁移, 迁移后恢复运行
+	HOST_MAINTENANCE_POLICY_COLD = "cold"
+	// 不迁移, 留在宿主机上
+	HOST_MAINTENANCE_POLICY_NONE = "none"
+
+	HOST_MAINTENANCE_DEFAULT_PARALLEL = 2
+	HOST_MAINTENANCE_MAX_PARALLEL     = 16
+
+	HOST_READINESS_CHECK_HOST_STATUS = "host_status"
+	HOST_READINESS_CHECK_HOST_HEALTH = "host_health"
+	HOST_READINESS_CHECK_NETWORK     = "network"
+	HOST_READINESS_CHECK_STORAGE     = "storage"
+)
+
+var HOST_MAINTENANCE_POLICIES = []string{
+	HOST_MAINTENANCE_POLICY_LIVE,
+	HOST_MAINTENANCE_POLICY_COLD,
+	HOST_MAINTENANCE_POLICY_NONE,
+}
+
+type HostEnterMaintenanceInput struct {
+	// 同时迁移的虚拟机数量
+	// default: 2
+	Parallel int `json:"parallel"`
+	// 优先迁移到的宿主机
+	PreferHost string `json:"prefer_host"`
+	// 未指定迁移方式的虚拟机的迁移方式
+	// enum: live, cold, none
+	// default: live
+	DefaultPolicy string `json:"default_policy"`
+}
+
+type HostExitMaintenanceInput struct {
+	// 跳过就绪检查
+	Force bool `json:"force"`
+}
+
+type HostMaintenanceGuest struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Policy string `json:"policy"`
+	// 迁移前的状态
+	Status string `json:"status"`
+	// 未能迁移的原因
+	Reason string `json:"reason,omitempty"`
+}
+
+type HostReadinessCheck struct {
+	// 检查项
+	Name string `json:"name"`
+	// 检查对象, 如存储名称
+	Target string `json:"target,omitempty"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type HostReadiness struct {
+	// 是否所有检查项都通过
+	Ready  bool                 `json:"ready"`
+	Checks []HostReadinessCheck `json:"checks"`
+}
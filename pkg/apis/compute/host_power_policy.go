@@ -0,0 +1,177 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+const (
+	// 宿主机正常运行
+	HOST_POWER_STATE_ON = "on"
+	// 正在迁出虚拟机, 不再参与调度
+	HOST_POWER_STATE_EVACUATING = "evacuating"
+	// 正在关机
+	HOST_POWER_STATE_POWERING_OFF = "powering_off"
+	// 已关机待机, 不参与调度
+	HOST_POWER_STATE_STANDBY = "standby"
+	// 正在开机
+	HOST_POWER_STATE_POWERING_ON = "powering_on"
+
+	// 只记录关机建议, 由管理员执行
+	HOST_POWER_POLICY_MODE_RECOMMEND = "recommend"
+	// 自动迁空并关机
+	HOST_POWER_POLICY_MODE_AUTO = "auto"
+
+	HOST_POWER_POLICY_STATUS_READY = "ready"
+
+	HOST_POWER_ACTION_POWER_OFF = "power_off"
+	HOST_POWER_ACTION_POWER_ON  = "power_on"
+
+	// 默认低负载阈值(百分比)
+	HOST_POWER_DEFAULT_LOW_UTILIZATION = 20
+	// 默认迁入后目标宿主机最高负载(百分比)
+	HOST_POWER_DEFAULT_MAX_UTILIZATION = 70
+	// 默认最少保持开机的宿主机数量
+	HOST_POWER_DEFAULT_MIN_ACTIVE_HOSTS = 2
+	// 默认每轮最多关机数
+	HOST_POWER_DEFAULT_MAX_POWER_OFFS = 1
+	// 默认评估间隔(分钟)
+	HOST_POWER_DEFAULT_INTERVAL_MINUTES = 60
+)
+
+var (
+	HOST_POWER_POLICY_MODES = []string{
+		HOST_POWER_POLICY_MODE_RECOMMEND,
+		HOST_POWER_POLICY_MODE_AUTO,
+	}
+
+	// 不参与调度的节能状态
+	HOST_POWER_INACTIVE_STATES = []string{
+		HOST_POWER_STATE_EVACUATING,
+		HOST_POWER_STATE_POWERING_OFF,
+		HOST_POWER_STATE_STANDBY,
+		HOST_POWER_STATE_POWERING_ON,
+	}
+)
+
+type HostPowerPolicyCreateInput struct {
+	apis.EnabledStatusInfrasResourceBaseCreateInput
+	ZoneResourceInput
+
+	// 调度标签Id或名称, 与可用区同时指定时取交集
+	SchedtagId string `json:"schedtag_id"`
+
+	// 执行方式
+	// enum: recommend, auto
+	// default: recommend
+	Mode string `json:"mode"`
+
+	// 宿主机CPU和内存负载均低于此百分比时可迁空并关机
+	// default: 20
+	LowUtilization int `json:"low_utilization"`
+	// 迁入后目标宿主机CPU和内存负载不超过此百分比, 开机宿主机负载超过此值时唤醒待机宿主机
+	// default: 70
+	MaxUtilization int `json:"max_utilization"`
+	// 最少保持开机的宿主机数量
+	// default: 2
+	MinActiveHosts int `json:"min_active_hosts"`
+	// 每轮评估最多关机的宿主机数量
+	// default: 1
+	MaxPowerOffs int `json:"max_power_offs"`
+
+	// 预留容量: 开机宿主机需能再创建reserve_count台此规格的虚拟机, 否则唤醒待机宿主机
+	ReserveVcpuCount int `json:"reserve_vcpu_count"`
+	// 预留虚拟机内存大小(MB)
+	ReserveVmemSize int `json:"reserve_vmem_size"`
+	// 预留虚拟机数量
+	ReserveCount int `json:"reserve_count"`
+
+	// 评估间隔(分钟)
+	// default: 60
+	IntervalMinutes int `json:"interval_minutes"`
+}
+
+type HostPowerPolicyUpdateInput struct {
+	apis.EnabledStatusInfrasResourceBaseUpdateInput
+
+	Mode             string `json:"mode"`
+	LowUtilization   *int   `json:"low_utilization"`
+	MaxUtilization   *int   `json:"max_utilization"`
+	MinActiveHosts   *int   `json:"min_active_hosts"`
+	MaxPowerOffs     *int   `json:"max_power_offs"`
+	ReserveVcpuCount *int   `json:"reserve_vcpu_count"`
+	ReserveVmemSize  *int   `json:"reserve_vmem_size"`
+	ReserveCount     *int   `json:"reserve_count"`
+	IntervalMinutes  *int   `json:"interval_minutes"`
+}
+
+type HostPowerPolicyListInput struct {
+	apis.EnabledStatusInfrasResourceBaseListInput
+	ZonalFilterListInput
+
+	// 按调度标签过滤
+	SchedtagId string `json:"schedtag_id"`
+	// 按执行方式过滤
+	Mode []string `json:"mode"`
+}
+
+type HostPowerPolicyDetails struct {
+	apis.EnabledStatusInfrasResourceBaseDetails
+	ZoneResourceInfo
+
+	// 调度标签名称
+	Schedtag string `json:"schedtag"`
+}
+
+type HostPowerPolicyEvaluateInput struct {
+	// 只计算节能计划, 不执行
+	DryRun bool `json:"dry_run"`
+}
+
+type HostPowerMigration struct {
+	GuestId   string `json:"guest_id"`
+	Guest     string `json:"guest"`
+	DstHostId string `json:"dst_host_id"`
+	DstHost   string `json:"dst_host"`
+}
+
+type HostPowerAction struct {
+	HostId string `json:"host_id"`
+	Host   string `json:"host"`
+	// enum: power_off, power_on
+	Action string `json:"action"`
+
+	// 关机前需迁出的虚拟机
+	Migrations []HostPowerMigration `json:"migrations,omitempty"`
+
+	Reason string `json:"reason"`
+}
+
+// HostPowerPlan is the result of one evaluation of host power policy
+type HostPowerPlan struct {
+	// 开机宿主机数量
+	ActiveHostCount int `json:"active_host_count"`
+	// 待机宿主机数量
+	StandbyHostCount int `json:"standby_host_count"`
+	// 开机宿主机CPU分配率(百分比)
+	CpuUtilization float64 `json:"cpu_utilization"`
+	// 开机宿主机内存分配率(百分比)
+	MemoryUtilization float64 `json:"memory_utilization"`
+	// 调度器预测开机宿主机能否满足预留容量
+	ReserveSatisfied bool `json:"reserve_satisfied"`
+
+	Actions []HostPowerAction `json:"actions"`
+
+	Note string `json:"note,omitempty"`
+}
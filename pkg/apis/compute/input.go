@@ -124,6 +124,11 @@ type BaremetalagentListInput struct {
 
 type DnsRecordListInput struct {
 	apis.AdminSharableVirtualResourceListInput
+
+	// 以是否开启健康检查过滤
+	HealthCheck *bool `json:"health_check"`
+	// 以流量策略过滤
+	TrafficPolicy []string `json:"traffic_policy"`
 }
 
 type DynamicschedtagListInput struct {
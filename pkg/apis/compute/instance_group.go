@@ -14,7 +14,108 @@
 
 package compute
 
-import "yunion.io/x/onecloud/pkg/apis"
+import (
+	"reflect"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	TOPOLOGY_KEY_ZONE = "zone"
+	TOPOLOGY_KEY_RACK = "rack"
+	TOPOLOGY_KEY_HOST = "host"
+	// 以名称带有指定前缀的调度标签划分拓扑域, 如 schedtag:pdu-
+	TOPOLOGY_KEY_SCHEDTAG_PREFIX = "schedtag:"
+	// 以宿主机指定元数据的值划分拓扑域, 如 metadata:power_domain
+	TOPOLOGY_KEY_METADATA_PREFIX = "metadata:"
+
+	// 不满足约束的宿主机不参与调度
+	TOPOLOGY_SPREAD_MODE_HARD = "hard"
+	// 尽量满足约束
+	TOPOLOGY_SPREAD_MODE_SOFT = "soft"
+)
+
+// TopologySpreadConstraint 约束实例组的虚拟机在拓扑域间均匀分布
+type TopologySpreadConstraint struct {
+	// 拓扑域划分依据
+	// enum: zone, rack, host, schedtag:<prefix>, metadata:<key>
+	TopologyKey string `json:"topology_key"`
+	// 任一拓扑域的虚拟机数量与虚拟机最少的拓扑域之差的上限
+	// default: 1
+	MaxSkew int `json:"max_skew"`
+	// 虚拟机至少分布的拓扑域数量, 拓扑域数量不足时按最少的拓扑域有0台虚拟机计算
+	MinDomains int `json:"min_domains"`
+	// 约束方式
+	// enum: hard, soft
+	// default: hard
+	Mode string `json:"mode"`
+}
+
+func (c *TopologySpreadConstraint) IsHard() bool {
+	return c.Mode != TOPOLOGY_SPREAD_MODE_SOFT
+}
+
+func (c *TopologySpreadConstraint) Validate() error {
+	switch {
+	case utils.IsInStringArray(c.TopologyKey, []string{TOPOLOGY_KEY_ZONE, TOPOLOGY_KEY_RACK, TOPOLOGY_KEY_HOST}):
+	case strings.HasPrefix(c.TopologyKey, TOPOLOGY_KEY_SCHEDTAG_PREFIX) && len(c.TopologyKey) > len(TOPOLOGY_KEY_SCHEDTAG_PREFIX):
+	case strings.HasPrefix(c.TopologyKey, TOPOLOGY_KEY_METADATA_PREFIX) && len(c.TopologyKey) > len(TOPOLOGY_KEY_METADATA_PREFIX):
+	default:
+		return httperrors.NewInputParameterError("invalid topology_key %q", c.TopologyKey)
+	}
+	if c.MaxSkew == 0 {
+		c.MaxSkew = 1
+	}
+	if c.MaxSkew < 0 {
+		return httperrors.NewInputParameterError("max_skew must be positive, got %d", c.MaxSkew)
+	}
+	if c.MinDomains < 0 {
+		return httperrors.NewInputParameterError("min_domains must not be negative, got %d", c.MinDomains)
+	}
+	if c.Mode == "" {
+		c.Mode = TOPOLOGY_SPREAD_MODE_HARD
+	}
+	if !utils.IsInStringArray(c.Mode, []string{TOPOLOGY_SPREAD_MODE_HARD, TOPOLOGY_SPREAD_MODE_SOFT}) {
+		return httperrors.NewInputParameterError("invalid topology spread mode %q", c.Mode)
+	}
+	return nil
+}
+
+type TopologySpreadConstraints []TopologySpreadConstraint
+
+func (cs TopologySpreadConstraints) String() string {
+	return jsonutils.Marshal(cs).String()
+}
+
+func (cs TopologySpreadConstraints) IsZero() bool {
+	return len(cs) == 0
+}
+
+func (cs TopologySpreadConstraints) Validate() error {
+	found := map[string]struct{}{}
+	for i := range cs {
+		if err := cs[i].Validate(); err != nil {
+			return err
+		}
+		if _, ok := found[cs[i].TopologyKey]; ok {
+			return httperrors.NewInputParameterError("duplicate topology_key %s", cs[i].TopologyKey)
+		}
+		found[cs[i].TopologyKey] = struct{}{}
+	}
+	return nil
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&TopologySpreadConstraints{}), func() gotypes.ISerializable {
+		return &TopologySpreadConstraints{}
+	})
+}
 
 type InstanceGroupListInput struct {
 	apis.VirtualResourceListInput
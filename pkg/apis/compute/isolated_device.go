@@ -78,6 +78,19 @@ type IsolatedDeviceCreateInput struct {
 
 	// 设备VendorId
 	VendorDeviceId string `json:"vendor_device_id"`
+
+	IsolatedDeviceTopologyInput
+}
+
+type IsolatedDeviceTopologyInput struct {
+	// 设备所在NUMA节点, -1表示未知
+	NumaNode *int `json:"numa_node"`
+
+	// 设备所在PCIe交换芯片的上行端口地址
+	PcieSwitch string `json:"pcie_switch"`
+
+	// 设备所在的NVLink等点对点互联组
+	PeerGroup string `json:"peer_group"`
 }
 
 type IsolatedDeviceReservedResourceInput struct {
@@ -94,6 +107,7 @@ type IsolatedDeviceReservedResourceInput struct {
 type IsolatedDeviceUpdateInput struct {
 	apis.StandaloneResourceBaseUpdateInput
 	IsolatedDeviceReservedResourceInput
+	IsolatedDeviceTopologyInput
 }
 
 type IsolatedDeviceJsonDesc struct {
@@ -103,4 +117,5 @@ type IsolatedDeviceJsonDesc struct {
 	Addr           string `json:"addr"`
 	VendorDeviceId string `json:"vendor_device_id"`
 	Vendor         string `json:"vendor"`
+	NumaNode       int    `json:"numa_node"`
 }
@@ -38,3 +38,11 @@ var VENDOR_ID_MAP = map[string]string{
 	"NVIDIA": NVIDIA_VENDOR_ID,
 	"AMD":    AMD_VENDOR_ID,
 }
+
+const (
+	// locality of a set of isolated devices, the larger the closer
+	ISOLATED_DEVICE_LOCALITY_NONE   = 0
+	ISOLATED_DEVICE_LOCALITY_NUMA   = 1
+	ISOLATED_DEVICE_LOCALITY_SWITCH = 2
+	ISOLATED_DEVICE_LOCALITY_PEER   = 3
+)
@@ -209,6 +209,27 @@ var LB_TLS_CERT_PUBKEY_ALGOS = choices.NewChoices(
 	LB_TLS_CERT_PUBKEY_ALGO_ECDSA,
 )
 
+const (
+	LB_CERT_SOURCE_UPLOAD = "upload"
+	LB_CERT_SOURCE_ACME   = "acme"
+
+	LB_ACME_CHALLENGE_HTTP01 = "http-01"
+	LB_ACME_CHALLENGE_DNS01  = "dns-01"
+
+	LB_CERT_STATUS_ACME_ISSUING = "acme_issuing"
+	LB_CERT_STATUS_ACME_FAILED  = "acme_failed"
+)
+
+var LB_CERT_SOURCES = choices.NewChoices(
+	LB_CERT_SOURCE_UPLOAD,
+	LB_CERT_SOURCE_ACME,
+)
+
+var LB_ACME_CHALLENGES = choices.NewChoices(
+	LB_ACME_CHALLENGE_HTTP01,
+	LB_ACME_CHALLENGE_DNS01,
+)
+
 // TODO may want extra for legacy apps
 const (
 	LB_TLS_CIPHER_POLICY_1_0        = "tls_cipher_policy_1_0"
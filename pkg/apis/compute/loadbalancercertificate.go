@@ -45,3 +45,34 @@ type LoadbalancerCertificateFilterListInput struct {
 	// 以证书名称排序
 	OrderByCertificate string `json:"order_by_certificate"`
 }
+
+type LoadbalancerCertificateAcmeInput struct {
+	// 证书来源
+	// | cert_source | 说明                              |
+	// |-------------|-----------------------------------|
+	// | upload      | 上传证书和私钥, 默认值            |
+	// | acme        | 通过ACME协议自动签发并在到期前续期 |
+	CertSource string `json:"cert_source"`
+
+	// ACME证书包含的域名, 第一个域名作为证书的CN, 通配符域名仅支持dns-01验证
+	AcmeDomains []string `json:"acme_domains"`
+
+	// ACME域名验证方式
+	// | acme_challenge_type | 说明                                          |
+	// |---------------------|-----------------------------------------------|
+	// | http-01             | 由负载均衡实例80端口的HTTP监听提供验证文件     |
+	// | dns-01              | 在本地DNS Zone中添加_acme-challenge TXT记录    |
+	AcmeChallengeType string `json:"acme_challenge_type"`
+
+	// http-01验证时提供验证文件的负载均衡实例名称或ID, 其lbagent需使用haproxy 2.2及以上版本
+	AcmeLoadbalancer string `json:"acme_loadbalancer"`
+
+	// ACME服务目录地址, 默认使用region服务配置的acme_directory_url
+	AcmeDirectoryUrl string `json:"acme_directory_url"`
+
+	// ACME账户联系邮箱
+	AcmeEmail string `json:"acme_email"`
+
+	// 证书到期前多少天自动续期, 默认30天
+	AcmeRenewBeforeDays int `json:"acme_renew_before_days"`
+}
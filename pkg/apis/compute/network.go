@@ -199,6 +199,26 @@ type NetworkCreateInput struct {
 	// 是否加入自动分配地址池
 	IsAutoAlloc *bool `json:"is_auto_alloc"`
 
+	// description: ipv6 range of guest ip start
+	// example: fd00:1::2
+	GuestIp6Start string `json:"guest_ip6_start"`
+
+	// description: ipv6 range of guest ip end
+	// example: fd00:1::ffff
+	GuestIp6End string `json:"guest_ip6_end"`
+
+	// description: ipv6 mask length
+	// example: 64
+	GuestIp6Mask int8 `json:"guest_ip6_mask"`
+
+	// description: guest ipv6 gateway
+	// example: fd00:1::1
+	GuestGateway6 string `json:"guest_gateway6"`
+
+	// description: guest ipv6 dns
+	// example: 2400:3200::1
+	GuestDns6 string `json:"guest_dns6"`
+
 	// VlanId
 	VlanId *int `json:"vlan_id"`
 
@@ -352,6 +372,17 @@ type NetworkUpdateInput struct {
 
 	GuestDomain string `json:"guest_domain"`
 
+	// IPv6起始地址
+	GuestIp6Start string `json:"guest_ip6_start"`
+	// IPv6结束地址
+	GuestIp6End string `json:"guest_ip6_end"`
+	// IPv6掩码
+	GuestIp6Mask *int8 `json:"guest_ip6_mask"`
+	// IPv6网关地址
+	GuestGateway6 string `json:"guest_gateway6"`
+	// IPv6 DNS
+	GuestDns6 string `json:"guest_dns6"`
+
 	VlanId *int `json:"vlan_id"`
 
 	// 分配策略
@@ -0,0 +1,174 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+const (
+	// 只记录迁移建议, 由管理员执行
+	REBALANCE_MODE_RECOMMEND = "recommend"
+	// 自动执行迁移
+	REBALANCE_MODE_AUTO = "auto"
+
+	REBALANCE_POLICY_STATUS_READY = "ready"
+
+	REBALANCE_RECOMMENDATION_STATUS_PENDING   = "pending"
+	REBALANCE_RECOMMENDATION_STATUS_MIGRATING = "migrating"
+	REBALANCE_RECOMMENDATION_STATUS_DONE      = "done"
+	REBALANCE_RECOMMENDATION_STATUS_FAILED    = "failed"
+	REBALANCE_RECOMMENDATION_STATUS_REJECTED  = "rejected"
+	REBALANCE_RECOMMENDATION_STATUS_EXPIRED   = "expired"
+
+	// 默认CPU分配率差值阈值(百分比)
+	REBALANCE_DEFAULT_CPU_THRESHOLD = 20
+	// 默认内存分配率差值阈值(百分比)
+	REBALANCE_DEFAULT_MEMORY_THRESHOLD = 20
+	// 默认迁移后目标宿主机最高负载(百分比)
+	REBALANCE_DEFAULT_MAX_UTILIZATION = 80
+	// 默认每轮最多迁移数
+	REBALANCE_DEFAULT_MAX_MIGRATIONS = 5
+	// 默认评估间隔(分钟)
+	REBALANCE_DEFAULT_INTERVAL_MINUTES = 60
+)
+
+var (
+	REBALANCE_MODES = []string{
+		REBALANCE_MODE_RECOMMEND,
+		REBALANCE_MODE_AUTO,
+	}
+)
+
+type RebalancePolicyCreateInput struct {
+	apis.EnabledStatusInfrasResourceBaseCreateInput
+	ZoneResourceInput
+
+	// 调度标签Id或名称, 与可用区同时指定时取交集
+	SchedtagId string `json:"schedtag_id"`
+
+	// 执行方式
+	// enum: recommend, auto
+	// default: recommend
+	Mode string `json:"mode"`
+
+	// 宿主机CPU负载最高与最低之差超过此百分比时触发均衡
+	// default: 20
+	CpuThreshold int `json:"cpu_threshold"`
+	// 宿主机内存负载最高与最低之差超过此百分比时触发均衡
+	// default: 20
+	MemoryThreshold int `json:"memory_threshold"`
+	// 迁移后目标宿主机CPU和内存负载不超过此百分比
+	// default: 80
+	MaxUtilization int `json:"max_utilization"`
+	// 每轮评估最多迁移的虚拟机数量
+	// default: 5
+	MaxMigrations int `json:"max_migrations"`
+	// 是否使用监控数据计算负载, 否则按已分配的CPU和内存计算
+	UseMetrics *bool `json:"use_metrics"`
+	// 评估间隔(分钟)
+	// default: 60
+	IntervalMinutes int `json:"interval_minutes"`
+}
+
+type RebalancePolicyUpdateInput struct {
+	apis.EnabledStatusInfrasResourceBaseUpdateInput
+
+	Mode            string `json:"mode"`
+	CpuThreshold    *int   `json:"cpu_threshold"`
+	MemoryThreshold *int   `json:"memory_threshold"`
+	MaxUtilization  *int   `json:"max_utilization"`
+	MaxMigrations   *int   `json:"max_migrations"`
+	UseMetrics      *bool  `json:"use_metrics"`
+	IntervalMinutes *int   `json:"interval_minutes"`
+}
+
+type RebalancePolicyListInput struct {
+	apis.EnabledStatusInfrasResourceBaseListInput
+	ZonalFilterListInput
+
+	// 按调度标签过滤
+	SchedtagId string `json:"schedtag_id"`
+	// 按执行方式过滤
+	Mode []string `json:"mode"`
+}
+
+type RebalancePolicyDetails struct {
+	apis.EnabledStatusInfrasResourceBaseDetails
+	ZoneResourceInfo
+
+	// 调度标签名称
+	Schedtag string `json:"schedtag"`
+}
+
+type RebalancePolicyEvaluateInput struct {
+	// 只计算迁移计划, 不记录也不执行
+	DryRun bool `json:"dry_run"`
+}
+
+type RebalanceMigration struct {
+	GuestId   string `json:"guest_id"`
+	Guest     string `json:"guest"`
+	SrcHostId string `json:"src_host_id"`
+	SrcHost   string `json:"src_host"`
+	DstHostId string `json:"dst_host_id"`
+	DstHost   string `json:"dst_host"`
+
+	// 迁移前后的不均衡度
+	ImbalanceBefore float64 `json:"imbalance_before"`
+	ImbalanceAfter  float64 `json:"imbalance_after"`
+
+	Reason string `json:"reason"`
+}
+
+// RebalancePlan is the result of one evaluation of rebalance policy
+type RebalancePlan struct {
+	// 参与均衡的宿主机数量
+	HostCount int `json:"host_count"`
+	// 宿主机CPU负载最高与最低之差(百分比)
+	CpuSpread float64 `json:"cpu_spread"`
+	// 宿主机内存负载最高与最低之差(百分比)
+	MemorySpread float64 `json:"memory_spread"`
+	// 不均衡度, 即CPU和内存负载标准差之和
+	Imbalance float64 `json:"imbalance"`
+	// 执行迁移后的不均衡度
+	ImbalanceAfter float64 `json:"imbalance_after"`
+
+	Migrations []RebalanceMigration `json:"migrations"`
+
+	Note string `json:"note,omitempty"`
+}
+
+type RebalanceRecommendationListInput struct {
+	apis.StatusStandaloneResourceListInput
+
+	// 按均衡策略过滤
+	RebalancePolicyId string `json:"rebalance_policy_id"`
+	// 按虚拟机过滤
+	GuestId string `json:"guest_id"`
+	// 按源或目标宿主机过滤
+	HostId string `json:"host_id"`
+}
+
+type RebalanceRecommendationDetails struct {
+	apis.StatusStandaloneResourceDetails
+
+	RebalancePolicy string `json:"rebalance_policy"`
+	Guest           string `json:"guest"`
+	SrcHost         string `json:"src_host"`
+	DstHost         string `json:"dst_host"`
+}
+
+type RebalanceRecommendationRejectInput struct {
+	Reason string `json:"reason"`
+}
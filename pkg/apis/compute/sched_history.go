@@ -0,0 +1,77 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"reflect"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+// SchedHistoryCandidate is a host considered by one scheduling request
+type SchedHistoryCandidate struct {
+	// 宿主机Id
+	HostId string `json:"host_id"`
+	// 宿主机名称
+	Host string `json:"host"`
+
+	// 被过滤的阶段, 未被过滤时为空
+	Stage string `json:"stage,omitempty"`
+	// 被过滤的原因
+	Reasons []string `json:"reasons,omitempty"`
+
+	// 优先级打分详情
+	Score string `json:"score,omitempty"`
+	// 被选中的次数
+	Selected int `json:"selected,omitempty"`
+}
+
+type SchedHistoryCandidates []SchedHistoryCandidate
+
+func (cs SchedHistoryCandidates) String() string {
+	return jsonutils.Marshal(cs).String()
+}
+
+func (cs SchedHistoryCandidates) IsZero() bool {
+	return len(cs) == 0
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&SchedHistoryCandidates{}), func() gotypes.ISerializable {
+		return &SchedHistoryCandidates{}
+	})
+}
+
+type SchedHistoryListInput struct {
+	apis.StandaloneAnonResourceListInput
+
+	// 按虚拟机Id过滤
+	ServerId string `json:"server_id"`
+	// 按调度会话Id过滤
+	SessionId []string `json:"session_id"`
+	// 按选中的宿主机Id过滤
+	HostId string `json:"host_id"`
+	// 按虚拟化类型过滤
+	Hypervisor []string `json:"hypervisor"`
+	// 按是否调度成功过滤
+	Success *bool `json:"success"`
+}
+
+type SchedHistoryDetails struct {
+	apis.StandaloneAnonResourceDetails
+}
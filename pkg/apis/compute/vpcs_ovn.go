@@ -95,6 +95,13 @@ const (
 	VpcEipGatewayMac3 = "ee:ee:ee:ee:ee:f0"
 )
 
+const (
+	// [100.65.64.0, 100.65.127.255], a /30 for each peering of vpcs, 16384
+	sVpcPeeringTransitStart = "100.65.64.0"
+	sVpcPeeringTransitEnd   = "100.65.127.252"
+	VpcPeeringTransitMask   = 30
+)
+
 var (
 	vpcMappedCidr      netutils.IPV4Prefix
 	vpcMappedGatewayIP netutils.IPV4Addr
@@ -108,6 +115,9 @@ var (
 
 	vpcMappedIPStart netutils.IPV4Addr
 	vpcMappedIPEnd   netutils.IPV4Addr
+
+	vpcPeeringTransitStart netutils.IPV4Addr
+	vpcPeeringTransitEnd   netutils.IPV4Addr
 )
 
 func init() {
@@ -140,6 +150,9 @@ func init() {
 
 	vpcMappedIPStart = mi(netutils.NewIPV4Addr(sVpcMappedIPStart))
 	vpcMappedIPEnd = mi(netutils.NewIPV4Addr(sVpcMappedIPEnd))
+
+	vpcPeeringTransitStart = mi(netutils.NewIPV4Addr(sVpcPeeringTransitStart))
+	vpcPeeringTransitEnd = mi(netutils.NewIPV4Addr(sVpcPeeringTransitEnd))
 }
 
 func VpcMappedCidr() netutils.IPV4Prefix {
@@ -177,3 +190,11 @@ func VpcMappedIPStart() netutils.IPV4Addr {
 func VpcMappedIPEnd() netutils.IPV4Addr {
 	return vpcMappedIPEnd
 }
+
+func VpcPeeringTransitStart() netutils.IPV4Addr {
+	return vpcPeeringTransitStart
+}
+
+func VpcPeeringTransitEnd() netutils.IPV4Addr {
+	return vpcPeeringTransitEnd
+}
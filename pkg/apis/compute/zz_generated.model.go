@@ -972,6 +972,8 @@ type SGroup struct {
 	// the upper limit number of guests with this group in a host
 	Granularity     int   `json:"granularity"`
 	ForceDispersion *bool `json:"force_dispersion,omitempty"`
+	// 拓扑分布约束
+	TopologySpreadConstraints *TopologySpreadConstraints `json:"topology_spread_constraints"`
 }
 
 // SGroupJointsBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGroupJointsBase.
@@ -1044,6 +1046,15 @@ type SGuest struct {
 	// 套餐名称
 	InstanceType     string `json:"instance_type"`
 	SshableLastState *bool  `json:"sshable_last_state,omitempty"`
+	// 是否为抢占式实例
+	Preemptible bool `json:"preemptible"`
+	// 被抢占时执行的操作
+	// example: stop
+	PreemptAction string `json:"preempt_action"`
+	// 计划被抢占的时间
+	PreemptAt time.Time `json:"preempt_at"`
+	// 抢占此实例的实例Id
+	PreemptedBy string `json:"preempted_by"`
 }
 
 // SGuestJointsBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGuestJointsBase.
@@ -1127,6 +1138,12 @@ type SGuestnetwork struct {
 	Driver string `json:"driver"`
 	// 带宽限制，单位mbps
 	BwLimit int `json:"bw_limit"`
+	// 入方向(流入虚拟机)带宽限制，单位mbps，为0时同BwLimit，目前仅对vpc网络生效
+	IngressBwLimit int `json:"ingress_bw_limit"`
+	// 出方向(流出虚拟机)带宽限制，单位mbps，为0时同BwLimit，目前仅对vpc网络生效
+	EgressBwLimit int `json:"egress_bw_limit"`
+	// 突发流量，单位kbits，为0时为带宽限制的2倍
+	BwBurst int `json:"bw_burst"`
 	// 网卡序号
 	Index byte `json:"index"`
 	// 是否为虚拟接口（无IP）
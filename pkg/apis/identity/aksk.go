@@ -25,6 +25,9 @@ const (
 	TOTP_TYPE             = "totp"
 	RECOVERY_SECRETS_TYPE = "recovery_secret"
 	OIDC_CREDENTIAL_TYPE  = "oidc"
+	ENCRYPT_KEY_TYPE      = "enc_key"
+
+	ENCRYPT_ALG_AES_256 = "aes-256"
 )
 
 type SAccessKeySecretBlob struct {
@@ -43,3 +46,8 @@ type SAccessKeySecretInfo struct {
 	AccessKey string
 	SAccessKeySecretBlob
 }
+
+type SEncryptKeySecretBlob struct {
+	Key string `json:"key"`
+	Alg string `json:"alg"`
+}
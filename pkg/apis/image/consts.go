@@ -40,9 +40,13 @@ const (
 
 	LocalFilePrefix = "file://"
 	S3Prefix        = "s3://"
+	RbdPrefix       = "rbd://"
+	NfsPrefix       = "nfs://"
 
 	IMAGE_STORAGE_DRIVER_LOCAL = "local"
 	IMAGE_STORAGE_DRIVER_S3    = "s3"
+	IMAGE_STORAGE_DRIVER_RBD   = "rbd"
+	IMAGE_STORAGE_DRIVER_NFS   = "nfs"
 
 	// image properties
 	IMAGE_OS_ARCH             = "os_arch"
@@ -57,6 +61,34 @@ const (
 	IMAGE_INSTALLED_CLOUDINIT = "installed_cloud_init"
 	IMAGE_DISABLE_USB_KBD     = "disable_usb_kbd"
 
+	// hardware hints of images imported from ovf packages
+	IMAGE_VCPU_COUNT  = "vcpu_count"
+	IMAGE_VMEM_SIZE   = "vmem_size"
+	IMAGE_NIC_COUNT   = "nic_count"
+	IMAGE_NET_DRIVER  = "net_driver"
+	IMAGE_DISK_DRIVER = "disk_driver"
+	IMAGE_BIOS        = "bios"
+	IMAGE_OVF_OS_TYPE = "ovf_os_type"
+
+	// base64 encoded detached signature of the sha256 digest of the image file
+	IMAGE_SIGNATURE = "img_signature"
+	// fingerprint of the trusted key which signs the image, optional
+	IMAGE_SIGNATURE_KEY = "img_signature_key"
+
+	IMAGE_SIGNATURE_STATUS_UNSIGNED   = "unsigned"
+	IMAGE_SIGNATURE_STATUS_VERIFIED   = "verified"
+	IMAGE_SIGNATURE_STATUS_UNVERIFIED = "unverified"
+
+	IMAGE_REPLICA_STATUS_PENDING     = "pending"
+	IMAGE_REPLICA_STATUS_REPLICATING = "replicating"
+	IMAGE_REPLICA_STATUS_READY       = "ready"
+	IMAGE_REPLICA_STATUS_FAILED      = "failed"
+
+	IMAGE_REPLICATION_POLICY_STATUS_READY = "ready"
+
+	IMAGE_IMPORT_FORMAT_OVA = "ova"
+	IMAGE_IMPORT_FORMAT_OVF = "ovf"
+
 	IMAGE_STATUS_UPDATING = "updating"
 )
 
@@ -49,3 +49,13 @@ type SubImageInfo struct {
 	Status     string    `json:"status"`
 	CreatedAt  time.Time `json:"created_at"`
 }
+
+type GuestImageImportInput struct {
+	// 导入格式, 支持 ova, ovf
+	ImportFormat string `json:"import_format"`
+	// ova或ovf描述文件的下载地址, 上传ova文件时不需要指定
+	CopyFrom string `json:"copy_from"`
+	// ovf引用的磁盘文件下载地址, 按文件名与ovf中的引用匹配
+	// 未指定时按ovf描述文件的地址查找相对路径
+	DiskUrls []string `json:"disk_urls"`
+}
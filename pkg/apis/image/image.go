@@ -49,6 +49,9 @@ type ImageListInput struct {
 
 	// 发行版本，可能值为: CentOS, Ubuntu, Debian, ArchLinux,  OpenEuler 等
 	Distributions []string `json:"distributions"`
+
+	// 以签名验证状态过滤, 可能值为: unsigned, verified, unverified
+	SignatureStatus []string `json:"signature_status"`
 }
 
 type GuestImageListInput struct {
@@ -68,6 +71,8 @@ type ImageDetails struct {
 	Properties map[string]string `json:"properties"`
 	// 自动清除时间
 	AutoDeleteAt time.Time `json:"auto_delete_at"`
+	// 镜像签名是否通过可信公钥验证
+	Verified bool `json:"verified"`
 	// 删除保护
 	DisableDelete bool `json:"disable_delete"`
 	//OssChecksum   string    `json:"oss_checksum"`
@@ -95,6 +100,11 @@ type ImageCreateInput struct {
 
 	// 镜像属性
 	Properties map[string]string `json:"properties"`
+
+	// 从其他区域复制镜像时, 源镜像所在区域
+	SourceRegion string `json:"source_region"`
+	// 从其他区域复制镜像时, 源镜像ID
+	SourceImageId string `json:"source_image_id"`
 }
 
 type ImageUpdateStatusInput struct {
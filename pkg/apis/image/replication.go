@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"reflect"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+type ImageReplicaRegions []string
+
+func (regions ImageReplicaRegions) IsZero() bool {
+	return len(regions) == 0
+}
+
+func (regions ImageReplicaRegions) String() string {
+	return jsonutils.Marshal(regions).String()
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&ImageReplicaRegions{}), func() gotypes.ISerializable {
+		return &ImageReplicaRegions{}
+	})
+}
+
+type ImageReplicateInput struct {
+	// 目标区域
+	// required: true
+	Regions []string `json:"regions"`
+}
+
+type ImageReplicaDetails struct {
+	// 目标区域
+	Region string `json:"region"`
+	// 目标区域的镜像ID
+	RemoteImageId string `json:"remote_image_id"`
+	// 复制状态, 可能值为: pending, replicating, ready, failed
+	Status string `json:"status"`
+	// 失败原因
+	Reason string `json:"reason"`
+	// 目标区域镜像的校验和
+	Checksum string `json:"checksum"`
+}
+
+type ImageReplicationPolicyCreateInput struct {
+	apis.EnabledStatusStandaloneResourceCreateInput
+
+	// 目标区域
+	// required: true
+	TargetRegions ImageReplicaRegions `json:"target_regions"`
+	// 复制标准镜像
+	IsStandard *bool `json:"is_standard"`
+	// 复制带有该标签的镜像
+	TagKey string `json:"tag_key"`
+	// 标签值, 为空则匹配任意值
+	TagValue string `json:"tag_value"`
+}
+
+type ImageReplicationPolicyUpdateInput struct {
+	apis.EnabledStatusStandaloneResourceBaseUpdateInput
+
+	// 目标区域
+	TargetRegions ImageReplicaRegions `json:"target_regions"`
+}
+
+type ImageReplicationPolicyListInput struct {
+	apis.EnabledStatusStandaloneResourceListInput
+
+	// 以目标区域过滤
+	TargetRegion string `json:"target_region"`
+}
+
+type ImageReplicationPolicyDetails struct {
+	apis.EnabledStatusStandaloneResourceDetails
+
+	SImageReplicationPolicy
+}
@@ -0,0 +1,40 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+type ImageTrustedKeyCreateInput struct {
+	apis.DomainLevelResourceCreateInput
+
+	// PEM格式的公钥, 用于验证该域内镜像的签名
+	// required: true
+	PublicKey string `json:"public_key"`
+}
+
+type ImageTrustedKeyListInput struct {
+	apis.DomainLevelResourceListInput
+
+	// 以公钥指纹过滤
+	Fingerprint []string `json:"fingerprint"`
+	// 以公钥类型过滤, 可能值为: rsa, ecdsa, ed25519
+	KeyType []string `json:"key_type"`
+}
+
+type ImageTrustedKeyDetails struct {
+	apis.DomainLevelResourceDetails
+
+	SImageTrustedKey
+}
@@ -62,6 +62,8 @@ type SImage struct {
 	// image copy from url, save origin checksum before probe
 	// 从镜像时长导入的镜像校验和
 	OssChecksum string `json:"oss_checksum"`
+	// 镜像签名验证状态
+	SignatureStatus string `json:"signature_status"`
 }
 
 // SImageMember is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageMember.
@@ -85,6 +87,29 @@ type SImageProperty struct {
 	Value string `json:"value"`
 }
 
+// SImageReplica is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageReplica.
+type SImageReplica struct {
+	SImagePeripheral
+	Region        string `json:"region"`
+	RemoteImageId string `json:"remote_image_id"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason"`
+	Checksum      string `json:"checksum"`
+}
+
+// SImageReplicationPolicy is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageReplicationPolicy.
+type SImageReplicationPolicy struct {
+	apis.SEnabledStatusStandaloneResourceBase
+	// 目标区域
+	TargetRegions *ImageReplicaRegions `json:"target_regions"`
+	// 复制标准镜像
+	IsStandard *bool `json:"is_standard"`
+	// 复制带有该标签的镜像
+	TagKey string `json:"tag_key"`
+	// 标签值, 为空则匹配任意值
+	TagValue string `json:"tag_value"`
+}
+
 // SImageSubformat is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageSubformat.
 type SImageSubformat struct {
 	SImagePeripheral
@@ -100,6 +125,17 @@ type SImageSubformat struct {
 	TorrentStatus   string `json:"torrent_status"`
 }
 
+// SImageTrustedKey is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageTrustedKey.
+type SImageTrustedKey struct {
+	apis.SDomainLevelResourceBase
+	// PEM格式的公钥
+	PublicKey string `json:"public_key"`
+	// 公钥指纹
+	Fingerprint string `json:"fingerprint"`
+	// 公钥类型, 可能值为: rsa, ecdsa, ed25519
+	KeyType string `json:"key_type"`
+}
+
 // SImageTag is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageTag.
 type SImageTag struct {
 	SImagePeripheral
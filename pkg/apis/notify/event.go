@@ -36,6 +36,8 @@ var (
 	ActionSyncStatus     SAction = "sync_status"
 	ActionCleanData      SAction = "clean_data"
 	ActionMigrate        SAction = "migrate"
+	ActionRenew          SAction = "renew"
+	ActionPreempt        SAction = "preempt"
 
 	ActionCreateBackupServer SAction = "add_backup_server"
 	ActionDelBackupServer    SAction = "delete_backup_server"
@@ -134,6 +134,9 @@ type CandidateResource struct {
 	// used by backup schedule
 	BackupCandidate *CandidateResource `json:"backup_candidate"`
 
+	// preemptible guests on host to be stopped or deleted
+	PreemptGuests []string `json:"preempt_guests,omitempty"`
+
 	// Error means no candidate found, include reasons
 	Error string `json:"error"`
 }
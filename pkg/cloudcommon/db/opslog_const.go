@@ -84,6 +84,11 @@ const (
 	ACT_PROBE_FAIL        = "probe_fail"
 	ACT_IMAGE_DELETE_FAIL = "delete_fail"
 
+	ACT_VERIFY_SIGNATURE      = "verify_signature"
+	ACT_VERIFY_SIGNATURE_FAIL = "verify_signature_fail"
+
+	ACT_REPLICATE = "replicate"
+
 	ACT_SWITCHED      = "switched"
 	ACT_SWITCH_FAILED = "switch_failed"
 
@@ -232,7 +237,8 @@ const (
 
 	ACT_RENEW = "renew"
 
-	ACT_SCHEDULE = "schedule"
+	ACT_SCHEDULE          = "schedule"
+	ACT_SCHEDULE_DECISION = "schedule_decision"
 
 	ACT_RECYCLE_PREPAID      = "recycle_prepaid"
 	ACT_UNDO_RECYCLE_PREPAID = "undo_recycle_prepaid"
@@ -287,4 +293,7 @@ const (
 
 	ACT_MERGE_NETWORK        = "merge_network"
 	ACT_MERGE_NETWORK_FAILED = "merge_network_failed"
+
+	ACT_REBALANCE  = "rebalance"
+	ACT_POWER_SAVE = "power_save"
 )
@@ -0,0 +1,88 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"context"
+	"encoding/base64"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+)
+
+const (
+	KEY_MANAGER_KEYSTONE = "keystone"
+)
+
+// SKeystoneKeyManager keeps master keys as keystone credentials of type enc_key
+type SKeystoneKeyManager struct{}
+
+func init() {
+	RegisterKeyManager(&SKeystoneKeyManager{})
+}
+
+func (manager *SKeystoneKeyManager) GetName() string {
+	return KEY_MANAGER_KEYSTONE
+}
+
+func (manager *SKeystoneKeyManager) fetchKey(ctx context.Context, keyId string) ([]byte, error) {
+	s := auth.GetAdminSession(ctx, "", "")
+	secret, err := modules.Credentials.GetEncryptKey(s, keyId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetEncryptKey %s", keyId)
+	}
+	return base64.StdEncoding.DecodeString(secret.Key)
+}
+
+func (manager *SKeystoneKeyManager) ValidateKey(ctx context.Context, userCred mcclient.TokenCredential, keyId string) error {
+	s := auth.GetAdminSession(ctx, "", "")
+	secret, err := modules.Credentials.Get(s, keyId, nil)
+	if err != nil {
+		if je, ok := err.(*httputils.JSONClientError); ok && je.Code == 404 {
+			return httperrors.NewResourceNotFoundError2("credential", keyId)
+		}
+		return errors.Wrap(err, "Credentials.Get")
+	}
+	if _, err := modules.DecodeEncryptKey(secret); err != nil {
+		return httperrors.NewInputParameterError("credential %s is not an encryption key", keyId)
+	}
+	userId, _ := secret.GetString("user_id")
+	if userId != userCred.GetUserId() && !userCred.HasSystemAdminPrivilege() {
+		return httperrors.NewForbiddenError("not allow to use encryption key %s", keyId)
+	}
+	return nil
+}
+
+func (manager *SKeystoneKeyManager) WrapDataKey(ctx context.Context, keyId string, dataKey []byte) (string, error) {
+	kek, err := manager.fetchKey(ctx, keyId)
+	if err != nil {
+		return "", err
+	}
+	return seclib2.WrapKey(kek, dataKey)
+}
+
+func (manager *SKeystoneKeyManager) UnwrapDataKey(ctx context.Context, keyId string, wrapped string) ([]byte, error) {
+	kek, err := manager.fetchKey(ctx, keyId)
+	if err != nil {
+		return nil, err
+	}
+	return seclib2.UnwrapKey(kek, wrapped)
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"context"
+	"sync"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+)
+
+const (
+	ErrKeyManagerNotFound = errors.Error("key manager not found")
+)
+
+// IKeyManager wraps and unwraps per-resource data keys with a master key
+// that never leaves the key management service
+type IKeyManager interface {
+	GetName() string
+
+	// ValidateKey checks that keyId exists and userCred is allowed to use it
+	ValidateKey(ctx context.Context, userCred mcclient.TokenCredential, keyId string) error
+
+	WrapDataKey(ctx context.Context, keyId string, dataKey []byte) (string, error)
+	UnwrapDataKey(ctx context.Context, keyId string, wrapped string) ([]byte, error)
+}
+
+var (
+	keyManagers    = map[string]IKeyManager{}
+	keyManagerLock = &sync.Mutex{}
+)
+
+func RegisterKeyManager(manager IKeyManager) {
+	keyManagerLock.Lock()
+	defer keyManagerLock.Unlock()
+
+	keyManagers[manager.GetName()] = manager
+}
+
+func GetKeyManager(name string) (IKeyManager, error) {
+	keyManagerLock.Lock()
+	defer keyManagerLock.Unlock()
+
+	manager, ok := keyManagers[name]
+	if !ok {
+		return nil, errors.Wrap(ErrKeyManagerNotFound, name)
+	}
+	return manager, nil
+}
+
+// GenerateWrappedDataKey creates a new data key and returns it along with its wrapped form
+func GenerateWrappedDataKey(ctx context.Context, manager IKeyManager, keyId string) ([]byte, string, error) {
+	dataKey, err := seclib2.GenerateDataKey()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "GenerateDataKey")
+	}
+	wrapped, err := manager.WrapDataKey(ctx, keyId, dataKey)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "WrapDataKey")
+	}
+	return dataKey, wrapped, nil
+}
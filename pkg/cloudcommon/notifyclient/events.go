@@ -56,6 +56,8 @@ var (
 	ActionCreateBackupServer = api.ActionCreateBackupServer
 	ActionDelBackupServer    = api.ActionDelBackupServer
 	ActionSyncStatus         = api.ActionSyncStatus
+	ActionRenew              = api.ActionRenew
+	ActionPreempt            = api.ActionPreempt
 
 	ActionPendingDelete = api.ActionPendingDelete
 )
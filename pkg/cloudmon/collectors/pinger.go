@@ -16,6 +16,7 @@ package collectors
 
 import (
 	"strconv"
+	"strings"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -53,9 +54,56 @@ func pingProbeCoolector(s *mcclient.ClientSession, args *common.ReportOptions) e
 		}
 		return nil
 	})
+	pingProbeDnsRecords(s, &args.PingProbeOptions)
 	return sendMetrics(s, metrics, args.Debug)
 }
 
+type sDnsRecord struct {
+	Id      string
+	Name    string
+	Records string
+}
+
+// pingProbeDnsRecords reports A/AAAA values of dnsrecords with health check
+// enabled not responding ping, so that region-dns stops answering them
+func pingProbeDnsRecords(s *mcclient.ClientSession, args *common.PingProbeOptions) {
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.JSONTrue, "health_check")
+	params.Add(jsonutils.NewString(string(rbacutils.ScopeSystem)), "scope")
+	listAll(s, modules.DNSRecords.List, params, func(data jsonutils.JSONObject) error {
+		rec := sDnsRecord{}
+		if err := data.Unmarshal(&rec); err != nil {
+			return errors.Wrap(err, "Unmarshal dnsrecord")
+		}
+		addrs := []string{}
+		for _, r := range strings.Split(rec.Records, ",") {
+			if strings.HasPrefix(r, "A:") || strings.HasPrefix(r, "AAAA:") {
+				addrs = append(addrs, r[strings.Index(r, ":")+1:])
+			}
+		}
+		if len(addrs) == 0 {
+			return nil
+		}
+		pingResults, err := Ping(addrs, args.ProbeCount, time.Second*time.Duration(args.TimeoutSecond), args.Debug)
+		if err != nil {
+			return errors.Wrapf(err, "Ping records of %s", rec.Name)
+		}
+		input := api.DnsRecordReportHealthInput{
+			Unhealthy: []string{},
+		}
+		for _, addr := range addrs {
+			if pingResults[addr].Loss() >= 100 {
+				input.Unhealthy = append(input.Unhealthy, addr)
+			}
+		}
+		_, err = modules.DNSRecords.PerformAction(s, rec.Id, "report-health", jsonutils.Marshal(input))
+		if err != nil {
+			return errors.Wrapf(err, "report health of %s", rec.Name)
+		}
+		return nil
+	})
+}
+
 type sNetwork struct {
 	Id           string
 	Name         string
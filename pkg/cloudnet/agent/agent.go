@@ -0,0 +1,213 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/cloudnet"
+)
+
+// Agent runs on router.  It pulls router config from cloudnet api on changes
+// of cloudnet resources, applies wireguard ifaces, routes and iptables rules,
+// then reports status of wireguard peers back
+type Agent struct {
+	opts *Options
+
+	// signaled by resource events to sync at once
+	syncCh chan struct{}
+
+	state     *sState
+	cfg       *api.RouterAgentConfig
+	appliedAt time.Time
+}
+
+func NewAgent(opts *Options) (*Agent, error) {
+	state, err := loadState(opts.stateFile)
+	if err != nil {
+		return nil, err
+	}
+	a := &Agent{
+		opts:   opts,
+		state:  state,
+		syncCh: make(chan struct{}, 1),
+	}
+	return a, nil
+}
+
+func (a *Agent) session(ctx context.Context) *mcclient.ClientSession {
+	return auth.GetAdminSession(ctx, a.opts.Region, "v2")
+}
+
+func (a *Agent) Start(ctx context.Context) {
+	wg := ctx.Value("wg").(*sync.WaitGroup)
+	defer func() {
+		log.Infoln("cloudnet agent: bye")
+		wg.Done()
+	}()
+
+	var (
+		syncDuration   = time.Duration(a.opts.SyncIntervalSeconds) * time.Second
+		reportDuration = time.Duration(a.opts.ReportIntervalSeconds) * time.Second
+		syncTimer      = time.NewTimer(0)
+		reportTimer    = time.NewTimer(reportDuration)
+	)
+	defer syncTimer.Stop()
+	defer reportTimer.Stop()
+	a.startWatch(ctx)
+	for {
+		select {
+		case <-a.syncCh:
+			if !syncTimer.Stop() {
+				select {
+				case <-syncTimer.C:
+				default:
+				}
+			}
+			if err := a.sync(ctx); err != nil {
+				log.Errorf("cloudnet agent: sync: %v", err)
+			}
+			syncTimer.Reset(syncDuration)
+		case <-syncTimer.C:
+			// polling is the fallback of missed resource events
+			if err := a.sync(ctx); err != nil {
+				log.Errorf("cloudnet agent: sync: %v", err)
+			}
+			syncTimer.Reset(syncDuration)
+		case <-reportTimer.C:
+			if err := a.report(ctx); err != nil {
+				log.Errorf("cloudnet agent: report: %v", err)
+			}
+			reportTimer.Reset(reportDuration)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (a *Agent) fetchConfig(ctx context.Context) (*api.RouterAgentConfig, error) {
+	j, err := cloudnet.Routers.GetSpecific(a.session(ctx), a.opts.RouterId, "agent-config", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "get agent config")
+	}
+	cfg := &api.RouterAgentConfig{}
+	if err := j.Unmarshal(cfg); err != nil {
+		return nil, errors.Wrap(err, "unmarshal agent config")
+	}
+	return cfg, nil
+}
+
+// sync fetches config and applies it when the digest changed, or when it's
+// time for a periodic resync in case the state was altered by others
+func (a *Agent) sync(ctx context.Context) error {
+	cfg, err := a.fetchConfig(ctx)
+	if err != nil {
+		return err
+	}
+	a.cfg = cfg
+
+	resyncDuration := time.Duration(a.opts.ResyncIntervalSeconds) * time.Second
+	if cfg.Digest == a.state.Digest && time.Since(a.appliedAt) < resyncDuration {
+		return nil
+	}
+	log.Infof("cloudnet agent: applying config %s, last applied %s", cfg.Digest, a.state.Digest)
+	if err := a.apply(ctx, cfg); err != nil {
+		return err
+	}
+	a.appliedAt = time.Now()
+	// report early so that api learns the new digest
+	return a.report(ctx)
+}
+
+func (a *Agent) apply(ctx context.Context, cfg *api.RouterAgentConfig) error {
+	var (
+		errs     []error
+		state    = *a.state
+		wgRoutes = a.state.WgRoutes
+		routes   = a.state.Routes
+	)
+	if cfg.RealizeWgIfaces {
+		wgRoutes = nil
+		for i := range cfg.Ifaces {
+			wgRoutes = append(wgRoutes, wgRouteLines(&cfg.Ifaces[i])...)
+		}
+	}
+	if cfg.RealizeRoutes {
+		routes = nil
+		for _, lines := range cfg.Routes {
+			routes = append(routes, lines...)
+		}
+	}
+
+	if err := ioutil.WriteFile("/proc/sys/net/ipv4/ip_forward", []byte("1"), 0644); err != nil {
+		errs = append(errs, errors.Wrap(err, "enable ip_forward"))
+	}
+	if cfg.RealizeWgIfaces {
+		ifnames, err := a.applyWireguard(ctx, cfg)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		// stale routes that are also wanted by the other part are left
+		// alone
+		if err := a.applyRoutes(ctx, wgRoutes, stale(a.state.WgRoutes, routes)); err != nil {
+			errs = append(errs, err)
+		}
+		state.Ifnames = ifnames
+		state.WgRoutes = wgRoutes
+	}
+	if cfg.RealizeRoutes {
+		if err := a.applyRoutes(ctx, routes, stale(a.state.Routes, wgRoutes)); err != nil {
+			errs = append(errs, err)
+		}
+		state.Routes = routes
+	}
+	if cfg.RealizeRules {
+		if err := a.applyRules(ctx, cfg.Rules); err != nil {
+			errs = append(errs, errors.Wrap(err, "apply rules"))
+		}
+	}
+	if len(errs) == 0 {
+		state.Digest = cfg.Digest
+	}
+	*a.state = state
+	if err := a.state.save(a.opts.stateFile); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (a *Agent) report(ctx context.Context) error {
+	if a.cfg == nil {
+		return nil
+	}
+	input, err := a.collectStatus(ctx, a.cfg)
+	if err != nil {
+		return errors.Wrap(err, "collect status")
+	}
+	if _, err := cloudnet.Routers.PerformAction(a.session(ctx), a.cfg.RouterId, "report-status", jsonutils.Marshal(input)); err != nil {
+		return errors.Wrap(err, "report status")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+)
+
+func TestParseWgDump(t *testing.T) {
+	out := "wg0\tcHJpdg==\tcHVi\t20000\toff\n" +
+		"wg0\tcGVlcjE=\t(none)\t192.168.1.2:20000\t10.1.0.0/16\t1600000000\t100\t200\t25\n" +
+		"wg0\tcGVlcjI=\t(none)\t(none)\t10.2.0.0/16\t0\t0\t0\toff\n"
+	r := parseWgDump(out)
+	peers := r["wg0"]
+	if len(peers) != 2 {
+		t.Fatalf("want 2 peers, got %#v", r)
+	}
+	p := peers[0]
+	if p.PublicKey != "cGVlcjE=" || p.Endpoint != "192.168.1.2:20000" ||
+		!p.LatestHandshake.Equal(time.Unix(1600000000, 0)) || p.RxBytes != 100 || p.TxBytes != 200 {
+		t.Errorf("peer1: %#v", p)
+	}
+	p = peers[1]
+	if p.Endpoint != "" || !p.LatestHandshake.IsZero() || p.LatencyMs >= 0 {
+		t.Errorf("peer2: %#v", p)
+	}
+}
+
+func TestParsePingAvgRtt(t *testing.T) {
+	cases := []struct {
+		out  string
+		want float64
+		ok   bool
+	}{
+		{"3 packets transmitted, 3 received, 0% packet loss, time 2003ms\nrtt min/avg/max/mdev = 0.045/0.054/0.061/0.006 ms\n", 0.054, true},
+		{"3 packets transmitted, 3 packets received, 0% packet loss\nround-trip min/avg/max = 1.101/2.202/3.303 ms\n", 2.202, true},
+		{"3 packets transmitted, 0 received, 100% packet loss, time 2046ms\n", 0, false},
+	}
+	for _, c := range cases {
+		got, ok := parsePingAvgRtt(c.out)
+		if got != c.want || ok != c.ok {
+			t.Errorf("%q: want %v %v, got %v %v", c.out, c.want, c.ok, got, ok)
+		}
+	}
+}
+
+func TestIptablesRestoreInput(t *testing.T) {
+	rules := []api.RouterAgentRule{
+		{Priority: 1000, Table: "nat", Chain: "POSTROUTING", Body: "-s 10.0.0.0/8 -j MASQUERADE"},
+		{Priority: 0, Table: "nat", Chain: "POSTROUTING", Body: "-s 10.1.0.0/16 -j SNAT --to-source 1.2.3.4"},
+		{Priority: 0, Table: "raw", Chain: "PREROUTING", Body: "-j NOTRACK"},
+	}
+	got, err := iptablesRestoreInput(rules)
+	if err == nil {
+		t.Errorf("want error for unsupported chain")
+	}
+	want := `*filter
+:CLOUDNET-FORWARD - [0:0]
+:CLOUDNET-INPUT - [0:0]
+COMMIT
+*mangle
+:CLOUDNET-FORWARD - [0:0]
+COMMIT
+*nat
+:CLOUDNET-POSTROUTING - [0:0]
+:CLOUDNET-PREROUTING - [0:0]
+-A CLOUDNET-POSTROUTING -s 10.1.0.0/16 -j SNAT --to-source 1.2.3.4
+-A CLOUDNET-POSTROUTING -s 10.0.0.0/8 -j MASQUERADE
+COMMIT
+`
+	if got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestWgConf(t *testing.T) {
+	iface := &api.RouterAgentIface{
+		Ifname:     "wg0",
+		PrivateKey: "cHJpdg==",
+		ListenPort: 20000,
+		Peers: []api.RouterAgentIfacePeer{
+			{
+				Name:                "r1",
+				PublicKey:           "cGVlcjE=",
+				AllowedIPs:          "10.1.0.0/16,10.3.0.0/16",
+				Endpoint:            "192.168.1.2:20000",
+				PersistentKeepalive: 25,
+			},
+		},
+	}
+	want := `[Interface]
+PrivateKey = cHJpdg==
+ListenPort = 20000
+
+[Peer]
+# r1
+PublicKey = cGVlcjE=
+AllowedIPs = 10.1.0.0/16,10.3.0.0/16
+Endpoint = 192.168.1.2:20000
+PersistentKeepalive = 25
+`
+	if got := wgConf(iface); got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+	lines := wgRouteLines(iface)
+	if len(lines) != 2 || lines[0] != "10.1.0.0/16 dev wg0" || lines[1] != "10.3.0.0/16 dev wg0" {
+		t.Errorf("route lines: %#v", lines)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+func (a *Agent) command(ctx context.Context, stdin string, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(a.opts.CommandTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	cmd := procutils.NewCommandContext(ctx, name, args...)
+	if stdin != "" {
+		w, err := cmd.StdinPipe()
+		if err != nil {
+			return "", errors.Wrapf(err, "stdin pipe of %s", cmd)
+		}
+		go func() {
+			defer w.Close()
+			io.WriteString(w, stdin)
+		}()
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return string(out), errors.Wrapf(err, "%s: %s", cmd, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+func (a *Agent) run(ctx context.Context, name string, args ...string) error {
+	_, err := a.command(ctx, "", name, args...)
+	return err
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+)
+
+const chainPrefix = "CLOUDNET-"
+
+type sChain struct {
+	table string
+	chain string
+}
+
+func (c sChain) agentChain() string {
+	return chainPrefix + c.chain
+}
+
+// chains are where rules from cloudnet api may go.  Rules are put in agent
+// owned chains which are flushed and refilled on each apply, builtin chains
+// jump to them
+var chains = []sChain{
+	{"filter", "FORWARD"},
+	{"filter", "INPUT"},
+	{"mangle", "FORWARD"},
+	{"nat", "POSTROUTING"},
+	{"nat", "PREROUTING"},
+}
+
+// iptablesRestoreInput returns input for "iptables-restore --noflush".  All
+// agent chains are declared so that they will be flushed even if there are
+// no rules for them
+func iptablesRestoreInput(rules []api.RouterAgentRule) (string, error) {
+	rules = append([]api.RouterAgentRule(nil), rules...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority < rules[j].Priority
+	})
+
+	var (
+		b      = &strings.Builder{}
+		errs   []error
+		tables []string
+		lines  = map[string][]string{}
+	)
+	for _, c := range chains {
+		if _, ok := lines[c.table]; !ok {
+			tables = append(tables, c.table)
+		}
+		lines[c.table] = append(lines[c.table], fmt.Sprintf(":%s - [0:0]", c.agentChain()))
+	}
+	for i := range rules {
+		rule := &rules[i]
+		var found bool
+		for _, c := range chains {
+			if c.table == rule.Table && c.chain == rule.Chain {
+				found = true
+				lines[c.table] = append(lines[c.table], fmt.Sprintf("-A %s %s", c.agentChain(), rule.Body))
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, errors.Errorf("unsupported chain %s of table %s", rule.Chain, rule.Table))
+		}
+	}
+	for _, table := range tables {
+		fmt.Fprintf(b, "*%s\n", table)
+		for _, line := range lines[table] {
+			fmt.Fprintf(b, "%s\n", line)
+		}
+		fmt.Fprintf(b, "COMMIT\n")
+	}
+	return b.String(), errors.NewAggregate(errs)
+}
+
+func (a *Agent) applyRules(ctx context.Context, rules []api.RouterAgentRule) error {
+	input, err := iptablesRestoreInput(rules)
+	if err != nil {
+		return err
+	}
+	if _, err := a.command(ctx, input, a.opts.IptablesRestoreBin, "--noflush"); err != nil {
+		return err
+	}
+	var errs []error
+	for _, c := range chains {
+		jump := []string{"-t", c.table, "-C", c.chain, "-j", c.agentChain()}
+		if err := a.run(ctx, a.opts.IptablesBin, jump...); err == nil {
+			continue
+		}
+		jump = []string{"-t", c.table, "-I", c.chain, "1", "-j", c.agentChain()}
+		if err := a.run(ctx, a.opts.IptablesBin, jump...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
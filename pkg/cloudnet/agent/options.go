@@ -0,0 +1,83 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"os"
+	"path/filepath"
+
+	"yunion.io/x/pkg/errors"
+
+	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
+)
+
+type AgentOptions struct {
+	RouterId string `help:"id or name of the router this agent runs on" required:"true"`
+
+	SyncIntervalSeconds   int `default:"60" help:"interval for fetching config from cloudnet api, in case resource events are missed"`
+	ResyncIntervalSeconds int `default:"300" help:"interval for reapplying config even if it has not changed"`
+	ReportIntervalSeconds int `default:"60" help:"interval for reporting peer status to cloudnet api"`
+	PingCount             int `default:"3" help:"number of echo requests sent to each peer endpoint for measuring latency"`
+	PingTimeoutSeconds    int `default:"1" help:"time to wait for each echo reply"`
+	CommandTimeoutSeconds int `default:"30" help:"timeout for each wg, ip and iptables command"`
+
+	DataDir string `default:"/var/lib/cloudnet-agent" help:"directory for wireguard conf and agent state"`
+
+	WgBin              string `default:"wg"`
+	IpBin              string `default:"ip"`
+	IptablesBin        string `default:"iptables"`
+	IptablesRestoreBin string `default:"iptables-restore"`
+	PingBin            string `default:"ping"`
+
+	wgConfDir string
+	stateFile string
+}
+
+type Options struct {
+	common_options.CommonOptions
+
+	AgentOptions
+}
+
+func (opts *Options) ValidateThenInit() error {
+	if opts.RouterId == "" {
+		return errors.Error("empty router_id")
+	}
+	if opts.SyncIntervalSeconds < 5 {
+		opts.SyncIntervalSeconds = 5
+	}
+	if opts.ResyncIntervalSeconds < opts.SyncIntervalSeconds {
+		opts.ResyncIntervalSeconds = opts.SyncIntervalSeconds
+	}
+	if opts.ReportIntervalSeconds < 10 {
+		opts.ReportIntervalSeconds = 10
+	}
+	if opts.PingCount <= 0 {
+		opts.PingCount = 1
+	}
+	if opts.PingTimeoutSeconds <= 0 {
+		opts.PingTimeoutSeconds = 1
+	}
+	if opts.CommandTimeoutSeconds <= 0 {
+		opts.CommandTimeoutSeconds = 30
+	}
+
+	opts.wgConfDir = filepath.Join(opts.DataDir, "wireguard")
+	opts.stateFile = filepath.Join(opts.DataDir, "state.json")
+	if err := os.MkdirAll(opts.wgConfDir, 0700); err != nil {
+		return errors.Wrapf(err, "mkdir %s", opts.wgConfDir)
+	}
+	return nil
+}
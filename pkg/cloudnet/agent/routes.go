@@ -0,0 +1,43 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+// applyRoutes makes sure routes in lines are present and removes those in
+// olds that are not in lines
+func (a *Agent) applyRoutes(ctx context.Context, lines, olds []string) error {
+	var errs []error
+	for _, line := range lines {
+		args := append([]string{"route", "replace"}, strings.Fields(line)...)
+		if err := a.run(ctx, a.opts.IpBin, args...); err != nil {
+			errs = append(errs, errors.Wrapf(err, "add route %q", line))
+		}
+	}
+	for _, line := range stale(olds, lines) {
+		args := append([]string{"route", "del"}, strings.Fields(line)...)
+		if err := a.run(ctx, a.opts.IpBin, args...); err != nil {
+			// the route is gone with the link, or was removed by others
+			log.Warningf("remove stale route %q: %v", line, err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
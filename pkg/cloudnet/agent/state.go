@@ -0,0 +1,77 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// sState records what was applied by the agent so that things no longer in
+// config can be removed after restart of the agent
+type sState struct {
+	Digest   string   `json:"digest"`
+	Ifnames  []string `json:"ifnames"`
+	WgRoutes []string `json:"wg_routes"`
+	Routes   []string `json:"routes"`
+}
+
+func loadState(path string) (*sState, error) {
+	state := &sState{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, errors.Wrapf(err, "read %s", path)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal %s", path)
+	}
+	return state, nil
+}
+
+func (state *sState) save(path string) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "marshal state")
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrapf(err, "write %s", tmp)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Wrapf(err, "rename %s", tmp)
+	}
+	return nil
+}
+
+// stale returns elements in olds but not in news
+func stale(olds, news []string) []string {
+	m := make(map[string]struct{}, len(news))
+	for _, s := range news {
+		m[s] = struct{}{}
+	}
+	var r []string
+	for _, s := range olds {
+		if _, ok := m[s]; !ok {
+			r = append(r, s)
+		}
+	}
+	return r
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+)
+
+// parseWgDump parses output of "wg show all dump".  The first line of each
+// iface has 5 fields: ifname, private key, public key, listen port, fwmark.
+// Lines of peers have 9 fields: ifname, public key, preshared key, endpoint,
+// allowed ips, latest handshake, rx bytes, tx bytes, persistent keepalive
+func parseWgDump(out string) map[string][]api.RouterAgentIfacePeerStatus {
+	r := map[string][]api.RouterAgentIfacePeerStatus{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) != 9 {
+			continue
+		}
+		peer := api.RouterAgentIfacePeerStatus{
+			PublicKey: fields[1],
+			LatencyMs: -1,
+		}
+		if fields[3] != "(none)" {
+			peer.Endpoint = fields[3]
+		}
+		if sec, err := strconv.ParseInt(fields[5], 10, 64); err == nil && sec > 0 {
+			peer.LatestHandshake = time.Unix(sec, 0)
+		}
+		peer.RxBytes, _ = strconv.ParseInt(fields[6], 10, 64)
+		peer.TxBytes, _ = strconv.ParseInt(fields[7], 10, 64)
+		r[fields[0]] = append(r[fields[0]], peer)
+	}
+	return r
+}
+
+// matches both iputils "rtt min/avg/max/mdev = 0.1/0.2/0.3/0.0 ms" and
+// busybox "round-trip min/avg/max = 0.1/0.2/0.3 ms"
+var regexpPingRtt = regexp.MustCompile(`min/avg/max\S* = [0-9.]+/([0-9.]+)/`)
+
+// parsePingAvgRtt returns average round trip time in milliseconds from ping
+// output
+func parsePingAvgRtt(out string) (float64, bool) {
+	m := regexpPingRtt.FindStringSubmatch(out)
+	if m == nil {
+		return 0, false
+	}
+	rtt, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return rtt, true
+}
+
+func (a *Agent) pingAvgRtt(ctx context.Context, endpoint string) float64 {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return -1
+	}
+	out, err := a.command(ctx, "", a.opts.PingBin, "-n", "-q",
+		"-c", fmt.Sprintf("%d", a.opts.PingCount),
+		"-W", fmt.Sprintf("%d", a.opts.PingTimeoutSeconds),
+		host,
+	)
+	if err != nil {
+		// ping exits non-zero when there is no reply at all
+		log.Debugf("ping %s: %v", host, err)
+	}
+	rtt, ok := parsePingAvgRtt(out)
+	if !ok {
+		return -1
+	}
+	return rtt
+}
+
+// collectStatus returns status of peers of wireguard ifaces in cfg
+func (a *Agent) collectStatus(ctx context.Context, cfg *api.RouterAgentConfig) (*api.RouterAgentReportStatusInput, error) {
+	out, err := a.command(ctx, "", a.opts.WgBin, "show", "all", "dump")
+	if err != nil {
+		return nil, err
+	}
+	dump := parseWgDump(out)
+
+	input := &api.RouterAgentReportStatusInput{
+		Digest: a.state.Digest,
+	}
+	wg := &sync.WaitGroup{}
+	for i := range cfg.Ifaces {
+		iface := &cfg.Ifaces[i]
+		peers, ok := dump[iface.Ifname]
+		if !ok {
+			continue
+		}
+		endpoints := map[string]string{}
+		for j := range iface.Peers {
+			endpoints[iface.Peers[j].PublicKey] = iface.Peers[j].Endpoint
+		}
+		for j := range peers {
+			peer := &peers[j]
+			endpoint := peer.Endpoint
+			if endpoint == "" {
+				endpoint = endpoints[peer.PublicKey]
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				peer.LatencyMs = a.pingAvgRtt(ctx, endpoint)
+			}()
+		}
+		input.Ifaces = append(input.Ifaces, api.RouterAgentIfaceStatus{
+			Ifname: iface.Ifname,
+			Peers:  peers,
+		})
+	}
+	wg.Wait()
+	return input, nil
+}
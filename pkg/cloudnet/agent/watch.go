@@ -0,0 +1,77 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/mcclient/informer"
+)
+
+// sWatchKind is a cloudnet resource the agent config is derived from
+type sWatchKind struct {
+	keyword       string
+	keywordPlural string
+}
+
+func (kind sWatchKind) KeyString() string {
+	return kind.keywordPlural
+}
+
+func (kind sWatchKind) GetKeyword() string {
+	return kind.keyword
+}
+
+var agentWatchKinds = []sWatchKind{
+	{"router", "routers"},
+	{"iface", "ifaces"},
+	{"ifacepeer", "ifacepeers"},
+	{"route", "routes"},
+	{"rule", "rules"},
+}
+
+// startWatch subscribes to changes of resources making up the agent config.
+// Each change triggers a sync, which applies the config only when its
+// digest changed.  The watch is retried in background until established
+func (a *Agent) startWatch(ctx context.Context) {
+	trigger := func() {
+		select {
+		case a.syncCh <- struct{}{}:
+		default:
+		}
+	}
+	handler := informer.EventHandlerFuncs{
+		AddFunc: func(obj *jsonutils.JSONDict) {
+			trigger()
+		},
+		UpdateFunc: func(oldObj, newObj *jsonutils.JSONDict) {
+			trigger()
+		},
+		DeleteFunc: func(obj *jsonutils.JSONDict) {
+			trigger()
+		},
+	}
+	informer.NewWatchManagerBySessionBg(a.session(ctx), func(watchMan *informer.SWatchManager) error {
+		for _, kind := range agentWatchKinds {
+			if err := watchMan.For(kind).AddEventHandler(ctx, handler); err != nil {
+				return errors.Wrapf(err, "watch resource %s", kind.keywordPlural)
+			}
+		}
+		return nil
+	})
+}
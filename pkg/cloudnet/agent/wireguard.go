@@ -0,0 +1,132 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+)
+
+// wgConf returns iface config in the format accepted by "wg setconf" and "wg
+// syncconf".  Note that it's not the wg-quick format
+func wgConf(iface *api.RouterAgentIface) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "[Interface]\n")
+	fmt.Fprintf(b, "PrivateKey = %s\n", iface.PrivateKey)
+	if iface.ListenPort > 0 {
+		fmt.Fprintf(b, "ListenPort = %d\n", iface.ListenPort)
+	}
+	for i := range iface.Peers {
+		peer := &iface.Peers[i]
+		fmt.Fprintf(b, "\n[Peer]\n")
+		fmt.Fprintf(b, "# %s\n", peer.Name)
+		fmt.Fprintf(b, "PublicKey = %s\n", peer.PublicKey)
+		fmt.Fprintf(b, "AllowedIPs = %s\n", peer.AllowedIPs)
+		if peer.Endpoint != "" {
+			fmt.Fprintf(b, "Endpoint = %s\n", peer.Endpoint)
+		}
+		if peer.PersistentKeepalive > 0 {
+			fmt.Fprintf(b, "PersistentKeepalive = %d\n", peer.PersistentKeepalive)
+		}
+	}
+	return b.String()
+}
+
+// wgRouteLines returns routes to allowed ips of peers, which wg-quick would
+// otherwise add for us
+func wgRouteLines(iface *api.RouterAgentIface) []string {
+	var lines []string
+	for i := range iface.Peers {
+		for _, allowedIP := range strings.Split(iface.Peers[i].AllowedIPs, ",") {
+			allowedIP = strings.TrimSpace(allowedIP)
+			if allowedIP == "" {
+				continue
+			}
+			lines = append(lines, allowedIP+" dev "+iface.Ifname)
+		}
+	}
+	return lines
+}
+
+func (a *Agent) linkExists(ctx context.Context, ifname string) bool {
+	return a.run(ctx, a.opts.IpBin, "link", "show", "dev", ifname) == nil
+}
+
+func (a *Agent) applyWgIface(ctx context.Context, iface *api.RouterAgentIface) error {
+	if !a.linkExists(ctx, iface.Ifname) {
+		if err := a.run(ctx, a.opts.IpBin, "link", "add", "dev", iface.Ifname, "type", "wireguard"); err != nil {
+			return err
+		}
+	}
+	confPath := filepath.Join(a.opts.wgConfDir, iface.Ifname+".conf")
+	if err := ioutil.WriteFile(confPath, []byte(wgConf(iface)), 0600); err != nil {
+		return errors.Wrapf(err, "write %s", confPath)
+	}
+	// syncconf only touches peers that differ, established sessions of
+	// unchanged peers are kept
+	if err := a.run(ctx, a.opts.WgBin, "syncconf", iface.Ifname, confPath); err != nil {
+		return err
+	}
+	if err := a.run(ctx, a.opts.IpBin, "link", "set", "dev", iface.Ifname, "up"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *Agent) removeWgIface(ctx context.Context, ifname string) error {
+	if a.linkExists(ctx, ifname) {
+		if err := a.run(ctx, a.opts.IpBin, "link", "del", "dev", ifname); err != nil {
+			return err
+		}
+	}
+	confPath := filepath.Join(a.opts.wgConfDir, ifname+".conf")
+	if err := os.Remove(confPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "remove %s", confPath)
+	}
+	return nil
+}
+
+// applyWireguard brings wireguard ifaces to the state described in cfg and
+// removes those it created before but are no longer in cfg.  It returns
+// names of ifaces in cfg
+func (a *Agent) applyWireguard(ctx context.Context, cfg *api.RouterAgentConfig) ([]string, error) {
+	var (
+		ifnames []string
+		errs    []error
+	)
+	for i := range cfg.Ifaces {
+		iface := &cfg.Ifaces[i]
+		ifnames = append(ifnames, iface.Ifname)
+		if err := a.applyWgIface(ctx, iface); err != nil {
+			errs = append(errs, errors.Wrapf(err, "apply wireguard iface %s", iface.Ifname))
+		}
+	}
+	for _, ifname := range stale(a.state.Ifnames, ifnames) {
+		log.Infof("removing stale wireguard iface %s", ifname)
+		if err := a.removeWgIface(ctx, ifname); err != nil {
+			errs = append(errs, errors.Wrapf(err, "remove wireguard iface %s", ifname))
+		}
+	}
+	return ifnames, errors.NewAggregate(errs)
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"yunion.io/x/log"
 	yerrors "yunion.io/x/pkg/util/errors"
@@ -41,6 +42,14 @@ type SIfacePeer struct {
 	AllowedIPs          string
 	Endpoint            string
 	PersistentKeepalive int
+
+	// Reported by cloudnet agent
+	ReportedEndpoint string
+	LatestHandshake  time.Time `nullable:"true"`
+	RxBytes          int64     `nullable:"false"`
+	TxBytes          int64     `nullable:"false"`
+	LatencyMs        float64   `nullable:"false"`
+	ReportedAt       time.Time `nullable:"true"`
 }
 
 type SIfacePeerManager struct {
@@ -17,6 +17,7 @@ package models
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -44,6 +45,12 @@ type SRouter struct {
 	RealizeWgIfaces bool `width:"16" charset:"ascii" nullable:"false" list:"user" create:"optional" update:"user"`
 	RealizeRoutes   bool `width:"16" charset:"ascii" nullable:"false" list:"user" create:"optional" update:"user"`
 	RealizeRules    bool `width:"16" charset:"ascii" nullable:"false" list:"user" create:"optional" update:"user"`
+
+	// AgentManaged routers run cloudnet agent which pulls config from
+	// api and applies it locally.  No ansible playbook will be run for them
+	AgentManaged    bool      `nullable:"false" list:"user" create:"optional" update:"user"`
+	AgentDigest     string    `length:"32" charset:"ascii" nullable:"false" list:"user"`
+	AgentReportedAt time.Time `nullable:"true" list:"user"`
 }
 
 type SRouterManager struct {
@@ -84,6 +91,7 @@ func (man *SRouterManager) ValidateCreateData(ctx context.Context, userCred mccl
 		validators.NewBoolValidator("realize_wg_ifaces").Default(true),
 		validators.NewBoolValidator("realize_routes").Default(true),
 		validators.NewBoolValidator("realize_rules").Default(true),
+		validators.NewBoolValidator("agent_managed").Default(false),
 	}
 	for _, v := range vs {
 		if err := v.Validate(data); err != nil {
@@ -130,6 +138,7 @@ func (router *SRouter) ValidateUpdateData(ctx context.Context, userCred mcclient
 		validators.NewBoolValidator("realize_wg_ifaces"),
 		validators.NewBoolValidator("realize_routes"),
 		validators.NewBoolValidator("realize_rules"),
+		validators.NewBoolValidator("agent_managed"),
 	}
 	for _, v := range vs {
 		v.Optional(true)
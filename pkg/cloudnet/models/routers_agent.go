@@ -0,0 +1,189 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// agentConfig returns what cloudnet agent needs to realize the router.  It
+// carries the same content as the ansible inventory and playbooks
+func (router *SRouter) agentConfig() (*api.RouterAgentConfig, error) {
+	cfg := &api.RouterAgentConfig{
+		RouterId:        router.Id,
+		RealizeWgIfaces: router.RealizeWgIfaces,
+		RealizeRoutes:   router.RealizeRoutes,
+		RealizeRules:    router.RealizeRules,
+	}
+	if router.RealizeWgIfaces {
+		ifaces, err := IfaceManager.getByRouter(router)
+		if err != nil {
+			return nil, errors.Wrap(err, "get ifaces")
+		}
+		for i := range ifaces {
+			iface := &ifaces[i]
+			if iface.PrivateKey == "" {
+				continue
+			}
+			ifacePeers, err := IfacePeerManager.getByIface(iface)
+			if err != nil {
+				return nil, errors.Wrapf(err, "get peers of iface %s", iface.Ifname)
+			}
+			agentIface := api.RouterAgentIface{
+				Ifname:     iface.Ifname,
+				PrivateKey: iface.PrivateKey,
+				ListenPort: iface.ListenPort,
+			}
+			for j := range ifacePeers {
+				ifacePeer := &ifacePeers[j]
+				if ifacePeer.PublicKey == "" {
+					continue
+				}
+				agentIface.Peers = append(agentIface.Peers, api.RouterAgentIfacePeer{
+					Name:                ifacePeer.Name,
+					PublicKey:           ifacePeer.PublicKey,
+					AllowedIPs:          ifacePeer.AllowedIPs,
+					Endpoint:            ifacePeer.Endpoint,
+					PersistentKeepalive: ifacePeer.PersistentKeepalive,
+				})
+			}
+			if len(agentIface.Peers) == 0 {
+				continue
+			}
+			sort.Slice(agentIface.Peers, func(i, j int) bool {
+				return agentIface.Peers[i].PublicKey < agentIface.Peers[j].PublicKey
+			})
+			cfg.Ifaces = append(cfg.Ifaces, agentIface)
+		}
+		sort.Slice(cfg.Ifaces, func(i, j int) bool {
+			return cfg.Ifaces[i].Ifname < cfg.Ifaces[j].Ifname
+		})
+	}
+	if router.RealizeRoutes {
+		routes, err := RouteManager.routeLinesRouter(router)
+		if err != nil {
+			return nil, errors.Wrap(err, "get routes")
+		}
+		for _, lines := range routes {
+			sort.Strings(lines)
+		}
+		cfg.Routes = routes
+	}
+	if router.RealizeRules {
+		d, err := RuleManager.firewalldDirectByRouter(router)
+		if err != nil {
+			return nil, errors.Wrap(err, "get rules")
+		}
+		for _, r := range d.Rules {
+			cfg.Rules = append(cfg.Rules, api.RouterAgentRule{
+				Priority: r.Priority,
+				Table:    r.Table,
+				Chain:    r.Chain,
+				Body:     r.Body,
+			})
+		}
+		sort.SliceStable(cfg.Rules, func(i, j int) bool {
+			ri, rj := &cfg.Rules[i], &cfg.Rules[j]
+			if ri.Priority != rj.Priority {
+				return ri.Priority < rj.Priority
+			}
+			return ri.Body < rj.Body
+		})
+	}
+
+	// encoding/json sorts map keys, the digest is thus stable
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal agent config")
+	}
+	cfg.Digest = fmt.Sprintf("%x", md5.Sum(data))
+	return cfg, nil
+}
+
+func (router *SRouter) AllowGetDetailsAgentConfig(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowGetSpec(userCred, router, "agent-config")
+}
+
+func (router *SRouter) GetDetailsAgentConfig(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.RouterAgentConfig, error) {
+	cfg, err := router.agentConfig()
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("make agent config: %v", err)
+	}
+	return cfg, nil
+}
+
+func (router *SRouter) AllowPerformReportStatus(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, router, "report-status")
+}
+
+// PerformReportStatus records what cloudnet agent observed on the router.
+// Peers unknown to us are ignored as they may have just been removed
+func (router *SRouter) PerformReportStatus(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.RouterAgentReportStatusInput) (jsonutils.JSONObject, error) {
+	now := time.Now()
+	if _, err := db.Update(router, func() error {
+		router.AgentDigest = input.Digest
+		router.AgentReportedAt = now
+		return nil
+	}); err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+
+	var errs []error
+	for i := range input.Ifaces {
+		ifaceStatus := &input.Ifaces[i]
+		iface, err := IfaceManager.getByRouterIfname(router, ifaceStatus.Ifname)
+		if err != nil {
+			log.Warningf("router %s(%s) report status: iface %s: %v", router.Name, router.Id, ifaceStatus.Ifname, err)
+			continue
+		}
+		for j := range ifaceStatus.Peers {
+			peerStatus := &ifaceStatus.Peers[j]
+			ifacePeer, err := IfacePeerManager.getByIfacePublicKey(iface, peerStatus.PublicKey)
+			if err != nil {
+				log.Warningf("router %s(%s) report status: iface %s peer %s: %v", router.Name, router.Id, iface.Ifname, peerStatus.PublicKey, err)
+				continue
+			}
+			if _, err := db.Update(ifacePeer, func() error {
+				ifacePeer.ReportedEndpoint = peerStatus.Endpoint
+				ifacePeer.LatestHandshake = peerStatus.LatestHandshake
+				ifacePeer.RxBytes = peerStatus.RxBytes
+				ifacePeer.TxBytes = peerStatus.TxBytes
+				ifacePeer.LatencyMs = peerStatus.LatencyMs
+				ifacePeer.ReportedAt = now
+				return nil
+			}); err != nil {
+				errs = append(errs, errors.Wrapf(err, "update peer %s of iface %s", ifacePeer.Name, iface.Ifname))
+			}
+		}
+	}
+	if err := errors.NewAggregate(errs); err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return nil, nil
+}
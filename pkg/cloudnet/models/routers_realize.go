@@ -32,6 +32,11 @@ import (
 )
 
 func (router *SRouter) realize(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if router.AgentManaged {
+		// cloudnet agent on the router will notice the change of
+		// config digest and reconcile by itself
+		return nil
+	}
 	plays := []*ansiblev2.Play{
 		router.playEssential(),
 	}
@@ -53,6 +53,8 @@ type SImage struct {
 	PublicScope string
 	ExternalId  string
 
+	SignatureStatus string
+
 	// SubImages record the subImages of the guest image.
 	// For normal image, it's nil.
 	SubImages []SSubImage
@@ -324,12 +324,15 @@ func (self *SBaremetalGuestDriver) RequestGuestCreateInsertIso(ctx context.Conte
 }
 
 func (self *SBaremetalGuestDriver) RequestStartOnHost(ctx context.Context, guest *models.SGuest, host *models.SHost, userCred mcclient.TokenCredential, task taskman.ITask) error {
-	desc := guest.GetJsonDescAtBaremetal(ctx, host)
+	desc, err := guest.GetJsonDescAtBaremetal(ctx, host)
+	if err != nil {
+		return err
+	}
 	config := jsonutils.NewDict()
 	config.Set("desc", jsonutils.Marshal(desc))
 	headers := task.GetTaskRequestHeader()
 	url := fmt.Sprintf("/baremetals/%s/servers/%s/start", host.Id, guest.Id)
-	_, err := host.BaremetalSyncRequest(ctx, "POST", url, headers, config)
+	_, err = host.BaremetalSyncRequest(ctx, "POST", url, headers, config)
 	return err
 }
 
@@ -429,7 +432,10 @@ func (self *SBaremetalGuestDriver) ValidateCreateDataOnHost(ctx context.Context,
 }
 
 func (self *SBaremetalGuestDriver) GetJsonDescAtHost(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost, params *jsonutils.JSONDict) (jsonutils.JSONObject, error) {
-	desc := guest.GetJsonDescAtBaremetal(ctx, host)
+	desc, err := guest.GetJsonDescAtBaremetal(ctx, host)
+	if err != nil {
+		return nil, err
+	}
 	return jsonutils.Marshal(desc), nil
 }
 
@@ -157,7 +157,10 @@ func (self *SContainerDriver) RequestUndeployGuestOnHost(ctx context.Context, gu
 }
 
 func (self *SContainerDriver) GetJsonDescAtHost(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost, params *jsonutils.JSONDict) (jsonutils.JSONObject, error) {
-	desc := guest.GetJsonDescAtHypervisor(ctx, host)
+	desc, err := guest.GetJsonDescAtHypervisor(ctx, host)
+	if err != nil {
+		return nil, err
+	}
 	return jsonutils.Marshal(desc), nil
 }
 
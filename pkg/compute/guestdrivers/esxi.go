@@ -282,7 +282,10 @@ type SEsxiInstanceSnapshotInfo struct {
 }
 
 func (self *SESXiGuestDriver) GetJsonDescAtHost(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost, params *jsonutils.JSONDict) (jsonutils.JSONObject, error) {
-	desc := guest.GetJsonDescAtHypervisor(ctx, host)
+	desc, err := guest.GetJsonDescAtHypervisor(ctx, host)
+	if err != nil {
+		return nil, err
+	}
 	// add image_info
 	if len(desc.Disks) == 0 {
 		return jsonutils.Marshal(desc), nil
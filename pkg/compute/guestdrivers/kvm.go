@@ -255,7 +255,10 @@ func (self *SKVMGuestDriver) RequestUndeployGuestOnHost(ctx context.Context, gue
 }
 
 func (self *SKVMGuestDriver) GetJsonDescAtHost(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost, params *jsonutils.JSONDict) (jsonutils.JSONObject, error) {
-	desc := guest.GetJsonDescAtHypervisor(ctx, host)
+	desc, err := guest.GetJsonDescAtHypervisor(ctx, host)
+	if err != nil {
+		return nil, err
+	}
 	return jsonutils.Marshal(desc), nil
 }
 
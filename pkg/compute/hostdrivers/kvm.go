@@ -339,6 +339,13 @@ func (self *SKVMHostDriver) RequestResizeDiskOnHost(ctx context.Context, host *m
 	if guest != nil {
 		content.Add(jsonutils.NewString(guest.Id), "server_id")
 	}
+	if disk.IsEncrypted() {
+		encInfo, err := disk.GetEncryptInfo(ctx)
+		if err != nil {
+			return errors.Wrap(err, "GetEncryptInfo")
+		}
+		content.Add(jsonutils.Marshal(encInfo), "encrypt_info")
+	}
 	body.Add(content, "disk")
 	_, err := host.Request(ctx, task.GetUserCred(), "POST", url, header, body)
 	return err
@@ -346,7 +353,15 @@ func (self *SKVMHostDriver) RequestResizeDiskOnHost(ctx context.Context, host *m
 
 func (self *SKVMHostDriver) RequestPrepareSaveDiskOnHost(ctx context.Context, host *models.SHost, disk *models.SDisk, imageId string, task taskman.ITask) error {
 	body := jsonutils.NewDict()
-	body.Add(jsonutils.Marshal(map[string]string{"image_id": imageId}), "disk")
+	content := jsonutils.Marshal(map[string]string{"image_id": imageId}).(*jsonutils.JSONDict)
+	if disk.IsEncrypted() {
+		encInfo, err := disk.GetEncryptInfo(ctx)
+		if err != nil {
+			return errors.Wrap(err, "GetEncryptInfo")
+		}
+		content.Add(jsonutils.Marshal(encInfo), "encrypt_info")
+	}
+	body.Add(content, "disk")
 	url := fmt.Sprintf("/disks/%s/save-prepare/%s", disk.StorageId, disk.Id)
 
 	header := task.GetTaskRequestHeader()
@@ -0,0 +1,481 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// +onecloud:swagger-gen-model-singular=capacity_reservation
+// +onecloud:swagger-gen-model-plural=capacity_reservations
+type SCapacityReservationManager struct {
+	db.SVirtualResourceBaseManager
+	SZoneResourceBaseManager
+}
+
+var CapacityReservationManager *SCapacityReservationManager
+
+func init() {
+	CapacityReservationManager = &SCapacityReservationManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SCapacityReservation{},
+			"capacity_reservations_tbl",
+			"capacity_reservation",
+			"capacity_reservations",
+		),
+	}
+	CapacityReservationManager.SetVirtualObject(CapacityReservationManager)
+}
+
+// SCapacityReservation reserves host resources in a zone or with a schedtag
+// for a project during a time window. The scheduler keeps other projects
+// from taking the reserved capacity, servers of the owner project created
+// in the window consume it
+type SCapacityReservation struct {
+	db.SVirtualResourceBase
+	SZoneResourceBase
+
+	// 调度标签Id
+	SchedtagId string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+
+	// 预留CPU核数
+	CpuCount int `nullable:"false" default:"0" list:"user" create:"optional" update:"admin"`
+	// 预留内存大小(MB)
+	MemorySize int `nullable:"false" default:"0" list:"user" create:"optional" update:"admin"`
+	// 预留本地存储大小(MB)
+	StorageSize int `nullable:"false" default:"0" list:"user" create:"optional" update:"admin"`
+
+	// 预留直通设备型号
+	IsolatedDeviceModel string `width:"32" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// 预留直通设备数量
+	IsolatedDeviceCount int `nullable:"false" default:"0" list:"user" create:"optional" update:"admin"`
+
+	// 预留开始时间
+	StartTime time.Time `nullable:"false" list:"user" create:"optional" update:"admin"`
+	// 预留结束时间
+	EndTime time.Time `nullable:"false" list:"user" create:"required" update:"admin"`
+}
+
+func (manager *SCapacityReservationManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowCreate(userCred, manager)
+}
+
+func (self *SCapacityReservation) AllowUpdateItem(ctx context.Context, userCred mcclient.TokenCredential) bool {
+	return db.IsAdminAllowUpdate(userCred, self)
+}
+
+func (self *SCapacityReservation) AllowDeleteItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowDelete(userCred, self)
+}
+
+// 容量预留列表
+func (manager *SCapacityReservationManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.CapacityReservationListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SZoneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.ZonalFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SZoneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.SchedtagId) > 0 {
+		schedtag, err := SchedtagManager.FetchByIdOrName(userCred, query.SchedtagId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(SchedtagManager.Keyword(), query.SchedtagId)
+		}
+		q = q.Equals("schedtag_id", schedtag.GetId())
+	}
+	if query.Active != nil {
+		now := time.Now().UTC()
+		if *query.Active {
+			q = q.LE("start_time", now).GT("end_time", now)
+		} else {
+			q = q.Filter(sqlchemy.OR(sqlchemy.GT(q.Field("start_time"), now), sqlchemy.LE(q.Field("end_time"), now)))
+		}
+	}
+	return q, nil
+}
+
+func (manager *SCapacityReservationManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.CapacityReservationListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SVirtualResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SZoneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.ZonalFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SZoneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SCapacityReservationManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SVirtualResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SZoneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SCapacityReservationManager) ListItemExportKeys(ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	keys stringutils2.SSortedStrings,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SVirtualResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ListItemExportKeys")
+	}
+	if keys.ContainsAny(manager.SZoneResourceBaseManager.GetExportKeys()...) {
+		q, err = manager.SZoneResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+		if err != nil {
+			return nil, errors.Wrap(err, "SZoneResourceBaseManager.ListItemExportKeys")
+		}
+	}
+	return q, nil
+}
+
+func validateCapacityReservationAmount(name string, val int) error {
+	if val < 0 {
+		return httperrors.NewOutOfRangeError("%s must not be negative", name)
+	}
+	return nil
+}
+
+func validateCapacityReservationWindow(start, end time.Time) error {
+	if !end.After(start) {
+		return httperrors.NewInputParameterError("end_time must be later than start_time")
+	}
+	if !end.After(time.Now()) {
+		return httperrors.NewInputParameterError("end_time must be in the future")
+	}
+	return nil
+}
+
+func (manager *SCapacityReservationManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.CapacityReservationCreateInput,
+) (api.CapacityReservationCreateInput, error) {
+	var err error
+	input.VirtualResourceCreateInput, err = manager.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.VirtualResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+
+	if input.ZoneId == "" && input.SchedtagId == "" {
+		return input, httperrors.NewMissingParameterError("zone_id or schedtag_id")
+	}
+	if input.ZoneId != "" {
+		_, input.ZoneResourceInput, err = ValidateZoneResourceInput(userCred, input.ZoneResourceInput)
+		if err != nil {
+			return input, errors.Wrap(err, "ValidateZoneResourceInput")
+		}
+	}
+	if input.SchedtagId != "" {
+		_schedtag, err := validators.ValidateModel(userCred, SchedtagManager, &input.SchedtagId)
+		if err != nil {
+			return input, err
+		}
+		schedtag := _schedtag.(*SSchedtag)
+		if schedtag.ResourceType != HostManager.KeywordPlural() {
+			return input, httperrors.NewInputParameterError("schedtag %s is not for hosts", schedtag.Name)
+		}
+	}
+
+	for name, val := range map[string]int{
+		"cpu_count":             input.CpuCount,
+		"memory_size":           input.MemorySize,
+		"storage_size":          input.StorageSize,
+		"isolated_device_count": input.IsolatedDeviceCount,
+	} {
+		if err := validateCapacityReservationAmount(name, val); err != nil {
+			return input, err
+		}
+	}
+	if input.CpuCount == 0 && input.MemorySize == 0 && input.StorageSize == 0 && input.IsolatedDeviceCount == 0 {
+		return input, httperrors.NewMissingParameterError("cpu_count, memory_size, storage_size or isolated_device_count")
+	}
+	if input.IsolatedDeviceCount > 0 {
+		if input.IsolatedDeviceModel == "" {
+			return input, httperrors.NewMissingParameterError("isolated_device_model")
+		}
+		cnt, err := IsolatedDeviceManager.Query().Equals("model", input.IsolatedDeviceModel).CountWithError()
+		if err != nil {
+			return input, httperrors.NewGeneralError(err)
+		}
+		if cnt == 0 {
+			return input, httperrors.NewResourceNotFoundError2(IsolatedDeviceManager.Keyword(), input.IsolatedDeviceModel)
+		}
+	}
+
+	if input.StartTime.IsZero() {
+		input.StartTime = time.Now().UTC()
+	}
+	if input.EndTime.IsZero() {
+		return input, httperrors.NewMissingParameterError("end_time")
+	}
+	if err := validateCapacityReservationWindow(input.StartTime, input.EndTime); err != nil {
+		return input, err
+	}
+	input.Status = api.CAPACITY_RESERVATION_STATUS_PENDING
+	if !input.StartTime.After(time.Now()) {
+		input.Status = api.CAPACITY_RESERVATION_STATUS_ACTIVE
+	}
+	return input, nil
+}
+
+func (self *SCapacityReservation) ValidateUpdateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.CapacityReservationUpdateInput,
+) (api.CapacityReservationUpdateInput, error) {
+	var err error
+	for name, val := range map[string]*int{
+		"cpu_count":             input.CpuCount,
+		"memory_size":           input.MemorySize,
+		"storage_size":          input.StorageSize,
+		"isolated_device_count": input.IsolatedDeviceCount,
+	} {
+		if val != nil {
+			if err := validateCapacityReservationAmount(name, *val); err != nil {
+				return input, err
+			}
+		}
+	}
+	if input.IsolatedDeviceCount != nil && *input.IsolatedDeviceCount > 0 && self.IsolatedDeviceModel == "" {
+		return input, httperrors.NewInputParameterError("isolated_device_model of reservation is not set")
+	}
+	if input.StartTime != nil || input.EndTime != nil {
+		start, end := self.StartTime, self.EndTime
+		if input.StartTime != nil {
+			start = *input.StartTime
+		}
+		if input.EndTime != nil {
+			end = *input.EndTime
+		}
+		if err := validateCapacityReservationWindow(start, end); err != nil {
+			return input, err
+		}
+	}
+	input.VirtualResourceBaseUpdateInput, err = self.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SVirtualResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
+func (self *SCapacityReservation) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SVirtualResourceBase.PostUpdate(ctx, userCred, query, data)
+	self.syncStatus(userCred, time.Now())
+}
+
+func (manager *SCapacityReservationManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.CapacityReservationDetails {
+	rows := make([]api.CapacityReservationDetails, len(objs))
+	virtRows := manager.SVirtualResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	zoneRows := manager.SZoneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+
+	schedtagIds := make([]string, 0)
+	for i := range rows {
+		rows[i] = api.CapacityReservationDetails{
+			VirtualResourceDetails: virtRows[i],
+			ZoneResourceInfo:       zoneRows[i],
+		}
+		reservation := objs[i].(*SCapacityReservation)
+		if reservation.SchedtagId != "" {
+			schedtagIds = append(schedtagIds, reservation.SchedtagId)
+		}
+		usage, err := reservation.GetUsage()
+		if err != nil {
+			log.Errorf("get usage of capacity reservation %s: %v", reservation.Name, err)
+			continue
+		}
+		rows[i].CapacityReservationUsage = *usage
+	}
+	schedtagNames, err := db.FetchIdNameMap2(SchedtagManager, schedtagIds)
+	if err != nil {
+		log.Errorf("fetch schedtag names: %v", err)
+	}
+	for i := range rows {
+		rows[i].Schedtag = schedtagNames[objs[i].(*SCapacityReservation).SchedtagId]
+	}
+	return rows
+}
+
+// IsActive tells whether the reservation takes effect at time t
+func (self *SCapacityReservation) IsActive(t time.Time) bool {
+	return !self.StartTime.After(t) && self.EndTime.After(t)
+}
+
+// GetHostIds returns ids of enabled hosts in scope of the reservation
+func (self *SCapacityReservation) GetHostIds() ([]string, error) {
+	q := HostManager.Query("id").IsTrue("enabled")
+	if self.ZoneId != "" {
+		q = q.Equals("zone_id", self.ZoneId)
+	}
+	if self.SchedtagId != "" {
+		sq := HostschedtagManager.Query("host_id").Equals("schedtag_id", self.SchedtagId).SubQuery()
+		q = q.In("id", sq)
+	}
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "query hosts")
+	}
+	defer rows.Close()
+	hostIds := []string{}
+	for rows.Next() {
+		var hostId string
+		if err := rows.Scan(&hostId); err != nil {
+			return nil, errors.Wrap(err, "scan host id")
+		}
+		hostIds = append(hostIds, hostId)
+	}
+	return hostIds, nil
+}
+
+// GetUsage sums up resources of live servers of the owner project on hosts in
+// scope of the reservation, whenever they were created
+func (self *SCapacityReservation) GetUsage() (*api.CapacityReservationUsage, error) {
+	usage := &api.CapacityReservationUsage{}
+	hostIds, err := self.GetHostIds()
+	if err != nil {
+		return nil, err
+	}
+	if len(hostIds) == 0 {
+		return usage, nil
+	}
+	guests := []SGuest{}
+	q := GuestManager.Query().In("host_id", hostIds).Equals("tenant_id", self.ProjectId).IsFalse("pending_deleted")
+	if err := db.FetchModelObjects(GuestManager, q, &guests); err != nil {
+		return nil, errors.Wrap(err, "fetch guests")
+	}
+	if len(guests) == 0 {
+		return usage, nil
+	}
+	guestIds := make([]string, len(guests))
+	for i := range guests {
+		guestIds[i] = guests[i].Id
+		usage.UsedCpuCount += guests[i].VcpuCount
+		usage.UsedMemorySize += guests[i].VmemSize
+	}
+
+	disks := []SDisk{}
+	diskIds := GuestdiskManager.Query("disk_id").In("guest_id", guestIds).SubQuery()
+	localStorages := StorageManager.Query("id").In("storage_type", api.HOST_STORAGE_LOCAL_TYPES).SubQuery()
+	dq := DiskManager.Query().In("id", diskIds).In("storage_id", localStorages)
+	if err := db.FetchModelObjects(DiskManager, dq, &disks); err != nil {
+		return nil, errors.Wrap(err, "fetch disks")
+	}
+	for i := range disks {
+		usage.UsedStorageSize += disks[i].DiskSize
+	}
+
+	if self.IsolatedDeviceModel != "" {
+		cnt, err := IsolatedDeviceManager.Query().In("guest_id", guestIds).Equals("model", self.IsolatedDeviceModel).CountWithError()
+		if err != nil {
+			return nil, errors.Wrap(err, "count isolated devices")
+		}
+		usage.UsedIsolatedDeviceCount = cnt
+	}
+
+	for _, pair := range [][2]int{
+		{usage.UsedCpuCount, self.CpuCount},
+		{usage.UsedMemorySize, self.MemorySize},
+		{usage.UsedStorageSize, self.StorageSize},
+		{usage.UsedIsolatedDeviceCount, self.IsolatedDeviceCount},
+	} {
+		if pair[1] > 0 {
+			usage.Utilization = math.Max(usage.Utilization, float64(pair[0])*100/float64(pair[1]))
+		}
+	}
+	return usage, nil
+}
+
+// GetActiveReservations returns reservations taking effect now
+func (manager *SCapacityReservationManager) GetActiveReservations() ([]SCapacityReservation, error) {
+	now := time.Now().UTC()
+	q := manager.Query().IsFalse("pending_deleted").LE("start_time", now).GT("end_time", now)
+	reservations := []SCapacityReservation{}
+	if err := db.FetchModelObjects(manager, q, &reservations); err != nil {
+		return nil, errors.Wrap(err, "fetch capacity reservations")
+	}
+	return reservations, nil
+}
+
+func (self *SCapacityReservation) syncStatus(userCred mcclient.TokenCredential, now time.Time) {
+	status := api.CAPACITY_RESERVATION_STATUS_ACTIVE
+	if self.StartTime.After(now) {
+		status = api.CAPACITY_RESERVATION_STATUS_PENDING
+	} else if !self.EndTime.After(now) {
+		status = api.CAPACITY_RESERVATION_STATUS_EXPIRED
+	}
+	if status != self.Status {
+		self.SetStatus(userCred, status, "")
+	}
+}
+
+// SyncStatus moves reservations into and out of their time windows
+func (manager *SCapacityReservationManager) SyncStatus(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().IsFalse("pending_deleted").NotEquals("status", api.CAPACITY_RESERVATION_STATUS_EXPIRED)
+	reservations := []SCapacityReservation{}
+	if err := db.FetchModelObjects(manager, q, &reservations); err != nil {
+		log.Errorf("fetch capacity reservations: %v", err)
+		return
+	}
+	now := time.Now()
+	for i := range reservations {
+		reservations[i].syncStatus(userCred, now)
+	}
+}
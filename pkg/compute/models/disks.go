@@ -84,6 +84,7 @@ type SDisk struct {
 	SStorageResourceBase `width:"128" charset:"ascii" nullable:"true" list:"admin" create:"optional"`
 	db.SMultiArchResourceBase
 	db.SAutoDeleteResourceBase
+	SEncryptedResourceBase
 
 	// 磁盘存储类型
 	// example: qcow2
@@ -516,6 +517,10 @@ func (manager *SDiskManager) ValidateCreateData(ctx context.Context, userCred mc
 		quotaKey = diskCreateInput2ComputeQuotaKeys(input, ownerId)
 	}
 
+	if len(diskConfig.EncryptKeyId) > 0 && input.Hypervisor != api.HYPERVISOR_KVM {
+		return input, httperrors.NewNotSupportedError("disk encryption is not supported by %s", input.Hypervisor)
+	}
+
 	input.VirtualResourceCreateInput, err = manager.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.VirtualResourceCreateInput)
 	if err != nil {
 		return input, err
@@ -717,6 +722,13 @@ func (self *SDisk) StartAllocate(ctx context.Context, host *SHost, storage *SSto
 			}
 		}
 	}
+	if self.IsEncrypted() {
+		encInfo, err := self.GetEncryptInfo(ctx)
+		if err != nil {
+			return errors.Wrap(err, "GetEncryptInfo")
+		}
+		content.Add(jsonutils.Marshal(encInfo), "encrypt_info")
+	}
 	if rebuild {
 		return host.GetHostDriver().RequestRebuildDiskOnStorage(ctx, host, storage, self, task, content)
 	} else {
@@ -1704,6 +1716,11 @@ func parseDiskInfo(ctx context.Context, userCred mcclient.TokenCredential, info
 	if len(info.ImageId) == 0 && info.SizeMb == 0 {
 		return nil, httperrors.NewInputParameterError("Diskinfo index %d: both imageID and size are absent", info.Index)
 	}
+	if len(info.EncryptKeyId) > 0 && userCred != nil {
+		if err := validateEncryptKey(ctx, userCred, info.EncryptKeyId); err != nil {
+			return nil, err
+		}
+	}
 	return info, nil
 }
 
@@ -1750,6 +1767,9 @@ func fillDiskConfigByImage(ctx context.Context, userCred mcclient.TokenCredentia
 		if image.Status != cloudprovider.IMAGE_STATUS_ACTIVE {
 			return httperrors.NewInvalidStatusError("Image status is not active")
 		}
+		if err := checkImageSignature(ctx, userCred, image); err != nil {
+			return err
+		}
 		diskConfig.ImageId = image.Id
 		diskConfig.ImageProperties = image.Properties
 		diskConfig.ImageProperties[imageapi.IMAGE_DISK_FORMAT] = image.DiskFormat
@@ -1770,6 +1790,26 @@ func fillDiskConfigByImage(ctx context.Context, userCred mcclient.TokenCredentia
 	return nil
 }
 
+// checkImageSignature rejects glance images whose signature is not verified when
+// EnforceImageSignature is set, images of public clouds are not signed by glance
+func checkImageSignature(ctx context.Context, userCred mcclient.TokenCredential, image *cloudprovider.SImage) error {
+	if !options.Options.EnforceImageSignature || len(image.ExternalId) > 0 {
+		return nil
+	}
+	if image.SignatureStatus != imageapi.IMAGE_SIGNATURE_STATUS_VERIFIED {
+		// the cached image info may be out of date
+		img, err := CachedimageManager.getImageInfo(ctx, userCred, image.Id, true)
+		if err != nil {
+			return errors.Wrapf(err, "refresh image %s", image.Id)
+		}
+		image.SignatureStatus = img.SignatureStatus
+	}
+	if image.SignatureStatus != imageapi.IMAGE_SIGNATURE_STATUS_VERIFIED {
+		return httperrors.NewForbiddenError("signature of image %s is %s, deploying unverified image is not allowed", image.Name, image.SignatureStatus)
+	}
+	return nil
+}
+
 func parseIsoInfo(ctx context.Context, userCred mcclient.TokenCredential, imageId string) (*cloudprovider.SImage, error) {
 	image, err := CachedimageManager.getImageInfo(ctx, userCred, imageId, false)
 	if err != nil {
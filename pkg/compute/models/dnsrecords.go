@@ -17,12 +17,16 @@ package models
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/util/regutils"
+	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
 	"yunion.io/x/onecloud/pkg/apis"
@@ -56,6 +60,10 @@ func init() {
 
 const DNS_RECORDS_SEPARATOR = ","
 
+// health check results not refreshed in time are ignored, e.g. when cloudmon
+// stops probing
+const dnsRecordHealthExpire = 10 * time.Minute
+
 type SDnsRecord struct {
 	db.SAdminSharableVirtualResourceBase
 	db.SEnabledResourceBase `nullable:"false" default:"true" create:"optional" list:"user"`
@@ -64,6 +72,23 @@ type SDnsRecord struct {
 	// example: 60
 	Ttl int `nullable:"true" default:"1" create:"optional" list:"user" update:"user" json:"ttl"`
 
+	// A/AAAA记录的流量策略
+	// | traffic_policy | 说明                                           |
+	// |----------------|------------------------------------------------|
+	// | simple         | 返回全部记录值, 默认值                         |
+	// | weighted       | 按权重每次返回一个记录值                       |
+	// | client_subnet  | 按客户端子网返回对应的记录值                   |
+	// | failover       | 按记录值顺序返回第一个健康的记录值             |
+	TrafficPolicy string `width:"16" charset:"ascii" nullable:"false" default:"simple" create:"optional" list:"user" update:"user"`
+	// 流量策略参数
+	TrafficPolicyOptions *api.SDnsRecordPolicyOptions `nullable:"true" create:"optional" list:"user" update:"user"`
+	// 是否根据cloudmon的ping探测结果剔除不健康的记录值
+	HealthCheck bool `nullable:"false" default:"false" create:"optional" list:"user" update:"user"`
+	// 最近一次健康检查失败的记录值
+	UnhealthyRecords string `width:"1024" charset:"ascii" nullable:"true" list:"user"`
+	// 最近一次健康检查结果上报时间
+	HealthCheckedAt time.Time `nullable:"true" list:"user"`
+
 	//Enabled tristate.TriState `nullable:"false" default:"true" create:"optional" list:"user"`
 }
 
@@ -352,13 +377,59 @@ func (man *SDnsRecordManager) ValidateCreateData(
 
 	data.Update(jsonutils.Marshal(input))
 
-	_, err = man.validateModelData(ctx, data, true)
+	records, err := man.validateModelData(ctx, data, true)
 	if err != nil {
 		return nil, err
 	}
+	if err := man.validateTrafficPolicy(data, records, "", nil); err != nil {
+		return nil, err
+	}
 	return man.SAdminSharableVirtualResourceBaseManager.ValidateRecordsData(man, data)
 }
 
+// validateTrafficPolicy checks traffic policy and its options against A/AAAA
+// record values.  Records of other types can only be answered in full.
+// policy and opts are used when data does not set them
+func (man *SDnsRecordManager) validateTrafficPolicy(data *jsonutils.JSONDict, records []string, policy string, opts *api.SDnsRecordPolicyOptions) error {
+	if data.Contains("traffic_policy") {
+		policy, _ = data.GetString("traffic_policy")
+	}
+	if policy == "" {
+		policy = api.DNS_RECORD_POLICY_SIMPLE
+	}
+	if !api.DNS_RECORD_POLICIES.Has(policy) {
+		return httperrors.NewInputParameterError("invalid traffic_policy %q, want %s", policy, api.DNS_RECORD_POLICIES.List())
+	}
+	if policy != api.DNS_RECORD_POLICY_SIMPLE && man.getRecordsType(records) != "A" {
+		return httperrors.NewInputParameterError("traffic_policy %s is only for A/AAAA records", policy)
+	}
+	if data.Contains("traffic_policy_options") {
+		opts = &api.SDnsRecordPolicyOptions{}
+		if err := data.Unmarshal(opts, "traffic_policy_options"); err != nil {
+			return httperrors.NewInputParameterError("unmarshal traffic_policy_options: %v", err)
+		}
+	} else if opts == nil {
+		opts = &api.SDnsRecordPolicyOptions{}
+	}
+	if err := opts.Validate(policy, dnsRecordValues(records)); err != nil {
+		return err
+	}
+	data.Set("traffic_policy", jsonutils.NewString(policy))
+	data.Set("traffic_policy_options", jsonutils.Marshal(opts))
+	return nil
+}
+
+// dnsRecordValues strips types from A/AAAA records
+func dnsRecordValues(records []string) []string {
+	values := []string{}
+	for _, r := range records {
+		if strings.HasPrefix(r, "A:") || strings.HasPrefix(r, "AAAA:") {
+			values = append(values, r[strings.Index(r, ":")+1:])
+		}
+	}
+	return values
+}
+
 func (man *SDnsRecordManager) QueryDns(projectId, name string) *SDnsRecord {
 	q := man.Query().
 		Equals("name", name).
@@ -403,6 +474,111 @@ func (man *SDnsRecordManager) QueryDnsIps(projectId, name, kind string) []*DnsIp
 	return dnsIps
 }
 
+// healthyValues drops values failed the last health check.  All values are
+// returned if none of them is healthy, so that clients can still try them
+func (rec *SDnsRecord) healthyValues(values []string, now time.Time) []string {
+	if !rec.HealthCheck || rec.UnhealthyRecords == "" || now.Sub(rec.HealthCheckedAt) > dnsRecordHealthExpire {
+		return values
+	}
+	unhealthy := strings.Split(rec.UnhealthyRecords, DNS_RECORDS_SEPARATOR)
+	ret := []string{}
+	for _, v := range values {
+		if !utils.IsInStringArray(v, unhealthy) {
+			ret = append(ret, v)
+		}
+	}
+	if len(ret) == 0 {
+		return values
+	}
+	return ret
+}
+
+// SelectValues picks from A/AAAA record values the ones answering the client
+// according to health check results and traffic policy of the record
+func (rec *SDnsRecord) SelectValues(values []string, clientIp net.IP, now time.Time) []string {
+	values = rec.healthyValues(values, now)
+	if len(values) == 0 {
+		return values
+	}
+	opts := rec.TrafficPolicyOptions
+	if opts == nil {
+		opts = &api.SDnsRecordPolicyOptions{}
+	}
+	switch rec.TrafficPolicy {
+	case api.DNS_RECORD_POLICY_WEIGHTED:
+		return selectDnsValueByWeight(values, opts.Weights, rand.Intn)
+	case api.DNS_RECORD_POLICY_CLIENT_SUBNET:
+		return selectDnsValuesBySubnet(values, opts.Subnets, clientIp)
+	case api.DNS_RECORD_POLICY_FAILOVER:
+		return values[:1]
+	}
+	return values
+}
+
+// selectDnsValueByWeight picks one value with probability proportional to
+// its weight.  Values without weight set weigh 1
+func selectDnsValueByWeight(values []string, weights []api.SDnsRecordWeight, intn func(int) int) []string {
+	weightOf := map[string]int{}
+	for _, w := range weights {
+		weightOf[w.Value] = w.Weight
+	}
+	total := 0
+	for _, v := range values {
+		w, ok := weightOf[v]
+		if !ok {
+			w = 1
+			weightOf[v] = w
+		}
+		total += w
+	}
+	if total <= 0 {
+		return values
+	}
+	n := intn(total)
+	for _, v := range values {
+		n -= weightOf[v]
+		if n < 0 {
+			return []string{v}
+		}
+	}
+	return values[len(values)-1:]
+}
+
+// selectDnsValuesBySubnet answers with values of the subnet with the longest
+// prefix containing the client.  Clients not in any subnet, or whose subnet
+// values are all dropped get all values
+func selectDnsValuesBySubnet(values []string, subnets []api.SDnsRecordSubnet, clientIp net.IP) []string {
+	if clientIp == nil {
+		return values
+	}
+	var (
+		match     *api.SDnsRecordSubnet
+		matchOnes = -1
+	)
+	for i := range subnets {
+		_, ipNet, err := net.ParseCIDR(subnets[i].Cidr)
+		if err != nil || !ipNet.Contains(clientIp) {
+			continue
+		}
+		if ones, _ := ipNet.Mask.Size(); ones > matchOnes {
+			match, matchOnes = &subnets[i], ones
+		}
+	}
+	if match == nil {
+		return values
+	}
+	ret := []string{}
+	for _, v := range match.Values {
+		if utils.IsInStringArray(v, values) {
+			ret = append(ret, v)
+		}
+	}
+	if len(ret) == 0 {
+		return values
+	}
+	return ret
+}
+
 func (rec *SDnsRecord) IsCNAME() bool {
 	return strings.HasPrefix(rec.Records, "CNAME:")
 }
@@ -428,6 +604,7 @@ func (rec *SDnsRecord) GetInfo() []string {
 }
 
 func (rec *SDnsRecord) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	policySet := data.Contains("traffic_policy") || data.Contains("traffic_policy_options")
 	data.UpdateDefault(jsonutils.Marshal(rec))
 	records, err := DnsRecordManager.validateModelData(ctx, data, false)
 	if err != nil {
@@ -436,6 +613,9 @@ func (rec *SDnsRecord) ValidateUpdateData(ctx context.Context, userCred mcclient
 	if len(records) > 0 {
 		data.Set("records", jsonutils.NewString(strings.Join(records, DNS_RECORDS_SEPARATOR)))
 	}
+	if err := rec.validateUpdateTrafficPolicy(data, records, policySet); err != nil {
+		return nil, err
+	}
 	input := apis.AdminSharableVirtualResourceBaseUpdateInput{}
 	err = data.Unmarshal(&input)
 	if err != nil {
@@ -449,6 +629,20 @@ func (rec *SDnsRecord) ValidateUpdateData(ctx context.Context, userCred mcclient
 	return data, nil
 }
 
+// validateUpdateTrafficPolicy validates traffic policy of the record when
+// the update sets it or new records, otherwise leaves it untouched
+func (rec *SDnsRecord) validateUpdateTrafficPolicy(data *jsonutils.JSONDict, records []string, policySet bool) error {
+	if !policySet && len(records) == 0 {
+		data.Remove("traffic_policy")
+		data.Remove("traffic_policy_options")
+		return nil
+	}
+	if len(records) == 0 {
+		records = rec.GetInfo()
+	}
+	return DnsRecordManager.validateTrafficPolicy(data, records, rec.TrafficPolicy, rec.TrafficPolicyOptions)
+}
+
 func (rec *SDnsRecord) AddInfo(ctx context.Context, userCred mcclient.TokenCredential, data jsonutils.JSONObject) error {
 	return rec.SAdminSharableVirtualResourceBase.AddInfo(ctx, userCred, DnsRecordManager, rec, data)
 }
@@ -505,6 +699,31 @@ func (rec *SDnsRecord) PerformDisable(ctx context.Context, userCred mcclient.Tok
 	return nil, nil
 }
 
+func (rec *SDnsRecord) AllowPerformReportHealth(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, rec, "report-health")
+}
+
+// 上报记录值的健康检查结果
+func (rec *SDnsRecord) PerformReportHealth(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DnsRecordReportHealthInput) (jsonutils.JSONObject, error) {
+	if !rec.HealthCheck {
+		return nil, httperrors.NewInvalidStatusError("health check of dnsrecord %s is not enabled", rec.Name)
+	}
+	unhealthy := strings.Join(input.Unhealthy, DNS_RECORDS_SEPARATOR)
+	changed := unhealthy != rec.UnhealthyRecords
+	diff, err := db.Update(rec, func() error {
+		rec.UnhealthyRecords = unhealthy
+		rec.HealthCheckedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "db.Update")
+	}
+	if changed {
+		db.OpsLog.LogEvent(rec, db.ACT_UPDATE, diff, userCred)
+	}
+	return nil, nil
+}
+
 // 域名记录列表
 func (manager *SDnsRecordManager) ListItemFilter(
 	ctx context.Context,
@@ -517,6 +736,16 @@ func (manager *SDnsRecordManager) ListItemFilter(
 	if err != nil {
 		return nil, errors.Wrap(err, "SAdminSharableVirtualResourceBaseManager.ListItemFilter")
 	}
+	if query.HealthCheck != nil {
+		if *query.HealthCheck {
+			q = q.IsTrue("health_check")
+		} else {
+			q = q.IsFalse("health_check")
+		}
+	}
+	if len(query.TrafficPolicy) > 0 {
+		q = q.In("traffic_policy", query.TrafficPolicy)
+	}
 	return q, nil
 }
 
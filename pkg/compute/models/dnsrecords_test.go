@@ -15,10 +15,14 @@
 package models
 
 import (
+	"net"
 	"reflect"
 	"testing"
+	"time"
 
 	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 )
 
 func TestDnsRecordsParseInputInfo(t *testing.T) {
@@ -126,3 +130,209 @@ func TestDnsRecordsParseInputInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestDnsRecordSelectValues(t *testing.T) {
+	now := time.Now()
+	values := []string{"10.0.0.1", "10.0.0.2", "10.1.0.1"}
+	cases := []struct {
+		name     string
+		rec      SDnsRecord
+		clientIp string
+		out      []string
+	}{
+		{
+			name: "simple",
+			rec:  SDnsRecord{TrafficPolicy: api.DNS_RECORD_POLICY_SIMPLE},
+			out:  values,
+		},
+		{
+			name: "failover",
+			rec: SDnsRecord{
+				TrafficPolicy:    api.DNS_RECORD_POLICY_FAILOVER,
+				HealthCheck:      true,
+				UnhealthyRecords: "10.0.0.1",
+				HealthCheckedAt:  now,
+			},
+			out: []string{"10.0.0.2"},
+		},
+		{
+			name: "failover (stale health check)",
+			rec: SDnsRecord{
+				TrafficPolicy:    api.DNS_RECORD_POLICY_FAILOVER,
+				HealthCheck:      true,
+				UnhealthyRecords: "10.0.0.1",
+				HealthCheckedAt:  now.Add(-2 * dnsRecordHealthExpire),
+			},
+			out: []string{"10.0.0.1"},
+		},
+		{
+			name: "all unhealthy",
+			rec: SDnsRecord{
+				TrafficPolicy:    api.DNS_RECORD_POLICY_SIMPLE,
+				HealthCheck:      true,
+				UnhealthyRecords: "10.0.0.1,10.0.0.2,10.1.0.1",
+				HealthCheckedAt:  now,
+			},
+			out: values,
+		},
+		{
+			name: "client subnet",
+			rec: SDnsRecord{
+				TrafficPolicy: api.DNS_RECORD_POLICY_CLIENT_SUBNET,
+				TrafficPolicyOptions: &api.SDnsRecordPolicyOptions{
+					Subnets: []api.SDnsRecordSubnet{
+						{Cidr: "10.0.0.0/8", Values: []string{"10.0.0.1", "10.0.0.2"}},
+						{Cidr: "10.1.0.0/16", Values: []string{"10.1.0.1"}},
+					},
+				},
+			},
+			clientIp: "10.1.2.3",
+			out:      []string{"10.1.0.1"},
+		},
+		{
+			name: "client subnet (unhealthy)",
+			rec: SDnsRecord{
+				TrafficPolicy: api.DNS_RECORD_POLICY_CLIENT_SUBNET,
+				TrafficPolicyOptions: &api.SDnsRecordPolicyOptions{
+					Subnets: []api.SDnsRecordSubnet{
+						{Cidr: "10.1.0.0/16", Values: []string{"10.1.0.1"}},
+					},
+				},
+				HealthCheck:      true,
+				UnhealthyRecords: "10.1.0.1",
+				HealthCheckedAt:  now,
+			},
+			clientIp: "10.1.2.3",
+			out:      []string{"10.0.0.1", "10.0.0.2"},
+		},
+		{
+			name: "client subnet (no match)",
+			rec: SDnsRecord{
+				TrafficPolicy: api.DNS_RECORD_POLICY_CLIENT_SUBNET,
+				TrafficPolicyOptions: &api.SDnsRecordPolicyOptions{
+					Subnets: []api.SDnsRecordSubnet{
+						{Cidr: "10.1.0.0/16", Values: []string{"10.1.0.1"}},
+					},
+				},
+			},
+			clientIp: "192.168.0.1",
+			out:      values,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.rec.SelectValues(values, net.ParseIP(c.clientIp), now)
+			if !reflect.DeepEqual(c.out, got) {
+				t.Errorf("want %#v, got %#v", c.out, got)
+			}
+		})
+	}
+}
+
+func TestSelectDnsValueByWeight(t *testing.T) {
+	values := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	weights := []api.SDnsRecordWeight{
+		{Value: "10.0.0.1", Weight: 3},
+		{Value: "10.0.0.2", Weight: 0},
+	}
+	// 10.0.0.3 weighs 1 by default
+	want := []string{"10.0.0.1", "10.0.0.1", "10.0.0.1", "10.0.0.3"}
+	for n := range want {
+		got := selectDnsValueByWeight(values, weights, func(total int) int {
+			if total != 4 {
+				t.Fatalf("total weight: want 4, got %d", total)
+			}
+			return n
+		})
+		if !reflect.DeepEqual(got, []string{want[n]}) {
+			t.Errorf("n=%d: want %s, got %#v", n, want[n], got)
+		}
+	}
+}
+
+func TestDnsRecordValidateUpdateTrafficPolicy(t *testing.T) {
+	rec := &SDnsRecord{
+		TrafficPolicy: api.DNS_RECORD_POLICY_WEIGHTED,
+		TrafficPolicyOptions: &api.SDnsRecordPolicyOptions{
+			Weights: []api.SDnsRecordWeight{{Value: "10.0.0.1", Weight: 3}},
+		},
+	}
+	rec.Records = "A:10.0.0.1,A:10.0.0.2"
+	cases := []struct {
+		name      string
+		input     string
+		records   []string
+		wantErr   bool
+		policy    string
+		optsValue string
+	}{
+		{
+			name:  "ttl only",
+			input: `{"ttl": 300}`,
+		},
+		{
+			name:      "options only",
+			input:     `{"traffic_policy_options": {"weights": [{"value": "10.0.0.2", "weight": 2}]}}`,
+			policy:    api.DNS_RECORD_POLICY_WEIGHTED,
+			optsValue: "10.0.0.2",
+		},
+		{
+			name:      "records keep weighted value",
+			input:     `{}`,
+			records:   []string{"A:10.0.0.1", "A:10.0.0.3"},
+			policy:    api.DNS_RECORD_POLICY_WEIGHTED,
+			optsValue: "10.0.0.1",
+		},
+		{
+			name:    "records drop weighted value",
+			input:   `{}`,
+			records: []string{"A:10.0.0.3"},
+			wantErr: true,
+		},
+		{
+			name:   "policy reset",
+			input:  `{"traffic_policy": "simple", "traffic_policy_options": {}}`,
+			policy: api.DNS_RECORD_POLICY_SIMPLE,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			obj, err := jsonutils.ParseString(c.input)
+			if err != nil {
+				t.Fatalf("invalid json string: %s\n%s", err, c.input)
+			}
+			data := obj.(*jsonutils.JSONDict)
+			policySet := data.Contains("traffic_policy") || data.Contains("traffic_policy_options")
+			data.UpdateDefault(jsonutils.Marshal(rec))
+			err = rec.validateUpdateTrafficPolicy(data, c.records, policySet)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %s", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate: %s", err)
+			}
+			if c.policy == "" {
+				if data.Contains("traffic_policy") || data.Contains("traffic_policy_options") {
+					t.Errorf("traffic policy should be left untouched, got %s", data)
+				}
+				return
+			}
+			policy, _ := data.GetString("traffic_policy")
+			if policy != c.policy {
+				t.Errorf("want policy %s, got %s", c.policy, policy)
+			}
+			opts := api.SDnsRecordPolicyOptions{}
+			data.Unmarshal(&opts, "traffic_policy_options")
+			if c.optsValue == "" {
+				if !opts.IsZero() {
+					t.Errorf("want empty options, got %s", opts)
+				}
+			} else if len(opts.Weights) != 1 || opts.Weights[0].Value != c.optsValue {
+				t.Errorf("want weight of %s, got %s", c.optsValue, opts)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"encoding/base64"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/kms"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SEncryptedResourceBase struct {
+	// 加密密钥ID
+	EncryptKeyId string `width:"36" charset:"ascii" nullable:"true" list:"user" json:"encrypt_key_id"`
+	// 管理加密密钥的KMS
+	EncryptKeyManager string `width:"32" charset:"ascii" nullable:"true" list:"admin" json:"encrypt_key_manager"`
+	// 被加密密钥包裹的数据密钥
+	EncryptedDataKey string `width:"256" charset:"ascii" nullable:"true" json:"encrypted_data_key"`
+}
+
+func validateEncryptKey(ctx context.Context, userCred mcclient.TokenCredential, keyId string) error {
+	manager, err := kms.GetKeyManager(options.Options.KeyManagementService)
+	if err != nil {
+		return httperrors.NewNotSupportedError("key management service %s: %v", options.Options.KeyManagementService, err)
+	}
+	return manager.ValidateKey(ctx, userCred, keyId)
+}
+
+func (res *SEncryptedResourceBase) IsEncrypted() bool {
+	return len(res.EncryptKeyId) > 0
+}
+
+// initEncryption generates a new data key for the resource and wraps it with keyId
+func (res *SEncryptedResourceBase) initEncryption(ctx context.Context, keyId string) error {
+	manager, err := kms.GetKeyManager(options.Options.KeyManagementService)
+	if err != nil {
+		return errors.Wrap(err, "GetKeyManager")
+	}
+	_, wrapped, err := kms.GenerateWrappedDataKey(ctx, manager, keyId)
+	if err != nil {
+		return errors.Wrap(err, "GenerateWrappedDataKey")
+	}
+	res.EncryptKeyId = keyId
+	res.EncryptKeyManager = manager.GetName()
+	res.EncryptedDataKey = wrapped
+	return nil
+}
+
+// inheritEncryption shares the data key of src, e.g. when a disk is created from a snapshot
+func (res *SEncryptedResourceBase) inheritEncryption(src *SEncryptedResourceBase) {
+	res.EncryptKeyId = src.EncryptKeyId
+	res.EncryptKeyManager = src.EncryptKeyManager
+	res.EncryptedDataKey = src.EncryptedDataKey
+}
+
+func (res *SEncryptedResourceBase) GetEncryptInfo(ctx context.Context) (*api.SEncryptInfo, error) {
+	if !res.IsEncrypted() {
+		return nil, nil
+	}
+	manager, err := kms.GetKeyManager(res.EncryptKeyManager)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetKeyManager")
+	}
+	dataKey, err := manager.UnwrapDataKey(ctx, res.EncryptKeyId, res.EncryptedDataKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "UnwrapDataKey with %s", res.EncryptKeyId)
+	}
+	return &api.SEncryptInfo{
+		Id:     res.EncryptKeyId,
+		Key:    base64.StdEncoding.EncodeToString(dataKey),
+		Format: api.DISK_ENCRYPT_FORMAT_LUKS,
+	}, nil
+}
@@ -0,0 +1,463 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// +onecloud:swagger-gen-model-singular=flow_log
+// +onecloud:swagger-gen-model-plural=flow_logs
+type SFlowLogManager struct {
+	db.SEnabledStatusInfrasResourceBaseManager
+	SVpcResourceBaseManager
+}
+
+var FlowLogManager *SFlowLogManager
+
+func init() {
+	FlowLogManager = &SFlowLogManager{
+		SEnabledStatusInfrasResourceBaseManager: db.NewEnabledStatusInfrasResourceBaseManager(
+			SFlowLog{},
+			"flow_logs_tbl",
+			"flow_log",
+			"flow_logs",
+		),
+	}
+	FlowLogManager.SetVirtualObject(FlowLogManager)
+}
+
+// SFlowLog records acl verdicts of guest ports of onecloud vpcs.  The
+// configuration closest to the guest port takes effect, i.e. guest, then
+// network, then vpc
+type SFlowLog struct {
+	db.SEnabledStatusInfrasResourceBase
+	SVpcResourceBase
+
+	// 采集对象类型
+	ResourceType string `width:"16" charset:"ascii" nullable:"false" list:"domain" create:"required"`
+	// 采集对象Id
+	ResourceId string `width:"36" charset:"ascii" nullable:"false" list:"domain" create:"required" index:"true"`
+
+	// 采集的流量类型
+	TrafficType string `width:"16" charset:"ascii" nullable:"false" default:"all" list:"domain" create:"optional" update:"domain"`
+	// 每秒最多记录的报文数
+	RateLimit int `nullable:"false" default:"100" list:"domain" create:"optional" update:"domain"`
+
+	// 投递方式
+	DeliveryType string `width:"16" charset:"ascii" nullable:"false" default:"logger" list:"domain" create:"optional"`
+	// 存储桶Id
+	BucketId string `width:"36" charset:"ascii" nullable:"true" list:"domain" create:"optional"`
+	// 存储桶中对象的前缀
+	BucketPrefix string `width:"128" charset:"utf8" nullable:"true" list:"domain" create:"optional" update:"domain"`
+	// 存储桶中记录的保存天数
+	RetentionDays int `nullable:"false" default:"7" list:"domain" create:"optional" update:"domain"`
+}
+
+func (manager *SFlowLogManager) GetContextManagers() [][]db.IModelManager {
+	return [][]db.IModelManager{
+		{VpcManager},
+	}
+}
+
+// VPC流日志列表
+func (manager *SFlowLogManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.FlowLogListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SVpcResourceBaseManager.ListItemFilter(ctx, q, userCred, query.VpcFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVpcResourceBaseManager.ListItemFilter")
+	}
+	if len(query.ResourceType) > 0 {
+		q = q.In("resource_type", query.ResourceType)
+	}
+	if len(query.ResourceId) > 0 {
+		q = q.Equals("resource_id", query.ResourceId)
+	}
+	if len(query.DeliveryType) > 0 {
+		q = q.In("delivery_type", query.DeliveryType)
+	}
+	return q, nil
+}
+
+func (manager *SFlowLogManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.FlowLogListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SVpcResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VpcFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVpcResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SFlowLogManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusInfrasResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SVpcResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+// validateFlowLogVpc makes sure the vpc is backed by ovn
+func validateFlowLogVpc(vpc *SVpc) error {
+	if vpc.Id == api.DEFAULT_VPC_ID {
+		return httperrors.NewInputParameterError("flow log is not supported in default vpc")
+	}
+	if vpc.IsManaged() {
+		return httperrors.NewNotSupportedError("flow log is not supported in public cloud vpc %s", vpc.Name)
+	}
+	return nil
+}
+
+// validateFlowLogResource resolves the resource to collect flow logs of and
+// returns its id and the vpc it is in
+func (manager *SFlowLogManager) validateFlowLogResource(userCred mcclient.TokenCredential, resType, resId string) (string, *SVpc, error) {
+	switch resType {
+	case api.FLOW_LOG_RESOURCE_TYPE_VPC:
+		_vpc, err := validators.ValidateModel(userCred, VpcManager, &resId)
+		if err != nil {
+			return "", nil, err
+		}
+		vpc := _vpc.(*SVpc)
+		return vpc.Id, vpc, validateFlowLogVpc(vpc)
+	case api.FLOW_LOG_RESOURCE_TYPE_NETWORK:
+		_network, err := validators.ValidateModel(userCred, NetworkManager, &resId)
+		if err != nil {
+			return "", nil, err
+		}
+		network := _network.(*SNetwork)
+		vpc, err := network.GetVpc()
+		if err != nil {
+			return "", nil, httperrors.NewGeneralError(errors.Wrapf(err, "GetVpc of network %s", network.Name))
+		}
+		return network.Id, vpc, validateFlowLogVpc(vpc)
+	case api.FLOW_LOG_RESOURCE_TYPE_GUEST:
+		_guest, err := validators.ValidateModel(userCred, GuestManager, &resId)
+		if err != nil {
+			return "", nil, err
+		}
+		guest := _guest.(*SGuest)
+		gns, err := guest.GetNetworks("")
+		if err != nil {
+			return "", nil, httperrors.NewGeneralError(errors.Wrapf(err, "GetNetworks of guest %s", guest.Name))
+		}
+		for i := range gns {
+			network := gns[i].GetNetwork()
+			if network == nil {
+				continue
+			}
+			vpc, err := network.GetVpc()
+			if err != nil {
+				continue
+			}
+			if validateFlowLogVpc(vpc) == nil {
+				return guest.Id, vpc, nil
+			}
+		}
+		return "", nil, httperrors.NewInputParameterError("guest %s is not in any vpc supporting flow log", guest.Name)
+	default:
+		return "", nil, httperrors.NewInputParameterError("invalid resource_type %q, want %s", resType, api.FLOW_LOG_RESOURCE_TYPES)
+	}
+}
+
+func validateFlowLogTrafficType(trafficType string) error {
+	if !utils.IsInStringArray(trafficType, api.FLOW_LOG_TRAFFIC_TYPES) {
+		return httperrors.NewInputParameterError("invalid traffic_type %q, want %s", trafficType, api.FLOW_LOG_TRAFFIC_TYPES)
+	}
+	return nil
+}
+
+func validateFlowLogRateLimit(rateLimit int) error {
+	if rateLimit <= 0 || rateLimit > 10000 {
+		return httperrors.NewOutOfRangeError("rate_limit must be in range [1, 10000]")
+	}
+	return nil
+}
+
+func validateFlowLogRetentionDays(days int) error {
+	if days <= 0 || days > 3650 {
+		return httperrors.NewOutOfRangeError("retention_days must be in range [1, 3650]")
+	}
+	return nil
+}
+
+func (manager *SFlowLogManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.FlowLogCreateInput,
+) (api.FlowLogCreateInput, error) {
+	var err error
+	input.EnabledStatusInfrasResourceBaseCreateInput, err = manager.SEnabledStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusInfrasResourceBaseCreateInput)
+	if err != nil {
+		return input, err
+	}
+	if input.Enabled == nil {
+		enabled := true
+		input.Enabled = &enabled
+	}
+
+	resId, vpc, err := manager.validateFlowLogResource(userCred, input.ResourceType, input.ResourceId)
+	if err != nil {
+		return input, err
+	}
+	cnt, err := manager.Query().Equals("resource_id", resId).CountWithError()
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return input, httperrors.NewDuplicateResourceError("%s %s already has flow log", input.ResourceType, input.ResourceId)
+	}
+	input.ResourceId = resId
+	input.VpcId = vpc.Id
+
+	if input.TrafficType == "" {
+		input.TrafficType = api.FLOW_LOG_TRAFFIC_TYPE_ALL
+	}
+	if err := validateFlowLogTrafficType(input.TrafficType); err != nil {
+		return input, err
+	}
+	if input.RateLimit == 0 {
+		input.RateLimit = api.FLOW_LOG_DEFAULT_RATE_LIMIT
+	}
+	if err := validateFlowLogRateLimit(input.RateLimit); err != nil {
+		return input, err
+	}
+
+	switch input.DeliveryType {
+	case "", api.FLOW_LOG_DELIVERY_LOGGER:
+		input.DeliveryType = api.FLOW_LOG_DELIVERY_LOGGER
+		input.BucketId = ""
+	case api.FLOW_LOG_DELIVERY_BUCKET:
+		if input.BucketId == "" {
+			return input, httperrors.NewMissingParameterError("bucket_id")
+		}
+		_, err := validators.ValidateModel(userCred, BucketManager, &input.BucketId)
+		if err != nil {
+			return input, err
+		}
+		if input.BucketPrefix == "" {
+			input.BucketPrefix = "flowlogs/"
+		}
+		if input.RetentionDays == 0 {
+			input.RetentionDays = api.FLOW_LOG_DEFAULT_RETENTION_DAYS
+		}
+		if err := validateFlowLogRetentionDays(input.RetentionDays); err != nil {
+			return input, err
+		}
+	default:
+		return input, httperrors.NewInputParameterError("invalid delivery_type %q, want %s", input.DeliveryType, api.FLOW_LOG_DELIVERY_TYPES)
+	}
+	return input, nil
+}
+
+func (fl *SFlowLog) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	fl.SEnabledStatusInfrasResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	fl.SetStatus(userCred, api.FLOW_LOG_STATUS_AVAILABLE, "")
+}
+
+func (fl *SFlowLog) ValidateUpdateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.FlowLogUpdateInput,
+) (api.FlowLogUpdateInput, error) {
+	var err error
+	if input.TrafficType != "" {
+		if err := validateFlowLogTrafficType(input.TrafficType); err != nil {
+			return input, err
+		}
+	}
+	if input.RateLimit != nil {
+		if err := validateFlowLogRateLimit(*input.RateLimit); err != nil {
+			return input, err
+		}
+	}
+	if input.RetentionDays != nil {
+		if err := validateFlowLogRetentionDays(*input.RetentionDays); err != nil {
+			return input, err
+		}
+	}
+	input.EnabledStatusInfrasResourceBaseUpdateInput, err = fl.SEnabledStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusInfrasResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SEnabledStatusInfrasResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
+func (manager *SFlowLogManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.FlowLogDetails {
+	rows := make([]api.FlowLogDetails, len(objs))
+	stdRows := manager.SEnabledStatusInfrasResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	vpcRows := manager.SVpcResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+
+	var (
+		resIds = map[string][]string{}
+		bktIds []string
+	)
+	for i := range rows {
+		rows[i] = api.FlowLogDetails{
+			EnabledStatusInfrasResourceBaseDetails: stdRows[i],
+			VpcResourceInfo:                        vpcRows[i],
+		}
+		fl := objs[i].(*SFlowLog)
+		resIds[fl.ResourceType] = append(resIds[fl.ResourceType], fl.ResourceId)
+		if fl.BucketId != "" {
+			bktIds = append(bktIds, fl.BucketId)
+		}
+	}
+
+	resNames := map[string]string{}
+	for resType, ids := range resIds {
+		var man db.IStandaloneModelManager
+		switch resType {
+		case api.FLOW_LOG_RESOURCE_TYPE_VPC:
+			man = VpcManager
+		case api.FLOW_LOG_RESOURCE_TYPE_NETWORK:
+			man = NetworkManager
+		case api.FLOW_LOG_RESOURCE_TYPE_GUEST:
+			man = GuestManager
+		default:
+			continue
+		}
+		names, err := db.FetchIdNameMap2(man, ids)
+		if err != nil {
+			log.Errorf("fetch %s names: %v", resType, err)
+			continue
+		}
+		for id, name := range names {
+			resNames[id] = name
+		}
+	}
+	bktNames, err := db.FetchIdNameMap2(BucketManager, bktIds)
+	if err != nil {
+		log.Errorf("fetch bucket names: %v", err)
+	}
+	for i := range rows {
+		fl := objs[i].(*SFlowLog)
+		rows[i].Resource = resNames[fl.ResourceId]
+		rows[i].Bucket = bktNames[fl.BucketId]
+	}
+	return rows
+}
+
+func (fl *SFlowLog) getBucket() (*SBucket, error) {
+	bucket, err := BucketManager.FetchById(fl.BucketId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch bucket %s", fl.BucketId)
+	}
+	return bucket.(*SBucket), nil
+}
+
+// cleanExpiredObjects removes flow records older than retention days from
+// the bucket.  Hosts put records at <prefix><flowLogId>/<date>/
+func (fl *SFlowLog) cleanExpiredObjects(ctx context.Context) error {
+	bucket, err := fl.getBucket()
+	if err != nil {
+		return err
+	}
+	iBucket, err := bucket.GetIBucket()
+	if err != nil {
+		return errors.Wrap(err, "GetIBucket")
+	}
+	prefix := fmt.Sprintf("%s%s/", fl.BucketPrefix, fl.Id)
+	objs, err := cloudprovider.GetAllObjects(iBucket, prefix, true)
+	if err != nil {
+		return errors.Wrapf(err, "GetAllObjects %s", prefix)
+	}
+	expireAt := time.Now().AddDate(0, 0, -fl.RetentionDays)
+	for _, obj := range objs {
+		if obj.GetLastModified().After(expireAt) {
+			continue
+		}
+		if err := iBucket.DeleteObject(ctx, obj.GetKey()); err != nil {
+			return errors.Wrapf(err, "DeleteObject %s", obj.GetKey())
+		}
+	}
+	return nil
+}
+
+func (manager *SFlowLogManager) CleanExpiredObjects(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	fls := []SFlowLog{}
+	q := manager.Query().Equals("delivery_type", api.FLOW_LOG_DELIVERY_BUCKET)
+	if err := db.FetchModelObjects(manager, q, &fls); err != nil {
+		log.Errorf("fetch flow logs: %v", err)
+		return
+	}
+	for i := range fls {
+		if err := fls[i].cleanExpiredObjects(ctx); err != nil {
+			log.Errorf("flow log %s(%s): clean expired objects: %v", fls[i].Name, fls[i].Id, err)
+		}
+	}
+}
+
+// cleanFlowLogs removes flow logs of the resource being deleted.  field is
+// vpc_id for vpcs, resource_id for networks and guests
+func (manager *SFlowLogManager) cleanFlowLogs(ctx context.Context, userCred mcclient.TokenCredential, field, id string) error {
+	fls := []SFlowLog{}
+	q := manager.Query().Equals(field, id)
+	if err := db.FetchModelObjects(manager, q, &fls); err != nil {
+		return errors.Wrapf(err, "fetch flow logs of %s", id)
+	}
+	for i := range fls {
+		if err := fls[i].Delete(ctx, userCred); err != nil {
+			return errors.Wrapf(err, "delete flow log %s", fls[i].Id)
+		}
+	}
+	return nil
+}
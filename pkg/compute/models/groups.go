@@ -84,6 +84,8 @@ type SGroup struct {
 	// the upper limit number of guests with this group in a host
 	Granularity     int               `nullable:"false" list:"user" get:"user" create:"optional" update:"user" default:"1"`
 	ForceDispersion tristate.TriState `list:"user" get:"user" create:"optional" update:"user" default:"true"`
+	// 拓扑分布约束
+	TopologySpreadConstraints *api.TopologySpreadConstraints `length:"text" nullable:"true" list:"user" get:"user" create:"optional" update:"user"`
 	// 是否启用
 	// Enabled tristate.TriState `nullable:"false" default:"true" create:"optional" list:"user" update:"user"`
 }
@@ -168,6 +170,55 @@ func (sm *SGroupManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field strin
 	return q, httperrors.ErrNotFound
 }
 
+func validateTopologySpreadConstraints(data *jsonutils.JSONDict) error {
+	if !data.Contains("topology_spread_constraints") {
+		return nil
+	}
+	constraints := api.TopologySpreadConstraints{}
+	if err := data.Unmarshal(&constraints, "topology_spread_constraints"); err != nil {
+		return httperrors.NewInputParameterError("unmarshal topology_spread_constraints: %v", err)
+	}
+	if err := constraints.Validate(); err != nil {
+		return err
+	}
+	data.Set("topology_spread_constraints", jsonutils.Marshal(constraints))
+	return nil
+}
+
+func (sm *SGroupManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	if err := validateTopologySpreadConstraints(data); err != nil {
+		return nil, err
+	}
+	input := apis.VirtualResourceCreateInput{}
+	err := data.Unmarshal(&input)
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("unmarshal VirtualResourceCreateInput fail %s", err)
+	}
+	input, err = sm.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input)
+	if err != nil {
+		return nil, err
+	}
+	data.Update(jsonutils.Marshal(input))
+	return data, nil
+}
+
+func (group *SGroup) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	if err := validateTopologySpreadConstraints(data); err != nil {
+		return nil, err
+	}
+	input := apis.VirtualResourceBaseUpdateInput{}
+	err := data.Unmarshal(&input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	input, err = group.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBase.ValidateUpdateData")
+	}
+	data.Update(jsonutils.Marshal(input))
+	return data, nil
+}
+
 func (sm *SGroupManager) FetchCustomizeColumns(
 	ctx context.Context,
 	userCred mcclient.TokenCredential,
@@ -198,6 +249,29 @@ func (group *SGroup) GetGuestCount() int {
 	return count
 }
 
+// GetHostGuestCounts returns number of guests of the group on each host
+func (group *SGroup) GetHostGuestCounts() (map[string]int, error) {
+	guests := GuestManager.Query().SubQuery()
+	groupguests := GroupguestManager.Query().SubQuery()
+	q := guests.Query(guests.Field("host_id"), sqlchemy.COUNT("guest_count"))
+	q = q.Join(groupguests, sqlchemy.Equals(groupguests.Field("guest_id"), guests.Field("id")))
+	q = q.Filter(sqlchemy.Equals(groupguests.Field("group_id"), group.Id))
+	q = q.Filter(sqlchemy.IsNotEmpty(guests.Field("host_id")))
+	q = q.GroupBy(guests.Field("host_id"))
+	results := []struct {
+		HostId     string
+		GuestCount int
+	}{}
+	if err := q.All(&results); err != nil {
+		return nil, errors.Wrap(err, "query guest count of hosts")
+	}
+	ret := make(map[string]int, len(results))
+	for _, r := range results {
+		ret[r.HostId] = r.GuestCount
+	}
+	return ret, nil
+}
+
 func (group *SGroup) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
 	q := GroupguestManager.Query().Equals("group_id", group.Id)
 	count, err := q.CountWithError()
@@ -758,6 +758,9 @@ func (self *SGuest) ValidateAttachDisk(ctx context.Context, disk *SDisk) error {
 	if !utils.IsInStringArray(self.Status, guestStatus) {
 		return httperrors.NewInputParameterError("Guest %s not support attach disk in status %s", self.Name, self.Status)
 	}
+	if disk.IsEncrypted() && self.Status != api.VM_READY {
+		return httperrors.NewInputParameterError("Encrypted disk %s can only be attached to a stopped guest", disk.Name)
+	}
 	return nil
 }
 
@@ -2363,11 +2366,6 @@ func (self *SGuest) PerformChangeBandwidth(ctx context.Context, userCred mcclien
 		return nil, httperrors.NewBadRequestError("Cannot change bandwidth in status %s", self.Status)
 	}
 
-	bandwidth, err := data.Int("bandwidth")
-	if err != nil || bandwidth < 0 {
-		return nil, httperrors.NewBadRequestError("Bandwidth must be non-negative")
-	}
-
 	ipStr, _ := data.GetString("ip_addr")
 	macStr, _ := data.GetString("mac")
 	index, _ := data.Int("index")
@@ -2376,9 +2374,20 @@ func (self *SGuest) PerformChangeBandwidth(ctx context.Context, userCred mcclien
 		return nil, err
 	}
 
-	if guestnic.BwLimit != int(bandwidth) {
+	bandwidth, ingressBandwidth, egressBandwidth, burst, err := guestnic.parseChangeBandwidthInput(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if guestnic.BwLimit != bandwidth ||
+		guestnic.IngressBwLimit != ingressBandwidth ||
+		guestnic.EgressBwLimit != egressBandwidth ||
+		guestnic.BwBurst != burst {
 		diff, err := db.Update(guestnic, func() error {
-			guestnic.BwLimit = int(bandwidth)
+			guestnic.BwLimit = bandwidth
+			guestnic.IngressBwLimit = ingressBandwidth
+			guestnic.EgressBwLimit = egressBandwidth
+			guestnic.BwBurst = burst
 			return nil
 		})
 		if err != nil {
@@ -2391,6 +2400,42 @@ func (self *SGuest) PerformChangeBandwidth(ctx context.Context, userCred mcclien
 	return nil, nil
 }
 
+// parseChangeBandwidthInput returns bandwidth settings of the nic changed by
+// data.  ingress_bandwidth, egress_bandwidth and burst are realized only for
+// guests in vpc networks.  Unspecified values are kept as they are
+func (self *SGuestnetwork) parseChangeBandwidthInput(data jsonutils.JSONObject) (int, int, int, int, error) {
+	var (
+		bandwidth        = self.BwLimit
+		ingressBandwidth = self.IngressBwLimit
+		egressBandwidth  = self.EgressBwLimit
+		burst            = self.BwBurst
+		found            = false
+	)
+	for _, arg := range []struct {
+		name string
+		val  *int
+	}{
+		{"bandwidth", &bandwidth},
+		{"ingress_bandwidth", &ingressBandwidth},
+		{"egress_bandwidth", &egressBandwidth},
+		{"burst", &burst},
+	} {
+		if !data.Contains(arg.name) {
+			continue
+		}
+		found = true
+		v, err := data.Int(arg.name)
+		if err != nil || v < 0 {
+			return 0, 0, 0, 0, httperrors.NewBadRequestError("%s must be non-negative", arg.name)
+		}
+		*arg.val = int(v)
+	}
+	if !found {
+		return 0, 0, 0, 0, httperrors.NewMissingParameterError("bandwidth")
+	}
+	return bandwidth, ingressBandwidth, egressBandwidth, burst, nil
+}
+
 func (self *SGuest) AllowPerformModifySrcCheck(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
 	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "modify-src-check")
 }
@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+func TestGuestnetworkParseChangeBandwidthInput(t *testing.T) {
+	gn := &SGuestnetwork{
+		BwLimit:        100,
+		IngressBwLimit: 50,
+		EgressBwLimit:  20,
+		BwBurst:        1000,
+	}
+	cases := []struct {
+		name     string
+		input    string
+		errClass string
+		want     [4]int
+	}{
+		{
+			name:     "missing bandwidth",
+			input:    `{"index": 0}`,
+			errClass: string(httperrors.ErrMissingParameter),
+		},
+		{
+			name:     "negative bandwidth",
+			input:    `{"bandwidth": -1}`,
+			errClass: string(httperrors.ErrBadRequest),
+		},
+		{
+			name:  "bandwidth only",
+			input: `{"bandwidth": 200}`,
+			want:  [4]int{200, 50, 20, 1000},
+		},
+		{
+			name:  "ingress and burst",
+			input: `{"ingress_bandwidth": 0, "burst": 2000}`,
+			want:  [4]int{100, 0, 20, 2000},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := jsonutils.ParseString(c.input)
+			if err != nil {
+				t.Fatalf("invalid json string: %s\n%s", err, c.input)
+			}
+			bw, ingress, egress, burst, err := gn.parseChangeBandwidthInput(data)
+			if c.errClass != "" {
+				jerr, ok := err.(*httputils.JSONClientError)
+				if !ok || jerr.Class != c.errClass {
+					t.Fatalf("want %s, got %v", c.errClass, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %s", err)
+			}
+			if got := [4]int{bw, ingress, egress, burst}; got != c.want {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
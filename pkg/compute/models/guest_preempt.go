@@ -0,0 +1,217 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// PreemptGuests marks preemptible guests chosen by scheduler to release
+// their resources on host to the preempting object.  The guests are stopped
+// or deleted after grace period by the preempting guest before it is
+// deployed, or by PreemptDueGuests
+func (manager *SGuestManager) PreemptGuests(ctx context.Context, userCred mcclient.TokenCredential, preemptor db.IModel, hostId string, guestIds []string) error {
+	grace := time.Duration(options.Options.PreemptionGracePeriodSeconds) * time.Second
+	errs := []error{}
+	for _, guestId := range guestIds {
+		obj, err := manager.FetchById(guestId)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "FetchById %s", guestId))
+			continue
+		}
+		err = obj.(*SGuest).markPreempted(ctx, userCred, preemptor, hostId, grace)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (self *SGuest) markPreempted(ctx context.Context, userCred mcclient.TokenCredential, preemptor db.IModel, hostId string, grace time.Duration) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	if !self.Preemptible {
+		return errors.Wrapf(httperrors.ErrInvalidStatus, "guest %s is not preemptible", self.Name)
+	}
+	if self.HostId != hostId {
+		return errors.Wrapf(httperrors.ErrInvalidStatus, "guest %s is not on host %s", self.Name, hostId)
+	}
+	if !self.PreemptAt.IsZero() {
+		return errors.Wrapf(httperrors.ErrConflict, "guest %s is already preempted by %s", self.Name, self.PreemptedBy)
+	}
+	_, err := db.Update(self, func() error {
+		self.PreemptAt = time.Now().Add(grace)
+		self.PreemptedBy = preemptor.GetId()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "update guest %s", self.Name)
+	}
+	notes := jsonutils.NewDict()
+	notes.Add(jsonutils.NewString(preemptor.GetId()), "preemptor_id")
+	notes.Add(jsonutils.NewString(preemptor.GetName()), "preemptor")
+	notes.Add(jsonutils.NewString(self.PreemptAction), "preempt_action")
+	notes.Add(jsonutils.NewTimeString(self.PreemptAt), "preempt_at")
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_PREEMPT, notes, userCred, true)
+	// the owner of preempting object has nothing to do with the guest
+	notifyclient.EventNotify(ctx, auth.AdminCredential(), notifyclient.SEventNotifyParam{
+		Obj:    self,
+		Action: notifyclient.ActionPreempt,
+	})
+	return nil
+}
+
+// PreemptDueGuests stops or deletes preempted guests whose grace period is over
+func (manager *SGuestManager) PreemptDueGuests(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().IsTrue("preemptible").IsNotEmpty("preempted_by")
+	q = q.Filter(sqlchemy.AND(
+		sqlchemy.IsNotNull(q.Field("preempt_at")),
+		sqlchemy.LE(q.Field("preempt_at"), time.Now()),
+	))
+	guests := make([]SGuest, 0)
+	err := db.FetchModelObjects(manager, q, &guests)
+	if err != nil {
+		log.Errorf("fetch preempted guests: %v", err)
+		return
+	}
+	for i := range guests {
+		_, err := guests[i].doPreempt(ctx, userCred, "")
+		if err != nil {
+			log.Errorf("preempt guest %s: %v", guests[i].Name, err)
+		}
+	}
+}
+
+// doPreempt stops or deletes the guest, notifying parentTaskId once done.
+// It tells whether a task is started
+func (self *SGuest) doPreempt(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) (bool, error) {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	preemptor, err := GuestManager.FetchById(self.PreemptedBy)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return false, errors.Wrapf(err, "fetch preemptor %s", self.PreemptedBy)
+	}
+	if preemptor == nil || preemptor.(*SGuest).isPreemptorGone() {
+		// preempting guest has gone, resources are available again
+		db.OpsLog.LogEvent(self, db.ACT_UPDATE, "preemptor is gone, cancel preemption", userCred)
+		return false, self.clearPreemptAt()
+	}
+
+	started := false
+	switch self.PreemptAction {
+	case api.PREEMPT_ACTION_DELETE:
+		if !utils.IsInStringArray(self.Status, []string{api.VM_RUNNING, api.VM_READY}) {
+			return false, errors.Wrapf(httperrors.ErrInvalidStatus, "status %s", self.Status)
+		}
+		self.SetDisableDelete(userCred, false)
+		err = self.StartDeleteGuestTask(ctx, userCred, parentTaskId, api.ServerDeleteInput{OverridePendingDelete: true})
+		if err != nil {
+			return false, errors.Wrap(err, "StartDeleteGuestTask")
+		}
+		started = true
+	default:
+		switch self.Status {
+		case api.VM_READY:
+		case api.VM_RUNNING:
+			err = self.StartGuestStopTask(ctx, userCred, true, false, parentTaskId)
+			if err != nil {
+				return false, errors.Wrap(err, "StartGuestStopTask")
+			}
+			started = true
+		default:
+			return false, errors.Wrapf(httperrors.ErrInvalidStatus, "status %s", self.Status)
+		}
+	}
+	return started, self.clearPreemptAt()
+}
+
+// GetPreemptVictims returns guests preempted by the guest which still hold
+// resources on its host
+func (self *SGuest) GetPreemptVictims() ([]SGuest, error) {
+	q := GuestManager.Query().Equals("preempted_by", self.Id).Equals("host_id", self.HostId)
+	q = q.NotEquals("status", api.VM_READY)
+	guests := make([]SGuest, 0)
+	err := db.FetchModelObjects(GuestManager, q, &guests)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return guests, nil
+}
+
+// PreemptDueVictims stops or deletes victims of the guest whose grace period
+// is over, notifying parentTaskId once all are done.  It tells whether any
+// task is started
+func (self *SGuest) PreemptDueVictims(ctx context.Context, userCred mcclient.TokenCredential, victims []SGuest, parentTaskId string) (bool, error) {
+	started := false
+	now := time.Now()
+	for i := range victims {
+		if victims[i].PreemptAt.IsZero() || victims[i].PreemptAt.After(now) {
+			continue
+		}
+		ok, err := victims[i].doPreempt(ctx, userCred, parentTaskId)
+		if err != nil {
+			return started, errors.Wrapf(err, "preempt guest %s", victims[i].Name)
+		}
+		started = started || ok
+	}
+	return started, nil
+}
+
+// StartReleasePreemptVictimsTask waits for victims of the guest to release
+// resources on its host, notifying parentTaskId once done
+func (self *SGuest) StartReleasePreemptVictimsTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestReleasePreemptVictimsTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SGuest) isPreemptorGone() bool {
+	if self.PendingDeleted || self.Deleted {
+		return true
+	}
+	return utils.IsInStringArray(self.Status, []string{api.VM_SCHEDULE_FAILED, api.VM_CREATE_FAILED})
+}
+
+func (self *SGuest) clearPreemptAt() error {
+	_, err := db.Update(self, func() error {
+		self.PreemptAt = time.Time{}
+		return nil
+	})
+	return err
+}
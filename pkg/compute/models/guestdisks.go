@@ -175,7 +175,7 @@ func (self *SGuestdisk) GetDisk() *SDisk {
 	return disk.(*SDisk)
 }
 
-func (self *SGuestdisk) GetJsonDescAtHost(host *SHost) *api.GuestdiskJsonDesc {
+func (self *SGuestdisk) GetJsonDescAtHost(ctx context.Context, host *SHost) (*api.GuestdiskJsonDesc, error) {
 	disk := self.GetDisk()
 	desc := &api.GuestdiskJsonDesc{
 		DiskId:    self.DiskId,
@@ -222,7 +222,14 @@ func (self *SGuestdisk) GetJsonDescAtHost(host *SHost) *api.GuestdiskJsonDesc {
 	desc.Mountpoint = self.Mountpoint
 	desc.Dev = disk.getDev()
 	desc.IsSSD = disk.IsSsd
-	return desc
+	if disk.IsEncrypted() {
+		encInfo, err := disk.GetEncryptInfo(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetEncryptInfo of disk %s", disk.Id)
+		}
+		desc.EncryptInfo = encInfo
+	}
+	return desc, nil
 }
 
 func (self *SGuestdisk) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
@@ -87,6 +87,12 @@ type SGuestnetwork struct {
 	Driver string `width:"16" charset:"ascii" nullable:"true" list:"user" update:"user"`
 	// 带宽限制，单位mbps
 	BwLimit int `nullable:"false" default:"0" list:"user"`
+	// 入方向(流入虚拟机)带宽限制，单位mbps，为0时同BwLimit，目前仅对vpc网络生效
+	IngressBwLimit int `nullable:"false" default:"0" list:"user"`
+	// 出方向(流出虚拟机)带宽限制，单位mbps，为0时同BwLimit，目前仅对vpc网络生效
+	EgressBwLimit int `nullable:"false" default:"0" list:"user"`
+	// 突发流量，单位kbits，为0时为带宽限制的2倍
+	BwBurst int `nullable:"false" default:"0" list:"user"`
 	// 网卡序号
 	Index int8 `nullable:"false" default:"0" list:"user" update:"user"`
 	// 是否为虚拟接口（无IP）
@@ -332,6 +338,14 @@ func (manager *SGuestnetworkManager) newGuestNetwork(
 			gn.IpAddr = ipAddr
 		}
 
+		if provider == api.CLOUD_PROVIDER_ONECLOUD && network.IsSupportIPv6() {
+			ip6Addr, err := network.GetFreeIP6("")
+			if err != nil {
+				return nil, errors.Wrap(err, "GetFreeIP6")
+			}
+			gn.Ip6Addr = ip6Addr
+		}
+
 		if vpc.Id != api.DEFAULT_VPC_ID && provider == api.CLOUD_PROVIDER_ONECLOUD {
 			var err error
 			GuestnetworkManager.lockAllocMappedAddr(ctx)
@@ -581,6 +595,13 @@ func (self *SGuestnetwork) getJsonDesc() *api.GuestnetworkJsonDesc {
 	desc.ExternalId = net.ExternalId
 	desc.TeamWith = self.TeamWith
 
+	if self.Ip6Addr != "" && net.IsSupportIPv6() {
+		desc.Ip6 = self.Ip6Addr
+		desc.Masklen6 = net.GuestIp6Mask
+		desc.Gateway6 = net.GuestGateway6
+		desc.Dns6 = net.GuestDns6
+	}
+
 	guest := self.getGuest()
 	if guest.GetHypervisor() != api.HYPERVISOR_KVM {
 		manual := true
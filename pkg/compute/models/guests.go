@@ -149,6 +149,16 @@ type SGuest struct {
 	InstanceType string `width:"64" charset:"utf8" nullable:"true" list:"user" create:"optional"`
 
 	SshableLastState tristate.TriState `nullable:"false" default:"false" list:"user"`
+
+	// 是否为抢占式实例
+	Preemptible bool `nullable:"false" default:"false" list:"user" create:"optional" index:"true"`
+	// 被抢占时执行的操作
+	// example: stop
+	PreemptAction string `width:"16" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+	// 计划被抢占的时间
+	PreemptAt time.Time `nullable:"true" list:"user"`
+	// 抢占此实例的实例Id
+	PreemptedBy string `width:"36" charset:"ascii" nullable:"true" list:"user"`
 }
 
 func (manager *SGuestManager) AllowListItems(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
@@ -976,6 +986,15 @@ func (self *SGuest) ValidateUpdateData(ctx context.Context, userCred mcclient.To
 		return input, httperrors.NewInputParameterError("name is too short")
 	}
 
+	if input.PreemptAction != nil {
+		if !self.Preemptible {
+			return input, httperrors.NewInputParameterError("server %s is not preemptible", self.Name)
+		}
+		if !utils.IsInStringArray(*input.PreemptAction, []string{api.PREEMPT_ACTION_STOP, api.PREEMPT_ACTION_DELETE}) {
+			return input, httperrors.NewInputParameterError("invalid preempt_action %s", *input.PreemptAction)
+		}
+	}
+
 	var err error
 	input.VirtualResourceBaseUpdateInput, err = self.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
 	if err != nil {
@@ -1131,6 +1150,18 @@ func (manager *SGuestManager) validateCreateData(
 	// var rootStorageType string
 	var osProf osprofile.SOSProfile
 	hypervisor = input.Hypervisor
+	if len(input.EncryptKeyId) > 0 {
+		for i := range input.Disks {
+			if len(input.Disks[i].EncryptKeyId) == 0 {
+				input.Disks[i].EncryptKeyId = input.EncryptKeyId
+			}
+		}
+	}
+	for i := range input.Disks {
+		if len(input.Disks[i].EncryptKeyId) > 0 && hypervisor != api.HYPERVISOR_KVM {
+			return nil, httperrors.NewNotSupportedError("disk encryption is not supported by %s", hypervisor)
+		}
+	}
 	if hypervisor != api.HYPERVISOR_CONTAINER {
 		if len(input.Disks) == 0 {
 			return nil, httperrors.NewInputParameterError("No disk information provided")
@@ -1208,6 +1239,26 @@ func (manager *SGuestManager) validateCreateData(
 		return nil, httperrors.NewBadRequestError("Miss operating system???")
 	}
 
+	if input.Preemptible {
+		if len(input.Hypervisor) == 0 {
+			input.Hypervisor = api.HYPERVISOR_KVM
+		}
+		if input.Hypervisor != api.HYPERVISOR_KVM {
+			return nil, httperrors.NewNotSupportedError("preemptible server is not supported by %s", input.Hypervisor)
+		}
+		if input.Backup {
+			return nil, httperrors.NewBadRequestError("Cannot create backup for preemptible server")
+		}
+		if len(input.PreemptAction) == 0 {
+			input.PreemptAction = api.PREEMPT_ACTION_STOP
+		}
+		if !utils.IsInStringArray(input.PreemptAction, []string{api.PREEMPT_ACTION_STOP, api.PREEMPT_ACTION_DELETE}) {
+			return nil, httperrors.NewInputParameterError("invalid preempt_action %s", input.PreemptAction)
+		}
+	} else {
+		input.PreemptAction = ""
+	}
+
 	hypervisor = input.Hypervisor
 	if hypervisor != api.HYPERVISOR_CONTAINER {
 		// support sku here
@@ -1660,13 +1711,15 @@ func (manager *SGuestManager) checkCreateQuota(
 func (self *SGuest) checkUpdateQuota(ctx context.Context, userCred mcclient.TokenCredential, vcpuCount int, vmemSize int) (quotas.IQuota, error) {
 	req := SQuota{}
 
+	var cpu, mem int
 	if vcpuCount > 0 && vcpuCount > int(self.VcpuCount) {
-		req.Cpu = vcpuCount - int(self.VcpuCount)
+		cpu = vcpuCount - int(self.VcpuCount)
 	}
 
 	if vmemSize > 0 && vmemSize > self.VmemSize {
-		req.Memory = vmemSize - self.VmemSize
+		mem = vmemSize - self.VmemSize
 	}
+	req.setGuestCpuMem(self.Preemptible, 0, cpu, mem)
 
 	keys, err := self.GetQuotaKeys()
 	if err != nil {
@@ -1730,12 +1783,10 @@ func getGuestResourceRequirements(
 	}
 
 	req := SQuota{
-		Count:          count,
-		Cpu:            int(vcpuCount) * count,
-		Memory:         int(vmemSize) * count,
 		Storage:        diskSize * count,
 		IsolatedDevice: devCount * count,
 	}
+	req.setGuestCpuMem(input.Preemptible, count, int(vcpuCount)*count, int(vmemSize)*count)
 	regionReq := SRegionQuota{
 		Port:  iNicCnt * count,
 		Eport: eNicCnt * count,
@@ -3149,6 +3200,10 @@ type SGuestCountStat struct {
 	TotalBackupCpuCount   int
 	TotalBackupMemSize    int
 	TotalBackupDiskSize   int
+
+	TotalPreemptibleGuestCount int
+	TotalPreemptibleCpuCount   int
+	TotalPreemptibleMemSize    int
 }
 
 func usageTotalGuestResouceCount(
@@ -3231,6 +3286,11 @@ func _guestResourceCountQuery(
 		"vcpu_count",
 		"vmem_size",
 	).IsNotEmpty("backup_host_id").SubQuery()
+	guestPreemptibleSubQuery := GuestManager.Query(
+		"id",
+		"vcpu_count",
+		"vmem_size",
+	).IsTrue("preemptible").SubQuery()
 
 	q := guests.Query(sqlchemy.COUNT("total_guest_count"),
 		sqlchemy.SUM("total_cpu_count", guests.Field("vcpu_count")),
@@ -3241,9 +3301,13 @@ func _guestResourceCountQuery(
 		sqlchemy.SUM("total_backup_cpu_count", guestBackupSubQuery.Field("vcpu_count")),
 		sqlchemy.SUM("total_backup_mem_size", guestBackupSubQuery.Field("vmem_size")),
 		sqlchemy.COUNT("total_backup_guest_count", guestBackupSubQuery.Field("id")),
+		sqlchemy.COUNT("total_preemptible_guest_count", guestPreemptibleSubQuery.Field("id")),
+		sqlchemy.SUM("total_preemptible_cpu_count", guestPreemptibleSubQuery.Field("vcpu_count")),
+		sqlchemy.SUM("total_preemptible_mem_size", guestPreemptibleSubQuery.Field("vmem_size")),
 	)
 
 	q = q.LeftJoin(guestBackupSubQuery, sqlchemy.Equals(guestBackupSubQuery.Field("id"), guests.Field("id")))
+	q = q.LeftJoin(guestPreemptibleSubQuery, sqlchemy.Equals(guestPreemptibleSubQuery.Field("id"), guests.Field("id")))
 
 	q = q.LeftJoin(diskSubQuery, sqlchemy.Equals(diskSubQuery.Field("guest_id"), guests.Field("id")))
 	q = q.LeftJoin(diskBackupSubQuery, sqlchemy.Equals(diskBackupSubQuery.Field("guest_id"), guests.Field("id")))
@@ -3548,6 +3612,10 @@ func (self *SGuest) createDiskOnHost(
 }
 
 func (self *SGuest) CreateIsolatedDeviceOnHost(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, devs []*api.IsolatedDeviceConfig, pendingUsage quotas.IQuota) error {
+	devs, err := IsolatedDeviceManager.pickLocalDevicesOnHost(host, devs)
+	if err != nil {
+		return err
+	}
 	for _, devConfig := range devs {
 		err := self.createIsolatedDeviceOnHost(ctx, userCred, host, devConfig, pendingUsage)
 		if err != nil {
@@ -3691,6 +3759,9 @@ func (self *SGuest) Delete(ctx context.Context, userCred mcclient.TokenCredentia
 }
 
 func (self *SGuest) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if err := FlowLogManager.cleanFlowLogs(ctx, userCred, "resource_id", self.Id); err != nil {
+		return errors.Wrap(err, "clean flow logs")
+	}
 	return self.SVirtualResourceBase.Delete(ctx, userCred)
 }
 
@@ -3890,7 +3961,7 @@ func (self *SGuest) GetIsolatedDevices() ([]SIsolatedDevice, error) {
 	return devs, nil
 }
 
-func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) *api.GuestJsonDesc {
+func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) (*api.GuestJsonDesc, error) {
 	desc := &api.GuestJsonDesc{
 		Name:        self.Name,
 		Description: self.Description,
@@ -3937,7 +4008,10 @@ func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) *a
 	// disks
 	disks, _ := self.GetGuestDisks()
 	for _, disk := range disks {
-		diskDesc := disk.GetJsonDescAtHost(host)
+		diskDesc, err := disk.GetJsonDescAtHost(ctx, host)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetJsonDescAtHost of disk %s", disk.DiskId)
+		}
 		desc.Disks = append(desc.Disks, diskDesc)
 	}
 
@@ -3999,10 +4073,10 @@ func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) *a
 		desc.ScallingGroupId = sggs[0].ScalingGroupId
 	}
 
-	return desc
+	return desc, nil
 }
 
-func (self *SGuest) GetJsonDescAtBaremetal(ctx context.Context, host *SHost) *api.GuestJsonDesc {
+func (self *SGuest) GetJsonDescAtBaremetal(ctx context.Context, host *SHost) (*api.GuestJsonDesc, error) {
 	desc := &api.GuestJsonDesc{
 		Name:        self.Name,
 		Description: self.Description,
@@ -4030,7 +4104,10 @@ func (self *SGuest) GetJsonDescAtBaremetal(ctx context.Context, host *SHost) *ap
 
 	disks, _ := self.GetGuestDisks()
 	for _, disk := range disks {
-		diskDesc := disk.GetJsonDescAtHost(host)
+		diskDesc, err := disk.GetJsonDescAtHost(ctx, host)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetJsonDescAtHost of disk %s", disk.DiskId)
+		}
 		desc.Disks = append(desc.Disks, diskDesc)
 	}
 
@@ -4065,7 +4142,7 @@ func (self *SGuest) GetJsonDescAtBaremetal(ctx context.Context, host *SHost) *ap
 	desc.UserData, _ = desc.Metadata["user_data"]
 	desc.PendingDeleted = self.PendingDeleted
 
-	return desc
+	return desc, nil
 }
 
 func (self *SGuest) getNetworkRoles() []string {
@@ -4947,6 +5024,7 @@ func (self *SGuest) ToSchedDesc() *schedapi.ScheduleInput {
 	}*/
 
 	config.Hypervisor = self.GetHypervisor()
+	config.Preemptible = self.Preemptible
 	desc.ServerConfig = *config
 	desc.OsArch = self.OsArch
 	return desc
@@ -5351,9 +5429,7 @@ func (self *SGuest) GetDiskSnapshotsNotInInstanceSnapshots() ([]SSnapshot, error
 func (self *SGuest) getGuestUsage(guestCount int) (SQuota, SRegionQuota, error) {
 	usage := SQuota{}
 	regionUsage := SRegionQuota{}
-	usage.Count = guestCount
-	usage.Cpu = int(self.VcpuCount) * guestCount
-	usage.Memory = int(self.VmemSize * guestCount)
+	usage.setGuestCpuMem(self.Preemptible, guestCount, int(self.VcpuCount)*guestCount, int(self.VmemSize*guestCount))
 	diskSize := self.getDiskSize()
 	if diskSize < 0 {
 		return usage, regionUsage, httperrors.NewInternalServerError("fetch disk size failed")
@@ -155,3 +155,15 @@ func (h *SHostHealthChecker) UnwatchHost(ctx context.Context, hostId string) {
 	h.cli.Unwatch(hostKey(hostId))
 	delete(h.hc, hostId)
 }
+
+// IsHostOnline tells whether host agent keeps its health key alive in etcd
+func (h *SHostHealthChecker) IsHostOnline(ctx context.Context, hostId string) (bool, error) {
+	_, err := h.cli.Get(ctx, hostKey(hostId))
+	if err == etcd.ErrNoSuchKey {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
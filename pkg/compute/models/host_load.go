@@ -0,0 +1,150 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+// sLoadGuest is a guest planners may move to another host.  Cpu is in cores,
+// Memory in MB
+type sLoadGuest struct {
+	Id     string
+	Name   string
+	Cpu    float64
+	Memory float64
+	// ids of instance groups forcing dispersion the guest belongs to
+	Groups []string
+}
+
+// sHostLoad is capacity and allocation of a host, with guests which may be
+// moved off it
+type sHostLoad struct {
+	Id          string
+	Name        string
+	CpuCapacity float64
+	MemCapacity float64
+	CpuUsed     float64
+	MemUsed     float64
+	Guests      []*sLoadGuest
+	// instance groups of guests staying on the host
+	PinnedGroups []string
+
+	groupCount map[string]int
+}
+
+func newHostLoad(host *SHost) sHostLoad {
+	return sHostLoad{
+		Id:          host.Id,
+		Name:        host.Name,
+		CpuCapacity: float64(host.GetVirtualCPUCount()),
+		MemCapacity: float64(host.GetVirtualMemorySize()),
+	}
+}
+
+func (h *sHostLoad) initGroupCount() {
+	h.groupCount = map[string]int{}
+	for _, gid := range h.PinnedGroups {
+		h.groupCount[gid]++
+	}
+	for _, g := range h.Guests {
+		for _, gid := range g.Groups {
+			h.groupCount[gid]++
+		}
+	}
+}
+
+// sGuestConstraints are what keeps guests on hosts from being moved freely
+type sGuestConstraints struct {
+	// guests with passthrough devices, which cannot be live migrated
+	DeviceGuests map[string]bool
+	// hosts holding backups of guests
+	BackupHosts map[string]bool
+	// maps instance group id forcing dispersion to max number of its guests on one host
+	Granularity map[string]int
+	// maps guest id to instance groups forcing dispersion it belongs to
+	GuestGroups map[string][]string
+}
+
+// fetchGuestConstraints collects constraints of guests on hosts
+func fetchGuestConstraints(hostIds []string, guests []SGuest) (*sGuestConstraints, error) {
+	guestIds := make([]string, len(guests))
+	for i := range guests {
+		guestIds[i] = guests[i].Id
+	}
+	c := &sGuestConstraints{
+		DeviceGuests: map[string]bool{},
+		BackupHosts:  map[string]bool{},
+		Granularity:  map[string]int{},
+		GuestGroups:  map[string][]string{},
+	}
+
+	devs := []SIsolatedDevice{}
+	q := IsolatedDeviceManager.Query().In("guest_id", guestIds)
+	if err := db.FetchModelObjects(IsolatedDeviceManager, q, &devs); err != nil {
+		return nil, errors.Wrap(err, "fetch isolated devices")
+	}
+	for i := range devs {
+		c.DeviceGuests[devs[i].GuestId] = true
+	}
+
+	backups := []SGuest{}
+	q = GuestManager.Query().In("backup_host_id", hostIds)
+	if err := db.FetchModelObjects(GuestManager, q, &backups); err != nil {
+		return nil, errors.Wrap(err, "fetch backup guests")
+	}
+	for i := range backups {
+		c.BackupHosts[backups[i].BackupHostId] = true
+	}
+
+	ggs := []SGroupguest{}
+	q = GroupguestManager.Query().In("guest_id", guestIds)
+	if err := db.FetchModelObjects(GroupguestManager, q, &ggs); err != nil {
+		return nil, errors.Wrap(err, "fetch groupguests")
+	}
+	groupIds := make([]string, 0, len(ggs))
+	for i := range ggs {
+		groupIds = append(groupIds, ggs[i].GroupId)
+	}
+	groups := []SGroup{}
+	q = GroupManager.Query().In("id", groupIds).IsTrue("enabled")
+	if err := db.FetchModelObjects(GroupManager, q, &groups); err != nil {
+		return nil, errors.Wrap(err, "fetch groups")
+	}
+	for i := range groups {
+		if groups[i].ForceDispersion.IsFalse() {
+			continue
+		}
+		c.Granularity[groups[i].Id] = groups[i].Granularity
+	}
+	for i := range ggs {
+		if _, ok := c.Granularity[ggs[i].GroupId]; ok {
+			c.GuestGroups[ggs[i].GuestId] = append(c.GuestGroups[ggs[i].GuestId], ggs[i].GroupId)
+		}
+	}
+	return c, nil
+}
+
+func (c *sGuestConstraints) newLoadGuest(guest *SGuest) *sLoadGuest {
+	return &sLoadGuest{
+		Id:     guest.Id,
+		Name:   guest.Name,
+		Cpu:    float64(guest.VcpuCount),
+		Memory: float64(guest.VmemSize),
+		Groups: c.GuestGroups[guest.Id],
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+)
+
+func newTestHostLoad(id string, guests ...*sLoadGuest) sHostLoad {
+	h := sHostLoad{
+		Id:          id,
+		Name:        id,
+		CpuCapacity: 16,
+		MemCapacity: 16384,
+		Guests:      guests,
+	}
+	for _, g := range guests {
+		h.CpuUsed += g.Cpu
+		h.MemUsed += g.Memory
+	}
+	return h
+}
+
+func newTestLoadGuest(id string, groups ...string) *sLoadGuest {
+	return &sLoadGuest{
+		Id:     id,
+		Name:   id,
+		Cpu:    2,
+		Memory: 2048,
+		Groups: groups,
+	}
+}
+
+// assertPlannedStrings compares planned moves or hosts in order
+func assertPlannedStrings(t *testing.T, want, got []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	}
+}
+
+func TestHostLoadGroupCount(t *testing.T) {
+	h := newTestHostLoad("h1", newTestLoadGuest("g1", "grp1"), newTestLoadGuest("g2", "grp1", "grp2"))
+	h.PinnedGroups = []string{"grp2"}
+	h.initGroupCount()
+	if h.groupCount["grp1"] != 2 || h.groupCount["grp2"] != 2 {
+		t.Errorf("want 2 guests of each group, got %v", h.groupCount)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// getMaintenancePolicy returns how the guest is moved off a host entering
+// maintenance, set by guest metadata or defaultPolicy otherwise
+func (self *SGuest) getMaintenancePolicy(defaultPolicy string) string {
+	policy := self.GetMetadata(api.GUEST_METADATA_MAINTENANCE_POLICY, nil)
+	if utils.IsInStringArray(policy, api.HOST_MAINTENANCE_POLICIES) {
+		return policy
+	}
+	return defaultPolicy
+}
+
+// validateMaintenanceMigrate checks the guest can be moved with policy.
+// Running guests are live migrated with live policy, stopped, migrated and
+// started again with cold policy. Stopped guests are migrated either way
+func (self *SGuest) validateMaintenanceMigrate(ctx context.Context, userCred mcclient.TokenCredential, policy, preferHostId string) error {
+	if len(self.BackupHostId) > 0 {
+		return httperrors.NewNotSupportedError("guest has backup")
+	}
+	switch {
+	case self.Status == api.VM_READY:
+		return self.validateMigrate(ctx, userCred, &api.GuestMigrateInput{PreferHost: preferHostId}, nil)
+	case !utils.IsInStringArray(self.Status, []string{api.VM_RUNNING, api.VM_SUSPEND}):
+		return httperrors.NewInvalidStatusError("cannot migrate guest in status %s", self.Status)
+	case policy == api.HOST_MAINTENANCE_POLICY_LIVE:
+		return self.validateMigrate(ctx, userCred, nil, &api.GuestLiveMigrateInput{PreferHost: preferHostId})
+	case self.Status == api.VM_SUSPEND:
+		return httperrors.NewInvalidStatusError("cannot cold migrate guest in status %s", self.Status)
+	}
+	if !self.GetDriver().IsSupportMigrate() {
+		return httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.GetHypervisor())
+	}
+	return self.GetDriver().CheckMigrate(self, userCred, api.GuestMigrateInput{PreferHost: preferHostId, AutoStart: true})
+}
+
+// getMaintenanceGuests splits guests on the host into those to migrate and
+// those which can not be moved with the reason
+func (self *SHost) getMaintenanceGuests(ctx context.Context, userCred mcclient.TokenCredential, defaultPolicy, preferHostId string) ([]api.HostMaintenanceGuest, []api.HostMaintenanceGuest, error) {
+	guests, err := self.GetGuests()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "GetGuests")
+	}
+	backups := []SGuest{}
+	q := GuestManager.Query().Equals("backup_host_id", self.Id)
+	if err := db.FetchModelObjects(GuestManager, q, &backups); err != nil {
+		return nil, nil, errors.Wrap(err, "fetch backup guests")
+	}
+
+	migrates, unmoved := []api.HostMaintenanceGuest{}, []api.HostMaintenanceGuest{}
+	for i := range guests {
+		guest := &guests[i]
+		mg := api.HostMaintenanceGuest{
+			Id:     guest.Id,
+			Name:   guest.Name,
+			Policy: guest.getMaintenancePolicy(defaultPolicy),
+			Status: guest.Status,
+		}
+		if mg.Policy == api.HOST_MAINTENANCE_POLICY_NONE {
+			mg.Reason = "maintenance policy is none"
+		} else if err := guest.validateMaintenanceMigrate(ctx, userCred, mg.Policy, preferHostId); err != nil {
+			mg.Reason = err.Error()
+		}
+		if len(mg.Reason) > 0 {
+			unmoved = append(unmoved, mg)
+		} else {
+			migrates = append(migrates, mg)
+		}
+	}
+	for i := range backups {
+		unmoved = append(unmoved, api.HostMaintenanceGuest{
+			Id:     backups[i].Id,
+			Name:   backups[i].Name,
+			Status: backups[i].Status,
+			Reason: "backup of the guest is on the host",
+		})
+	}
+	return migrates, unmoved, nil
+}
+
+// setMaintenance cordons or uncordons the host, scheduler skips hosts in
+// maintenance
+func (self *SHost) setMaintenance(userCred mcclient.TokenCredential, maintenance bool, notes string) error {
+	_, err := db.Update(self, func() error {
+		self.IsMaintenance = maintenance
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update is_maintenance")
+	}
+	db.OpsLog.LogEvent(self, db.ACT_HOST_MAINTENANCE, notes, userCred)
+	if err := self.ClearSchedDescCache(); err != nil {
+		log.Errorf("host %s clear sched desc cache: %v", self.Name, err)
+	}
+	return nil
+}
+
+func (self *SHost) AllowPerformEnterMaintenance(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostEnterMaintenanceInput) bool {
+	return db.IsAdminAllowPerform(userCred, self, "enter-maintenance")
+}
+
+// 进入维护模式, 宿主机不再参与调度, 并按虚拟机的迁移方式迁出所有虚拟机
+func (self *SHost) PerformEnterMaintenance(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostEnterMaintenanceInput) (jsonutils.JSONObject, error) {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	if self.HostType != api.HOST_TYPE_HYPERVISOR {
+		return nil, httperrors.NewNotSupportedError("host type %s can't enter maintenance", self.HostType)
+	}
+	if self.IsMaintenance {
+		return nil, httperrors.NewInvalidStatusError("host %s is in maintenance", self.Name)
+	}
+	if self.HostStatus != api.HOST_ONLINE {
+		return nil, httperrors.NewInvalidStatusError("host %s is %s", self.Name, self.HostStatus)
+	}
+	if self.PowerState != api.HOST_POWER_STATE_ON {
+		return nil, httperrors.NewInvalidStatusError("host %s power state is %s", self.Name, self.PowerState)
+	}
+	if input.Parallel == 0 {
+		input.Parallel = api.HOST_MAINTENANCE_DEFAULT_PARALLEL
+	}
+	if input.Parallel < 1 || input.Parallel > api.HOST_MAINTENANCE_MAX_PARALLEL {
+		return nil, httperrors.NewOutOfRangeError("parallel should be in range 1-%d", api.HOST_MAINTENANCE_MAX_PARALLEL)
+	}
+	if len(input.DefaultPolicy) == 0 {
+		input.DefaultPolicy = api.HOST_MAINTENANCE_POLICY_LIVE
+	}
+	if !utils.IsInStringArray(input.DefaultPolicy, api.HOST_MAINTENANCE_POLICIES) {
+		return nil, httperrors.NewInputParameterError("invalid default_policy %s, want %s", input.DefaultPolicy, api.HOST_MAINTENANCE_POLICIES)
+	}
+	if len(input.PreferHost) > 0 {
+		iHost, err := HostManager.FetchByIdOrName(userCred, input.PreferHost)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(HostManager.Keyword(), input.PreferHost)
+		}
+		preferHost := iHost.(*SHost)
+		if preferHost.Id == self.Id {
+			return nil, httperrors.NewInputParameterError("prefer_host is the host entering maintenance")
+		}
+		if err := preferHost.IsAssignable(userCred); err != nil {
+			return nil, errors.Wrap(err, "IsAssignable")
+		}
+		input.PreferHost = preferHost.Id
+	}
+
+	migrates, unmoved, err := self.getMaintenanceGuests(ctx, userCred, input.DefaultPolicy, input.PreferHost)
+	if err != nil {
+		return nil, err
+	}
+	if err := self.setMaintenance(userCred, true, "enter maintenance"); err != nil {
+		return nil, err
+	}
+	params := jsonutils.NewDict()
+	params.Set("guests", jsonutils.Marshal(migrates))
+	params.Set("unmoved_guests", jsonutils.Marshal(unmoved))
+	params.Set("parallel", jsonutils.NewInt(int64(input.Parallel)))
+	params.Set("prefer_host_id", jsonutils.NewString(input.PreferHost))
+	return nil, self.StartEnterMaintenanceTask(ctx, userCred, params, "")
+}
+
+func (self *SHost) StartEnterMaintenanceTask(ctx context.Context, userCred mcclient.TokenCredential, params *jsonutils.JSONDict, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "HostEnterMaintenanceTask", self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// StartMaintenanceMigrateTask moves the guest off its host with policy,
+// notifying parentTaskId once done
+func (self *SGuest) StartMaintenanceMigrateTask(ctx context.Context, userCred mcclient.TokenCredential, policy, preferHostId, parentTaskId string) error {
+	if policy == api.HOST_MAINTENANCE_POLICY_LIVE && utils.IsInStringArray(self.Status, []string{api.VM_RUNNING, api.VM_SUSPEND}) {
+		return self.StartGuestLiveMigrateTask(ctx, userCred, self.Status, preferHostId, nil, parentTaskId)
+	}
+	if self.Status == api.VM_READY {
+		return self.StartMigrateTask(ctx, userCred, false, false, self.Status, preferHostId, parentTaskId)
+	}
+	params := jsonutils.NewDict()
+	params.Set("prefer_host_id", jsonutils.NewString(preferHostId))
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestColdMigrateTask", self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SHost) AllowPerformExitMaintenance(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostExitMaintenanceInput) bool {
+	return db.IsAdminAllowPerform(userCred, self, "exit-maintenance")
+}
+
+// 退出维护模式, 就绪检查全部通过后宿主机恢复参与调度
+func (self *SHost) PerformExitMaintenance(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostExitMaintenanceInput) (jsonutils.JSONObject, error) {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	if !self.IsMaintenance {
+		return nil, httperrors.NewInvalidStatusError("host %s is not in maintenance", self.Name)
+	}
+	readiness := self.checkReadiness(ctx, userCred)
+	if !readiness.Ready && !input.Force {
+		failed := []string{}
+		for _, check := range readiness.Checks {
+			if !check.Passed {
+				failed = append(failed, fmt.Sprintf("%s %s: %s", check.Name, check.Target, check.Reason))
+			}
+		}
+		return nil, httperrors.NewInvalidStatusError("host %s is not ready: %s", self.Name, strings.Join(failed, "; "))
+	}
+	if err := self.setMaintenance(userCred, false, "exit maintenance"); err != nil {
+		return nil, err
+	}
+	if utils.IsInStringArray(self.Status, []string{api.BAREMETAL_MAINTAINING, api.BAREMETAL_MAINTAIN_FAIL}) {
+		self.SetStatus(userCred, api.HOST_STATUS_RUNNING, "exit maintenance")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_HOST_MAINTAINING, readiness, userCred, true)
+	return jsonutils.Marshal(readiness), nil
+}
+
+func (self *SHost) AllowGetDetailsMaintenanceReadiness(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return db.IsAdminAllowGetSpec(userCred, self, "maintenance-readiness")
+}
+
+// 退出维护模式前的就绪检查
+func (self *SHost) GetDetailsMaintenanceReadiness(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.HostReadiness, error) {
+	return self.checkReadiness(ctx, userCred), nil
+}
+
+// checkReadiness checks the host is fine to run guests again: host agent is
+// online and keeps its health key in etcd, answers ping of region, and its
+// storages are online with shared file storages mounted
+func (self *SHost) checkReadiness(ctx context.Context, userCred mcclient.TokenCredential) *api.HostReadiness {
+	ret := &api.HostReadiness{Ready: true}
+	addCheck := func(name, target string, err error) {
+		check := api.HostReadinessCheck{Name: name, Target: target, Passed: err == nil}
+		if err != nil {
+			check.Reason = err.Error()
+			ret.Ready = false
+		}
+		ret.Checks = append(ret.Checks, check)
+	}
+
+	var err error
+	if self.HostStatus != api.HOST_ONLINE {
+		err = fmt.Errorf("host status is %s", self.HostStatus)
+	}
+	addCheck(api.HOST_READINESS_CHECK_HOST_STATUS, "", err)
+
+	if hostHealthChecker != nil && self.EnableHealthCheck {
+		online, err := hostHealthChecker.IsHostOnline(ctx, self.Id)
+		if err == nil && !online {
+			err = fmt.Errorf("host health key not found")
+		}
+		addCheck(api.HOST_READINESS_CHECK_HOST_HEALTH, "", err)
+	}
+
+	_, err = self.Request(ctx, userCred, "GET", "/ping", nil, nil)
+	addCheck(api.HOST_READINESS_CHECK_NETWORK, self.AccessIp, err)
+
+	for _, hs := range self.GetHoststorages() {
+		storage := hs.GetStorage()
+		if storage == nil {
+			continue
+		}
+		addCheck(api.HOST_READINESS_CHECK_STORAGE, storage.Name, self.checkStorageReadiness(ctx, userCred, &hs, storage))
+	}
+	return ret
+}
+
+func (self *SHost) checkStorageReadiness(ctx context.Context, userCred mcclient.TokenCredential, hs *SHoststorage, storage *SStorage) error {
+	if !storage.GetEnabled() {
+		return fmt.Errorf("storage is disabled")
+	}
+	if storage.Status != api.STORAGE_ONLINE {
+		return fmt.Errorf("storage status is %s", storage.Status)
+	}
+	if !utils.IsInStringArray(storage.StorageType, api.SHARED_FILE_STORAGE) || len(hs.MountPoint) == 0 {
+		return nil
+	}
+	ret, err := self.Request(ctx, userCred, "GET", "/storages/is-mount-point?mount_point="+url.QueryEscape(hs.MountPoint), nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "request is-mount-point")
+	}
+	if !jsonutils.QueryBoolean(ret, "is_mount_point", false) {
+		reason, _ := ret.GetString("error")
+		return fmt.Errorf("%s is not mounted: %s", hs.MountPoint, reason)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+func (self *SHost) SetPowerState(userCred mcclient.TokenCredential, state string, reason string) error {
+	if self.PowerState == state {
+		return nil
+	}
+	oldState := self.PowerState
+	_, err := db.Update(self, func() error {
+		self.PowerState = state
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update power_state")
+	}
+	notes := fmt.Sprintf("power state %s=>%s", oldState, state)
+	if len(reason) > 0 {
+		notes = fmt.Sprintf("%s: %s", notes, reason)
+	}
+	db.OpsLog.LogEvent(self, db.ACT_POWER_SAVE, notes, userCred)
+	return nil
+}
+
+func (self *SHost) isPowerActive() bool {
+	return !utils.IsInStringArray(self.PowerState, api.HOST_POWER_INACTIVE_STATES)
+}
+
+// validatePowerControl checks the host is a kvm host converted from a
+// baremetal, which baremetal agent can power off and on through its BMC
+func (self *SHost) validatePowerControl() (*SGuest, error) {
+	if self.HostType != api.HOST_TYPE_HYPERVISOR || !self.IsBaremetal {
+		return nil, httperrors.NewNotSupportedError("host %s is not a kvm host converted from baremetal", self.Name)
+	}
+	if !self.HasBMC() {
+		return nil, httperrors.NewNotSupportedError("host %s has no BMC credentials", self.Name)
+	}
+	server := self.GetBaremetalServer()
+	if server == nil {
+		return nil, httperrors.NewNotSupportedError("host %s has no baremetal server", self.Name)
+	}
+	return server, nil
+}
+
+// getPowerStandbyGuests returns guests which have to be moved off before
+// the host is powered off
+func (self *SHost) getPowerStandbyGuests() ([]SGuest, error) {
+	q := GuestManager.Query().Equals("host_id", self.Id).NotEquals("hypervisor", api.HYPERVISOR_BAREMETAL)
+	guests := []SGuest{}
+	if err := db.FetchModelObjects(GuestManager, q, &guests); err != nil {
+		return nil, errors.Wrap(err, "fetch guests")
+	}
+	return guests, nil
+}
+
+// startPowerStandby marks the host evacuating so scheduler skips it and live
+// migrates its guests away, to dstHosts by guest id if given, otherwise to
+// hosts chosen by scheduler. The host is powered off by syncPowerStandby
+// once it is empty
+func (self *SHost) startPowerStandby(ctx context.Context, userCred mcclient.TokenCredential, dstHosts map[string]string, reason string) error {
+	if _, err := self.validatePowerControl(); err != nil {
+		return err
+	}
+	if !self.isPowerActive() {
+		return httperrors.NewInvalidStatusError("host %s power state is %s", self.Name, self.PowerState)
+	}
+	guests, err := self.getPowerStandbyGuests()
+	if err != nil {
+		return err
+	}
+	backups, err := GuestManager.Query().Equals("backup_host_id", self.Id).CountWithError()
+	if err != nil {
+		return errors.Wrap(err, "count backup guests")
+	}
+	if backups > 0 {
+		return httperrors.NewNotSupportedError("host %s has %d backup guests", self.Name, backups)
+	}
+	inputs := make([]*api.GuestLiveMigrateInput, len(guests))
+	for i := range guests {
+		inputs[i] = &api.GuestLiveMigrateInput{PreferHost: dstHosts[guests[i].Id]}
+		if err := guests[i].validateMigrate(ctx, userCred, nil, inputs[i]); err != nil {
+			return errors.Wrapf(err, "guest %s", guests[i].Name)
+		}
+	}
+
+	if err := self.SetPowerState(userCred, api.HOST_POWER_STATE_EVACUATING, reason); err != nil {
+		return err
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_POWER_SAVE, reason, userCred, true)
+	for i := range guests {
+		guest := &guests[i]
+		err := guest.StartGuestLiveMigrateTask(ctx, userCred, guest.Status, inputs[i].PreferHost, inputs[i].SkipCpuCheck, "")
+		if err != nil {
+			log.Errorf("host %s power standby: live migrate guest %s: %v", self.Name, guest.Name, err)
+			logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_POWER_SAVE, err, userCred, false)
+		}
+	}
+	return self.syncPowerStandby(ctx, userCred)
+}
+
+// syncPowerStandby powers off an evacuating host once all its guests are
+// gone, or gives up when migrations ended with guests left on the host. A
+// host powering on becomes active when its host agent is online again
+func (self *SHost) syncPowerStandby(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if self.PowerState == api.HOST_POWER_STATE_POWERING_ON && self.HostStatus == api.HOST_ONLINE {
+		return self.SetPowerState(userCred, api.HOST_POWER_STATE_ON, "")
+	}
+	if self.PowerState != api.HOST_POWER_STATE_EVACUATING {
+		return nil
+	}
+	guests, err := self.getPowerStandbyGuests()
+	if err != nil {
+		return err
+	}
+	if len(guests) == 0 {
+		return self.StartHostPowerOffTask(ctx, userCred, "")
+	}
+	for i := range guests {
+		if utils.IsInStringArray(guests[i].Status, []string{api.VM_START_MIGRATE, api.VM_MIGRATING}) {
+			return nil
+		}
+	}
+	reason := fmt.Sprintf("%d guests left after migration", len(guests))
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_POWER_SAVE, reason, userCred, false)
+	return self.SetPowerState(userCred, api.HOST_POWER_STATE_ON, reason)
+}
+
+func (self *SHost) StartHostPowerOffTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	self.SetPowerState(userCred, api.HOST_POWER_STATE_POWERING_OFF, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "HostPowerOffTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SHost) StartHostPowerOnTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	self.SetPowerState(userCred, api.HOST_POWER_STATE_POWERING_ON, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "HostPowerOnTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SHost) AllowPerformPowerStandby(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "power-standby")
+}
+
+// 迁出虚拟机后关机待机, 待机宿主机不参与调度
+func (self *SHost) PerformPowerStandby(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	if self.HostStatus != api.HOST_ONLINE {
+		return nil, httperrors.NewInvalidStatusError("host %s is %s", self.Name, self.HostStatus)
+	}
+	return nil, self.startPowerStandby(ctx, userCred, nil, "manual")
+}
+
+func (self *SHost) AllowPerformPowerResume(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "power-resume")
+}
+
+// 开机并恢复参与调度, 也可用于取消正在迁出虚拟机的待机操作
+func (self *SHost) PerformPowerResume(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	switch self.PowerState {
+	case api.HOST_POWER_STATE_EVACUATING:
+		return nil, self.SetPowerState(userCred, api.HOST_POWER_STATE_ON, "canceled")
+	case api.HOST_POWER_STATE_STANDBY:
+		if self.HostStatus == api.HOST_ONLINE {
+			// powered on out of band
+			return nil, self.SetPowerState(userCred, api.HOST_POWER_STATE_ON, "host is online")
+		}
+		if _, err := self.validatePowerControl(); err != nil {
+			return nil, err
+		}
+		return nil, self.StartHostPowerOnTask(ctx, userCred, "")
+	}
+	return nil, httperrors.NewInvalidStatusError("host %s power state is %s", self.Name, self.PowerState)
+}
@@ -0,0 +1,267 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"sort"
+)
+
+// sPowerHost is a host whose guests are live migrated off it before going
+// to standby
+type sPowerHost struct {
+	sHostLoad
+
+	// host runs guests which cannot be live migrated
+	Pinned bool
+	// host is powered off or on its way to standby
+	Standby bool
+	// host can be powered off and on through its BMC
+	PowerControl bool
+}
+
+func (h *sPowerHost) util() float64 {
+	var cpu, mem float64
+	if h.CpuCapacity > 0 {
+		cpu = h.CpuUsed / h.CpuCapacity
+	}
+	if h.MemCapacity > 0 {
+		mem = h.MemUsed / h.MemCapacity
+	}
+	if cpu > mem {
+		return cpu
+	}
+	return mem
+}
+
+type sPowerMove struct {
+	Guest *sLoadGuest
+	Dst   *sPowerHost
+}
+
+type sPowerOff struct {
+	Host  *sPowerHost
+	Moves []sPowerMove
+}
+
+// sPowerPlanner decides which under utilized hosts can be emptied and
+// powered off, and which standby hosts have to be powered on again.
+// Utilizations are ratios in [0, 1], reserved resources are cores and MB
+// that must stay free on active hosts below MaxUtilization
+type sPowerPlanner struct {
+	Hosts []*sPowerHost
+	// maps instance group id to max number of its guests on one host
+	Granularity map[string]int
+
+	LowUtilization float64
+	MaxUtilization float64
+	MinActiveHosts int
+	MaxPowerOffs   int
+	ReserveCpu     float64
+	ReserveMem     float64
+}
+
+func (p *sPowerPlanner) init() {
+	for _, h := range p.Hosts {
+		h.initGroupCount()
+	}
+}
+
+func (p *sPowerPlanner) activeHosts() []*sPowerHost {
+	ret := []*sPowerHost{}
+	for _, h := range p.Hosts {
+		if !h.Standby {
+			ret = append(ret, h)
+		}
+	}
+	return ret
+}
+
+// utilization returns allocation ratios of cpu and memory over active hosts
+func (p *sPowerPlanner) utilization() (float64, float64) {
+	var cpuCap, memCap, cpuUsed, memUsed float64
+	for _, h := range p.activeHosts() {
+		cpuCap += h.CpuCapacity
+		memCap += h.MemCapacity
+		cpuUsed += h.CpuUsed
+		memUsed += h.MemUsed
+	}
+	var cpu, mem float64
+	if cpuCap > 0 {
+		cpu = cpuUsed / cpuCap
+	}
+	if memCap > 0 {
+		mem = memUsed / memCap
+	}
+	return cpu, mem
+}
+
+// headroom returns cpu and memory left on hosts before they reach
+// MaxUtilization
+func (p *sPowerPlanner) headroom(hosts []*sPowerHost) (float64, float64) {
+	var cpu, mem float64
+	for _, h := range hosts {
+		if c := h.CpuCapacity*p.MaxUtilization - h.CpuUsed; c > 0 {
+			cpu += c
+		}
+		if m := h.MemCapacity*p.MaxUtilization - h.MemUsed; m > 0 {
+			mem += m
+		}
+	}
+	return cpu, mem
+}
+
+func (p *sPowerPlanner) fits(h *sPowerHost, g *sLoadGuest) bool {
+	if h.CpuUsed+g.Cpu > h.CpuCapacity*p.MaxUtilization {
+		return false
+	}
+	if h.MemUsed+g.Memory > h.MemCapacity*p.MaxUtilization {
+		return false
+	}
+	for _, gid := range g.Groups {
+		if gran, ok := p.Granularity[gid]; ok && h.groupCount[gid] >= gran {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *sPowerPlanner) place(h *sPowerHost, g *sLoadGuest, sign float64) {
+	h.CpuUsed += sign * g.Cpu
+	h.MemUsed += sign * g.Memory
+	for _, gid := range g.Groups {
+		h.groupCount[gid] += int(sign)
+	}
+}
+
+// evacuate places guests of src onto the least utilized of targets, biggest
+// guests first. Placement is reverted and nil returned if any guest fits
+// nowhere
+func (p *sPowerPlanner) evacuate(src *sPowerHost, targets []*sPowerHost) []sPowerMove {
+	guests := make([]*sLoadGuest, len(src.Guests))
+	copy(guests, src.Guests)
+	sort.SliceStable(guests, func(i, j int) bool {
+		return guests[i].Memory > guests[j].Memory
+	})
+	moves := []sPowerMove{}
+	for _, g := range guests {
+		var dst *sPowerHost
+		for _, h := range targets {
+			if !p.fits(h, g) {
+				continue
+			}
+			if dst == nil || h.util() < dst.util() {
+				dst = h
+			}
+		}
+		if dst == nil {
+			for _, m := range moves {
+				p.place(m.Dst, m.Guest, -1)
+			}
+			return nil
+		}
+		p.place(dst, g, 1)
+		moves = append(moves, sPowerMove{Guest: g, Dst: dst})
+	}
+	return moves
+}
+
+// planPowerOff picks hosts under LowUtilization, least utilized first, whose
+// guests fit on remaining active hosts while those keep the reserved
+// headroom
+func (p *sPowerPlanner) planPowerOff() []sPowerOff {
+	p.init()
+	active := p.activeHosts()
+	candidates := []*sPowerHost{}
+	for _, h := range active {
+		if h.PowerControl && !h.Pinned && h.util() < p.LowUtilization {
+			candidates = append(candidates, h)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].util() < candidates[j].util()
+	})
+
+	offs := []sPowerOff{}
+	off := map[string]bool{}
+	for _, src := range candidates {
+		if len(offs) >= p.MaxPowerOffs || len(active)-len(offs)-1 < p.MinActiveHosts {
+			break
+		}
+		targets := []*sPowerHost{}
+		for _, h := range active {
+			if h != src && !off[h.Id] {
+				targets = append(targets, h)
+			}
+		}
+		moves := p.evacuate(src, targets)
+		if moves == nil {
+			continue
+		}
+		cpu, mem := p.headroom(targets)
+		if cpu < p.ReserveCpu || mem < p.ReserveMem {
+			for _, m := range moves {
+				p.place(m.Dst, m.Guest, -1)
+			}
+			continue
+		}
+		off[src.Id] = true
+		offs = append(offs, sPowerOff{Host: src, Moves: moves})
+	}
+	return offs
+}
+
+// planPowerOn picks standby hosts, biggest first, until there are
+// MinActiveHosts active hosts under MaxUtilization keeping the reserved
+// headroom again. If force is set, e.g. scheduler forecast failed, at least
+// one host is picked
+func (p *sPowerPlanner) planPowerOn(force bool) []*sPowerHost {
+	active := p.activeHosts()
+	standby := []*sPowerHost{}
+	for _, h := range p.Hosts {
+		if h.Standby && h.PowerControl {
+			standby = append(standby, h)
+		}
+	}
+	sort.SliceStable(standby, func(i, j int) bool {
+		return standby[i].MemCapacity > standby[j].MemCapacity
+	})
+
+	var cpuCap, memCap, cpuUsed, memUsed float64
+	for _, h := range active {
+		cpuCap += h.CpuCapacity
+		memCap += h.MemCapacity
+		cpuUsed += h.CpuUsed
+		memUsed += h.MemUsed
+	}
+	cpuFree, memFree := p.headroom(active)
+	ons := []*sPowerHost{}
+	satisfied := func() bool {
+		return len(active)+len(ons) >= p.MinActiveHosts &&
+			cpuUsed <= cpuCap*p.MaxUtilization && memUsed <= memCap*p.MaxUtilization &&
+			cpuFree >= p.ReserveCpu && memFree >= p.ReserveMem
+	}
+
+	for _, h := range standby {
+		if satisfied() && (!force || len(ons) > 0) {
+			break
+		}
+		cpuCap += h.CpuCapacity
+		memCap += h.MemCapacity
+		cpuFree += h.CpuCapacity * p.MaxUtilization
+		memFree += h.MemCapacity * p.MaxUtilization
+		ons = append(ons, h)
+	}
+	return ons
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+)
+
+func newTestPowerHost(id string, guests ...*sLoadGuest) *sPowerHost {
+	return &sPowerHost{
+		sHostLoad:    newTestHostLoad(id, guests...),
+		PowerControl: true,
+	}
+}
+
+func newTestPowerPlanner(hosts ...*sPowerHost) *sPowerPlanner {
+	return &sPowerPlanner{
+		Hosts:          hosts,
+		Granularity:    map[string]int{"grp": 1},
+		LowUtilization: 0.2,
+		MaxUtilization: 0.7,
+		MinActiveHosts: 1,
+		MaxPowerOffs:   1,
+	}
+}
+
+func TestPowerPlannerPowerOff(t *testing.T) {
+	g := newTestLoadGuest
+	cases := []struct {
+		name    string
+		planner *sPowerPlanner
+		want    []string
+	}{
+		{
+			name: "idle host emptied",
+			planner: newTestPowerPlanner(
+				newTestPowerHost("h1", g("g1"), g("g2")),
+				newTestPowerHost("h2", g("g3")),
+			),
+			want: []string{"h2"},
+		},
+		{
+			name: "busy hosts kept",
+			planner: newTestPowerPlanner(
+				newTestPowerHost("h1", g("g1"), g("g2")),
+				newTestPowerHost("h2", g("g3"), g("g4")),
+			),
+		},
+		{
+			// 5 guests take 10G of h1, one more exceeds 70%
+			name: "destination full",
+			planner: newTestPowerPlanner(
+				newTestPowerHost("h1", g("g1"), g("g2"), g("g3"), g("g4"), g("g5")),
+				newTestPowerHost("h2", g("g6")),
+			),
+		},
+		{
+			name: "pinned host kept",
+			planner: func() *sPowerPlanner {
+				h2 := newTestPowerHost("h2", g("g3"))
+				h2.Pinned = true
+				return newTestPowerPlanner(newTestPowerHost("h1", g("g1")), h2)
+			}(),
+			want: []string{"h1"},
+		},
+		{
+			name: "no bmc",
+			planner: func() *sPowerPlanner {
+				h1 := newTestPowerHost("h1", g("g1"))
+				h2 := newTestPowerHost("h2", g("g3"))
+				h1.PowerControl = false
+				h2.PowerControl = false
+				return newTestPowerPlanner(h1, h2)
+			}(),
+		},
+		{
+			name: "min active hosts",
+			planner: func() *sPowerPlanner {
+				p := newTestPowerPlanner(newTestPowerHost("h1", g("g1")), newTestPowerHost("h2"))
+				p.MinActiveHosts = 2
+				return p
+			}(),
+		},
+		{
+			name: "instance group dispersion",
+			planner: newTestPowerPlanner(
+				newTestPowerHost("h1", g("g1", "grp")),
+				newTestPowerHost("h2", g("g2", "grp"), g("g3"), g("g4")),
+			),
+		},
+		{
+			// a second host cannot go as the last one alone cannot keep 12 cores free
+			name: "reserve headroom",
+			planner: func() *sPowerPlanner {
+				p := newTestPowerPlanner(newTestPowerHost("h1"), newTestPowerHost("h2"), newTestPowerHost("h3"))
+				p.MaxPowerOffs = 2
+				p.ReserveCpu = 12
+				return p
+			}(),
+			want: []string{"h1"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			offs := c.planner.planPowerOff()
+			got := []string{}
+			for _, off := range offs {
+				got = append(got, off.Host.Id)
+				if len(off.Moves) != len(off.Host.Guests) {
+					t.Errorf("host %s: %d moves for %d guests", off.Host.Id, len(off.Moves), len(off.Host.Guests))
+				}
+				for _, m := range off.Moves {
+					if m.Dst == off.Host {
+						t.Errorf("guest %s moved onto its own host", m.Guest.Id)
+					}
+				}
+			}
+			assertPlannedStrings(t, c.want, got)
+		})
+	}
+}
+
+func TestPowerPlannerPowerOn(t *testing.T) {
+	g := newTestLoadGuest
+	standby := func(id string, mem float64) *sPowerHost {
+		h := newTestPowerHost(id)
+		h.Standby = true
+		h.MemCapacity = mem
+		return h
+	}
+	cases := []struct {
+		name    string
+		planner *sPowerPlanner
+		force   bool
+		want    []string
+	}{
+		{
+			name:    "enough capacity",
+			planner: newTestPowerPlanner(newTestPowerHost("h1", g("g1")), standby("h2", 16384)),
+		},
+		{
+			name:    "forecast failed",
+			planner: newTestPowerPlanner(newTestPowerHost("h1", g("g1")), standby("h2", 16384), standby("h3", 32768)),
+			force:   true,
+			want:    []string{"h3"},
+		},
+		{
+			name: "over max utilization",
+			planner: newTestPowerPlanner(
+				newTestPowerHost("h1", g("g1"), g("g2"), g("g3"), g("g4"), g("g5"), g("g6")),
+				standby("h2", 16384),
+			),
+			want: []string{"h2"},
+		},
+		{
+			name: "below min active hosts",
+			planner: func() *sPowerPlanner {
+				p := newTestPowerPlanner(newTestPowerHost("h1"), standby("h2", 16384), standby("h3", 16384))
+				p.MinActiveHosts = 3
+				return p
+			}(),
+			want: []string{"h2", "h3"},
+		},
+		{
+			name:    "no standby hosts",
+			planner: newTestPowerPlanner(newTestPowerHost("h1")),
+			force:   true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ons := c.planner.planPowerOn(c.force)
+			got := []string{}
+			for _, h := range ons {
+				got = append(got, h.Id)
+			}
+			assertPlannedStrings(t, c.want, got)
+		})
+	}
+}
@@ -0,0 +1,680 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// +onecloud:swagger-gen-model-singular=host_power_policy
+// +onecloud:swagger-gen-model-plural=host_power_policies
+type SHostPowerPolicyManager struct {
+	db.SEnabledStatusInfrasResourceBaseManager
+	SZoneResourceBaseManager
+}
+
+var HostPowerPolicyManager *SHostPowerPolicyManager
+
+func init() {
+	HostPowerPolicyManager = &SHostPowerPolicyManager{
+		SEnabledStatusInfrasResourceBaseManager: db.NewEnabledStatusInfrasResourceBaseManager(
+			SHostPowerPolicy{},
+			"host_power_policies_tbl",
+			"host_power_policy",
+			"host_power_policies",
+		),
+	}
+	HostPowerPolicyManager.SetVirtualObject(HostPowerPolicyManager)
+}
+
+// SHostPowerPolicy periodically empties under utilized kvm hosts in a zone
+// or with a schedtag by live migration and powers them off, and powers them
+// on again when active hosts run short of capacity
+type SHostPowerPolicy struct {
+	db.SEnabledStatusInfrasResourceBase
+	SZoneResourceBase
+
+	// 调度标签Id
+	SchedtagId string `width:"36" charset:"ascii" nullable:"true" list:"domain" create:"optional"`
+
+	// 执行方式
+	Mode string `width:"16" charset:"ascii" nullable:"false" default:"recommend" list:"domain" create:"optional" update:"domain"`
+
+	// 低负载阈值(百分比)
+	LowUtilization int `nullable:"false" default:"20" list:"domain" create:"optional" update:"domain"`
+	// 迁入后目标宿主机最高负载(百分比)
+	MaxUtilization int `nullable:"false" default:"70" list:"domain" create:"optional" update:"domain"`
+	// 最少保持开机的宿主机数量
+	MinActiveHosts int `nullable:"false" default:"2" list:"domain" create:"optional" update:"domain"`
+	// 每轮最多关机数
+	MaxPowerOffs int `nullable:"false" default:"1" list:"domain" create:"optional" update:"domain"`
+
+	// 预留虚拟机CPU核数
+	ReserveVcpuCount int `nullable:"false" default:"0" list:"domain" create:"optional" update:"domain"`
+	// 预留虚拟机内存大小(MB)
+	ReserveVmemSize int `nullable:"false" default:"0" list:"domain" create:"optional" update:"domain"`
+	// 预留虚拟机数量
+	ReserveCount int `nullable:"false" default:"0" list:"domain" create:"optional" update:"domain"`
+
+	// 评估间隔(分钟)
+	IntervalMinutes int `nullable:"false" default:"60" list:"domain" create:"optional" update:"domain"`
+
+	// 上次评估时间
+	LastEvaluatedAt time.Time `nullable:"true" list:"domain"`
+}
+
+// 宿主机节能策略列表
+func (manager *SHostPowerPolicyManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.HostPowerPolicyListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SZoneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.ZonalFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SZoneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.SchedtagId) > 0 {
+		schedtag, err := SchedtagManager.FetchByIdOrName(userCred, query.SchedtagId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(SchedtagManager.Keyword(), query.SchedtagId)
+		}
+		q = q.Equals("schedtag_id", schedtag.GetId())
+	}
+	if len(query.Mode) > 0 {
+		q = q.In("mode", query.Mode)
+	}
+	return q, nil
+}
+
+func (manager *SHostPowerPolicyManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.HostPowerPolicyListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SZoneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.ZonalFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SZoneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SHostPowerPolicyManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusInfrasResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SZoneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func validateHostPowerCount(name string, val, min int) error {
+	if val < min || val > 100 {
+		return httperrors.NewOutOfRangeError("%s must be in range [%d, 100]", name, min)
+	}
+	return nil
+}
+
+func validateHostPowerReserve(name string, val int) error {
+	if val < 0 {
+		return httperrors.NewOutOfRangeError("%s must not be negative", name)
+	}
+	return nil
+}
+
+func validateHostPowerPolicyMode(mode string) error {
+	if !utils.IsInStringArray(mode, api.HOST_POWER_POLICY_MODES) {
+		return httperrors.NewInputParameterError("invalid mode %q, want %s", mode, api.HOST_POWER_POLICY_MODES)
+	}
+	return nil
+}
+
+func (manager *SHostPowerPolicyManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.HostPowerPolicyCreateInput,
+) (api.HostPowerPolicyCreateInput, error) {
+	var err error
+	input.EnabledStatusInfrasResourceBaseCreateInput, err = manager.SEnabledStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusInfrasResourceBaseCreateInput)
+	if err != nil {
+		return input, err
+	}
+	if input.Enabled == nil {
+		enabled := true
+		input.Enabled = &enabled
+	}
+
+	if input.ZoneId == "" && input.SchedtagId == "" {
+		return input, httperrors.NewMissingParameterError("zone_id or schedtag_id")
+	}
+	if input.ZoneId != "" {
+		_, input.ZoneResourceInput, err = ValidateZoneResourceInput(userCred, input.ZoneResourceInput)
+		if err != nil {
+			return input, errors.Wrap(err, "ValidateZoneResourceInput")
+		}
+	}
+	if input.SchedtagId != "" {
+		_schedtag, err := validators.ValidateModel(userCred, SchedtagManager, &input.SchedtagId)
+		if err != nil {
+			return input, err
+		}
+		schedtag := _schedtag.(*SSchedtag)
+		if schedtag.ResourceType != HostManager.KeywordPlural() {
+			return input, httperrors.NewInputParameterError("schedtag %s is not for hosts", schedtag.Name)
+		}
+	}
+
+	if input.Mode == "" {
+		input.Mode = api.HOST_POWER_POLICY_MODE_RECOMMEND
+	}
+	if err := validateHostPowerPolicyMode(input.Mode); err != nil {
+		return input, err
+	}
+	if input.LowUtilization == 0 {
+		input.LowUtilization = api.HOST_POWER_DEFAULT_LOW_UTILIZATION
+	}
+	if err := validateRebalancePercent("low_utilization", input.LowUtilization); err != nil {
+		return input, err
+	}
+	if input.MaxUtilization == 0 {
+		input.MaxUtilization = api.HOST_POWER_DEFAULT_MAX_UTILIZATION
+	}
+	if err := validateRebalancePercent("max_utilization", input.MaxUtilization); err != nil {
+		return input, err
+	}
+	if input.LowUtilization >= input.MaxUtilization {
+		return input, httperrors.NewInputParameterError("low_utilization must be less than max_utilization")
+	}
+	if input.MinActiveHosts == 0 {
+		input.MinActiveHosts = api.HOST_POWER_DEFAULT_MIN_ACTIVE_HOSTS
+	}
+	if err := validateHostPowerCount("min_active_hosts", input.MinActiveHosts, 1); err != nil {
+		return input, err
+	}
+	if input.MaxPowerOffs == 0 {
+		input.MaxPowerOffs = api.HOST_POWER_DEFAULT_MAX_POWER_OFFS
+	}
+	if err := validateHostPowerCount("max_power_offs", input.MaxPowerOffs, 1); err != nil {
+		return input, err
+	}
+	for name, val := range map[string]int{
+		"reserve_vcpu_count": input.ReserveVcpuCount,
+		"reserve_vmem_size":  input.ReserveVmemSize,
+		"reserve_count":      input.ReserveCount,
+	} {
+		if err := validateHostPowerReserve(name, val); err != nil {
+			return input, err
+		}
+	}
+	if input.ReserveCount > 0 && (input.ReserveVcpuCount == 0 || input.ReserveVmemSize == 0) {
+		return input, httperrors.NewMissingParameterError("reserve_vcpu_count and reserve_vmem_size")
+	}
+	if input.IntervalMinutes == 0 {
+		input.IntervalMinutes = api.HOST_POWER_DEFAULT_INTERVAL_MINUTES
+	}
+	if err := validateRebalanceIntervalMinutes(input.IntervalMinutes); err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (policy *SHostPowerPolicy) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	policy.SEnabledStatusInfrasResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	policy.SetStatus(userCred, api.HOST_POWER_POLICY_STATUS_READY, "")
+}
+
+func (policy *SHostPowerPolicy) ValidateUpdateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.HostPowerPolicyUpdateInput,
+) (api.HostPowerPolicyUpdateInput, error) {
+	var err error
+	if input.Mode != "" {
+		if err := validateHostPowerPolicyMode(input.Mode); err != nil {
+			return input, err
+		}
+	}
+	low, max := policy.LowUtilization, policy.MaxUtilization
+	if input.LowUtilization != nil {
+		if err := validateRebalancePercent("low_utilization", *input.LowUtilization); err != nil {
+			return input, err
+		}
+		low = *input.LowUtilization
+	}
+	if input.MaxUtilization != nil {
+		if err := validateRebalancePercent("max_utilization", *input.MaxUtilization); err != nil {
+			return input, err
+		}
+		max = *input.MaxUtilization
+	}
+	if low >= max {
+		return input, httperrors.NewInputParameterError("low_utilization must be less than max_utilization")
+	}
+	if input.MinActiveHosts != nil {
+		if err := validateHostPowerCount("min_active_hosts", *input.MinActiveHosts, 1); err != nil {
+			return input, err
+		}
+	}
+	if input.MaxPowerOffs != nil {
+		if err := validateHostPowerCount("max_power_offs", *input.MaxPowerOffs, 1); err != nil {
+			return input, err
+		}
+	}
+	for name, val := range map[string]*int{
+		"reserve_vcpu_count": input.ReserveVcpuCount,
+		"reserve_vmem_size":  input.ReserveVmemSize,
+		"reserve_count":      input.ReserveCount,
+	} {
+		if val != nil {
+			if err := validateHostPowerReserve(name, *val); err != nil {
+				return input, err
+			}
+		}
+	}
+	if input.IntervalMinutes != nil {
+		if err := validateRebalanceIntervalMinutes(*input.IntervalMinutes); err != nil {
+			return input, err
+		}
+	}
+	input.EnabledStatusInfrasResourceBaseUpdateInput, err = policy.SEnabledStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusInfrasResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SEnabledStatusInfrasResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
+func (manager *SHostPowerPolicyManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.HostPowerPolicyDetails {
+	rows := make([]api.HostPowerPolicyDetails, len(objs))
+	stdRows := manager.SEnabledStatusInfrasResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	zoneRows := manager.SZoneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+
+	schedtagIds := make([]string, 0)
+	for i := range rows {
+		rows[i] = api.HostPowerPolicyDetails{
+			EnabledStatusInfrasResourceBaseDetails: stdRows[i],
+			ZoneResourceInfo:                       zoneRows[i],
+		}
+		policy := objs[i].(*SHostPowerPolicy)
+		if policy.SchedtagId != "" {
+			schedtagIds = append(schedtagIds, policy.SchedtagId)
+		}
+	}
+	schedtagNames, err := db.FetchIdNameMap2(SchedtagManager, schedtagIds)
+	if err != nil {
+		log.Errorf("fetch schedtag names: %v", err)
+	}
+	for i := range rows {
+		rows[i].Schedtag = schedtagNames[objs[i].(*SHostPowerPolicy).SchedtagId]
+	}
+	return rows
+}
+
+func (policy *SHostPowerPolicy) AllowPerformEvaluate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostPowerPolicyEvaluateInput) bool {
+	return db.IsAdminAllowPerform(userCred, policy, "evaluate")
+}
+
+// 立即评估宿主机负载并生成节能计划
+func (policy *SHostPowerPolicy) PerformEvaluate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostPowerPolicyEvaluateInput) (*api.HostPowerPlan, error) {
+	plan, err := policy.evaluate(ctx, userCred, input.DryRun)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return plan, nil
+}
+
+// getHosts returns enabled kvm hosts in scope of the policy which are either
+// online or powered off by power management
+func (policy *SHostPowerPolicy) getHosts() ([]SHost, error) {
+	q := HostManager.Query().Equals("host_type", api.HOST_TYPE_HYPERVISOR).IsTrue("enabled")
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.Equals(q.Field("host_status"), api.HOST_ONLINE),
+		sqlchemy.In(q.Field("power_state"), api.HOST_POWER_INACTIVE_STATES),
+	))
+	q = q.Filter(sqlchemy.OR(sqlchemy.IsNull(q.Field("is_maintenance")), sqlchemy.IsFalse(q.Field("is_maintenance"))))
+	if policy.ZoneId != "" {
+		q = q.Equals("zone_id", policy.ZoneId)
+	}
+	if policy.SchedtagId != "" {
+		sq := HostschedtagManager.Query("host_id").Equals("schedtag_id", policy.SchedtagId).SubQuery()
+		q = q.In("id", sq)
+	}
+	hosts := []SHost{}
+	if err := db.FetchModelObjects(HostManager, q, &hosts); err != nil {
+		return nil, errors.Wrap(err, "fetch hosts")
+	}
+	return hosts, nil
+}
+
+// newPlanner collects allocated resources of hosts in scope of the policy.
+// Hosts being evacuated or powered off are left out, hosts being powered on
+// count as empty active hosts
+func (policy *SHostPowerPolicy) newPlanner(hosts []SHost) (*sPowerPlanner, error) {
+	hostIds := make([]string, len(hosts))
+	for i := range hosts {
+		hostIds[i] = hosts[i].Id
+	}
+	guests := []SGuest{}
+	q := GuestManager.Query().In("host_id", hostIds).NotEquals("hypervisor", api.HYPERVISOR_BAREMETAL)
+	if err := db.FetchModelObjects(GuestManager, q, &guests); err != nil {
+		return nil, errors.Wrap(err, "fetch guests")
+	}
+	constraints, err := fetchGuestConstraints(hostIds, guests)
+	if err != nil {
+		return nil, err
+	}
+
+	planner := &sPowerPlanner{
+		Granularity:    constraints.Granularity,
+		LowUtilization: float64(policy.LowUtilization) / 100,
+		MaxUtilization: float64(policy.MaxUtilization) / 100,
+		MinActiveHosts: policy.MinActiveHosts,
+		MaxPowerOffs:   policy.MaxPowerOffs,
+		ReserveCpu:     float64(policy.ReserveVcpuCount * policy.ReserveCount),
+		ReserveMem:     float64(policy.ReserveVmemSize * policy.ReserveCount),
+	}
+	phosts := map[string]*sPowerHost{}
+	for i := range hosts {
+		host := &hosts[i]
+		if utils.IsInStringArray(host.PowerState, []string{api.HOST_POWER_STATE_EVACUATING, api.HOST_POWER_STATE_POWERING_OFF}) {
+			continue
+		}
+		_, err := host.validatePowerControl()
+		ph := &sPowerHost{
+			sHostLoad:    newHostLoad(host),
+			Pinned:       constraints.BackupHosts[host.Id],
+			Standby:      host.PowerState == api.HOST_POWER_STATE_STANDBY,
+			PowerControl: err == nil,
+		}
+		phosts[host.Id] = ph
+		planner.Hosts = append(planner.Hosts, ph)
+	}
+	for i := range guests {
+		guest := &guests[i]
+		ph, ok := phosts[guest.HostId]
+		if !ok {
+			continue
+		}
+		if guest.Status != api.VM_RUNNING {
+			// stopped guests do not take resources but keep the host
+			// from being powered off
+			ph.Pinned = true
+			continue
+		}
+		pg := constraints.newLoadGuest(guest)
+		ph.CpuUsed += pg.Cpu
+		ph.MemUsed += pg.Memory
+		if guest.Hypervisor != api.HYPERVISOR_KVM || guest.IsSystem || constraints.DeviceGuests[guest.Id] {
+			ph.Pinned = true
+			ph.PinnedGroups = append(ph.PinnedGroups, pg.Groups...)
+			continue
+		}
+		ph.Guests = append(ph.Guests, pg)
+	}
+	return planner, nil
+}
+
+// forecastReserve asks scheduler whether active hosts can still take
+// reserve_count guests of the reserved spec
+func (policy *SHostPowerPolicy) forecastReserve(ctx context.Context) (bool, error) {
+	if policy.ReserveCount <= 0 {
+		return true, nil
+	}
+	conf := api.NewServerConfigs()
+	conf.Hypervisor = api.HYPERVISOR_KVM
+	conf.PreferZone = policy.ZoneId
+	if policy.SchedtagId != "" {
+		conf.Schedtags = append(conf.Schedtags, &api.SchedtagConfig{
+			Id:           policy.SchedtagId,
+			Strategy:     api.STRATEGY_REQUIRE,
+			ResourceType: HostManager.KeywordPlural(),
+		})
+	}
+	input := &schedapi.ScheduleInput{
+		ServerConfig: schedapi.ServerConfig{
+			ServerConfigs: conf,
+			Ncpu:          policy.ReserveVcpuCount,
+			Memory:        policy.ReserveVmemSize,
+			Name:          fmt.Sprintf("%s-reserve", policy.Name),
+		},
+	}
+	s := auth.GetAdminSession(ctx, options.Options.Region, "")
+	input.Project = s.GetProjectId()
+	input.Domain = s.GetDomainId()
+	canCreate, _, err := modules.SchedManager.DoScheduleForecast(s, input, policy.ReserveCount)
+	if err != nil {
+		return false, errors.Wrap(err, "DoScheduleForecast")
+	}
+	return canCreate, nil
+}
+
+func (policy *SHostPowerPolicy) evaluate(ctx context.Context, userCred mcclient.TokenCredential, dryRun bool) (*api.HostPowerPlan, error) {
+	lockman.LockObject(ctx, policy)
+	defer lockman.ReleaseObject(ctx, policy)
+
+	hosts, err := policy.getHosts()
+	if err != nil {
+		return nil, err
+	}
+	plan := &api.HostPowerPlan{
+		Actions: []api.HostPowerAction{},
+	}
+	// wait for hosts in transition before taking further actions
+	inTransition := false
+	for i := range hosts {
+		switch hosts[i].PowerState {
+		case api.HOST_POWER_STATE_EVACUATING, api.HOST_POWER_STATE_POWERING_OFF, api.HOST_POWER_STATE_POWERING_ON:
+			inTransition = true
+			plan.Note = fmt.Sprintf("host %s is %s", hosts[i].Name, hosts[i].PowerState)
+		}
+	}
+
+	planner, err := policy.newPlanner(hosts)
+	if err != nil {
+		return nil, errors.Wrap(err, "newPlanner")
+	}
+	for _, h := range planner.Hosts {
+		if h.Standby {
+			plan.StandbyHostCount++
+		} else {
+			plan.ActiveHostCount++
+		}
+	}
+	cpu, mem := planner.utilization()
+	plan.CpuUtilization = cpu * 100
+	plan.MemoryUtilization = mem * 100
+	plan.ReserveSatisfied, err = policy.forecastReserve(ctx)
+	if err != nil {
+		// without forecast, rely on allocation only
+		log.Errorf("host power policy %s: %v", policy.Name, err)
+		plan.Note = fmt.Sprintf("forecast failed: %v", err)
+		plan.ReserveSatisfied = true
+	}
+
+	if inTransition {
+		return plan, nil
+	}
+	for _, h := range planner.planPowerOn(!plan.ReserveSatisfied) {
+		reason := "active hosts are short of capacity"
+		if !plan.ReserveSatisfied {
+			reason = "scheduler forecast cannot place reserved guests"
+		}
+		plan.Actions = append(plan.Actions, api.HostPowerAction{
+			HostId: h.Id,
+			Host:   h.Name,
+			Action: api.HOST_POWER_ACTION_POWER_ON,
+			Reason: reason,
+		})
+	}
+	// never power off while capacity is short
+	if len(plan.Actions) == 0 && plan.ReserveSatisfied {
+		for _, off := range planner.planPowerOff() {
+			action := api.HostPowerAction{
+				HostId:     off.Host.Id,
+				Host:       off.Host.Name,
+				Action:     api.HOST_POWER_ACTION_POWER_OFF,
+				Migrations: []api.HostPowerMigration{},
+				Reason:     fmt.Sprintf("utilization %.1f%% under %d%%", off.Host.util()*100, policy.LowUtilization),
+			}
+			for _, m := range off.Moves {
+				action.Migrations = append(action.Migrations, api.HostPowerMigration{
+					GuestId:   m.Guest.Id,
+					Guest:     m.Guest.Name,
+					DstHostId: m.Dst.Id,
+					DstHost:   m.Dst.Name,
+				})
+			}
+			plan.Actions = append(plan.Actions, action)
+		}
+	}
+	if dryRun {
+		return plan, nil
+	}
+
+	if policy.Mode == api.HOST_POWER_POLICY_MODE_AUTO {
+		for i := range plan.Actions {
+			if err := policy.execute(ctx, userCred, &plan.Actions[i]); err != nil {
+				log.Errorf("host power policy %s: %s host %s: %v", policy.Name, plan.Actions[i].Action, plan.Actions[i].Host, err)
+			}
+		}
+	}
+
+	_, err = db.Update(policy, func() error {
+		policy.LastEvaluatedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update policy")
+	}
+	db.OpsLog.LogEvent(policy, db.ACT_POWER_SAVE, plan, userCred)
+	logclient.AddActionLogWithContext(ctx, policy, logclient.ACT_POWER_SAVE, plan, userCred, true)
+	return plan, nil
+}
+
+func (policy *SHostPowerPolicy) execute(ctx context.Context, userCred mcclient.TokenCredential, action *api.HostPowerAction) error {
+	obj, err := HostManager.FetchById(action.HostId)
+	if err != nil {
+		return errors.Wrap(err, "fetch host")
+	}
+	host := obj.(*SHost)
+	lockman.LockObject(ctx, host)
+	defer lockman.ReleaseObject(ctx, host)
+
+	reason := fmt.Sprintf("host power policy %s: %s", policy.Name, action.Reason)
+	switch action.Action {
+	case api.HOST_POWER_ACTION_POWER_OFF:
+		dstHosts := map[string]string{}
+		for _, m := range action.Migrations {
+			dstHosts[m.GuestId] = m.DstHostId
+		}
+		err = host.startPowerStandby(ctx, userCred, dstHosts, reason)
+	case api.HOST_POWER_ACTION_POWER_ON:
+		if host.PowerState != api.HOST_POWER_STATE_STANDBY {
+			return httperrors.NewInvalidStatusError("host %s power state is %s", host.Name, host.PowerState)
+		}
+		err = host.StartHostPowerOnTask(ctx, userCred, "")
+	}
+	if err != nil {
+		logclient.AddActionLogWithContext(ctx, host, logclient.ACT_POWER_SAVE, err, userCred, false)
+		return err
+	}
+	return nil
+}
+
+func (policy *SHostPowerPolicy) isDue() bool {
+	return policy.LastEvaluatedAt.IsZero() ||
+		time.Since(policy.LastEvaluatedAt) >= time.Duration(policy.IntervalMinutes)*time.Minute
+}
+
+// AutoPowerSave syncs hosts being evacuated or powered on and evaluates
+// enabled policies whose interval elapsed
+func (manager *SHostPowerPolicyManager) AutoPowerSave(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	hosts := []SHost{}
+	q := HostManager.Query().In("power_state", []string{api.HOST_POWER_STATE_EVACUATING, api.HOST_POWER_STATE_POWERING_ON})
+	if err := db.FetchModelObjects(HostManager, q, &hosts); err != nil {
+		log.Errorf("fetch evacuating hosts: %v", err)
+		return
+	}
+	for i := range hosts {
+		host := &hosts[i]
+		lockman.LockObject(ctx, host)
+		err := host.syncPowerStandby(ctx, userCred)
+		lockman.ReleaseObject(ctx, host)
+		if err != nil {
+			log.Errorf("sync power standby of host %s: %v", host.Name, err)
+		}
+	}
+
+	policies := []SHostPowerPolicy{}
+	q = manager.Query().IsTrue("enabled")
+	if err := db.FetchModelObjects(manager, q, &policies); err != nil {
+		log.Errorf("fetch host power policies: %v", err)
+		return
+	}
+	for i := range policies {
+		policy := &policies[i]
+		if !policy.isDue() {
+			continue
+		}
+		if _, err := policy.evaluate(ctx, userCred, false); err != nil {
+			log.Errorf("host power policy %s(%s): %v", policy.Name, policy.Id, err)
+			logclient.AddActionLogWithContext(ctx, policy, logclient.ACT_POWER_SAVE, err, userCred, false)
+		}
+	}
+}
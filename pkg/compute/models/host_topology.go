@@ -0,0 +1,94 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"sort"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+// GetTopologyDomains returns topology domain of hosts by topology key of
+// spread constraints.  Hosts having no value of the key are left out
+func (manager *SHostManager) GetTopologyDomains(topologyKey string, hostIds []string) (map[string]string, error) {
+	ret := make(map[string]string, len(hostIds))
+	if len(hostIds) == 0 {
+		return ret, nil
+	}
+	switch {
+	case topologyKey == api.TOPOLOGY_KEY_HOST:
+		for _, id := range hostIds {
+			ret[id] = id
+		}
+	case topologyKey == api.TOPOLOGY_KEY_ZONE || topologyKey == api.TOPOLOGY_KEY_RACK:
+		hosts := []SHost{}
+		q := manager.Query().In("id", hostIds)
+		if err := db.FetchModelObjects(manager, q, &hosts); err != nil {
+			return nil, errors.Wrap(err, "fetch hosts")
+		}
+		for i := range hosts {
+			domain := hosts[i].ZoneId
+			if topologyKey == api.TOPOLOGY_KEY_RACK {
+				domain = hosts[i].Rack
+			}
+			if len(domain) > 0 {
+				ret[hosts[i].Id] = domain
+			}
+		}
+	case strings.HasPrefix(topologyKey, api.TOPOLOGY_KEY_SCHEDTAG_PREFIX):
+		prefix := strings.TrimPrefix(topologyKey, api.TOPOLOGY_KEY_SCHEDTAG_PREFIX)
+		schedtags := SchedtagManager.Query().SubQuery()
+		hostschedtags := HostschedtagManager.Query().SubQuery()
+		q := hostschedtags.Query(hostschedtags.Field("host_id"), schedtags.Field("name"))
+		q = q.Join(schedtags, sqlchemy.Equals(hostschedtags.Field("schedtag_id"), schedtags.Field("id")))
+		q = q.Filter(sqlchemy.In(hostschedtags.Field("host_id"), hostIds))
+		q = q.Filter(sqlchemy.Startswith(schedtags.Field("name"), prefix))
+		tags := []struct {
+			HostId string
+			Name   string
+		}{}
+		if err := q.All(&tags); err != nil {
+			return nil, errors.Wrap(err, "query host schedtags")
+		}
+		// a host tagged by several schedtags of the prefix belongs to the
+		// first one by name
+		sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+		for _, tag := range tags {
+			if _, ok := ret[tag.HostId]; !ok {
+				ret[tag.HostId] = tag.Name
+			}
+		}
+	case strings.HasPrefix(topologyKey, api.TOPOLOGY_KEY_METADATA_PREFIX):
+		key := strings.TrimPrefix(topologyKey, api.TOPOLOGY_KEY_METADATA_PREFIX)
+		metadata := []db.SMetadata{}
+		q := db.Metadata.Query().Equals("obj_type", manager.Keyword()).In("obj_id", hostIds).Equals("key", key)
+		if err := db.FetchModelObjects(db.Metadata, q, &metadata); err != nil {
+			return nil, errors.Wrap(err, "fetch host metadata")
+		}
+		for _, m := range metadata {
+			if len(m.Value) > 0 {
+				ret[m.ObjId] = m.Value
+			}
+		}
+	default:
+		return nil, errors.Errorf("unsupported topology key %q", topologyKey)
+	}
+	return ret, nil
+}
@@ -167,6 +167,10 @@ type SHost struct {
 	// 是否处于维护状态
 	IsMaintenance bool `nullable:"true" default:"false" list:"domain"`
 
+	// 节能状态, 非on状态的宿主机不参与调度
+	// example: on
+	PowerState string `width:"16" charset:"ascii" nullable:"false" default:"on" list:"domain"`
+
 	LastPingAt        time.Time ``
 	EnableHealthCheck bool      `nullable:"true" default:"false"`
 
@@ -420,6 +424,9 @@ func (manager *SHostManager) ListItemFilter(
 			q = q.IsFalse("is_maintenance")
 		}
 	}
+	if len(query.PowerState) > 0 {
+		q = q.In("power_state", query.PowerState)
+	}
 	if query.IsImport != nil {
 		if *query.IsImport {
 			q = q.IsTrue("is_import")
@@ -0,0 +1,156 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"fmt"
+	"strconv"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+// IIsolatedDeviceTopology is implemented by isolated devices whose PCIe/NUMA
+// locality is known, so that a set of devices close to each other can be picked
+type IIsolatedDeviceTopology interface {
+	GetNumaNode() int
+	GetPcieSwitch() string
+	GetPeerGroup() string
+}
+
+func (self *SIsolatedDevice) GetNumaNode() int {
+	return self.NumaNode
+}
+
+func (self *SIsolatedDevice) GetPcieSwitch() string {
+	return self.PcieSwitch
+}
+
+func (self *SIsolatedDevice) GetPeerGroup() string {
+	return self.PeerGroup
+}
+
+type sIsolatedDeviceLocalityLevel struct {
+	locality int
+	key      func(dev IIsolatedDeviceTopology) string
+}
+
+var isolatedDeviceLocalityLevels = []sIsolatedDeviceLocalityLevel{
+	{
+		locality: api.ISOLATED_DEVICE_LOCALITY_PEER,
+		key:      func(dev IIsolatedDeviceTopology) string { return dev.GetPeerGroup() },
+	},
+	{
+		locality: api.ISOLATED_DEVICE_LOCALITY_SWITCH,
+		key:      func(dev IIsolatedDeviceTopology) string { return dev.GetPcieSwitch() },
+	},
+	{
+		locality: api.ISOLATED_DEVICE_LOCALITY_NUMA,
+		key: func(dev IIsolatedDeviceTopology) string {
+			if dev.GetNumaNode() < 0 {
+				return ""
+			}
+			return strconv.Itoa(dev.GetNumaNode())
+		},
+	},
+}
+
+// SelectLocalIsolatedDevices picks count devices out of devs which share the
+// closest topology level, peer group first, then PCIe switch and NUMA node.
+// Among the groups large enough the smallest one is used, leaving larger
+// groups to larger requests. It returns indexes of the picked devices and
+// their locality, nil if devs are not enough.
+func SelectLocalIsolatedDevices(devs []IIsolatedDeviceTopology, count int) ([]int, int) {
+	if count <= 0 || len(devs) < count {
+		return nil, api.ISOLATED_DEVICE_LOCALITY_NONE
+	}
+	if count == 1 {
+		return []int{0}, api.ISOLATED_DEVICE_LOCALITY_PEER
+	}
+	for _, level := range isolatedDeviceLocalityLevels {
+		groups := map[string][]int{}
+		for i, dev := range devs {
+			key := level.key(dev)
+			if len(key) > 0 {
+				groups[key] = append(groups[key], i)
+			}
+		}
+		var best []int
+		bestKey := ""
+		for key, idxs := range groups {
+			if len(idxs) < count {
+				continue
+			}
+			if best == nil || len(idxs) < len(best) || (len(idxs) == len(best) && key < bestKey) {
+				best, bestKey = idxs, key
+			}
+		}
+		if best != nil {
+			return best[:count], level.locality
+		}
+	}
+	idxs := make([]int, count)
+	for i := range idxs {
+		idxs[i] = i
+	}
+	return idxs, api.ISOLATED_DEVICE_LOCALITY_NONE
+}
+
+func (manager *SIsolatedDeviceManager) selectLocalDevices(devs []SIsolatedDevice, count int) []SIsolatedDevice {
+	topos := make([]IIsolatedDeviceTopology, len(devs))
+	for i := range devs {
+		topos[i] = &devs[i]
+	}
+	idxs, _ := SelectLocalIsolatedDevices(topos, count)
+	ret := make([]SIsolatedDevice, 0, len(idxs))
+	for _, idx := range idxs {
+		ret = append(ret, devs[idx])
+	}
+	return ret
+}
+
+// pickLocalDevicesOnHost assigns devices close to each other to the configs
+// requesting devices by model, so that a multi-device guest does not get
+// devices across sockets
+func (manager *SIsolatedDeviceManager) pickLocalDevicesOnHost(host *SHost, devConfigs []*api.IsolatedDeviceConfig) ([]*api.IsolatedDeviceConfig, error) {
+	modelConfigs := map[string][]int{}
+	for i, devConfig := range devConfigs {
+		if len(devConfig.Id) == 0 && len(devConfig.Model) > 0 {
+			modelConfigs[devConfig.Model] = append(modelConfigs[devConfig.Model], i)
+		}
+	}
+	ret := make([]*api.IsolatedDeviceConfig, len(devConfigs))
+	copy(ret, devConfigs)
+	for model, idxs := range modelConfigs {
+		if len(idxs) < 2 {
+			continue
+		}
+		devs, err := manager.findHostUnusedByModel(model, host.Id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "findHostUnusedByModel %s", model)
+		}
+		if len(devs) < len(idxs) {
+			return nil, fmt.Errorf("Can't found %d %s model on host %s", len(idxs), model, host.Id)
+		}
+		devs = manager.selectLocalDevices(devs, len(idxs))
+		for i, idx := range idxs {
+			devConfig := *devConfigs[idx]
+			devConfig.Id = devs[i].Id
+			ret[idx] = &devConfig
+		}
+	}
+	return ret, nil
+}
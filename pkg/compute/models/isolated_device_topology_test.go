@@ -0,0 +1,74 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func newTestTopologyDevs(devs ...SIsolatedDevice) []IIsolatedDeviceTopology {
+	ret := make([]IIsolatedDeviceTopology, len(devs))
+	for i := range devs {
+		ret[i] = &devs[i]
+	}
+	return ret
+}
+
+func TestSelectLocalIsolatedDevices(t *testing.T) {
+	devs := newTestTopologyDevs(
+		SIsolatedDevice{NumaNode: 0, PcieSwitch: "02:00.0"},
+		SIsolatedDevice{NumaNode: 1, PcieSwitch: "82:00.0", PeerGroup: "peer1"},
+		SIsolatedDevice{NumaNode: 0, PcieSwitch: "02:00.0"},
+		SIsolatedDevice{NumaNode: 1, PcieSwitch: "83:00.0", PeerGroup: "peer1"},
+		SIsolatedDevice{NumaNode: 0, PcieSwitch: "05:00.0"},
+		SIsolatedDevice{NumaNode: -1},
+	)
+	tests := []struct {
+		name         string
+		count        int
+		wantIdxs     []int
+		wantLocality int
+	}{
+		{"peer group", 2, []int{1, 3}, api.ISOLATED_DEVICE_LOCALITY_PEER},
+		{"numa node", 3, []int{0, 2, 4}, api.ISOLATED_DEVICE_LOCALITY_NUMA},
+		{"across numa", 4, []int{0, 1, 2, 3}, api.ISOLATED_DEVICE_LOCALITY_NONE},
+		{"single", 1, []int{0}, api.ISOLATED_DEVICE_LOCALITY_PEER},
+		{"not enough", 7, nil, api.ISOLATED_DEVICE_LOCALITY_NONE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idxs, locality := SelectLocalIsolatedDevices(devs, tt.count)
+			if !reflect.DeepEqual(idxs, tt.wantIdxs) || locality != tt.wantLocality {
+				t.Errorf("got %v %d, want %v %d", idxs, locality, tt.wantIdxs, tt.wantLocality)
+			}
+		})
+	}
+
+	// the smallest switch large enough is used
+	devs = newTestTopologyDevs(
+		SIsolatedDevice{NumaNode: 0, PcieSwitch: "02:00.0"},
+		SIsolatedDevice{NumaNode: 0, PcieSwitch: "02:00.0"},
+		SIsolatedDevice{NumaNode: 0, PcieSwitch: "02:00.0"},
+		SIsolatedDevice{NumaNode: 1, PcieSwitch: "82:00.0"},
+		SIsolatedDevice{NumaNode: 1, PcieSwitch: "82:00.0"},
+	)
+	idxs, locality := SelectLocalIsolatedDevices(devs, 2)
+	if !reflect.DeepEqual(idxs, []int{3, 4}) || locality != api.ISOLATED_DEVICE_LOCALITY_SWITCH {
+		t.Errorf("best fit got %v %d", idxs, locality)
+	}
+}
@@ -103,6 +103,15 @@ type SIsolatedDevice struct {
 
 	// reserved storage size for isolated device, default 100G
 	ReservedStorage int `nullable:"true" default:"102400" list:"domain" update:"domain" create:"domain_optional"`
+
+	// 设备所在NUMA节点, -1表示未知
+	NumaNode int `nullable:"false" default:"-1" allow_zero:"true" list:"domain" update:"domain" create:"domain_optional"`
+
+	// 设备所在PCIe交换芯片的上行端口地址
+	PcieSwitch string `width:"16" charset:"ascii" nullable:"true" list:"domain" update:"domain" create:"domain_optional"`
+
+	// 设备所在的NVLink等点对点互联组
+	PeerGroup string `width:"64" charset:"ascii" nullable:"true" list:"domain" update:"domain" create:"domain_optional"`
 }
 
 func (manager *SIsolatedDeviceManager) AllowListItems(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
@@ -152,6 +161,12 @@ func (manager *SIsolatedDeviceManager) ValidateCreateData(ctx context.Context,
 	if input.ReservedStorage != nil && *input.ReservedStorage < 0 {
 		return input, httperrors.NewInputParameterError("reserved storage must >= 0")
 	}
+	if input.NumaNode == nil {
+		numaNode := -1
+		input.NumaNode = &numaNode
+	} else if *input.NumaNode < -1 {
+		return input, httperrors.NewInputParameterError("numa node must >= -1")
+	}
 	return input, nil
 }
 
@@ -178,6 +193,9 @@ func (self *SIsolatedDevice) ValidateUpdateData(
 	if input.ReservedStorage != nil && *input.ReservedStorage < 0 {
 		return input, httperrors.NewInputParameterError("reserved storage must >= 0")
 	}
+	if input.NumaNode != nil && *input.NumaNode < -1 {
+		return input, httperrors.NewInputParameterError("numa node must >= -1")
+	}
 	return input, nil
 }
 
@@ -602,6 +620,7 @@ func (self *SIsolatedDevice) getDesc() *api.IsolatedDeviceJsonDesc {
 		Addr:           self.Addr,
 		VendorDeviceId: self.VendorDeviceId,
 		Vendor:         self.getVendor(),
+		NumaNode:       self.NumaNode,
 	}
 }
 
@@ -764,7 +783,7 @@ func (manager *SIsolatedDeviceManager) DeleteDevicesByHost(ctx context.Context,
 
 func (manager *SIsolatedDeviceManager) GetDevsOnHost(hostId string, model string, count int) ([]SIsolatedDevice, error) {
 	devs := make([]SIsolatedDevice, 0)
-	q := manager.Query().Equals("host_id", hostId).Equals("model", model).IsNullOrEmpty("guest_id").Limit(count)
+	q := manager.Query().Equals("host_id", hostId).Equals("model", model).IsNullOrEmpty("guest_id")
 	err := db.FetchModelObjects(manager, q, &devs)
 	if err != nil {
 		return nil, err
@@ -772,7 +791,10 @@ func (manager *SIsolatedDeviceManager) GetDevsOnHost(hostId string, model string
 	if len(devs) == 0 {
 		return nil, nil
 	}
-	return devs, nil
+	if len(devs) < count {
+		return devs, nil
+	}
+	return manager.selectLocalDevices(devs, count), nil
 }
 
 func (self *SIsolatedDevice) GetUniqValues() jsonutils.JSONObject {
@@ -0,0 +1,509 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"database/sql"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/util/regutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/acme"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+)
+
+const (
+	acmeIssueTimeout         = 15 * time.Minute
+	acmeDnsRecordTTL         = 60
+	acmeDefaultRenewBefore   = 30
+	acmeFailedRetryInterval  = 24 * time.Hour
+	acmeHttpChallengeTimeout = time.Minute
+)
+
+func (lbcert *SLoadbalancerCertificate) IsAcme() bool {
+	return lbcert.CertSource == api.LB_CERT_SOURCE_ACME
+}
+
+func (lbcert *SLoadbalancerCertificate) GetAcmeDomains() []string {
+	return strings.Fields(lbcert.AcmeDomains)
+}
+
+func (man *SLoadbalancerCertificateManager) validateAcmeInput(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, input *api.LoadbalancerCertificateAcmeInput) error {
+	if len(input.AcmeDomains) == 0 {
+		return httperrors.NewMissingParameterError("acme_domains")
+	}
+	if input.AcmeChallengeType == "" {
+		input.AcmeChallengeType = api.LB_ACME_CHALLENGE_HTTP01
+	}
+	if !api.LB_ACME_CHALLENGES.Has(input.AcmeChallengeType) {
+		return httperrors.NewInputParameterError("invalid acme_challenge_type %q, want %s", input.AcmeChallengeType, api.LB_ACME_CHALLENGES)
+	}
+	seen := map[string]bool{}
+	for i, domain := range input.AcmeDomains {
+		domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+		name := domain
+		if strings.HasPrefix(domain, "*.") {
+			if input.AcmeChallengeType != api.LB_ACME_CHALLENGE_DNS01 {
+				return httperrors.NewInputParameterError("wildcard domain %s requires %s challenge", domain, api.LB_ACME_CHALLENGE_DNS01)
+			}
+			name = domain[2:]
+		}
+		if !regutils.MatchDomainName(name) {
+			return httperrors.NewInputParameterError("invalid domain %s", domain)
+		}
+		if seen[domain] {
+			return httperrors.NewDuplicateNameError("acme_domains", domain)
+		}
+		seen[domain] = true
+		input.AcmeDomains[i] = domain
+	}
+
+	switch input.AcmeChallengeType {
+	case api.LB_ACME_CHALLENGE_HTTP01:
+		if input.AcmeLoadbalancer == "" {
+			return httperrors.NewMissingParameterError("acme_loadbalancer")
+		}
+		lb, _, err := ValidateLoadbalancerResourceInput(userCred, api.LoadbalancerResourceInput{LoadbalancerId: input.AcmeLoadbalancer})
+		if err != nil {
+			return err
+		}
+		if lb.IsManaged() {
+			return httperrors.NewNotSupportedError("http-01 challenge is served by lbagent, loadbalancer %s is managed by cloud provider", lb.Name)
+		}
+		cnt, err := LoadbalancerListenerManager.Query().
+			Equals("loadbalancer_id", lb.Id).
+			Equals("listener_type", api.LB_LISTENER_TYPE_HTTP).
+			Equals("listener_port", 80).
+			IsFalse("pending_deleted").
+			CountWithError()
+		if err != nil {
+			return httperrors.NewGeneralError(err)
+		}
+		if cnt == 0 {
+			return httperrors.NewInputParameterError("loadbalancer %s has no http listener on port 80 for http-01 challenge", lb.Name)
+		}
+		input.AcmeLoadbalancer = lb.Id
+	case api.LB_ACME_CHALLENGE_DNS01:
+		input.AcmeLoadbalancer = ""
+		for _, domain := range input.AcmeDomains {
+			if _, _, err := findAcmeDnsZone(ownerId, domain); err != nil {
+				return err
+			}
+		}
+	}
+
+	if input.AcmeDirectoryUrl == "" {
+		input.AcmeDirectoryUrl = options.Options.AcmeDirectoryUrl
+	}
+	if input.AcmeDirectoryUrl == "" {
+		return httperrors.NewMissingParameterError("acme_directory_url")
+	}
+	if input.AcmeEmail != "" && !regutils.MatchEmail(input.AcmeEmail) {
+		return httperrors.NewInputParameterError("invalid acme_email %s", input.AcmeEmail)
+	}
+	if input.AcmeRenewBeforeDays < 0 {
+		return httperrors.NewInputParameterError("acme_renew_before_days must be positive")
+	}
+	if input.AcmeRenewBeforeDays == 0 {
+		input.AcmeRenewBeforeDays = acmeDefaultRenewBefore
+	}
+	return nil
+}
+
+// findAcmeDnsZone returns the dns zone with the longest suffix of the domain
+// and the record name of dns-01 challenge relative to the zone
+func findAcmeDnsZone(ownerId mcclient.IIdentityProvider, domain string) (*SDnsZone, string, error) {
+	domain = strings.TrimPrefix(domain, "*.")
+	candidates := []string{}
+	for name := domain; name != ""; {
+		candidates = append(candidates, name)
+		idx := strings.IndexByte(name, '.')
+		if idx < 0 {
+			break
+		}
+		name = name[idx+1:]
+	}
+	q := DnsZoneManager.Query().In("name", candidates)
+	q = db.SharableManagerFilterByOwner(DnsZoneManager, q, ownerId, rbacutils.ScopeDomain)
+	zones := []SDnsZone{}
+	if err := db.FetchModelObjects(DnsZoneManager, q, &zones); err != nil {
+		return nil, "", errors.Wrap(err, "fetch dns zones")
+	}
+	var zone *SDnsZone
+	for i := range zones {
+		if zone == nil || len(zones[i].Name) > len(zone.Name) {
+			zone = &zones[i]
+		}
+	}
+	if zone == nil {
+		return nil, "", httperrors.NewResourceNotFoundError("no dns zone for domain %s", domain)
+	}
+	name := acme.DNS01ChallengeLabel
+	if domain != zone.Name {
+		name += "." + strings.TrimSuffix(domain, "."+zone.Name)
+	}
+	return zone, name, nil
+}
+
+func (lbcert *SLoadbalancerCertificate) AllowPerformAcmeRenew(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return lbcert.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, lbcert, "acme-renew")
+}
+
+// 立即通过ACME重新签发证书
+func (lbcert *SLoadbalancerCertificate) PerformAcmeRenew(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !lbcert.IsAcme() {
+		return nil, httperrors.NewUnsupportOperationError("certificate %s is not issued by acme", lbcert.Name)
+	}
+	if lbcert.Status == api.LB_CERT_STATUS_ACME_ISSUING {
+		return nil, httperrors.NewInvalidStatusError("certificate %s is being issued", lbcert.Name)
+	}
+	return nil, lbcert.StartAcmeIssueTask(ctx, userCred, "")
+}
+
+func (lbcert *SLoadbalancerCertificate) StartAcmeIssueTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "LoadbalancerCertificateAcmeIssueTask", lbcert, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	_, err = db.Update(lbcert, func() error {
+		lbcert.AcmeLastAttemptAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update acme_last_attempt_at")
+	}
+	lbcert.SetStatus(userCred, api.LB_CERT_STATUS_ACME_ISSUING, "")
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// needAcmeRenew reports whether the certificate is about to expire.
+// Certificates failed to issue are retried at most once a day
+func (lbcert *SLoadbalancerCertificate) needAcmeRenew(now time.Time) bool {
+	switch lbcert.Status {
+	case api.LB_CERT_STATUS_ACME_ISSUING:
+		return false
+	case api.LB_CERT_STATUS_ACME_FAILED:
+		return now.Sub(lbcert.AcmeLastAttemptAt) >= acmeFailedRetryInterval
+	}
+	if !lbcert.IsComplete() {
+		return true
+	}
+	renewBefore := time.Duration(lbcert.AcmeRenewBeforeDays) * 24 * time.Hour
+	return now.Add(renewBefore).After(lbcert.NotAfter)
+}
+
+func (man *SLoadbalancerCertificateManager) AutoRenewAcmeCertificates(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	lbcerts := []SLoadbalancerCertificate{}
+	q := man.Query().Equals("cert_source", api.LB_CERT_SOURCE_ACME).IsFalse("pending_deleted")
+	if err := db.FetchModelObjects(man, q, &lbcerts); err != nil {
+		log.Errorf("fetch acme certificates: %v", err)
+		return
+	}
+	now := time.Now()
+	for i := range lbcerts {
+		lbcert := &lbcerts[i]
+		if !lbcert.needAcmeRenew(now) {
+			continue
+		}
+		if err := lbcert.StartAcmeIssueTask(ctx, userCred, ""); err != nil {
+			log.Errorf("renew acme certificate %s(%s): %v", lbcert.Name, lbcert.Id, err)
+		}
+	}
+}
+
+// findAcmeAccount returns another certificate of the domain holding a
+// registered account of the same acme server and email, nil if none
+func (lbcert *SLoadbalancerCertificate) findAcmeAccount() (*SLoadbalancerCertificate, error) {
+	q := LoadbalancerCertificateManager.Query().
+		Equals("domain_id", lbcert.DomainId).
+		Equals("acme_directory_url", lbcert.AcmeDirectoryUrl).
+		Equals("acme_email", lbcert.AcmeEmail).
+		IsNotEmpty("acme_account_key").
+		IsNotEmpty("acme_account_url").
+		NotEquals("id", lbcert.Id).
+		Asc("created_at")
+	account := &SLoadbalancerCertificate{}
+	account.SetModelManager(LoadbalancerCertificateManager, account)
+	err := q.First(account)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "query acme account")
+	}
+	return account, nil
+}
+
+func (lbcert *SLoadbalancerCertificate) getAcmeClient(ctx context.Context) (*acme.Client, error) {
+	var (
+		key *ecdsa.PrivateKey
+		err error
+	)
+	if lbcert.AcmeAccountKey == "" {
+		// share the account registered by certificates of the same acme
+		// server and email, instead of registering one per certificate
+		account, err := lbcert.findAcmeAccount()
+		if err != nil {
+			return nil, err
+		}
+		if account != nil {
+			_, err = db.Update(lbcert, func() error {
+				lbcert.AcmeAccountKey = account.AcmeAccountKey
+				lbcert.AcmeAccountUrl = account.AcmeAccountUrl
+				return nil
+			})
+			if err != nil {
+				return nil, errors.Wrap(err, "save acme account")
+			}
+		}
+	}
+	if lbcert.AcmeAccountKey != "" {
+		key, err = acme.ParseAccountKey(lbcert.AcmeAccountKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "ParseAccountKey")
+		}
+	} else {
+		key, err = acme.GenerateAccountKey()
+		if err != nil {
+			return nil, err
+		}
+		keyPEM, err := acme.MarshalAccountKey(key)
+		if err != nil {
+			return nil, err
+		}
+		_, err = db.Update(lbcert, func() error {
+			lbcert.AcmeAccountKey = keyPEM
+			lbcert.AcmeAccountUrl = ""
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "save acme account key")
+		}
+	}
+	c := &acme.Client{
+		DirectoryURL: lbcert.AcmeDirectoryUrl,
+		Key:          key,
+		AccountURL:   lbcert.AcmeAccountUrl,
+		HTTPClient:   httputils.GetClient(options.Options.AcmeInsecureSkipVerify, acmeHttpChallengeTimeout),
+	}
+	if c.AccountURL == "" {
+		accountUrl, err := c.Register(ctx, lbcert.AcmeEmail)
+		if err != nil {
+			return nil, errors.Wrap(err, "register acme account")
+		}
+		_, err = db.Update(lbcert, func() error {
+			lbcert.AcmeAccountUrl = accountUrl
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "save acme account url")
+		}
+	}
+	return c, nil
+}
+
+// AcmeIssue gets the certificate issued by the acme server and saves it.
+// Challenges published for the order are withdrawn before return
+func (lbcert *SLoadbalancerCertificate) AcmeIssue(ctx context.Context, userCred mcclient.TokenCredential) error {
+	actx, cancel := context.WithTimeout(ctx, acmeIssueTimeout)
+	defer cancel()
+
+	c, err := lbcert.getAcmeClient(actx)
+	if err != nil {
+		return err
+	}
+	domains := lbcert.GetAcmeDomains()
+	order, err := c.NewOrder(actx, domains)
+	if err != nil {
+		return err
+	}
+
+	var (
+		authzUrls      = []string{}
+		chals          = []*acme.Challenge{}
+		httpChallenges = jsonutils.NewDict()
+		records        = []*SDnsRecordSet{}
+	)
+	defer func() {
+		lbcert.withdrawAcmeChallenges(ctx, userCred, records)
+	}()
+	for _, authzUrl := range order.Authorizations {
+		authz, err := c.GetAuthorization(actx, authzUrl)
+		if err != nil {
+			return err
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+		chal := authz.Challenge(lbcert.AcmeChallengeType)
+		if chal == nil {
+			return errors.Errorf("acme server offers no %s challenge for %s", lbcert.AcmeChallengeType, authz.Identifier.Value)
+		}
+		switch lbcert.AcmeChallengeType {
+		case api.LB_ACME_CHALLENGE_HTTP01:
+			keyAuth, err := c.KeyAuthorization(chal.Token)
+			if err != nil {
+				return err
+			}
+			httpChallenges.Set(chal.Token, jsonutils.NewString(keyAuth))
+		case api.LB_ACME_CHALLENGE_DNS01:
+			value, err := c.DNS01ChallengeRecord(chal.Token)
+			if err != nil {
+				return err
+			}
+			record, err := lbcert.addAcmeDnsRecord(ctx, userCred, authz.Identifier.Value, value)
+			if err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		authzUrls = append(authzUrls, authzUrl)
+		chals = append(chals, chal)
+	}
+
+	if len(chals) > 0 {
+		if httpChallenges.Length() > 0 {
+			_, err := db.Update(lbcert, func() error {
+				lbcert.AcmeHttpChallenges = httpChallenges
+				return nil
+			})
+			if err != nil {
+				return errors.Wrap(err, "publish http challenges")
+			}
+		}
+		// give lbagents and dns servers time to pick up the challenges
+		select {
+		case <-actx.Done():
+			return actx.Err()
+		case <-time.After(time.Duration(options.Options.AcmeChallengeWaitSeconds) * time.Second):
+		}
+		for i := range chals {
+			if err := c.Accept(actx, chals[i]); err != nil {
+				return err
+			}
+		}
+		for _, authzUrl := range authzUrls {
+			if _, err := c.WaitAuthorization(actx, authzUrl); err != nil {
+				return err
+			}
+		}
+	}
+
+	order, err = c.WaitOrder(actx, order.URL)
+	if err != nil {
+		return err
+	}
+	if order.Status != acme.StatusReady {
+		return errors.Errorf("acme order %s, want %s", order.Status, acme.StatusReady)
+	}
+	csr, keyPEM, err := acme.NewCertificateRequest(domains)
+	if err != nil {
+		return err
+	}
+	order, err = c.Finalize(actx, order, csr)
+	if err != nil {
+		return err
+	}
+	certPEM, err := c.FetchCert(actx, order.Certificate)
+	if err != nil {
+		return err
+	}
+	return lbcert.setAcmeCertificate(ctx, userCred, string(certPEM), keyPEM)
+}
+
+func (lbcert *SLoadbalancerCertificate) addAcmeDnsRecord(ctx context.Context, userCred mcclient.TokenCredential, domain, value string) (*SDnsRecordSet, error) {
+	zone, name, err := findAcmeDnsZone(lbcert.GetOwnerId(), domain)
+	if err != nil {
+		return nil, err
+	}
+	record := &SDnsRecordSet{}
+	record.SetModelManager(DnsRecordSetManager, record)
+	record.DnsZoneId = zone.Id
+	record.Name = name
+	record.Status = api.DNS_RECORDSET_STATUS_AVAILABLE
+	record.Enabled = tristate.True
+	record.DnsType = "TXT"
+	record.DnsValue = value
+	record.TTL = acmeDnsRecordTTL
+	record.Description = "acme challenge of loadbalancer certificate " + lbcert.Id
+	if err := DnsRecordSetManager.TableSpec().Insert(ctx, record); err != nil {
+		return nil, errors.Wrapf(err, "insert dns record %s.%s", name, zone.Name)
+	}
+	zone.DoSyncRecords(ctx, userCred)
+	return record, nil
+}
+
+func (lbcert *SLoadbalancerCertificate) withdrawAcmeChallenges(ctx context.Context, userCred mcclient.TokenCredential, records []*SDnsRecordSet) {
+	if lbcert.AcmeHttpChallenges != nil && lbcert.AcmeHttpChallenges.Length() > 0 {
+		_, err := db.Update(lbcert, func() error {
+			lbcert.AcmeHttpChallenges = jsonutils.NewDict()
+			return nil
+		})
+		if err != nil {
+			log.Errorf("withdraw http challenges of %s(%s): %v", lbcert.Name, lbcert.Id, err)
+		}
+	}
+	for _, record := range records {
+		if err := record.Delete(ctx, userCred); err != nil {
+			log.Errorf("delete acme dns record %s: %v", record.Name, err)
+			continue
+		}
+		if zone, err := record.GetDnsZone(); err == nil {
+			zone.DoSyncRecords(ctx, userCred)
+		}
+	}
+}
+
+// setAcmeCertificate saves the issued certificate along with attributes
+// derived from it
+func (lbcert *SLoadbalancerCertificate) setAcmeCertificate(ctx context.Context, userCred mcclient.TokenCredential, certPEM, keyPEM string) error {
+	data := jsonutils.NewDict()
+	data.Set("certificate", jsonutils.NewString(certPEM))
+	data.Set("private_key", jsonutils.NewString(keyPEM))
+	v := validators.NewCertKeyValidator("certificate", "private_key")
+	if err := v.Validate(data); err != nil {
+		return errors.Wrap(err, "validate issued certificate")
+	}
+	data = v.UpdateCertKeyInfo(ctx, data)
+	certBase := db.SCertificateResourceBase{}
+	if err := data.Unmarshal(&certBase); err != nil {
+		return errors.Wrap(err, "unmarshal certificate info")
+	}
+	diff, err := db.Update(lbcert, func() error {
+		lbcert.SCertificateResourceBase = certBase
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "save certificate")
+	}
+	db.OpsLog.LogEvent(lbcert, db.ACT_UPDATE, diff, userCred)
+	return nil
+}
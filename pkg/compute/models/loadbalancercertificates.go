@@ -22,6 +22,8 @@ import (
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
+	"strings"
+	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -73,6 +75,29 @@ type SLoadbalancerCertificate struct {
 	// SCloudregionResourceBase
 
 	db.SCertificateResourceBase
+
+	// 证书来源, upload: 用户上传, acme: 通过ACME签发并自动续期
+	CertSource string `width:"16" charset:"ascii" nullable:"false" default:"upload" list:"user" create:"optional"`
+	// ACME证书域名, 以空格分隔
+	AcmeDomains string `width:"1024" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// ACME域名验证方式
+	AcmeChallengeType string `width:"16" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// http-01验证时提供验证文件的负载均衡实例ID
+	AcmeLoadbalancerId string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// ACME服务目录地址
+	AcmeDirectoryUrl string `width:"256" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// ACME账户联系邮箱
+	AcmeEmail string `width:"128" charset:"utf8" nullable:"true" list:"user" create:"optional"`
+	// ACME账户地址
+	AcmeAccountUrl string `width:"256" charset:"ascii" nullable:"true" list:"admin"`
+	// ACME账户私钥
+	AcmeAccountKey string `nullable:"true"`
+	// 进行中的http-01验证, token到key authorization的映射, 由lbagent提供给ACME服务
+	AcmeHttpChallenges *jsonutils.JSONDict `nullable:"true" list:"admin"`
+	// 证书到期前多少天自动续期
+	AcmeRenewBeforeDays int `nullable:"false" default:"30" list:"user" create:"optional" update:"user"`
+	// 最近一次签发的时间
+	AcmeLastAttemptAt time.Time `nullable:"true" list:"user"`
 }
 
 func (lbcert *SLoadbalancerCertificate) GetCachedCerts() ([]SCachedLoadbalancerCertificate, error) {
@@ -103,6 +128,16 @@ func (lbcert *SLoadbalancerCertificate) ValidateUpdateData(ctx context.Context,
 		updateData.Set("description", jsonutils.NewString(desc))
 	}
 
+	if days, err := data.Int("acme_renew_before_days"); err == nil {
+		if !lbcert.IsAcme() {
+			return nil, httperrors.NewInputParameterError("acme_renew_before_days is only for acme certificates")
+		}
+		if days <= 0 {
+			return nil, httperrors.NewInputParameterError("acme_renew_before_days must be positive")
+		}
+		updateData.Set("acme_renew_before_days", jsonutils.NewInt(days))
+	}
+
 	input := apis.SharableVirtualResourceBaseUpdateInput{}
 	err := updateData.Unmarshal(&input)
 	if err != nil {
@@ -119,6 +154,12 @@ func (lbcert *SLoadbalancerCertificate) ValidateUpdateData(ctx context.Context,
 
 func (lbcert *SLoadbalancerCertificate) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	lbcert.SSharableVirtualResourceBase.PostCreate(ctx, userCred, ownerProjId, query, data)
+	if lbcert.IsAcme() {
+		if err := lbcert.StartAcmeIssueTask(ctx, userCred, ""); err != nil {
+			log.Errorf("StartAcmeIssueTask for %s(%s): %v", lbcert.Name, lbcert.Id, err)
+		}
+		return
+	}
 	lbcert.SetStatus(userCred, api.LB_STATUS_ENABLED, "")
 }
 
@@ -315,11 +356,45 @@ func (man *SLoadbalancerCertificateManager) QueryDistinctExtraField(q *sqlchemy.
 }
 
 func (man *SLoadbalancerCertificateManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
-	v := validators.NewCertKeyValidator("certificate", "private_key")
-	if err := v.Validate(data); err != nil {
-		return nil, err
+	acmeInput := api.LoadbalancerCertificateAcmeInput{}
+	if err := data.Unmarshal(&acmeInput); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal acme input: %v", err)
+	}
+	if acmeInput.CertSource == "" {
+		acmeInput.CertSource = api.LB_CERT_SOURCE_UPLOAD
+	}
+	if !api.LB_CERT_SOURCES.Has(acmeInput.CertSource) {
+		return nil, httperrors.NewInputParameterError("invalid cert_source %q, want %s", acmeInput.CertSource, api.LB_CERT_SOURCES)
+	}
+	if acmeInput.CertSource == api.LB_CERT_SOURCE_ACME {
+		if data.Contains("certificate") || data.Contains("private_key") {
+			return nil, httperrors.NewInputParameterError("certificate and private_key of acme certificates are issued by acme server")
+		}
+		if err := man.validateAcmeInput(ctx, userCred, ownerId, &acmeInput); err != nil {
+			return nil, err
+		}
+		data.Set("cert_source", jsonutils.NewString(acmeInput.CertSource))
+		data.Set("acme_domains", jsonutils.NewString(strings.Join(acmeInput.AcmeDomains, " ")))
+		data.Set("acme_challenge_type", jsonutils.NewString(acmeInput.AcmeChallengeType))
+		data.Set("acme_loadbalancer_id", jsonutils.NewString(acmeInput.AcmeLoadbalancer))
+		data.Set("acme_directory_url", jsonutils.NewString(acmeInput.AcmeDirectoryUrl))
+		data.Set("acme_email", jsonutils.NewString(acmeInput.AcmeEmail))
+		data.Set("acme_renew_before_days", jsonutils.NewInt(int64(acmeInput.AcmeRenewBeforeDays)))
+		// certificate and private key are required columns.  They will be
+		// filled once issued
+		data.Set("certificate", jsonutils.NewString(""))
+		data.Set("private_key", jsonutils.NewString(""))
+	} else {
+		v := validators.NewCertKeyValidator("certificate", "private_key")
+		if err := v.Validate(data); err != nil {
+			return nil, err
+		}
+		data = v.UpdateCertKeyInfo(ctx, data)
+		data.Set("cert_source", jsonutils.NewString(acmeInput.CertSource))
+		for _, k := range []string{"acme_domains", "acme_challenge_type", "acme_loadbalancer_id", "acme_directory_url", "acme_email", "acme_renew_before_days"} {
+			data.Remove(k)
+		}
 	}
-	data = v.UpdateCertKeyInfo(ctx, data)
 
 	input := apis.SharableVirtualResourceCreateInput{}
 	err := data.Unmarshal(&input)
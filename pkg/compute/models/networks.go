@@ -62,6 +62,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/util/billing"
 	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
 	"yunion.io/x/onecloud/pkg/util/rand"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
@@ -117,11 +118,16 @@ type SNetwork struct {
 
 	GuestDomain string `width:"128" charset:"ascii" nullable:"true" get:"user" update:"user"`
 
-	GuestIp6Start string `width:"64" charset:"ascii" nullable:"true"`
-	GuestIp6End   string `width:"64" charset:"ascii" nullable:"true"`
-	GuestIp6Mask  int8   `nullable:"true"`
-	GuestGateway6 string `width:"64" charset:"ascii" nullable:"true"`
-	GuestDns6     string `width:"64" charset:"ascii" nullable:"true"`
+	// IPv6起始地址
+	GuestIp6Start string `width:"64" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+	// IPv6结束地址
+	GuestIp6End string `width:"64" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+	// IPv6掩码
+	GuestIp6Mask int8 `nullable:"true" list:"user" update:"user" create:"optional"`
+	// IPv6网关地址
+	GuestGateway6 string `width:"64" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+	// IPv6 DNS
+	GuestDns6 string `width:"64" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
 
 	GuestDomain6 string `width:"128" charset:"ascii" nullable:"true"`
 
@@ -351,6 +357,68 @@ func (self *SNetwork) getIPRange() netutils.IPV4AddrRange {
 	return netutils.NewIPV4AddrRange(start, end)
 }
 
+// IsSupportIPv6 reports whether the network is dual-stack
+func (self *SNetwork) IsSupportIPv6() bool {
+	return self.GuestIp6Start != "" && self.GuestIp6End != "" && self.GuestIp6Mask > 0
+}
+
+func (self *SNetwork) getUsedIp6Addrs() []string {
+	q := GuestnetworkManager.Query("ip6_addr").Equals("network_id", self.Id).IsNotEmpty("ip6_addr")
+	results, err := q.AllStringMap()
+	if err != nil {
+		log.Errorf("getUsedIp6Addrs fail %s", err)
+		return nil
+	}
+	addrs := make([]string, 0, len(results))
+	for _, result := range results {
+		addrs = append(addrs, result["ip6_addr"])
+	}
+	return addrs
+}
+
+// GetFreeIP6 returns the requested ipv6 address if it's in range and not
+// used, or the lowest free address of the range when reqAddr is empty
+func (self *SNetwork) GetFreeIP6(reqAddr string) (string, error) {
+	startIp, err := netutils2.ParseIPv6(self.GuestIp6Start)
+	if err != nil {
+		return "", errors.Wrap(err, "GuestIp6Start")
+	}
+	endIp, err := netutils2.ParseIPv6(self.GuestIp6End)
+	if err != nil {
+		return "", errors.Wrap(err, "GuestIp6End")
+	}
+	used := make(map[string]bool)
+	for _, addr := range self.getUsedIp6Addrs() {
+		if ip, err := netutils2.ParseIPv6(addr); err == nil {
+			used[ip.String()] = true
+		}
+	}
+	if gw, err := netutils2.ParseIPv6(self.GuestGateway6); err == nil {
+		used[gw.String()] = true
+	}
+	if reqAddr != "" {
+		ip, err := netutils2.ParseIPv6(reqAddr)
+		if err != nil {
+			return "", httperrors.NewInputParameterError("invalid ipv6 address %s", reqAddr)
+		}
+		if netutils2.IPv6Compare(ip, startIp) < 0 || netutils2.IPv6Compare(ip, endIp) > 0 {
+			return "", httperrors.NewInputParameterError("ipv6 address %s not in range %s-%s", reqAddr, self.GuestIp6Start, self.GuestIp6End)
+		}
+		if used[ip.String()] {
+			return "", httperrors.NewConflictError("ipv6 address %s has been used", reqAddr)
+		}
+		return ip.String(), nil
+	}
+	// ipv6 ranges are usually too large to be scanned.  At most len(used)
+	// addresses from the start are taken
+	for ip, i := startIp, 0; i <= len(used) && netutils2.IPv6Compare(ip, endIp) <= 0; ip, i = netutils2.IPv6Add(ip, 1), i+1 {
+		if !used[ip.String()] {
+			return ip.String(), nil
+		}
+	}
+	return "", errors.Wrapf(httperrors.ErrOutOfResource, "no free ipv6 address in network %s", self.Name)
+}
+
 func isIpUsed(ipstr string, addrTable map[string]bool, recentUsedAddrTable map[string]bool) bool {
 	_, ok := addrTable[ipstr]
 	if !ok {
@@ -1284,6 +1352,61 @@ func isValidMaskLen(maskLen int64) bool {
 	}
 }
 
+func isValidIp6MaskLen(maskLen int8) bool {
+	return maskLen >= 48 && maskLen <= 126
+}
+
+// validateNetworkIp6 checks the ipv6 range of dual-stack networks and returns
+// the normalized start, end and gateway.  Networks of onecloud vpc always take
+// the 1st address of the prefix as gateway
+func validateNetworkIp6(start, end string, masklen int8, gateway, dns string, isOneCloudVpc bool) (string, string, string, error) {
+	if start == "" && end == "" {
+		if gateway != "" || dns != "" {
+			return "", "", "", httperrors.NewInputParameterError("guest_ip6_start and guest_ip6_end required")
+		}
+		return "", "", "", nil
+	}
+	if !isValidIp6MaskLen(masklen) {
+		return "", "", "", httperrors.NewInputParameterError("Invalid ipv6 masklen %d", masklen)
+	}
+	startIp, err := netutils2.ParseIPv6(start)
+	if err != nil {
+		return "", "", "", httperrors.NewInputParameterError("Invalid ipv6 start ip: %s", start)
+	}
+	endIp, err := netutils2.ParseIPv6(end)
+	if err != nil {
+		return "", "", "", httperrors.NewInputParameterError("Invalid ipv6 end ip: %s", end)
+	}
+	if netutils2.IPv6Compare(startIp, endIp) > 0 {
+		startIp, endIp = endIp, startIp
+	}
+	ipMask := net.CIDRMask(int(masklen), 128)
+	prefix := startIp.Mask(ipMask)
+	if !prefix.Equal(endIp.Mask(ipMask)) {
+		return "", "", "", httperrors.NewInputParameterError("ipv6 start and end ip not in the same subnet")
+	}
+	if isOneCloudVpc {
+		gw := netutils2.IPv6Add(prefix, 1)
+		if netutils2.IPv6Compare(startIp, gw) <= 0 {
+			startIp = netutils2.IPv6Add(gw, 1)
+		}
+		gateway = gw.String()
+	} else if gateway != "" {
+		gw, err := netutils2.ParseIPv6(gateway)
+		if err != nil {
+			return "", "", "", httperrors.NewInputParameterError("Invalid ipv6 gateway: %s", gateway)
+		}
+		if !prefix.Equal(gw.Mask(ipMask)) {
+			return "", "", "", httperrors.NewInputParameterError("ipv6 gateway must be in the same subnet as start, end ip")
+		}
+		gateway = gw.String()
+	}
+	if dns != "" && !netutils2.IsIPv6Addr(dns) {
+		return "", "", "", httperrors.NewInputParameterError("Invalid ipv6 dns: %s", dns)
+	}
+	return startIp.String(), endIp.String(), gateway, nil
+}
+
 func (self *SNetwork) ensureIfnameHint() {
 	if self.IfnameHint != "" {
 		return
@@ -1613,6 +1736,11 @@ func (manager *SNetworkManager) ValidateCreateData(ctx context.Context, userCred
 		ipEnd = brdAddr.StepDown().StepDown()
 		input.GuestGateway = gateway.String()
 	}
+	if vpc.Id != api.DEFAULT_VPC_ID {
+		if err := vpc.validatePeeringNetworkRange(networkCidrRange(ipStart, ipEnd, masklen)); err != nil {
+			return input, err
+		}
+	}
 
 	{
 		netRange := netutils.NewIPV4AddrRange(ipStart, ipEnd)
@@ -1630,6 +1758,18 @@ func (manager *SNetworkManager) ValidateCreateData(ctx context.Context, userCred
 		}
 	}
 
+	input.GuestIp6Start, input.GuestIp6End, input.GuestGateway6, err = validateNetworkIp6(
+		input.GuestIp6Start, input.GuestIp6End, input.GuestIp6Mask, input.GuestGateway6, input.GuestDns6,
+		region.Provider == api.CLOUD_PROVIDER_ONECLOUD && vpc.Id != api.DEFAULT_VPC_ID,
+	)
+	if err != nil {
+		return input, err
+	}
+	if input.GuestIp6Start == "" {
+		input.GuestIp6Mask = 0
+		input.GuestDns6 = ""
+	}
+
 	input.GuestIpStart = ipStart.String()
 	input.GuestIpEnd = ipEnd.String()
 	input.SharableVirtualResourceCreateInput, err = manager.SSharableVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.SharableVirtualResourceCreateInput)
@@ -1755,6 +1895,57 @@ func (self *SNetwork) validateUpdateData(ctx context.Context, userCred mcclient.
 	return input, nil
 }
 
+// validateUpdateIp6Data merges the ipv6 settings with the current ones and
+// makes sure assigned ipv6 addresses are still in range
+func (self *SNetwork) validateUpdateIp6Data(input api.NetworkUpdateInput) (api.NetworkUpdateInput, error) {
+	if input.GuestIp6Start == "" && input.GuestIp6End == "" && input.GuestIp6Mask == nil &&
+		input.GuestGateway6 == "" && input.GuestDns6 == "" {
+		return input, nil
+	}
+	var (
+		start   = self.GuestIp6Start
+		end     = self.GuestIp6End
+		masklen = self.GuestIp6Mask
+		gateway = self.GuestGateway6
+		dns     = self.GuestDns6
+	)
+	if input.GuestIp6Start != "" {
+		start = input.GuestIp6Start
+	}
+	if input.GuestIp6End != "" {
+		end = input.GuestIp6End
+	}
+	if input.GuestIp6Mask != nil {
+		masklen = *input.GuestIp6Mask
+	}
+	if input.GuestGateway6 != "" {
+		gateway = input.GuestGateway6
+	}
+	if input.GuestDns6 != "" {
+		dns = input.GuestDns6
+	}
+	start, end, gateway, err := validateNetworkIp6(start, end, masklen, gateway, dns, self.isOneCloudVpcNetwork())
+	if err != nil {
+		return input, err
+	}
+	startIp, _ := netutils2.ParseIPv6(start)
+	endIp, _ := netutils2.ParseIPv6(end)
+	for _, addr := range self.getUsedIp6Addrs() {
+		ip, err := netutils2.ParseIPv6(addr)
+		if err != nil {
+			continue
+		}
+		if netutils2.IPv6Compare(ip, startIp) < 0 || netutils2.IPv6Compare(ip, endIp) > 0 {
+			return input, httperrors.NewInputParameterError("IPv6 address %s been assigned out of new range", addr)
+		}
+	}
+	input.GuestIp6Start = start
+	input.GuestIp6End = end
+	input.GuestIp6Mask = &masklen
+	input.GuestGateway6 = gateway
+	return input, nil
+}
+
 func (self *SNetwork) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.NetworkUpdateInput) (api.NetworkUpdateInput, error) {
 	if !self.isManaged() && !self.isOneCloudVpcNetwork() {
 		var err error
@@ -1771,6 +1962,19 @@ func (self *SNetwork) ValidateUpdateData(ctx context.Context, userCred mcclient.
 		input.GuestDomain = ""
 		input.GuestDhcp = ""
 	}
+	if !self.isManaged() {
+		var err error
+		input, err = self.validateUpdateIp6Data(input)
+		if err != nil {
+			return input, errors.Wrap(err, "validateUpdateIp6Data")
+		}
+	} else {
+		input.GuestIp6Start = ""
+		input.GuestIp6End = ""
+		input.GuestIp6Mask = nil
+		input.GuestGateway6 = ""
+		input.GuestDns6 = ""
+	}
 
 	var err error
 	input.SharableVirtualResourceBaseUpdateInput, err = self.SSharableVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.SharableVirtualResourceBaseUpdateInput)
@@ -1878,6 +2082,9 @@ func (self *SNetwork) RealDelete(ctx context.Context, userCred mcclient.TokenCre
 	DeleteResourceJointSchedtags(self, ctx, userCred)
 	db.OpsLog.LogEvent(self, db.ACT_DELOCATE, self.GetShortDesc(ctx), userCred)
 	self.SetStatus(userCred, api.NETWORK_STATUS_DELETED, "real delete")
+	if err := FlowLogManager.cleanFlowLogs(ctx, userCred, "resource_id", self.Id); err != nil {
+		return errors.Wrap(err, "clean flow logs")
+	}
 	networkinterfaces, err := self.GetNetworkInterfaces()
 	if err != nil {
 		return errors.Wrap(err, "GetNetworkInterfaces")
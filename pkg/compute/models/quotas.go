@@ -95,6 +95,13 @@ type SQuota struct {
 	Group int `default:"-1" allow_zero:"true" json:"group"`
 	// 直通设备(GPU)配额
 	IsolatedDevice int `default:"-1" allow_zero:"true" json:"isolated_device"`
+
+	// 抢占式主机数量配额
+	PreemptibleCount int `default:"-1" allow_zero:"true" json:"preemptible_count"`
+	// 抢占式主机CPU核数量配额
+	PreemptibleCpu int `default:"-1" allow_zero:"true" json:"preemptible_cpu"`
+	// 抢占式主机内存容量配额
+	PreemptibleMemory int `default:"-1" allow_zero:"true" json:"preemptible_memory"`
 }
 
 func (self *SQuota) GetKeys() quotas.IQuotaKeys {
@@ -137,6 +144,9 @@ func (self *SQuota) FetchSystemQuota() {
 	self.Storage = defaultValue(options.Options.DefaultStorageQuota)
 	self.Group = defaultValue(options.Options.DefaultGroupQuota)
 	self.IsolatedDevice = defaultValue(options.Options.DefaultIsolatedDeviceQuota)
+	self.PreemptibleCount = defaultValue(options.Options.DefaultPreemptibleServerQuota)
+	self.PreemptibleCpu = defaultValue(options.Options.DefaultPreemptibleCpuQuota)
+	self.PreemptibleMemory = defaultValue(options.Options.DefaultPreemptibleMemoryQuota)
 }
 
 func (self *SQuota) FetchUsage(ctx context.Context) error {
@@ -190,12 +200,16 @@ func (self *SQuota) FetchUsage(ctx context.Context) error {
 
 	guest := usageTotalGuestResouceCount(scope, ownerId, rangeObjs, nil, hypervisors, false, false, nil, nil, providers, brands, keys.CloudEnv, nil)
 
-	self.Count = guest.TotalGuestCount
-	self.Cpu = guest.TotalCpuCount
-	self.Memory = guest.TotalMemSize
+	// preemptible guests are accounted separately
+	self.Count = guest.TotalGuestCount - guest.TotalPreemptibleGuestCount
+	self.Cpu = guest.TotalCpuCount - guest.TotalPreemptibleCpuCount
+	self.Memory = guest.TotalMemSize - guest.TotalPreemptibleMemSize
 	self.Storage = diskSize
 	self.Group = 0
 	self.IsolatedDevice = guest.TotalIsolatedCount
+	self.PreemptibleCount = guest.TotalPreemptibleGuestCount
+	self.PreemptibleCpu = guest.TotalPreemptibleCpuCount
+	self.PreemptibleMemory = guest.TotalPreemptibleMemSize
 	return nil
 }
 
@@ -218,6 +232,15 @@ func (self *SQuota) ResetNegative() {
 	if self.IsolatedDevice < 0 {
 		self.IsolatedDevice = 0
 	}
+	if self.PreemptibleCount < 0 {
+		self.PreemptibleCount = 0
+	}
+	if self.PreemptibleCpu < 0 {
+		self.PreemptibleCpu = 0
+	}
+	if self.PreemptibleMemory < 0 {
+		self.PreemptibleMemory = 0
+	}
 }
 
 func (self *SQuota) IsEmpty() bool {
@@ -239,6 +262,15 @@ func (self *SQuota) IsEmpty() bool {
 	if self.IsolatedDevice > 0 {
 		return false
 	}
+	if self.PreemptibleCount > 0 {
+		return false
+	}
+	if self.PreemptibleCpu > 0 {
+		return false
+	}
+	if self.PreemptibleMemory > 0 {
+		return false
+	}
 	return true
 }
 
@@ -250,6 +282,9 @@ func (self *SQuota) Add(quota quotas.IQuota) {
 	self.Storage = self.Storage + quotas.NonNegative(squota.Storage)
 	self.Group = self.Group + quotas.NonNegative(squota.Group)
 	self.IsolatedDevice = self.IsolatedDevice + quotas.NonNegative(squota.IsolatedDevice)
+	self.PreemptibleCount = self.PreemptibleCount + quotas.NonNegative(squota.PreemptibleCount)
+	self.PreemptibleCpu = self.PreemptibleCpu + quotas.NonNegative(squota.PreemptibleCpu)
+	self.PreemptibleMemory = self.PreemptibleMemory + quotas.NonNegative(squota.PreemptibleMemory)
 }
 
 func nonNegative(val int) int {
@@ -264,6 +299,9 @@ func (self *SQuota) Sub(quota quotas.IQuota) {
 	self.Storage = nonNegative(self.Storage - squota.Storage)
 	self.Group = nonNegative(self.Group - squota.Group)
 	self.IsolatedDevice = nonNegative(self.IsolatedDevice - squota.IsolatedDevice)
+	self.PreemptibleCount = nonNegative(self.PreemptibleCount - squota.PreemptibleCount)
+	self.PreemptibleCpu = nonNegative(self.PreemptibleCpu - squota.PreemptibleCpu)
+	self.PreemptibleMemory = nonNegative(self.PreemptibleMemory - squota.PreemptibleMemory)
 }
 
 func (self *SQuota) Allocable(request quotas.IQuota) int {
@@ -287,6 +325,15 @@ func (self *SQuota) Allocable(request quotas.IQuota) int {
 	if self.IsolatedDevice >= 0 && squota.IsolatedDevice > 0 && (cnt < 0 || cnt > self.IsolatedDevice/squota.IsolatedDevice) {
 		cnt = self.IsolatedDevice / squota.IsolatedDevice
 	}
+	if self.PreemptibleCount >= 0 && squota.PreemptibleCount > 0 && (cnt < 0 || cnt > self.PreemptibleCount/squota.PreemptibleCount) {
+		cnt = self.PreemptibleCount / squota.PreemptibleCount
+	}
+	if self.PreemptibleCpu >= 0 && squota.PreemptibleCpu > 0 && (cnt < 0 || cnt > self.PreemptibleCpu/squota.PreemptibleCpu) {
+		cnt = self.PreemptibleCpu / squota.PreemptibleCpu
+	}
+	if self.PreemptibleMemory >= 0 && squota.PreemptibleMemory > 0 && (cnt < 0 || cnt > self.PreemptibleMemory/squota.PreemptibleMemory) {
+		cnt = self.PreemptibleMemory / squota.PreemptibleMemory
+	}
 	return cnt
 }
 
@@ -310,6 +357,15 @@ func (self *SQuota) Update(quota quotas.IQuota) {
 	if squota.IsolatedDevice > 0 {
 		self.IsolatedDevice = squota.IsolatedDevice
 	}
+	if squota.PreemptibleCount > 0 {
+		self.PreemptibleCount = squota.PreemptibleCount
+	}
+	if squota.PreemptibleCpu > 0 {
+		self.PreemptibleCpu = squota.PreemptibleCpu
+	}
+	if squota.PreemptibleMemory > 0 {
+		self.PreemptibleMemory = squota.PreemptibleMemory
+	}
 }
 
 func (used *SQuota) Exceed(request quotas.IQuota, quota quotas.IQuota) error {
@@ -334,6 +390,15 @@ func (used *SQuota) Exceed(request quotas.IQuota, quota quotas.IQuota) error {
 	if quotas.Exceed(used.IsolatedDevice, sreq.IsolatedDevice, squota.IsolatedDevice) {
 		err.Add(used, "isolated_device", squota.IsolatedDevice, used.IsolatedDevice, sreq.IsolatedDevice)
 	}
+	if quotas.Exceed(used.PreemptibleCount, sreq.PreemptibleCount, squota.PreemptibleCount) {
+		err.Add(used, "preemptible_count", squota.PreemptibleCount, used.PreemptibleCount, sreq.PreemptibleCount)
+	}
+	if quotas.Exceed(used.PreemptibleCpu, sreq.PreemptibleCpu, squota.PreemptibleCpu) {
+		err.Add(used, "preemptible_cpu", squota.PreemptibleCpu, used.PreemptibleCpu, sreq.PreemptibleCpu)
+	}
+	if quotas.Exceed(used.PreemptibleMemory, sreq.PreemptibleMemory, squota.PreemptibleMemory) {
+		err.Add(used, "preemptible_memory", squota.PreemptibleMemory, used.PreemptibleMemory, sreq.PreemptibleMemory)
+	}
 	if err.IsError() {
 		return err
 	} else {
@@ -357,9 +422,26 @@ func (self *SQuota) ToJSON(prefix string) jsonutils.JSONObject {
 	ret.Add(jsonutils.NewInt(int64(self.Storage)), keyName(prefix, "storage"))
 	ret.Add(jsonutils.NewInt(int64(self.Group)), keyName(prefix, "group"))
 	ret.Add(jsonutils.NewInt(int64(self.IsolatedDevice)), keyName(prefix, "isolated_device"))
+	ret.Add(jsonutils.NewInt(int64(self.PreemptibleCount)), keyName(prefix, "preemptible_count"))
+	ret.Add(jsonutils.NewInt(int64(self.PreemptibleCpu)), keyName(prefix, "preemptible_cpu"))
+	ret.Add(jsonutils.NewInt(int64(self.PreemptibleMemory)), keyName(prefix, "preemptible_memory"))
 	return ret
 }
 
+// setGuestCpuMem sets count, cpu and memory of guests to the quota,
+// preemptible guests are charged to the preemptible quota instead
+func (self *SQuota) setGuestCpuMem(preemptible bool, count, cpu, mem int) {
+	if preemptible {
+		self.PreemptibleCount = count
+		self.PreemptibleCpu = cpu
+		self.PreemptibleMemory = mem
+	} else {
+		self.Count = count
+		self.Cpu = cpu
+		self.Memory = mem
+	}
+}
+
 func (manager *SQuotaManager) FetchIdNames(ctx context.Context, idMap map[string]map[string]string) (map[string]map[string]string, error) {
 	for field := range idMap {
 		switch field {
@@ -0,0 +1,240 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// sRebalanceHost is a host whose guests can be live migrated by rebalancer
+type sRebalanceHost struct {
+	sHostLoad
+}
+
+func (h *sRebalanceHost) cpuUtil() float64 {
+	if h.CpuCapacity <= 0 {
+		return 0
+	}
+	return h.CpuUsed / h.CpuCapacity
+}
+
+func (h *sRebalanceHost) memUtil() float64 {
+	if h.MemCapacity <= 0 {
+		return 0
+	}
+	return h.MemUsed / h.MemCapacity
+}
+
+type sRebalanceMove struct {
+	Guest           *sLoadGuest
+	Src             *sRebalanceHost
+	Dst             *sRebalanceHost
+	ImbalanceBefore float64
+	ImbalanceAfter  float64
+	Reason          string
+}
+
+// sRebalancePlanner greedily picks live migrations moving guests off the
+// most loaded host, each one reducing imbalance the most, until spreads of
+// host utilization fall under thresholds or maxMigrations is reached.
+// Thresholds and maxUtilization are ratios in [0, 1]
+type sRebalancePlanner struct {
+	Hosts []*sRebalanceHost
+	// maps instance group id to max number of its guests on one host
+	Granularity map[string]int
+
+	CpuThreshold   float64
+	MemThreshold   float64
+	MaxUtilization float64
+	MaxMigrations  int
+}
+
+const rebalanceMinImprovement = 1e-4
+
+func stddev(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	mean := sum / float64(len(vals))
+	var sq float64
+	for _, v := range vals {
+		sq += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sq / float64(len(vals)))
+}
+
+func (p *sRebalancePlanner) init() {
+	for _, h := range p.Hosts {
+		h.initGroupCount()
+	}
+}
+
+// imbalance is sum of standard deviations of cpu and memory utilization
+func (p *sRebalancePlanner) imbalance() float64 {
+	cpus := make([]float64, len(p.Hosts))
+	mems := make([]float64, len(p.Hosts))
+	for i, h := range p.Hosts {
+		cpus[i] = h.cpuUtil()
+		mems[i] = h.memUtil()
+	}
+	return stddev(cpus) + stddev(mems)
+}
+
+// spread returns differences between max and min of cpu and memory
+// utilization, and the hosts with max utilization
+func (p *sRebalancePlanner) spread() (float64, float64, *sRebalanceHost, *sRebalanceHost) {
+	if len(p.Hosts) == 0 {
+		return 0, 0, nil, nil
+	}
+	var (
+		cpuMax, cpuMin = p.Hosts[0], p.Hosts[0]
+		memMax, memMin = p.Hosts[0], p.Hosts[0]
+	)
+	for _, h := range p.Hosts[1:] {
+		if h.cpuUtil() > cpuMax.cpuUtil() {
+			cpuMax = h
+		}
+		if h.cpuUtil() < cpuMin.cpuUtil() {
+			cpuMin = h
+		}
+		if h.memUtil() > memMax.memUtil() {
+			memMax = h
+		}
+		if h.memUtil() < memMin.memUtil() {
+			memMin = h
+		}
+	}
+	return cpuMax.cpuUtil() - cpuMin.cpuUtil(), memMax.memUtil() - memMin.memUtil(), cpuMax, memMax
+}
+
+func (p *sRebalancePlanner) fits(g *sLoadGuest, dst *sRebalanceHost) bool {
+	if dst.CpuCapacity <= 0 || dst.MemCapacity <= 0 {
+		return false
+	}
+	if (dst.CpuUsed+g.Cpu)/dst.CpuCapacity > p.MaxUtilization {
+		return false
+	}
+	if (dst.MemUsed+g.Memory)/dst.MemCapacity > p.MaxUtilization {
+		return false
+	}
+	for _, gid := range g.Groups {
+		granularity, ok := p.Granularity[gid]
+		if !ok {
+			granularity = 1
+		}
+		if dst.groupCount[gid] >= granularity {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *sRebalancePlanner) apply(g *sLoadGuest, src, dst *sRebalanceHost) {
+	src.CpuUsed -= g.Cpu
+	src.MemUsed -= g.Memory
+	dst.CpuUsed += g.Cpu
+	dst.MemUsed += g.Memory
+	for _, gid := range g.Groups {
+		src.groupCount[gid]--
+		dst.groupCount[gid]++
+	}
+	for i := range src.Guests {
+		if src.Guests[i] == g {
+			src.Guests = append(src.Guests[:i], src.Guests[i+1:]...)
+			break
+		}
+	}
+	dst.Guests = append(dst.Guests, g)
+}
+
+// imbalanceAfter returns imbalance as if the guest were moved
+func (p *sRebalancePlanner) imbalanceAfter(g *sLoadGuest, src, dst *sRebalanceHost) float64 {
+	src.CpuUsed -= g.Cpu
+	src.MemUsed -= g.Memory
+	dst.CpuUsed += g.Cpu
+	dst.MemUsed += g.Memory
+	ret := p.imbalance()
+	src.CpuUsed += g.Cpu
+	src.MemUsed += g.Memory
+	dst.CpuUsed -= g.Cpu
+	dst.MemUsed -= g.Memory
+	return ret
+}
+
+func (p *sRebalancePlanner) plan() []sRebalanceMove {
+	p.init()
+	// visit hosts and guests in stable order so that plans are reproducible
+	sort.Slice(p.Hosts, func(i, j int) bool { return p.Hosts[i].Id < p.Hosts[j].Id })
+	for _, h := range p.Hosts {
+		sort.Slice(h.Guests, func(i, j int) bool { return h.Guests[i].Id < h.Guests[j].Id })
+	}
+
+	var (
+		moves []sRebalanceMove
+		moved = map[string]bool{}
+	)
+	for len(moves) < p.MaxMigrations {
+		cpuSpread, memSpread, cpuMax, memMax := p.spread()
+		if cpuSpread <= p.CpuThreshold && memSpread <= p.MemThreshold {
+			break
+		}
+		// drain the host standing out in the dimension exceeding its
+		// threshold the most
+		src, reason := cpuMax, fmt.Sprintf("cpu utilization spread %.0f%% exceeds %.0f%%", cpuSpread*100, p.CpuThreshold*100)
+		if memSpread-p.MemThreshold > cpuSpread-p.CpuThreshold {
+			src, reason = memMax, fmt.Sprintf("memory utilization spread %.0f%% exceeds %.0f%%", memSpread*100, p.MemThreshold*100)
+		}
+
+		before := p.imbalance()
+		var best *sRebalanceMove
+		for _, g := range src.Guests {
+			if moved[g.Id] {
+				continue
+			}
+			for _, dst := range p.Hosts {
+				if dst == src || !p.fits(g, dst) {
+					continue
+				}
+				after := p.imbalanceAfter(g, src, dst)
+				if after > before-rebalanceMinImprovement {
+					continue
+				}
+				if best == nil || after < best.ImbalanceAfter {
+					best = &sRebalanceMove{
+						Guest:           g,
+						Src:             src,
+						Dst:             dst,
+						ImbalanceBefore: before,
+						ImbalanceAfter:  after,
+						Reason:          reason,
+					}
+				}
+			}
+		}
+		if best == nil {
+			break
+		}
+		p.apply(best.Guest, best.Src, best.Dst)
+		moved[best.Guest.Id] = true
+		moves = append(moves, *best)
+	}
+	return moves
+}
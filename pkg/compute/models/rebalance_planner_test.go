@@ -0,0 +1,114 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+)
+
+func newTestRebalanceHost(id string, guests ...*sLoadGuest) *sRebalanceHost {
+	return &sRebalanceHost{sHostLoad: newTestHostLoad(id, guests...)}
+}
+
+func TestRebalancePlanner(t *testing.T) {
+	cases := []struct {
+		name    string
+		hosts   []*sRebalanceHost
+		maxMigr int
+		want    []string
+	}{
+		{
+			name: "balanced",
+			hosts: []*sRebalanceHost{
+				newTestRebalanceHost("h1", newTestLoadGuest("g1"), newTestLoadGuest("g2")),
+				newTestRebalanceHost("h2", newTestLoadGuest("g3"), newTestLoadGuest("g4")),
+			},
+			maxMigr: 5,
+		},
+		{
+			name: "drain loaded host",
+			hosts: []*sRebalanceHost{
+				newTestRebalanceHost("h1",
+					newTestLoadGuest("g1"), newTestLoadGuest("g2"),
+					newTestLoadGuest("g3"), newTestLoadGuest("g4"),
+				),
+				newTestRebalanceHost("h2"),
+			},
+			maxMigr: 5,
+			want:    []string{"g1:h1->h2", "g2:h1->h2"},
+		},
+		{
+			name: "max migrations",
+			hosts: []*sRebalanceHost{
+				newTestRebalanceHost("h1",
+					newTestLoadGuest("g1"), newTestLoadGuest("g2"),
+					newTestLoadGuest("g3"), newTestLoadGuest("g4"),
+				),
+				newTestRebalanceHost("h2"),
+			},
+			maxMigr: 1,
+			want:    []string{"g1:h1->h2"},
+		},
+		{
+			name: "anti affinity",
+			hosts: []*sRebalanceHost{
+				newTestRebalanceHost("h1",
+					newTestLoadGuest("g1", "grp"), newTestLoadGuest("g2"),
+					newTestLoadGuest("g3"), newTestLoadGuest("g4"),
+				),
+				newTestRebalanceHost("h2", newTestLoadGuest("g5", "grp")),
+			},
+			maxMigr: 5,
+			want:    []string{"g2:h1->h2"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := &sRebalancePlanner{
+				Hosts:          c.hosts,
+				CpuThreshold:   0.2,
+				MemThreshold:   0.2,
+				MaxUtilization: 0.8,
+				MaxMigrations:  c.maxMigr,
+			}
+			moves := p.plan()
+			got := []string{}
+			for _, m := range moves {
+				got = append(got, m.Guest.Id+":"+m.Src.Id+"->"+m.Dst.Id)
+				if m.ImbalanceAfter >= m.ImbalanceBefore {
+					t.Errorf("move %s does not reduce imbalance", m.Guest.Id)
+				}
+			}
+			assertPlannedStrings(t, c.want, got)
+		})
+	}
+}
+
+func TestRebalanceMetricsMissingHosts(t *testing.T) {
+	hosts := make([]SHost, 3)
+	for i, id := range []string{"h1", "h2", "h3"} {
+		hosts[i].Id = id
+		hosts[i].Name = id
+	}
+	metrics := &sRebalanceMetrics{
+		hostCpu: map[string]float64{"h1": 50, "h2": 20},
+		hostMem: map[string]float64{"h1": 1024, "h3": 2048},
+	}
+	assertPlannedStrings(t, []string{"h2", "h3"}, metrics.missingHosts(hosts))
+
+	metrics.hostCpu["h3"] = 10
+	metrics.hostMem["h2"] = 512
+	assertPlannedStrings(t, nil, metrics.missingHosts(hosts))
+}
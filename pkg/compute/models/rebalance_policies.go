@@ -0,0 +1,662 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/influxdb"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// +onecloud:swagger-gen-model-singular=rebalance_policy
+// +onecloud:swagger-gen-model-plural=rebalance_policies
+type SRebalancePolicyManager struct {
+	db.SEnabledStatusInfrasResourceBaseManager
+	SZoneResourceBaseManager
+}
+
+var RebalancePolicyManager *SRebalancePolicyManager
+
+func init() {
+	RebalancePolicyManager = &SRebalancePolicyManager{
+		SEnabledStatusInfrasResourceBaseManager: db.NewEnabledStatusInfrasResourceBaseManager(
+			SRebalancePolicy{},
+			"rebalance_policies_tbl",
+			"rebalance_policy",
+			"rebalance_policies",
+		),
+	}
+	RebalancePolicyManager.SetVirtualObject(RebalancePolicyManager)
+}
+
+// SRebalancePolicy periodically evaluates load of kvm hosts in a zone or with
+// a schedtag and live migrates guests from busy hosts to idle ones
+type SRebalancePolicy struct {
+	db.SEnabledStatusInfrasResourceBase
+	SZoneResourceBase
+
+	// 调度标签Id
+	SchedtagId string `width:"36" charset:"ascii" nullable:"true" list:"domain" create:"optional"`
+
+	// 执行方式
+	Mode string `width:"16" charset:"ascii" nullable:"false" default:"recommend" list:"domain" create:"optional" update:"domain"`
+
+	// CPU负载差值阈值(百分比)
+	CpuThreshold int `nullable:"false" default:"20" list:"domain" create:"optional" update:"domain"`
+	// 内存负载差值阈值(百分比)
+	MemoryThreshold int `nullable:"false" default:"20" list:"domain" create:"optional" update:"domain"`
+	// 迁移后目标宿主机最高负载(百分比)
+	MaxUtilization int `nullable:"false" default:"80" list:"domain" create:"optional" update:"domain"`
+	// 每轮最多迁移数
+	MaxMigrations int `nullable:"false" default:"5" list:"domain" create:"optional" update:"domain"`
+	// 是否使用监控数据计算负载
+	UseMetrics bool `nullable:"false" default:"false" list:"domain" create:"optional" update:"domain"`
+	// 评估间隔(分钟)
+	IntervalMinutes int `nullable:"false" default:"60" list:"domain" create:"optional" update:"domain"`
+
+	// 上次评估时间
+	LastEvaluatedAt time.Time `nullable:"true" list:"domain"`
+	// 上次评估时的不均衡度
+	LastImbalance float64 `nullable:"true" list:"domain"`
+}
+
+// 负载均衡策略列表
+func (manager *SRebalancePolicyManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.RebalancePolicyListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SZoneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.ZonalFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SZoneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.SchedtagId) > 0 {
+		schedtag, err := SchedtagManager.FetchByIdOrName(userCred, query.SchedtagId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(SchedtagManager.Keyword(), query.SchedtagId)
+		}
+		q = q.Equals("schedtag_id", schedtag.GetId())
+	}
+	if len(query.Mode) > 0 {
+		q = q.In("mode", query.Mode)
+	}
+	return q, nil
+}
+
+func (manager *SRebalancePolicyManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.RebalancePolicyListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SZoneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.ZonalFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SZoneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SRebalancePolicyManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusInfrasResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SZoneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func validateRebalanceMode(mode string) error {
+	if !utils.IsInStringArray(mode, api.REBALANCE_MODES) {
+		return httperrors.NewInputParameterError("invalid mode %q, want %s", mode, api.REBALANCE_MODES)
+	}
+	return nil
+}
+
+func validateRebalancePercent(name string, val int) error {
+	if val <= 0 || val > 100 {
+		return httperrors.NewOutOfRangeError("%s must be in range [1, 100]", name)
+	}
+	return nil
+}
+
+func validateRebalanceMaxMigrations(val int) error {
+	if val <= 0 || val > 100 {
+		return httperrors.NewOutOfRangeError("max_migrations must be in range [1, 100]")
+	}
+	return nil
+}
+
+func validateRebalanceIntervalMinutes(val int) error {
+	if val < 5 {
+		return httperrors.NewOutOfRangeError("interval_minutes must be at least 5")
+	}
+	return nil
+}
+
+func (manager *SRebalancePolicyManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.RebalancePolicyCreateInput,
+) (api.RebalancePolicyCreateInput, error) {
+	var err error
+	input.EnabledStatusInfrasResourceBaseCreateInput, err = manager.SEnabledStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusInfrasResourceBaseCreateInput)
+	if err != nil {
+		return input, err
+	}
+	if input.Enabled == nil {
+		enabled := true
+		input.Enabled = &enabled
+	}
+
+	if input.ZoneId == "" && input.SchedtagId == "" {
+		return input, httperrors.NewMissingParameterError("zone_id or schedtag_id")
+	}
+	if input.ZoneId != "" {
+		_, input.ZoneResourceInput, err = ValidateZoneResourceInput(userCred, input.ZoneResourceInput)
+		if err != nil {
+			return input, errors.Wrap(err, "ValidateZoneResourceInput")
+		}
+	}
+	if input.SchedtagId != "" {
+		_schedtag, err := validators.ValidateModel(userCred, SchedtagManager, &input.SchedtagId)
+		if err != nil {
+			return input, err
+		}
+		schedtag := _schedtag.(*SSchedtag)
+		if schedtag.ResourceType != HostManager.KeywordPlural() {
+			return input, httperrors.NewInputParameterError("schedtag %s is not for hosts", schedtag.Name)
+		}
+	}
+
+	if input.Mode == "" {
+		input.Mode = api.REBALANCE_MODE_RECOMMEND
+	}
+	if err := validateRebalanceMode(input.Mode); err != nil {
+		return input, err
+	}
+	if input.CpuThreshold == 0 {
+		input.CpuThreshold = api.REBALANCE_DEFAULT_CPU_THRESHOLD
+	}
+	if err := validateRebalancePercent("cpu_threshold", input.CpuThreshold); err != nil {
+		return input, err
+	}
+	if input.MemoryThreshold == 0 {
+		input.MemoryThreshold = api.REBALANCE_DEFAULT_MEMORY_THRESHOLD
+	}
+	if err := validateRebalancePercent("memory_threshold", input.MemoryThreshold); err != nil {
+		return input, err
+	}
+	if input.MaxUtilization == 0 {
+		input.MaxUtilization = api.REBALANCE_DEFAULT_MAX_UTILIZATION
+	}
+	if err := validateRebalancePercent("max_utilization", input.MaxUtilization); err != nil {
+		return input, err
+	}
+	if input.MaxMigrations == 0 {
+		input.MaxMigrations = api.REBALANCE_DEFAULT_MAX_MIGRATIONS
+	}
+	if err := validateRebalanceMaxMigrations(input.MaxMigrations); err != nil {
+		return input, err
+	}
+	if input.IntervalMinutes == 0 {
+		input.IntervalMinutes = api.REBALANCE_DEFAULT_INTERVAL_MINUTES
+	}
+	if err := validateRebalanceIntervalMinutes(input.IntervalMinutes); err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (policy *SRebalancePolicy) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	policy.SEnabledStatusInfrasResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	policy.SetStatus(userCred, api.REBALANCE_POLICY_STATUS_READY, "")
+}
+
+func (policy *SRebalancePolicy) ValidateUpdateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.RebalancePolicyUpdateInput,
+) (api.RebalancePolicyUpdateInput, error) {
+	var err error
+	if input.Mode != "" {
+		if err := validateRebalanceMode(input.Mode); err != nil {
+			return input, err
+		}
+	}
+	for name, val := range map[string]*int{
+		"cpu_threshold":    input.CpuThreshold,
+		"memory_threshold": input.MemoryThreshold,
+		"max_utilization":  input.MaxUtilization,
+	} {
+		if val != nil {
+			if err := validateRebalancePercent(name, *val); err != nil {
+				return input, err
+			}
+		}
+	}
+	if input.MaxMigrations != nil {
+		if err := validateRebalanceMaxMigrations(*input.MaxMigrations); err != nil {
+			return input, err
+		}
+	}
+	if input.IntervalMinutes != nil {
+		if err := validateRebalanceIntervalMinutes(*input.IntervalMinutes); err != nil {
+			return input, err
+		}
+	}
+	input.EnabledStatusInfrasResourceBaseUpdateInput, err = policy.SEnabledStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusInfrasResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SEnabledStatusInfrasResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
+func (policy *SRebalancePolicy) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	// keep recommendations as audit trail, only stop pending ones from
+	// being executed
+	return RebalanceRecommendationManager.expirePending(ctx, userCred, policy.Id, "rebalance policy deleted")
+}
+
+func (manager *SRebalancePolicyManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.RebalancePolicyDetails {
+	rows := make([]api.RebalancePolicyDetails, len(objs))
+	stdRows := manager.SEnabledStatusInfrasResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	zoneRows := manager.SZoneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+
+	schedtagIds := make([]string, 0)
+	for i := range rows {
+		rows[i] = api.RebalancePolicyDetails{
+			EnabledStatusInfrasResourceBaseDetails: stdRows[i],
+			ZoneResourceInfo:                       zoneRows[i],
+		}
+		policy := objs[i].(*SRebalancePolicy)
+		if policy.SchedtagId != "" {
+			schedtagIds = append(schedtagIds, policy.SchedtagId)
+		}
+	}
+	schedtagNames, err := db.FetchIdNameMap2(SchedtagManager, schedtagIds)
+	if err != nil {
+		log.Errorf("fetch schedtag names: %v", err)
+	}
+	for i := range rows {
+		rows[i].Schedtag = schedtagNames[objs[i].(*SRebalancePolicy).SchedtagId]
+	}
+	return rows
+}
+
+func (policy *SRebalancePolicy) AllowPerformEvaluate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.RebalancePolicyEvaluateInput) bool {
+	return db.IsAdminAllowPerform(userCred, policy, "evaluate")
+}
+
+// 立即评估宿主机负载并生成迁移计划
+func (policy *SRebalancePolicy) PerformEvaluate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.RebalancePolicyEvaluateInput) (*api.RebalancePlan, error) {
+	plan, err := policy.evaluate(ctx, userCred, input.DryRun)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return plan, nil
+}
+
+// getHosts returns enabled and online kvm hosts in scope of the policy
+func (policy *SRebalancePolicy) getHosts() ([]SHost, error) {
+	q := HostManager.Query().Equals("host_type", api.HOST_TYPE_HYPERVISOR).
+		IsTrue("enabled").Equals("host_status", api.HOST_ONLINE)
+	q = q.Filter(sqlchemy.OR(sqlchemy.IsNull(q.Field("is_maintenance")), sqlchemy.IsFalse(q.Field("is_maintenance"))))
+	if policy.ZoneId != "" {
+		q = q.Equals("zone_id", policy.ZoneId)
+	}
+	if policy.SchedtagId != "" {
+		sq := HostschedtagManager.Query("host_id").Equals("schedtag_id", policy.SchedtagId).SubQuery()
+		q = q.In("id", sq)
+	}
+	hosts := []SHost{}
+	if err := db.FetchModelObjects(HostManager, q, &hosts); err != nil {
+		return nil, errors.Wrap(err, "fetch hosts")
+	}
+	return hosts, nil
+}
+
+const rebalanceMetricsWindow = "30m"
+
+// sRebalanceMetrics holds mean cpu utilization in percent and memory used
+// in MB of hosts and guests in last rebalanceMetricsWindow
+type sRebalanceMetrics struct {
+	hostCpu  map[string]float64
+	hostMem  map[string]float64
+	guestCpu map[string]float64
+	guestMem map[string]float64
+}
+
+// missingHosts returns names of hosts without cpu or memory metrics
+func (m *sRebalanceMetrics) missingHosts(hosts []SHost) []string {
+	missing := []string{}
+	for i := range hosts {
+		_, cpuOk := m.hostCpu[hosts[i].Id]
+		_, memOk := m.hostMem[hosts[i].Id]
+		if !cpuOk || !memOk {
+			missing = append(missing, hosts[i].Name)
+		}
+	}
+	return missing
+}
+
+func queryRebalanceMetric(dbinst *influxdb.SInfluxdb, sql, tag string) (map[string]float64, error) {
+	res, err := dbinst.Query(sql)
+	if err != nil {
+		return nil, errors.Wrapf(err, "query %s", sql)
+	}
+	ret := map[string]float64{}
+	if len(res) == 0 {
+		return ret, nil
+	}
+	for _, series := range res[0] {
+		if series.Tags == nil || len(series.Values) == 0 || len(series.Values[0]) < 2 || series.Values[0][1] == nil {
+			continue
+		}
+		id, _ := series.Tags.GetString(tag)
+		val, err := series.Values[0][1].Float()
+		if id == "" || err != nil {
+			continue
+		}
+		ret[id] = val
+	}
+	return ret, nil
+}
+
+func fetchRebalanceMetrics() (*sRebalanceMetrics, error) {
+	url, err := auth.GetServiceURL(apis.SERVICE_TYPE_INFLUXDB, options.Options.Region, "", "")
+	if err != nil {
+		return nil, errors.Wrap(err, "get influxdb url")
+	}
+	var (
+		dbinst = influxdb.NewInfluxdb(url)
+		ret    = &sRebalanceMetrics{}
+		mb     = float64(1024 * 1024)
+	)
+	for _, m := range []struct {
+		sql   string
+		tag   string
+		dst   *map[string]float64
+		scale float64
+	}{
+		{`SELECT mean("usage_active") FROM "telegraf".."cpu" WHERE time > now() - %s AND "cpu" = 'cpu-total' GROUP BY "host_id"`, "host_id", &ret.hostCpu, 1},
+		{`SELECT mean("used") FROM "telegraf".."mem" WHERE time > now() - %s GROUP BY "host_id"`, "host_id", &ret.hostMem, mb},
+		{`SELECT mean("usage_active") FROM "telegraf".."vm_cpu" WHERE time > now() - %s GROUP BY "vm_id"`, "vm_id", &ret.guestCpu, 1},
+		{`SELECT mean("rss") FROM "telegraf".."vm_mem" WHERE time > now() - %s GROUP BY "vm_id"`, "vm_id", &ret.guestMem, mb},
+	} {
+		vals, err := queryRebalanceMetric(dbinst, fmt.Sprintf(m.sql, rebalanceMetricsWindow), m.tag)
+		if err != nil {
+			return nil, err
+		}
+		for k := range vals {
+			vals[k] /= m.scale
+		}
+		*m.dst = vals
+	}
+	return ret, nil
+}
+
+// newPlanner collects load of hosts and guests in scope of the policy.
+// Without metrics, load is what is allocated to running guests against
+// overcommitted capacity, which is also what scheduler sees
+func (policy *SRebalancePolicy) newPlanner() (*sRebalancePlanner, string, error) {
+	hosts, err := policy.getHosts()
+	if err != nil {
+		return nil, "", err
+	}
+	var note string
+	var metrics *sRebalanceMetrics
+	if policy.UseMetrics {
+		metrics, err = fetchRebalanceMetrics()
+		if err != nil {
+			log.Errorf("rebalance policy %s: fetch metrics: %v", policy.Name, err)
+			note = fmt.Sprintf("metrics unavailable, use allocated resources: %v", err)
+			metrics = nil
+		} else if missing := metrics.missingHosts(hosts); len(missing) > 0 {
+			// load of hosts without metrics would be taken as 0, making
+			// them targets of every migration
+			note = fmt.Sprintf("metrics missing for hosts %s, use allocated resources", strings.Join(missing, ","))
+			metrics = nil
+		}
+	}
+
+	hostIds := make([]string, len(hosts))
+	for i := range hosts {
+		hostIds[i] = hosts[i].Id
+	}
+	guests := []SGuest{}
+	q := GuestManager.Query().In("host_id", hostIds).Equals("status", api.VM_RUNNING)
+	if err := db.FetchModelObjects(GuestManager, q, &guests); err != nil {
+		return nil, "", errors.Wrap(err, "fetch guests")
+	}
+	constraints, err := fetchGuestConstraints(hostIds, guests)
+	if err != nil {
+		return nil, "", err
+	}
+	guestIds := make([]string, len(guests))
+	for i := range guests {
+		guestIds[i] = guests[i].Id
+	}
+	migrating := map[string]bool{}
+	{
+		recs := []SRebalanceRecommendation{}
+		q := RebalanceRecommendationManager.Query().In("guest_id", guestIds).Equals("status", api.REBALANCE_RECOMMENDATION_STATUS_MIGRATING)
+		if err := db.FetchModelObjects(RebalanceRecommendationManager, q, &recs); err != nil {
+			return nil, "", errors.Wrap(err, "fetch migrating recommendations")
+		}
+		for i := range recs {
+			migrating[recs[i].GuestId] = true
+		}
+	}
+
+	planner := &sRebalancePlanner{
+		Granularity:    constraints.Granularity,
+		CpuThreshold:   float64(policy.CpuThreshold) / 100,
+		MemThreshold:   float64(policy.MemoryThreshold) / 100,
+		MaxUtilization: float64(policy.MaxUtilization) / 100,
+		MaxMigrations:  policy.MaxMigrations,
+	}
+	phosts := map[string]*sRebalanceHost{}
+	for i := range hosts {
+		host := &hosts[i]
+		ph := &sRebalanceHost{
+			sHostLoad: newHostLoad(host),
+		}
+		if metrics != nil {
+			ph.CpuCapacity = float64(host.CpuCount)
+			ph.MemCapacity = float64(host.MemSize)
+			ph.CpuUsed = metrics.hostCpu[host.Id] / 100 * ph.CpuCapacity
+			ph.MemUsed = metrics.hostMem[host.Id]
+		}
+		phosts[host.Id] = ph
+		planner.Hosts = append(planner.Hosts, ph)
+	}
+	for i := range guests {
+		guest := &guests[i]
+		ph, ok := phosts[guest.HostId]
+		if !ok {
+			continue
+		}
+		pg := constraints.newLoadGuest(guest)
+		if metrics != nil {
+			if cpu, ok := metrics.guestCpu[guest.Id]; ok {
+				pg.Cpu = float64(guest.VcpuCount) * cpu / 100
+			}
+			if mem, ok := metrics.guestMem[guest.Id]; ok {
+				pg.Memory = mem
+			}
+		} else {
+			ph.CpuUsed += pg.Cpu
+			ph.MemUsed += pg.Memory
+		}
+		if guest.Hypervisor != api.HYPERVISOR_KVM || guest.IsSystem || guest.BackupHostId != "" ||
+			constraints.DeviceGuests[guest.Id] || migrating[guest.Id] {
+			// count in load of the host but never move it
+			ph.PinnedGroups = append(ph.PinnedGroups, pg.Groups...)
+			continue
+		}
+		ph.Guests = append(ph.Guests, pg)
+	}
+	return planner, note, nil
+}
+
+func (policy *SRebalancePolicy) evaluate(ctx context.Context, userCred mcclient.TokenCredential, dryRun bool) (*api.RebalancePlan, error) {
+	lockman.LockObject(ctx, policy)
+	defer lockman.ReleaseObject(ctx, policy)
+
+	if err := RebalanceRecommendationManager.syncMigrating(ctx, userCred, policy.Id); err != nil {
+		log.Errorf("rebalance policy %s: sync migrating recommendations: %v", policy.Name, err)
+	}
+
+	plan := &api.RebalancePlan{
+		Migrations: []api.RebalanceMigration{},
+	}
+	if !dryRun && policy.Mode == api.REBALANCE_MODE_AUTO {
+		// do not pile up migrations onto hosts whose load is still changing
+		cnt, err := RebalanceRecommendationManager.Query().Equals("rebalance_policy_id", policy.Id).
+			Equals("status", api.REBALANCE_RECOMMENDATION_STATUS_MIGRATING).CountWithError()
+		if err != nil {
+			return nil, errors.Wrap(err, "count migrating recommendations")
+		}
+		if cnt > 0 {
+			plan.Note = fmt.Sprintf("%d migrations of last round are still in progress", cnt)
+			return plan, nil
+		}
+	}
+
+	planner, note, err := policy.newPlanner()
+	if err != nil {
+		return nil, errors.Wrap(err, "newPlanner")
+	}
+	plan.Note = note
+	plan.HostCount = len(planner.Hosts)
+	cpuSpread, memSpread, _, _ := planner.spread()
+	plan.CpuSpread = cpuSpread * 100
+	plan.MemorySpread = memSpread * 100
+	plan.Imbalance = planner.imbalance()
+	moves := planner.plan()
+	plan.ImbalanceAfter = planner.imbalance()
+	for _, move := range moves {
+		plan.Migrations = append(plan.Migrations, api.RebalanceMigration{
+			GuestId:         move.Guest.Id,
+			Guest:           move.Guest.Name,
+			SrcHostId:       move.Src.Id,
+			SrcHost:         move.Src.Name,
+			DstHostId:       move.Dst.Id,
+			DstHost:         move.Dst.Name,
+			ImbalanceBefore: move.ImbalanceBefore,
+			ImbalanceAfter:  move.ImbalanceAfter,
+			Reason:          move.Reason,
+		})
+	}
+	if dryRun {
+		return plan, nil
+	}
+
+	if err := RebalanceRecommendationManager.expirePending(ctx, userCred, policy.Id, "superseded by new evaluation"); err != nil {
+		return nil, errors.Wrap(err, "expire pending recommendations")
+	}
+	for i := range plan.Migrations {
+		rec, err := RebalanceRecommendationManager.newFromMigration(ctx, userCred, policy, &plan.Migrations[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "record recommendation for guest %s", plan.Migrations[i].Guest)
+		}
+		if policy.Mode == api.REBALANCE_MODE_AUTO {
+			if err := rec.execute(ctx, userCred, true); err != nil {
+				log.Errorf("rebalance policy %s: execute recommendation %s: %v", policy.Name, rec.Name, err)
+			}
+		}
+	}
+
+	_, err = db.Update(policy, func() error {
+		policy.LastEvaluatedAt = time.Now().UTC()
+		policy.LastImbalance = plan.Imbalance
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update policy")
+	}
+	db.OpsLog.LogEvent(policy, db.ACT_REBALANCE, plan, userCred)
+	logclient.AddActionLogWithContext(ctx, policy, logclient.ACT_REBALANCE, plan, userCred, true)
+	return plan, nil
+}
+
+func (policy *SRebalancePolicy) isDue() bool {
+	return policy.LastEvaluatedAt.IsZero() ||
+		time.Since(policy.LastEvaluatedAt) >= time.Duration(policy.IntervalMinutes)*time.Minute
+}
+
+// AutoRebalance evaluates enabled policies whose interval elapsed
+func (manager *SRebalancePolicyManager) AutoRebalance(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	policies := []SRebalancePolicy{}
+	q := manager.Query().IsTrue("enabled")
+	if err := db.FetchModelObjects(manager, q, &policies); err != nil {
+		log.Errorf("fetch rebalance policies: %v", err)
+		return
+	}
+	for i := range policies {
+		policy := &policies[i]
+		if !policy.isDue() {
+			continue
+		}
+		if _, err := policy.evaluate(ctx, userCred, false); err != nil {
+			log.Errorf("rebalance policy %s(%s): %v", policy.Name, policy.Id, err)
+			logclient.AddActionLogWithContext(ctx, policy, logclient.ACT_REBALANCE, err, userCred, false)
+		}
+	}
+}
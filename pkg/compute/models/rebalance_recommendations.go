@@ -0,0 +1,320 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// +onecloud:swagger-gen-model-singular=rebalance_recommendation
+// +onecloud:swagger-gen-model-plural=rebalance_recommendations
+type SRebalanceRecommendationManager struct {
+	db.SStatusStandaloneResourceBaseManager
+}
+
+var RebalanceRecommendationManager *SRebalanceRecommendationManager
+
+func init() {
+	RebalanceRecommendationManager = &SRebalanceRecommendationManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SRebalanceRecommendation{},
+			"rebalance_recommendations_tbl",
+			"rebalance_recommendation",
+			"rebalance_recommendations",
+		),
+	}
+	RebalanceRecommendationManager.SetVirtualObject(RebalanceRecommendationManager)
+}
+
+// SRebalanceRecommendation is a live migration planned by rebalance policy.
+// Records are kept after execution as audit trail
+type SRebalanceRecommendation struct {
+	db.SStatusStandaloneResourceBase
+
+	// 均衡策略Id
+	RebalancePolicyId string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	// 虚拟机Id
+	GuestId string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	// 源宿主机Id
+	SrcHostId string `width:"36" charset:"ascii" nullable:"false" list:"admin"`
+	// 目标宿主机Id
+	DstHostId string `width:"36" charset:"ascii" nullable:"false" list:"admin"`
+
+	// 迁移前的不均衡度
+	ImbalanceBefore float64 `nullable:"false" list:"admin"`
+	// 迁移后的不均衡度
+	ImbalanceAfter float64 `nullable:"false" list:"admin"`
+	// 迁移原因
+	Reason string `width:"256" charset:"utf8" nullable:"true" list:"admin"`
+
+	// 是否自动执行
+	AutoExecuted bool `nullable:"false" default:"false" list:"admin"`
+	// 执行时间
+	ExecutedAt time.Time `nullable:"true" list:"admin"`
+}
+
+// 负载均衡迁移建议列表
+func (manager *SRebalanceRecommendationManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.RebalanceRecommendationListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.RebalancePolicyId) > 0 {
+		policy, err := RebalancePolicyManager.FetchByIdOrName(userCred, query.RebalancePolicyId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(RebalancePolicyManager.Keyword(), query.RebalancePolicyId)
+		}
+		q = q.Equals("rebalance_policy_id", policy.GetId())
+	}
+	if len(query.GuestId) > 0 {
+		guest, err := GuestManager.FetchByIdOrName(userCred, query.GuestId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(GuestManager.Keyword(), query.GuestId)
+		}
+		q = q.Equals("guest_id", guest.GetId())
+	}
+	if len(query.HostId) > 0 {
+		host, err := HostManager.FetchByIdOrName(userCred, query.HostId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(HostManager.Keyword(), query.HostId)
+		}
+		q = q.Filter(sqlchemy.OR(
+			sqlchemy.Equals(q.Field("src_host_id"), host.GetId()),
+			sqlchemy.Equals(q.Field("dst_host_id"), host.GetId()),
+		))
+	}
+	return q, nil
+}
+
+func (manager *SRebalanceRecommendationManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.RebalanceRecommendationListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SRebalanceRecommendationManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	return nil, httperrors.NewUnsupportOperationError("rebalance recommendations are made by rebalance policies")
+}
+
+func (manager *SRebalanceRecommendationManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.RebalanceRecommendationDetails {
+	rows := make([]api.RebalanceRecommendationDetails, len(objs))
+	stdRows := manager.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+
+	var policyIds, guestIds, hostIds []string
+	for i := range rows {
+		rows[i] = api.RebalanceRecommendationDetails{
+			StatusStandaloneResourceDetails: stdRows[i],
+		}
+		rec := objs[i].(*SRebalanceRecommendation)
+		policyIds = append(policyIds, rec.RebalancePolicyId)
+		guestIds = append(guestIds, rec.GuestId)
+		hostIds = append(hostIds, rec.SrcHostId, rec.DstHostId)
+	}
+	policyNames, err := db.FetchIdNameMap2(RebalancePolicyManager, policyIds)
+	if err != nil {
+		log.Errorf("fetch rebalance policy names: %v", err)
+	}
+	guestNames, err := db.FetchIdNameMap2(GuestManager, guestIds)
+	if err != nil {
+		log.Errorf("fetch guest names: %v", err)
+	}
+	hostNames, err := db.FetchIdNameMap2(HostManager, hostIds)
+	if err != nil {
+		log.Errorf("fetch host names: %v", err)
+	}
+	for i := range rows {
+		rec := objs[i].(*SRebalanceRecommendation)
+		rows[i].RebalancePolicy = policyNames[rec.RebalancePolicyId]
+		rows[i].Guest = guestNames[rec.GuestId]
+		rows[i].SrcHost = hostNames[rec.SrcHostId]
+		rows[i].DstHost = hostNames[rec.DstHostId]
+	}
+	return rows
+}
+
+func (manager *SRebalanceRecommendationManager) newFromMigration(ctx context.Context, userCred mcclient.TokenCredential, policy *SRebalancePolicy, m *api.RebalanceMigration) (*SRebalanceRecommendation, error) {
+	rec := &SRebalanceRecommendation{
+		RebalancePolicyId: policy.Id,
+		GuestId:           m.GuestId,
+		SrcHostId:         m.SrcHostId,
+		DstHostId:         m.DstHostId,
+		ImbalanceBefore:   m.ImbalanceBefore,
+		ImbalanceAfter:    m.ImbalanceAfter,
+		Reason:            m.Reason,
+	}
+	rec.SetModelManager(manager, rec)
+	rec.Status = api.REBALANCE_RECOMMENDATION_STATUS_PENDING
+
+	err := func() error {
+		lockman.LockClass(ctx, manager, "")
+		defer lockman.ReleaseClass(ctx, manager, "")
+
+		var err error
+		rec.Name, err = db.GenerateName(ctx, manager, nil, fmt.Sprintf("%s-to-%s", m.Guest, m.DstHost))
+		if err != nil {
+			return errors.Wrap(err, "GenerateName")
+		}
+		return manager.TableSpec().Insert(ctx, rec)
+	}()
+	if err != nil {
+		return nil, err
+	}
+	db.OpsLog.LogEvent(rec, db.ACT_CREATE, m, userCred)
+	return rec, nil
+}
+
+// expirePending marks pending recommendations of the policy expired
+func (manager *SRebalanceRecommendationManager) expirePending(ctx context.Context, userCred mcclient.TokenCredential, policyId, reason string) error {
+	recs := []SRebalanceRecommendation{}
+	q := manager.Query().Equals("rebalance_policy_id", policyId).Equals("status", api.REBALANCE_RECOMMENDATION_STATUS_PENDING)
+	if err := db.FetchModelObjects(manager, q, &recs); err != nil {
+		return errors.Wrap(err, "fetch pending recommendations")
+	}
+	for i := range recs {
+		recs[i].SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_EXPIRED, reason)
+	}
+	return nil
+}
+
+// rebalanceMigrateTimeout is how long a live migration is waited for before
+// its recommendation is considered failed
+const rebalanceMigrateTimeout = 2 * time.Hour
+
+// syncMigrating updates recommendations of the policy being executed with
+// the outcome of live migration
+func (manager *SRebalanceRecommendationManager) syncMigrating(ctx context.Context, userCred mcclient.TokenCredential, policyId string) error {
+	recs := []SRebalanceRecommendation{}
+	q := manager.Query().Equals("rebalance_policy_id", policyId).Equals("status", api.REBALANCE_RECOMMENDATION_STATUS_MIGRATING)
+	if err := db.FetchModelObjects(manager, q, &recs); err != nil {
+		return errors.Wrap(err, "fetch migrating recommendations")
+	}
+	for i := range recs {
+		rec := &recs[i]
+		guest := GuestManager.FetchGuestById(rec.GuestId)
+		if guest == nil {
+			rec.SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_FAILED, "guest not found")
+			continue
+		}
+		migrating := guest.Status == api.VM_START_MIGRATE || guest.Status == api.VM_MIGRATING
+		switch {
+		case guest.HostId == rec.DstHostId && !migrating:
+			rec.SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_DONE, "")
+		case guest.Status == api.VM_MIGRATE_FAILED:
+			rec.SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_FAILED, "live migrate failed")
+		case !migrating:
+			rec.SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_FAILED, fmt.Sprintf("guest is %s on host %s", guest.Status, guest.HostId))
+		case time.Since(rec.ExecutedAt) > rebalanceMigrateTimeout:
+			rec.SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_FAILED, "live migrate timeout")
+		}
+	}
+	return nil
+}
+
+// execute starts live migration of the guest to destination host
+func (rec *SRebalanceRecommendation) execute(ctx context.Context, userCred mcclient.TokenCredential, auto bool) error {
+	if rec.Status != api.REBALANCE_RECOMMENDATION_STATUS_PENDING {
+		return httperrors.NewInvalidStatusError("recommendation is %s", rec.Status)
+	}
+	guest := GuestManager.FetchGuestById(rec.GuestId)
+	if guest == nil {
+		rec.SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_EXPIRED, "guest not found")
+		return httperrors.NewResourceNotFoundError2(GuestManager.Keyword(), rec.GuestId)
+	}
+	if guest.HostId != rec.SrcHostId {
+		rec.SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_EXPIRED, "guest is no longer on source host")
+		return httperrors.NewConflictError("guest %s is no longer on source host", guest.Name)
+	}
+
+	input := &api.GuestLiveMigrateInput{PreferHost: rec.DstHostId}
+	err := guest.validateMigrate(ctx, userCred, nil, input)
+	if err == nil {
+		err = guest.StartGuestLiveMigrateTask(ctx, userCred, guest.Status, input.PreferHost, input.SkipCpuCheck, "")
+	}
+	if err != nil {
+		rec.SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_FAILED, err.Error())
+		logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_REBALANCE, err, userCred, false)
+		return err
+	}
+
+	_, err = db.Update(rec, func() error {
+		rec.AutoExecuted = auto
+		rec.ExecutedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update recommendation")
+	}
+	rec.SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_MIGRATING, "")
+	logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_REBALANCE, rec.Name, userCred, true)
+	return nil
+}
+
+func (rec *SRebalanceRecommendation) AllowPerformExecute(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, rec, "execute")
+}
+
+// 执行迁移建议
+func (rec *SRebalanceRecommendation) PerformExecute(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	lockman.LockObject(ctx, rec)
+	defer lockman.ReleaseObject(ctx, rec)
+
+	return nil, rec.execute(ctx, userCred, false)
+}
+
+func (rec *SRebalanceRecommendation) AllowPerformReject(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.RebalanceRecommendationRejectInput) bool {
+	return db.IsAdminAllowPerform(userCred, rec, "reject")
+}
+
+// 拒绝迁移建议
+func (rec *SRebalanceRecommendation) PerformReject(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.RebalanceRecommendationRejectInput) (jsonutils.JSONObject, error) {
+	if rec.Status != api.REBALANCE_RECOMMENDATION_STATUS_PENDING {
+		return nil, httperrors.NewInvalidStatusError("recommendation is %s", rec.Status)
+	}
+	return nil, rec.SetStatus(userCred, api.REBALANCE_RECOMMENDATION_STATUS_REJECTED, input.Reason)
+}
@@ -144,6 +144,25 @@ func (man *SRouteTableManager) validateRoutes(data *jsonutils.JSONDict, update b
 	return data, nil
 }
 
+// validateVpcPeeringRoutes makes sure routes targeting vpc peering
+// connections refer to peerings of the vpc
+func (man *SRouteTableManager) validateVpcPeeringRoutes(userCred mcclient.TokenCredential, vpcId string, routes api.SRoutes) error {
+	for _, route := range routes {
+		if route.NextHopType != api.Next_HOP_TYPE_VPCPEERING {
+			continue
+		}
+		_peer, err := validators.ValidateModel(userCred, VpcPeeringConnectionManager, &route.NextHopId)
+		if err != nil {
+			return err
+		}
+		peer := _peer.(*SVpcPeeringConnection)
+		if peer.VpcId != vpcId && peer.PeerVpcId != vpcId {
+			return httperrors.NewInputParameterError("vpc peering connection %s is not connected with vpc %s", peer.Name, vpcId)
+		}
+	}
+	return nil
+}
+
 func (man *SRouteTableManager) ValidateCreateData(
 	ctx context.Context,
 	userCred mcclient.TokenCredential,
@@ -159,6 +178,11 @@ func (man *SRouteTableManager) ValidateCreateData(
 	if err != nil {
 		return input, err
 	}
+	if input.Routes != nil {
+		if err := man.validateVpcPeeringRoutes(userCred, input.VpcId, *input.Routes); err != nil {
+			return input, err
+		}
+	}
 	input.StatusInfrasResourceBaseCreateInput, err = man.SStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StatusInfrasResourceBaseCreateInput)
 	if err != nil {
 		return input, errors.Wrap(err, "SStatusInfrasResourceBaseManager.ValidateCreateData")
@@ -221,6 +245,11 @@ func (rt *SRouteTable) ValidateUpdateData(
 	if err != nil {
 		return input, errors.Wrap(err, "RouteTableManager.validateRoutes")
 	}
+	if input.Routes != nil {
+		if err := RouteTableManager.validateVpcPeeringRoutes(userCred, rt.VpcId, *input.Routes); err != nil {
+			return input, err
+		}
+	}
 	input.StatusInfrasResourceBaseUpdateInput, err = rt.SStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.StatusInfrasResourceBaseUpdateInput)
 	if err != nil {
 		return input, errors.Wrap(err, "SStatusInfrasResourceBase.ValidateUpdateData")
@@ -252,6 +281,9 @@ func (rt *SRouteTable) PerformAddRoutes(ctx context.Context, userCred mcclient.T
 		if err != nil {
 			return nil, err
 		}
+		if err := RouteTableManager.validateVpcPeeringRoutes(userCred, rt.VpcId, adds); err != nil {
+			return nil, err
+		}
 		for _, add := range adds {
 			found := false
 			for _, route := range routes {
@@ -0,0 +1,221 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// +onecloud:swagger-gen-model-singular=sched_history
+// +onecloud:swagger-gen-model-plural=sched_histories
+type SSchedHistoryManager struct {
+	db.SStandaloneAnonResourceBaseManager
+}
+
+var SchedHistoryManager *SSchedHistoryManager
+
+func init() {
+	SchedHistoryManager = &SSchedHistoryManager{
+		SStandaloneAnonResourceBaseManager: db.NewStandaloneAnonResourceBaseManager(
+			SSchedHistory{},
+			"sched_histories_tbl",
+			"sched_history",
+			"sched_histories",
+		),
+	}
+	SchedHistoryManager.SetVirtualObject(SchedHistoryManager)
+}
+
+// SSchedHistory is the decision of one scheduling request, written by the
+// scheduler once the request completes and kept for
+// SchedHistoryRetentionDays
+type SSchedHistory struct {
+	db.SStandaloneAnonResourceBase
+
+	// 调度会话Id
+	SessionId string `width:"128" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	// 调度的虚拟机Id, 以逗号分隔
+	GuestIds string `length:"text" charset:"ascii" nullable:"true" list:"admin"`
+	// 选中的宿主机Id, 以逗号分隔
+	HostIds string `length:"text" charset:"ascii" nullable:"true" list:"admin"`
+
+	// 虚拟化类型
+	Hypervisor string `width:"16" charset:"ascii" nullable:"true" list:"admin"`
+	// 项目Id
+	ProjectId string `width:"128" charset:"ascii" nullable:"true" list:"admin"`
+	// 请求数量
+	Count int `nullable:"false" default:"0" list:"admin"`
+	// 调度请求摘要
+	Request *jsonutils.JSONDict `length:"medium" charset:"utf8" nullable:"true" get:"admin"`
+
+	// 是否调度成功
+	Success bool `nullable:"false" default:"false" list:"admin"`
+	// 失败原因
+	Error string `length:"text" charset:"utf8" nullable:"true" list:"admin"`
+	// 参与调度的宿主机, 包括过滤原因和打分详情
+	Candidates *api.SchedHistoryCandidates `length:"medium" charset:"utf8" nullable:"true" get:"admin"`
+	// 调度耗时, 单位毫秒
+	Consuming int64 `nullable:"false" default:"0" list:"admin"`
+}
+
+func (manager *SSchedHistoryManager) AllowCreateItem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return false
+}
+
+func (history *SSchedHistory) AllowUpdateItem(ctx context.Context, userCred mcclient.TokenCredential) bool {
+	return false
+}
+
+// 调度历史列表
+func (manager *SSchedHistoryManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.SchedHistoryListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStandaloneAnonResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StandaloneAnonResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStandaloneAnonResourceBaseManager.ListItemFilter")
+	}
+	if len(query.ServerId) > 0 {
+		guestId := query.ServerId
+		guest, err := GuestManager.FetchByIdOrName(userCred, query.ServerId)
+		if err == nil {
+			guestId = guest.GetId()
+		} else if errors.Cause(err) != sql.ErrNoRows {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		// the guest may have been deleted, match the raw id
+		q = q.In("id", SchedHistoryResourceManager.historyIdQuery(GuestManager.Keyword(), guestId))
+	}
+	if len(query.HostId) > 0 {
+		hostId := query.HostId
+		host, err := HostManager.FetchByIdOrName(userCred, query.HostId)
+		if err == nil {
+			hostId = host.GetId()
+		} else if errors.Cause(err) != sql.ErrNoRows {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		q = q.In("id", SchedHistoryResourceManager.historyIdQuery(HostManager.Keyword(), hostId))
+	}
+	if len(query.SessionId) > 0 {
+		q = q.In("session_id", query.SessionId)
+	}
+	if len(query.Hypervisor) > 0 {
+		q = q.In("hypervisor", query.Hypervisor)
+	}
+	if query.Success != nil {
+		if *query.Success {
+			q = q.IsTrue("success")
+		} else {
+			q = q.IsFalse("success")
+		}
+	}
+	return q, nil
+}
+
+func (manager *SSchedHistoryManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.SchedHistoryListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStandaloneAnonResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StandaloneAnonResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStandaloneAnonResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SSchedHistoryManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStandaloneAnonResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SSchedHistoryManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.SchedHistoryDetails {
+	rows := make([]api.SchedHistoryDetails, len(objs))
+	stdRows := manager.SStandaloneAnonResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.SchedHistoryDetails{
+			StandaloneAnonResourceDetails: stdRows[i],
+		}
+	}
+	return rows
+}
+
+// Record saves the decision of a scheduling request.  Called by scheduler
+func (manager *SSchedHistoryManager) Record(ctx context.Context, history *SSchedHistory) error {
+	history.SetModelManager(manager, history)
+	if err := manager.TableSpec().Insert(ctx, history); err != nil {
+		return errors.Wrapf(err, "insert sched history of session %s", history.SessionId)
+	}
+	for resType, resIds := range map[string]string{
+		GuestManager.Keyword(): history.GuestIds,
+		HostManager.Keyword():  history.HostIds,
+	} {
+		if len(resIds) == 0 {
+			continue
+		}
+		err := SchedHistoryResourceManager.addResources(ctx, history.Id, resType, strings.Split(resIds, ","))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (manager *SSchedHistoryManager) CleanExpiredHistories(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	if options.Options.SchedHistoryRetentionDays <= 0 {
+		return
+	}
+	expireAt := time.Now().Add(-time.Duration(options.Options.SchedHistoryRetentionDays) * 24 * time.Hour)
+	for _, tbl := range []string{manager.TableSpec().Name(), SchedHistoryResourceManager.TableSpec().Name()} {
+		_, err := sqlchemy.GetDB().Exec(
+			fmt.Sprintf(
+				"delete from %s where created_at < ?",
+				tbl,
+			), expireAt,
+		)
+		if err != nil {
+			log.Errorf("clean %s before %s: %v", tbl, expireAt, err)
+		}
+	}
+}
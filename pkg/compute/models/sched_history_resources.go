@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+// +onecloud:swagger-gen-ignore
+type SSchedHistoryResourceManager struct {
+	db.SResourceBaseManager
+}
+
+var SchedHistoryResourceManager *SSchedHistoryResourceManager
+
+func init() {
+	SchedHistoryResourceManager = &SSchedHistoryResourceManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SSchedHistoryResource{},
+			"sched_history_resources_tbl",
+			"sched_history_resource",
+			"sched_history_resources",
+		),
+	}
+	SchedHistoryResourceManager.SetVirtualObject(SchedHistoryResourceManager)
+}
+
+// SSchedHistoryResource indexes the guests scheduled and the hosts selected by
+// a sched history, so that histories can be filtered by guest or host id
+type SSchedHistoryResource struct {
+	db.SResourceBase
+
+	HistoryId    string `width:"36" charset:"ascii" nullable:"false" primary:"true"`
+	ResourceType string `width:"16" charset:"ascii" nullable:"false" primary:"true"`
+	ResourceId   string `width:"128" charset:"ascii" nullable:"false" primary:"true" index:"true"`
+}
+
+func (res *SSchedHistoryResource) GetId() string {
+	return fmt.Sprintf("%s/%s/%s", res.HistoryId, res.ResourceType, res.ResourceId)
+}
+
+func (manager *SSchedHistoryResourceManager) addResources(ctx context.Context, historyId string, resType string, resIds []string) error {
+	for _, resId := range resIds {
+		res := &SSchedHistoryResource{
+			HistoryId:    historyId,
+			ResourceType: resType,
+			ResourceId:   resId,
+		}
+		res.SetModelManager(manager, res)
+		if err := manager.TableSpec().InsertOrUpdate(ctx, res); err != nil {
+			return errors.Wrapf(err, "insert %s %s of sched history %s", resType, resId, historyId)
+		}
+	}
+	return nil
+}
+
+// historyIdQuery returns ids of the histories involving the resource
+func (manager *SSchedHistoryResourceManager) historyIdQuery(resType string, resId string) *sqlchemy.SSubQuery {
+	return manager.Query("history_id").
+		Equals("resource_type", resType).
+		Equals("resource_id", resId).
+		SubQuery()
+}
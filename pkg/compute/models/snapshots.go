@@ -59,6 +59,7 @@ type SSnapshot struct {
 	SManagedResourceBase
 	SCloudregionResourceBase `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
 	db.SMultiArchResourceBase
+	SEncryptedResourceBase
 
 	// 磁盘Id
 	DiskId string `width:"36" charset:"ascii" nullable:"true" create:"required" list:"user" index:"true"`
@@ -484,6 +485,15 @@ func (self *SSnapshotManager) AddRefCount(snapshotId string, count int) {
 	}
 }
 
+func (self *SSnapshotManager) FetchSnapshotById(snapshotId string) *SSnapshot {
+	snapshot, err := self.FetchById(snapshotId)
+	if err != nil {
+		log.Errorf("FetchById fail %s", err)
+		return nil
+	}
+	return snapshot.(*SSnapshot)
+}
+
 func (self *SSnapshotManager) GetDiskSnapshotsByCreate(diskId, createdBy string) []SSnapshot {
 	dest := make([]SSnapshot, 0)
 	q := self.Query().SubQuery()
@@ -566,6 +576,7 @@ func (self *SSnapshotManager) CreateSnapshot(ctx context.Context, owner mcclient
 	snapshot.OutOfChain = driver.SnapshotIsOutOfChain(disk)
 	snapshot.Size = disk.DiskSize
 	snapshot.DiskType = disk.DiskType
+	snapshot.inheritEncryption(&disk.SEncryptedResourceBase)
 	snapshot.Location = location
 	snapshot.CreatedBy = createdBy
 	snapshot.ManagerId = storage.ManagerId
@@ -1210,6 +1210,19 @@ func (self *SStorage) createDisk(ctx context.Context, name string, diskConfig *a
 	disk.Name = name
 	disk.fetchDiskInfo(diskConfig)
 
+	if len(disk.SnapshotId) > 0 {
+		snapshot := SnapshotManager.FetchSnapshotById(disk.SnapshotId)
+		if snapshot != nil && snapshot.IsEncrypted() {
+			disk.inheritEncryption(&snapshot.SEncryptedResourceBase)
+		}
+	}
+	if !disk.IsEncrypted() && len(diskConfig.EncryptKeyId) > 0 {
+		err := disk.initEncryption(ctx, diskConfig.EncryptKeyId)
+		if err != nil {
+			return nil, errors.Wrap(err, "initEncryption")
+		}
+	}
+
 	disk.StorageId = self.Id
 	disk.AutoDelete = autoDelete
 	disk.ProjectId = ownerId.GetProjectId()
@@ -17,6 +17,7 @@ package models
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"strings"
 
 	"yunion.io/x/jsonutils"
@@ -63,6 +64,9 @@ type SVpcPeeringConnection struct {
 	PeerVpcId        string `width:"36" charset:"ascii" nullable:"true" list:"domain" create:"required" json:"peer_vpc_id"`
 	PeerAccountId    string `width:"36" charset:"ascii" nullable:"true" list:"domain"`
 	Bandwidth        int    `nullable:"false" default:"0" list:"user" create:"optional"`
+
+	// 本地vpc对等连接的互联网段
+	OvnTransitCidr string `width:"18" charset:"ascii" nullable:"true" list:"domain"`
 }
 
 func (manager *SVpcPeeringConnectionManager) GetContextManagers() [][]db.IModelManager {
@@ -143,8 +147,11 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 	}
 	peerVpc := _peerVpc.(*SVpc)
 
+	if len(vpc.ManagerId) == 0 && len(peerVpc.ManagerId) == 0 {
+		return manager.validateOnecloudCreateData(input, vpc, peerVpc)
+	}
 	if len(vpc.ManagerId) == 0 || len(peerVpc.ManagerId) == 0 {
-		return input, httperrors.NewInputParameterError("Only public cloud support vpcpeering")
+		return input, httperrors.NewInputParameterError("vpc peering between onecloud vpc and public cloud vpc is not supported")
 	}
 
 	// get account,providerFactory
@@ -218,6 +225,123 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 	return input, nil
 }
 
+// validateOnecloudCreateData validates peering of two onecloud vpcs.  They are
+// connected by vpcagent in the same ovn northbound db, so they must be in the
+// same region and address spaces of their networks must not overlap
+func (manager *SVpcPeeringConnectionManager) validateOnecloudCreateData(input api.VpcPeeringConnectionCreateInput, vpc, peerVpc *SVpc) (api.VpcPeeringConnectionCreateInput, error) {
+	if vpc.Id == api.DEFAULT_VPC_ID || peerVpc.Id == api.DEFAULT_VPC_ID {
+		return input, httperrors.NewInputParameterError("default vpc cannot be peered")
+	}
+	if vpc.Id == peerVpc.Id {
+		return input, httperrors.NewInputParameterError("vpc cannot be peered with itself")
+	}
+	if vpc.CloudregionId != peerVpc.CloudregionId {
+		return input, httperrors.NewNotSupportedError("onecloud vpc peering across regions is not supported")
+	}
+
+	peerRanges, err := peerVpc.getNetworkCidrRanges()
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if err := vpc.validateNetworkOverlap(peerVpc, peerRanges); err != nil {
+		return input, err
+	}
+
+	q := manager.Query()
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.AND(
+			sqlchemy.Equals(q.Field("vpc_id"), vpc.Id),
+			sqlchemy.Equals(q.Field("peer_vpc_id"), peerVpc.Id),
+		),
+		sqlchemy.AND(
+			sqlchemy.Equals(q.Field("vpc_id"), peerVpc.Id),
+			sqlchemy.Equals(q.Field("peer_vpc_id"), vpc.Id),
+		),
+	))
+	cnt, err := q.CountWithError()
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return input, httperrors.NewNotSupportedError("vpc %s and vpc %s have already connected", vpc.Name, peerVpc.Name)
+	}
+
+	input.VpcId = vpc.Id
+	input.PeerVpcId = peerVpc.Id
+	return input, nil
+}
+
+// getNetworkCidrRanges returns address ranges of networks in the vpc, with
+// the gateway and broadcast addresses included
+func (self *SVpc) getNetworkCidrRanges() ([]netutils.IPV4AddrRange, error) {
+	nets, err := self.GetNetworks()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetNetworks of vpc %s", self.Name)
+	}
+	ranges := make([]netutils.IPV4AddrRange, 0, len(nets))
+	for i := range nets {
+		prefix, err := netutils.NewIPV4Prefix(fmt.Sprintf("%s/%d", nets[i].GuestIpStart, nets[i].GuestIpMask))
+		if err != nil {
+			return nil, errors.Wrapf(err, "network %s cidr", nets[i].Name)
+		}
+		ranges = append(ranges, prefix.ToIPRange())
+	}
+	return ranges, nil
+}
+
+// validateNetworkOverlap checks that networks in the vpc do not overlap with
+// the address ranges from the other vpc
+func (self *SVpc) validateNetworkOverlap(other *SVpc, ranges []netutils.IPV4AddrRange) error {
+	myRanges, err := self.getNetworkCidrRanges()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	for i := range myRanges {
+		for j := range ranges {
+			if myRanges[i].IsOverlap(ranges[j]) {
+				return httperrors.NewConflictError("address range %s of vpc %s overlaps with %s of vpc %s",
+					myRanges[i].String(), self.Name, ranges[j].String(), other.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// networkCidrRange returns the address range of the subnet holding network
+// addresses from ipStart to ipEnd, as peered vpcs route the whole subnet
+func networkCidrRange(ipStart, ipEnd netutils.IPV4Addr, masklen int8) netutils.IPV4AddrRange {
+	return netutils.NewIPV4AddrRange(ipStart.NetAddr(masklen), ipEnd.BroadcastAddr(masklen))
+}
+
+// validatePeeringNetworkRange checks that the address range of a new network
+// in the vpc does not overlap with networks in its peered onecloud vpcs
+func (self *SVpc) validatePeeringNetworkRange(ipRange netutils.IPV4AddrRange) error {
+	q := VpcPeeringConnectionManager.Query()
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.Equals(q.Field("vpc_id"), self.Id),
+		sqlchemy.Equals(q.Field("peer_vpc_id"), self.Id),
+	))
+	peerings := []SVpcPeeringConnection{}
+	if err := db.FetchModelObjects(VpcPeeringConnectionManager, q, &peerings); err != nil {
+		return httperrors.NewGeneralError(errors.Wrap(err, "fetch vpc peering connections"))
+	}
+	for i := range peerings {
+		peerVpcId := peerings[i].PeerVpcId
+		if peerVpcId == self.Id {
+			peerVpcId = peerings[i].VpcId
+		}
+		_peerVpc, err := VpcManager.FetchById(peerVpcId)
+		if err != nil {
+			return httperrors.NewGeneralError(errors.Wrapf(err, "fetch peer vpc %s", peerVpcId))
+		}
+		peerVpc := _peerVpc.(*SVpc)
+		if err := peerVpc.validateNetworkOverlap(self, []netutils.IPV4AddrRange{ipRange}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (self *SVpcPeeringConnection) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	params := jsonutils.NewDict()
 	task, err := taskman.TaskManager.NewTask(ctx, "VpcPeeringConnectionCreateTask", self, userCred, params, "", "", nil)
@@ -0,0 +1,79 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/pkg/util/netutils"
+)
+
+func TestNetworkCidrRange(t *testing.T) {
+	mustAddr := func(s string) netutils.IPV4Addr {
+		addr, err := netutils.NewIPV4Addr(s)
+		if err != nil {
+			t.Fatalf("invalid ip %s: %s", s, err)
+		}
+		return addr
+	}
+	mustRange := func(s string) netutils.IPV4AddrRange {
+		prefix, err := netutils.NewIPV4Prefix(s)
+		if err != nil {
+			t.Fatalf("invalid prefix %s: %s", s, err)
+		}
+		return prefix.ToIPRange()
+	}
+	cases := []struct {
+		name    string
+		start   string
+		end     string
+		masklen int8
+		peer    string
+		overlap bool
+	}{
+		{
+			name:    "start/end inside peer subnet",
+			start:   "10.1.0.10",
+			end:     "10.1.0.20",
+			masklen: 24,
+			peer:    "10.1.0.128/25",
+			overlap: true,
+		},
+		{
+			name:    "start/end in adjacent subnet",
+			start:   "10.1.1.10",
+			end:     "10.1.1.20",
+			masklen: 24,
+			peer:    "10.1.0.0/24",
+			overlap: false,
+		},
+		{
+			name:    "whole prefix",
+			start:   "10.1.0.0",
+			end:     "10.1.0.255",
+			masklen: 24,
+			peer:    "10.1.0.0/24",
+			overlap: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := networkCidrRange(mustAddr(c.start), mustAddr(c.end), c.masklen)
+			if got := r.IsOverlap(mustRange(c.peer)); got != c.overlap {
+				t.Errorf("%s overlaps %s: want %v, got %v", r.String(), c.peer, c.overlap, got)
+			}
+		})
+	}
+}
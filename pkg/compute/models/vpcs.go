@@ -963,6 +963,10 @@ func (self *SVpc) RealDelete(ctx context.Context, userCred mcclient.TokenCredent
 		}
 	}
 
+	if err := FlowLogManager.cleanFlowLogs(ctx, userCred, "vpc_id", self.Id); err != nil {
+		return errors.Wrap(err, "clean flow logs")
+	}
+
 	dnsZones, err := self.GetDnsZones()
 	if err != nil {
 		return errors.Wrapf(err, "self.GetDnsZones")
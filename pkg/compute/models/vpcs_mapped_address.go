@@ -16,11 +16,13 @@ package models
 
 import (
 	"context"
+	"fmt"
 
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/utils"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 )
 
@@ -32,6 +34,9 @@ const (
 
 	LOCK_CLASS_hosts_mapped_addr = "hosts-mapped-addr"
 	LOCK_OBJ_hosts_mapped_addr   = "the-addr"
+
+	LOCK_CLASS_vpc_peering_transit_cidr = "vpc-peering-transit-cidr"
+	LOCK_OBJ_vpc_peering_transit_cidr   = "the-cidr"
 )
 
 func (man *SGuestnetworkManager) lockAllocMappedAddr(ctx context.Context) {
@@ -107,3 +112,61 @@ func (man *SHostManager) allocOvnMappedIpAddr(ctx context.Context) (string, erro
 	}
 	return "", errors.Wrap(errMappedIpExhausted, "hosts")
 }
+
+func (man *SVpcPeeringConnectionManager) lockAllocOvnTransitCidr(ctx context.Context) {
+	lockman.LockRawObject(ctx, LOCK_CLASS_vpc_peering_transit_cidr, LOCK_OBJ_vpc_peering_transit_cidr)
+}
+
+func (man *SVpcPeeringConnectionManager) unlockAllocOvnTransitCidr(ctx context.Context) {
+	lockman.ReleaseRawObject(ctx, LOCK_CLASS_vpc_peering_transit_cidr, LOCK_OBJ_vpc_peering_transit_cidr)
+}
+
+func (man *SVpcPeeringConnectionManager) allocOvnTransitCidr(ctx context.Context) (string, error) {
+	var (
+		used []string
+		cidr string
+	)
+
+	q := man.Query("ovn_transit_cidr").IsNotEmpty("ovn_transit_cidr")
+	rows, err := q.Rows()
+	if err != nil {
+		return "", err
+	}
+	for rows.Next() {
+		if err := rows.Scan(&cidr); err != nil {
+			return "", errors.Wrap(err, "scan vpc peering transit cidr")
+		}
+		used = append(used, cidr)
+	}
+
+	sip := api.VpcPeeringTransitStart()
+	eip := api.VpcPeeringTransitEnd()
+	for i := sip; i <= eip; i += 4 {
+		s := fmt.Sprintf("%s/%d", i.String(), api.VpcPeeringTransitMask)
+		if !utils.IsInStringArray(s, used) {
+			return s, nil
+		}
+	}
+	return "", errors.Wrap(errMappedIpExhausted, "vpc peering connections")
+}
+
+// AllocOvnTransitCidr allocates the transit cidr connecting routers of the
+// two onecloud vpcs
+func (self *SVpcPeeringConnection) AllocOvnTransitCidr(ctx context.Context) error {
+	if self.OvnTransitCidr != "" {
+		return nil
+	}
+	VpcPeeringConnectionManager.lockAllocOvnTransitCidr(ctx)
+	defer VpcPeeringConnectionManager.unlockAllocOvnTransitCidr(ctx)
+	cidr, err := VpcPeeringConnectionManager.allocOvnTransitCidr(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Update(self, func() error {
+		self.OvnTransitCidr = cidr
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "db update transit cidr")
+	}
+	return nil
+}
@@ -48,6 +48,11 @@ type ComputeOptions struct {
 
 	LoadbalancerPendingDeleteCheckInterval int `default:"3600" help:"Interval between checks of pending deleted loadbalancer objects, defaults to 1h"`
 
+	AcmeDirectoryUrl           string `default:"https://acme-v02.api.letsencrypt.org/directory" help:"Default ACME directory url for issuing loadbalancer certificates"`
+	AcmeInsecureSkipVerify     bool   `default:"false" help:"Skip tls verification of ACME server, for test servers like pebble"`
+	AcmeRenewCheckIntervalHour int    `default:"6" help:"Interval between checks of ACME loadbalancer certificates to renew, defaults to 6h"`
+	AcmeChallengeWaitSeconds   int    `default:"30" help:"Time to wait for lbagents and dns servers to publish ACME challenges before validation"`
+
 	ImageCacheStoragePolicy string `default:"least_used" choices:"best_fit|least_used" help:"Policy to choose storage for image cache, best_fit or least_used"`
 	MetricsRetentionDays    int32  `default:"30" help:"Retention days for monitoring metrics in influxdb"`
 
@@ -70,6 +75,10 @@ type ComputeOptions struct {
 	DefaultSnapshotQuota         int `default:"10" help:"Common snapshot quota per tenant, default 10"`
 	DefaultInstanceSnapshotQuota int `default:"10" help:"Common instance snapshot quota per tenant, default 10"`
 
+	DefaultPreemptibleServerQuota int `default:"50" help:"Common preemptible server quota per tenant, default 50"`
+	DefaultPreemptibleCpuQuota    int `default:"200" help:"Common preemptible server CPU quota per tenant, default 200"`
+	DefaultPreemptibleMemoryQuota int `default:"204800" help:"Common preemptible server memory quota per tenant in MB, default 200G"`
+
 	DefaultBucketQuota    int `default:"100" help:"Common bucket quota per tenant, default 100"`
 	DefaultObjectGBQuota  int `default:"500" help:"Common object size quota per tenant in GB, default 500GB"`
 	DefaultObjectCntQuota int `default:"5000" help:"Common object count quota per tenant, default 5000"`
@@ -122,6 +131,8 @@ type ComputeOptions struct {
 
 	SnapshotCreateDiskProtocol string `help:"Snapshot create disk protocol" choices:"url|fuse" default:"fuse"`
 
+	KeyManagementService string `help:"Key management service used to wrap data keys of encrypted disks" default:"keystone"`
+
 	HostOfflineMaxSeconds        int `help:"Maximal seconds interval that a host considered offline during which it did not ping region, default is 3 minues" default:"180"`
 	HostOfflineDetectionInterval int `help:"Interval to check offline hosts, defualt is half a minute" default:"30"`
 
@@ -159,6 +170,15 @@ type ComputeOptions struct {
 	SyncExtDiskSnapshotIntervalMinutes int  `help:"sync snapshot for external disk" default:"20"`
 	AutoReconcileBackupServers         bool `help:"auto reconcile backup servers" default:"false"`
 
+	RebalanceCheckIntervalSeconds int `help:"interval to check rebalance policies due for evaluation" default:"300"`
+	HostPowerCheckIntervalSeconds int `help:"interval to sync hosts in power transition and check host power policies due for evaluation" default:"300"`
+
+	SchedHistoryRetentionDays int `help:"days to keep scheduling decision histories, 0 means never clean" default:"30"`
+
+	PreemptionGracePeriodSeconds    int `help:"grace period before preempted servers are stopped or deleted" default:"30"`
+	PreemptionCheckIntervalSeconds  int `help:"interval to check preempted servers whose grace period is over" default:"10"`
+	PreemptionReleaseTimeoutSeconds int `help:"timeout for preempted servers to release resources before the preempting server fails to deploy" default:"600"`
+
 	SCapabilityOptions
 	SASControllerOptions
 	common_options.CommonOptions
@@ -175,6 +195,8 @@ type ComputeOptions struct {
 
 	ProhibitRefreshingCloudImage bool `help:"Prohibit refreshing cloud image"`
 
+	EnforceImageSignature bool `help:"Only allow deploying guests from images whose signature is verified by a trusted key"`
+
 	GlobalMacPrefix string `help:"Global prefix of MAC address, default to 00:22" default:"00:22"`
 
 	esxi.EsxiOptions
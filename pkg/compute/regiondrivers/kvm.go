@@ -93,11 +93,14 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerData(ctx context.Context
 	if zone == nil {
 		return nil, httperrors.NewInputParameterError("zone info missing")
 	}
+	networkType := api.LB_NETWORK_TYPE_CLASSIC
 	if vpc.Id != api.DEFAULT_VPC_ID {
-		return nil, httperrors.NewInputParameterError("vpc lb is not allowed for now")
-	}
-
-	if clusterV.Model == nil {
+		// vpc lb is realized by ovn load balancers of vpcagent, no lbcluster is involved
+		if clusterV.Model != nil {
+			return nil, httperrors.NewInputParameterError("vpc lb cannot be placed in lbcluster")
+		}
+		networkType = api.LB_NETWORK_TYPE_VPC
+	} else if clusterV.Model == nil {
 		clusters := models.LoadbalancerClusterManager.FindByZoneId(zone.Id)
 		if len(clusters) == 0 {
 			return nil, httperrors.NewInputParameterError("zone %s(%s) has no lbcluster", zone.Name, zone.Id)
@@ -141,11 +144,16 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerData(ctx context.Context
 	data.Set("cloudregion_id", jsonutils.NewString(region.GetId()))
 	data.Set("zone_id", jsonutils.NewString(zone.GetId()))
 	data.Set("vpc_id", jsonutils.NewString(vpc.GetId()))
-	data.Set("network_type", jsonutils.NewString(api.LB_NETWORK_TYPE_CLASSIC))
+	data.Set("network_type", jsonutils.NewString(networkType))
 	data.Set("address_type", jsonutils.NewString(api.LB_ADDR_TYPE_INTRANET))
 	return data, nil
 }
 
+// isVpcLoadbalancer tells whether the lb is served by ovn in a vpc instead of by lbagents of lbcluster
+func isVpcLoadbalancer(lb *models.SLoadbalancer) bool {
+	return lb != nil && lb.VpcId != "" && lb.VpcId != api.DEFAULT_VPC_ID
+}
+
 func (self *SKVMRegionDriver) ValidateCreateLoadbalancerAclData(ctx context.Context, userCred mcclient.TokenCredential, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
 	return data, nil
 }
@@ -194,6 +202,9 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerBackendData(ctx context.
 		basename = guest.Name
 		backend = backendV.Model
 	case api.LB_BACKEND_HOST:
+		if isVpcLoadbalancer(lb) {
+			return nil, httperrors.NewInputParameterError("host backend is not supported by vpc lb")
+		}
 		backendV := validators.NewModelIdOrNameValidator("backend", "host", userCred)
 		err := backendV.Validate(data)
 		if err != nil {
@@ -502,6 +513,15 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 		return nil, err
 	}
 
+	if isVpcLoadbalancer(lb) {
+		if listenerType != api.LB_LISTENER_TYPE_TCP && listenerType != api.LB_LISTENER_TYPE_UDP {
+			return nil, httperrors.NewInputParameterError("vpc lb supports only tcp and udp listener, got %s", listenerType)
+		}
+		if aclStatusV.Value == api.LB_BOOL_ON {
+			return nil, httperrors.NewInputParameterError("acl is not supported by vpc lb")
+		}
+	}
+
 	if redirectType := redirectV.Value; redirectType != api.LB_REDIRECT_OFF {
 		if listenerType != api.LB_LISTENER_TYPE_HTTP && listenerType != api.LB_LISTENER_TYPE_HTTPS {
 			return nil, httperrors.NewInputParameterError("redirect can only be enabled for http/https listener")
@@ -679,6 +699,15 @@ func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerData(ctx context
 	if err := models.LoadbalancerListenerManager.ValidateAcl(aclStatusV, aclTypeV, aclV, data, lblis.GetProviderName()); err != nil {
 		return nil, err
 	}
+	if aclStatusV.Value == api.LB_BOOL_ON {
+		lb, err := lblis.GetLoadbalancer()
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		if isVpcLoadbalancer(lb) {
+			return nil, httperrors.NewInputParameterError("acl is not supported by vpc lb")
+		}
+	}
 
 	{
 		if backendGroup == nil {
@@ -971,6 +1000,48 @@ func (self *SKVMRegionDriver) ValidateCreateEipData(ctx context.Context, userCre
 	return nil
 }
 
+func (self *SKVMRegionDriver) IsSupportedNatGateway() bool {
+	return true
+}
+
+func (self *SKVMRegionDriver) IsSupportedNatAutoRenew() bool {
+	return false
+}
+
+func (self *SKVMRegionDriver) ValidateCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, input api.NatgatewayCreateInput) (api.NatgatewayCreateInput, error) {
+	if input.VpcId == api.DEFAULT_VPC_ID {
+		return input, httperrors.NewUnsupportOperationError("nat gateway is not supported in default vpc")
+	}
+	_vpc, err := models.VpcManager.FetchById(input.VpcId)
+	if err != nil {
+		return input, httperrors.NewGeneralError(errors.Wrapf(err, "fetch vpc %s", input.VpcId))
+	}
+	vpc := _vpc.(*models.SVpc)
+	if !utils.IsInStringArray(vpc.ExternalAccessMode, []string{
+		api.VPC_EXTERNAL_ACCESS_MODE_EIP,
+		api.VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW,
+	}) {
+		return input, httperrors.NewInputParameterError("vpc %s external access mode %q does not support eip", vpc.Name, vpc.ExternalAccessMode)
+	}
+	if len(input.Duration) > 0 {
+		return input, httperrors.NewInputParameterError("%s does not support prepaid nat gateway", self.GetProvider())
+	}
+	if input.EipBw > 0 {
+		return input, httperrors.NewInputParameterError("%s does not support allocating eip for nat gateway, use an existing eip", self.GetProvider())
+	}
+	if len(input.Eip) == 0 {
+		return input, httperrors.NewMissingParameterError("eip")
+	}
+	return input, nil
+}
+
+func (self *SKVMRegionDriver) RequestSyncNatGatewayStatus(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nat.SetStatus(userCred, api.NAT_STAUTS_AVAILABLE, "syncstatus")
+	})
+	return nil
+}
+
 func (self *SKVMRegionDriver) ValidateSnapshotDelete(ctx context.Context, snapshot *models.SSnapshot) error {
 	storage := snapshot.GetStorage()
 	if storage == nil {
@@ -1238,7 +1309,11 @@ func (self *SKVMRegionDriver) RequestSyncSnapshotStatus(ctx context.Context, use
 }
 
 func (self *SKVMRegionDriver) RequestAssociateEipForNAT(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, eip *models.SElasticip, task taskman.ITask) error {
-	return errors.Wrapf(cloudprovider.ErrNotSupported, "RequestAssociateEipForNAT")
+	opts := api.ElasticipAssociateInput{
+		InstanceType: api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY,
+		InstanceId:   nat.Id,
+	}
+	return eip.StartEipAssociateTask(ctx, userCred, jsonutils.Marshal(opts).(*jsonutils.JSONDict), task.GetTaskId())
 }
 
 func (self *SKVMRegionDriver) RequestPreSnapshotPolicyApply(ctx context.Context, userCred mcclient.
@@ -1397,6 +1472,18 @@ func (self *SKVMRegionDriver) GetMaxElasticcacheSecurityGroupCount() int {
 
 func (self *SKVMRegionDriver) RequestAssociatEip(ctx context.Context, userCred mcclient.TokenCredential, eip *models.SElasticip, input api.ElasticipAssociateInput, obj db.IStatusStandaloneModel, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		if input.InstanceType == api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY {
+			// the eip is used as external address of nat entries,
+			// which are realized by vpcagent
+			nat := obj.(*models.SNatGateway)
+			if err := eip.AssociateInstance(ctx, userCred, api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY, nat); err != nil {
+				return nil, errors.Wrapf(err, "associate eip %s(%s) to nat %s(%s)", eip.Name, eip.Id, nat.Name, nat.Id)
+			}
+			if err := eip.SetStatus(userCred, api.EIP_STATUS_READY, api.EIP_STATUS_ASSOCIATE); err != nil {
+				return nil, errors.Wrapf(err, "set eip status to %s", api.EIP_STATUS_READY)
+			}
+			return nil, nil
+		}
 		if input.InstanceType != api.EIP_ASSOCIATE_TYPE_SERVER {
 			return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "instance type %s", input.InstanceType)
 		}
@@ -84,6 +84,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.GroupguestManager,
 
 		models.CloudproviderCapabilityManager,
+		models.SchedHistoryResourceManager,
 
 		models.ScalingTimerManager,
 		models.ScalingAlarmManager,
@@ -206,6 +207,12 @@ func InitHandlers(app *appsrv.Application) {
 
 		models.VpcPeeringConnectionManager,
 		models.InterVpcNetworkManager,
+		models.FlowLogManager,
+		models.RebalancePolicyManager,
+		models.RebalanceRecommendationManager,
+		models.HostPowerPolicyManager,
+		models.SchedHistoryManager,
+		models.CapacityReservationManager,
 
 		models.NatSkuManager,
 		models.NasSkuManager,
@@ -119,6 +119,7 @@ func StartService() {
 		cron.AddJobAtIntervals("CleanPendingDeleteServers", time.Duration(opts.PendingDeleteCheckSeconds)*time.Second, models.GuestManager.CleanPendingDeleteServers)
 		cron.AddJobAtIntervals("CleanPendingDeleteDisks", time.Duration(opts.PendingDeleteCheckSeconds)*time.Second, models.DiskManager.CleanPendingDeleteDisks)
 		cron.AddJobAtIntervals("CleanPendingDeleteLoadbalancers", time.Duration(opts.LoadbalancerPendingDeleteCheckInterval)*time.Second, models.LoadbalancerAgentManager.CleanPendingDeleteLoadbalancers)
+		cron.AddJobAtIntervalsWithStartRun("AutoRenewAcmeLoadbalancerCertificates", time.Duration(opts.AcmeRenewCheckIntervalHour)*time.Hour, models.LoadbalancerCertificateManager.AutoRenewAcmeCertificates, true)
 		if opts.PrepaidExpireCheck {
 			cron.AddJobAtIntervals("CleanExpiredPrepaidServers", time.Duration(opts.PrepaidExpireCheckSeconds)*time.Second, models.GuestManager.DeleteExpiredPrepaidServers)
 		}
@@ -162,6 +163,14 @@ func StartService() {
 
 		cron.AddJobEveryFewDays("SyncCloudImages", opts.SyncCloudImagesDay, opts.SyncCloudImagesHour, 0, 0, models.SyncPublicCloudImages, true)
 
+		cron.AddJobEveryFewHour("CleanExpiredFlowLogObjects", 6, 20, 0, models.FlowLogManager.CleanExpiredObjects, false)
+		cron.AddJobEveryFewHour("CleanExpiredSchedHistories", 6, 40, 0, models.SchedHistoryManager.CleanExpiredHistories, false)
+
+		cron.AddJobAtIntervals("AutoRebalanceHosts", time.Duration(opts.RebalanceCheckIntervalSeconds)*time.Second, models.RebalancePolicyManager.AutoRebalance)
+		cron.AddJobAtIntervals("AutoPowerSaveHosts", time.Duration(opts.HostPowerCheckIntervalSeconds)*time.Second, models.HostPowerPolicyManager.AutoPowerSave)
+		cron.AddJobAtIntervals("PreemptDueGuests", time.Duration(opts.PreemptionCheckIntervalSeconds)*time.Second, models.GuestManager.PreemptDueGuests)
+		cron.AddJobAtIntervals("SyncCapacityReservationStatus", time.Duration(60)*time.Second, models.CapacityReservationManager.SyncStatus)
+
 		cron.AddJobEveryFewHour("InspectAllTemplate", 1, 0, 0, models.GuestTemplateManager.InspectAllTemplate, true)
 
 		cron.AddJobAtIntervalsWithStartRun("ScheduledTaskCheck", time.Duration(60)*time.Second, models.ScheduledTaskManager.Timer, true)
@@ -44,13 +44,17 @@ func (self *BaremetalServerStartTask) OnInit(ctx context.Context, obj db.IStanda
 		self.OnStartCompleteFailed(ctx, guest, jsonutils.NewString("Baremetal is None"))
 		return
 	}
-	desc := guest.GetJsonDescAtBaremetal(ctx, baremetal)
+	desc, err := guest.GetJsonDescAtBaremetal(ctx, baremetal)
+	if err != nil {
+		self.OnStartCompleteFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		return
+	}
 	config := jsonutils.NewDict()
 	config.Set("desc", jsonutils.Marshal(desc))
 	url := fmt.Sprintf("/baremetals/%s/servers/%s/start", baremetal.Id, guest.Id)
 	headers := self.GetTaskRequestHeader()
 	self.SetStage("OnStartComplete", nil)
-	_, err := baremetal.BaremetalSyncRequest(ctx, "POST", url, headers, config)
+	_, err = baremetal.BaremetalSyncRequest(ctx, "POST", url, headers, config)
 	if err != nil {
 		log.Errorln(err)
 		self.OnStartCompleteFailed(ctx, guest, jsonutils.NewString(err.Error()))
@@ -140,7 +140,16 @@ func (self *GuestBatchCreateTask) allocateGuestOnHost(ctx context.Context, guest
 
 	host, _ := guest.GetHost()
 
-	quotaCpuMem := models.SQuota{Count: 1, Cpu: int(guest.VcpuCount), Memory: guest.VmemSize}
+	quotaCpuMem := models.SQuota{}
+	if guest.Preemptible {
+		quotaCpuMem.PreemptibleCount = 1
+		quotaCpuMem.PreemptibleCpu = int(guest.VcpuCount)
+		quotaCpuMem.PreemptibleMemory = guest.VmemSize
+	} else {
+		quotaCpuMem.Count = 1
+		quotaCpuMem.Cpu = int(guest.VcpuCount)
+		quotaCpuMem.Memory = guest.VmemSize
+	}
 	keys, err := guest.GetQuotaKeys()
 	if err != nil {
 		log.Errorf("guest.GetQuotaKeys fail %s", err)
@@ -145,12 +145,15 @@ func (self *GuestConvertEsxiToKvmTask) RequestHostCreateGuestFromEsxi(
 ) error {
 	host, _ := guest.GetHost()
 	params := jsonutils.NewDict()
-	desc := guest.GetJsonDescAtHypervisor(ctx, host)
+	desc, err := guest.GetJsonDescAtHypervisor(ctx, host)
+	if err != nil {
+		return err
+	}
 	params.Set("desc", jsonutils.Marshal(desc))
 	params.Set("esxi_access_info", esxiAccessInfo)
 	url := fmt.Sprintf("%s/servers/%s/create-form-esxi", host.ManagerUri, guest.Id)
 	header := self.GetTaskRequestHeader()
-	_, _, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, params, false)
+	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, params, false)
 	if err != nil {
 		return err
 	}
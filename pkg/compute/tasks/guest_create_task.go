@@ -99,9 +99,31 @@ func (self *GuestCreateTask) OnCdromPrepared(ctx context.Context, obj db.IStanda
 	log.Infof("DEPLOY GUEST %s", guest.Name)
 	log.Infof("XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX")
 	guest.SetStatus(self.UserCred, api.VM_DEPLOYING, "")
+	victims, err := guest.GetPreemptVictims()
+	if err != nil {
+		self.OnPreemptVictimsReleasedFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		return
+	}
+	if len(victims) > 0 {
+		// do not deploy onto the host until preempted guests are gone
+		self.SetStage("OnPreemptVictimsReleased", nil)
+		err = guest.StartReleasePreemptVictimsTask(ctx, self.UserCred, self.GetId())
+		if err != nil {
+			self.OnPreemptVictimsReleasedFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		}
+		return
+	}
 	self.StartDeployGuest(ctx, guest)
 }
 
+func (self *GuestCreateTask) OnPreemptVictimsReleased(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.StartDeployGuest(ctx, obj.(*models.SGuest))
+}
+
+func (self *GuestCreateTask) OnPreemptVictimsReleasedFailed(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.OnDeployGuestDescCompleteFailed(ctx, obj, data)
+}
+
 func (self *GuestCreateTask) OnCdromPreparedFailed(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
 	guest := obj.(*models.SGuest)
 	guest.SetStatus(self.UserCred, api.VM_DISK_FAILED, "")
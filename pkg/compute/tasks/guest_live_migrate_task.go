@@ -281,7 +281,11 @@ func (self *GuestMigrateTask) sharedStorageMigrateConf(ctx context.Context, gues
 	body := jsonutils.NewDict()
 	body.Set("is_local_storage", jsonutils.JSONFalse)
 	body.Set("qemu_version", jsonutils.NewString(guest.GetQemuVersion(self.UserCred)))
-	targetDesc := guest.GetJsonDescAtHypervisor(ctx, targetHost)
+	targetDesc, err := guest.GetJsonDescAtHypervisor(ctx, targetHost)
+	if err != nil {
+		self.TaskFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		return nil, true
+	}
 	body.Set("desc", jsonutils.Marshal(targetDesc))
 	return body, false
 }
@@ -313,7 +317,11 @@ func (self *GuestMigrateTask) localStorageMigrateConf(ctx context.Context,
 	body.Set("disks_uri", jsonutils.NewString(disksUri))
 	body.Set("server_url", jsonutils.NewString(serverUrl))
 	body.Set("qemu_version", jsonutils.NewString(guest.GetQemuVersion(self.UserCred)))
-	targetDesc := guest.GetJsonDescAtHypervisor(ctx, targetHost)
+	targetDesc, err := guest.GetJsonDescAtHypervisor(ctx, targetHost)
+	if err != nil {
+		self.TaskFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		return nil, true
+	}
 	if len(targetDesc.Disks) == 0 {
 		self.TaskFailed(ctx, guest, jsonutils.NewString("Get disksDesc error"))
 		return nil, true
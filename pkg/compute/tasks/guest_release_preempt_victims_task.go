@@ -0,0 +1,90 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/compute/options"
+)
+
+// GuestReleasePreemptVictimsTask waits until guests preempted by a guest
+// have released their resources on its host, stopping or deleting them once
+// their grace period is over, so that the guest is not deployed onto a host
+// still full of them
+type GuestReleasePreemptVictimsTask struct {
+	SGuestBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(GuestReleasePreemptVictimsTask{})
+}
+
+func (self *GuestReleasePreemptVictimsTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	timeout := options.Options.PreemptionGracePeriodSeconds + options.Options.PreemptionReleaseTimeoutSeconds
+	params := jsonutils.NewDict()
+	params.Set("deadline", jsonutils.NewTimeString(time.Now().Add(time.Duration(timeout)*time.Second)))
+	self.SaveParams(params)
+	self.SetStage("on_wait_victims_released", nil)
+	self.OnWaitVictimsReleased(ctx, obj, nil)
+}
+
+func (self *GuestReleasePreemptVictimsTask) OnWaitVictimsReleased(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guest := obj.(*models.SGuest)
+	victims, err := guest.GetPreemptVictims()
+	if err != nil {
+		self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+		return
+	}
+	if len(victims) == 0 {
+		self.SetStageComplete(ctx, nil)
+		return
+	}
+	deadline, _ := self.Params.GetTime("deadline")
+	if time.Now().After(deadline) {
+		self.SetStageFailed(ctx, jsonutils.NewString(fmt.Sprintf("%d preempted servers still hold resources on host", len(victims))))
+		return
+	}
+
+	self.SetStage("OnVictimsPreempted", nil)
+	started, err := guest.PreemptDueVictims(ctx, self.UserCred, victims, self.GetId())
+	if err != nil {
+		log.Errorf("guest %s release preempted guests: %v", guest.Name, err)
+	}
+	if !started {
+		// victims are in grace period or being stopped by others
+		self.SetStage("on_wait_victims_released", nil)
+		time.Sleep(time.Second * 2)
+		self.ScheduleRun(nil)
+	}
+}
+
+func (self *GuestReleasePreemptVictimsTask) OnVictimsPreempted(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.SetStage("on_wait_victims_released", nil)
+	self.OnWaitVictimsReleased(ctx, obj, nil)
+}
+
+func (self *GuestReleasePreemptVictimsTask) OnVictimsPreemptedFailed(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.SetStageFailed(ctx, data)
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// HostEnterMaintenanceTask moves guests off a cordoned host, at most
+// parallel guests at a time, and reports guests which could not be moved
+type HostEnterMaintenanceTask struct {
+	taskman.STask
+}
+
+// GuestColdMigrateTask stops a running guest, migrates it and starts it
+// again on the new host
+type GuestColdMigrateTask struct {
+	SGuestBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(HostEnterMaintenanceTask{})
+	taskman.RegisterTask(GuestColdMigrateTask{})
+}
+
+func (self *HostEnterMaintenanceTask) getGuests(key string) []api.HostMaintenanceGuest {
+	guests := []api.HostMaintenanceGuest{}
+	if self.Params.Contains(key) {
+		self.Params.Unmarshal(&guests, key)
+	}
+	return guests
+}
+
+func (self *HostEnterMaintenanceTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	host := obj.(*models.SHost)
+	self.migrateNext(ctx, host)
+}
+
+func (self *HostEnterMaintenanceTask) migrateNext(ctx context.Context, host *models.SHost) {
+	guests := self.getGuests("guests")
+	unmoved := self.getGuests("unmoved_guests")
+	parallel, _ := self.Params.Int("parallel")
+	preferHostId, _ := self.Params.GetString("prefer_host_id")
+
+	self.SetStage("OnGuestsMigrated", nil)
+	batch := []api.HostMaintenanceGuest{}
+	for len(guests) > 0 && int64(len(batch)) < parallel {
+		mg := guests[0]
+		guests = guests[1:]
+		guest := models.GuestManager.FetchGuestById(mg.Id)
+		if guest == nil || guest.HostId != host.Id {
+			continue
+		}
+		err := guest.StartMaintenanceMigrateTask(ctx, self.UserCred, mg.Policy, preferHostId, self.Id)
+		if err != nil {
+			log.Errorf("host %s enter maintenance: migrate guest %s: %v", host.Name, guest.Name, err)
+			mg.Reason = err.Error()
+			unmoved = append(unmoved, mg)
+			continue
+		}
+		batch = append(batch, mg)
+	}
+
+	params := jsonutils.NewDict()
+	params.Set("guests", jsonutils.Marshal(guests))
+	params.Set("unmoved_guests", jsonutils.Marshal(unmoved))
+	params.Set("batch", jsonutils.Marshal(batch))
+	self.SaveParams(params)
+	if len(batch) == 0 {
+		self.onMaintenanceComplete(ctx, host, unmoved)
+	}
+}
+
+// OnGuestsMigrated runs once all migrations of the batch ended, guests still
+// on the host are reported as unmoved
+func (self *HostEnterMaintenanceTask) OnGuestsMigrated(ctx context.Context, host *models.SHost, data jsonutils.JSONObject) {
+	unmoved := self.getGuests("unmoved_guests")
+	for _, mg := range self.getGuests("batch") {
+		guest := models.GuestManager.FetchGuestById(mg.Id)
+		if guest == nil || guest.HostId != host.Id {
+			continue
+		}
+		mg.Reason = fmt.Sprintf("migrate failed, guest status %s", guest.Status)
+		unmoved = append(unmoved, mg)
+	}
+	params := jsonutils.NewDict()
+	params.Set("unmoved_guests", jsonutils.Marshal(unmoved))
+	self.SaveParams(params)
+	self.migrateNext(ctx, host)
+}
+
+func (self *HostEnterMaintenanceTask) OnGuestsMigratedFailed(ctx context.Context, host *models.SHost, data jsonutils.JSONObject) {
+	self.OnGuestsMigrated(ctx, host, data)
+}
+
+func (self *HostEnterMaintenanceTask) onMaintenanceComplete(ctx context.Context, host *models.SHost, unmoved []api.HostMaintenanceGuest) {
+	result := jsonutils.NewDict()
+	result.Set("unmoved_guests", jsonutils.Marshal(unmoved))
+	host = models.HostManager.FetchHostById(host.Id)
+	if host == nil || !host.IsMaintenance {
+		// exited maintenance meanwhile
+		self.SetStageComplete(ctx, result)
+		return
+	}
+	if len(unmoved) == 0 {
+		host.SetStatus(self.UserCred, api.BAREMETAL_MAINTAINING, "enter maintenance")
+		logclient.AddActionLogWithStartable(self, host, logclient.ACT_HOST_MAINTAINING, result, self.UserCred, true)
+		self.SetStageComplete(ctx, result)
+		return
+	}
+	reason := fmt.Sprintf("%d guests not moved", len(unmoved))
+	host.SetStatus(self.UserCred, api.BAREMETAL_MAINTAIN_FAIL, reason)
+	logclient.AddActionLogWithStartable(self, host, logclient.ACT_HOST_MAINTAINING, result, self.UserCred, false)
+	self.SetStageFailed(ctx, result)
+}
+
+func (self *GuestColdMigrateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guest := obj.(*models.SGuest)
+	self.SetStage("OnGuestStopped", nil)
+	if guest.Status == api.VM_READY {
+		self.OnGuestStopped(ctx, guest, nil)
+		return
+	}
+	if err := guest.StartGuestStopTask(ctx, self.UserCred, false, false, self.GetTaskId()); err != nil {
+		self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *GuestColdMigrateTask) OnGuestStopped(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	preferHostId, _ := self.Params.GetString("prefer_host_id")
+	self.SetStage("OnGuestMigrated", nil)
+	if err := guest.StartMigrateTask(ctx, self.UserCred, false, true, api.VM_READY, preferHostId, self.GetTaskId()); err != nil {
+		self.OnGuestMigratedFailed(ctx, guest, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *GuestColdMigrateTask) OnGuestStoppedFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.SetStageFailed(ctx, data)
+}
+
+func (self *GuestColdMigrateTask) OnGuestMigrated(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.SetStageComplete(ctx, nil)
+}
+
+// OnGuestMigratedFailed starts the guest again, so that it is not left
+// stopped by a failed migration
+func (self *GuestColdMigrateTask) OnGuestMigratedFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	guest = models.GuestManager.FetchGuestById(guest.Id)
+	if guest != nil && guest.Status == api.VM_READY {
+		if err := guest.StartGueststartTask(ctx, self.UserCred, nil, ""); err != nil {
+			log.Errorf("start guest %s after failed cold migration: %v", guest.Name, err)
+		}
+	}
+	self.SetStageFailed(ctx, data)
+}
@@ -176,7 +176,10 @@ func (self *HostImportLibvirtServersTask) CreateImportedLibvirtGuestOnHost(
 		disksPath.Set(disk.DiskId, jsonutils.NewString(disk.AccessPath))
 	}
 	body := jsonutils.NewDict()
-	desc := guest.GetJsonDescAtHypervisor(ctx, host)
+	desc, err := guest.GetJsonDescAtHypervisor(ctx, host)
+	if err != nil {
+		return err
+	}
 	body.Set("desc", jsonutils.Marshal(desc))
 	body.Set("disks_path", disksPath)
 	if len(guestDesc.MonitorPath) > 0 {
@@ -0,0 +1,110 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// HostPowerOffTask powers off an evacuated kvm host through baremetal agent,
+// which stops the baremetal server the host runs on via IPMI or Redfish
+type HostPowerOffTask struct {
+	SBaremetalBaseTask
+}
+
+// HostPowerOnTask powers on a standby kvm host through baremetal agent
+type HostPowerOnTask struct {
+	SBaremetalBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(HostPowerOffTask{})
+	taskman.RegisterTask(HostPowerOnTask{})
+}
+
+func (self *HostPowerOffTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	host := obj.(*models.SHost)
+	server := host.GetBaremetalServer()
+	if server == nil {
+		self.OnPowerOffCompleteFailed(ctx, host, jsonutils.NewString("baremetal server not found"))
+		return
+	}
+	params := jsonutils.NewDict()
+	params.Set("timeout", jsonutils.NewInt(90))
+	url := fmt.Sprintf("/baremetals/%s/servers/%s/stop", host.Id, server.Id)
+	headers := self.GetTaskRequestHeader()
+	self.SetStage("OnPowerOffComplete", nil)
+	_, err := host.BaremetalSyncRequest(ctx, "POST", url, headers, params)
+	if err != nil {
+		self.OnPowerOffCompleteFailed(ctx, host, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *HostPowerOffTask) OnPowerOffComplete(ctx context.Context, host *models.SHost, body jsonutils.JSONObject) {
+	host.SetPowerState(self.UserCred, api.HOST_POWER_STATE_STANDBY, "")
+	logclient.AddActionLogWithStartable(self, host, logclient.ACT_VM_STOP, "power standby", self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *HostPowerOffTask) OnPowerOffCompleteFailed(ctx context.Context, host *models.SHost, reason jsonutils.JSONObject) {
+	// host is still up, let it take guests again
+	host.SetPowerState(self.UserCred, api.HOST_POWER_STATE_ON, reason.String())
+	logclient.AddActionLogWithStartable(self, host, logclient.ACT_VM_STOP, reason, self.UserCred, false)
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *HostPowerOnTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	host := obj.(*models.SHost)
+	server := host.GetBaremetalServer()
+	if server == nil {
+		self.OnPowerOnCompleteFailed(ctx, host, jsonutils.NewString("baremetal server not found"))
+		return
+	}
+	desc, err := server.GetJsonDescAtBaremetal(ctx, host)
+	if err != nil {
+		self.OnPowerOnCompleteFailed(ctx, host, jsonutils.NewString(err.Error()))
+		return
+	}
+	params := jsonutils.NewDict()
+	params.Set("desc", jsonutils.Marshal(desc))
+	url := fmt.Sprintf("/baremetals/%s/servers/%s/start", host.Id, server.Id)
+	headers := self.GetTaskRequestHeader()
+	self.SetStage("OnPowerOnComplete", nil)
+	_, err = host.BaremetalSyncRequest(ctx, "POST", url, headers, params)
+	if err != nil {
+		self.OnPowerOnCompleteFailed(ctx, host, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *HostPowerOnTask) OnPowerOnComplete(ctx context.Context, host *models.SHost, body jsonutils.JSONObject) {
+	// host stays powering_on until host agent reports online again
+	logclient.AddActionLogWithStartable(self, host, logclient.ACT_VM_START, "power resume", self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *HostPowerOnTask) OnPowerOnCompleteFailed(ctx context.Context, host *models.SHost, reason jsonutils.JSONObject) {
+	host.SetPowerState(self.UserCred, api.HOST_POWER_STATE_STANDBY, reason.String())
+	logclient.AddActionLogWithStartable(self, host, logclient.ACT_VM_START, reason, self.UserCred, false)
+	self.SetStageFailed(ctx, reason)
+}
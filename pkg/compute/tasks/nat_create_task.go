@@ -61,6 +61,13 @@ func (self *NatGatewayCreateTask) OnInit(ctx context.Context, obj db.IStandalone
 		return
 	}
 
+	if !vpc.IsManaged() {
+		// nat of onecloud vpc is realized by vpcagent on the vpc
+		// external router, nothing to create remotely
+		self.OnCreateNatGatewayCreateComplete(ctx, nat, nil)
+		return
+	}
+
 	opts.VpcId = vpc.ExternalId
 
 	if len(nat.NetworkId) > 0 {
@@ -87,6 +87,13 @@ func (self *SNatDEntryCreateTask) OnAssociateEipComplete(ctx context.Context, dn
 		self.taskFailed(ctx, dnat, errors.Wrapf(err, "dnat.GetNatgateway"))
 		return
 	}
+	if len(nat.GetCloudproviderId()) == 0 {
+		// dnat entries of onecloud vpc are realized by vpcagent
+		dnat.SetStatus(self.UserCred, api.NAT_STAUTS_AVAILABLE, "")
+		logclient.AddActionLogWithStartable(self, nat, logclient.ACT_NAT_CREATE_DNAT, nil, self.UserCred, true)
+		self.SetStageComplete(ctx, nil)
+		return
+	}
 	iNat, err := nat.GetINatGateway()
 	if err != nil {
 		self.taskFailed(ctx, dnat, errors.Wrapf(err, "nat.GetINatGateway"))
@@ -87,6 +87,13 @@ func (self *SNatSEntryCreateTask) OnAssociateEipComplete(ctx context.Context, sn
 		self.taskFailed(ctx, snat, errors.Wrapf(err, "snat.GetNatgateway"))
 		return
 	}
+	if len(nat.GetCloudproviderId()) == 0 {
+		// snat entries of onecloud vpc are realized by vpcagent
+		snat.SetStatus(self.UserCred, api.NAT_STAUTS_AVAILABLE, "")
+		logclient.AddActionLogWithStartable(self, nat, logclient.ACT_NAT_CREATE_SNAT, nil, self.UserCred, true)
+		self.SetStageComplete(ctx, nil)
+		return
+	}
 	iNat, err := nat.GetINatGateway()
 	if err != nil {
 		self.taskFailed(ctx, snat, errors.Wrapf(err, "nat.GetINatGateway"))
@@ -20,6 +20,9 @@ import (
 	"sort"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/stringutils"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
@@ -141,14 +144,53 @@ func doScheduleObjects(
 		return
 	}
 
+	// a fresh session per request, so that the decision the scheduler
+	// records in sched_histories can be found from the events of guests
+	schedInput.SessionId = stringutils.UUID4()
+	schedInput.ForGuests = make([]*schedapi.ForGuest, len(objs))
+	for i, obj := range objs {
+		schedInput.ForGuests[i] = &schedapi.ForGuest{
+			Id:   obj.GetId(),
+			Name: obj.GetName(),
+		}
+	}
+
 	output, err := doScheduleWithInput(ctx, task, schedInput, len(objs))
 	if err != nil {
+		logScheduleEvents(task, objs, schedInput.SessionId, nil, err.Error())
 		onSchedulerRequestFail(ctx, task, objs, jsonutils.NewString(err.Error()))
 		return
 	}
+	logScheduleEvents(task, objs, schedInput.SessionId, output.Candidates, "")
 	onSchedulerResults(ctx, task, objs, output.Candidates)
 }
 
+func logScheduleEvents(
+	task IScheduleTask,
+	objs []IScheduleModel,
+	sessionId string,
+	results []*schedapi.CandidateResource,
+	reason string,
+) {
+	// objs are matched with results in name order, same as onSchedulerResults
+	sort.Sort(sortedIScheduleModelList(objs))
+	for idx, obj := range objs {
+		notes := jsonutils.NewDict()
+		notes.Set("session_id", jsonutils.NewString(sessionId))
+		if idx < len(results) {
+			if len(results[idx].Error) > 0 {
+				notes.Set("error", jsonutils.NewString(results[idx].Error))
+			} else {
+				notes.Set("host_id", jsonutils.NewString(results[idx].HostId))
+				notes.Set("host", jsonutils.NewString(results[idx].Name))
+			}
+		} else if len(reason) > 0 {
+			notes.Set("error", jsonutils.NewString(reason))
+		}
+		db.OpsLog.LogEvent(obj, db.ACT_SCHEDULE_DECISION, notes, task.GetUserCred())
+	}
+}
+
 func cancelPendingUsage(ctx context.Context, task IScheduleTask) {
 	ClearTaskPendingUsage(ctx, task.(taskman.ITask))
 	ClearTaskPendingRegionUsage(ctx, task.(taskman.ITask))
@@ -214,7 +256,11 @@ func onSchedulerResults(
 
 		if result.BackupCandidate == nil {
 			// normal schedule
-			onScheduleSucc(ctx, task, obj, result)
+			err := onScheduleSucc(ctx, task, obj, result)
+			if err != nil {
+				onObjScheduleFail(ctx, task, obj, jsonutils.NewString(err.Error()))
+				continue
+			}
 		} else {
 			// backup schedule
 			onMasterSlaveScheduleSucc(ctx, task, obj, result, result.BackupCandidate)
@@ -245,11 +291,21 @@ func onScheduleSucc(
 	task IScheduleTask,
 	obj IScheduleModel,
 	candidate *schedapi.CandidateResource,
-) {
+) error {
 	hostId := candidate.HostId
 	lockman.LockRawObject(ctx, models.HostManager.KeywordPlural(), hostId)
 	defer lockman.ReleaseRawObject(ctx, models.HostManager.KeywordPlural(), hostId)
 
+	if len(candidate.PreemptGuests) > 0 {
+		// the resources of guests failed to be preempted are not released
+		err := models.GuestManager.PreemptGuests(ctx, task.GetUserCred(), obj, hostId, candidate.PreemptGuests)
+		if err != nil {
+			log.Errorf("preempt guests %v on host %s for %s: %v", candidate.PreemptGuests, hostId, obj.GetName(), err)
+			models.HostManager.ClearSchedDescSessionCache(candidate.HostId, candidate.SessionId)
+			return errors.Wrapf(err, "preempt guests on host %s", candidate.Name)
+		}
+	}
 	task.SaveScheduleResult(ctx, obj, candidate)
 	models.HostManager.ClearSchedDescSessionCache(candidate.HostId, candidate.SessionId)
+	return nil
 }
@@ -58,6 +58,19 @@ func (self *VpcPeeringConnectionCreateTask) OnInit(ctx context.Context, obj db.I
 		return
 	}
 
+	if !vpc.IsManaged() {
+		// routers of onecloud vpcs are connected by vpcagent through
+		// the transit cidr
+		err := peer.AllocOvnTransitCidr(ctx)
+		if err != nil {
+			self.taskFailed(ctx, peer, errors.Wrapf(err, "AllocOvnTransitCidr"))
+			return
+		}
+		peer.SetStatus(self.GetUserCred(), api.VPC_PEERING_CONNECTION_STATUS_ACTIVE, "")
+		self.taskComplete(ctx, peer)
+		return
+	}
+
 	iVpc, err := vpc.GetIVpc()
 	if err != nil {
 		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetIVpc"))
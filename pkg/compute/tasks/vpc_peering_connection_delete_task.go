@@ -52,6 +52,11 @@ func (self *VpcPeeringConnectionDeleteTask) taskComplete(ctx context.Context, pe
 func (self *VpcPeeringConnectionDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
 	peer := obj.(*models.SVpcPeeringConnection)
 
+	if len(peer.ExternalId) == 0 {
+		self.taskComplete(ctx, peer)
+		return
+	}
+
 	vpc, err := peer.GetVpc()
 	if err != nil {
 		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetVpc"))
@@ -64,11 +69,6 @@ func (self *VpcPeeringConnectionDeleteTask) OnInit(ctx context.Context, obj db.I
 		return
 	}
 
-	if len(peer.ExternalId) == 0 {
-		self.taskComplete(ctx, peer)
-		return
-	}
-
 	iPeer, err := iVpc.GetICloudVpcPeeringConnectionById(peer.ExternalId)
 	if err != nil {
 		if errors.Cause(err) != cloudprovider.ErrNotFound {
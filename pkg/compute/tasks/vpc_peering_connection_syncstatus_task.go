@@ -51,6 +51,16 @@ func (self *VpcPeeringConnectionSyncstatusTask) OnInit(ctx context.Context, obj
 		return
 	}
 
+	if !svpc.IsManaged() {
+		status := api.VPC_PEERING_CONNECTION_STATUS_ACTIVE
+		if len(peer.OvnTransitCidr) == 0 {
+			status = api.VPC_PEERING_CONNECTION_STATUS_UNKNOWN
+		}
+		peer.SetStatus(self.GetUserCred(), status, "syncstatus")
+		self.SetStageComplete(ctx, nil)
+		return
+	}
+
 	extVpc, err := svpc.GetIVpc()
 	if err != nil {
 		self.taskFail(ctx, peer, errors.Wrap(err, "svpc.GetIVpc()"))
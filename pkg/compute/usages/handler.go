@@ -852,6 +852,10 @@ func guestHypervisorsUsage(
 	count[fmt.Sprintf("%s.ha.memory", prefix)] = guest.TotalBackupMemSize
 	count[fmt.Sprintf("%s.ha.disk", prefix)] = guest.TotalBackupDiskSize
 
+	count[fmt.Sprintf("%s.preemptible", prefix)] = guest.TotalPreemptibleGuestCount
+	count[fmt.Sprintf("%s.preemptible.cpu", prefix)] = guest.TotalPreemptibleCpuCount
+	count[fmt.Sprintf("%s.preemptible.memory", prefix)] = guest.TotalPreemptibleMemSize
+
 	return count
 }
 
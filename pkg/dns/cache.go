@@ -0,0 +1,634 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/util/netutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/informer"
+)
+
+const (
+	defaultCacheResyncInterval = 5 * time.Minute
+)
+
+// sCacheKind describes how objects of a region resource are loaded,
+// decoded from watch events and indexed in memory
+type sCacheKind struct {
+	keyword       string
+	keywordPlural string
+
+	// fetch loads all objects from database for full resync
+	fetch func() ([]interface{}, error)
+	// decode converts object carried by watch events
+	decode func(obj *jsonutils.JSONDict) (interface{}, error)
+	// keep returns false for objects that should not be served, e.g.
+	// pending deleted guests
+	keep      func(obj interface{}) bool
+	getId     func(obj interface{}) string
+	indexKeys func(obj interface{}) []string
+}
+
+// KeyString implements informer.IResourceManager
+func (kind *sCacheKind) KeyString() string {
+	return kind.keywordPlural
+}
+
+// GetKeyword implements informer.IResourceManager
+func (kind *sCacheKind) GetKeyword() string {
+	return kind.keyword
+}
+
+// sCacheStore holds objects of one kind and serves as event handler of its
+// watcher.  Objects are kept when full resync fails so that stale answers can
+// still be served while database is unreachable
+type sCacheStore struct {
+	kind *sCacheKind
+
+	lock         sync.RWMutex
+	objs         map[string]interface{}
+	index        map[string]map[string]interface{}
+	ready        bool
+	syncedAt     time.Time
+	syncFailures int64
+}
+
+func newCacheStore(kind *sCacheKind) *sCacheStore {
+	return &sCacheStore{
+		kind:  kind,
+		objs:  map[string]interface{}{},
+		index: map[string]map[string]interface{}{},
+	}
+}
+
+func (s *sCacheStore) resync() error {
+	objs, err := s.kind.fetch()
+	if err != nil {
+		s.lock.Lock()
+		s.syncFailures += 1
+		s.lock.Unlock()
+		cacheSyncFailures.WithLabelValues(s.kind.keywordPlural).Inc()
+		return errors.Wrapf(err, "fetch %s", s.kind.keywordPlural)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.objs = map[string]interface{}{}
+	s.index = map[string]map[string]interface{}{}
+	for _, obj := range objs {
+		s.addLocked(obj)
+	}
+	s.ready = true
+	s.syncedAt = time.Now()
+	return nil
+}
+
+func (s *sCacheStore) addLocked(obj interface{}) {
+	if s.kind.keep != nil && !s.kind.keep(obj) {
+		return
+	}
+	id := s.kind.getId(obj)
+	s.objs[id] = obj
+	for _, key := range s.kind.indexKeys(obj) {
+		objs, ok := s.index[key]
+		if !ok {
+			objs = map[string]interface{}{}
+			s.index[key] = objs
+		}
+		objs[id] = obj
+	}
+}
+
+func (s *sCacheStore) removeLocked(id string) {
+	obj, ok := s.objs[id]
+	if !ok {
+		return
+	}
+	delete(s.objs, id)
+	for _, key := range s.kind.indexKeys(obj) {
+		if objs, ok := s.index[key]; ok {
+			delete(objs, id)
+			if len(objs) == 0 {
+				delete(s.index, key)
+			}
+		}
+	}
+}
+
+func (s *sCacheStore) upsert(obj interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.removeLocked(s.kind.getId(obj))
+	s.addLocked(obj)
+}
+
+func (s *sCacheStore) remove(obj interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.removeLocked(s.kind.getId(obj))
+}
+
+func (s *sCacheStore) isReady() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.ready
+}
+
+func (s *sCacheStore) age() time.Duration {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.syncedAt.IsZero() {
+		return 0
+	}
+	return time.Since(s.syncedAt)
+}
+
+func (s *sCacheStore) count() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.objs)
+}
+
+func (s *sCacheStore) get(id string) interface{} {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.objs[id]
+}
+
+// lookup returns objects with the index key, ordered by id
+func (s *sCacheStore) lookup(key string) []interface{} {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return sortedCacheObjs(s.index[key], s.kind.getId)
+}
+
+func (s *sCacheStore) list() []interface{} {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return sortedCacheObjs(s.objs, s.kind.getId)
+}
+
+func sortedCacheObjs(objs map[string]interface{}, getId func(obj interface{}) string) []interface{} {
+	ret := make([]interface{}, 0, len(objs))
+	for _, obj := range objs {
+		ret = append(ret, obj)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return getId(ret[i]) < getId(ret[j])
+	})
+	return ret
+}
+
+func (s *sCacheStore) decode(obj *jsonutils.JSONDict) interface{} {
+	ret, err := s.kind.decode(obj)
+	if err != nil {
+		log.Errorf("decode %s event object: %v", s.kind.keyword, err)
+		return nil
+	}
+	return ret
+}
+
+// OnAdd implements informer.EventHandler
+func (s *sCacheStore) OnAdd(obj *jsonutils.JSONDict) {
+	if o := s.decode(obj); o != nil {
+		s.upsert(o)
+	}
+}
+
+// OnUpdate implements informer.EventHandler
+func (s *sCacheStore) OnUpdate(oldObj, newObj *jsonutils.JSONDict) {
+	if o := s.decode(newObj); o != nil {
+		s.upsert(o)
+	}
+}
+
+// OnDelete implements informer.EventHandler
+func (s *sCacheStore) OnDelete(obj *jsonutils.JSONDict) {
+	if o := s.decode(obj); o != nil {
+		s.remove(o)
+	}
+}
+
+func cacheKeyName(name string) string {
+	return "name:" + name
+}
+
+func cacheKeyIp(ip string) string {
+	return "ip:" + ip
+}
+
+func cacheKeyGuest(guestId string) string {
+	return "guest:" + guestId
+}
+
+func newCacheKind(
+	manager db.IModelManager,
+	newObj func() db.IModel,
+	fetch func() ([]interface{}, error),
+	keep func(obj interface{}) bool,
+	getId func(obj interface{}) string,
+	indexKeys func(obj interface{}) []string,
+) *sCacheKind {
+	kind := &sCacheKind{
+		keyword:       manager.Keyword(),
+		keywordPlural: manager.KeywordPlural(),
+		fetch:         fetch,
+		decode: func(obj *jsonutils.JSONDict) (interface{}, error) {
+			o := newObj()
+			if err := obj.Unmarshal(o); err != nil {
+				return nil, err
+			}
+			o.SetModelManager(manager, o)
+			return o, nil
+		},
+		keep: keep,
+		getId: func(obj interface{}) string {
+			return obj.(db.IModel).GetId()
+		},
+		indexKeys: indexKeys,
+	}
+	if getId != nil {
+		kind.getId = getId
+	}
+	return kind
+}
+
+func noCacheIndexKeys(obj interface{}) []string {
+	return nil
+}
+
+type sRegionDNSCache struct {
+	dnsrecords    *sCacheStore
+	hosts         *sCacheStore
+	guests        *sCacheStore
+	guestnetworks *sCacheStore
+	hostnetworks  *sCacheStore
+	networks      *sCacheStore
+	wires         *sCacheStore
+}
+
+func newRegionDNSCache() *sRegionDNSCache {
+	return &sRegionDNSCache{
+		dnsrecords: newCacheStore(newCacheKind(
+			models.DnsRecordManager,
+			func() db.IModel { return &models.SDnsRecord{} },
+			func() ([]interface{}, error) {
+				objs := []models.SDnsRecord{}
+				err := db.FetchModelObjects(models.DnsRecordManager, models.DnsRecordManager.Query(), &objs)
+				ret := make([]interface{}, len(objs))
+				for i := range objs {
+					ret[i] = &objs[i]
+				}
+				return ret, err
+			},
+			func(obj interface{}) bool {
+				return obj.(*models.SDnsRecord).Enabled.IsTrue()
+			},
+			nil,
+			func(obj interface{}) []string {
+				return []string{cacheKeyName(obj.(*models.SDnsRecord).Name)}
+			},
+		)),
+		hosts: newCacheStore(newCacheKind(
+			models.HostManager,
+			func() db.IModel { return &models.SHost{} },
+			func() ([]interface{}, error) {
+				objs := []models.SHost{}
+				err := db.FetchModelObjects(models.HostManager, models.HostManager.Query(), &objs)
+				ret := make([]interface{}, len(objs))
+				for i := range objs {
+					ret[i] = &objs[i]
+				}
+				return ret, err
+			},
+			nil,
+			nil,
+			func(obj interface{}) []string {
+				return []string{cacheKeyName(obj.(*models.SHost).Name)}
+			},
+		)),
+		guests: newCacheStore(newCacheKind(
+			models.GuestManager,
+			func() db.IModel { return &models.SGuest{} },
+			func() ([]interface{}, error) {
+				objs := []models.SGuest{}
+				err := db.FetchModelObjects(models.GuestManager, models.GuestManager.Query(), &objs)
+				ret := make([]interface{}, len(objs))
+				for i := range objs {
+					ret[i] = &objs[i]
+				}
+				return ret, err
+			},
+			func(obj interface{}) bool {
+				return !obj.(*models.SGuest).PendingDeleted
+			},
+			nil,
+			func(obj interface{}) []string {
+				return []string{cacheKeyName(obj.(*models.SGuest).Name)}
+			},
+		)),
+		guestnetworks: newCacheStore(newCacheKind(
+			models.GuestnetworkManager,
+			func() db.IModel { return &models.SGuestnetwork{} },
+			func() ([]interface{}, error) {
+				objs := []models.SGuestnetwork{}
+				err := db.FetchModelObjects(models.GuestnetworkManager, models.GuestnetworkManager.Query(), &objs)
+				ret := make([]interface{}, len(objs))
+				for i := range objs {
+					ret[i] = &objs[i]
+				}
+				return ret, err
+			},
+			nil,
+			func(obj interface{}) string {
+				return strconv.FormatInt(obj.(*models.SGuestnetwork).RowId, 10)
+			},
+			func(obj interface{}) []string {
+				gn := obj.(*models.SGuestnetwork)
+				keys := []string{cacheKeyGuest(gn.GuestId)}
+				if len(gn.IpAddr) > 0 {
+					keys = append(keys, cacheKeyIp(gn.IpAddr))
+				}
+				return keys
+			},
+		)),
+		hostnetworks: newCacheStore(newCacheKind(
+			models.HostnetworkManager,
+			func() db.IModel { return &models.SHostnetwork{} },
+			func() ([]interface{}, error) {
+				objs := []models.SHostnetwork{}
+				err := db.FetchModelObjects(models.HostnetworkManager, models.HostnetworkManager.Query(), &objs)
+				ret := make([]interface{}, len(objs))
+				for i := range objs {
+					ret[i] = &objs[i]
+				}
+				return ret, err
+			},
+			nil,
+			func(obj interface{}) string {
+				return strconv.FormatInt(obj.(*models.SHostnetwork).RowId, 10)
+			},
+			func(obj interface{}) []string {
+				hn := obj.(*models.SHostnetwork)
+				if len(hn.IpAddr) == 0 {
+					return nil
+				}
+				return []string{cacheKeyIp(hn.IpAddr)}
+			},
+		)),
+		networks: newCacheStore(newCacheKind(
+			models.NetworkManager,
+			func() db.IModel { return &models.SNetwork{} },
+			func() ([]interface{}, error) {
+				objs := []models.SNetwork{}
+				err := db.FetchModelObjects(models.NetworkManager, models.NetworkManager.Query(), &objs)
+				ret := make([]interface{}, len(objs))
+				for i := range objs {
+					ret[i] = &objs[i]
+				}
+				return ret, err
+			},
+			nil,
+			nil,
+			noCacheIndexKeys,
+		)),
+		wires: newCacheStore(newCacheKind(
+			models.WireManager,
+			func() db.IModel { return &models.SWire{} },
+			func() ([]interface{}, error) {
+				objs := []models.SWire{}
+				err := db.FetchModelObjects(models.WireManager, models.WireManager.Query(), &objs)
+				ret := make([]interface{}, len(objs))
+				for i := range objs {
+					ret[i] = &objs[i]
+				}
+				return ret, err
+			},
+			nil,
+			nil,
+			noCacheIndexKeys,
+		)),
+	}
+}
+
+func (c *sRegionDNSCache) stores() []*sCacheStore {
+	return []*sCacheStore{
+		c.dnsrecords,
+		c.hosts,
+		c.guests,
+		c.guestnetworks,
+		c.hostnetworks,
+		c.networks,
+		c.wires,
+	}
+}
+
+// isReady returns true when all kinds have been fully synced at least once.
+// Queries go to database before that
+func (c *sRegionDNSCache) isReady() bool {
+	for _, s := range c.stores() {
+		if !s.isReady() {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *sRegionDNSCache) resync() {
+	for _, s := range c.stores() {
+		if err := s.resync(); err != nil {
+			log.Warningf("resync region dns cache, keep serving stale %s of age %s: %v", s.kind.keywordPlural, s.age(), err)
+		}
+	}
+}
+
+func (c *sRegionDNSCache) startResync(interval time.Duration) {
+	go func() {
+		c.resync()
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		for range tick.C {
+			c.resync()
+		}
+	}()
+}
+
+func (c *sRegionDNSCache) startWatch(region string) {
+	ctx := context.Background()
+	s := auth.GetAdminSession(ctx, region, "")
+	informer.NewWatchManagerBySessionBg(s, func(watchMan *informer.SWatchManager) error {
+		for _, store := range c.stores() {
+			if err := watchMan.For(store.kind).AddEventHandler(ctx, store); err != nil {
+				return errors.Wrapf(err, "watch resource %s", store.kind.keywordPlural)
+			}
+		}
+		return nil
+	})
+}
+
+// queryDns mirrors models.DnsRecordManager.QueryDns
+func (c *sRegionDNSCache) queryDns(projectId, name string) *models.SDnsRecord {
+	for _, obj := range c.dnsrecords.lookup(cacheKeyName(name)) {
+		rec := obj.(*models.SDnsRecord)
+		if rec.IsPublic || (len(projectId) > 0 && rec.ProjectId == projectId) {
+			return rec
+		}
+	}
+	return nil
+}
+
+// getHostByName returns the host only when the name is unique, as does
+// db.FetchByName
+func (c *sRegionDNSCache) getHostByName(name string) *models.SHost {
+	objs := c.hosts.lookup(cacheKeyName(name))
+	if len(objs) != 1 {
+		return nil
+	}
+	return objs[0].(*models.SHost)
+}
+
+// getGuestIpsWithName mirrors models.GuestManager.GetIpInProjectWithName
+func (c *sRegionDNSCache) getGuestIpsWithName(name string) []string {
+	intIps := []string{}
+	extIps := []string{}
+	for _, guest := range c.guests.lookup(cacheKeyName(name)) {
+		for _, obj := range c.guestnetworks.lookup(cacheKeyGuest(guest.(*models.SGuest).Id)) {
+			gn := obj.(*models.SGuestnetwork)
+			if len(gn.IpAddr) == 0 {
+				continue
+			}
+			network, ok := c.networks.get(gn.NetworkId).(*models.SNetwork)
+			if !ok || len(network.GuestGateway) == 0 {
+				continue
+			}
+			addr, _ := netutils.NewIPV4Addr(gn.IpAddr)
+			if netutils.IsExitAddress(addr) {
+				extIps = append(extIps, gn.IpAddr)
+			} else {
+				intIps = append(intIps, gn.IpAddr)
+			}
+		}
+	}
+	if len(intIps) > 0 {
+		return intIps
+	}
+	return extIps
+}
+
+func (c *sRegionDNSCache) getGuestByAddress(ip string) *models.SGuest {
+	for _, obj := range c.guestnetworks.lookup(cacheKeyIp(ip)) {
+		if guest, ok := c.guests.get(obj.(*models.SGuestnetwork).GuestId).(*models.SGuest); ok {
+			return guest
+		}
+	}
+	return nil
+}
+
+func (c *sRegionDNSCache) getHostByAddress(ip string) *models.SHost {
+	for _, obj := range c.hostnetworks.lookup(cacheKeyIp(ip)) {
+		if host, ok := c.hosts.get(obj.(*models.SHostnetwork).BaremetalId).(*models.SHost); ok {
+			return host
+		}
+	}
+	return nil
+}
+
+// getOnPremiseNetworkOfIP mirrors models.NetworkManager.GetOnPremiseNetworkOfIP
+// without server type and is_public filters
+func (c *sRegionDNSCache) getOnPremiseNetworkOfIP(ip string) *models.SNetwork {
+	addr, err := netutils.NewIPV4Addr(ip)
+	if err != nil {
+		return nil
+	}
+	for _, obj := range c.networks.list() {
+		network := obj.(*models.SNetwork)
+		wire, ok := c.wires.get(network.WireId).(*models.SWire)
+		if !ok || wire.VpcId != api.DEFAULT_VPC_ID {
+			continue
+		}
+		if network.IsAddressInRange(addr) {
+			return network
+		}
+	}
+	return nil
+}
+
+// useCache tells whether lookups should be served from memory.  Once synced,
+// the cache is used even if later resync fails
+func (r *SRegionDNS) useCache() bool {
+	return r.cache != nil && r.cache.isReady()
+}
+
+func (r *SRegionDNS) queryDns(projectId, name string) *models.SDnsRecord {
+	if r.useCache() {
+		return r.cache.queryDns(projectId, name)
+	}
+	return models.DnsRecordManager.QueryDns(projectId, name)
+}
+
+func (r *SRegionDNS) getHostByName(name string) *models.SHost {
+	if r.useCache() {
+		return r.cache.getHostByName(name)
+	}
+	host, _ := models.HostManager.FetchByName(nil, name)
+	if host == nil {
+		return nil
+	}
+	return host.(*models.SHost)
+}
+
+func (r *SRegionDNS) getGuestIpsWithName(projectId, name string) []string {
+	if r.useCache() {
+		return r.cache.getGuestIpsWithName(name)
+	}
+	wantOnlyExit := false
+	return models.GuestManager.GetIpInProjectWithName(projectId, name, wantOnlyExit)
+}
+
+func (r *SRegionDNS) getGuestByAddress(ip string) *models.SGuest {
+	if r.useCache() {
+		return r.cache.getGuestByAddress(ip)
+	}
+	return models.GuestnetworkManager.GetGuestByAddress(ip)
+}
+
+func (r *SRegionDNS) getHostByAddress(ip string) *models.SHost {
+	if r.useCache() {
+		return r.cache.getHostByAddress(ip)
+	}
+	return models.HostnetworkManager.GetHostByAddress(ip)
+}
+
+func (r *SRegionDNS) getOnPremiseNetworkOfIP(ip string) *models.SNetwork {
+	if r.useCache() {
+		return r.cache.getOnPremiseNetworkOfIP(ip)
+	}
+	network, _ := models.NetworkManager.GetOnPremiseNetworkOfIP(ip, "", tristate.None)
+	return network
+}
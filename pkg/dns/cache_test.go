@@ -0,0 +1,95 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"fmt"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+)
+
+type testCacheObj struct {
+	Id   string
+	Name string
+}
+
+func newTestCacheStore(fetch func() ([]interface{}, error)) *sCacheStore {
+	return newCacheStore(&sCacheKind{
+		keyword:       "test",
+		keywordPlural: "tests",
+		fetch:         fetch,
+		decode: func(obj *jsonutils.JSONDict) (interface{}, error) {
+			o := &testCacheObj{}
+			return o, obj.Unmarshal(o)
+		},
+		keep: func(obj interface{}) bool {
+			return obj.(*testCacheObj).Name != "hidden"
+		},
+		getId: func(obj interface{}) string {
+			return obj.(*testCacheObj).Id
+		},
+		indexKeys: func(obj interface{}) []string {
+			return []string{cacheKeyName(obj.(*testCacheObj).Name)}
+		},
+	})
+}
+
+func TestCacheStore(t *testing.T) {
+	fail := false
+	s := newTestCacheStore(func() ([]interface{}, error) {
+		if fail {
+			return nil, fmt.Errorf("database unreachable")
+		}
+		return []interface{}{
+			&testCacheObj{Id: "2", Name: "foo"},
+			&testCacheObj{Id: "1", Name: "foo"},
+			&testCacheObj{Id: "3", Name: "hidden"},
+		}, nil
+	})
+	if s.isReady() {
+		t.Fatalf("store should not be ready before first resync")
+	}
+	if err := s.resync(); err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+	objs := s.lookup(cacheKeyName("foo"))
+	if len(objs) != 2 || objs[0].(*testCacheObj).Id != "1" {
+		t.Fatalf("lookup foo: %#v", objs)
+	}
+	if s.count() != 2 {
+		t.Fatalf("hidden object should not be kept, count %d", s.count())
+	}
+
+	s.OnUpdate(nil, jsonutils.Marshal(&testCacheObj{Id: "1", Name: "bar"}).(*jsonutils.JSONDict))
+	if objs := s.lookup(cacheKeyName("foo")); len(objs) != 1 {
+		t.Fatalf("stale index after update: %#v", objs)
+	}
+	if objs := s.lookup(cacheKeyName("bar")); len(objs) != 1 {
+		t.Fatalf("lookup bar after update: %#v", objs)
+	}
+	s.OnDelete(jsonutils.Marshal(&testCacheObj{Id: "2", Name: "foo"}).(*jsonutils.JSONDict))
+	if objs := s.lookup(cacheKeyName("foo")); len(objs) != 0 {
+		t.Fatalf("lookup foo after delete: %#v", objs)
+	}
+
+	fail = true
+	if err := s.resync(); err == nil {
+		t.Fatalf("resync should fail")
+	}
+	if !s.isReady() || s.count() != 1 || s.syncFailures != 1 {
+		t.Fatalf("stale objects should be kept on resync failure, count %d, failures %d", s.count(), s.syncFailures)
+	}
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/metrics"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
@@ -44,7 +45,6 @@ import (
 	"yunion.io/x/sqlchemy"
 
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
-	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/util/k8s"
@@ -86,13 +86,19 @@ type SRegionDNS struct {
 	AdminPassword string
 	Region        string
 	K8sSkip       bool
+	// CacheSkip makes all lookups go to database
+	CacheSkip           bool
+	CacheResyncInterval time.Duration
 
 	K8sManager            *k8s.SKubeClusterManager
 	primaryZoneLabelCount int
+	cache                 *sRegionDNSCache
 }
 
 func New() *SRegionDNS {
-	r := &SRegionDNS{}
+	r := &SRegionDNS{
+		CacheResyncInterval: defaultCacheResyncInterval,
+	}
 	return r
 }
 
@@ -118,11 +124,21 @@ func (r *SRegionDNS) initDB(c *caddy.Controller) error {
 }
 
 func (r *SRegionDNS) initK8s() {
-	r.initAuth()
 	r.K8sManager = k8s.NewKubeClusterManager(r.Region, 30*time.Second)
 	r.K8sManager.Start()
 }
 
+// initCache starts periodic full resync of the in-memory index, and watches
+// region resources for changes between resyncs when auth is configured
+func (r *SRegionDNS) initCache(c *caddy.Controller) {
+	r.cache = newRegionDNSCache()
+	r.cache.startResync(r.CacheResyncInterval)
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, r.cache.collectors()...)
+		return nil
+	})
+}
+
 func (r *SRegionDNS) getAdminSession(ctx context.Context) *mcclient.ClientSession {
 	return auth.GetAdminSession(ctx, r.Region, "")
 }
@@ -250,7 +266,7 @@ func (r *SRegionDNS) IsNameError(err error) bool {
 
 // Records looks up records in region mysql
 func (r *SRegionDNS) Records(state request.Request, exact bool) ([]msg.Service, error) {
-	req, e := parseRequest(state)
+	req, e := r.parseRequest(state)
 	if e != nil {
 		return nil, e
 	}
@@ -259,21 +275,18 @@ func (r *SRegionDNS) Records(state request.Request, exact bool) ([]msg.Service,
 
 func (r *SRegionDNS) getHostIpWithName(req *recordRequest) string {
 	name := req.QueryName()
-	host, _ := models.HostManager.FetchByName(nil, name)
+	host := r.getHostByName(name)
 	if host == nil {
 		return ""
 	}
-	ip := host.(*models.SHost).AccessIp
+	ip := host.AccessIp
 	return ip
 }
 
 func (r *SRegionDNS) getGuestIpWithName(req *recordRequest) []string {
-	ips := []string{}
 	name := req.QueryName()
 	projectId := req.ProjectId()
-	wantOnlyExit := false
-	ips = models.GuestManager.GetIpInProjectWithName(projectId, name, wantOnlyExit)
-	return ips
+	return r.getGuestIpsWithName(projectId, name)
 }
 
 func getK8sServiceBackends(cli *kubernetes.Clientset, req *recordRequest) ([]string, error) {
@@ -329,7 +342,7 @@ func (r *SRegionDNS) queryLocalDnsRecords(req *recordRequest) (recs []msg.Servic
 			}
 			return uint32(ttl)
 		}
-		rec = models.DnsRecordManager.QueryDns(projId, name)
+		rec = r.queryDns(projId, name)
 	)
 
 	if rec == nil {
@@ -350,6 +363,7 @@ func (r *SRegionDNS) queryLocalDnsRecords(req *recordRequest) (recs []msg.Servic
 		qtype   = req.Type()
 		pref    = qtype + ":"
 		prefLen = len(pref)
+		vals    = []string{}
 	)
 	for _, recStr := range rec.GetInfo() {
 		if !strings.HasPrefix(recStr, pref) {
@@ -393,12 +407,15 @@ func (r *SRegionDNS) queryLocalDnsRecords(req *recordRequest) (recs []msg.Servic
 				TTL:      getTtl(rec.Ttl),
 			})
 		} else {
-			recs = append(recs, msg.Service{
-				Host: val,
-				TTL:  getTtl(rec.Ttl),
-			})
+			vals = append(vals, val)
 		}
 	}
+	for _, val := range rec.SelectValues(vals, req.ClientIP(), time.Now()) {
+		recs = append(recs, msg.Service{
+			Host: val,
+			TTL:  getTtl(rec.Ttl),
+		})
+	}
 	return
 }
 
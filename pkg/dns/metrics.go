@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"github.com/coredns/coredns/plugin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cacheSyncFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: PluginName,
+		Name:      "cache_sync_failures_total",
+		Help:      "Counter of failed full resync of region dns cache.",
+	}, []string{"resource"})
+)
+
+// collectors returns collectors exposing age and size of each kind in
+// region dns cache
+func (c *sRegionDNSCache) collectors() []prometheus.Collector {
+	cs := []prometheus.Collector{cacheSyncFailures}
+	for _, store := range c.stores() {
+		s := store
+		labels := prometheus.Labels{"resource": s.kind.keywordPlural}
+		cs = append(cs,
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace:   plugin.Namespace,
+				Subsystem:   PluginName,
+				Name:        "cache_age_seconds",
+				Help:        "Seconds since last successful full resync of region dns cache.",
+				ConstLabels: labels,
+			}, func() float64 {
+				return s.age().Seconds()
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace:   plugin.Namespace,
+				Subsystem:   PluginName,
+				Name:        "cache_objects",
+				Help:        "Number of objects in region dns cache.",
+				ConstLabels: labels,
+			}, func() float64 {
+				return float64(s.count())
+			}),
+		)
+	}
+	return cs
+}
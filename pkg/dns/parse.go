@@ -15,14 +15,13 @@
 package dns
 
 import (
+	"net"
 	"strings"
 
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/request"
 	"github.com/miekg/dns"
 
-	"yunion.io/x/pkg/tristate"
-
 	"yunion.io/x/onecloud/pkg/compute/models"
 )
 
@@ -34,7 +33,7 @@ type recordRequest struct {
 	network      *models.SNetwork
 }
 
-func parseRequest(state request.Request) (r *recordRequest, err error) {
+func (rDNS *SRegionDNS) parseRequest(state request.Request) (r *recordRequest, err error) {
 	base, _ := dnsutil.TrimZone(state.Name(), state.Zone)
 	segs := dns.SplitDomainName(base)
 	r = &recordRequest{
@@ -48,10 +47,10 @@ func parseRequest(state request.Request) (r *recordRequest, err error) {
 	//
 	// Order matters here, we want to find the srcIP project as accurately
 	// as possible
-	if guest := models.GuestnetworkManager.GetGuestByAddress(srcIP); guest != nil {
+	if guest := rDNS.getGuestByAddress(srcIP); guest != nil {
 		r.srcProjectId = guest.ProjectId
 		r.srcInCloud = true
-	} else if network, _ := models.NetworkManager.GetOnPremiseNetworkOfIP(srcIP, "", tristate.None); network != nil {
+	} else if network := rDNS.getOnPremiseNetworkOfIP(srcIP); network != nil {
 		r.srcProjectId = network.ProjectId
 		r.srcInCloud = true
 	}
@@ -91,6 +90,19 @@ func (r recordRequest) SrcIP4() string {
 	return ip
 }
 
+// ClientIP returns address in the EDNS client subnet option if any, or the
+// source address of the request
+func (r recordRequest) ClientIP() net.IP {
+	if opt := r.state.Req.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if subnet, ok := o.(*dns.EDNS0_SUBNET); ok && subnet.Address != nil {
+				return subnet.Address
+			}
+		}
+	}
+	return net.ParseIP(r.state.IP())
+}
+
 func (r recordRequest) ProjectId() string {
 	return r.srcProjectId
 }
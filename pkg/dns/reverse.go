@@ -21,8 +21,6 @@ import (
 	"github.com/coredns/coredns/plugin/etcd/msg"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/request"
-
-	"yunion.io/x/onecloud/pkg/compute/models"
 )
 
 // Reverse implements the ServiceBackend interface
@@ -37,25 +35,29 @@ func (r *SRegionDNS) Reverse(state request.Request, exact bool, opt plugin.Optio
 }
 
 func (r *SRegionDNS) getNameForIp(ip string, state request.Request) ([]msg.Service, error) {
-	req, e := parseRequest(state)
+	req, e := r.parseRequest(state)
 	if e != nil {
 		return nil, e
 	}
 
 	// 1. try local dns records table
-	records := models.DnsRecordManager.QueryDnsIps(req.ProjectId(), req.Name(), req.Type())
-	for _, rec := range records {
-		return []msg.Service{{Host: rec.Addr, TTL: uint32(rec.Ttl)}}, nil
+	if rec := r.queryDns(req.ProjectId(), req.Name()); rec != nil {
+		pref := req.Type() + ":"
+		for _, recStr := range rec.GetInfo() {
+			if strings.HasPrefix(recStr, pref) {
+				return []msg.Service{{Host: recStr[len(pref):], TTL: uint32(rec.Ttl)}}, nil
+			}
+		}
 	}
 
 	// 2. try hosts table
-	host := models.HostnetworkManager.GetHostByAddress(ip)
+	host := r.getHostByAddress(ip)
 	if host != nil {
 		return []msg.Service{{Host: r.joinDomain(host.Name), TTL: defaultTTL}}, nil
 	}
 
 	// 3. try guests table
-	guest := models.GuestnetworkManager.GetGuestByAddress(ip)
+	guest := r.getGuestByAddress(ip)
 	if guest != nil {
 		return []msg.Service{{Host: r.joinDomain(guest.Name), TTL: defaultTTL}}, nil
 	}
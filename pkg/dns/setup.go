@@ -16,6 +16,7 @@ package dns
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -55,8 +56,20 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error(PluginName, err)
 	}
 
-	if !rDNS.K8sSkip {
-		go rDNS.initK8s()
+	if !rDNS.CacheSkip {
+		rDNS.initCache(c)
+	}
+
+	if !rDNS.K8sSkip || (!rDNS.CacheSkip && len(rDNS.AuthUrl) > 0) {
+		go func() {
+			rDNS.initAuth()
+			if !rDNS.CacheSkip {
+				rDNS.cache.startWatch(rDNS.Region)
+			}
+			if !rDNS.K8sSkip {
+				rDNS.initK8s()
+			}
+		}()
 	}
 
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
@@ -132,6 +145,17 @@ func parseConfig(c *caddy.Controller) (*SRegionDNS, error) {
 					rDNS.Upstream = u
 				case "k8s_skip":
 					rDNS.K8sSkip = true
+				case "cache_skip":
+					rDNS.CacheSkip = true
+				case "cache_resync_interval":
+					if !c.NextArg() {
+						return nil, c.ArgErr()
+					}
+					interval, err := time.ParseDuration(c.Val())
+					if err != nil || interval <= 0 {
+						return nil, c.Errf("invalid cache_resync_interval %q", c.Val())
+					}
+					rDNS.CacheResyncInterval = interval
 				default:
 					if c.Val() != "}" {
 						return nil, c.Errf("unknown property %q", c.Val())
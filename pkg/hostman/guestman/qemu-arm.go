@@ -258,6 +258,12 @@ function nic_mtu() {
 		cmd += " -device pvscsi,id=scsi"
 	}
 
+	secrets, err := s.getEncryptSecretsDesc(disks)
+	if err != nil {
+		return "", errors.Wrap(err, "getEncryptSecretsDesc")
+	}
+	cmd += secrets
+
 	for _, disk := range disks {
 		format, _ := disk.GetString("format")
 		cmd += s.getDriveDesc(disk, format)
@@ -1285,6 +1285,9 @@ func (s *SKVMGuestInstance) SetCgroup() {
 	s.cgroupPid = s.GetPid()
 	s.setCgroupIo()
 	s.setCgroupCpu()
+	if options.HostOptions.EnableCpuBinding {
+		s.setCgroupCpuset()
+	}
 }
 
 func (s *SKVMGuestInstance) setCgroupIo() {
@@ -1314,6 +1317,40 @@ func (s *SKVMGuestInstance) setCgroupCpu() {
 	cgrouputils.CgroupSet(strconv.Itoa(s.cgroupPid), int(cpu)*cpuWeight)
 }
 
+// getIsolatedDevicesNumaNode returns numa node shared by all passthrough
+// devices of guest, -1 if not known or devices across nodes
+func (s *SKVMGuestInstance) getIsolatedDevicesNumaNode() int {
+	devs, _ := s.Desc.GetArray("isolated_devices")
+	node := int64(-1)
+	for i, dev := range devs {
+		devNode, err := dev.Int("numa_node")
+		if err != nil || devNode < 0 {
+			return -1
+		}
+		if i > 0 && devNode != node {
+			return -1
+		}
+		node = devNode
+	}
+	return int(node)
+}
+
+// setCgroupCpuset co-locates vcpus with passthrough devices on their numa node
+func (s *SKVMGuestInstance) setCgroupCpuset() {
+	node := s.getIsolatedDevicesNumaNode()
+	if node < 0 {
+		return
+	}
+	cpuset, err := cgrouputils.GetNumaNodeCpuset(node)
+	if err != nil {
+		log.Errorf("get numa node %d cpuset: %v", node, err)
+		return
+	}
+	if !cgrouputils.PinProcessCpuset(strconv.Itoa(s.cgroupPid), cpuset) {
+		log.Errorf("guest %s pin cpuset %s of numa node %d failed", s.Id, cpuset, node)
+	}
+}
+
 func (s *SKVMGuestInstance) CreateFromDesc(desc jsonutils.JSONObject) error {
 	if err := s.PrepareDir(); err != nil {
 		uuid, _ := desc.GetString("uuid")
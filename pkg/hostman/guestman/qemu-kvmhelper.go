@@ -16,6 +16,7 @@ package guestman
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
 	"path"
 	"strings"
@@ -33,6 +34,7 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
 	"yunion.io/x/onecloud/pkg/util/qemutils"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
 	"yunion.io/x/onecloud/pkg/util/sysutils"
 )
 
@@ -172,7 +174,15 @@ func (s *SKVMGuestInstance) getDriveDesc(disk jsonutils.JSONObject, format strin
 	cmd += fmt.Sprintf(" file=$DISK_%d", diskIndex)
 	cmd += ",if=none"
 	cmd += fmt.Sprintf(",id=drive_%d", diskIndex)
-	if len(format) == 0 || format == "qcow2" {
+	if disk.Contains("encrypt_info") {
+		secretId := getDiskSecretId(diskIndex)
+		if format == "raw" {
+			// encrypted raw disks are luks volumes
+			cmd += fmt.Sprintf(",format=luks,key-secret=%s", secretId)
+		} else {
+			cmd += fmt.Sprintf(",format=qcow2,encrypt.format=luks,encrypt.key-secret=%s", secretId)
+		}
+	} else if len(format) == 0 || format == "qcow2" {
 		// pass    # qemu will automatically detect image format
 	} else if format == "raw" {
 		cmd += ",format=raw"
@@ -186,6 +196,49 @@ func (s *SKVMGuestInstance) getDriveDesc(disk jsonutils.JSONObject, format strin
 	return cmd
 }
 
+func getDiskSecretId(diskIndex int64) string {
+	return fmt.Sprintf("sec_%d", diskIndex)
+}
+
+func (s *SKVMGuestInstance) getMasterKeyPath() string {
+	return path.Join(s.HomeDir(), "master-key")
+}
+
+// getEncryptSecretsDesc passes the data keys of encrypted disks to qemu,
+// each secret is encrypted by a per-guest master key so it never appears in plain text
+func (s *SKVMGuestInstance) getEncryptSecretsDesc(disks []jsonutils.JSONObject) (string, error) {
+	var (
+		cmd       = ""
+		masterKey []byte
+	)
+	for _, disk := range disks {
+		if !disk.Contains("encrypt_info") {
+			continue
+		}
+		if masterKey == nil {
+			key, err := seclib2.GenerateDataKey()
+			if err != nil {
+				return "", errors.Wrap(err, "generate master key")
+			}
+			err = ioutil.WriteFile(s.getMasterKeyPath(), key, 0600)
+			if err != nil {
+				return "", errors.Wrap(err, "write master key")
+			}
+			masterKey = key
+			cmd += fmt.Sprintf(" -object secret,id=masterkey0,format=raw,file=%s", s.getMasterKeyPath())
+		}
+		diskIndex, _ := disk.Int("index")
+		key, _ := disk.GetString("encrypt_info", "key")
+		iv, data, err := seclib2.EncryptQemuDiskSecret(masterKey, key)
+		if err != nil {
+			return "", errors.Wrapf(err, "encrypt secret of disk %d", diskIndex)
+		}
+		cmd += fmt.Sprintf(" -object secret,id=%s,data=%s,keyid=masterkey0,iv=%s,format=base64",
+			getDiskSecretId(diskIndex), data, iv)
+	}
+	return cmd, nil
+}
+
 func (s *SKVMGuestInstance) GetDiskAddr(idx int) int {
 	var base = 5
 	if s.IsVdiSpice() {
@@ -612,6 +665,12 @@ function nic_mtu() {
 		cmd += " -device pvscsi,id=scsi"
 	}
 
+	secrets, err := s.getEncryptSecretsDesc(disks)
+	if err != nil {
+		return "", errors.Wrap(err, "getEncryptSecretsDesc")
+	}
+	cmd += secrets
+
 	for _, disk := range disks {
 		format, _ := disk.GetString("format")
 		cmd += s.getDriveDesc(disk, format)
@@ -0,0 +1,309 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostflowlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/hostinfo"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+const (
+	// max number of records in one action log or bucket object
+	flowLogBatchSize = 500
+	// max number of records buffered for each flow log between flushes
+	flowLogBufferSize = 100000
+	// how long the delivery configuration of flow logs are cached
+	flowLogConfTTL = 5 * time.Minute
+)
+
+type sFlowLogConf struct {
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+	DeliveryType string `json:"delivery_type"`
+	BucketId     string `json:"bucket_id"`
+	BucketPrefix string `json:"bucket_prefix"`
+
+	fetchedAt time.Time
+	notFound  bool
+}
+
+func (conf *sFlowLogConf) GetId() string {
+	return conf.Id
+}
+
+func (conf *sFlowLogConf) GetName() string {
+	return conf.Name
+}
+
+func (conf *sFlowLogConf) Keyword() string {
+	return "flow_log"
+}
+
+// SFlowLogCollector tails the ovn-controller log for acl logs and delivers
+// them to where the flow log configuration says
+type SFlowLogCollector struct {
+	logPath       string
+	flushInterval time.Duration
+
+	file   *os.File
+	reader *bufio.Reader
+	// partial line read at the end of file
+	partial []byte
+
+	records   map[string][]*api.FlowLogRecord
+	confs     map[string]*sFlowLogConf
+	lastFlush time.Time
+
+	// set by Stop from another goroutine
+	stopped int32
+	mu      sync.Mutex
+}
+
+var flowLogCollector *SFlowLogCollector
+
+func Init() {
+	if !options.HostOptions.EnableOvnFlowLog {
+		return
+	}
+	if flowLogCollector == nil {
+		flowLogCollector = NewFlowLogCollector(
+			options.HostOptions.OvnControllerLogPath,
+			time.Duration(options.HostOptions.FlowLogFlushIntervalSeconds)*time.Second,
+		)
+	}
+}
+
+func Start() {
+	if flowLogCollector != nil {
+		go flowLogCollector.Start()
+	}
+}
+
+func Stop() {
+	if flowLogCollector != nil {
+		flowLogCollector.Stop()
+	}
+}
+
+func NewFlowLogCollector(logPath string, flushInterval time.Duration) *SFlowLogCollector {
+	return &SFlowLogCollector{
+		logPath:       logPath,
+		flushInterval: flushInterval,
+		records:       map[string][]*api.FlowLogRecord{},
+		confs:         map[string]*sFlowLogConf{},
+		lastFlush:     time.Now(),
+	}
+}
+
+func (c *SFlowLogCollector) Start() {
+	for atomic.LoadInt32(&c.stopped) == 0 {
+		if err := c.tail(); err != nil {
+			log.Errorf("flow log: tail %s: %v", c.logPath, err)
+			c.closeFile()
+		}
+		if time.Since(c.lastFlush) >= c.flushInterval {
+			c.flush(context.Background())
+			c.lastFlush = time.Now()
+		}
+		time.Sleep(time.Second)
+	}
+	c.closeFile()
+}
+
+func (c *SFlowLogCollector) Stop() {
+	atomic.StoreInt32(&c.stopped, 1)
+}
+
+func (c *SFlowLogCollector) closeFile() {
+	if c.file != nil {
+		c.file.Close()
+		c.file = nil
+		c.reader = nil
+		c.partial = nil
+	}
+}
+
+// reopenIfRotated opens the log file.  It is reopened from the start when
+// the file was rotated or truncated.  On first open, records already in the
+// file are skipped
+func (c *SFlowLogCollector) reopenIfRotated() error {
+	fi, err := os.Stat(c.logPath)
+	if err != nil {
+		return errors.Wrap(err, "stat")
+	}
+	whence := io.SeekEnd
+	if c.file != nil {
+		ofi, err := c.file.Stat()
+		if err != nil {
+			return errors.Wrap(err, "stat opened file")
+		}
+		pos, err := c.file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return errors.Wrap(err, "current offset")
+		}
+		if os.SameFile(fi, ofi) && fi.Size() >= pos {
+			return nil
+		}
+		c.closeFile()
+		whence = io.SeekStart
+	}
+	f, err := os.Open(c.logPath)
+	if err != nil {
+		return errors.Wrap(err, "open")
+	}
+	if _, err := f.Seek(0, whence); err != nil {
+		f.Close()
+		return errors.Wrap(err, "seek")
+	}
+	c.file = f
+	c.reader = bufio.NewReader(f)
+	return nil
+}
+
+func (c *SFlowLogCollector) tail() error {
+	if err := c.reopenIfRotated(); err != nil {
+		return err
+	}
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err == io.EOF {
+			c.partial = append(c.partial, line...)
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "read")
+		}
+		if len(c.partial) > 0 {
+			line = append(c.partial, line...)
+			c.partial = nil
+		}
+		rec, err := parseAclLog(string(line))
+		if err != nil {
+			if errors.Cause(err) != errNotAclLog {
+				log.Debugf("flow log: %v: %s", err, line)
+			}
+			continue
+		}
+		c.add(rec)
+	}
+}
+
+func (c *SFlowLogCollector) add(rec *api.FlowLogRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	recs := c.records[rec.FlowLogId]
+	if len(recs) >= flowLogBufferSize {
+		return
+	}
+	rec.HostId = hostinfo.Instance().GetHostId()
+	c.records[rec.FlowLogId] = append(recs, rec)
+}
+
+func (c *SFlowLogCollector) getConf(ctx context.Context, id string) (*sFlowLogConf, error) {
+	if conf, ok := c.confs[id]; ok && time.Since(conf.fetchedAt) < flowLogConfTTL {
+		return conf, nil
+	}
+	s := hostutils.GetComputeSession(ctx)
+	conf := &sFlowLogConf{
+		Id:        id,
+		fetchedAt: time.Now(),
+	}
+	obj, err := modules.FlowLogs.Get(s, id, nil)
+	if err != nil {
+		if httputils.ErrorCode(err) != 404 {
+			return nil, errors.Wrapf(err, "get flow log %s", id)
+		}
+		conf.notFound = true
+	} else if err := obj.Unmarshal(conf); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal flow log %s", id)
+	}
+	c.confs[id] = conf
+	return conf, nil
+}
+
+func (c *SFlowLogCollector) flush(ctx context.Context) {
+	c.mu.Lock()
+	records := c.records
+	c.records = map[string][]*api.FlowLogRecord{}
+	c.mu.Unlock()
+
+	for id, recs := range records {
+		conf, err := c.getConf(ctx, id)
+		if err != nil {
+			log.Errorf("flow log: %v", err)
+			continue
+		}
+		if conf.notFound {
+			continue
+		}
+		for len(recs) > 0 {
+			n := len(recs)
+			if n > flowLogBatchSize {
+				n = flowLogBatchSize
+			}
+			if err := c.deliver(ctx, conf, recs[:n]); err != nil {
+				log.Errorf("flow log %s(%s): deliver %d records: %v", conf.Name, conf.Id, n, err)
+			}
+			recs = recs[n:]
+		}
+	}
+}
+
+func (c *SFlowLogCollector) deliver(ctx context.Context, conf *sFlowLogConf, recs []*api.FlowLogRecord) error {
+	switch conf.DeliveryType {
+	case api.FLOW_LOG_DELIVERY_BUCKET:
+		return c.deliverToBucket(ctx, conf, recs)
+	default:
+		logclient.AddActionLogWithContext(ctx, conf, logclient.ACT_FLOW_LOG, jsonutils.Marshal(recs), auth.AdminCredential(), true)
+		return nil
+	}
+}
+
+// deliverToBucket puts records as one json object per line at
+// <prefix><flowLogId>/<yyyy>/<mm>/<dd>/<hostId>-<unixnano>.json
+func (c *SFlowLogCollector) deliverToBucket(ctx context.Context, conf *sFlowLogConf, recs []*api.FlowLogRecord) error {
+	var buf bytes.Buffer
+	for _, rec := range recs {
+		buf.WriteString(jsonutils.Marshal(rec).String())
+		buf.WriteByte('\n')
+	}
+	now := time.Now().UTC()
+	key := fmt.Sprintf("%s%s/%s/%s-%d.json",
+		conf.BucketPrefix, conf.Id, now.Format("2006/01/02"),
+		hostinfo.Instance().GetHostId(), now.UnixNano())
+	s := hostutils.GetComputeSession(ctx)
+	size := int64(buf.Len())
+	return modules.Buckets.Upload(s, conf.BucketId, key, &buf, size, "", "", nil)
+}
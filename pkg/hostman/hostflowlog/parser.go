@@ -0,0 +1,120 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostflowlog
+
+import (
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+const (
+	errNotAclLog = errors.Error("not an acl log")
+	errBadAclLog = errors.Error("bad acl log")
+)
+
+// parseAclLog parses acl log line of ovn-controller like the following
+//
+//	2021-07-09T08:35:44.222Z|00005|acl_log(ovn_pinctrl0)|INFO|name="xx", verdict=allow, severity=info, direction=to-lport: tcp,vlan_tci=0x0000,dl_src=..,dl_dst=..,nw_src=10.0.0.1,nw_dst=10.0.0.2,nw_tos=0,nw_ecn=0,nw_ttl=64,tp_src=34567,tp_dst=80,tcp_flags=syn
+//
+// direction is only present in newer ovn
+func parseAclLog(line string) (*api.FlowLogRecord, error) {
+	parts := strings.SplitN(strings.TrimSpace(line), "|", 5)
+	if len(parts) != 5 || !strings.HasPrefix(parts[2], "acl_log") {
+		return nil, errNotAclLog
+	}
+	var (
+		ts  = parts[0]
+		msg = parts[4]
+	)
+	i := strings.Index(msg, ": ")
+	if i < 0 {
+		return nil, errors.Wrap(errBadAclLog, "no flow")
+	}
+	var (
+		header = msg[:i]
+		flow   = msg[i+2:]
+		rec    = &api.FlowLogRecord{
+			Timestamp: ts,
+		}
+	)
+	for _, field := range strings.Split(header, ",") {
+		k, v := splitKeyValue(field)
+		switch k {
+		case "name":
+			rec.FlowLogId = strings.Trim(v, `"`)
+		case "verdict":
+			rec.Verdict = v
+		case "severity":
+			rec.Severity = v
+		case "direction":
+			rec.Direction = v
+		}
+	}
+	if rec.FlowLogId == "" || rec.FlowLogId == "<unnamed>" {
+		return nil, errors.Wrap(errBadAclLog, "acl has no name")
+	}
+
+	var errs []error
+	atoi := func(k, v string) int {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "%s=%s", k, v))
+		}
+		return n
+	}
+	for j, field := range strings.Split(flow, ",") {
+		k, v := splitKeyValue(field)
+		if j == 0 && v == "" {
+			rec.Protocol = k
+			continue
+		}
+		switch k {
+		case "dl_src":
+			rec.SrcMac = v
+		case "dl_dst":
+			rec.DstMac = v
+		case "nw_src":
+			rec.SrcIp = v
+		case "nw_dst":
+			rec.DstIp = v
+		case "tp_src":
+			rec.SrcPort = atoi(k, v)
+		case "tp_dst":
+			rec.DstPort = atoi(k, v)
+		case "icmp_type":
+			rec.IcmpType = atoi(k, v)
+		case "icmp_code":
+			rec.IcmpCode = atoi(k, v)
+		case "tcp_flags":
+			rec.TcpFlags = v
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Wrapf(errBadAclLog, "%v", errors.NewAggregate(errs))
+	}
+	return rec, nil
+}
+
+func splitKeyValue(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
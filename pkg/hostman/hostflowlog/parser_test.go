@@ -0,0 +1,96 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostflowlog
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestParseAclLog(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want *api.FlowLogRecord
+		err  error
+	}{
+		{
+			name: "tcp with direction",
+			in:   `2021-07-09T08:35:44.222Z|00005|acl_log(ovn_pinctrl0)|INFO|name="fl-1", verdict=allow, severity=info, direction=to-lport: tcp,vlan_tci=0x0000,dl_src=00:22:11:00:00:01,dl_dst=00:22:11:00:00:02,nw_src=10.0.0.1,nw_dst=10.0.0.2,nw_tos=0,nw_ecn=0,nw_ttl=64,tp_src=34567,tp_dst=80,tcp_flags=syn`,
+			want: &api.FlowLogRecord{
+				FlowLogId: "fl-1",
+				Timestamp: "2021-07-09T08:35:44.222Z",
+				Verdict:   "allow",
+				Severity:  "info",
+				Direction: "to-lport",
+				Protocol:  "tcp",
+				SrcMac:    "00:22:11:00:00:01",
+				DstMac:    "00:22:11:00:00:02",
+				SrcIp:     "10.0.0.1",
+				DstIp:     "10.0.0.2",
+				SrcPort:   34567,
+				DstPort:   80,
+				TcpFlags:  "syn",
+			},
+		},
+		{
+			name: "icmp without direction",
+			in:   `2021-07-09T08:35:44.222Z|00006|acl_log(ovn_pinctrl0)|INFO|name="fl-2", verdict=drop, severity=warning: icmp,vlan_tci=0x0000,dl_src=00:22:11:00:00:01,dl_dst=00:22:11:00:00:02,nw_src=10.0.0.1,nw_dst=10.0.0.2,nw_tos=0,nw_ecn=0,nw_ttl=64,icmp_type=8,icmp_code=0`,
+			want: &api.FlowLogRecord{
+				FlowLogId: "fl-2",
+				Timestamp: "2021-07-09T08:35:44.222Z",
+				Verdict:   "drop",
+				Severity:  "warning",
+				Protocol:  "icmp",
+				SrcMac:    "00:22:11:00:00:01",
+				DstMac:    "00:22:11:00:00:02",
+				SrcIp:     "10.0.0.1",
+				DstIp:     "10.0.0.2",
+				IcmpType:  8,
+			},
+		},
+		{
+			name: "not acl log",
+			in:   `2021-07-09T08:35:44.222Z|00007|binding|INFO|Claiming lport x for this chassis.`,
+			err:  errNotAclLog,
+		},
+		{
+			name: "unnamed acl",
+			in:   `2021-07-09T08:35:44.222Z|00008|acl_log(ovn_pinctrl0)|INFO|name=<unnamed>, verdict=allow, severity=info: udp,nw_src=10.0.0.1,nw_dst=10.0.0.2,tp_src=53,tp_dst=53`,
+			err:  errBadAclLog,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseAclLog(c.in)
+			if c.err != nil {
+				if errors.Cause(err) != c.err {
+					t.Fatalf("want err %v, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want\n%#v\ngot\n%#v", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostdhcp
+
+import (
+	"net"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/types"
+	guestman "yunion.io/x/onecloud/pkg/hostman/guestman/types"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/dhcp"
+)
+
+// SGuestDHCP6Server serves stateful DHCPv6 and router advertisements to
+// guests with ipv6 address on the bridge.  Guests of ipv4 only networks are
+// ignored
+type SGuestDHCP6Server struct {
+	dhcp6 *dhcp.DHCPv6Server
+	ra    *dhcp.RouterAdvertServer
+
+	iface string
+}
+
+func NewGuestDHCP6Server(iface string) (*SGuestDHCP6Server, error) {
+	dhcp6, err := dhcp.NewDHCPv6Server(iface)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewDHCPv6Server")
+	}
+	ra, err := dhcp.NewRouterAdvertServer(iface)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewRouterAdvertServer")
+	}
+	return &SGuestDHCP6Server{
+		dhcp6: dhcp6,
+		ra:    ra,
+		iface: iface,
+	}, nil
+}
+
+func (s *SGuestDHCP6Server) Start(blocking bool) {
+	log.Infof("SGuestDHCP6Server starting ...")
+	go func() {
+		if err := s.ra.ListenAndServe(s); err != nil {
+			log.Errorf("RA serve error: %s", err)
+		}
+	}()
+	serve := func() {
+		if err := s.dhcp6.ListenAndServe(s); err != nil {
+			log.Errorf("DHCPv6 serve error: %s", err)
+		}
+	}
+	if blocking {
+		serve()
+	} else {
+		go serve()
+	}
+}
+
+func (s *SGuestDHCP6Server) getNicDesc(mac net.HardwareAddr) *types.SServerNic {
+	if guestman.GuestDescGetter == nil {
+		return nil
+	}
+	var (
+		macStr      = mac.String()
+		isCandidate = false
+	)
+	_, guestNic := guestman.GuestDescGetter.GetGuestNicDesc(macStr, "", "", s.iface, isCandidate)
+	if guestNic == nil {
+		_, guestNic = guestman.GuestDescGetter.GetGuestNicDesc(macStr, "", "", s.iface, !isCandidate)
+	}
+	if guestNic == nil || jsonutils.QueryBoolean(guestNic, "virtual", false) {
+		return nil
+	}
+	var nicdesc = new(types.SServerNic)
+	if err := guestNic.Unmarshal(nicdesc); err != nil {
+		log.Errorln(err)
+		return nil
+	}
+	if nicdesc.Ip6 == "" || nicdesc.Masklen6 <= 0 {
+		return nil
+	}
+	return nicdesc
+}
+
+func nicDns6(nicdesc *types.SServerNic) []net.IP {
+	if ip := net.ParseIP(nicdesc.Dns6); ip != nil {
+		return []net.IP{ip}
+	}
+	return nil
+}
+
+func (s *SGuestDHCP6Server) ServeDHCPv6(mac net.HardwareAddr) (*dhcp.DHCPv6Config, error) {
+	nicdesc := s.getNicDesc(mac)
+	if nicdesc == nil {
+		return nil, nil
+	}
+	log.Infof("Make DHCPv6 Reply %s TO %s", nicdesc.Ip6, mac)
+	return &dhcp.DHCPv6Config{
+		ClientIP:    net.ParseIP(nicdesc.Ip6),
+		DNSServers:  nicDns6(nicdesc),
+		Domain:      nicdesc.Domain,
+		LeaseTime:   time.Duration(options.HostOptions.DhcpLeaseTime) * time.Second,
+		RenewalTime: time.Duration(options.HostOptions.DhcpRenewalTime) * time.Second,
+	}, nil
+}
+
+func (s *SGuestDHCP6Server) ServeRouterSolicit(mac net.HardwareAddr) (*dhcp.RouterAdvertConfig, error) {
+	nicdesc := s.getNicDesc(mac)
+	if nicdesc == nil {
+		return nil, nil
+	}
+	return &dhcp.RouterAdvertConfig{
+		Prefix:     net.ParseIP(nicdesc.Ip6),
+		PrefixLen:  nicdesc.Masklen6,
+		Mtu:        nicdesc.Mtu,
+		DNSServers: nicDns6(nicdesc),
+	}, nil
+}
@@ -237,9 +237,10 @@ type SNIC struct {
 	WireId  string
 	Mask    int
 
-	Bandwidth  int
-	BridgeDev  hostbridge.IBridgeDriver
-	dhcpServer *hostdhcp.SGuestDHCPServer
+	Bandwidth   int
+	BridgeDev   hostbridge.IBridgeDriver
+	dhcpServer  *hostdhcp.SGuestDHCPServer
+	dhcp6Server *hostdhcp.SGuestDHCP6Server
 }
 
 func (n *SNIC) EnableDHCPRelay() bool {
@@ -363,6 +364,13 @@ func NewNIC(desc string) (*SNIC, error) {
 	if err != nil {
 		return nil, err
 	}
+	if options.HostOptions.EnableDhcp6 {
+		// ipv6 may be disabled on the host, guests then go without dhcpv6
+		nic.dhcp6Server, err = hostdhcp.NewGuestDHCP6Server(nic.Bridge)
+		if err != nil {
+			log.Warningf("create dhcpv6 server on %s: %v", nic.Bridge, err)
+		}
+	}
 	// dhcp server start after guest manager init
 	return nic, nil
 }
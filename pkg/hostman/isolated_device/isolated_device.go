@@ -18,7 +18,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -262,6 +265,9 @@ func (dev *sBaseDevice) GetApiResourceData() jsonutils.JSONObject {
 		"addr":             dev.GetAddr(),
 		"model":            dev.dev.ModelName,
 		"vendor_device_id": dev.GetVendorDeviceId(),
+		"numa_node":        dev.dev.NumaNode,
+		"pcie_switch":      dev.dev.PcieSwitch,
+		"peer_group":       dev.dev.PeerGroup,
 	}
 	detected := false
 	if _, err := detectPCIDevByAddr(dev.GetAddr()); err == nil {
@@ -482,6 +488,10 @@ type PCIDevice struct {
 	SubdeviceId   string `json:"subdevice_id"`
 	ModelName     string `json:"model_name"`
 
+	NumaNode   int    `json:"numa_node"`
+	PcieSwitch string `json:"pcie_switch"`
+	PeerGroup  string `json:"peer_group"`
+
 	RestIOMMUGroupDevs []*PCIDevice `json:"-"`
 }
 
@@ -490,6 +500,7 @@ func NewPCIDevice(line string) (*PCIDevice, error) {
 	if err := dev.checkSameIOMMUGroupDevice(); err != nil {
 		return nil, err
 	}
+	dev.fillTopology()
 	if err := dev.forceBindVFIOPCIDriver(o.HostOptions.UseBootVga); err != nil {
 		return nil, fmt.Errorf("Force bind vfio-pci driver: %v", err)
 	}
@@ -518,6 +529,61 @@ func parseLspci(line string) *PCIDevice {
 	return &dev
 }
 
+// fillTopology reads NUMA node and upstream PCIe switch of the device from
+// sysfs, peer groups can't be probed while the device is bound to vfio-pci
+// and are taken from host options
+func (d *PCIDevice) fillTopology() {
+	d.NumaNode = -1
+	sysPath := fmt.Sprintf("/sys/bus/pci/devices/0000:%s", d.Addr)
+	if content, err := fileutils2.FileGetContents(path.Join(sysPath, "numa_node")); err != nil {
+		log.Warningf("get numa node of %s: %v", d.Addr, err)
+	} else if node, err := strconv.Atoi(strings.TrimSpace(content)); err == nil && node >= 0 {
+		d.NumaNode = node
+	}
+	if realPath, err := filepath.EvalSymlinks(sysPath); err != nil {
+		log.Warningf("get pcie path of %s: %v", d.Addr, err)
+	} else {
+		d.PcieSwitch = getPcieSwitch(realPath)
+	}
+	d.PeerGroup = getPeerGroup(d.Addr, o.HostOptions.IsolatedDevicePeerGroups)
+}
+
+var pciDomainAddrRegexp = regexp.MustCompile(`^[0-9a-fA-F]{4}:` + BUSID_REGEX + `$`)
+
+// getPcieSwitch returns the upstream port of the switch the device hangs on,
+// e.g. 0000:02:00.0 of
+// /sys/devices/pci0000:00/0000:00:03.0/0000:02:00.0/0000:03:08.0/0000:04:00.0
+func getPcieSwitch(devPath string) string {
+	bridges := []string{}
+	for _, seg := range strings.Split(devPath, "/") {
+		if pciDomainAddrRegexp.MatchString(seg) {
+			bridges = append(bridges, seg)
+		}
+	}
+	if len(bridges) < 2 {
+		return ""
+	}
+	// drop the device itself
+	bridges = bridges[:len(bridges)-1]
+	if len(bridges) >= 2 {
+		return bridges[len(bridges)-2][5:]
+	}
+	return bridges[0][5:]
+}
+
+// getPeerGroup returns the index of the group holding addr, groups are comma
+// separated pci addresses of devices linked by NVLink or alike
+func getPeerGroup(addr string, groups []string) string {
+	for idx, group := range groups {
+		for _, a := range strings.Split(group, ",") {
+			if strings.TrimSpace(a) == addr {
+				return fmt.Sprintf("peer%d", idx)
+			}
+		}
+	}
+	return ""
+}
+
 func (d *PCIDevice) GetVendorDeviceId() string {
 	return fmt.Sprintf("%s:%s", d.VendorId, d.DeviceId)
 }
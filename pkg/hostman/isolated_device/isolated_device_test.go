@@ -73,3 +73,41 @@ func Test_parseLspci(t *testing.T) {
 		})
 	}
 }
+
+func Test_getPcieSwitch(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{
+			path: "/sys/devices/pci0000:00/0000:00:03.0/0000:02:00.0/0000:03:08.0/0000:04:00.0",
+			want: "02:00.0",
+		},
+		{
+			path: "/sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0",
+			want: "3a:00.0",
+		},
+		{
+			path: "/sys/devices/pci0000:00/0000:00:02.0",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		if got := getPcieSwitch(tt.path); got != tt.want {
+			t.Errorf("getPcieSwitch(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_getPeerGroup(t *testing.T) {
+	groups := []string{"3b:00.0,3c:00.0", "86:00.0, 87:00.0"}
+	for addr, want := range map[string]string{
+		"3c:00.0": "peer0",
+		"87:00.0": "peer1",
+		"af:00.0": "",
+	} {
+		if got := getPeerGroup(addr, groups); got != want {
+			t.Errorf("getPeerGroup(%s) = %q, want %q", addr, got, want)
+		}
+	}
+}
@@ -72,6 +72,7 @@ type SHostOptions struct {
 	DhcpRelay       []string `help:"DHCP relay upstream"`
 	DhcpLeaseTime   int      `default:"100663296" help:"DHCP lease time in seconds"`
 	DhcpRenewalTime int      `default:"67108864" help:"DHCP renewal time in seconds"`
+	EnableDhcp6     bool     `default:"true" help:"Serve DHCPv6 and router advertisements for guests on dual-stack networks"`
 
 	TunnelPaddingBytes int64 `help:"Specify tunnel padding bytes" default:"0"`
 
@@ -90,6 +91,8 @@ type SHostOptions struct {
 	SetVncPassword         bool `default:"true" help:"Auto set vnc password after monitor connected"`
 	UseBootVga             bool `default:"false" help:"Use boot VGA GPU for guest"`
 
+	IsolatedDevicePeerGroups []string `help:"Passthrough devices linked by NVLink or alike, each group is comma separated pci addresses, e.g. 3b:00.0,3c:00.0"`
+
 	EnableCpuBinding         bool `default:"true" help:"Enable cpu binding and rebalance"`
 	EnableOpenflowController bool `default:"false"`
 
@@ -131,6 +134,10 @@ type SHostOptions struct {
 	OvnEipBridge              string `help:"name of bridge for eip traffic management" default:"$HOST_OVN_EIP_BRIDGE|breip"`
 	OvnUnderlayMtu            int    `help:"mtu of ovn underlay network" default:"1500"`
 
+	EnableOvnFlowLog            bool   `help:"collect vpc flow logs from ovn-controller acl logs" default:"false"`
+	OvnControllerLogPath        string `help:"path of ovn-controller log file" default:"$HOST_OVN_CONTROLLER_LOG_PATH|/var/log/ovn/ovn-controller.log"`
+	FlowLogFlushIntervalSeconds int    `help:"interval in seconds to deliver collected flow records" default:"60"`
+
 	EnableRemoteExecutor bool   `help:"Enable remote executor" default:"false"`
 	EnableHealthChecker  bool   `help:"enable host health checker" default:"false"`
 	HealthDriver         string `help:"Component save host health state" default:"etcd"`
@@ -24,6 +24,7 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
 	"yunion.io/x/onecloud/pkg/hostman/hostdeployer/deployclient"
 )
@@ -51,12 +52,12 @@ type IDisk interface {
 
 	PrepareMigrate(liveMigrate bool) (string, error)
 	CreateFromUrl(ctx context.Context, url string, size int64) error
-	CreateFromTemplate(context.Context, string, string, int64) (jsonutils.JSONObject, error)
+	CreateFromTemplate(context.Context, string, string, int64, *api.SEncryptInfo) (jsonutils.JSONObject, error)
 	CreateFromSnapshotLocation(ctx context.Context, location string, size int64) error
 	CreateFromRbdSnapshot(ctx context.Context, snapshotId, srcDiskId, srcPool string) error
 	CreateFromImageFuse(ctx context.Context, url string, size int64) error
 	CreateRaw(ctx context.Context, sizeMb int, diskFromat string, fsFormat string,
-		encryption bool, diskId string, back string) (jsonutils.JSONObject, error)
+		encryptInfo *api.SEncryptInfo, diskId string, back string) (jsonutils.JSONObject, error)
 	PostCreateFromImageFuse()
 	CreateSnapshot(snapshotId string) error
 	DeleteSnapshot(snapshotId, convertSnapshot string, pendingDelete bool) error
@@ -100,7 +101,7 @@ func (d *SBaseDisk) CreateFromUrl(ctx context.Context, url string, size int64) e
 	return fmt.Errorf("Not implemented")
 }
 
-func (d *SBaseDisk) CreateFromTemplate(context.Context, string, string, int64) (jsonutils.JSONObject, error) {
+func (d *SBaseDisk) CreateFromTemplate(context.Context, string, string, int64, *api.SEncryptInfo) (jsonutils.JSONObject, error) {
 	return nil, fmt.Errorf("Not implemented")
 }
 
@@ -179,3 +180,16 @@ func (d *SBaseDisk) CreateFromRbdSnapshot(ctx context.Context, napshotUrl, srcDi
 func (d *SBaseDisk) DoDeleteSnapshot(snapshotId string) error {
 	return fmt.Errorf("Not implement disk.DoDeleteSnapshot")
 }
+
+// fetchEncryptInfo returns the data key region attached to a disk request, if any
+func fetchEncryptInfo(params jsonutils.JSONObject) (*api.SEncryptInfo, error) {
+	if params == nil || !params.Contains("encrypt_info") {
+		return nil, nil
+	}
+	info := &api.SEncryptInfo{}
+	err := params.Unmarshal(info, "encrypt_info")
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal encrypt_info")
+	}
+	return info, nil
+}
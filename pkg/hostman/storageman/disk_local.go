@@ -143,7 +143,11 @@ func (d *SLocalDisk) Resize(ctx context.Context, params interface{}) (jsonutils.
 	}
 
 	sizeMb, _ := diskInfo.Int("size")
-	disk, err := qemuimg.NewQemuImage(d.GetPath())
+	encryptInfo, err := fetchEncryptInfo(diskInfo)
+	if err != nil {
+		return nil, err
+	}
+	disk, err := qemuimg.NewEncryptedQemuImageWithInfo(d.GetPath(), encryptInfo)
 	if err != nil {
 		log.Errorf("qemuimg.NewQemuImage %s fail: %s", d.GetPath(), err)
 		return nil, err
@@ -156,6 +160,10 @@ func (d *SLocalDisk) Resize(ctx context.Context, params interface{}) (jsonutils.
 		// d.Fallocate()
 	}
 
+	if encryptInfo != nil {
+		// filesystem of an encrypted disk is resized by the guest itself
+		return d.GetDiskDesc(), nil
+	}
 	if err := d.ResizeFs(d.GetPath()); err != nil {
 		return nil, errors.Wrapf(err, "resize fs %s", d.GetPath())
 	}
@@ -199,9 +207,9 @@ func (d *SLocalDisk) CreateFromImageFuse(ctx context.Context, url string, size i
 	return nil
 }
 
-func (d *SLocalDisk) CreateFromTemplate(ctx context.Context, imageId, format string, size int64) (jsonutils.JSONObject, error) {
+func (d *SLocalDisk) CreateFromTemplate(ctx context.Context, imageId, format string, size int64, encryptInfo *api.SEncryptInfo) (jsonutils.JSONObject, error) {
 	var imageCacheManager = storageManager.LocalStorageImagecacheManager
-	ret, err := d.createFromTemplate(ctx, imageId, format, imageCacheManager)
+	ret, err := d.createFromTemplate(ctx, imageId, format, imageCacheManager, encryptInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -210,13 +218,16 @@ func (d *SLocalDisk) CreateFromTemplate(ctx context.Context, imageId, format str
 	if size > retSize {
 		params := jsonutils.NewDict()
 		params.Set("size", jsonutils.NewInt(size))
+		if encryptInfo != nil {
+			params.Set("encrypt_info", jsonutils.Marshal(encryptInfo))
+		}
 		return d.Resize(ctx, params)
 	}
 	return ret, nil
 }
 
 func (d *SLocalDisk) createFromTemplate(
-	ctx context.Context, imageId, format string, imageCacheManager IImageCacheManger,
+	ctx context.Context, imageId, format string, imageCacheManager IImageCacheManger, encryptInfo *api.SEncryptInfo,
 ) (jsonutils.JSONObject, error) {
 	imageCache, err := imageCacheManager.AcquireImage(ctx, imageId, d.GetZoneName(), "", "", "")
 	if err != nil {
@@ -233,6 +244,19 @@ func (d *SLocalDisk) createFromTemplate(
 		}
 	}
 
+	if encryptInfo != nil {
+		// an encrypted disk does not share the plain template as backing file
+		cacheImg, err := qemuimg.NewQemuImage(cacheImagePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "NewQemuImage(%s)", cacheImagePath)
+		}
+		err = cacheImg.ConvertToEncrypted(d.GetPath(), qemuimg.QCOW2, encryptInfo)
+		if err != nil {
+			return nil, errors.Wrapf(err, "ConvertToEncrypted(%s)", cacheImagePath)
+		}
+		return d.GetDiskDesc(), nil
+	}
+
 	newImg, err := qemuimg.NewQemuImage(d.GetPath())
 	if err != nil {
 		return nil, errors.Wrapf(err, "NewQemuImage(%s)", d.GetPath())
@@ -258,7 +282,7 @@ func (d *SLocalDisk) CreateFromUrl(ctx context.Context, url string, size int64)
 }
 
 func (d *SLocalDisk) CreateRaw(ctx context.Context, sizeMB int, diskFormat, fsFormat string,
-	encryption bool, uuid string, back string) (jsonutils.JSONObject, error) {
+	encryptInfo *api.SEncryptInfo, uuid string, back string) (jsonutils.JSONObject, error) {
 	if fileutils2.Exists(d.GetPath()) {
 		os.Remove(d.GetPath())
 	}
@@ -269,10 +293,13 @@ func (d *SLocalDisk) CreateRaw(ctx context.Context, sizeMB int, diskFormat, fsFo
 		return nil, err
 	}
 
-	switch diskFormat {
-	case "qcow2":
+	switch {
+	case encryptInfo != nil:
+		// encrypted local disks are always qcow2 with LUKS encryption
+		err = img.CreateEncrypted(sizeMB, qemuimg.QCOW2, encryptInfo, back)
+	case diskFormat == "qcow2":
 		err = img.CreateQcow2(sizeMB, false, back)
-	case "vmdk":
+	case diskFormat == "vmdk":
 		err = img.CreateVmdk(sizeMB, false)
 	default:
 		err = img.CreateRaw(sizeMB)
@@ -287,7 +314,7 @@ func (d *SLocalDisk) CreateRaw(ctx context.Context, sizeMB int, diskFormat, fsFo
 		// d.Fallocate
 	}
 
-	if utils.IsInStringArray(fsFormat, []string{"swap", "ext2", "ext3", "ext4", "xfs"}) {
+	if encryptInfo == nil && utils.IsInStringArray(fsFormat, []string{"swap", "ext2", "ext3", "ext4", "xfs"}) {
 		d.FormatFs(fsFormat, uuid, d.GetPath())
 	}
 
@@ -418,10 +445,11 @@ func (d *SLocalDisk) DeleteSnapshot(snapshotId, convertSnapshot string, pendingD
 }
 
 func (d *SLocalDisk) PrepareSaveToGlance(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
-	// diskInfo, ok := params.(*jsonutils.JSONDict)
-	// if !ok {
-	// 	return nil, hostutils.ParamsError
-	// }
+	diskInfo, _ := params.(jsonutils.JSONObject)
+	encryptInfo, err := fetchEncryptInfo(diskInfo)
+	if err != nil {
+		return nil, err
+	}
 	if err := d.Probe(); err != nil {
 		return nil, err
 	}
@@ -431,6 +459,20 @@ func (d *SLocalDisk) PrepareSaveToGlance(ctx context.Context, params interface{}
 		return nil, err
 	}
 	backupPath := path.Join(destDir, fmt.Sprintf("%s.%s", d.Id, appctx.AppContextTaskId(ctx)))
+	if encryptInfo != nil {
+		// images are saved in plain text, they are not bound to the disk key
+		img, err := qemuimg.NewEncryptedQemuImageWithInfo(d.GetPath(), encryptInfo)
+		if err != nil {
+			return nil, errors.Wrapf(err, "NewQemuImage(%s)", d.GetPath())
+		}
+		if err := img.ConvertToPlain(backupPath, qemuimg.QCOW2); err != nil {
+			procutils.NewCommand("rm", "-f", backupPath).Run()
+			return nil, errors.Wrap(err, "ConvertToPlain")
+		}
+		res := jsonutils.NewDict()
+		res.Set("backup", jsonutils.NewString(backupPath))
+		return res, nil
+	}
 	if err := procutils.NewCommand("cp", "--sparse=always", "-f", d.GetPath(), backupPath).Run(); err != nil {
 		log.Errorln(err)
 		procutils.NewCommand("rm", "-f", backupPath).Run()
@@ -38,9 +38,9 @@ func NewNasDisk(storage IStorage, id string) *SNasDisk {
 	return &SNasDisk{*NewLocalDisk(storage, id)}
 }
 
-func (d *SNasDisk) CreateFromTemplate(ctx context.Context, imageId, format string, size int64) (jsonutils.JSONObject, error) {
+func (d *SNasDisk) CreateFromTemplate(ctx context.Context, imageId, format string, size int64, encryptInfo *api.SEncryptInfo) (jsonutils.JSONObject, error) {
 	imageCacheManager := storageManager.GetStoragecacheById(d.Storage.GetStoragecacheId())
-	ret, err := d.SLocalDisk.createFromTemplate(ctx, imageId, format, imageCacheManager)
+	ret, err := d.SLocalDisk.createFromTemplate(ctx, imageId, format, imageCacheManager, encryptInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -49,6 +49,9 @@ func (d *SNasDisk) CreateFromTemplate(ctx context.Context, imageId, format strin
 	if size > retSize {
 		params := jsonutils.NewDict()
 		params.Set("size", jsonutils.NewInt(size))
+		if encryptInfo != nil {
+			params.Set("encrypt_info", jsonutils.Marshal(encryptInfo))
+		}
 		return d.Resize(ctx, params)
 	}
 	return ret, nil
@@ -29,6 +29,7 @@ import (
 	"yunion.io/x/onecloud/pkg/appctx"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
 )
 
 type SRBDDisk struct {
@@ -126,6 +127,10 @@ func (d *SRBDDisk) Resize(ctx context.Context, params interface{}) (jsonutils.JS
 	if err := storage.resizeImage(pool, d.Id, uint64(sizeMb)); err != nil {
 		return nil, err
 	}
+	if diskInfo.Contains("encrypt_info") {
+		// the luks payload grows with the rbd image, filesystem is resized by the guest
+		return d.GetDiskDesc(), nil
+	}
 
 	if err := d.ResizeFs(d.GetPath()); err != nil {
 		return nil, errors.Wrapf(err, "resize fs %s", d.GetPath())
@@ -135,6 +140,11 @@ func (d *SRBDDisk) Resize(ctx context.Context, params interface{}) (jsonutils.JS
 }
 
 func (d *SRBDDisk) PrepareSaveToGlance(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	diskInfo, _ := params.(jsonutils.JSONObject)
+	encryptInfo, err := fetchEncryptInfo(diskInfo)
+	if err != nil {
+		return nil, err
+	}
 	if err := d.Probe(); err != nil {
 		return nil, err
 	}
@@ -145,6 +155,15 @@ func (d *SRBDDisk) PrepareSaveToGlance(ctx context.Context, params interface{})
 	}
 	storage := d.Storage.(*SRbdStorage)
 	pool, _ := storage.GetStorageConf().GetString("pool")
+	if encryptInfo != nil {
+		// decrypt into a plain raw image in the image cache pool
+		img := qemuimg.NewLuksQemuImage(d.GetPath(), encryptInfo)
+		dest := fmt.Sprintf("rbd:%s/%s%s", imageCache.GetPath(), imageName, storage.getStorageConfString())
+		if err := img.ConvertToPlain(dest, qemuimg.RAW); err != nil {
+			return nil, errors.Wrapf(err, "decrypt %s to %s/%s", d.Id, imageCache.GetPath(), imageName)
+		}
+		return jsonutils.Marshal(map[string]string{"backup": imageName}), nil
+	}
 	if err := storage.cloneImage(ctx, pool, d.Id, imageCache.GetPath(), imageName); err != nil {
 		log.Errorf("clone image %s from pool %s to %s/%s error: %v", d.Id, pool, imageCache.GetPath(), imageName, err)
 		return nil, err
@@ -162,8 +181,8 @@ func (d *SRBDDisk) PrepareMigrate(liveMigrate bool) (string, error) {
 	return "", fmt.Errorf("Not support")
 }
 
-func (d *SRBDDisk) CreateFromTemplate(ctx context.Context, imageId string, format string, size int64) (jsonutils.JSONObject, error) {
-	ret, err := d.createFromTemplate(ctx, imageId, format)
+func (d *SRBDDisk) CreateFromTemplate(ctx context.Context, imageId string, format string, size int64, encryptInfo *api.SEncryptInfo) (jsonutils.JSONObject, error) {
+	ret, err := d.createFromTemplate(ctx, imageId, format, encryptInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -173,13 +192,16 @@ func (d *SRBDDisk) CreateFromTemplate(ctx context.Context, imageId string, forma
 	if size > retSize {
 		params := jsonutils.NewDict()
 		params.Set("size", jsonutils.NewInt(size))
+		if encryptInfo != nil {
+			params.Set("encrypt_info", jsonutils.Marshal(encryptInfo))
+		}
 		return d.Resize(ctx, params)
 	}
 
 	return ret, nil
 }
 
-func (d *SRBDDisk) createFromTemplate(ctx context.Context, imageId, format string) (jsonutils.JSONObject, error) {
+func (d *SRBDDisk) createFromTemplate(ctx context.Context, imageId, format string, encryptInfo *api.SEncryptInfo) (jsonutils.JSONObject, error) {
 	var imageCacheManager = storageManager.GetStoragecacheById(d.Storage.GetStoragecacheId())
 	if imageCacheManager == nil {
 		return nil, fmt.Errorf("failed to find image cache manger for storage %s", d.Storage.GetStorageName())
@@ -194,6 +216,16 @@ func (d *SRBDDisk) createFromTemplate(ctx context.Context, imageId, format strin
 	storage := d.Storage.(*SRbdStorage)
 	destPool, _ := storage.StorageConf.GetString("pool")
 	storage.deleteImage(destPool, d.Id) //重装系统时，需要删除以前的系统盘
+	if encryptInfo != nil {
+		// an encrypted disk can not be a copy-on-write clone of the plain template
+		src := fmt.Sprintf("rbd:%s/%s%s", imageCacheManager.GetPath(), imageCache.GetName(), storage.getStorageConfString())
+		cacheImg := &qemuimg.SQemuImage{Path: src, Format: qemuimg.RAW}
+		err = cacheImg.ConvertToEncrypted(d.GetPath(), qemuimg.LUKS, encryptInfo)
+		if err != nil {
+			return nil, errors.Wrapf(err, "ConvertToEncrypted(%s)", imageCache.GetName())
+		}
+		return d.GetDiskDesc(), nil
+	}
 	err = storage.cloneImage(ctx, imageCacheManager.GetPath(), imageCache.GetName(), destPool, d.Id)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cloneImage(%s)", imageCache.GetName())
@@ -205,9 +237,16 @@ func (d *SRBDDisk) CreateFromImageFuse(ctx context.Context, url string, size int
 	return fmt.Errorf("Not support")
 }
 
-func (d *SRBDDisk) CreateRaw(ctx context.Context, sizeMb int, diskFromat string, fsFormat string, encryption bool, diskId string, back string) (jsonutils.JSONObject, error) {
+func (d *SRBDDisk) CreateRaw(ctx context.Context, sizeMb int, diskFromat string, fsFormat string, encryptInfo *api.SEncryptInfo, diskId string, back string) (jsonutils.JSONObject, error) {
 	storage := d.Storage.(*SRbdStorage)
 	pool, _ := storage.StorageConf.GetString("pool")
+	if encryptInfo != nil {
+		img := &qemuimg.SQemuImage{Path: d.GetPath()}
+		if err := img.CreateEncrypted(sizeMb, qemuimg.LUKS, encryptInfo, ""); err != nil {
+			return nil, errors.Wrap(err, "CreateEncrypted")
+		}
+		return d.GetDiskDesc(), nil
+	}
 	if err := storage.createImage(pool, diskId, uint64(sizeMb)); err != nil {
 		return nil, err
 	}
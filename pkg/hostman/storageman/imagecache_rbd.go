@@ -19,11 +19,13 @@ package storageman
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
+	imageapi "yunion.io/x/onecloud/pkg/apis/image"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman/remotefile"
@@ -71,7 +73,61 @@ func (r *SRbdImageCache) Load() error {
 	return fmt.Errorf("invalid rbd image %s at host %s", origin.String(), options.HostOptions.Hostname)
 }
 
+// cloneFromImageStorage creates the image cache from the image kept in the rbd pool of the image service
+// directly if the pool is in the same ceph cluster, without downloading it to the local image cache
+func (r *SRbdImageCache) cloneFromImageStorage(ctx context.Context, zone string) error {
+	image, err := modules.Images.Get(hostutils.GetImageSession(ctx, zone), r.imageId, nil)
+	if err != nil {
+		return errors.Wrapf(err, "get image %s", r.imageId)
+	}
+	location, _ := image.GetString("location")
+	if !strings.HasPrefix(location, imageapi.RbdPrefix) {
+		return errors.Wrapf(errors.ErrNotSupported, "image location %s", location)
+	}
+	poolImage := strings.SplitN(location[len(imageapi.RbdPrefix):], "/", 2)
+	if len(poolImage) != 2 {
+		return fmt.Errorf("invalid rbd image location %s", location)
+	}
+	imageCacheManger := r.Manager.(*SRbdImageCacheManager)
+	storage := imageCacheManger.storage.(*SRbdStorage)
+	client, err := storage.GetClient()
+	if err != nil {
+		return errors.Wrap(err, "GetClient")
+	}
+	defer client.Close()
+	client.SetPool(poolImage[0])
+	img, err := client.GetImage(poolImage[1])
+	if err != nil {
+		return errors.Wrapf(err, "image %s not in the ceph cluster of storage %s", location, storage.GetId())
+	}
+	diskFormat, _ := image.GetString("disk_format")
+	if diskFormat == string(qemuimg.RAW) {
+		log.Infof("clone image %s from %s to rbd pool %s", r.imageId, location, r.Manager.GetPath())
+		err = img.Clone(ctx, imageCacheManger.Pool, r.GetName())
+		if err != nil {
+			return errors.Wrapf(err, "clone %s", location)
+		}
+	} else {
+		log.Infof("convert image %s from %s to rbd pool %s", r.imageId, location, r.Manager.GetPath())
+		src := fmt.Sprintf("rbd:%s%s", img.GetName(), storage.getStorageConfString())
+		err = procutils.NewRemoteCommandAsFarAsPossible(qemutils.GetQemuImg(),
+			"convert", "-f", diskFormat, "-O", "raw", src, r.GetPath()).Run()
+		if err != nil {
+			return errors.Wrapf(err, "convert %s", location)
+		}
+	}
+	r.imageName, _ = image.GetString("name")
+	return r.Load()
+}
+
 func (r *SRbdImageCache) Acquire(ctx context.Context, zone, srcUrl, format, checksum string) error {
+	if r.Load() != nil {
+		err := r.cloneFromImageStorage(ctx, zone)
+		if err == nil {
+			return nil
+		}
+		log.Debugf("cloneFromImageStorage %s: %s, fallback to download", r.imageId, err)
+	}
 	localImageCache, err := storageManager.LocalStorageImagecacheManager.AcquireImage(ctx, r.imageId, zone, srcUrl, format, checksum)
 	if err != nil {
 		return errors.Wrapf(err, "LocalStorage.AcquireImage")
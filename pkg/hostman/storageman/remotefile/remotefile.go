@@ -55,6 +55,9 @@ type SRemoteFile struct {
 	chksum string
 	format string
 	name   string
+
+	// resume the interrupted download with a range request
+	resume bool
 }
 
 func NewRemoteFile(
@@ -135,6 +138,7 @@ func (r *SRemoteFile) VerifyIntegrity() error {
 func (r *SRemoteFile) fetch(preChksum string) error {
 	var err error
 	for i := 0; i < 3; i++ {
+		r.resume = i > 0
 		err = r.download(true, preChksum)
 		if err == nil {
 			if len(r.chksum) > 0 && fileutils2.Exists(r.tmpPath) {
@@ -193,6 +197,13 @@ func (r *SRemoteFile) downloadInternal(getData bool, preChksum string) error {
 			header.Set(k, v)
 		}
 	}
+	var offset int64
+	if getData && r.resume && !r.compress {
+		if info, err := os.Stat(r.tmpPath); err == nil && info.Size() > 0 {
+			offset = info.Size()
+			header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+	}
 	var method, url = "HEAD", r.url
 	if len(r.downloadUrl) > 0 {
 		url = r.downloadUrl
@@ -210,10 +221,19 @@ func (r *SRemoteFile) downloadInternal(getData bool, preChksum string) error {
 	defer resp.Body.Close()
 	if resp.StatusCode < 300 {
 		if getData {
-			os.Remove(r.tmpPath)
-			fi, err := os.Create(r.tmpPath)
-			if err != nil {
-				return errors.Wrapf(err, "os.Create(%s)", r.tmpPath)
+			var fi *os.File
+			if offset > 0 && resp.StatusCode == http.StatusPartialContent {
+				log.Infof("resume downloading %s from offset %d", r.tmpPath, offset)
+				fi, err = os.OpenFile(r.tmpPath, os.O_WRONLY|os.O_APPEND, 0644)
+				if err != nil {
+					return errors.Wrapf(err, "os.OpenFile(%s)", r.tmpPath)
+				}
+			} else {
+				os.Remove(r.tmpPath)
+				fi, err = os.Create(r.tmpPath)
+				if err != nil {
+					return errors.Wrapf(err, "os.Create(%s)", r.tmpPath)
+				}
 			}
 			defer fi.Close()
 
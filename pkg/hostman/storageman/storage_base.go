@@ -334,9 +334,12 @@ func (s *SBaseStorage) CreateRawDisk(ctx context.Context, disk IDisk, createPara
 	size, _ := createParams.DiskInfo.Int("size")
 	diskFromat, _ := createParams.DiskInfo.GetString("format")
 	fsFormat, _ := createParams.DiskInfo.GetString("fs_format")
-	encryption := jsonutils.QueryBoolean(createParams.DiskInfo, "encryption", false)
+	encryptInfo, err := fetchEncryptInfo(createParams.DiskInfo)
+	if err != nil {
+		return nil, err
+	}
 
-	return disk.CreateRaw(ctx, int(size), diskFromat, fsFormat, encryption, createParams.DiskId, "")
+	return disk.CreateRaw(ctx, int(size), diskFromat, fsFormat, encryptInfo, createParams.DiskId, "")
 }
 
 func (s *SBaseStorage) CreateDiskFromTemplate(ctx context.Context, disk IDisk, createParams *SDiskCreateByDiskinfo) (jsonutils.JSONObject, error) {
@@ -345,8 +348,12 @@ func (s *SBaseStorage) CreateDiskFromTemplate(ctx context.Context, disk IDisk, c
 		format     = "qcow2" // force qcow2
 		size, _    = createParams.DiskInfo.Int("size")
 	)
+	encryptInfo, err := fetchEncryptInfo(createParams.DiskInfo)
+	if err != nil {
+		return nil, err
+	}
 
-	return disk.CreateFromTemplate(ctx, imageId, format, size)
+	return disk.CreateFromTemplate(ctx, imageId, format, size, encryptInfo)
 }
 
 func (s *SBaseStorage) CreateDiskFromSnpashot(ctx context.Context, disk IDisk, createParams *SDiskCreateByDiskinfo) (jsonutils.JSONObject, error) {
@@ -448,7 +448,11 @@ func (s *SLocalStorage) DestinationPrepareMigrate(
 		// create local disk
 		backingFile, _ := disksBackingFile.GetString(diskId)
 		size, _ := diskinfo.Int("size")
-		_, err := disk.CreateRaw(ctx, int(size), "qcow2", "", false, "", backingFile)
+		encryptInfo, err := fetchEncryptInfo(diskinfo)
+		if err != nil {
+			return err
+		}
+		_, err = disk.CreateRaw(ctx, int(size), "qcow2", "", encryptInfo, "", backingFile)
 		if err != nil {
 			log.Errorln(err)
 			return err
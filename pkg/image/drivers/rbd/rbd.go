@@ -0,0 +1,177 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/cephutils"
+)
+
+type Err string
+
+func (e Err) Error() string {
+	return string(e)
+}
+
+const (
+	ErrClientNotInit = Err("rbd client not init")
+	ErrImageNotFound = Err("rbd image not found")
+)
+
+var client *RbdClient
+
+// RbdClient stores the images in the pool as rbd images of the raw file data
+type RbdClient struct {
+	*cephutils.CephClient
+	monHost string
+	key     string
+	pool    string
+}
+
+func (c *RbdClient) Location(name string) string {
+	return fmt.Sprintf("%s%s/%s", image.RbdPrefix, c.pool, name)
+}
+
+// QemuPath returns the path of the image which qemu-img is able to open directly
+func QemuPath(name string) (string, error) {
+	if client == nil {
+		return "", ErrClientNotInit
+	}
+	conf := []string{"mon_host=" + strings.ReplaceAll(client.monHost, ",", `\;`)}
+	if len(client.key) > 0 {
+		key := client.key
+		for _, k := range []string{":", "@", "="} {
+			key = strings.ReplaceAll(key, k, fmt.Sprintf(`\%s`, k))
+		}
+		conf = append(conf, "key="+key)
+	}
+	return fmt.Sprintf("rbd:%s/%s:%s", client.pool, name, strings.Join(conf, ":")), nil
+}
+
+func Init(monHost, key, pool string) error {
+	if client != nil {
+		return nil
+	}
+	cli, err := cephutils.NewClient(monHost, key, pool)
+	if err != nil {
+		return errors.Wrap(err, "new ceph client")
+	}
+	client = &RbdClient{
+		CephClient: cli,
+		monHost:    monHost,
+		key:        key,
+		pool:       pool,
+	}
+	return nil
+}
+
+func Put(filePath, name string) (string, error) {
+	if client == nil {
+		return "", ErrClientNotInit
+	}
+	reader, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "open file %s", filePath)
+	}
+	defer reader.Close()
+	return PutStream(reader, name)
+}
+
+func PutStream(reader io.Reader, name string) (string, error) {
+	if client == nil {
+		return "", ErrClientNotInit
+	}
+	_, err := client.ImportImage(name, reader)
+	if err != nil {
+		return "", errors.Wrapf(err, "import image %s", name)
+	}
+	return client.Location(name), nil
+}
+
+func getImage(name string) (*cephutils.SImage, error) {
+	if client == nil {
+		return nil, ErrClientNotInit
+	}
+	img, err := client.GetImage(name)
+	if errors.Cause(err) == cloudprovider.ErrNotFound {
+		return nil, errors.Wrapf(ErrImageNotFound, "%s", name)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "get image %s", name)
+	}
+	return img, nil
+}
+
+func Get(name string) (int64, io.ReadCloser, error) {
+	img, err := getImage(name)
+	if err != nil {
+		return -1, nil, err
+	}
+	info, err := img.GetInfo()
+	if err != nil {
+		return -1, nil, errors.Wrapf(err, "get image info %s", name)
+	}
+	reader, err := img.Export()
+	if err != nil {
+		return -1, nil, errors.Wrapf(err, "export image %s", name)
+	}
+	return info.SizeByte, reader, nil
+}
+
+type rangeReader struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (r *rangeReader) Close() error {
+	return r.closer.Close()
+}
+
+// GetRange reads length bytes from offset of the image, to the end if length < 0.
+// rbd export has no ranged read, so the data before offset is skipped from the stream
+func GetRange(name string, offset, length int64) (io.ReadCloser, error) {
+	_, reader, err := Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		_, err = io.CopyN(ioutil.Discard, reader, offset)
+		if err != nil {
+			reader.Close()
+			return nil, errors.Wrapf(err, "skip to offset %d", offset)
+		}
+	}
+	if length < 0 {
+		return reader, nil
+	}
+	return &rangeReader{Reader: io.LimitReader(reader, length), closer: reader}, nil
+}
+
+func Remove(name string) error {
+	img, err := getImage(name)
+	if err != nil {
+		return err
+	}
+	return img.Delete()
+}
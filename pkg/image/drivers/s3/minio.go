@@ -103,6 +103,28 @@ func Get(fileName string) (*minio.Object, error) {
 	return obj, nil
 }
 
+// GetRange reads length bytes of the object from offset, to the end if length < 0
+func GetRange(fileName string, offset, length int64) (*minio.Object, error) {
+	if client == nil {
+		return nil, ErrClientNotInit
+	}
+	opts := minio.GetObjectOptions{}
+	end := int64(0)
+	if length > 0 {
+		end = offset + length - 1
+	}
+	if offset > 0 || end > 0 {
+		if err := opts.SetRange(offset, end); err != nil {
+			return nil, errors.Wrap(err, "set range")
+		}
+	}
+	obj, err := client.GetObject(client.bucket, fileName, opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get object %s", fileName)
+	}
+	return obj, nil
+}
+
 func Remove(fileName string) error {
 	if client == nil {
 		return ErrClientNotInit
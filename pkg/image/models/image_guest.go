@@ -17,6 +17,7 @@ package models
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 	"time"
@@ -40,6 +41,7 @@ import (
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/image/options"
 	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
@@ -71,9 +73,32 @@ type SGuestImage struct {
 	Protected tristate.TriState `nullable:"false" default:"true" list:"user" get:"user" create:"optional" update:"user"`
 }
 
+func (manager *SGuestImageManager) CustomizeHandlerInfo(info *appsrv.SHandlerInfo) {
+	manager.SSharableVirtualResourceBaseManager.CustomizeHandlerInfo(info)
+
+	switch info.GetName(nil) {
+	case "create":
+		// ova packages are uploaded in the body of create requests
+		info.SetProcessTimeout(time.Minute * 120).SetWorkerManager(imgStreamingWorkerMan)
+	}
+}
+
+func (manager *SGuestImageManager) FetchCreateHeaderData(ctx context.Context, header http.Header) (jsonutils.JSONObject, error) {
+	return modules.FetchImageMeta(header), nil
+}
+
 func (manager *SGuestImageManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential,
 	ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
 
+	if data.Contains("import_format") {
+		err := validateGuestImageImport(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		// the number of sub images is unknown until the ovf descriptor is parsed,
+		// reserve quota of the root image and check the others on import
+		data.Set("image_number", jsonutils.NewInt(1))
+	}
 	if !data.Contains("image_number") {
 		return nil, httperrors.NewMissingParameterError("image_number")
 	}
@@ -107,6 +132,10 @@ func (gi *SGuestImage) PostCreate(ctx context.Context, userCred mcclient.TokenCr
 	kwargs := data.(*jsonutils.JSONDict)
 	// get image number
 	imageNumber, _ := kwargs.Int("image_number")
+	if kwargs.Contains("import_format") {
+		gi.startImport(ctx, userCred, kwargs)
+		return
+	}
 	// deal public params
 	kwargs.Remove("size")
 	kwargs.Remove("image_number")
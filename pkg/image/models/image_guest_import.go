@@ -0,0 +1,219 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/ovfutils"
+)
+
+func getImportCopyFrom(ctx context.Context, input api.GuestImageImportInput) string {
+	if len(input.CopyFrom) > 0 {
+		return input.CopyFrom
+	}
+	appParams := appsrv.AppContextGetParams(ctx)
+	if appParams != nil {
+		return appParams.Request.Header.Get(modules.IMAGE_META_COPY_FROM)
+	}
+	return ""
+}
+
+func isImportUpload(ctx context.Context) bool {
+	appParams := appsrv.AppContextGetParams(ctx)
+	return appParams != nil && appParams.Request.ContentLength > 0
+}
+
+func validateGuestImageImport(ctx context.Context, data *jsonutils.JSONDict) error {
+	input := api.GuestImageImportInput{}
+	err := data.Unmarshal(&input)
+	if err != nil {
+		return httperrors.NewInputParameterError("unmarshal import input: %v", err)
+	}
+	switch input.ImportFormat {
+	case api.IMAGE_IMPORT_FORMAT_OVA:
+		if len(getImportCopyFrom(ctx, input)) == 0 && !isImportUpload(ctx) {
+			return httperrors.NewMissingParameterError("copy_from")
+		}
+	case api.IMAGE_IMPORT_FORMAT_OVF:
+		if len(getImportCopyFrom(ctx, input)) == 0 {
+			return httperrors.NewMissingParameterError("copy_from")
+		}
+	default:
+		return httperrors.NewInputParameterError("unsupported import format %s", input.ImportFormat)
+	}
+	return nil
+}
+
+// GetImportDir is where the files of an ovf package are kept until converted to sub images
+func (gi *SGuestImage) GetImportDir() string {
+	return filepath.Join(options.Options.FilesystemStoreDatadir, fmt.Sprintf("%s.import", gi.Id))
+}
+
+func (gi *SGuestImage) startImport(ctx context.Context, userCred mcclient.TokenCredential, data *jsonutils.JSONDict) {
+	input := api.GuestImageImportInput{}
+	data.Unmarshal(&input)
+	input.CopyFrom = getImportCopyFrom(ctx, input)
+
+	params := jsonutils.Marshal(input).(*jsonutils.JSONDict)
+	subParams := jsonutils.DeepCopy(data).(*jsonutils.JSONDict)
+	for _, key := range []string{"size", "image_number", "name", "images", "import_format", "copy_from", "disk_urls"} {
+		subParams.Remove(key)
+	}
+	params.Add(subParams, "sub_image_params")
+
+	if len(input.CopyFrom) == 0 {
+		gi.SetStatus(userCred, api.IMAGE_STATUS_SAVING, "import upload")
+		info, err := func() (*ovfutils.SOvfInfo, error) {
+			err := os.MkdirAll(gi.GetImportDir(), 0755)
+			if err != nil {
+				return nil, errors.Wrap(err, "MkdirAll")
+			}
+			return ovfutils.ExtractOva(appsrv.AppContextGetParams(ctx).Request.Body, gi.GetImportDir())
+		}()
+		if err != nil {
+			gi.OnImportFailed(ctx, userCred, jsonutils.NewString(fmt.Sprintf("import upload fail %s", err)))
+			return
+		}
+		params.Add(jsonutils.Marshal(info), "ovf_info")
+	}
+
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestImageImportTask", gi, userCred, params, "", "", nil)
+	if err != nil {
+		gi.OnImportFailed(ctx, userCred, jsonutils.NewString(err.Error()))
+		return
+	}
+	task.ScheduleRun(nil)
+}
+
+func (gi *SGuestImage) OnImportFailed(ctx context.Context, userCred mcclient.TokenCredential, reason jsonutils.JSONObject) {
+	images, err := GuestImageJointManager.GetImagesByGuestImageId(gi.Id)
+	if err != nil {
+		log.Errorf("GetImagesByGuestImageId %s: %s", gi.Id, err)
+	} else if len(images) == 0 {
+		// sub images are not created, release the quota reserved on create
+		pendingUsage := SQuota{Image: 1}
+		pendingUsage.SetKeys(imageCreateInput2QuotaKeys("qcow2", gi.GetOwnerId()))
+		quotas.CancelPendingUsage(ctx, userCred, &pendingUsage, &pendingUsage, true)
+	}
+	for i := range images {
+		if utils.IsInStringArray(images[i].Status, []string{api.IMAGE_STATUS_QUEUED, api.IMAGE_STATUS_SAVING}) {
+			images[i].OnSaveFailed(ctx, userCred, reason)
+		}
+	}
+	gi.SetStatus(userCred, api.IMAGE_STATUS_KILLED, reason.String())
+	logclient.AddActionLogWithContext(ctx, gi, logclient.ACT_IMAGE_SAVE, reason, userCred, false)
+	gi.CleanImportDir()
+}
+
+func (gi *SGuestImage) CleanImportDir() {
+	err := os.RemoveAll(gi.GetImportDir())
+	if err != nil {
+		log.Errorf("remove %s: %s", gi.GetImportDir(), err)
+	}
+}
+
+// CreateImportedSubImages creates one sub image per disk of an imported package, the first one is the root image
+func (gi *SGuestImage) CreateImportedSubImages(ctx context.Context, userCred mcclient.TokenCredential,
+	params *jsonutils.JSONDict, count int) ([]*SImage, error) {
+
+	ownerId := gi.GetOwnerId()
+	keys := imageCreateInput2QuotaKeys("qcow2", ownerId)
+	if count > 1 {
+		extraUsage := SQuota{Image: count - 1}
+		extraUsage.SetKeys(keys)
+		if err := quotas.CheckSetPendingQuota(ctx, userCred, &extraUsage); err != nil {
+			return nil, httperrors.NewOutOfQuotaError("%s", err)
+		}
+	}
+	defer func() {
+		pendingUsage := SQuota{Image: count}
+		pendingUsage.SetKeys(keys)
+		quotas.CancelPendingUsage(ctx, userCred, &pendingUsage, &pendingUsage, true)
+	}()
+
+	images := make([]*SImage, 0, count)
+	for i := 0; i < count; i++ {
+		imgParams := jsonutils.DeepCopy(params).(*jsonutils.JSONDict)
+		imgParams.Set("is_guest_image", jsonutils.JSONTrue)
+		imgParams.Set("disk_format", jsonutils.NewString("qcow2"))
+		if i == 0 {
+			imgParams.Set("generate_name", jsonutils.NewString(fmt.Sprintf("%s-%s", gi.Name, "root")))
+		} else {
+			imgParams.Set("generate_name", jsonutils.NewString(fmt.Sprintf("%s-%s-%d", gi.Name, "data", i-1)))
+			imgParams.Set("is_data", jsonutils.JSONTrue)
+		}
+		model, err := db.DoCreate(ImageManager, ctx, userCred, nil, imgParams, ownerId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "create sub image %d", i)
+		}
+		image := model.(*SImage)
+		_, err = GuestImageJointManager.CreateGuestImageJoint(ctx, gi.Id, image.Id)
+		if err != nil {
+			image.OnJointFailed(ctx, userCred)
+			return nil, errors.Wrapf(err, "join sub image %s", image.Id)
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// OvfImageProperties are the hardware hints of disk idx of an ovf package, which
+// the server create form uses to pre-fill cpu, memory, nics and firmware
+func OvfImageProperties(info *ovfutils.SOvfInfo, idx int) *jsonutils.JSONDict {
+	props := jsonutils.NewDict()
+	if len(info.Disks[idx].Driver) > 0 {
+		props.Set(api.IMAGE_DISK_DRIVER, jsonutils.NewString(info.Disks[idx].Driver))
+	}
+	if idx > 0 {
+		return props
+	}
+	if info.CpuCount > 0 {
+		props.Set(api.IMAGE_VCPU_COUNT, jsonutils.NewInt(int64(info.CpuCount)))
+	}
+	if info.MemoryMB > 0 {
+		props.Set(api.IMAGE_VMEM_SIZE, jsonutils.NewInt(int64(info.MemoryMB)))
+	}
+	if len(info.Nics) > 0 {
+		props.Set(api.IMAGE_NIC_COUNT, jsonutils.NewInt(int64(len(info.Nics))))
+		props.Set(api.IMAGE_NET_DRIVER, jsonutils.NewString(info.Nics[0].NetDriver()))
+	}
+	props.Set(api.IMAGE_BIOS, jsonutils.NewString(info.Firmware))
+	if info.Firmware == ovfutils.FIRMWARE_UEFI {
+		props.Set(api.IMAGE_UEFI_SUPPORT, jsonutils.JSONTrue)
+	}
+	if len(info.OsType) > 0 {
+		props.Set(api.IMAGE_OVF_OS_TYPE, jsonutils.NewString(info.OsType))
+	}
+	return props
+}
@@ -0,0 +1,403 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+// +onecloud:swagger-gen-ignore
+type SImageReplicaManager struct {
+	db.SResourceBaseManager
+}
+
+var ImageReplicaManager *SImageReplicaManager
+
+func init() {
+	ImageReplicaManager = &SImageReplicaManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SImageReplica{},
+			"image_replicas_tbl",
+			"image_replica",
+			"image_replicas",
+		),
+	}
+	ImageReplicaManager.SetVirtualObject(ImageReplicaManager)
+	ImageReplicaManager.TableSpec().AddIndex(true, "image_id", "region")
+}
+
+// SImageReplica records the copy of an image in a peer region
+type SImageReplica struct {
+	SImagePeripheral
+
+	Region        string `width:"128" charset:"utf8" nullable:"false"`
+	RemoteImageId string `width:"36" charset:"ascii" nullable:"true"`
+	Status        string `width:"16" charset:"ascii" nullable:"false"`
+	Reason        string `width:"256" charset:"utf8" nullable:"true"`
+	Checksum      string `width:"32" charset:"ascii" nullable:"true"`
+}
+
+func (manager *SImageReplicaManager) GetReplicas(imageId string) ([]SImageReplica, error) {
+	q := manager.Query().Equals("image_id", imageId)
+	replicas := make([]SImageReplica, 0)
+	err := db.FetchModelObjects(manager, q, &replicas)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return replicas, nil
+}
+
+func (manager *SImageReplicaManager) FetchReplica(imageId string, region string) (*SImageReplica, error) {
+	q := manager.Query().Equals("image_id", imageId).Equals("region", region)
+	replica := &SImageReplica{}
+	replica.SetModelManager(manager, replica)
+	err := q.First(replica)
+	if err != nil {
+		return nil, err
+	}
+	return replica, nil
+}
+
+func (manager *SImageReplicaManager) fetchOrCreateReplica(ctx context.Context, imageId string, region string) (*SImageReplica, error) {
+	replica, err := manager.FetchReplica(imageId, region)
+	if err == nil {
+		return replica, nil
+	}
+	if errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "FetchReplica")
+	}
+	replica = &SImageReplica{}
+	replica.SetModelManager(manager, replica)
+	replica.ImageId = imageId
+	replica.Region = region
+	replica.Status = api.IMAGE_REPLICA_STATUS_PENDING
+	err = manager.TableSpec().Insert(ctx, replica)
+	if err != nil {
+		return nil, errors.Wrap(err, "Insert")
+	}
+	return replica, nil
+}
+
+func (self *SImageReplica) SetStatus(status string, reason string) error {
+	_, err := db.Update(self, func() error {
+		self.Status = status
+		self.Reason = reason
+		return nil
+	})
+	return err
+}
+
+func (self *SImageReplica) GetDetails() api.ImageReplicaDetails {
+	return api.ImageReplicaDetails{
+		Region:        self.Region,
+		RemoteImageId: self.RemoteImageId,
+		Status:        self.Status,
+		Reason:        self.Reason,
+		Checksum:      self.Checksum,
+	}
+}
+
+// SyncStatus checks the remote image of a replicating replica, the replica is ready
+// once the remote image is active and its checksum matches the source image
+func (self *SImageReplica) SyncStatus(ctx context.Context, image *SImage) error {
+	s := auth.GetAdminSession(ctx, self.Region, "")
+	meta, err := modules.Images.GetById(s, self.RemoteImageId, nil)
+	if err != nil {
+		if httputils.ErrorCode(err) == http.StatusNotFound {
+			return self.SetStatus(api.IMAGE_REPLICA_STATUS_FAILED, "remote image not found")
+		}
+		return errors.Wrapf(err, "get image %s of region %s", self.RemoteImageId, self.Region)
+	}
+	status, _ := meta.GetString("status")
+	switch {
+	case status == api.IMAGE_STATUS_ACTIVE:
+		checksum, _ := meta.GetString("checksum")
+		_, err := db.Update(self, func() error {
+			self.Checksum = checksum
+			if checksum != image.Checksum {
+				self.Status = api.IMAGE_REPLICA_STATUS_FAILED
+				self.Reason = fmt.Sprintf("checksum mismatch %s != %s", checksum, image.Checksum)
+			} else {
+				self.Status = api.IMAGE_REPLICA_STATUS_READY
+				self.Reason = ""
+			}
+			return nil
+		})
+		return err
+	case utils.IsInStringArray(status, api.ImageDeadStatus) || status == api.IMAGE_STATUS_SAVE_FAIL:
+		return self.SetStatus(api.IMAGE_REPLICA_STATUS_FAILED, fmt.Sprintf("remote image status %s", status))
+	}
+	return nil
+}
+
+func (self *SImage) AllowGetDetailsReplicas(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowGetSpec(userCred, self, "replicas")
+}
+
+// 镜像在其他区域的副本
+func (self *SImage) GetDetailsReplicas(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	replicas, err := ImageReplicaManager.GetReplicas(self.Id)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	ret := make([]api.ImageReplicaDetails, len(replicas))
+	for i := range replicas {
+		ret[i] = replicas[i].GetDetails()
+	}
+	return jsonutils.Marshal(ret), nil
+}
+
+func (self *SImage) AllowPerformReplicate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "replicate")
+}
+
+// 复制镜像到其他区域
+func (self *SImage) PerformReplicate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ImageReplicateInput) (jsonutils.JSONObject, error) {
+	if self.Status != api.IMAGE_STATUS_ACTIVE {
+		return nil, httperrors.NewInvalidStatusError("cannot replicate image in status %s", self.Status)
+	}
+	if self.IsGuestImage.IsTrue() {
+		return nil, httperrors.NewForbiddenError("cannot replicate image which is the part of guest image")
+	}
+	if len(input.Regions) == 0 {
+		return nil, httperrors.NewMissingParameterError("regions")
+	}
+	for _, region := range input.Regions {
+		if region == options.Options.Region {
+			return nil, httperrors.NewInputParameterError("cannot replicate image to the region %s itself", region)
+		}
+		s := auth.GetAdminSession(ctx, region, "")
+		if _, err := s.GetServiceURL(modules.Images.ServiceType(), ""); err != nil {
+			return nil, httperrors.NewInputParameterError("no image service in region %s: %v", region, err)
+		}
+	}
+	return nil, self.StartReplicateTask(ctx, userCred, input.Regions, "")
+}
+
+func (self *SImage) StartReplicateTask(ctx context.Context, userCred mcclient.TokenCredential, regions []string, parentTaskId string) error {
+	for _, region := range regions {
+		replica, err := ImageReplicaManager.fetchOrCreateReplica(ctx, self.Id, region)
+		if err != nil {
+			return errors.Wrapf(err, "create replica of region %s", region)
+		}
+		if replica.Status != api.IMAGE_REPLICA_STATUS_REPLICATING {
+			replica.SetStatus(api.IMAGE_REPLICA_STATUS_PENDING, "")
+		}
+	}
+	params := jsonutils.NewDict()
+	params.Set("regions", jsonutils.NewStringArray(regions))
+	task, err := taskman.TaskManager.NewTask(ctx, "ImageReplicateTask", self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// Replicate creates an image in the peer region which pulls the image data and all
+// subformats from this region, a replica already ready or replicating is skipped
+func (self *SImage) Replicate(ctx context.Context, userCred mcclient.TokenCredential, region string) error {
+	replica, err := ImageReplicaManager.fetchOrCreateReplica(ctx, self.Id, region)
+	if err != nil {
+		return err
+	}
+	if utils.IsInStringArray(replica.Status, []string{api.IMAGE_REPLICA_STATUS_READY, api.IMAGE_REPLICA_STATUS_REPLICATING}) {
+		return nil
+	}
+	s := auth.GetAdminSession(ctx, region, "")
+	if len(replica.RemoteImageId) > 0 {
+		// remove the remote image of the last failed replication
+		params := jsonutils.NewDict()
+		params.Set("override_pending_delete", jsonutils.JSONTrue)
+		_, err := modules.Images.Delete(s, replica.RemoteImageId, params)
+		if err != nil && httputils.ErrorCode(err) != http.StatusNotFound {
+			log.Warningf("delete image %s of region %s: %s", replica.RemoteImageId, region, err)
+		}
+	}
+
+	input := api.ImageCreateInput{}
+	input.Name = self.Name
+	input.Description = self.Description
+	input.DiskFormat = self.DiskFormat
+	input.MinDiskMB = &self.MinDiskMB
+	input.MinRamMB = &self.MinRamMB
+	isData := self.IsData.Bool()
+	input.IsData = &isData
+	input.ProjectId = self.ProjectId
+	input.ProjectDomainId = self.DomainId
+	input.SourceRegion = options.Options.Region
+	input.SourceImageId = self.Id
+	input.Properties, err = ImagePropertyManager.GetProperties(self.Id)
+	if err != nil {
+		return errors.Wrap(err, "GetProperties")
+	}
+	result, err := modules.Images.Create(s, jsonutils.Marshal(input))
+	if err != nil {
+		replica.SetStatus(api.IMAGE_REPLICA_STATUS_FAILED, err.Error())
+		return errors.Wrapf(err, "create image in region %s", region)
+	}
+	remoteId, _ := result.GetString("id")
+	_, err = db.Update(replica, func() error {
+		replica.RemoteImageId = remoteId
+		replica.Status = api.IMAGE_REPLICA_STATUS_REPLICATING
+		replica.Reason = ""
+		replica.Checksum = ""
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update replica")
+	}
+	db.OpsLog.LogEvent(self, db.ACT_REPLICATE, fmt.Sprintf("replicate to image %s of region %s", remoteId, region), userCred)
+	return nil
+}
+
+// SyncReplicaStatus refreshes the status of the replicas under replicating
+func (manager *SImageReplicaManager) SyncReplicaStatus(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().Equals("status", api.IMAGE_REPLICA_STATUS_REPLICATING)
+	replicas := make([]SImageReplica, 0)
+	err := db.FetchModelObjects(manager, q, &replicas)
+	if err != nil {
+		log.Errorf("fetch replicating image replicas: %s", err)
+		return
+	}
+	for i := range replicas {
+		obj, err := ImageManager.FetchById(replicas[i].ImageId)
+		if err != nil {
+			log.Errorf("fetch image %s: %s", replicas[i].ImageId, err)
+			continue
+		}
+		err = replicas[i].SyncStatus(ctx, obj.(*SImage))
+		if err != nil {
+			log.Errorf("sync status of image %s replica in region %s: %s", replicas[i].ImageId, replicas[i].Region, err)
+		}
+	}
+}
+
+func (self *SImage) StartReplicaPullTask(ctx context.Context, userCred mcclient.TokenCredential, sourceRegion, sourceImageId string, parentTaskId string) error {
+	params := jsonutils.NewDict()
+	params.Set("source_region", jsonutils.NewString(sourceRegion))
+	params.Set("source_image_id", jsonutils.NewString(sourceImageId))
+
+	msg := fmt.Sprintf("replicate from image %s of region %s", sourceImageId, sourceRegion)
+	self.SetStatus(userCred, api.IMAGE_STATUS_SAVING, msg)
+	db.OpsLog.LogEvent(self, db.ACT_SAVING, msg, userCred)
+
+	task, err := taskman.TaskManager.NewTask(ctx, "ImageReplicaPullTask", self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// PullReplica downloads the image data and all active subformats of the source image
+// from the image service of the source region, checksums are verified against the source
+func (self *SImage) PullReplica(ctx context.Context, sourceRegion, sourceImageId string) error {
+	s := auth.GetAdminSession(ctx, sourceRegion, "")
+	err := func() error {
+		meta, body, _, err := modules.Images.Download2(s, sourceImageId, "", false)
+		if err != nil {
+			return errors.Wrap(err, "download")
+		}
+		defer body.Close()
+		err = self.SaveImageFromStream(body, true)
+		if err != nil {
+			return errors.Wrap(err, "SaveImageFromStream")
+		}
+		checksum, _ := meta.GetString("checksum")
+		if len(checksum) > 0 && checksum != self.Checksum {
+			return errors.Errorf("checksum mismatch %s != %s", self.Checksum, checksum)
+		}
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	ret, err := modules.Images.GetSpecific(s, sourceImageId, "subformats", nil)
+	if err != nil {
+		return errors.Wrap(err, "get subformats")
+	}
+	subformats := make([]SImageSubformatDetails, 0)
+	err = ret.Unmarshal(&subformats)
+	if err != nil {
+		return errors.Wrap(err, "unmarshal subformats")
+	}
+	for i := range subformats {
+		if subformats[i].Format == self.DiskFormat || subformats[i].Status != api.IMAGE_STATUS_ACTIVE {
+			continue
+		}
+		err := self.pullSubformat(ctx, s, sourceImageId, subformats[i])
+		if err != nil {
+			return errors.Wrapf(err, "pull subformat %s", subformats[i].Format)
+		}
+	}
+	return nil
+}
+
+func (self *SImage) pullSubformat(ctx context.Context, s *mcclient.ClientSession, sourceImageId string, source SImageSubformatDetails) error {
+	if subimg := ImageSubformatManager.FetchSubImage(self.Id, source.Format); subimg != nil {
+		return nil
+	}
+	_, body, _, err := modules.Images.Download2(s, sourceImageId, source.Format, false)
+	if err != nil {
+		return errors.Wrap(err, "download")
+	}
+	defer body.Close()
+	localPath := self.GetPath(source.Format)
+	sp, err := self.saveImageFromStream(localPath, body, true)
+	if err != nil {
+		return errors.Wrap(err, "saveImageFromStream")
+	}
+	if len(source.Checksum) > 0 && sp.CheckSum != source.Checksum {
+		return errors.Errorf("checksum mismatch %s != %s", sp.CheckSum, source.Checksum)
+	}
+	fastHash, err := fileutils2.FastCheckSum(localPath)
+	if err != nil {
+		return errors.Wrap(err, "FastCheckSum")
+	}
+
+	subformat := &SImageSubformat{}
+	subformat.SetModelManager(ImageSubformatManager, subformat)
+	subformat.ImageId = self.Id
+	subformat.Format = source.Format
+	subformat.Size = sp.Size
+	subformat.Checksum = sp.CheckSum
+	subformat.FastHash = fastHash
+	subformat.Location = fmt.Sprintf("%s%s", LocalFilePrefix, localPath)
+	subformat.Status = api.IMAGE_STATUS_ACTIVE
+	// torrent is generated locally on convert
+	subformat.TorrentStatus = api.IMAGE_STATUS_QUEUED
+	return ImageSubformatManager.TableSpec().Insert(ctx, subformat)
+}
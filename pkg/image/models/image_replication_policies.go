@@ -0,0 +1,230 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SImageReplicationPolicyManager struct {
+	db.SEnabledStatusStandaloneResourceBaseManager
+}
+
+var ImageReplicationPolicyManager *SImageReplicationPolicyManager
+
+func init() {
+	ImageReplicationPolicyManager = &SImageReplicationPolicyManager{
+		SEnabledStatusStandaloneResourceBaseManager: db.NewEnabledStatusStandaloneResourceBaseManager(
+			SImageReplicationPolicy{},
+			"image_replication_policies_tbl",
+			"image_replication_policy",
+			"image_replication_policies",
+		),
+	}
+	ImageReplicationPolicyManager.SetVirtualObject(ImageReplicationPolicyManager)
+}
+
+// SImageReplicationPolicy replicates the matched images to the target regions automatically
+type SImageReplicationPolicy struct {
+	db.SEnabledStatusStandaloneResourceBase
+
+	// 目标区域
+	TargetRegions *api.ImageReplicaRegions `nullable:"false" list:"admin" create:"admin_required" update:"admin"`
+	// 复制标准镜像
+	IsStandard tristate.TriState `nullable:"true" list:"admin" create:"admin_optional"`
+	// 复制带有该标签的镜像
+	TagKey string `width:"128" charset:"utf8" nullable:"true" list:"admin" create:"admin_optional"`
+	// 标签值, 为空则匹配任意值
+	TagValue string `width:"128" charset:"utf8" nullable:"true" list:"admin" create:"admin_optional"`
+}
+
+func validateReplicaRegions(ctx context.Context, regions api.ImageReplicaRegions) error {
+	if len(regions) == 0 {
+		return httperrors.NewMissingParameterError("target_regions")
+	}
+	for _, region := range regions {
+		if region == options.Options.Region {
+			return httperrors.NewInputParameterError("cannot replicate image to the region %s itself", region)
+		}
+		s := auth.GetAdminSession(ctx, region, "")
+		if _, err := s.GetServiceURL(modules.Images.ServiceType(), ""); err != nil {
+			return httperrors.NewInputParameterError("no image service in region %s: %v", region, err)
+		}
+	}
+	return nil
+}
+
+// 镜像复制策略列表
+func (manager *SImageReplicationPolicyManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query api.ImageReplicationPolicyListInput) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.TargetRegion) > 0 {
+		q = q.Filter(sqlchemy.Contains(q.Field("target_regions"), query.TargetRegion))
+	}
+	return q, nil
+}
+
+func (manager *SImageReplicationPolicyManager) OrderByExtraFields(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query api.ImageReplicationPolicyListInput) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusStandaloneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SImageReplicationPolicyManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SImageReplicationPolicyManager) FetchCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, objs []interface{}, fields stringutils2.SSortedStrings, isList bool) []api.ImageReplicationPolicyDetails {
+	rows := make([]api.ImageReplicationPolicyDetails, len(objs))
+	stdRows := manager.SEnabledStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.ImageReplicationPolicyDetails{
+			EnabledStatusStandaloneResourceDetails: stdRows[i],
+		}
+	}
+	return rows
+}
+
+func (manager *SImageReplicationPolicyManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.ImageReplicationPolicyCreateInput) (api.ImageReplicationPolicyCreateInput, error) {
+	var err error
+	input.EnabledStatusStandaloneResourceCreateInput, err = manager.SEnabledStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusStandaloneResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	if err := validateReplicaRegions(ctx, input.TargetRegions); err != nil {
+		return input, err
+	}
+	if input.IsStandard == nil && len(input.TagKey) == 0 {
+		return input, httperrors.NewMissingParameterError("is_standard or tag_key")
+	}
+	input.Status = api.IMAGE_REPLICATION_POLICY_STATUS_READY
+	return input, nil
+}
+
+func (policy *SImageReplicationPolicy) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ImageReplicationPolicyUpdateInput) (api.ImageReplicationPolicyUpdateInput, error) {
+	var err error
+	input.EnabledStatusStandaloneResourceBaseUpdateInput, err = policy.SEnabledStatusStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusStandaloneResourceBaseUpdateInput)
+	if err != nil {
+		return input, err
+	}
+	if input.TargetRegions != nil {
+		if err := validateReplicaRegions(ctx, input.TargetRegions); err != nil {
+			return input, err
+		}
+	}
+	return input, nil
+}
+
+// getMatchedImages returns the active images matched by the policy, images of guest image are excluded
+func (policy *SImageReplicationPolicy) getMatchedImages() ([]SImage, error) {
+	q := ImageManager.Query().Equals("status", api.IMAGE_STATUS_ACTIVE).IsFalse("is_guest_image").IsFalse("pending_deleted")
+	if !policy.IsStandard.IsNone() {
+		if policy.IsStandard.IsTrue() {
+			q = q.IsTrue("is_standard")
+		} else {
+			q = q.IsFalse("is_standard")
+		}
+	}
+	if len(policy.TagKey) > 0 {
+		metaQ := db.Metadata.Query("obj_id").Equals("obj_type", ImageManager.Keyword()).Equals("key", db.USER_TAG_PREFIX+policy.TagKey)
+		if len(policy.TagValue) > 0 {
+			metaQ = metaQ.Equals("value", policy.TagValue)
+		}
+		q = q.In("id", metaQ.SubQuery())
+	}
+	images := make([]SImage, 0)
+	err := db.FetchModelObjects(ImageManager, q, &images)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return images, nil
+}
+
+// Apply replicates the matched images to the target regions which have no replica yet
+func (policy *SImageReplicationPolicy) Apply(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if policy.TargetRegions == nil {
+		return nil
+	}
+	images, err := policy.getMatchedImages()
+	if err != nil {
+		return err
+	}
+	for i := range images {
+		replicas, err := ImageReplicaManager.GetReplicas(images[i].Id)
+		if err != nil {
+			return err
+		}
+		replicated := make([]string, 0)
+		for _, replica := range replicas {
+			// failed replications are retried by the replicate action only
+			replicated = append(replicated, replica.Region)
+		}
+		regions := make([]string, 0)
+		for _, region := range *policy.TargetRegions {
+			if !utils.IsInStringArray(region, replicated) {
+				regions = append(regions, region)
+			}
+		}
+		if len(regions) == 0 {
+			continue
+		}
+		err = images[i].StartReplicateTask(ctx, userCred, regions, "")
+		if err != nil {
+			log.Errorf("StartReplicateTask of image %s: %s", images[i].Id, err)
+		}
+	}
+	return nil
+}
+
+// ApplyReplicationPolicies runs all the enabled replication policies
+func (manager *SImageReplicationPolicyManager) ApplyReplicationPolicies(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().IsTrue("enabled")
+	policies := make([]SImageReplicationPolicy, 0)
+	err := db.FetchModelObjects(manager, q, &policies)
+	if err != nil {
+		log.Errorf("fetch image replication policies: %s", err)
+		return
+	}
+	for i := range policies {
+		err := policies[i].Apply(ctx, userCred)
+		if err != nil {
+			log.Errorf("apply image replication policy %s: %s", policies[i].Name, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+)
+
+func (self *SImage) IsSignatureVerified() bool {
+	return self.SignatureStatus == api.IMAGE_SIGNATURE_STATUS_VERIFIED
+}
+
+func (self *SImage) setSignatureStatus(status string) error {
+	if self.SignatureStatus == status {
+		return nil
+	}
+	_, err := db.Update(self, func() error {
+		self.SignatureStatus = status
+		return nil
+	})
+	return err
+}
+
+// VerifySignature checks the detached signature in the image properties against the
+// sha256 digest of the image file with the keys trusted by the domain of the image
+func (self *SImage) VerifySignature(ctx context.Context, userCred mcclient.TokenCredential) error {
+	props, err := ImagePropertyManager.GetProperties(self.Id)
+	if err != nil {
+		return errors.Wrap(err, "GetProperties")
+	}
+	signature := props[api.IMAGE_SIGNATURE]
+	if len(signature) == 0 {
+		return self.setSignatureStatus(api.IMAGE_SIGNATURE_STATUS_UNSIGNED)
+	}
+
+	fingerprint, verifyErr := self.verifySignature(signature, props[api.IMAGE_SIGNATURE_KEY])
+	if verifyErr != nil {
+		log.Warningf("verify signature of image %s(%s): %s", self.Name, self.Id, verifyErr)
+		db.OpsLog.LogEvent(self, db.ACT_VERIFY_SIGNATURE_FAIL, verifyErr.Error(), userCred)
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_IMAGE_VERIFY_SIGNATURE, verifyErr.Error(), userCred, false)
+		return self.setSignatureStatus(api.IMAGE_SIGNATURE_STATUS_UNVERIFIED)
+	}
+	notes := fmt.Sprintf("signature verified by trusted key %s", fingerprint)
+	db.OpsLog.LogEvent(self, db.ACT_VERIFY_SIGNATURE, notes, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_IMAGE_VERIFY_SIGNATURE, notes, userCred, true)
+	return self.setSignatureStatus(api.IMAGE_SIGNATURE_STATUS_VERIFIED)
+}
+
+// verifySignature returns the fingerprint of the trusted key which the signature is verified by
+func (self *SImage) verifySignature(signature, fingerprint string) (string, error) {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return "", errors.Wrap(err, "decode signature")
+	}
+	keys, err := ImageTrustedKeyManager.GetTrustedKeys(self.DomainId, fingerprint)
+	if err != nil {
+		return "", errors.Wrap(err, "GetTrustedKeys")
+	}
+	if len(keys) == 0 {
+		return "", errors.Wrapf(httperrors.ErrNotFound, "no trusted key of domain %s", self.DomainId)
+	}
+	digest, err := self.sha256Digest()
+	if err != nil {
+		return "", errors.Wrap(err, "sha256Digest")
+	}
+	for i := range keys {
+		pub, err := keys[i].GetPublicKey()
+		if err != nil {
+			log.Errorf("invalid trusted key %s: %s", keys[i].Id, err)
+			continue
+		}
+		if seclib2.VerifyDigestSignature(pub, digest, sig) == nil {
+			return keys[i].Fingerprint, nil
+		}
+	}
+	return "", seclib2.ErrInvalidSignature
+}
+
+// sha256Digest reads the image from its storage, which is not always a local file
+func (self *SImage) sha256Digest() ([]byte, error) {
+	location := self.Location
+	if len(location) == 0 {
+		location = self.GetPath("")
+	}
+	_, rc, err := GetImage(location)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetImage")
+	}
+	defer rc.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return nil, errors.Wrap(err, "read image")
+	}
+	return h.Sum(nil), nil
+}
+
+func (self *SImage) StartVerifySignatureTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "ImageVerifySignatureTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SImage) AllowPerformVerifySignature(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "verify-signature")
+}
+
+// 使用可信公钥重新验证镜像签名
+func (self *SImage) PerformVerifySignature(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if self.Status != api.IMAGE_STATUS_ACTIVE {
+		return nil, httperrors.NewInvalidStatusError("cannot verify signature in status %s", self.Status)
+	}
+	return nil, self.StartVerifySignatureTask(ctx, userCred, "")
+}
@@ -210,9 +210,12 @@ func (self *SImageSubformat) SaveTorrent() error {
 }
 
 func (self *SImageSubformat) GetLocalLocation() string {
-	if len(self.Location) > len(LocalFilePrefix) {
+	if strings.HasPrefix(self.Location, LocalFilePrefix) {
 		return self.Location[len(LocalFilePrefix):]
+	} else if strings.HasPrefix(self.Location, api.NfsPrefix) {
+		return filepath.Join(options.Options.NfsMountPoint, self.Location[len(api.NfsPrefix):])
 	}
+	// the subformat is not accessible as a local file, e.g. saved to the rbd pool
 	return ""
 }
 
@@ -224,6 +227,10 @@ func (self *SImageSubformat) getLocalTorrentLocation() string {
 }
 
 func (self *SImageSubformat) seedTorrent(imageId string) error {
+	if len(self.GetLocalLocation()) == 0 {
+		// nothing to seed if the image file is not local
+		return nil
+	}
 	file := self.getLocalTorrentLocation()
 	log.Debugf("add torrent %s to seed...", file)
 	return torrent.SeedTorrent(file, imageId, self.Format)
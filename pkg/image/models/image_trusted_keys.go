@@ -0,0 +1,161 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SImageTrustedKeyManager struct {
+	db.SDomainLevelResourceBaseManager
+}
+
+var ImageTrustedKeyManager *SImageTrustedKeyManager
+
+func init() {
+	ImageTrustedKeyManager = &SImageTrustedKeyManager{
+		SDomainLevelResourceBaseManager: db.NewDomainLevelResourceBaseManager(
+			SImageTrustedKey{},
+			"image_trusted_keys_tbl",
+			"image_trusted_key",
+			"image_trusted_keys",
+		),
+	}
+	ImageTrustedKeyManager.SetVirtualObject(ImageTrustedKeyManager)
+}
+
+// SImageTrustedKey is a public key trusted by a domain to sign its images
+type SImageTrustedKey struct {
+	db.SDomainLevelResourceBase
+
+	// PEM格式的公钥
+	PublicKey string `type:"text" nullable:"false" create:"domain_required" list:"user" get:"user"`
+	// 公钥指纹
+	Fingerprint string `width:"64" charset:"ascii" nullable:"false" index:"true" list:"user" get:"user"`
+	// 公钥类型, 可能值为: rsa, ecdsa, ed25519
+	KeyType string `width:"16" charset:"ascii" nullable:"false" list:"user" get:"user"`
+}
+
+// 可信镜像签名公钥列表
+func (manager *SImageTrustedKeyManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query api.ImageTrustedKeyListInput) (*sqlchemy.SQuery, error) {
+	q, err := manager.SDomainLevelResourceBaseManager.ListItemFilter(ctx, q, userCred, query.DomainLevelResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SDomainLevelResourceBaseManager.ListItemFilter")
+	}
+	if len(query.Fingerprint) > 0 {
+		q = q.In("fingerprint", query.Fingerprint)
+	}
+	if len(query.KeyType) > 0 {
+		q = q.In("key_type", query.KeyType)
+	}
+	return q, nil
+}
+
+func (manager *SImageTrustedKeyManager) FetchCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, objs []interface{}, fields stringutils2.SSortedStrings, isList bool) []api.ImageTrustedKeyDetails {
+	rows := make([]api.ImageTrustedKeyDetails, len(objs))
+	domainRows := manager.SDomainLevelResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.ImageTrustedKeyDetails{
+			DomainLevelResourceDetails: domainRows[i],
+		}
+	}
+	return rows
+}
+
+func (manager *SImageTrustedKeyManager) OrderByExtraFields(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query api.ImageTrustedKeyListInput) (*sqlchemy.SQuery, error) {
+	q, err := manager.SDomainLevelResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.DomainLevelResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SDomainLevelResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SImageTrustedKeyManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SDomainLevelResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SImageTrustedKeyManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.ImageTrustedKeyCreateInput) (api.ImageTrustedKeyCreateInput, error) {
+	var err error
+	input.DomainLevelResourceCreateInput, err = manager.SDomainLevelResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.DomainLevelResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	if len(input.PublicKey) == 0 {
+		return input, httperrors.NewMissingParameterError("public_key")
+	}
+	pub, err := seclib2.ParsePublicKeyPem(input.PublicKey)
+	if err != nil {
+		return input, httperrors.NewInputParameterError("invalid public_key: %v", err)
+	}
+	if _, err := seclib2.PublicKeyType(pub); err != nil {
+		return input, httperrors.NewInputParameterError("%v", err)
+	}
+	fingerprint, err := seclib2.PublicKeyFingerprint(pub)
+	if err != nil {
+		return input, httperrors.NewInputParameterError("%v", err)
+	}
+	cnt, err := manager.Query().Equals("domain_id", ownerId.GetProjectDomainId()).Equals("fingerprint", fingerprint).CountWithError()
+	if err != nil {
+		return input, httperrors.NewInternalServerError("CountWithError %v", err)
+	}
+	if cnt > 0 {
+		return input, httperrors.NewDuplicateResourceError("public key %s already trusted", fingerprint)
+	}
+	return input, nil
+}
+
+func (key *SImageTrustedKey) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	pub, err := key.GetPublicKey()
+	if err != nil {
+		return httperrors.NewInputParameterError("invalid public_key: %v", err)
+	}
+	key.KeyType, _ = seclib2.PublicKeyType(pub)
+	key.Fingerprint, _ = seclib2.PublicKeyFingerprint(pub)
+	return key.SDomainLevelResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data)
+}
+
+func (key *SImageTrustedKey) GetPublicKey() (crypto.PublicKey, error) {
+	return seclib2.ParsePublicKeyPem(key.PublicKey)
+}
+
+// GetTrustedKeys returns the keys trusted by the domain, only the key of the given fingerprint if not empty
+func (manager *SImageTrustedKeyManager) GetTrustedKeys(domainId string, fingerprint string) ([]SImageTrustedKey, error) {
+	q := manager.Query().Equals("domain_id", domainId)
+	if len(fingerprint) > 0 {
+		q = q.Equals("fingerprint", fingerprint)
+	}
+	keys := make([]SImageTrustedKey, 0)
+	err := db.FetchModelObjects(manager, q, &keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return keys, nil
+}
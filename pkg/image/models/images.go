@@ -41,7 +41,9 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/drivers/rbd"
 	"yunion.io/x/onecloud/pkg/image/options"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
@@ -141,6 +143,9 @@ type SImage struct {
 	// image copy from url, save origin checksum before probe
 	// 从镜像时长导入的镜像校验和
 	OssChecksum string `width:"32" charset:"ascii" nullable:"true" get:"user" list:"user"`
+
+	// 镜像签名验证状态
+	SignatureStatus string `width:"16" charset:"ascii" nullable:"true" default:"unsigned" get:"user" list:"user"`
 }
 
 func (manager *SImageManager) CustomizeHandlerInfo(info *appsrv.SHandlerInfo) {
@@ -206,6 +211,7 @@ func (manager *SImageManager) IsCustomizedGetDetailsBody() bool {
 func (self *SImage) CustomizedGetDetailsBody(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	filePath := self.Location
 	status := self.Status
+	fileSize := self.Size
 
 	if self.IsGuestImage.IsFalse() {
 		formatStr := jsonutils.GetAnyString(query, []string{"format", "disk_format"})
@@ -217,13 +223,16 @@ func (self *SImage) CustomizedGetDetailsBody(ctx context.Context, userCred mccli
 					if !isTorrent {
 						filePath = subimg.Location
 						status = subimg.Status
+						fileSize = subimg.Size
 					} else {
 						filePath = subimg.getLocalTorrentLocation()
 						status = subimg.TorrentStatus
+						fileSize = subimg.TorrentSize
 					}
 				} else {
 					filePath = subimg.Location
 					status = subimg.Status
+					fileSize = subimg.Size
 				}
 			} else {
 				return nil, httperrors.NewNotFoundError("format %s not found", formatStr)
@@ -239,13 +248,18 @@ func (self *SImage) CustomizedGetDetailsBody(ctx context.Context, userCred mccli
 		return nil, httperrors.NewInvalidStatusError("empty file path")
 	}
 
+	appParams := appsrv.AppContextGetParams(ctx)
+	if rangeStr := appParams.Request.Header.Get("Range"); len(rangeStr) > 0 && fileSize > 0 {
+		return nil, self.streamImageRange(appParams.Response, filePath, fileSize, rangeStr)
+	}
+
 	size, rc, err := GetImage(filePath)
 	if err != nil {
 		return nil, errors.Wrap(err, "get image")
 	}
 	defer rc.Close()
 
-	appParams := appsrv.AppContextGetParams(ctx)
+	appParams.Response.Header().Set("Accept-Ranges", "bytes")
 	appParams.Response.Header().Set("Content-Length", strconv.FormatInt(size, 10))
 
 	_, err = streamutils.StreamPipe(rc, appParams.Response, false, nil)
@@ -256,6 +270,35 @@ func (self *SImage) CustomizedGetDetailsBody(ctx context.Context, userCred mccli
 	return nil, nil
 }
 
+// streamImageRange serves the single byte range request, which the host downloader uses to resume an interrupted download
+func (self *SImage) streamImageRange(w http.ResponseWriter, filePath string, fileSize int64, rangeStr string) error {
+	objRange := cloudprovider.ParseRange(rangeStr)
+	end := objRange.End
+	if end <= 0 || end >= fileSize {
+		end = fileSize - 1
+	}
+	if objRange.Start > end {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", fileSize))
+		return httperrors.NewInputParameterError("invalid range %s of size %d", rangeStr, fileSize)
+	}
+	length := end - objRange.Start + 1
+	rc, err := GetImageRange(filePath, objRange.Start, length)
+	if err != nil {
+		return errors.Wrap(err, "get image range")
+	}
+	defer rc.Close()
+
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", objRange.Start, end, fileSize))
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	w.WriteHeader(http.StatusPartialContent)
+	_, err = streamutils.StreamPipe(rc, w, false, nil)
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	return nil
+}
+
 func (self *SImage) getMoreDetails(out api.ImageDetails) api.ImageDetails {
 	properties, err := ImagePropertyManager.GetProperties(self.Id)
 	if err != nil {
@@ -274,6 +317,7 @@ func (self *SImage) getMoreDetails(out api.ImageDetails) api.ImageDetails {
 	}
 	out.OssChecksum = ossChksum
 	out.DisableDelete = self.Protected.Bool()
+	out.Verified = self.IsSignatureVerified()
 	return out
 }
 
@@ -373,6 +417,15 @@ func (manager *SImageManager) ValidateCreateData(ctx context.Context, userCred m
 		return input, errors.Wrap(err, "SSharableVirtualResourceBaseManager.ValidateCreateData")
 	}
 
+	if len(input.SourceImageId) > 0 {
+		if !db.IsAdminAllowCreate(userCred, manager) {
+			return input, httperrors.NewForbiddenError("only admin can replicate image from other region")
+		}
+		if len(input.SourceRegion) == 0 {
+			return input, httperrors.NewMissingParameterError("source_region")
+		}
+	}
+
 	// If this image is the part of guest image (contains "guest_image_id"),
 	// we do not need to check and set pending quota
 	// because that pending quota has been checked and set in SGuestImage.ValidateCreateData
@@ -400,8 +453,14 @@ func (self *SImage) CustomizeCreate(ctx context.Context, userCred mcclient.Token
 
 func (self *SImage) GetPath(format string) string {
 	path := filepath.Join(options.Options.FilesystemStoreDatadir, self.Id)
-	if options.Options.StorageDriver == api.IMAGE_STORAGE_DRIVER_S3 {
+	switch options.Options.StorageDriver {
+	case api.IMAGE_STORAGE_DRIVER_S3:
 		path = filepath.Join(options.Options.S3MountPoint, self.Id)
+	case api.IMAGE_STORAGE_DRIVER_NFS:
+		path = filepath.Join(options.Options.NfsMountPoint, self.Id)
+	case api.IMAGE_STORAGE_DRIVER_RBD:
+		// images are received and converted in the scratch area before saved to the pool
+		path = filepath.Join(options.Options.ScratchDir, self.Id)
 	}
 	if len(format) > 0 {
 		path = fmt.Sprintf("%s.%s", path, format)
@@ -492,6 +551,20 @@ func (self *SImage) SaveImageFromStream(reader io.Reader, calChecksum bool) erro
 		return err
 	}
 
+	return self.saveLocalImageInfo(localPath, sp.Size, sp.CheckSum, calChecksum)
+}
+
+// SaveImageFromLocalFile records the image file already written to the local path of the image
+func (self *SImage) SaveImageFromLocalFile() error {
+	localPath := self.GetPath("")
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return errors.Wrap(err, "stat")
+	}
+	return self.saveLocalImageInfo(localPath, stat.Size(), "", false)
+}
+
+func (self *SImage) saveLocalImageInfo(localPath string, size int64, checksum string, calChecksum bool) error {
 	virtualSizeBytes := int64(0)
 	format := ""
 	img, err := qemuimg.NewQemuImage(localPath)
@@ -510,9 +583,9 @@ func (self *SImage) SaveImageFromStream(reader io.Reader, calChecksum bool) erro
 	}
 
 	_, err = db.Update(self, func() error {
-		self.Size = sp.Size
+		self.Size = size
 		if calChecksum {
-			self.Checksum = sp.CheckSum
+			self.Checksum = checksum
 			self.FastHash = fastChksum
 		}
 		self.Location = fmt.Sprintf("%s%s", LocalFilePrefix, localPath)
@@ -570,6 +643,15 @@ func (self *SImage) PostCreate(ctx context.Context, userCred mcclient.TokenCrede
 		}
 	}
 
+	if sourceImageId, _ := data.GetString("source_image_id"); len(sourceImageId) > 0 {
+		sourceRegion, _ := data.GetString("source_region")
+		err := self.StartReplicaPullTask(ctx, userCred, sourceRegion, sourceImageId, "")
+		if err != nil {
+			self.OnSaveFailed(ctx, userCred, jsonutils.NewString(fmt.Sprintf("replicate fail %s", err)))
+		}
+		return
+	}
+
 	appParams := appsrv.AppContextGetParams(ctx)
 	if appParams.Request.ContentLength > 0 {
 		db.OpsLog.LogEvent(self, db.ACT_SAVING, "create upload", userCred)
@@ -646,6 +728,7 @@ func (self *SImage) ValidateUpdateData(ctx context.Context, userCred mcclient.To
 				if !isProbe {
 					// no probe
 					self.SetStatus(userCred, api.IMAGE_STATUS_ACTIVE, "data disk image upload success")
+					self.StartVerifySignatureTask(ctx, userCred, "")
 				} else {
 					data.Remove("status")
 					// For guest image, DoConvertAfterProbe is not necessary.
@@ -694,6 +777,13 @@ func (self *SImage) PostUpdate(ctx context.Context, userCred mcclient.TokenCrede
 		if err != nil {
 			log.Errorf("save properties error %s", err)
 		}
+		// signature is given after the image is saved
+		if self.Status == api.IMAGE_STATUS_ACTIVE && (props.Contains(api.IMAGE_SIGNATURE) || props.Contains(api.IMAGE_SIGNATURE_KEY)) {
+			err := self.StartVerifySignatureTask(ctx, userCred, "")
+			if err != nil {
+				log.Errorf("StartVerifySignatureTask error %s", err)
+			}
+		}
 	}
 }
 
@@ -1108,6 +1198,8 @@ func (self *SImage) GetLocalLocation() string {
 		return self.Location[len(api.LocalFilePrefix):]
 	} else if strings.HasPrefix(self.Location, api.S3Prefix) {
 		return path.Join(options.Options.S3MountPoint, self.Location[len(api.S3Prefix):])
+	} else if strings.HasPrefix(self.Location, api.NfsPrefix) {
+		return path.Join(options.Options.NfsMountPoint, self.Location[len(api.NfsPrefix):])
 	} else {
 		return ""
 	}
@@ -1118,6 +1210,10 @@ func (self *SImage) GetPrefix() string {
 		return api.LocalFilePrefix
 	} else if strings.HasPrefix(self.Location, api.S3Prefix) {
 		return api.S3Prefix
+	} else if strings.HasPrefix(self.Location, api.RbdPrefix) {
+		return api.RbdPrefix
+	} else if strings.HasPrefix(self.Location, api.NfsPrefix) {
+		return api.NfsPrefix
 	} else {
 		return api.LocalFilePrefix
 	}
@@ -1126,12 +1222,23 @@ func (self *SImage) GetPrefix() string {
 func (self *SImage) GetNewLocation(newLocalPath string) string {
 	if strings.HasPrefix(self.Location, api.S3Prefix) {
 		return api.S3Prefix + path.Base(newLocalPath)
+	} else if strings.HasPrefix(self.Location, api.NfsPrefix) {
+		return api.NfsPrefix + path.Base(newLocalPath)
 	} else {
 		return api.LocalFilePrefix + newLocalPath
 	}
 }
 
 func (self *SImage) getQemuImage() (*qemuimg.SQemuImage, error) {
+	if strings.HasPrefix(self.Location, api.RbdPrefix) {
+		// qemu-img reads the image from the pool directly, the converted
+		// subformats are written to the scratch area
+		qemuPath, err := rbd.QemuPath(path.Base(self.Location))
+		if err != nil {
+			return nil, errors.Wrap(err, "rbd.QemuPath")
+		}
+		return qemuimg.NewQemuImageWithIOLevel(qemuPath, qemuimg.IONiceIdle)
+	}
 	return qemuimg.NewQemuImageWithIOLevel(self.GetLocalLocation(), qemuimg.IONiceIdle)
 }
 
@@ -1285,6 +1392,10 @@ func (manager *SImageManager) ListItemFilter(
 	propFilter([]string{api.IMAGE_OS_TYPE}, query.OsTypes)
 	propFilter([]string{api.IMAGE_OS_DISTRO, "distro"}, query.Distributions)
 
+	if len(query.SignatureStatus) > 0 {
+		q = q.In("signature_status", query.SignatureStatus)
+	}
+
 	return q, nil
 }
 
@@ -18,65 +18,74 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"yunion.io/x/pkg/errors"
 
 	"yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/image/drivers/rbd"
 	"yunion.io/x/onecloud/pkg/image/drivers/s3"
 	"yunion.io/x/onecloud/pkg/image/options"
 	"yunion.io/x/onecloud/pkg/util/procutils"
 )
 
-var local Storage = &LocalStorage{}
-var s3Instance Storage = &S3Storage{}
+// storages are the registered image storage drivers keyed by the location prefix
+var storages = map[string]Storage{}
 var storage Storage
 
+func registerStorage(prefix string, s Storage) {
+	storages[prefix] = s
+}
+
+func init() {
+	registerStorage(image.LocalFilePrefix, &LocalStorage{})
+	registerStorage(image.S3Prefix, &S3Storage{})
+	registerStorage(image.RbdPrefix, &RbdStorage{})
+	registerStorage(image.NfsPrefix, &NfsStorage{})
+}
+
 func GetStorage() Storage {
 	return storage
 }
 
-func GetImage(location string) (int64, io.ReadCloser, error) {
-	switch {
-	case strings.HasPrefix(location, image.S3Prefix):
-		return s3Instance.GetImage(location[len(image.S3Prefix):])
-	case strings.HasPrefix(location, image.LocalFilePrefix):
-		return local.GetImage(location[len(image.LocalFilePrefix):])
-	default:
-		return local.GetImage(location)
+// getStorageByLocation returns the storage of the location and the path of the image in the storage
+func getStorageByLocation(location string) (Storage, string) {
+	for prefix, s := range storages {
+		if strings.HasPrefix(location, prefix) {
+			return s, location[len(prefix):]
+		}
 	}
+	return storages[image.LocalFilePrefix], location
+}
+
+func GetImage(location string) (int64, io.ReadCloser, error) {
+	s, imagePath := getStorageByLocation(location)
+	return s.GetImage(imagePath)
+}
+
+// GetImageRange reads length bytes of the image from offset, to the end if length < 0
+func GetImageRange(location string, offset, length int64) (io.ReadCloser, error) {
+	s, imagePath := getStorageByLocation(location)
+	return s.GetImageRange(imagePath, offset, length)
 }
 
 func RemoveImage(location string) error {
-	switch {
-	case strings.HasPrefix(location, image.S3Prefix):
-		return s3Instance.RemoveImage(location[len(image.S3Prefix):])
-	case strings.HasPrefix(location, image.LocalFilePrefix):
-		return local.RemoveImage(location[len(image.LocalFilePrefix):])
-	default:
-		return local.RemoveImage(location)
-	}
+	s, imagePath := getStorageByLocation(location)
+	return s.RemoveImage(imagePath)
 }
 
 func IsCheckStatusEnabled(img *SImage) bool {
-	switch {
-	case strings.HasPrefix(img.Location, image.S3Prefix):
-		return s3Instance.IsCheckStatusEnabled()
-	case strings.HasPrefix(img.Location, image.LocalFilePrefix):
-		return local.IsCheckStatusEnabled()
-	default:
-		return local.IsCheckStatusEnabled()
-	}
+	s, _ := getStorageByLocation(img.Location)
+	return s.IsCheckStatusEnabled()
 }
 
 func Init(storageBackend string) {
-	switch storageBackend {
-	case image.IMAGE_STORAGE_DRIVER_LOCAL:
-		storage = &LocalStorage{}
-	case image.IMAGE_STORAGE_DRIVER_S3:
-		storage = &S3Storage{}
-	default:
-		storage = &LocalStorage{}
+	storage = storages[image.LocalFilePrefix]
+	for _, s := range storages {
+		if s.Type() == storageBackend {
+			storage = s
+		}
 	}
 }
 
@@ -85,11 +94,37 @@ type Storage interface {
 	SaveImage(string) (string, error)
 	CleanTempfile(string) error
 	GetImage(string) (int64, io.ReadCloser, error)
+	GetImageRange(imagePath string, offset, length int64) (io.ReadCloser, error)
 	RemoveImage(string) error
 
 	IsCheckStatusEnabled() bool
 }
 
+type fileRangeReader struct {
+	io.Reader
+	file *os.File
+}
+
+func (r *fileRangeReader) Close() error {
+	return r.file.Close()
+}
+
+func getFileRange(filePath string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open file %s", filePath)
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "seek file %s to %d", filePath, offset)
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return &fileRangeReader{Reader: io.LimitReader(f, length), file: f}, nil
+}
+
 type LocalStorage struct{}
 
 func (s *LocalStorage) Type() string {
@@ -116,6 +151,10 @@ func (s *LocalStorage) GetImage(imagePath string) (int64, io.ReadCloser, error)
 	return fstat.Size(), f, nil
 }
 
+func (s *LocalStorage) GetImageRange(imagePath string, offset, length int64) (io.ReadCloser, error) {
+	return getFileRange(imagePath, offset, length)
+}
+
 func (s *LocalStorage) IsCheckStatusEnabled() bool {
 	return true
 }
@@ -159,6 +198,14 @@ func (s *S3Storage) GetImage(imagePath string) (int64, io.ReadCloser, error) {
 	return objInfo.Size, obj, nil
 }
 
+func (s *S3Storage) GetImageRange(imagePath string, offset, length int64) (io.ReadCloser, error) {
+	obj, err := s3.GetRange(imagePathToName(imagePath), offset, length)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3 get image range")
+	}
+	return obj, nil
+}
+
 func (s *S3Storage) IsCheckStatusEnabled() bool {
 	return options.Options.S3CheckImageStatus
 }
@@ -166,3 +213,79 @@ func (s *S3Storage) IsCheckStatusEnabled() bool {
 func (s *S3Storage) RemoveImage(fileName string) error {
 	return s3.Remove(fileName)
 }
+
+// RbdStorage keeps the images as rbd images in the ceph pool, hosts on the same
+// ceph cluster clone their image caches from the pool directly
+type RbdStorage struct{}
+
+func (s *RbdStorage) Type() string {
+	return image.IMAGE_STORAGE_DRIVER_RBD
+}
+
+func (s *RbdStorage) SaveImage(imagePath string) (string, error) {
+	return rbd.Put(imagePath, imagePathToName(imagePath))
+}
+
+func (s *RbdStorage) CleanTempfile(filePath string) error {
+	return os.Remove(filePath)
+}
+
+func (s *RbdStorage) GetImage(imagePath string) (int64, io.ReadCloser, error) {
+	return rbd.Get(imagePathToName(imagePath))
+}
+
+func (s *RbdStorage) GetImageRange(imagePath string, offset, length int64) (io.ReadCloser, error) {
+	return rbd.GetRange(imagePathToName(imagePath), offset, length)
+}
+
+func (s *RbdStorage) RemoveImage(imagePath string) error {
+	err := rbd.Remove(imagePathToName(imagePath))
+	if errors.Cause(err) == rbd.ErrImageNotFound {
+		return nil
+	}
+	return err
+}
+
+func (s *RbdStorage) IsCheckStatusEnabled() bool {
+	return false
+}
+
+// NfsStorage keeps the images in the nfs export mounted at NfsMountPoint,
+// the location is the image path relative to the mount point
+type NfsStorage struct{}
+
+func (s *NfsStorage) Type() string {
+	return image.IMAGE_STORAGE_DRIVER_NFS
+}
+
+func (s *NfsStorage) SaveImage(imagePath string) (string, error) {
+	name := imagePathToName(imagePath)
+	nfsPath := filepath.Join(options.Options.NfsMountPoint, name)
+	if imagePath != nfsPath {
+		out, err := procutils.NewCommand("mv", "-f", imagePath, nfsPath).Output()
+		if err != nil {
+			return "", errors.Wrapf(err, "mv %s to nfs storage: %s", imagePath, out)
+		}
+	}
+	return fmt.Sprintf("%s%s", image.NfsPrefix, name), nil
+}
+
+func (s *NfsStorage) CleanTempfile(filePath string) error {
+	return nil
+}
+
+func (s *NfsStorage) GetImage(imagePath string) (int64, io.ReadCloser, error) {
+	return storages[image.LocalFilePrefix].GetImage(filepath.Join(options.Options.NfsMountPoint, imagePath))
+}
+
+func (s *NfsStorage) GetImageRange(imagePath string, offset, length int64) (io.ReadCloser, error) {
+	return getFileRange(filepath.Join(options.Options.NfsMountPoint, imagePath), offset, length)
+}
+
+func (s *NfsStorage) RemoveImage(imagePath string) error {
+	return os.Remove(filepath.Join(options.Options.NfsMountPoint, imagePath))
+}
+
+func (s *NfsStorage) IsCheckStatusEnabled() bool {
+	return true
+}
@@ -42,7 +42,7 @@ type SImageOptions struct {
 
 	DeployServerSocketPath string `help:"Deploy server listen socket path" default:"/var/run/onecloud/deploy.sock"`
 
-	StorageDriver string `help:"image backend storage" default:"local" choices:"s3|local"`
+	StorageDriver string `help:"image backend storage" default:"local" choices:"s3|local|rbd|nfs"`
 
 	S3AccessKey        string `help:"s3 access key"`
 	S3SecretKey        string `help:"s3 secret key"`
@@ -51,6 +51,18 @@ type SImageOptions struct {
 	S3BucketName       string `help:"s3 bucket name" default:"onecloud-images"`
 	S3MountPoint       string `help:"s3fs mount point" default:"/opt/cloud/workspace/data/glance/s3images"`
 	S3CheckImageStatus bool   `help:"Enable s3 check image status"`
+
+	RbdMonHost string `help:"ceph mon hosts of the rbd image storage, separated by comma"`
+	RbdKey     string `help:"ceph client.admin key of the rbd image storage"`
+	RbdPool    string `help:"ceph pool to store images" default:"images"`
+
+	NfsMountPoint   string `help:"mount point of the nfs image storage" default:"/opt/cloud/workspace/data/glance/nfsimages"`
+	NfsExportPath   string `help:"nfs export to mount at nfs_mount_point, e.g. 192.168.0.1:/images, leave empty if mounted by the system"`
+	NfsMountOptions string `help:"options to mount the nfs export" default:"vers=4,hard"`
+
+	ScratchDir string `help:"scratch directory to receive and convert images which are saved to a remote storage" default:"/opt/cloud/workspace/data/glance/scratch"`
+
+	ImageReplicationIntervalSeconds int `help:"Interval to apply image replication policies and sync replica status" default:"300"`
 }
 
 var (
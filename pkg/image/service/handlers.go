@@ -55,6 +55,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.ImageMemberManager,
 		models.ImagePropertyManager,
 		models.ImageSubformatManager,
+		models.ImageReplicaManager,
 
 		models.GuestImageJointManager,
 
@@ -71,6 +72,8 @@ func InitHandlers(app *appsrv.Application) {
 		models.ImageManager,
 
 		models.GuestImageManager,
+		models.ImageTrustedKeyManager,
+		models.ImageReplicationPolicyManager,
 	} {
 		db.RegisterModelManager(manager)
 		handler := db.NewModelHandler(manager)
@@ -31,6 +31,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
 	"yunion.io/x/onecloud/pkg/hostman/hostdeployer/deployclient"
+	"yunion.io/x/onecloud/pkg/image/drivers/rbd"
 	"yunion.io/x/onecloud/pkg/image/drivers/s3"
 	"yunion.io/x/onecloud/pkg/image/models"
 	"yunion.io/x/onecloud/pkg/image/options"
@@ -101,8 +102,13 @@ func StartService() {
 
 	go models.CheckImages()
 	models.Init(options.Options.StorageDriver)
-	if options.Options.StorageDriver == api.IMAGE_STORAGE_DRIVER_S3 {
+	switch options.Options.StorageDriver {
+	case api.IMAGE_STORAGE_DRIVER_S3:
 		initS3()
+	case api.IMAGE_STORAGE_DRIVER_RBD:
+		initRbd()
+	case api.IMAGE_STORAGE_DRIVER_NFS:
+		initNfs()
 	}
 
 	if len(options.Options.DeployServerSocketPath) > 0 {
@@ -116,6 +122,10 @@ func StartService() {
 		cron.AddJobAtIntervals("CalculateQuotaUsages", time.Duration(opts.CalculateQuotaUsageIntervalSeconds)*time.Second, models.QuotaManager.CalculateQuotaUsages)
 		cron.AddJobAtIntervals("CleanPendingDeleteGuestImages",
 			time.Duration(options.Options.PendingDeleteCheckSeconds)*time.Second, models.GuestImageManager.CleanPendingDeleteImages)
+		cron.AddJobAtIntervals("SyncImageReplicaStatus",
+			time.Duration(options.Options.ImageReplicationIntervalSeconds)*time.Second, models.ImageReplicaManager.SyncReplicaStatus)
+		cron.AddJobAtIntervals("ApplyImageReplicationPolicies",
+			time.Duration(options.Options.ImageReplicationIntervalSeconds)*time.Second, models.ImageReplicationPolicyManager.ApplyReplicationPolicies)
 
 		cron.Start()
 	}
@@ -131,6 +141,9 @@ func StartService() {
 		if options.Options.StorageDriver == api.IMAGE_STORAGE_DRIVER_S3 {
 			procutils.NewCommand("umount", options.Options.S3MountPoint).Run()
 		}
+		if options.Options.StorageDriver == api.IMAGE_STORAGE_DRIVER_NFS && len(options.Options.NfsExportPath) > 0 {
+			procutils.NewCommand("umount", options.Options.NfsMountPoint).Run()
+		}
 	})
 }
 
@@ -175,3 +188,36 @@ func initS3() {
 		log.Fatalf("failed mount s3fs %s %s", err, out)
 	}
 }
+
+func initRbd() {
+	if !fileutils2.Exists(options.Options.ScratchDir) {
+		err := os.MkdirAll(options.Options.ScratchDir, 0755)
+		if err != nil {
+			log.Fatalf("fail to create %s: %s", options.Options.ScratchDir, err)
+		}
+	}
+	err := rbd.Init(options.Options.RbdMonHost, options.Options.RbdKey, options.Options.RbdPool)
+	if err != nil {
+		log.Fatalf("failed init rbd client %s", err)
+	}
+}
+
+func initNfs() {
+	if !fileutils2.Exists(options.Options.NfsMountPoint) {
+		err := os.MkdirAll(options.Options.NfsMountPoint, 0755)
+		if err != nil {
+			log.Fatalf("fail to create %s: %s", options.Options.NfsMountPoint, err)
+		}
+	}
+	if len(options.Options.NfsExportPath) == 0 {
+		return
+	}
+	if procutils.NewCommand("mountpoint", "-q", options.Options.NfsMountPoint).Run() == nil {
+		return
+	}
+	out, err := procutils.NewCommand("mount", "-t", "nfs", "-o", options.Options.NfsMountOptions,
+		options.Options.NfsExportPath, options.Options.NfsMountPoint).Output()
+	if err != nil {
+		log.Fatalf("failed mount nfs %s %s", err, out)
+	}
+}
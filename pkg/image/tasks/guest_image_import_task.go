@@ -0,0 +1,221 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/image/models"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/ovfutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+type GuestImageImportTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(GuestImageImportTask{})
+}
+
+func (self *GuestImageImportTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guestImage := obj.(*models.SGuestImage)
+
+	input := api.GuestImageImportInput{}
+	self.Params.Unmarshal(&input)
+	if len(input.CopyFrom) > 0 {
+		log.Infof("Import %s guest image from %s", input.ImportFormat, input.CopyFrom)
+	}
+
+	guestImage.SetStatus(self.UserCred, api.IMAGE_STATUS_SAVING, "import")
+	self.SetStage("OnImportComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		err := os.MkdirAll(guestImage.GetImportDir(), 0755)
+		if err != nil {
+			return nil, errors.Wrap(err, "MkdirAll")
+		}
+		info, err := self.fetchPackage(ctx, guestImage.GetImportDir(), input)
+		if err != nil {
+			return nil, errors.Wrap(err, "fetch package")
+		}
+		if len(info.Disks) == 0 {
+			return nil, fmt.Errorf("no disk found in %s package", input.ImportFormat)
+		}
+		files, err := info.GetDiskFiles()
+		if err != nil {
+			return nil, err
+		}
+		subParams, _ := self.Params.Get("sub_image_params")
+		if subParams == nil {
+			subParams = jsonutils.NewDict()
+		}
+		images, err := guestImage.CreateImportedSubImages(ctx, self.UserCred, subParams.(*jsonutils.JSONDict), len(info.Disks))
+		if err != nil {
+			return nil, err
+		}
+		for i, image := range images {
+			localPath := filepath.Join(guestImage.GetImportDir(), files[i].LocalName())
+			err := self.convertDisk(ctx, image, localPath)
+			if err != nil {
+				return nil, errors.Wrapf(err, "convert disk %s", info.Disks[i].DiskId)
+			}
+			err = models.ImagePropertyManager.SaveProperties(ctx, self.UserCred, image.Id, models.OvfImageProperties(info, i))
+			if err != nil {
+				log.Errorf("save ovf properties of %s: %s", image.Id, err)
+			}
+		}
+		return nil, nil
+	})
+}
+
+func (self *GuestImageImportTask) fetchPackage(ctx context.Context, dir string, input api.GuestImageImportInput) (*ovfutils.SOvfInfo, error) {
+	if self.Params.Contains("ovf_info") {
+		// uploaded package has been extracted on create
+		info := &ovfutils.SOvfInfo{}
+		err := self.Params.Unmarshal(info, "ovf_info")
+		if err != nil {
+			return nil, errors.Wrap(err, "unmarshal ovf_info")
+		}
+		return info, nil
+	}
+	resp, err := self.download(ctx, input.CopyFrom)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if input.ImportFormat == api.IMAGE_IMPORT_FORMAT_OVA {
+		return ovfutils.ExtractOva(resp.Body, dir)
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read ovf descriptor")
+	}
+	info, err := ovfutils.ParseOvf(content)
+	if err != nil {
+		return nil, err
+	}
+	files, err := info.GetDiskFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		diskUrl, err := getOvfFileUrl(input, file)
+		if err != nil {
+			return nil, err
+		}
+		err = func() error {
+			resp, err := self.download(ctx, diskUrl)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			_, err = ovfutils.SaveFile(resp.Body, file, dir)
+			return err
+		}()
+		if err != nil {
+			return nil, errors.Wrapf(err, "download %s", diskUrl)
+		}
+	}
+	return info, nil
+}
+
+// getOvfFileUrl finds the url of a file referenced by an ovf descriptor, either
+// from the disk urls given on import or relative to the url of the descriptor
+func getOvfFileUrl(input api.GuestImageImportInput, file *ovfutils.SOvfFile) (string, error) {
+	for _, diskUrl := range input.DiskUrls {
+		u, err := url.Parse(diskUrl)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid disk url %s", diskUrl)
+		}
+		if path.Base(u.Path) == file.LocalName() {
+			return diskUrl, nil
+		}
+	}
+	base, err := url.Parse(input.CopyFrom)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid url %s", input.CopyFrom)
+	}
+	ref, err := url.Parse(file.Href)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid href %s", file.Href)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+func (self *GuestImageImportTask) download(ctx context.Context, src string) (*http.Response, error) {
+	client := httputils.GetTimeoutClient(0)
+	transport := httputils.GetTransport(true)
+	transport.Proxy = options.Options.HttpTransportProxyFunc()
+	client.Transport = transport
+	return httputils.Request(client, ctx, httputils.GET, src, http.Header{}, nil, false)
+}
+
+func (self *GuestImageImportTask) convertDisk(ctx context.Context, image *models.SImage, localPath string) error {
+	image.SetStatus(self.UserCred, api.IMAGE_STATUS_SAVING, "import")
+	img, err := qemuimg.NewQemuImage(localPath)
+	if err != nil {
+		return errors.Wrap(err, "NewQemuImage")
+	}
+	if !img.IsValid() {
+		return fmt.Errorf("%s is not a valid disk image", localPath)
+	}
+	err = img.Convert2Qcow2To(image.GetPath(""), true)
+	if err != nil {
+		return errors.Wrap(err, "Convert2Qcow2To")
+	}
+	// the source is not needed anymore, free the space early for the following disks
+	if err := os.Remove(localPath); err != nil && !os.IsNotExist(err) {
+		log.Warningf("remove %s: %s", localPath, err)
+	}
+	return image.SaveImageFromLocalFile()
+}
+
+func (self *GuestImageImportTask) OnImportComplete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guestImage := obj.(*models.SGuestImage)
+	guestImage.CleanImportDir()
+	images, err := models.GuestImageJointManager.GetImagesByGuestImageId(guestImage.Id)
+	if err != nil {
+		self.OnImportCompleteFailed(ctx, obj, jsonutils.NewString(err.Error()))
+		return
+	}
+	for i := range images {
+		images[i].OnSaveTaskSuccess(self, self.UserCred, "import success")
+		images[i].ImageProbeAndCustomization(ctx, self.UserCred, false)
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *GuestImageImportTask) OnImportCompleteFailed(ctx context.Context, obj db.IStandaloneModel, err jsonutils.JSONObject) {
+	guestImage := obj.(*models.SGuestImage)
+	guestImage.OnImportFailed(ctx, self.UserCred, err)
+	self.SetStageFailed(ctx, err)
+}
@@ -90,7 +90,7 @@ func (self *PutImageTask) OnInit(ctx context.Context, obj db.IStandaloneModel, d
 			if err != nil {
 				log.Errorf("failed update image location %s", err)
 			} else {
-				if !strings.Contains(imagePath, options.Options.S3MountPoint) {
+				if !strings.Contains(imagePath, options.Options.S3MountPoint) && !strings.Contains(imagePath, options.Options.NfsMountPoint) {
 					if err = procutils.NewCommand("rm", "-f", imagePath).Run(); err != nil {
 						log.Errorf("failed remove file %s: %s", imagePath, err)
 					}
@@ -142,8 +142,14 @@ func (self *PutImageTask) OnInit(ctx context.Context, obj db.IStandaloneModel, d
 				if err != nil {
 					log.Errorf("failed update subimg %s", err)
 				}
-				if err = procutils.NewCommand("rm", "-f", imagePath).Run(); err != nil {
-					log.Errorf("failed remove file %s: %s", imagePath, err)
+				if len(subimgs[i].GetLocalLocation()) == 0 {
+					// the local file is going away, stop seeding it
+					subimgs[i].StopTorrent()
+				}
+				if !strings.Contains(imagePath, options.Options.NfsMountPoint) {
+					if err = procutils.NewCommand("rm", "-f", imagePath).Run(); err != nil {
+						log.Errorf("failed remove file %s: %s", imagePath, err)
+					}
 				}
 			}
 			db.Update(&subimgs[i], func() error {
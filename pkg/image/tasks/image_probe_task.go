@@ -42,6 +42,10 @@ func init() {
 
 func (self *ImageProbeTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
 	image := obj.(*models.SImage)
+	// verify the signature of the image as uploaded, before any conversion
+	if err := image.VerifySignature(ctx, self.UserCred); err != nil {
+		log.Errorf("VerifySignature of image %s: %s", image.Id, err)
+	}
 	err, ok := image.IsIso()
 	if err == nil && !ok {
 		// not a iso
@@ -0,0 +1,106 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/image/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// ImageReplicateTask creates the replicas of an image in the peer regions,
+// the peer image service pulls the data from this region by itself
+type ImageReplicateTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(ImageReplicateTask{})
+	taskman.RegisterTask(ImageReplicaPullTask{})
+}
+
+func (self *ImageReplicateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	image := obj.(*models.SImage)
+	regions := jsonutils.GetQueryStringArray(self.Params, "regions")
+	failed := jsonutils.NewDict()
+	for _, region := range regions {
+		err := image.Replicate(ctx, self.UserCred, region)
+		if err != nil {
+			log.Errorf("replicate image %s to region %s: %s", image.Id, region, err)
+			failed.Set(region, jsonutils.NewString(err.Error()))
+		}
+	}
+	if failed.Length() > 0 {
+		logclient.AddActionLogWithStartable(self, image, logclient.ACT_IMAGE_REPLICATE, failed, self.UserCred, false)
+		self.SetStageFailed(ctx, failed)
+		return
+	}
+	logclient.AddActionLogWithStartable(self, image, logclient.ACT_IMAGE_REPLICATE, fmt.Sprintf("replicate to %v", regions), self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+// ImageReplicaPullTask runs in the image service of the target region, it pulls the
+// image data and subformats from the source region then converts the missing formats
+type ImageReplicaPullTask struct {
+	taskman.STask
+}
+
+func (self *ImageReplicaPullTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	image := obj.(*models.SImage)
+	sourceRegion, _ := self.Params.GetString("source_region")
+	sourceImageId, _ := self.Params.GetString("source_image_id")
+
+	self.SetStage("OnPullComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, image.PullReplica(ctx, sourceRegion, sourceImageId)
+	})
+}
+
+func (self *ImageReplicaPullTask) OnPullComplete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	image := obj.(*models.SImage)
+	image.OnSaveTaskSuccess(self, self.UserCred, "replicate success")
+	if err := image.VerifySignature(ctx, self.UserCred); err != nil {
+		log.Errorf("VerifySignature of image %s: %s", image.Id, err)
+	}
+	// properties are replicated from the source, no need to probe again
+	self.SetStage("OnConvertComplete", nil)
+	if err := image.StartImageConvertTask(ctx, self.UserCred, self.GetId()); err != nil {
+		log.Errorf("start image convert task failed %s", err)
+		if err := image.StartPutImageTask(ctx, self.UserCred, self.GetId()); err != nil {
+			self.OnPullCompleteFailed(ctx, obj, jsonutils.NewString(err.Error()))
+		}
+	}
+}
+
+func (self *ImageReplicaPullTask) OnPullCompleteFailed(ctx context.Context, obj db.IStandaloneModel, err jsonutils.JSONObject) {
+	image := obj.(*models.SImage)
+	image.OnSaveTaskFailed(self, self.UserCred, err)
+	self.SetStageFailed(ctx, err)
+}
+
+func (self *ImageReplicaPullTask) OnConvertComplete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *ImageReplicaPullTask) OnConvertCompleteFailed(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.SetStageFailed(ctx, data)
+}
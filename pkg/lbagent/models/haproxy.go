@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -29,6 +30,7 @@ import (
 	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	agentutils "yunion.io/x/onecloud/pkg/lbagent/utils"
 	"yunion.io/x/onecloud/pkg/mcclient/models"
+	"yunion.io/x/onecloud/pkg/util/acme"
 )
 
 var haproxyConfigErrNop = errors.New("nop haproxy config snippet")
@@ -75,6 +77,10 @@ func (b *LoadbalancerCorpus) GenHaproxyConfigs(dir string, opts *AgentParams) (*
 			}
 		}
 		for _, lbcert := range b.LoadbalancerCertificates {
+			if lbcert.Certificate == "" {
+				// acme certificates not issued yet
+				continue
+			}
 			d := []byte(lbcert.Certificate)
 			if len(d) > 0 && d[len(d)-1] != '\n' {
 				d = append(d, '\n')
@@ -423,10 +429,37 @@ func (b *LoadbalancerCorpus) haproxyRedirectLine(r *models.LoadbalancerHTTPRedir
 	return line
 }
 
+// haproxyAcmeChallengeLines answers http-01 challenges of acme certificates
+// validated through the loadbalancer.  Only http listeners on port 80 are
+// visited by acme servers.  "http-request return" requires haproxy 2.2 or
+// later
+func (b *LoadbalancerCorpus) haproxyAcmeChallengeLines(listener *LoadbalancerListener) []string {
+	if listener.ListenerType != "http" || listener.ListenerPort != 80 {
+		return nil
+	}
+	lines := []string{}
+	for _, lbcert := range b.LoadbalancerCertificates {
+		if lbcert.AcmeLoadbalancerId != listener.LoadbalancerId {
+			continue
+		}
+		for token, keyAuth := range lbcert.AcmeHttpChallenges {
+			line := fmt.Sprintf("http-request return status 200 content-type text/plain string %q if { path %s }",
+				keyAuth, acme.HTTP01ChallengePath(token))
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
 func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *LoadbalancerListener, opts *AgentParams) error {
 	var (
 		lb = listener.loadbalancer
 	)
+	if listener.ListenerType == "https" && listener.certificate != nil && listener.certificate.Certificate == "" {
+		// acme certificate being issued
+		return haproxyConfigErrNop
+	}
 
 	data := b.genHaproxyConfigCommon(lb, listener, opts)
 	{
@@ -440,7 +473,7 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 
 	var (
 		rules     = listener.rules.OrderedEnabledList()
-		ruleLines = []string{}
+		ruleLines = b.haproxyAcmeChallengeLines(listener)
 		backends  = []interface{}{}
 
 		ruleBackendIdGen = func(id string) string {
@@ -196,6 +196,9 @@ type LoadbalancerCertificate struct {
 	NotAfter                time.Time
 	CommonName              string
 	SubjectAlternativeNames string
+
+	AcmeLoadbalancerId string
+	AcmeHttpChallenges map[string]string
 }
 
 type LoadbalancerCluster struct {
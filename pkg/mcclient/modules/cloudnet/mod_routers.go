@@ -37,6 +37,8 @@ func init() {
 				"user",
 				"host",
 				"port",
+				"agent_managed",
+				"agent_reported_at",
 			},
 			[]string{"tenant"},
 		),
@@ -30,6 +30,7 @@ import (
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
 )
 
 type SCredentialManager struct {
@@ -43,6 +44,7 @@ const (
 	TOTP_TYPE             = api.TOTP_TYPE
 	RECOVERY_SECRETS_TYPE = api.RECOVERY_SECRETS_TYPE
 	OIDC_CREDENTIAL_TYPE  = api.OIDC_CREDENTIAL_TYPE
+	ENCRYPT_KEY_TYPE      = api.ENCRYPT_KEY_TYPE
 )
 
 type STotpSecret struct {
@@ -74,6 +76,11 @@ type SOpenIDConnectCredential struct {
 	api.SAccessKeySecretBlob
 }
 
+type SEncryptKeySecret struct {
+	KeyId string `json:"-"`
+	api.SEncryptKeySecretBlob
+}
+
 func (manager *SCredentialManager) fetchCredentials(s *mcclient.ClientSession, secType string, uid string, pid string) ([]jsonutils.JSONObject, error) {
 	query := jsonutils.NewDict()
 	query.Add(jsonutils.NewString(secType), "type")
@@ -105,6 +112,10 @@ func (manager *SCredentialManager) FetchOIDCSecrets(s *mcclient.ClientSession, u
 	return manager.fetchCredentials(s, OIDC_CREDENTIAL_TYPE, uid, pid)
 }
 
+func (manager *SCredentialManager) FetchEncryptKeys(s *mcclient.ClientSession, uid string) ([]jsonutils.JSONObject, error) {
+	return manager.fetchCredentials(s, ENCRYPT_KEY_TYPE, uid, "")
+}
+
 func (manager *SCredentialManager) GetTotpSecret(s *mcclient.ClientSession, uid string) (string, error) {
 	secrets, err := manager.FetchTotpSecrets(s, uid)
 	if err != nil {
@@ -237,6 +248,82 @@ func (manager *SCredentialManager) GetOIDCSecret(s *mcclient.ClientSession, uid
 	return oidcCreds, nil
 }
 
+func DecodeEncryptKey(secret jsonutils.JSONObject) (SEncryptKeySecret, error) {
+	curr := SEncryptKeySecret{}
+	typ, _ := secret.GetString("type")
+	if typ != ENCRYPT_KEY_TYPE {
+		return curr, errors.Wrapf(httperrors.ErrInvalidFormat, "credential type %s is not %s", typ, ENCRYPT_KEY_TYPE)
+	}
+	blobStr, err := secret.GetString("blob")
+	if err != nil {
+		return curr, errors.Wrap(err, "secret.GetString")
+	}
+	blobJson, err := jsonutils.ParseString(blobStr)
+	if err != nil {
+		return curr, errors.Wrap(err, "jsonutils.ParseString")
+	}
+	err = blobJson.Unmarshal(&curr)
+	if err != nil {
+		return curr, errors.Wrap(err, "blobJson.Unmarshal")
+	}
+	curr.KeyId, err = secret.GetString("id")
+	if err != nil {
+		return curr, errors.Wrap(err, "secret.GetString('id')")
+	}
+	return curr, nil
+}
+
+func (manager *SCredentialManager) GetEncryptKey(s *mcclient.ClientSession, kid string) (SEncryptKeySecret, error) {
+	secret, err := manager.Get(s, kid, nil)
+	if err != nil {
+		return SEncryptKeySecret{}, errors.Wrap(err, "Get")
+	}
+	return DecodeEncryptKey(secret)
+}
+
+func (manager *SCredentialManager) GetEncryptKeys(s *mcclient.ClientSession, uid string) ([]SEncryptKeySecret, error) {
+	secrets, err := manager.FetchEncryptKeys(s, uid)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]SEncryptKeySecret, 0)
+	for i := range secrets {
+		curr, err := DecodeEncryptKey(secrets[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "DecodeEncryptKey")
+		}
+		keys = append(keys, curr)
+	}
+	return keys, nil
+}
+
+func (manager *SCredentialManager) CreateEncryptKey(s *mcclient.ClientSession, uid string, name string) (SEncryptKeySecret, error) {
+	encKey := SEncryptKeySecret{}
+	key, err := seclib2.GenerateDataKey()
+	if err != nil {
+		return encKey, errors.Wrap(err, "GenerateDataKey")
+	}
+	encKey.Key = base64.StdEncoding.EncodeToString(key)
+	encKey.Alg = api.ENCRYPT_ALG_AES_256
+	blobJson := jsonutils.Marshal(&encKey)
+	params := jsonutils.NewDict()
+	if len(name) == 0 {
+		name = fmt.Sprintf("enc-key-%s-%d", uid, time.Now().Unix())
+	}
+	params.Add(jsonutils.NewString(ENCRYPT_KEY_TYPE), "type")
+	if len(uid) > 0 {
+		params.Add(jsonutils.NewString(uid), "user_id")
+	}
+	params.Add(jsonutils.NewString(blobJson.String()), "blob")
+	params.Add(jsonutils.NewString(name), "name")
+	result, err := manager.Create(s, params)
+	if err != nil {
+		return encKey, err
+	}
+	encKey.KeyId, _ = result.GetString("id")
+	return encKey, nil
+}
+
 func (manager *SCredentialManager) DoCreateAccessKeySecret(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	key, err := manager.CreateAccessKeySecret(s, "", "", time.Time{})
 	if err != nil {
@@ -402,6 +489,10 @@ func (manager *SCredentialManager) RemoveRecoverySecrets(s *mcclient.ClientSessi
 	return manager.removeCredentials(s, RECOVERY_SECRETS_TYPE, uid, "")
 }
 
+func (manager *SCredentialManager) RemoveEncryptKeys(s *mcclient.ClientSession, uid string) error {
+	return manager.removeCredentials(s, ENCRYPT_KEY_TYPE, uid, "")
+}
+
 func (manager *SCredentialManager) RemoveOIDCSecrets(s *mcclient.ClientSession, uid string, pid string) error {
 	return manager.removeCredentials(s, OIDC_CREDENTIAL_TYPE, uid, pid)
 }
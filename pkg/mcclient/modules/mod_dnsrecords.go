@@ -22,7 +22,7 @@ var (
 
 func init() {
 	DNSRecords = NewComputeManager("dnsrecord", "dnsrecords",
-		[]string{"ID", "Name", "Records", "TTL", "is_public"},
+		[]string{"ID", "Name", "Records", "TTL", "is_public", "traffic_policy", "health_check"},
 		[]string{})
 
 	registerCompute(&DNSRecords)
@@ -0,0 +1,29 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+import "yunion.io/x/onecloud/pkg/mcclient/modulebase"
+
+var (
+	HostPowerPolicies modulebase.ResourceManager
+)
+
+func init() {
+	HostPowerPolicies = NewComputeManager("host_power_policy", "host_power_policies",
+		[]string{"ID", "Name", "Enabled", "Status", "Zone", "Schedtag", "Mode", "Low_Utilization", "Max_Utilization", "Min_Active_Hosts", "Max_Power_Offs", "Reserve_Vcpu_Count", "Reserve_Vmem_Size", "Reserve_Count", "Interval_Minutes", "Last_Evaluated_At"},
+		[]string{})
+
+	registerCompute(&HostPowerPolicies)
+}
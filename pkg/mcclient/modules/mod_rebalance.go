@@ -0,0 +1,34 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+import "yunion.io/x/onecloud/pkg/mcclient/modulebase"
+
+var (
+	RebalancePolicies        modulebase.ResourceManager
+	RebalanceRecommendations modulebase.ResourceManager
+)
+
+func init() {
+	RebalancePolicies = NewComputeManager("rebalance_policy", "rebalance_policies",
+		[]string{"ID", "Name", "Enabled", "Status", "Zone", "Schedtag", "Mode", "Cpu_Threshold", "Memory_Threshold", "Max_Utilization", "Max_Migrations", "Use_Metrics", "Interval_Minutes", "Last_Evaluated_At", "Last_Imbalance"},
+		[]string{})
+	RebalanceRecommendations = NewComputeManager("rebalance_recommendation", "rebalance_recommendations",
+		[]string{"ID", "Name", "Status", "Rebalance_Policy", "Guest", "Src_Host", "Dst_Host", "Imbalance_Before", "Imbalance_After", "Reason", "Auto_Executed", "Executed_At", "Created_At"},
+		[]string{})
+
+	registerCompute(&RebalancePolicies)
+	registerCompute(&RebalanceRecommendations)
+}
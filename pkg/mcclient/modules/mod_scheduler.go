@@ -116,6 +116,32 @@ func (this *SchedulerManager) DoForecast(s *mcclient.ClientSession, params jsonu
 	return obj, err
 }
 
+// Simulate packs workloads on scheduler candidates changed by hypothetical
+// host removals and additions, workloads without project are owned by the
+// project of session
+func (this *SchedulerManager) Simulate(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	workloads, err := params.GetArray("workloads")
+	if err != nil {
+		return nil, err
+	}
+	for _, workload := range workloads {
+		data, ok := workload.(*jsonutils.JSONDict)
+		if !ok || data.Contains("project_id") {
+			continue
+		}
+		data.Set("project_id", jsonutils.NewString(s.GetProjectId()))
+		data.Set("domain_id", jsonutils.NewString(s.GetProjectDomainId()))
+	}
+	body := params.(*jsonutils.JSONDict)
+	body.Set("workloads", jsonutils.NewArray(workloads...))
+	url := newSchedURL("simulate")
+	_, obj, err := modulebase.JsonRequest(this.ResourceManager, s, "POST", url, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
 func (this *SchedulerManager) Cleanup(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	url := newSchedURL("cleanup")
 	return modulebase.Post(this.ResourceManager, s, url, params, "")
@@ -0,0 +1,98 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import "yunion.io/x/jsonutils"
+
+type CapacityReservationListOptions struct {
+	BaseListOptions
+
+	Zone       string `help:"filter by zone"`
+	SchedtagId string `help:"filter by schedtag"`
+	Active     *bool  `help:"only list reservations in effect"`
+}
+
+func (opts *CapacityReservationListOptions) Params() (jsonutils.JSONObject, error) {
+	return ListStructToParams(opts)
+}
+
+type CapacityReservationIdOptions struct {
+	ID string `help:"ID or name of capacity reservation"`
+}
+
+func (opts *CapacityReservationIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *CapacityReservationIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+type CapacityReservationCreateOptions struct {
+	BaseCreateOptions
+
+	ProjectId  string `help:"project owning the reserved capacity" json:"project_id"`
+	ZoneId     string `help:"zone of hosts to reserve capacity on"`
+	SchedtagId string `help:"schedtag of hosts to reserve capacity on"`
+
+	CpuCount            int    `help:"reserved cpu count"`
+	MemorySize          int    `help:"reserved memory size in MB"`
+	StorageSize         int    `help:"reserved local storage size in MB"`
+	IsolatedDeviceModel string `help:"model of reserved isolated devices"`
+	IsolatedDeviceCount int    `help:"number of reserved isolated devices"`
+
+	StartTime string `help:"start time of reservation, e.g. 2026-01-01T00:00:00Z, defaults to now"`
+	EndTime   string `help:"end time of reservation, e.g. 2026-02-01T00:00:00Z" required:"true"`
+}
+
+func (opts *CapacityReservationCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type CapacityReservationUpdateOptions struct {
+	BaseUpdateOptions
+
+	CpuCount            *int   `help:"reserved cpu count"`
+	MemorySize          *int   `help:"reserved memory size in MB"`
+	StorageSize         *int   `help:"reserved local storage size in MB"`
+	IsolatedDeviceCount *int   `help:"number of reserved isolated devices"`
+	StartTime           string `help:"start time of reservation"`
+	EndTime             string `help:"end time of reservation"`
+}
+
+func (opts *CapacityReservationUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params, err := opts.BaseUpdateOptions.Params()
+	if err != nil {
+		return nil, err
+	}
+	dict := params.(*jsonutils.JSONDict)
+	for key, val := range map[string]*int{
+		"cpu_count":             opts.CpuCount,
+		"memory_size":           opts.MemorySize,
+		"storage_size":          opts.StorageSize,
+		"isolated_device_count": opts.IsolatedDeviceCount,
+	} {
+		if val != nil {
+			dict.Add(jsonutils.NewInt(int64(*val)), key)
+		}
+	}
+	if len(opts.StartTime) > 0 {
+		dict.Add(jsonutils.NewString(opts.StartTime), "start_time")
+	}
+	if len(opts.EndTime) > 0 {
+		dict.Add(jsonutils.NewString(opts.EndTime), "end_time")
+	}
+	return dict, nil
+}
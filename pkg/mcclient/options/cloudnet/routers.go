@@ -32,6 +32,8 @@ type RouterCreateOptions struct {
 	RealizeWgIfaces string `choices:"on|off" default:"on" help:"apply wg ifaces config on realization"`
 	RealizeRoutes   string `choices:"on|off" default:"on" help:"apply routes config on realization"`
 	RealizeRules    string `choices:"on|off" default:"on" help:"apply firewall rules on realization"`
+
+	AgentManaged string `choices:"on|off" default:"off" help:"router runs cloudnet agent which pulls and applies config by itself, instead of ansible"`
 }
 
 func (opts *RouterCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -65,6 +67,8 @@ type RouterUpdateOptions struct {
 	RealizeWgIfaces string `json:",omitzero" choices:"on|off" help:"apply wg ifaces config on realization"`
 	RealizeRoutes   string `json:",omitzero" choices:"on|off" help:"apply routes config on realization"`
 	RealizeRules    string `json:",omitzero" choices:"on|off" help:"apply firewall rules on realization"`
+
+	AgentManaged string `json:",omitzero" choices:"on|off" help:"router runs cloudnet agent which pulls and applies config by itself, instead of ansible"`
 }
 
 func (opts *RouterUpdateOptions) Params() (jsonutils.JSONObject, error) {
@@ -118,3 +122,7 @@ type RouterActionUnregisterIfnameOptions struct {
 type RouterActionRealizeOptions struct {
 	ID string `json:"-"`
 }
+
+type RouterAgentConfigOptions struct {
+	ID string `json:"-"`
+}
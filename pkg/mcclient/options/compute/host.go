@@ -52,6 +52,8 @@ type HostListOptions struct {
 
 	Sn string `help:"find host by sn"`
 
+	PowerState []string `help:"filter hosts by power state" choices:"on|evacuating|powering_off|standby|powering_on"`
+
 	OrderByServerCount string `help:"Order by server count" choices:"desc|asc"`
 
 	options.BaseListOptions
@@ -85,3 +87,23 @@ type HostStatusStatisticsOptions struct {
 	HostListOptions
 	options.StatusStatisticsOptions
 }
+
+type HostEnterMaintenanceOptions struct {
+	options.BaseIdOptions
+	Parallel      int    `help:"Number of guests migrated at the same time"`
+	PreferHost    string `help:"Migrate guests to this host"`
+	DefaultPolicy string `help:"Migrate policy of guests without maintenance policy" choices:"live|cold|none"`
+}
+
+func (opts *HostEnterMaintenanceOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(opts)
+}
+
+type HostExitMaintenanceOptions struct {
+	options.BaseIdOptions
+	Force bool `help:"Exit maintenance even if readiness checks failed"`
+}
+
+func (opts *HostExitMaintenanceOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(opts)
+}
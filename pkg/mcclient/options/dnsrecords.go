@@ -16,6 +16,8 @@ package options
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"yunion.io/x/jsonutils"
 )
@@ -56,6 +58,54 @@ func parseDNSRecords(opts *DNSRecordOptions, params *jsonutils.JSONDict) {
 	}
 }
 
+type DNSTrafficPolicyOptions struct {
+	TrafficPolicy string   `help:"Traffic policy of A/AAAA records" choices:"simple|weighted|client_subnet|failover"`
+	Weight        []string `help:"Weight of record value for weighted policy, in the format of value:weight" json:"-"`
+	Subnet        []string `help:"Record values for clients of the subnet for client_subnet policy, in the format of cidr=value[,value...]" json:"-"`
+	HealthCheck   *bool    `help:"Drop record values not responding ping probe of cloudmon"`
+}
+
+func parseDNSTrafficPolicy(opts *DNSTrafficPolicyOptions, params *jsonutils.JSONDict) error {
+	if len(opts.Weight) == 0 && len(opts.Subnet) == 0 {
+		return nil
+	}
+	policyOpts := jsonutils.NewDict()
+	if len(opts.Weight) > 0 {
+		weights := jsonutils.NewArray()
+		for _, w := range opts.Weight {
+			i := strings.LastIndex(w, ":")
+			if i <= 0 {
+				return fmt.Errorf("invalid weight %q, want value:weight", w)
+			}
+			weight, err := strconv.Atoi(w[i+1:])
+			if err != nil {
+				return fmt.Errorf("invalid weight %q: %v", w, err)
+			}
+			weights.Add(jsonutils.Marshal(map[string]interface{}{
+				"value":  w[:i],
+				"weight": weight,
+			}))
+		}
+		policyOpts.Set("weights", weights)
+	}
+	if len(opts.Subnet) > 0 {
+		subnets := jsonutils.NewArray()
+		for _, sn := range opts.Subnet {
+			parts := strings.SplitN(sn, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid subnet %q, want cidr=value[,value...]", sn)
+			}
+			subnets.Add(jsonutils.Marshal(map[string]interface{}{
+				"cidr":   parts[0],
+				"values": strings.Split(parts[1], ","),
+			}))
+		}
+		policyOpts.Set("subnets", subnets)
+	}
+	params.Set("traffic_policy_options", policyOpts)
+	return nil
+}
+
 type DNSCreateOptions struct {
 	NAME     string `help:"DNS name to create"`
 	TTL      int64  `help:"TTL in seconds" positional:"false"`
@@ -63,6 +113,7 @@ type DNSCreateOptions struct {
 	IsPublic *bool  `help:"Make the newly created record public to all"`
 
 	DNSRecordOptions
+	DNSTrafficPolicyOptions
 }
 
 func (opts *DNSCreateOptions) Params() (*jsonutils.JSONDict, error) {
@@ -71,6 +122,9 @@ func (opts *DNSCreateOptions) Params() (*jsonutils.JSONDict, error) {
 		return nil, err
 	}
 	parseDNSRecords(&opts.DNSRecordOptions, params)
+	if err := parseDNSTrafficPolicy(&opts.DNSTrafficPolicyOptions, params); err != nil {
+		return nil, err
+	}
 	return params, nil
 }
 
@@ -81,6 +135,7 @@ type DNSUpdateOptions struct {
 	Desc string `help:"Description" json:"description"`
 
 	DNSRecordOptions
+	DNSTrafficPolicyOptions
 }
 
 func (opts *DNSUpdateOptions) Params() (*jsonutils.JSONDict, error) {
@@ -89,6 +144,9 @@ func (opts *DNSUpdateOptions) Params() (*jsonutils.JSONDict, error) {
 		return nil, err
 	}
 	parseDNSRecords(&opts.DNSRecordOptions, params)
+	if err := parseDNSTrafficPolicy(&opts.DNSTrafficPolicyOptions, params); err != nil {
+		return nil, err
+	}
 	return params, nil
 }
 
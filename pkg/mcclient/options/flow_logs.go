@@ -0,0 +1,91 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import "yunion.io/x/jsonutils"
+
+type FlowLogListOptions struct {
+	BaseListOptions
+
+	Vpc          string   `help:"filter by vpc"`
+	ResourceType []string `help:"filter by resource type" choices:"vpc|network|guest"`
+	ResourceId   string   `help:"filter by resource id"`
+	DeliveryType []string `help:"filter by delivery type" choices:"logger|bucket"`
+}
+
+func (opts *FlowLogListOptions) Params() (jsonutils.JSONObject, error) {
+	return ListStructToParams(opts)
+}
+
+type FlowLogIdOptions struct {
+	ID string `help:"ID or name of flow log"`
+}
+
+func (opts *FlowLogIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *FlowLogIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+type FlowLogCreateOptions struct {
+	EnabledStatusCreateOptions
+
+	ResourceType string `help:"type of resource to collect flow logs of" choices:"vpc|network|guest" required:"true"`
+	ResourceId   string `help:"id or name of resource to collect flow logs of" required:"true"`
+
+	TrafficType string `help:"traffic to log" choices:"all|accept|drop"`
+	RateLimit   int    `help:"max number of packets logged per second"`
+
+	DeliveryType  string `help:"where to deliver flow records" choices:"logger|bucket"`
+	BucketId      string `help:"bucket to deliver flow records to"`
+	BucketPrefix  string `help:"object key prefix in the bucket"`
+	RetentionDays int    `help:"days to keep flow records in the bucket"`
+}
+
+func (opts *FlowLogCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type FlowLogUpdateOptions struct {
+	BaseUpdateOptions
+
+	TrafficType   string `help:"traffic to log" choices:"all|accept|drop"`
+	RateLimit     *int   `help:"max number of packets logged per second"`
+	BucketPrefix  string `help:"object key prefix in the bucket"`
+	RetentionDays *int   `help:"days to keep flow records in the bucket"`
+}
+
+func (opts *FlowLogUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params, err := opts.BaseUpdateOptions.Params()
+	if err != nil {
+		return nil, err
+	}
+	dict := params.(*jsonutils.JSONDict)
+	if len(opts.TrafficType) > 0 {
+		dict.Add(jsonutils.NewString(opts.TrafficType), "traffic_type")
+	}
+	if opts.RateLimit != nil {
+		dict.Add(jsonutils.NewInt(int64(*opts.RateLimit)), "rate_limit")
+	}
+	if len(opts.BucketPrefix) > 0 {
+		dict.Add(jsonutils.NewString(opts.BucketPrefix), "bucket_prefix")
+	}
+	if opts.RetentionDays != nil {
+		dict.Add(jsonutils.NewInt(int64(*opts.RetentionDays)), "retention_days")
+	}
+	return dict, nil
+}
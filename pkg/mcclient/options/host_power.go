@@ -0,0 +1,112 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import "yunion.io/x/jsonutils"
+
+type HostPowerPolicyListOptions struct {
+	BaseListOptions
+
+	Zone       string   `help:"filter by zone"`
+	SchedtagId string   `help:"filter by schedtag"`
+	Mode       []string `help:"filter by mode" choices:"recommend|auto"`
+}
+
+func (opts *HostPowerPolicyListOptions) Params() (jsonutils.JSONObject, error) {
+	return ListStructToParams(opts)
+}
+
+type HostPowerPolicyIdOptions struct {
+	ID string `help:"ID or name of host power policy"`
+}
+
+func (opts *HostPowerPolicyIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *HostPowerPolicyIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+type HostPowerPolicyCreateOptions struct {
+	EnabledStatusCreateOptions
+
+	ZoneId     string `help:"zone of hosts to manage"`
+	SchedtagId string `help:"schedtag of hosts to manage"`
+	Mode       string `help:"recommend power actions or execute them automatically" choices:"recommend|auto"`
+
+	LowUtilization   int `help:"percent of cpu and memory allocation under which a host may be emptied and powered off"`
+	MaxUtilization   int `help:"percent of cpu and memory allocation a destination host may reach, standby hosts are powered on above it"`
+	MinActiveHosts   int `help:"min number of hosts to keep powered on"`
+	MaxPowerOffs     int `help:"max number of hosts to power off in one evaluation"`
+	ReserveVcpuCount int `help:"cpu count of reserved guest spec forecasted on active hosts"`
+	ReserveVmemSize  int `help:"memory size in MB of reserved guest spec forecasted on active hosts"`
+	ReserveCount     int `help:"number of reserved guests active hosts must be able to take"`
+	IntervalMinutes  int `help:"minutes between evaluations"`
+}
+
+func (opts *HostPowerPolicyCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type HostPowerPolicyUpdateOptions struct {
+	BaseUpdateOptions
+
+	Mode             string `help:"recommend power actions or execute them automatically" choices:"recommend|auto"`
+	LowUtilization   *int   `help:"percent of cpu and memory allocation under which a host may be emptied and powered off"`
+	MaxUtilization   *int   `help:"percent of cpu and memory allocation a destination host may reach, standby hosts are powered on above it"`
+	MinActiveHosts   *int   `help:"min number of hosts to keep powered on"`
+	MaxPowerOffs     *int   `help:"max number of hosts to power off in one evaluation"`
+	ReserveVcpuCount *int   `help:"cpu count of reserved guest spec forecasted on active hosts"`
+	ReserveVmemSize  *int   `help:"memory size in MB of reserved guest spec forecasted on active hosts"`
+	ReserveCount     *int   `help:"number of reserved guests active hosts must be able to take"`
+	IntervalMinutes  *int   `help:"minutes between evaluations"`
+}
+
+func (opts *HostPowerPolicyUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params, err := opts.BaseUpdateOptions.Params()
+	if err != nil {
+		return nil, err
+	}
+	dict := params.(*jsonutils.JSONDict)
+	if len(opts.Mode) > 0 {
+		dict.Add(jsonutils.NewString(opts.Mode), "mode")
+	}
+	for key, val := range map[string]*int{
+		"low_utilization":    opts.LowUtilization,
+		"max_utilization":    opts.MaxUtilization,
+		"min_active_hosts":   opts.MinActiveHosts,
+		"max_power_offs":     opts.MaxPowerOffs,
+		"reserve_vcpu_count": opts.ReserveVcpuCount,
+		"reserve_vmem_size":  opts.ReserveVmemSize,
+		"reserve_count":      opts.ReserveCount,
+		"interval_minutes":   opts.IntervalMinutes,
+	} {
+		if val != nil {
+			dict.Add(jsonutils.NewInt(int64(*val)), key)
+		}
+	}
+	return dict, nil
+}
+
+type HostPowerPolicyEvaluateOptions struct {
+	HostPowerPolicyIdOptions
+
+	DryRun bool `help:"only compute the power plan"`
+}
+
+func (opts *HostPowerPolicyEvaluateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(map[string]bool{"dry_run": opts.DryRun}), nil
+}
@@ -77,6 +77,33 @@ func (opts *LoadbalancerCertificateCreateOptions) Params() (*jsonutils.JSONDict,
 	return params, nil
 }
 
+type LoadbalancerCertificateAcmeCreateOptions struct {
+	SharableProjectizedResourceBaseCreateInput
+
+	NAME string
+
+	AcmeDomains         []string `required:"true" help:"domains of the certificate, the first one is used as common name"`
+	AcmeChallengeType   string   `choices:"http-01|dns-01" default:"http-01" help:"acme challenge type"`
+	AcmeLoadbalancer    string   `help:"loadbalancer serving http-01 challenges with http listener on port 80, its lbagents require haproxy 2.2 or later"`
+	AcmeDirectoryUrl    string   `help:"acme directory url, defaults to the one configured in region"`
+	AcmeEmail           string   `help:"contact email of acme account"`
+	AcmeRenewBeforeDays int      `help:"days before expiry to renew the certificate, defaults to 30"`
+}
+
+func (opts *LoadbalancerCertificateAcmeCreateOptions) Params() (*jsonutils.JSONDict, error) {
+	params, err := StructToParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := opts.SharableProjectizedResourceBaseCreateInput.Params()
+	if err != nil {
+		return nil, err
+	}
+	params.Update(sp)
+	params.Set("cert_source", jsonutils.NewString("acme"))
+	return params, nil
+}
+
 type LoadbalancerCertificateGetOptions struct {
 	ID string `json:"-"`
 }
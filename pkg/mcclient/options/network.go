@@ -70,6 +70,12 @@ type NetworkUpdateOptions struct {
 	ExternalId  string `help:"External ID"`
 	AllocPolicy string `help:"Address allocation policy" choices:"none|stepdown|stepup|random"`
 	IsAutoAlloc *bool  `help:"Add network into auto-allocation pool" negative:"no_auto_alloc"`
+
+	Ip6Start string `help:"Start of IPv6 address range"`
+	Ip6End   string `help:"End of IPv6 address range"`
+	Ip6Mask  int64  `help:"Length of IPv6 network mask"`
+	Gateway6 string `help:"IPv6 gateway"`
+	Dns6     string `help:"IPv6 DNS server"`
 }
 
 func (opts *NetworkUpdateOptions) Params() (jsonutils.JSONObject, error) {
@@ -125,6 +131,21 @@ func (opts *NetworkUpdateOptions) Params() (jsonutils.JSONObject, error) {
 	if opts.IsAutoAlloc != nil {
 		params.Add(jsonutils.NewBool(*opts.IsAutoAlloc), "is_auto_alloc")
 	}
+	if len(opts.Ip6Start) > 0 {
+		params.Add(jsonutils.NewString(opts.Ip6Start), "guest_ip6_start")
+	}
+	if len(opts.Ip6End) > 0 {
+		params.Add(jsonutils.NewString(opts.Ip6End), "guest_ip6_end")
+	}
+	if opts.Ip6Mask > 0 {
+		params.Add(jsonutils.NewInt(opts.Ip6Mask), "guest_ip6_mask")
+	}
+	if len(opts.Gateway6) > 0 {
+		params.Add(jsonutils.NewString(opts.Gateway6), "guest_gateway6")
+	}
+	if len(opts.Dns6) > 0 {
+		params.Add(jsonutils.NewString(opts.Dns6), "guest_dns6")
+	}
 	if params.Size() == 0 {
 		return nil, shell.InvalidUpdateError()
 	}
@@ -0,0 +1,142 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import "yunion.io/x/jsonutils"
+
+type RebalancePolicyListOptions struct {
+	BaseListOptions
+
+	Zone       string   `help:"filter by zone"`
+	SchedtagId string   `help:"filter by schedtag"`
+	Mode       []string `help:"filter by mode" choices:"recommend|auto"`
+}
+
+func (opts *RebalancePolicyListOptions) Params() (jsonutils.JSONObject, error) {
+	return ListStructToParams(opts)
+}
+
+type RebalancePolicyIdOptions struct {
+	ID string `help:"ID or name of rebalance policy"`
+}
+
+func (opts *RebalancePolicyIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *RebalancePolicyIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+type RebalancePolicyCreateOptions struct {
+	EnabledStatusCreateOptions
+
+	ZoneId     string `help:"zone of hosts to rebalance"`
+	SchedtagId string `help:"schedtag of hosts to rebalance"`
+	Mode       string `help:"recommend migrations or execute them automatically" choices:"recommend|auto"`
+
+	CpuThreshold    int   `help:"percent of cpu load spread between hosts to trigger rebalance"`
+	MemoryThreshold int   `help:"percent of memory load spread between hosts to trigger rebalance"`
+	MaxUtilization  int   `help:"percent of cpu and memory load a destination host may reach"`
+	MaxMigrations   int   `help:"max number of migrations in one evaluation"`
+	UseMetrics      *bool `help:"compute host load from monitoring metrics instead of allocation" negative:"no_use_metrics"`
+	IntervalMinutes int   `help:"minutes between evaluations"`
+}
+
+func (opts *RebalancePolicyCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type RebalancePolicyUpdateOptions struct {
+	BaseUpdateOptions
+
+	Mode            string `help:"recommend migrations or execute them automatically" choices:"recommend|auto"`
+	CpuThreshold    *int   `help:"percent of cpu load spread between hosts to trigger rebalance"`
+	MemoryThreshold *int   `help:"percent of memory load spread between hosts to trigger rebalance"`
+	MaxUtilization  *int   `help:"percent of cpu and memory load a destination host may reach"`
+	MaxMigrations   *int   `help:"max number of migrations in one evaluation"`
+	UseMetrics      *bool  `help:"compute host load from monitoring metrics instead of allocation" negative:"no_use_metrics"`
+	IntervalMinutes *int   `help:"minutes between evaluations"`
+}
+
+func (opts *RebalancePolicyUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params, err := opts.BaseUpdateOptions.Params()
+	if err != nil {
+		return nil, err
+	}
+	dict := params.(*jsonutils.JSONDict)
+	if len(opts.Mode) > 0 {
+		dict.Add(jsonutils.NewString(opts.Mode), "mode")
+	}
+	for key, val := range map[string]*int{
+		"cpu_threshold":    opts.CpuThreshold,
+		"memory_threshold": opts.MemoryThreshold,
+		"max_utilization":  opts.MaxUtilization,
+		"max_migrations":   opts.MaxMigrations,
+		"interval_minutes": opts.IntervalMinutes,
+	} {
+		if val != nil {
+			dict.Add(jsonutils.NewInt(int64(*val)), key)
+		}
+	}
+	if opts.UseMetrics != nil {
+		dict.Add(jsonutils.NewBool(*opts.UseMetrics), "use_metrics")
+	}
+	return dict, nil
+}
+
+type RebalancePolicyEvaluateOptions struct {
+	RebalancePolicyIdOptions
+
+	DryRun bool `help:"only compute the migration plan"`
+}
+
+func (opts *RebalancePolicyEvaluateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(map[string]bool{"dry_run": opts.DryRun}), nil
+}
+
+type RebalanceRecommendationListOptions struct {
+	BaseListOptions
+
+	RebalancePolicyId string `help:"filter by rebalance policy"`
+	GuestId           string `help:"filter by guest"`
+	HostId            string `help:"filter by source or destination host"`
+}
+
+func (opts *RebalanceRecommendationListOptions) Params() (jsonutils.JSONObject, error) {
+	return ListStructToParams(opts)
+}
+
+type RebalanceRecommendationIdOptions struct {
+	ID string `help:"ID or name of rebalance recommendation"`
+}
+
+func (opts *RebalanceRecommendationIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *RebalanceRecommendationIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+type RebalanceRecommendationRejectOptions struct {
+	RebalanceRecommendationIdOptions
+
+	Reason string `help:"reason of rejection"`
+}
+
+func (opts *RebalanceRecommendationRejectOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(map[string]string{"reason": opts.Reason}), nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import "yunion.io/x/jsonutils"
+
+type SchedHistoryListOptions struct {
+	BaseListOptions
+
+	ServerId   string   `help:"filter by server"`
+	SessionId  []string `help:"filter by schedule session"`
+	HostId     string   `help:"filter by selected host"`
+	Hypervisor []string `help:"filter by hypervisor"`
+	Success    *bool    `help:"filter by whether scheduling succeeded" negative:"no_success"`
+}
+
+func (opts *SchedHistoryListOptions) Params() (jsonutils.JSONObject, error) {
+	return ListStructToParams(opts)
+}
+
+type SchedHistoryIdOptions struct {
+	ID string `help:"ID of sched history"`
+}
+
+func (opts *SchedHistoryIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *SchedHistoryIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
@@ -221,6 +221,7 @@ type ServerConfigs struct {
 	ResourceType                 string `help:"Resource type" choices:"shared|prepaid|dedicated"`
 	Backup                       bool   `help:"Create server with backup server"`
 	AutoSwitchToBackupOnHostDown bool   `help:"Auto switch to backup server on host down"`
+	Preemptible                  bool   `help:"Create preemptible kvm server which can be preempted by other servers"`
 
 	Schedtag       []string `help:"Schedule policy, key = aggregate name, value = require|exclude|prefer|avoid" metavar:"<KEY:VALUE>"`
 	Disk           []string `help:"Disk descriptions" nargs:"+"`
@@ -245,6 +246,7 @@ func (o ServerConfigs) Data() (*computeapi.ServerConfigs, error) {
 		Hypervisor:       o.Hypervisor,
 		ResourceType:     o.ResourceType,
 		Backup:           o.Backup,
+		Preemptible:      o.Preemptible,
 		Count:            o.Count,
 	}
 	for i, d := range o.Disk {
@@ -371,6 +373,7 @@ type ServerCreateOptionalOptions struct {
 	NoAccountInit    *bool    `help:"Not reset account password"`
 	AllowDelete      *bool    `help:"Unlock server to allow deleting" json:"-"`
 	ShutdownBehavior string   `help:"Behavior after VM server shutdown" metavar:"<SHUTDOWN_BEHAVIOR>" choices:"stop|terminate"`
+	PreemptAction    string   `help:"Action taken when preemptible server is preempted" choices:"stop|delete"`
 	AutoStart        bool     `help:"Auto start server after it is created"`
 	Deploy           []string `help:"Specify deploy files in virtual server file system" json:"-"`
 	Group            []string `help:"Group ID or Name of virtual server"`
@@ -469,6 +472,7 @@ func (opts *ServerCreateOptionalOptions) OptionalParams() (*computeapi.ServerCre
 		Vdi:                opts.Vdi,
 		Bios:               opts.Bios,
 		ShutdownBehavior:   opts.ShutdownBehavior,
+		PreemptAction:      opts.PreemptAction,
 		AutoStart:          opts.AutoStart,
 		Duration:           opts.Duration,
 		AutoRenew:          opts.AutoRenew,
@@ -583,6 +587,7 @@ type ServerUpdateOptions struct {
 	Boot             string `help:"Boot device" choices:"disk|cdrom"`
 	Delete           string `help:"Lock server to prevent from deleting" choices:"enable|disable" json:"-"`
 	ShutdownBehavior string `help:"Behavior after VM server shutdown" choices:"stop|terminate"`
+	PreemptAction    string `help:"Action taken when preemptible server is preempted" choices:"stop|delete"`
 }
 
 func (opts *ServerUpdateOptions) Params() (jsonutils.JSONObject, error) {
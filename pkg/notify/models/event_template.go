@@ -479,6 +479,11 @@ func init() {
 			"added to the recycle bin",
 			"加入回收站",
 		},
+		sI18nElme{
+			string(api.ActionPreempt),
+			"preempted",
+			"被抢占",
+		},
 		sI18nElme{
 			string(api.ResultFailed),
 			"failed",
@@ -88,6 +88,8 @@ const (
 	DefaultScalingPolicyExecute    = "scaling policy execute"
 	DefaultSnapshotPolicyExecute   = "snapshot policy execute"
 	DefaultResourceOperationFailed = "resource operation failed"
+	DefaultCertificateRenewFailed  = "certificate renew failed"
+	DefaultServerPreempt           = "server preempt"
 )
 
 func (sm *STopicManager) InitializeData() error {
@@ -101,6 +103,8 @@ func (sm *STopicManager) InitializeData() error {
 		DefaultScalingPolicyExecute,
 		DefaultSnapshotPolicyExecute,
 		DefaultResourceOperationFailed,
+		DefaultCertificateRenewFailed,
+		DefaultServerPreempt,
 	)
 	q := sm.Query()
 	topics := make([]STopic, 0, initSNames.Len())
@@ -217,6 +221,14 @@ func (sm *STopicManager) InitializeData() error {
 				notify.ActionMigrate,
 			)
 			t.Type = notify.TOPIC_TYPE_RESOURCE
+		case DefaultCertificateRenewFailed:
+			t.addResources(notify.TOPIC_RESOURCE_LOADBALANCERCERTIFICATE)
+			t.addAction(notify.ActionRenew)
+			t.Type = notify.TOPIC_TYPE_RESOURCE
+		case DefaultServerPreempt:
+			t.addResources(notify.TOPIC_RESOURCE_SERVER)
+			t.addAction(notify.ActionPreempt)
+			t.Type = notify.TOPIC_TYPE_RESOURCE
 		}
 		err := sm.TableSpec().Insert(ctx, t)
 		if err != nil {
@@ -435,6 +447,8 @@ func init() {
 			notify.ActionMigrate:            12,
 			notify.ActionCreateBackupServer: 13,
 			notify.ActionDelBackupServer:    14,
+			notify.ActionRenew:              15,
+			notify.ActionPreempt:            16,
 		},
 	)
 }
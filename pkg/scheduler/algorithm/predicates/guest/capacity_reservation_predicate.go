@@ -0,0 +1,145 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"fmt"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// CapacityReservationPredicate keeps guests of other projects from taking
+// capacity reserved by capacity reservations.  Free resources of all hosts
+// in scope of a reservation are summed up, guests of other projects may only
+// use what is left after subtracting the capacity not yet consumed by the
+// owner project
+type CapacityReservationPredicate struct {
+	predicates.BasePredicate
+
+	reservations []*core.CapacityReservation
+	// maps reservation id to count of guests allowed in its scope
+	allowed map[string]int64
+}
+
+func (p *CapacityReservationPredicate) Name() string {
+	return "guest_capacity_reservation"
+}
+
+func (p *CapacityReservationPredicate) Clone() core.FitPredicate {
+	return &CapacityReservationPredicate{}
+}
+
+func reservationRequest(d *api.SchedInfo, r *core.CapacityReservation) core.ReservationResource {
+	req := core.ReservationResource{
+		CPU:     int64(d.Ncpu),
+		Memory:  int64(d.Memory),
+		Storage: d.AllDiskBackendSize()[computeapi.STORAGE_LOCAL],
+	}
+	if r.IsolatedDeviceModel != "" {
+		for _, dev := range d.IsolatedDevices {
+			if dev.Model == r.IsolatedDeviceModel {
+				req.IsolatedDevice++
+			}
+		}
+	}
+	return req
+}
+
+func reservationFree(c core.Candidater, r *core.CapacityReservation) core.ReservationResource {
+	getter := c.Getter()
+	freeStorage, _ := getter.GetFreeStorageSizeOfType(computeapi.STORAGE_LOCAL, false)
+	free := core.ReservationResource{
+		CPU:     getter.FreeCPUCount(false),
+		Memory:  getter.FreeMemorySize(false),
+		Storage: freeStorage,
+	}
+	if r.IsolatedDeviceModel != "" {
+		free.IsolatedDevice = int64(len(getter.UnusedIsolatedDevicesByModel(r.IsolatedDeviceModel)))
+	}
+	return free
+}
+
+func (p *CapacityReservationPredicate) PreExecute(u *core.Unit, cs []core.Candidater) (bool, error) {
+	reservations, err := u.GetCapacityReservations()
+	if err != nil {
+		return false, err
+	}
+	d := u.SchedData()
+	p.allowed = make(map[string]int64)
+	for _, r := range reservations {
+		if r.ProjectId == d.Project {
+			continue
+		}
+		scope := []core.Candidater{}
+		for _, c := range cs {
+			if r.HostIds.Has(c.IndexKey()) {
+				scope = append(scope, c)
+			}
+		}
+		if len(scope) == 0 {
+			continue
+		}
+		// prefer_host and prefer_zone narrow candidates of the request,
+		// while the reserved capacity is taken from the whole scope
+		if cached, ok := u.FetchHostCandidates(r.HostIds.List()); ok && len(cached) > 0 {
+			scope = cached
+		}
+		free := core.ReservationResource{}
+		for _, c := range scope {
+			f := reservationFree(c, r)
+			free.CPU += f.CPU
+			free.Memory += f.Memory
+			free.Storage += f.Storage
+			free.IsolatedDevice += f.IsolatedDevice
+		}
+		allowed := r.AllowedCount(free, reservationRequest(d, r))
+		if allowed < 0 {
+			continue
+		}
+		p.reservations = append(p.reservations, r)
+		p.allowed[r.Id] = allowed
+		// allowed is a budget of the whole scope, capacity of each
+		// candidate alone would let a batch take it several times
+		u.AddSelectLimit(fmt.Sprintf("capacity reservation %s", r.Name), r.HostIds, allowed)
+	}
+	return len(p.reservations) > 0, nil
+}
+
+func (p *CapacityReservationPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	h := predicates.NewPredicateHelper(p, u, c)
+
+	capacity := int64(-1)
+	for _, r := range p.reservations {
+		if !r.HostIds.Has(c.IndexKey()) {
+			continue
+		}
+		allowed := p.allowed[r.Id]
+		if allowed <= 0 {
+			h.Exclude(fmt.Sprintf("capacity reserved by capacity reservation %s", r.Name))
+			return h.GetResult()
+		}
+		if capacity < 0 || allowed < capacity {
+			capacity = allowed
+		}
+	}
+	if capacity >= 0 {
+		h.SetCapacity(capacity)
+	}
+
+	return h.GetResult()
+}
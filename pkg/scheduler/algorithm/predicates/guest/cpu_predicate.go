@@ -75,6 +75,12 @@ func (f *CPUPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core.Pr
 
 	freeCPUCount := getter.FreeCPUCount(useRsvd)
 	reqCPUCount := int64(d.Ncpu)
+	if u.IsPreempting() {
+		// cpus of preemptible guests are taken as free
+		u.SetCapacityWithoutPreemption(c.IndexKey(), f.Name(), freeCPUCount/reqCPUCount)
+		preemptibleCPU, _ := core.GetPreemptibleResource(c)
+		freeCPUCount += preemptibleCPU
+	}
 	if freeCPUCount < reqCPUCount {
 		totalCPUCount := getter.TotalCPUCount(useRsvd)
 		h.AppendInsufficientResourceError(reqCPUCount, totalCPUCount, freeCPUCount)
@@ -56,6 +56,12 @@ func (p *MemoryPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core
 	getter := c.Getter()
 	freeMemSize := getter.FreeMemorySize(useRsvd)
 	reqMemSize := int64(d.Memory)
+	if u.IsPreempting() {
+		// memory of preemptible guests is taken as free
+		u.SetCapacityWithoutPreemption(c.IndexKey(), p.Name(), freeMemSize/reqMemSize)
+		_, preemptibleMem := core.GetPreemptibleResource(c)
+		freeMemSize += preemptibleMem
+	}
 	if freeMemSize < reqMemSize {
 		totalMemSize := getter.TotalMemorySize(useRsvd)
 		h.AppendInsufficientResourceError(reqMemSize, totalMemSize, freeMemSize)
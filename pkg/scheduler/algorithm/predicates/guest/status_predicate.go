@@ -28,6 +28,18 @@ const (
 	ExpectedEnableStatus = "enable"
 )
 
+// powerStateGetter is implemented by getters of hosts which may be powered
+// off by host power policies
+type powerStateGetter interface {
+	PowerState() string
+}
+
+// maintenanceGetter is implemented by getters of hosts which may be cordoned
+// by entering maintenance
+type maintenanceGetter interface {
+	IsMaintenance() bool
+}
+
 // StatusPredicate is to filter the current state of host is available,
 // not available host's capacity will be set to 0 and filtered out.
 type StatusPredicate struct {
@@ -62,6 +74,16 @@ func (p *StatusPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core
 		h.Exclude2("enable_status", curEnableStatus, true)
 	}
 
+	if pg, ok := getter.(powerStateGetter); ok {
+		if utils.IsInStringArray(pg.PowerState(), api.HOST_POWER_INACTIVE_STATES) {
+			h.Exclude2("power_state", pg.PowerState(), api.HOST_POWER_STATE_ON)
+		}
+	}
+
+	if mg, ok := getter.(maintenanceGetter); ok && mg.IsMaintenance() {
+		h.Exclude2("is_maintenance", true, false)
+	}
+
 	zone := getter.Zone()
 	if zone.Status != ExpectedEnableStatus {
 		h.Exclude2("zone_status", zone.Status, ExpectedEnableStatus)
@@ -0,0 +1,74 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// TopologySpreadPredicate filters out candidates whose topology domain
+// already holds so many guests of an instance group that placing another
+// one there would exceed max skew of a hard spread constraint of the group
+type TopologySpreadPredicate struct {
+	predicates.BasePredicate
+
+	spreads []*core.TopologySpread
+}
+
+func (p *TopologySpreadPredicate) Name() string {
+	return "guest_topology_spread"
+}
+
+func (p *TopologySpreadPredicate) Clone() core.FitPredicate {
+	return &TopologySpreadPredicate{}
+}
+
+func (p *TopologySpreadPredicate) PreExecute(u *core.Unit, cs []core.Candidater) (bool, error) {
+	if len(u.SchedData().InstanceGroupsDetail) == 0 {
+		return false, nil
+	}
+	spreads, err := u.GetTopologySpreads(cs)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range spreads {
+		if s.Constraint.IsHard() {
+			p.spreads = append(p.spreads, s)
+		}
+	}
+	return len(p.spreads) > 0, nil
+}
+
+func (p *TopologySpreadPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	h := predicates.NewPredicateHelper(p, u, c)
+
+	for _, s := range p.spreads {
+		key := s.Constraint.TopologyKey
+		if _, ok := s.Domain(c.IndexKey()); !ok {
+			h.Exclude(fmt.Sprintf("host has no %s for topology spread of instance group %s", key, s.GroupId))
+			break
+		}
+		if !s.MayFit(c.IndexKey(), u.SchedData().Count) {
+			skew, _ := s.Skew(c.IndexKey())
+			h.Exclude(fmt.Sprintf("%s skew %d of instance group %s exceeds %d", key, skew, s.GroupId, s.Constraint.MaxSkew))
+			break
+		}
+	}
+
+	return h.GetResult()
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/priorities"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// CapacityReservationPriority prefers candidates in scope of capacity
+// reservations of the project, so that its guests consume the reserved
+// capacity
+type CapacityReservationPriority struct {
+	priorities.BasePriority
+
+	reservations []*core.CapacityReservation
+}
+
+func (p *CapacityReservationPriority) Name() string {
+	return "guest_capacity_reservation"
+}
+
+func (p *CapacityReservationPriority) Clone() core.Priority {
+	return &CapacityReservationPriority{}
+}
+
+func (p *CapacityReservationPriority) PreExecute(u *core.Unit, cs []core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	reservations, err := u.GetCapacityReservations()
+	if err != nil {
+		return false, nil, err
+	}
+	for _, r := range reservations {
+		if r.ProjectId == u.SchedData().Project {
+			p.reservations = append(p.reservations, r)
+		}
+	}
+	return len(p.reservations) > 0, nil, nil
+}
+
+func (p *CapacityReservationPriority) Map(u *core.Unit, c core.Candidater) (core.HostPriority, error) {
+	h := priorities.NewPriorityHelper(p, u, c)
+
+	for _, r := range p.reservations {
+		if r.HostIds.Has(c.IndexKey()) {
+			h.SetPreferScore(1)
+			break
+		}
+	}
+
+	return h.GetResult()
+}
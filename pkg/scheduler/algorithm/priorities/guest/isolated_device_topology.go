@@ -0,0 +1,78 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"fmt"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	computemodels "yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/priorities"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// IsolatedDeviceTopologyPriority prefers hosts where the requested isolated
+// devices of a model can be taken from the same peer group, PCIe switch or
+// NUMA node
+type IsolatedDeviceTopologyPriority struct {
+	priorities.BasePriority
+
+	// vendor:model => count of devices requested
+	requests map[string]int
+}
+
+func (p *IsolatedDeviceTopologyPriority) Name() string {
+	return "guest_isolated_device_topology"
+}
+
+func (p *IsolatedDeviceTopologyPriority) Clone() core.Priority {
+	return &IsolatedDeviceTopologyPriority{}
+}
+
+func (p *IsolatedDeviceTopologyPriority) PreExecute(u *core.Unit, cs []core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	requests := make(map[string]int)
+	for _, dev := range u.SchedData().IsolatedDevices {
+		if len(dev.Id) == 0 && len(dev.Model) > 0 {
+			requests[fmt.Sprintf("%s:%s", dev.Vendor, dev.Model)] += 1
+		}
+	}
+	p.requests = make(map[string]int)
+	for vendorModel, count := range requests {
+		if count > 1 {
+			p.requests[vendorModel] = count
+		}
+	}
+	return len(p.requests) > 0, nil, nil
+}
+
+func (p *IsolatedDeviceTopologyPriority) Map(u *core.Unit, c core.Candidater) (core.HostPriority, error) {
+	h := priorities.NewPriorityHelper(p, u, c)
+
+	score := api.ISOLATED_DEVICE_LOCALITY_PEER
+	for vendorModel, count := range p.requests {
+		devs := c.Getter().UnusedIsolatedDevicesByVendorModel(vendorModel)
+		topos := make([]computemodels.IIsolatedDeviceTopology, len(devs))
+		for i := range devs {
+			topos[i] = devs[i]
+		}
+		_, locality := computemodels.SelectLocalIsolatedDevices(topos, count)
+		if locality < score {
+			score = locality
+		}
+	}
+	h.SetPreferScore(score)
+
+	return h.GetResult()
+}
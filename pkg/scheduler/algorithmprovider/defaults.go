@@ -48,6 +48,8 @@ func defaultPredicates() sets.String {
 		factory.RegisterFitPredicate("p-CloudproviderschedtagFilter", predicates.NewCloudproviderSchedtagPredicate()),
 		factory.RegisterFitPredicate("q-CloudregionschedtagFilter", predicates.NewCloudregionSchedtagPredicate()),
 		factory.RegisterFitPredicate("r-ZoneschedtagFilter", predicates.NewZoneSchedtagPredicate()),
+		factory.RegisterFitPredicate("s-GuestTopologySpreadFilter", &predicateguest.TopologySpreadPredicate{}),
+		factory.RegisterFitPredicate("t-GuestCapacityReservationFilter", &predicateguest.CapacityReservationPredicate{}),
 		factory.RegisterFitPredicate("z-QuotaFilter", &predicates.SQuotaPredicate{}),
 	)
 }
@@ -58,5 +60,8 @@ func defaultPriorities() sets.String {
 		factory.RegisterPriority("guest-lowload", &priorityguest.LowLoadPriority{}, 1),
 		factory.RegisterPriority("guest-creating", &priorityguest.CreatingPriority{}, 1),
 		factory.RegisterPriority("guest-capacity", &priorityguest.CapacityPriority{}, 1),
+		factory.RegisterPriority("guest-topology-spread", &priorityguest.TopologySpreadPriority{}, 1),
+		factory.RegisterPriority("guest-isolated-device-topology", &priorityguest.IsolatedDeviceTopologyPriority{}, 1),
+		factory.RegisterPriority("guest-capacity-reservation", &priorityguest.CapacityReservationPriority{}, 1),
 	)
 }
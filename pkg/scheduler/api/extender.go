@@ -0,0 +1,52 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+// ExtenderCandidate is a candidate as seen by extenders
+type ExtenderCandidate struct {
+	Id        string   `json:"id"`
+	Name      string   `json:"name"`
+	ZoneId    string   `json:"zone_id"`
+	HostType  string   `json:"host_type"`
+	Status    string   `json:"status"`
+	Schedtags []string `json:"schedtags"`
+}
+
+// ExtenderArgs is the body posted to extender filter and prioritize verbs
+type ExtenderArgs struct {
+	SchedInfo  *SchedInfo          `json:"sched_info"`
+	Candidates []ExtenderCandidate `json:"candidates"`
+}
+
+// ExtenderFilterResult is the reply of extender filter verb.  Candidates
+// are ids of candidates that fit, FailedCandidates maps ids of the others to
+// reasons
+type ExtenderFilterResult struct {
+	Candidates       []string          `json:"candidates"`
+	FailedCandidates map[string]string `json:"failed_candidates"`
+	Error            string            `json:"error"`
+}
+
+type ExtenderCandidateScore struct {
+	Id    string `json:"id"`
+	Score int    `json:"score"`
+}
+
+// ExtenderPrioritizeResult is the reply of extender prioritize verb.  Scores
+// are in range of [-1, 2] and will be multiplied by weight of the extender
+type ExtenderPrioritizeResult struct {
+	Scores []ExtenderCandidateScore `json:"scores"`
+	Error  string                   `json:"error"`
+}
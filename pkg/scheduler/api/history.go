@@ -90,10 +90,11 @@ type HistoryTask struct {
 	Time      string     `json:"time"`
 	Consuming string     `json:"consuming"`
 	//Result    []SchedResultItem `json:"result"`
-	Result      interface{} `json:"result"`
-	Error       string      `json:"error"`
-	Logs        []string    `json:"logs"`
-	CapacityMap interface{} `json:"capacity_map"`
+	Result      interface{}      `json:"result"`
+	Error       string           `json:"error"`
+	Logs        []string         `json:"logs"`
+	CapacityMap interface{}      `json:"capacity_map"`
+	Extenders   []ExtenderResult `json:"extenders,omitempty"`
 }
 
 type HistoryDetail struct {
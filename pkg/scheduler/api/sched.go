@@ -15,8 +15,9 @@
 package api
 
 import (
-	"net/http" //"yunion.io/x/jsonutils"
+	"net/http"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
@@ -85,6 +86,10 @@ func FetchSchedInfo(req *http.Request) (*SchedInfo, error) {
 		return nil, err
 	}
 
+	return newSchedInfoByJSON(userCred, body)
+}
+
+func newSchedInfoByJSON(userCred mcclient.TokenCredential, body jsonutils.JSONObject) (*SchedInfo, error) {
 	input, err := cmdline.FetchScheduleInputByJSON(body)
 	if err != nil {
 		return nil, err
@@ -292,4 +297,27 @@ type SchedForecastResult struct {
 	AllowCount         int64                    `json:"allow_count"`
 	NotAllowReasons    []string                 `json:"not_allow_reasons"`
 	FilteredCandidates []FilteredCandidate      `json:"filtered_candidates"`
+	Extenders          []ExtenderResult         `json:"extenders,omitempty"`
+	PreemptGuests      []PreemptGuest           `json:"preempt_guests,omitempty"`
+}
+
+// PreemptGuest is a preemptible guest to be stopped or deleted to release
+// resources for guests being scheduled
+type PreemptGuest struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	HostId   string `json:"host_id"`
+	HostName string `json:"host_name"`
+}
+
+// ExtenderResult records what an extender did to candidates during one
+// schedule
+type ExtenderResult struct {
+	Name      string            `json:"name"`
+	Verb      string            `json:"verb"`
+	Ignored   bool              `json:"ignored"`
+	Error     string            `json:"error,omitempty"`
+	Filtered  map[string]string `json:"filtered,omitempty"`
+	Scores    map[string]int    `json:"scores,omitempty"`
+	Consuming string            `json:"consuming"`
 }
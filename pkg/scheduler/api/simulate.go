@@ -0,0 +1,183 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+const (
+	// DefaultSimulateMaxCount limits guests placed by one simulation
+	DefaultSimulateMaxCount = 1000
+)
+
+// SimulateArgs describes a capacity simulation. Hosts of region or zone in
+// candidate cache are changed by RemoveHosts and AddHosts, then workloads
+// are placed on them by weight round after round until nothing fits.
+type SimulateArgs struct {
+	Region      string             `json:"region"`
+	Zone        string             `json:"zone"`
+	RemoveHosts []string           `json:"remove_hosts"`
+	AddHosts    []SimulateAddHost  `json:"add_hosts"`
+	Workloads   []SimulateWorkload `json:"-"`
+	MaxCount    int64              `json:"max_count"`
+
+	UserCred mcclient.TokenCredential `json:"-"`
+}
+
+// SimulateAddHost adds Count empty hosts the same as TemplateHost
+type SimulateAddHost struct {
+	TemplateHost string `json:"template_host"`
+	Count        int    `json:"count"`
+}
+
+// SimulateWorkload is one kind of guest of the workload mix, Weight guests
+// of it are placed in each round
+type SimulateWorkload struct {
+	Name   string     `json:"name"`
+	Weight int        `json:"weight"`
+	Data   *SchedInfo `json:"data"`
+}
+
+type SimulateWorkloadResult struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+	// host name => count of guests placed
+	Hosts map[string]int64 `json:"hosts"`
+	// filter excluding most candidates when the workload stops fitting
+	Bottleneck string `json:"bottleneck"`
+	// filter name => count of candidates excluded by it
+	FilteredCandidates map[string]int64 `json:"filtered_candidates"`
+}
+
+type SimulateHostResult struct {
+	Id        string   `json:"id"`
+	Name      string   `json:"name"`
+	Zone      string   `json:"zone"`
+	Schedtags []string `json:"schedtags"`
+	Simulated bool     `json:"simulated"`
+	Count     int64    `json:"count"`
+
+	FreeCPUCount     int64 `json:"free_cpu_count"`
+	FreeMemSize      int64 `json:"free_mem_size"`
+	FreeStorageSize  int64 `json:"free_storage_size"`
+	TotalCPUCount    int64 `json:"total_cpu_count"`
+	TotalMemSize     int64 `json:"total_mem_size"`
+	TotalStorageSize int64 `json:"total_storage_size"`
+}
+
+type SimulateResult struct {
+	Count        int64                    `json:"count"`
+	Truncated    bool                     `json:"truncated"`
+	Bottleneck   string                   `json:"bottleneck"`
+	RemovedHosts []string                 `json:"removed_hosts"`
+	Workloads    []SimulateWorkloadResult `json:"workloads"`
+	Hosts        []SimulateHostResult     `json:"hosts"`
+}
+
+// FetchSimulateArgs parses simulation request, each of workloads is the same
+// as schedule input along with its name and weight
+func FetchSimulateArgs(req *http.Request) (*SimulateArgs, error) {
+	userCred, err := FetchUserCred(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch user cred")
+	}
+
+	body, err := appsrv.FetchJSON(req)
+	if err != nil {
+		return nil, err
+	}
+
+	args := new(SimulateArgs)
+	if err := body.Unmarshal(args); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal simulate args: %v", err)
+	}
+	args.UserCred = userCred
+	if len(args.Region) > 0 {
+		region, err := models.CloudregionManager.FetchByIdOrName(userCred, args.Region)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch region %s", args.Region)
+		}
+		args.Region = region.GetId()
+	}
+	if len(args.Zone) > 0 {
+		zone, err := models.ZoneManager.FetchByIdOrName(userCred, args.Zone)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch zone %s", args.Zone)
+		}
+		args.Zone = zone.GetId()
+	}
+	if args.MaxCount <= 0 {
+		args.MaxCount = DefaultSimulateMaxCount
+	}
+	for i, h := range args.AddHosts {
+		if len(h.TemplateHost) == 0 || h.Count <= 0 {
+			return nil, httperrors.NewInputParameterError("add_hosts.%d: template_host and positive count required", i)
+		}
+	}
+
+	workloads, err := body.GetArray("workloads")
+	if err != nil || len(workloads) == 0 {
+		return nil, httperrors.NewMissingParameterError("workloads")
+	}
+	for i, obj := range workloads {
+		workload, err := newSimulateWorkload(userCred, i, obj)
+		if err != nil {
+			return nil, err
+		}
+		args.Workloads = append(args.Workloads, *workload)
+	}
+	return args, nil
+}
+
+func newSimulateWorkload(userCred mcclient.TokenCredential, idx int, obj jsonutils.JSONObject) (*SimulateWorkload, error) {
+	data, err := newSchedInfoByJSON(userCred, obj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "workloads.%d", idx)
+	}
+	if data.Hypervisor == SchedTypeBaremetal {
+		return nil, httperrors.NewNotSupportedError("workloads.%d: baremetal is not supported", idx)
+	}
+	if data.Backup {
+		return nil, httperrors.NewNotSupportedError("workloads.%d: backup guest is not supported", idx)
+	}
+	// guests are placed one by one
+	data.Count = 1
+	data.IsSuggestion = true
+	data.ShowSuggestionDetails = true
+	data.SuggestionAll = true
+
+	workload := &SimulateWorkload{
+		Name:   jsonutils.GetAnyString(obj, []string{"name"}),
+		Weight: 1,
+		Data:   data,
+	}
+	if weight, _ := obj.Int("weight"); weight > 0 {
+		workload.Weight = int(weight)
+	}
+	if len(workload.Name) == 0 {
+		workload.Name = fmt.Sprintf("workload-%d", idx)
+	}
+	return workload, nil
+}
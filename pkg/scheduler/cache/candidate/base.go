@@ -56,6 +56,11 @@ type BaseHostDesc struct {
 
 	SharedDomains []string               `json:"shared_domains"`
 	PendingUsage  map[string]interface{} `json:"pending_usage"`
+
+	// usage of guests placed by capacity simulation
+	simulatedUsage *schedmodels.SPendingUsage
+	// host added by capacity simulation
+	simulatedHost bool
 }
 
 type baseHostGetter struct {
@@ -189,6 +194,14 @@ func (b baseHostGetter) HostStatus() string {
 	return b.h.HostStatus
 }
 
+func (b baseHostGetter) PowerState() string {
+	return b.h.PowerState
+}
+
+func (b baseHostGetter) IsMaintenance() bool {
+	return b.h.SHost.IsMaintenance
+}
+
 func (b baseHostGetter) Enabled() bool {
 	return b.h.GetEnabled()
 }
@@ -368,7 +381,11 @@ func (b BaseHostDesc) GetSchedDesc() *jsonutils.JSONDict {
 func (b *BaseHostDesc) GetPendingUsage() *schedmodels.SPendingUsage {
 	usage, err := schedmodels.HostPendingUsageManager.GetPendingUsage(b.GetId())
 	if err != nil {
-		return schedmodels.NewPendingUsageBySchedInfo(b.GetId(), nil)
+		usage = schedmodels.NewPendingUsageBySchedInfo(b.GetId(), nil)
+	}
+	if b.simulatedUsage != nil {
+		usage = usage.Copy()
+		usage.Add(b.simulatedUsage.Copy())
 	}
 	return usage
 }
@@ -472,6 +489,9 @@ func (h *BaseHostDesc) fillIsolatedDevices(b *baseBuilder, host *computemodels.S
 			Model:          devModel.Model,
 			Addr:           devModel.Addr,
 			VendorDeviceID: devModel.VendorDeviceId,
+			NumaNode:       devModel.NumaNode,
+			PcieSwitch:     devModel.PcieSwitch,
+			PeerGroup:      devModel.PeerGroup,
 		}
 		devs[index] = dev
 	}
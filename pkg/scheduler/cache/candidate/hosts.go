@@ -49,6 +49,10 @@ func newHostGetter(h *HostDesc) *hostGetter {
 	}
 }
 
+func (h *hostGetter) PreemptibleGuests() []*core.PreemptibleGuest {
+	return h.h.PreemptibleGuests
+}
+
 func (h *hostGetter) CreatingGuestCount() int {
 	return int(h.h.CreatingGuestCount)
 }
@@ -137,6 +141,9 @@ type HostDesc struct {
 	IsMaintenance             bool              `json:"is_maintenance"`
 	GuestReservedResource     *ReservedResource `json:"guest_reserved_resource"`
 	GuestReservedResourceUsed *ReservedResource `json:"guest_reserved_used"`
+
+	// running preemptible guests not yet preempted
+	PreemptibleGuests []*core.PreemptibleGuest `json:"preemptible_guests"`
 }
 
 type ReservedResource struct {
@@ -852,6 +859,7 @@ func (b *HostBuilder) fillGuestsResourceInfo(desc *HostDesc, host *computemodels
 		creatingMemSize     int64
 		creatingCPUCount    int64
 		creatingGuestCount  int64
+		preemptibleGuests   = []*core.PreemptibleGuest{}
 	)
 	guestsOnHost, ok := b.hostGuests[host.Id]
 	if !ok {
@@ -868,6 +876,15 @@ func (b *HostBuilder) fillGuestsResourceInfo(desc *HostDesc, host *computemodels
 			runningCount++
 			memSize += int64(guest.VmemSize)
 			cpuCount += int64(guest.VcpuCount)
+			if guest.Preemptible && guest.HostId == host.Id && guest.PreemptAt.IsZero() {
+				preemptibleGuests = append(preemptibleGuests, &core.PreemptibleGuest{
+					Id:        guest.Id,
+					Name:      guest.Name,
+					VcpuCount: int64(guest.VcpuCount),
+					VmemSize:  int64(guest.VmemSize),
+					CreatedAt: guest.CreatedAt,
+				})
+			}
 		} else if IsGuestCreating(guest) {
 			creatingGuestCount++
 			creatingMemSize += int64(guest.VmemSize)
@@ -875,6 +892,10 @@ func (b *HostBuilder) fillGuestsResourceInfo(desc *HostDesc, host *computemodels
 		} else if IsGuestPendingDelete(guest) {
 			memFakeDeletedSize += int64(guest.VmemSize)
 			cpuFakeDeletedCount += int64(guest.VcpuCount)
+		} else if guest.Preemptible {
+			// stopped preemptible guests release their resources
+			guestCount++
+			continue
 		}
 		guestCount++
 		cpuReqCount += int64(guest.VcpuCount)
@@ -900,6 +921,7 @@ func (b *HostBuilder) fillGuestsResourceInfo(desc *HostDesc, host *computemodels
 	desc.RequiredCPUCount = cpuReqCount
 	desc.CreatingCPUCount = creatingCPUCount
 	desc.FakeDeletedCPUCount = cpuFakeDeletedCount
+	desc.PreemptibleGuests = preemptibleGuests
 
 	desc.TotalMemSize = int64(float32(desc.MemSize) * desc.MemCmtbound)
 	desc.TotalCPUCount = int64(float32(desc.CpuCount) * desc.CPUCmtbound)
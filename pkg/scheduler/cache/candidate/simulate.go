@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package candidate
+
+import (
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	schedmodels "yunion.io/x/onecloud/pkg/scheduler/models"
+)
+
+// SimulateCopy returns a copy of host for capacity simulation. Guests placed
+// on the copy by AddSimulatedUsage never change the cached host
+func (h *HostDesc) SimulateCopy() *HostDesc {
+	base := *h.BaseHostDesc
+	base.simulatedUsage = schedmodels.NewPendingUsageBySchedInfo(h.GetId(), nil)
+	desc := *h
+	desc.BaseHostDesc = &base
+	// guests are never preempted in simulation
+	desc.PreemptibleGuests = nil
+	return &desc
+}
+
+// SimulateNewHost returns an empty host with the same hardware, storages,
+// networks and schedtags as h
+func (h *HostDesc) SimulateNewHost(id, name string) *HostDesc {
+	desc := h.SimulateCopy()
+	host := *desc.SHost
+	host.Id = id
+	host.Name = name
+	desc.SHost = &host
+	desc.simulatedUsage.HostId = id
+	desc.simulatedHost = true
+
+	desc.GuestCount = 0
+	desc.CreatingGuestCount = 0
+	desc.RunningGuestCount = 0
+	desc.RunningCPUCount = 0
+	desc.CreatingCPUCount = 0
+	desc.RequiredCPUCount = 0
+	desc.FakeDeletedCPUCount = 0
+	desc.RunningMemSize = 0
+	desc.CreatingMemSize = 0
+	desc.RequiredMemSize = 0
+	desc.FakeDeletedMemSize = 0
+	desc.GuestReservedResourceUsed = NewReservedResource(0, 0, 0)
+	desc.FreeCPUCount = desc.TotalCPUCount - desc.GetReservedCPUCount()
+	desc.FreeMemSize = desc.TotalMemSize - desc.GetReservedMemSize()
+	desc.Tenants = make(map[string]int64)
+	desc.PendingUsage = nil
+
+	groups := make(map[string]*api.CandidateGroup, len(h.InstanceGroups))
+	for id, g := range h.InstanceGroups {
+		groups[id] = &api.CandidateGroup{SGroup: g.SGroup}
+	}
+	desc.InstanceGroups = groups
+
+	// local storages are shared with the template host, give back the
+	// space used by its disks
+	for _, s := range h.Storages {
+		if !s.IsLocal() {
+			continue
+		}
+		used := int64(float32(s.GetCapacity())*s.GetOvercommitBound()) - s.GetFreeCapacity()
+		usage := desc.simulatedUsage.DiskUsage
+		usage.Set(s.StorageType, usage.Get(s.StorageType)-int(used))
+	}
+	return desc
+}
+
+// IsSimulated tells whether host is added by capacity simulation
+func (h *HostDesc) IsSimulated() bool {
+	return h.simulatedHost
+}
+
+// AddSimulatedUsage places resources of a simulated guest on host copied by
+// SimulateCopy or SimulateNewHost
+func (h *HostDesc) AddSimulatedUsage(usage *schedmodels.SPendingUsage) {
+	if h.simulatedUsage == nil {
+		h.simulatedUsage = schedmodels.NewPendingUsageBySchedInfo(h.GetId(), nil)
+	}
+	h.simulatedUsage.Add(usage.Copy())
+}
@@ -0,0 +1,167 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/sets"
+
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	o "yunion.io/x/onecloud/pkg/scheduler/options"
+)
+
+// ReservationResource is an amount of resources reserved, requested or free
+type ReservationResource struct {
+	CPU            int64
+	Memory         int64
+	Storage        int64
+	IsolatedDevice int64
+}
+
+// CapacityReservation is a reservation taking effect with the capacity not
+// yet consumed by servers of its owner project
+type CapacityReservation struct {
+	Id                  string
+	Name                string
+	ProjectId           string
+	IsolatedDeviceModel string
+
+	HostIds sets.String
+	// capacity reserved but not consumed yet
+	Remaining ReservationResource
+}
+
+func remainingOf(reserved, used int) int64 {
+	if used >= reserved {
+		return 0
+	}
+	return int64(reserved - used)
+}
+
+func newCapacityReservation(r *models.SCapacityReservation) (*CapacityReservation, error) {
+	hostIds, err := r.GetHostIds()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetHostIds of %s", r.Name)
+	}
+	usage, err := r.GetUsage()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetUsage of %s", r.Name)
+	}
+	return &CapacityReservation{
+		Id:                  r.Id,
+		Name:                r.Name,
+		ProjectId:           r.ProjectId,
+		IsolatedDeviceModel: r.IsolatedDeviceModel,
+		HostIds:             sets.NewString(hostIds...),
+		Remaining: ReservationResource{
+			CPU:            remainingOf(r.CpuCount, usage.UsedCpuCount),
+			Memory:         remainingOf(r.MemorySize, usage.UsedMemorySize),
+			Storage:        remainingOf(r.StorageSize, usage.UsedStorageSize),
+			IsolatedDevice: remainingOf(r.IsolatedDeviceCount, usage.UsedIsolatedDeviceCount),
+		},
+	}, nil
+}
+
+func fetchCapacityReservations() ([]*CapacityReservation, error) {
+	ret := []*CapacityReservation{}
+	if !o.GetOptions().EnableCapacityReservation {
+		return ret, nil
+	}
+	reservations, err := models.CapacityReservationManager.GetActiveReservations()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range reservations {
+		if !reservations[i].IsActive(now) {
+			continue
+		}
+		r, err := newCapacityReservation(&reservations[i])
+		if err != nil {
+			return nil, err
+		}
+		if r.IsConsumed() {
+			continue
+		}
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
+
+// IsConsumed tells whether all reserved capacity is used by the owner project
+func (r *CapacityReservation) IsConsumed() bool {
+	return r.Remaining == ReservationResource{}
+}
+
+// AllowedCount returns how many guests requesting req can be placed in scope
+// of the reservation, having free resources in scope, without taking the
+// remaining reserved capacity, -1 if the reservation does not limit req
+func (r *CapacityReservation) AllowedCount(free, req ReservationResource) int64 {
+	allowed := int64(-1)
+	for _, dim := range [][3]int64{
+		{free.CPU, req.CPU, r.Remaining.CPU},
+		{free.Memory, req.Memory, r.Remaining.Memory},
+		{free.Storage, req.Storage, r.Remaining.Storage},
+		{free.IsolatedDevice, req.IsolatedDevice, r.Remaining.IsolatedDevice},
+	} {
+		f, q, rem := dim[0], dim[1], dim[2]
+		if q <= 0 || rem <= 0 {
+			continue
+		}
+		cnt := int64(0)
+		if f-rem >= q {
+			cnt = (f - rem) / q
+		}
+		if allowed < 0 || cnt < allowed {
+			allowed = cnt
+		}
+	}
+	return allowed
+}
+
+// GetCapacityReservations returns reservations taking effect now
+func (u *Unit) GetCapacityReservations() ([]*CapacityReservation, error) {
+	u.capacityReservationLock.Lock()
+	defer u.capacityReservationLock.Unlock()
+
+	if u.capacityReservations != nil {
+		return u.capacityReservations, nil
+	}
+	reservations, err := fetchCapacityReservations()
+	if err != nil {
+		return nil, err
+	}
+	u.capacityReservations = reservations
+	return reservations, nil
+}
+
+// ICandidateFetcher fetches cached candidates besides those of a request, it
+// is implemented by the scheduler manager
+type ICandidateFetcher interface {
+	FetchCandidates(resType string, ids []string) []Candidater
+}
+
+// FetchHostCandidates returns cached host candidates of ids, ok is false when
+// the unit is not bound to a scheduler manager
+func (u *Unit) FetchHostCandidates(ids []string) ([]Candidater, bool) {
+	fetcher, ok := u.SchedulerManager.(ICandidateFetcher)
+	if !ok {
+		return nil, false
+	}
+	return fetcher.FetchCandidates(api.HostTypeHost, ids), true
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+)
+
+func TestCapacityReservationAllowedCount(t *testing.T) {
+	r := &CapacityReservation{
+		Remaining: ReservationResource{
+			CPU:    8,
+			Memory: 16384,
+		},
+	}
+	if r.IsConsumed() {
+		t.Errorf("reservation with remaining capacity should not be consumed")
+	}
+
+	cases := []struct {
+		name string
+		free ReservationResource
+		req  ReservationResource
+		want int64
+	}{
+		{
+			name: "not limiting storage only request",
+			free: ReservationResource{CPU: 8, Memory: 16384, Storage: 102400},
+			req:  ReservationResource{Storage: 10240},
+			want: -1,
+		},
+		{
+			name: "headroom beyond reserved capacity",
+			free: ReservationResource{CPU: 24, Memory: 65536},
+			req:  ReservationResource{CPU: 4, Memory: 8192},
+			want: 4,
+		},
+		{
+			name: "no headroom",
+			free: ReservationResource{CPU: 10, Memory: 65536},
+			req:  ReservationResource{CPU: 4, Memory: 1024},
+			want: 0,
+		},
+		{
+			name: "free capacity below reserved",
+			free: ReservationResource{CPU: 4, Memory: 8192},
+			req:  ReservationResource{CPU: 1, Memory: 1024},
+			want: 0,
+		},
+	}
+	for _, c := range cases {
+		if got := r.AllowedCount(c.free, c.req); got != c.want {
+			t.Errorf("%s: want %d, got %d", c.name, c.want, got)
+		}
+	}
+
+	consumed := &CapacityReservation{}
+	if !consumed.IsConsumed() {
+		t.Errorf("reservation without remaining capacity should be consumed")
+	}
+}
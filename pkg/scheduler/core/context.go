@@ -22,6 +22,7 @@ import (
 
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/util/sets"
 
 	"yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/compute/models"
@@ -407,6 +408,26 @@ type Unit struct {
 	SelectPriorityMap        map[string]SSelectPriority
 	SelectPriorityUpdaterMap map[string]SSelectPriorityUpdater
 	SelectPriorityLock       sync.Mutex
+
+	ExtenderResults    []api.ExtenderResult
+	extenderResultLock sync.Mutex
+
+	topologySpreads    []*TopologySpread
+	topologySpreadLock sync.Mutex
+
+	capacityReservations    []*CapacityReservation
+	capacityReservationLock sync.Mutex
+
+	// limits of guests selected on groups of candidates
+	selectLimits    []*selectLimit
+	selectLimitLock sync.Mutex
+
+	// preempting is set when preemptible guests on candidates are taken
+	// as free resources
+	preempting bool
+	// capacities of candidates not counting preemptible guests
+	capacityWithoutPreemption     map[string]map[string]int64
+	capacityWithoutPreemptionLock sync.Mutex
 }
 
 func NewScheduleUnit(info *api.SchedInfo, schedManager interface{}) *Unit {
@@ -477,6 +498,47 @@ func (u *Unit) AppendFailedCandidates(fcs []FailedCandidate) {
 	}
 }
 
+func (u *Unit) AppendExtenderResult(r api.ExtenderResult) {
+	u.extenderResultLock.Lock()
+	defer u.extenderResultLock.Unlock()
+
+	u.ExtenderResults = append(u.ExtenderResults, r)
+}
+
+func (u *Unit) GetExtenderResults() []api.ExtenderResult {
+	u.extenderResultLock.Lock()
+	defer u.extenderResultLock.Unlock()
+
+	if len(u.ExtenderResults) == 0 {
+		return nil
+	}
+	ret := make([]api.ExtenderResult, len(u.ExtenderResults))
+	copy(ret, u.ExtenderResults)
+	return ret
+}
+
+// GetTopologySpreads returns topology spreads of instance groups the guests
+// join.  They are computed among candidates on first call and shared by
+// predicates, priorities and host selection afterwards
+func (u *Unit) GetTopologySpreads(cs []Candidater) ([]*TopologySpread, error) {
+	u.topologySpreadLock.Lock()
+	defer u.topologySpreadLock.Unlock()
+
+	if u.topologySpreads != nil {
+		return u.topologySpreads, nil
+	}
+	candidateIds := make([]string, len(cs))
+	for i := range cs {
+		candidateIds[i] = cs[i].IndexKey()
+	}
+	spreads, err := fetchTopologySpreads(u.SchedInfo.InstanceGroupsDetail, candidateIds, u.SchedInfo.HostId)
+	if err != nil {
+		return nil, err
+	}
+	u.topologySpreads = spreads
+	return spreads, nil
+}
+
 func (u *Unit) AppendSelectPlugin(p SelectPlugin) {
 	u.selectPlugins = append(u.selectPlugins, p)
 }
@@ -485,6 +547,29 @@ func (u *Unit) AllSelectPlugins() []SelectPlugin {
 	return u.selectPlugins
 }
 
+// AddSelectLimit caps the total count of guests selected on candidates of
+// hostIds, for limits applying to a group of candidates rather than to each
+func (u *Unit) AddSelectLimit(name string, hostIds sets.String, count int64) {
+	u.selectLimitLock.Lock()
+	defer u.selectLimitLock.Unlock()
+
+	u.selectLimits = append(u.selectLimits, &selectLimit{
+		name:    name,
+		hostIds: hostIds,
+		count:   count,
+	})
+}
+
+func (u *Unit) newSelectLimiter() *selectLimiter {
+	u.selectLimitLock.Lock()
+	defer u.selectLimitLock.Unlock()
+
+	return &selectLimiter{
+		limits: u.selectLimits,
+		used:   make([]int64, len(u.selectLimits)),
+	}
+}
+
 func (u *Unit) GetCapacity(id string) int64 {
 	var (
 		capacityObj *Capacity
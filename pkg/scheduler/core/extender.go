@@ -0,0 +1,186 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core/score"
+)
+
+const (
+	ExtenderVerbFilter     = "filter"
+	ExtenderVerbPrioritize = "prioritize"
+)
+
+// Extender is an external process filtering and scoring candidates along
+// with builtin predicates and priorities
+type Extender interface {
+	Name() string
+	// IsIgnorable tells whether errors of the extender can be ignored, in
+	// which case scheduling goes on as if the extender does not exist
+	IsIgnorable() bool
+	IsFilter() bool
+	IsPrioritizer() bool
+	Weight() int
+
+	// Filter returns candidates that fit and reasons of the filtered ones
+	// keyed by candidate id
+	Filter(unit *Unit, candidates []Candidater) ([]Candidater, map[string]string, error)
+	// Prioritize returns scores keyed by candidate id, each one is in
+	// range of [score.MinScore, score.MaxScore]
+	Prioritize(unit *Unit, candidates []Candidater) (map[string]score.TScore, error)
+}
+
+// ExtenderProvider is implemented by schedulers consulting extenders
+type ExtenderProvider interface {
+	Extenders() []Extender
+}
+
+type extenderFailure struct {
+	reason string
+}
+
+func (f extenderFailure) GetReason() string {
+	return f.reason
+}
+
+func (f extenderFailure) GetType() string {
+	return "extender"
+}
+
+func extenderStage(e Extender) string {
+	return fmt.Sprintf("extender:%s", e.Name())
+}
+
+func findCandidatesThatFitExtenders(unit *Unit, candidates []Candidater, extenders []Extender) ([]Candidater, error) {
+	for _, e := range extenders {
+		if !e.IsFilter() || len(candidates) == 0 {
+			continue
+		}
+		startTime := time.Now()
+		result := api.ExtenderResult{
+			Name: e.Name(),
+			Verb: ExtenderVerbFilter,
+		}
+		filtered, failed, err := e.Filter(unit, candidates)
+		result.Consuming = fmt.Sprintf("%s", time.Since(startTime))
+		if err != nil {
+			result.Error = err.Error()
+			if !e.IsIgnorable() {
+				unit.AppendExtenderResult(result)
+				return nil, errors.Wrapf(err, "extender %s filter", e.Name())
+			}
+			log.Warningf("ignorable extender %s filter: %v", e.Name(), err)
+			result.Ignored = true
+			unit.AppendExtenderResult(result)
+			continue
+		}
+
+		stage := extenderStage(e)
+		fits := make(map[string]bool, len(filtered))
+		for _, c := range filtered {
+			fits[c.IndexKey()] = true
+		}
+		var (
+			fcs  []FailedCandidate
+			logs []SchedLog
+		)
+		result.Filtered = make(map[string]string)
+		for _, c := range candidates {
+			id := c.IndexKey()
+			if fits[id] {
+				continue
+			}
+			reason, ok := failed[id]
+			if !ok || reason == "" {
+				reason = fmt.Sprintf("filtered by extender %s", e.Name())
+			}
+			result.Filtered[id] = reason
+			unit.SetCapacity(id, stage, NewNormalCounter(0))
+			fcs = append(fcs, FailedCandidate{
+				Stage:     stage,
+				Candidate: c,
+				Reasons:   []PredicateFailureReason{extenderFailure{reason: reason}},
+			})
+			logs = append(logs, NewSchedLog(
+				fmt.Sprintf("%v:%s", c.Getter().Name(), id),
+				stage,
+				LogMessages{&LogMessage{Type: "extender", Info: reason}},
+				true,
+			))
+		}
+		unit.AppendFailedCandidates(fcs)
+		unit.LogManager.Appends(logs)
+		unit.AppendExtenderResult(result)
+		candidates = filtered
+	}
+	return candidates, nil
+}
+
+func prioritizeCandidatesByExtenders(unit *Unit, candidates []Candidater, extenders []Extender) error {
+	for _, e := range extenders {
+		if !e.IsPrioritizer() || len(candidates) == 0 {
+			continue
+		}
+		startTime := time.Now()
+		result := api.ExtenderResult{
+			Name: e.Name(),
+			Verb: ExtenderVerbPrioritize,
+		}
+		scores, err := e.Prioritize(unit, candidates)
+		result.Consuming = fmt.Sprintf("%s", time.Since(startTime))
+		if err != nil {
+			result.Error = err.Error()
+			if !e.IsIgnorable() {
+				unit.AppendExtenderResult(result)
+				return errors.Wrapf(err, "extender %s prioritize", e.Name())
+			}
+			log.Warningf("ignorable extender %s prioritize: %v", e.Name(), err)
+			result.Ignored = true
+			unit.AppendExtenderResult(result)
+			continue
+		}
+
+		stage := extenderStage(e)
+		weight := e.Weight()
+		if weight <= 0 {
+			weight = 1
+		}
+		result.Scores = make(map[string]int)
+		for _, c := range candidates {
+			id := c.IndexKey()
+			s, ok := scores[id]
+			if !ok {
+				continue
+			}
+			if s < score.MinScore {
+				s = score.MinScore
+			} else if s > score.MaxScore {
+				s = score.MaxScore
+			}
+			s = s * score.TScore(weight)
+			result.Scores[id] = int(s)
+			unit.SetScore(id, score.NewScore(s, stage))
+		}
+		unit.AppendExtenderResult(result)
+	}
+	return nil
+}
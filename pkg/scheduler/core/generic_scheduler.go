@@ -82,6 +82,7 @@ type GenericScheduler struct {
 	Scheduler
 	predicates map[string]FitPredicate
 	priorities []PriorityConfig
+	extenders  []Extender
 }
 
 func NewGenericScheduler(s Scheduler) (*GenericScheduler, error) {
@@ -97,6 +98,9 @@ func NewGenericScheduler(s Scheduler) (*GenericScheduler, error) {
 	g.Scheduler = s
 	g.predicates = predicates
 	g.priorities = priorities
+	if ep, ok := s.(ExtenderProvider); ok {
+		g.extenders = ep.Extenders()
+	}
 	return g, nil
 }
 
@@ -108,7 +112,6 @@ func (g *GenericScheduler) Schedule(unit *Unit, candidates []Candidater, helper
 
 	// get schedule context and information
 	schedInfo := unit.SchedInfo
-	isSuggestion := schedInfo.IsSuggestion
 
 	// new trace follow all steps
 	trace := utiltrace.New(fmt.Sprintf("SessionID: %s, schedule info: %s",
@@ -127,6 +130,33 @@ func (g *GenericScheduler) Schedule(unit *Unit, candidates []Candidater, helper
 	if err != nil {
 		return nil, err
 	}
+	selectedCandidates, err := g.selectCandidates(unit, candidates, trace)
+	if unit.CanPreempt() && (err != nil || countSelected(selectedCandidates) < int64(unit.SchedData().Count)) {
+		// not enough resource, try again taking preemptible guests as free resources
+		trace.Step("Computing with preemption")
+		pUnit, pSelected, pErr := g.selectCandidatesWithPreemption(unit, candidates, trace)
+		if pErr != nil {
+			log.Warningf("Schedule with preemption: %v", pErr)
+		} else if countSelected(pSelected) > countSelected(selectedCandidates) {
+			unit, selectedCandidates, err = pUnit, pSelected, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resultItems, err := generateScheduleResult(unit, selectedCandidates, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	itemList := &SchedResultItemList{Unit: unit, Data: resultItems}
+	return helper.ResultHelp(itemList, unit.SchedInfo), nil
+}
+
+// selectCandidates runs predicates and priorities on candidates and selects
+// hosts for unit
+func (g *GenericScheduler) selectCandidates(unit *Unit, candidates []Candidater, trace *utiltrace.Trace) ([]*SelectedCandidate, error) {
 	trace.Step("Computing predicates")
 
 	// load all predicates and find the candidate can statisfy schedule condition
@@ -135,8 +165,16 @@ func (g *GenericScheduler) Schedule(unit *Unit, candidates []Candidater, helper
 		return nil, err
 	}
 
+	if len(g.extenders) > 0 {
+		trace.Step("Computing extender filters")
+		filteredCandidates, err = findCandidatesThatFitExtenders(unit, filteredCandidates, g.extenders)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// if there is no candidate and not from scheduler/test api will return
-	if len(filteredCandidates) == 0 && !isSuggestion {
+	if len(filteredCandidates) == 0 && !unit.SchedInfo.IsSuggestion {
 		return nil, &FitError{
 			Unit:               unit,
 			FailedCandidateMap: unit.FailedCandidateMap,
@@ -145,6 +183,14 @@ func (g *GenericScheduler) Schedule(unit *Unit, candidates []Candidater, helper
 
 	var selectedCandidates []*SelectedCandidate
 	if len(filteredCandidates) > 0 {
+		if len(g.extenders) > 0 {
+			trace.Step("Computing extender priorities")
+			// extender scores are summarized along with builtin priorities
+			if err := prioritizeCandidatesByExtenders(unit, filteredCandidates, g.extenders); err != nil {
+				return nil, err
+			}
+		}
+
 		trace.Step("Prioritizing")
 		// load all priorities and calculate the candidate's score
 		priorityList, err := PrioritizeCandidates(unit, filteredCandidates, g.priorities)
@@ -161,14 +207,22 @@ func (g *GenericScheduler) Schedule(unit *Unit, candidates []Candidater, helper
 	} else {
 		selectedCandidates = []*SelectedCandidate{}
 	}
+	return selectedCandidates, nil
+}
 
-	resultItems, err := generateScheduleResult(unit, selectedCandidates, candidates)
+// selectCandidatesWithPreemption schedules again with a new unit taking
+// preemptible guests on candidates as free resources
+func (g *GenericScheduler) selectCandidatesWithPreemption(unit *Unit, candidates []Candidater, trace *utiltrace.Trace) (*Unit, []*SelectedCandidate, error) {
+	pg, err := NewGenericScheduler(g.Scheduler)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	itemList := &SchedResultItemList{Unit: unit, Data: resultItems}
-	return helper.ResultHelp(itemList, unit.SchedInfo), nil
+	pUnit := newPreemptingUnit(unit)
+	selectedCandidates, err := pg.selectCandidates(pUnit, candidates, trace)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pUnit, selectedCandidates, nil
 }
 
 func newSchedResultByCtx(u *Unit, count int64, c Candidater) *SchedResultItem {
@@ -190,6 +244,9 @@ func newSchedResultByCtx(u *Unit, count int64, c Candidater) *SchedResultItem {
 		r.CapacityDetails = GetCapacities(u, id)
 		r.ScoreDetails = u.GetScoreDetails(id)
 	}
+	if u.IsPreempting() {
+		r.preemption = newHostPreemption(u, c)
+	}
 	return r
 }
 
@@ -332,9 +389,15 @@ func SelectHosts(unit *Unit, priorityList HostPriorityList) ([]*SelectedCandidat
 	selectedCandidates := []*SelectedCandidate{}
 
 	plugins := unit.AllSelectPlugins()
+	limiter := unit.newSelectLimiter()
 
 	sort.Sort(sort.Reverse(priorityList))
 
+	if unit.IsPreempting() {
+		// fill candidates without preempting any guest first
+		count = selectHostsWithoutPreemption(unit, limiter, priorityList, selectedMap, count)
+	}
+
 completed:
 	for len(priorityList) > 0 {
 		log.V(10).Debugf("PriorityList: %#v", priorityList)
@@ -344,6 +407,9 @@ completed:
 				break completed
 			}
 			hostID := it.Host
+			if !limiter.Allows(hostID) {
+				continue
+			}
 			var (
 				selectedItem *SelectedCandidate
 				ok           bool
@@ -354,9 +420,13 @@ completed:
 					Candidate: it.Candidate,
 				}
 				selectedMap[hostID] = selectedItem
+			} else if unit.GetCapacity(hostID) <= selectedItem.Count {
+				// already filled up by hosts selected without preemption
+				continue
 			}
 			selectedItem.Count++
 			count--
+			limiter.Take(hostID)
 			// if capacity of the host large than selected count, this host can be added to priorityList.
 			if unit.GetCapacity(hostID) > selectedItem.Count {
 				priorityList0 = append(priorityList0, it)
@@ -383,6 +453,43 @@ completed:
 	return selectedCandidates, nil
 }
 
+// selectHostsWithoutPreemption selects hosts by capacity not counting
+// preemptible guests and returns count of guests left to select
+func selectHostsWithoutPreemption(unit *Unit, limiter *selectLimiter, priorityList HostPriorityList, selectedMap map[string]*SelectedCandidate, count int) int {
+	for len(priorityList) > 0 && count > 0 {
+		priorityList0 := HostPriorityList{}
+		for _, it := range priorityList {
+			if count <= 0 {
+				break
+			}
+			hostID := it.Host
+			if !limiter.Allows(hostID) {
+				continue
+			}
+			capacity := unit.getCapacityWithoutPreemption(hostID)
+			selectedItem, ok := selectedMap[hostID]
+			if ok && capacity <= selectedItem.Count || !ok && capacity <= 0 {
+				continue
+			}
+			if !ok {
+				selectedItem = &SelectedCandidate{
+					Count:     0,
+					Candidate: it.Candidate,
+				}
+				selectedMap[hostID] = selectedItem
+			}
+			selectedItem.Count++
+			count--
+			limiter.Take(hostID)
+			if capacity > selectedItem.Count {
+				priorityList0 = append(priorityList0, it)
+			}
+		}
+		priorityList = priorityList0
+	}
+	return count
+}
+
 func findCandidatesThatFit(unit *Unit, candidates []Candidater, predicates map[string]FitPredicate) ([]Candidater, error) {
 	var filtered []Candidater
 
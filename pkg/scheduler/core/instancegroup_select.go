@@ -34,6 +34,19 @@ func transToInstanceGroupSchedResult(result *SchedResultItemList, schedInfo *api
 		item.Count = 0
 	}
 	guestInfos, backGuestInfos, groups := generateGuestInfo(schedInfo)
+	if result.Unit != nil {
+		candidates := make([]Candidater, len(result.Data))
+		for i := range result.Data {
+			candidates[i] = result.Data[i].Candidater
+		}
+		spreads, err := result.Unit.GetTopologySpreads(candidates)
+		if err != nil {
+			log.Errorf("GetTopologySpreads: %v", err)
+		}
+		for i := range guestInfos {
+			guestInfos[i].topologySpreads = spreads
+		}
+	}
 	hosts := buildHosts(result, groups)
 	if len(backGuestInfos) > 0 {
 		return getBackupSchedResult(hosts, guestInfos, backGuestInfos, schedInfo.SessionId)
@@ -45,6 +58,22 @@ type sGuestInfo struct {
 	schedInfo            *api.SchedInfo
 	instanceGroupsDetail map[string]*models.SGroup
 	preferHost           string
+	// topology spreads of instance groups the guest joins
+	topologySpreads []*TopologySpread
+}
+
+// topologySkew returns sum of skews of the host in topology spreads of the
+// guest.  Hosts out of any domain are treated as exceeding max skew
+func (info *sGuestInfo) topologySkew(hostId string) int64 {
+	var sum int64
+	for _, s := range info.topologySpreads {
+		skew, ok := s.Skew(hostId)
+		if !ok {
+			skew = s.Constraint.MaxSkew + 1
+		}
+		sum += int64(skew)
+	}
+	return sum
 }
 
 type sSchedResultItem struct {
@@ -96,21 +125,22 @@ func buildHosts(result *SchedResultItemList, groups map[string]*models.SGroup) [
 // sortHost sorts the host for guest that is the backup one of the high-availability guest
 // if isBackup is true and the master one if isBackup is false.
 func sortHosts(hosts []*sSchedResultItem, guestInfo *sGuestInfo, isBackup *bool) {
-	sortIndexi, sortIndexj := make([]int64, 5), make([]int64, 5)
+	sortIndexi, sortIndexj := make([]int64, 6), make([]int64, 6)
 	sort.Slice(hosts, func(i, j int) bool {
+		sortIndexi[0], sortIndexj[0] = guestInfo.topologySkew(hosts[i].ID), guestInfo.topologySkew(hosts[j].ID)
 		switch {
 		case isBackup == nil:
-			sortIndexi[0], sortIndexj[0] = hosts[i].Count, hosts[j].Count
+			sortIndexi[1], sortIndexj[1] = hosts[i].Count, hosts[j].Count
 		case *isBackup:
-			sortIndexi[0], sortIndexj[0] = hosts[i].backupCount, hosts[j].backupCount
+			sortIndexi[1], sortIndexj[1] = hosts[i].backupCount, hosts[j].backupCount
 		default:
-			sortIndexi[0], sortIndexj[0] = hosts[i].masterCount, hosts[j].masterCount
+			sortIndexi[1], sortIndexj[1] = hosts[i].masterCount, hosts[j].masterCount
 		}
-		sortIndexi[1], sortIndexj[1] = hosts[i].Count, hosts[j].Count
-		sortIndexi[2], sortIndexj[2] = -(hosts[i].minInstanceGroupCapacity(guestInfo.instanceGroupsDetail)), -(hosts[j].minInstanceGroupCapacity(guestInfo.instanceGroupsDetail))
-		sortIndexi[3], sortIndexj[3] = scoreNormalization(hosts[i].Score, hosts[j].Score)
-		sortIndexi[4], sortIndexj[4] = -(hosts[i].Capacity), -(hosts[j].Capacity)
-		for i := 0; i < 5; i++ {
+		sortIndexi[2], sortIndexj[2] = hosts[i].Count, hosts[j].Count
+		sortIndexi[3], sortIndexj[3] = -(hosts[i].minInstanceGroupCapacity(guestInfo.instanceGroupsDetail)), -(hosts[j].minInstanceGroupCapacity(guestInfo.instanceGroupsDetail))
+		sortIndexi[4], sortIndexj[4] = scoreNormalization(hosts[i].Score, hosts[j].Score)
+		sortIndexi[5], sortIndexj[5] = -(hosts[i].Capacity), -(hosts[j].Capacity)
+		for i := 0; i < 6; i++ {
 			if sortIndexi[i] == sortIndexj[i] {
 				continue
 			}
@@ -290,6 +320,9 @@ func markHostUsed(host *sSchedResultItem, guestInfo sGuestInfo, isBackup *bool)
 	for gid := range guestInfo.instanceGroupsDetail {
 		host.instanceGroupCapacity[gid] = host.instanceGroupCapacity[gid] - 1
 	}
+	for _, s := range guestInfo.topologySpreads {
+		s.Add(host.ID, 1)
+	}
 	host.Capacity--
 	host.Count++
 	if isBackup == nil {
@@ -307,6 +340,9 @@ func unMarkHostUsed(host *sSchedResultItem, guestInfo sGuestInfo, isBackup *bool
 	for gid := range guestInfo.instanceGroupsDetail {
 		host.instanceGroupCapacity[gid] = host.instanceGroupCapacity[gid] + 1
 	}
+	for _, s := range guestInfo.topologySpreads {
+		s.Add(host.ID, -1)
+	}
 	host.Capacity++
 	host.Count--
 	if isBackup == nil {
@@ -320,8 +356,9 @@ func unMarkHostUsed(host *sSchedResultItem, guestInfo sGuestInfo, isBackup *bool
 }
 
 // selectHost select host from hosts for guest described by guestInfo.
-// If forced is true, all instanceGroups will be forced.
-// Otherwise, the instanceGroups with ForceDispersion 'false' will be unforced.
+// If forced is true, all instanceGroups and topology spreads will be forced.
+// Otherwise, the instanceGroups with ForceDispersion 'false' and soft topology
+// spreads will be unforced.
 func selectHost(hosts []*sSchedResultItem, guestInfo sGuestInfo, isBackup *bool, forced bool) *sSchedResultItem {
 	sortHosts(hosts, &guestInfo, isBackup)
 	var idx = -1
@@ -347,6 +384,12 @@ Loop:
 				continue Loop
 			}
 		}
+		// check topology spreads, soft ones are only checked when forced
+		for _, s := range guestInfo.topologySpreads {
+			if (forced || s.Constraint.IsHard()) && !s.Fits(host.ID) {
+				continue Loop
+			}
+		}
 		idx = i
 		choosed = true
 		break
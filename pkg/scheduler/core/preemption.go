@@ -0,0 +1,204 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	o "yunion.io/x/onecloud/pkg/scheduler/options"
+)
+
+// PreemptibleGuest is a running preemptible guest which can be stopped or
+// deleted to release resources for non-preemptible guests
+type PreemptibleGuest struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	VcpuCount int64     `json:"vcpu_count"`
+	VmemSize  int64     `json:"vmem_size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IPreemptibleGuestsGetter is implemented by property getters of candidates
+// hosting preemptible guests
+type IPreemptibleGuestsGetter interface {
+	PreemptibleGuests() []*PreemptibleGuest
+}
+
+// GetPreemptibleGuests returns preemptible guests on candidate
+func GetPreemptibleGuests(c Candidater) []*PreemptibleGuest {
+	getter, ok := c.Getter().(IPreemptibleGuestsGetter)
+	if !ok {
+		return nil
+	}
+	return getter.PreemptibleGuests()
+}
+
+// GetPreemptibleResource returns cpu count and memory size released if all
+// preemptible guests on candidate are preempted
+func GetPreemptibleResource(c Candidater) (int64, int64) {
+	var cpu, mem int64
+	for _, g := range GetPreemptibleGuests(c) {
+		cpu += g.VcpuCount
+		mem += g.VmemSize
+	}
+	return cpu, mem
+}
+
+// CanPreempt tells whether guests of unit are allowed to preempt others.
+// Only non-preemptible kvm guests being created can preempt
+func (u *Unit) CanPreempt() bool {
+	if !o.GetOptions().EnableGuestPreemption {
+		return false
+	}
+	d := u.SchedData()
+	if d.Preemptible || d.Backup || d.ChangeConfig || len(d.HostId) > 0 {
+		return false
+	}
+	return u.GetHypervisor() == computeapi.HYPERVISOR_KVM
+}
+
+// IsPreempting tells whether preemptible guests are taken as free resources
+func (u *Unit) IsPreempting() bool {
+	return u.preempting
+}
+
+// SetCapacityWithoutPreemption records capacity of candidate computed by
+// predicate without preempting any guest
+func (u *Unit) SetCapacityWithoutPreemption(id string, name string, capacity int64) {
+	u.capacityWithoutPreemptionLock.Lock()
+	defer u.capacityWithoutPreemptionLock.Unlock()
+
+	if u.capacityWithoutPreemption == nil {
+		u.capacityWithoutPreemption = make(map[string]map[string]int64)
+	}
+	if _, ok := u.capacityWithoutPreemption[id]; !ok {
+		u.capacityWithoutPreemption[id] = make(map[string]int64)
+	}
+	u.capacityWithoutPreemption[id][name] = capacity
+}
+
+func (u *Unit) getCapacityWithoutPreemption(id string) int64 {
+	capacity := u.GetCapacity(id)
+
+	u.capacityWithoutPreemptionLock.Lock()
+	defer u.capacityWithoutPreemptionLock.Unlock()
+
+	for _, c := range u.capacityWithoutPreemption[id] {
+		if c < capacity {
+			capacity = c
+		}
+	}
+	return capacity
+}
+
+func newPreemptingUnit(u *Unit) *Unit {
+	unit := NewScheduleUnit(u.SchedInfo, u.SchedulerManager)
+	unit.preempting = true
+	return unit
+}
+
+// hostPreemption chooses preemptible guests on host one guest after another
+// being placed on it.  Newest guests are preempted first for they have done
+// the least work
+type hostPreemption struct {
+	freeCPU int64
+	freeMem int64
+	reqCPU  int64
+	reqMem  int64
+
+	guests []*PreemptibleGuest
+	chosen []*PreemptibleGuest
+
+	placed       int64
+	reclaimedCPU int64
+	reclaimedMem int64
+}
+
+func newHostPreemption(u *Unit, c Candidater) *hostPreemption {
+	d := u.SchedData()
+	useRsvd := len(d.IsolatedDevices) > 0
+	getter := c.Getter()
+	guests := make([]*PreemptibleGuest, len(GetPreemptibleGuests(c)))
+	copy(guests, GetPreemptibleGuests(c))
+	sort.SliceStable(guests, func(i, j int) bool {
+		return guests[i].CreatedAt.After(guests[j].CreatedAt)
+	})
+	return &hostPreemption{
+		freeCPU: getter.FreeCPUCount(useRsvd),
+		freeMem: getter.FreeMemorySize(useRsvd),
+		reqCPU:  int64(d.Ncpu),
+		reqMem:  int64(d.Memory),
+		guests:  guests,
+	}
+}
+
+// next returns guests to be preempted to place one more guest on host
+func (p *hostPreemption) next() []*PreemptibleGuest {
+	p.placed++
+	needCPU := p.placed*p.reqCPU - p.freeCPU
+	needMem := p.placed*p.reqMem - p.freeMem
+	ret := []*PreemptibleGuest{}
+	for (p.reclaimedCPU < needCPU || p.reclaimedMem < needMem) && len(p.guests) > 0 {
+		g := p.guests[0]
+		p.guests = p.guests[1:]
+		p.reclaimedCPU += g.VcpuCount
+		p.reclaimedMem += g.VmemSize
+		ret = append(ret, g)
+	}
+	p.chosen = append(p.chosen, ret...)
+	return ret
+}
+
+func (item *SchedResultItem) nextPreemptGuests() []string {
+	if item.preemption == nil {
+		return nil
+	}
+	guests := item.preemption.next()
+	if len(guests) == 0 {
+		return nil
+	}
+	ids := make([]string, len(guests))
+	for i := range guests {
+		ids[i] = guests[i].Id
+	}
+	return ids
+}
+
+func (item *SchedResultItem) getPreemptGuests() []api.PreemptGuest {
+	if item.preemption == nil {
+		return nil
+	}
+	ret := make([]api.PreemptGuest, len(item.preemption.chosen))
+	for i, g := range item.preemption.chosen {
+		ret[i] = api.PreemptGuest{
+			Id:       g.Id,
+			Name:     g.Name,
+			HostId:   item.ID,
+			HostName: item.Name,
+		}
+	}
+	return ret
+}
+
+func countSelected(scs []*SelectedCandidate) int64 {
+	var cnt int64
+	for _, sc := range scs {
+		cnt += sc.Count
+	}
+	return cnt
+}
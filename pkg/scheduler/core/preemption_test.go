@@ -0,0 +1,55 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+)
+
+func TestHostPreemption(t *testing.T) {
+	p := &hostPreemption{
+		freeCPU: 2,
+		freeMem: 4096,
+		reqCPU:  4,
+		reqMem:  4096,
+		guests: []*PreemptibleGuest{
+			{Id: "g1", VcpuCount: 1, VmemSize: 1024},
+			{Id: "g2", VcpuCount: 2, VmemSize: 2048},
+			{Id: "g3", VcpuCount: 4, VmemSize: 8192},
+		},
+	}
+	ids := func(gs []*PreemptibleGuest) []string {
+		ret := []string{}
+		for _, g := range gs {
+			ret = append(ret, g.Id)
+		}
+		return ret
+	}
+
+	// 2 cpus are short of the first guest
+	if got := ids(p.next()); len(got) != 2 || got[0] != "g1" || got[1] != "g2" {
+		t.Errorf("first guest: want [g1 g2], got %v", got)
+	}
+	// 3 cpus and 3072 memory reclaimed are short of 6 cpus and 4096 memory for two guests
+	if got := ids(p.next()); len(got) != 1 || got[0] != "g3" {
+		t.Errorf("second guest: want [g3], got %v", got)
+	}
+	if got := p.next(); len(got) != 0 {
+		t.Errorf("third guest: want nothing left to preempt, got %v", ids(got))
+	}
+	if len(p.chosen) != 3 {
+		t.Errorf("chosen: want 3 guests, got %d", len(p.chosen))
+	}
+}
@@ -48,6 +48,8 @@ type SchedResultItem struct {
 	*AllocatedResource
 
 	SchedData *api.SchedInfo
+
+	preemption *hostPreemption
 }
 
 type SchedResultItemList struct {
@@ -81,6 +83,8 @@ func (item *SchedResultItem) ToCandidateResource(storageUsed *StorageUsed) *sche
 		Name:   item.Name,
 		Disks:  item.getDisks(storageUsed),
 		Nets:   item.Nets,
+
+		PreemptGuests: item.nextPreemptGuests(),
 	}
 }
 
@@ -151,11 +151,15 @@ func transToSchedForecastResult(result *SchedResultItemList) *api.SchedForecastR
 		filteredCandidates = append(filteredCandidates, filteredCandidate)
 	}
 	ret.FilteredCandidates = filteredCandidates
+	ret.Extenders = unit.GetExtenderResults()
 
 	var (
 		output     = transToSchedResult(result, schedData)
 		readyCount int64
 	)
+	for _, item := range result.Data {
+		ret.PreemptGuests = append(ret.PreemptGuests, item.getPreemptGuests()...)
+	}
 	for _, candi := range output.Candidates {
 		if len(candi.Error) == 0 {
 			readyCount++
@@ -0,0 +1,51 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"yunion.io/x/pkg/util/sets"
+)
+
+// selectLimit caps the total count of guests selected on a group of candidates
+type selectLimit struct {
+	name    string
+	hostIds sets.String
+	count   int64
+}
+
+// selectLimiter tracks guests selected against the limits of a unit
+type selectLimiter struct {
+	limits []*selectLimit
+	used   []int64
+}
+
+// Allows tells whether one more guest may be selected on candidate hostId
+func (l *selectLimiter) Allows(hostId string) bool {
+	for i, limit := range l.limits {
+		if limit.hostIds.Has(hostId) && l.used[i] >= limit.count {
+			return false
+		}
+	}
+	return true
+}
+
+// Take records a guest selected on candidate hostId
+func (l *selectLimiter) Take(hostId string) {
+	for i, limit := range l.limits {
+		if limit.hostIds.Has(hostId) {
+			l.used[i]++
+		}
+	}
+}
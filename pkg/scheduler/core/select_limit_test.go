@@ -0,0 +1,73 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+
+	"yunion.io/x/pkg/util/sets"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+)
+
+type fakeCandidate struct {
+	Candidater
+	id string
+}
+
+func (c *fakeCandidate) IndexKey() string {
+	return c.id
+}
+
+func TestSelectHostsWithLimit(t *testing.T) {
+	newUnit := func(count int) (*Unit, HostPriorityList) {
+		info := &api.SchedInfo{
+			ScheduleInput: &schedapi.ScheduleInput{
+				ServerConfig: schedapi.ServerConfig{
+					ServerConfigs: &computeapi.ServerConfigs{Count: count},
+				},
+			},
+		}
+		u := NewScheduleUnit(info, nil)
+		list := HostPriorityList{}
+		for _, id := range []string{"h1", "h2", "h3"} {
+			u.SetCapacity(id, "test", NewNormalCounter(4))
+			list = append(list, HostPriority{Host: id, Score: u.GetScore(id), Candidate: &fakeCandidate{id: id}})
+		}
+		// only 3 guests may go to h1 and h2 together
+		u.AddSelectLimit("test", sets.NewString("h1", "h2"), 3)
+		return u, list
+	}
+
+	u, list := newUnit(7)
+	selected, err := SelectHosts(u, list)
+	if err != nil {
+		t.Fatalf("select 7 guests: %s", err)
+	}
+	counts := map[string]int64{}
+	for _, sc := range selected {
+		counts[sc.Candidate.IndexKey()] = sc.Count
+	}
+	if counts["h1"]+counts["h2"] != 3 || counts["h3"] != 4 {
+		t.Errorf("want 3 guests on h1 and h2 and 4 on h3, got %v", counts)
+	}
+
+	u, list = newUnit(8)
+	if _, err := SelectHosts(u, list); err == nil {
+		t.Errorf("select 8 guests beyond the limit should fail")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"sort"
+
+	"yunion.io/x/pkg/errors"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+// TopologySpread tracks how guests of an instance group are distributed over
+// topology domains of a spread constraint.  Only domains of candidates are
+// taken into account, guests in domains having no candidate can not affect
+// where new guests go
+type TopologySpread struct {
+	GroupId    string
+	Constraint computeapi.TopologySpreadConstraint
+
+	// maps candidate id to its domain
+	hostDomains map[string]string
+	// maps domain to number of guests of the group in it
+	counts map[string]int
+}
+
+// newTopologySpread counts guests of group in each domain.  hostDomains maps
+// ids of candidates and hosts having group guests to their domain,
+// hostCounts maps host id to number of group guests on it
+func newTopologySpread(groupId string, constraint computeapi.TopologySpreadConstraint, candidateIds []string, hostDomains map[string]string, hostCounts map[string]int) *TopologySpread {
+	s := &TopologySpread{
+		GroupId:     groupId,
+		Constraint:  constraint,
+		hostDomains: map[string]string{},
+		counts:      map[string]int{},
+	}
+	for _, id := range candidateIds {
+		if domain, ok := hostDomains[id]; ok {
+			s.hostDomains[id] = domain
+			s.counts[domain] += 0
+		}
+	}
+	for hostId, cnt := range hostCounts {
+		domain, ok := hostDomains[hostId]
+		if !ok {
+			continue
+		}
+		if _, ok := s.counts[domain]; ok {
+			s.counts[domain] += cnt
+		}
+	}
+	return s
+}
+
+func (s *TopologySpread) Domain(hostId string) (string, bool) {
+	domain, ok := s.hostDomains[hostId]
+	return domain, ok
+}
+
+// minCount returns the least number of group guests in a domain, which is
+// 0 when there are fewer domains than MinDomains
+func (s *TopologySpread) minCount() int {
+	if len(s.counts) == 0 || len(s.counts) < s.Constraint.MinDomains {
+		return 0
+	}
+	min := -1
+	for _, cnt := range s.counts {
+		if min < 0 || cnt < min {
+			min = cnt
+		}
+	}
+	return min
+}
+
+// Skew returns skew of the domain of host as if another guest were placed
+// on it.  ok is false if host does not belong to any domain
+func (s *TopologySpread) Skew(hostId string) (int, bool) {
+	domain, ok := s.hostDomains[hostId]
+	if !ok {
+		return 0, false
+	}
+	return s.counts[domain] + 1 - s.minCount(), true
+}
+
+// MayFit tells whether any of count guests to place could go to host
+// without exceeding MaxSkew, assuming the others go to the least populated
+// domains
+func (s *TopologySpread) MayFit(hostId string, count int) bool {
+	skew, ok := s.Skew(hostId)
+	if !ok {
+		return false
+	}
+	if count > 1 && len(s.counts) >= s.Constraint.MinDomains {
+		skew -= count - 1
+	}
+	return skew <= s.Constraint.MaxSkew
+}
+
+// Fits tells whether another guest could go to host without exceeding
+// MaxSkew
+func (s *TopologySpread) Fits(hostId string) bool {
+	return s.MayFit(hostId, 1)
+}
+
+// Add records delta guests placed on host
+func (s *TopologySpread) Add(hostId string, delta int) {
+	if domain, ok := s.hostDomains[hostId]; ok {
+		s.counts[domain] += delta
+	}
+}
+
+// fetchTopologySpreads returns spreads of constraints of groups.  The guest
+// being migrated from srcHostId is not counted
+func fetchTopologySpreads(groups map[string]*models.SGroup, candidateIds []string, srcHostId string) ([]*TopologySpread, error) {
+	groupIds := make([]string, 0, len(groups))
+	for groupId := range groups {
+		groupIds = append(groupIds, groupId)
+	}
+	sort.Strings(groupIds)
+	ret := []*TopologySpread{}
+	for _, groupId := range groupIds {
+		group := groups[groupId]
+		if group == nil || group.TopologySpreadConstraints == nil || len(*group.TopologySpreadConstraints) == 0 {
+			continue
+		}
+		hostCounts, err := group.GetHostGuestCounts()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetHostGuestCounts of group %s", group.Name)
+		}
+		if len(srcHostId) > 0 && hostCounts[srcHostId] > 0 {
+			hostCounts[srcHostId]--
+		}
+		hostIds := append([]string{}, candidateIds...)
+		for hostId := range hostCounts {
+			hostIds = append(hostIds, hostId)
+		}
+		for _, constraint := range *group.TopologySpreadConstraints {
+			hostDomains, err := models.HostManager.GetTopologyDomains(constraint.TopologyKey, hostIds)
+			if err != nil {
+				return nil, errors.Wrapf(err, "GetTopologyDomains by %s", constraint.TopologyKey)
+			}
+			ret = append(ret, newTopologySpread(groupId, constraint, candidateIds, hostDomains, hostCounts))
+		}
+	}
+	return ret, nil
+}
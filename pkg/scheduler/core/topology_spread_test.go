@@ -0,0 +1,74 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestTopologySpread(t *testing.T) {
+	constraint := computeapi.TopologySpreadConstraint{
+		TopologyKey: computeapi.TOPOLOGY_KEY_RACK,
+		MaxSkew:     1,
+		MinDomains:  3,
+		Mode:        computeapi.TOPOLOGY_SPREAD_MODE_HARD,
+	}
+	hostDomains := map[string]string{
+		"h1": "r1",
+		"h2": "r1",
+		"h3": "r2",
+		"h4": "r3",
+		// not a candidate
+		"h5": "r4",
+	}
+	hostCounts := map[string]int{
+		"h1": 1,
+		"h2": 1,
+		"h5": 3,
+	}
+	s := newTopologySpread("g", constraint, []string{"h1", "h2", "h3", "h4", "h6"}, hostDomains, hostCounts)
+
+	if _, ok := s.Domain("h6"); ok {
+		t.Errorf("h6 should belong to no domain")
+	}
+	if skew, _ := s.Skew("h1"); skew != 3 {
+		t.Errorf("skew of h1: want 3, got %d", skew)
+	}
+	if s.Fits("h1") || !s.Fits("h3") || s.Fits("h6") {
+		t.Errorf("only hosts of empty racks should fit")
+	}
+	if s.MayFit("h1", 2) || !s.MayFit("h1", 3) {
+		t.Errorf("r1 may only get the third of three guests")
+	}
+
+	s.Add("h3", 1)
+	s.Add("h4", 1)
+	if s.Fits("h1") {
+		t.Errorf("r1 should not fit while other racks have fewer guests")
+	}
+	s.Add("h3", 1)
+	s.Add("h4", 1)
+	if !s.Fits("h1") {
+		t.Errorf("r1 should fit once every rack has as many guests")
+	}
+
+	constraint.MinDomains = 4
+	s = newTopologySpread("g", constraint, []string{"h3", "h4"}, hostDomains, map[string]int{"h3": 1, "h4": 1})
+	if s.Fits("h3") {
+		t.Errorf("min count should be 0 with fewer domains than min_domains")
+	}
+}
@@ -243,6 +243,21 @@ type IsolatedDeviceDesc struct {
 	Model          string
 	Addr           string
 	VendorDeviceID string
+	NumaNode       int
+	PcieSwitch     string
+	PeerGroup      string
+}
+
+func (i *IsolatedDeviceDesc) GetNumaNode() int {
+	return i.NumaNode
+}
+
+func (i *IsolatedDeviceDesc) GetPcieSwitch() string {
+	return i.PcieSwitch
+}
+
+func (i *IsolatedDeviceDesc) GetPeerGroup() string {
+	return i.PeerGroup
 }
 
 func (i *IsolatedDeviceDesc) VendorID() string {
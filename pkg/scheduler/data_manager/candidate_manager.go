@@ -204,6 +204,16 @@ func (cm *CandidateManager) GetCandidates(args CandidateGetArgs) ([]core.Candida
 		return nil, errors.Wrapf(err, "GetCandidates from implement")
 	}
 
+	cs := make([]core.Candidater, len(candidates))
+	for i := range candidates {
+		cs[i] = candidates[i].(core.Candidater)
+	}
+	return FilterCandidates(cs, args), nil
+}
+
+// FilterCandidates returns candidates matching region, zone, cloudprovider
+// and host types of args
+func FilterCandidates(candidates []core.Candidater, args CandidateGetArgs) []core.Candidater {
 	result := []core.Candidater{}
 
 	matchZone := func(r core.Candidater, zoneId string) bool {
@@ -249,9 +259,7 @@ func (cm *CandidateManager) GetCandidates(args CandidateGetArgs) ([]core.Candida
 		return utils.IsInStringArray(c.Getter().HostType(), hostTypes)
 	}
 
-	for _, c := range candidates {
-		r := c.(core.Candidater)
-
+	for _, r := range candidates {
 		if !matchRegion(r, args.RegionID) {
 			continue
 		}
@@ -271,7 +279,7 @@ func (cm *CandidateManager) GetCandidates(args CandidateGetArgs) ([]core.Candida
 		result = append(result, r)
 	}
 
-	return result, nil
+	return result
 }
 
 func (cm *CandidateManager) GetCandidatesByIds(resType string, ids []string) ([]core.Candidater, error) {
@@ -0,0 +1,29 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package extender calls external http endpoints to filter and prioritize
+// candidates.  Extenders are configured by a yaml or json file like
+//
+//	extenders:
+//	- name: rack-aware
+//	  url_prefix: http://127.0.0.1:8898/extender
+//	  filter_verb: filter
+//	  prioritize_verb: prioritize
+//	  weight: 2
+//	  timeout_seconds: 3
+//	  ignorable: true
+//
+// Requests are posted as api.ExtenderArgs to <url_prefix>/<verb>.  Replies
+// are api.ExtenderFilterResult and api.ExtenderPrioritizeResult respectively
+package extender // import "yunion.io/x/onecloud/pkg/scheduler/extender"
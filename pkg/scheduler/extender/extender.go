@@ -0,0 +1,256 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extender
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/core/score"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	DefaultTimeoutSeconds = 5
+)
+
+type ExtenderConfig struct {
+	Name           string `json:"name"`
+	UrlPrefix      string `json:"url_prefix"`
+	FilterVerb     string `json:"filter_verb"`
+	PrioritizeVerb string `json:"prioritize_verb"`
+	Weight         int    `json:"weight"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	Ignorable      bool   `json:"ignorable"`
+}
+
+type ExtendersConfig struct {
+	Extenders []ExtenderConfig `json:"extenders"`
+}
+
+func (c *ExtenderConfig) Validate() error {
+	if c.Name == "" {
+		return errors.Error("empty name")
+	}
+	if c.UrlPrefix == "" {
+		return errors.Errorf("extender %s: empty url_prefix", c.Name)
+	}
+	if c.FilterVerb == "" && c.PrioritizeVerb == "" {
+		return errors.Errorf("extender %s: neither filter_verb nor prioritize_verb is set", c.Name)
+	}
+	if c.Weight < 0 {
+		return errors.Errorf("extender %s: negative weight %d", c.Name, c.Weight)
+	}
+	if c.TimeoutSeconds < 0 {
+		return errors.Errorf("extender %s: negative timeout_seconds %d", c.Name, c.TimeoutSeconds)
+	}
+	return nil
+}
+
+type HTTPExtender struct {
+	config ExtenderConfig
+	client *http.Client
+}
+
+func NewHTTPExtender(config ExtenderConfig) (*HTTPExtender, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Weight == 0 {
+		config.Weight = 1
+	}
+	if config.TimeoutSeconds == 0 {
+		config.TimeoutSeconds = DefaultTimeoutSeconds
+	}
+	config.UrlPrefix = strings.TrimRight(config.UrlPrefix, "/")
+	e := &HTTPExtender{
+		config: config,
+		client: httputils.GetTimeoutClient(time.Duration(config.TimeoutSeconds) * time.Second),
+	}
+	return e, nil
+}
+
+func (e *HTTPExtender) Name() string {
+	return e.config.Name
+}
+
+func (e *HTTPExtender) IsIgnorable() bool {
+	return e.config.Ignorable
+}
+
+func (e *HTTPExtender) IsFilter() bool {
+	return e.config.FilterVerb != ""
+}
+
+func (e *HTTPExtender) IsPrioritizer() bool {
+	return e.config.PrioritizeVerb != ""
+}
+
+func (e *HTTPExtender) Weight() int {
+	return e.config.Weight
+}
+
+func (e *HTTPExtender) args(unit *core.Unit, candidates []core.Candidater) *api.ExtenderArgs {
+	args := &api.ExtenderArgs{
+		SchedInfo:  unit.SchedInfo,
+		Candidates: make([]api.ExtenderCandidate, 0, len(candidates)),
+	}
+	for _, c := range candidates {
+		getter := c.Getter()
+		ec := api.ExtenderCandidate{
+			Id:       c.IndexKey(),
+			Name:     getter.Name(),
+			HostType: getter.HostType(),
+			Status:   getter.Status(),
+		}
+		if zone := getter.Zone(); zone != nil {
+			ec.ZoneId = zone.Id
+		}
+		for _, tag := range getter.HostSchedtags() {
+			ec.Schedtags = append(ec.Schedtags, tag.Name)
+		}
+		args.Candidates = append(args.Candidates, ec)
+	}
+	return args
+}
+
+func (e *HTTPExtender) send(verb string, args *api.ExtenderArgs, result interface{}) error {
+	url := fmt.Sprintf("%s/%s", e.config.UrlPrefix, verb)
+	_, resp, err := httputils.JSONRequest(e.client, context.Background(), httputils.POST, url, nil, jsonutils.Marshal(args), false)
+	if err != nil {
+		return errors.Wrapf(err, "post %s", url)
+	}
+	if resp == nil {
+		return errors.Errorf("post %s: empty response", url)
+	}
+	if err := resp.Unmarshal(result); err != nil {
+		return errors.Wrapf(err, "unmarshal response of %s", url)
+	}
+	return nil
+}
+
+func (e *HTTPExtender) Filter(unit *core.Unit, candidates []core.Candidater) ([]core.Candidater, map[string]string, error) {
+	result := &api.ExtenderFilterResult{}
+	if err := e.send(e.config.FilterVerb, e.args(unit, candidates), result); err != nil {
+		return nil, nil, err
+	}
+	if result.Error != "" {
+		return nil, nil, errors.Error(result.Error)
+	}
+	fits := make(map[string]bool, len(result.Candidates))
+	for _, id := range result.Candidates {
+		fits[id] = true
+	}
+	filtered := make([]core.Candidater, 0, len(result.Candidates))
+	for _, c := range candidates {
+		if fits[c.IndexKey()] {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, result.FailedCandidates, nil
+}
+
+func (e *HTTPExtender) Prioritize(unit *core.Unit, candidates []core.Candidater) (map[string]score.TScore, error) {
+	result := &api.ExtenderPrioritizeResult{}
+	if err := e.send(e.config.PrioritizeVerb, e.args(unit, candidates), result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, errors.Error(result.Error)
+	}
+	scores := make(map[string]score.TScore, len(result.Scores))
+	for _, s := range result.Scores {
+		scores[s.Id] = score.TScore(s.Score)
+	}
+	return scores, nil
+}
+
+var (
+	extenders     []core.Extender
+	extendersLock sync.RWMutex
+)
+
+// LoadConfig loads extenders from yaml or json file at path
+func LoadConfig(path string) ([]core.Extender, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", path)
+	}
+	obj, err := jsonutils.ParseYAML(string(content))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s", path)
+	}
+	config := &ExtendersConfig{}
+	if err := obj.Unmarshal(config); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal %s", path)
+	}
+	names := make(map[string]bool)
+	ret := make([]core.Extender, 0, len(config.Extenders))
+	for _, c := range config.Extenders {
+		if names[c.Name] {
+			return nil, errors.Errorf("duplicate extender %s", c.Name)
+		}
+		names[c.Name] = true
+		e, err := NewHTTPExtender(c)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, e)
+	}
+	return ret, nil
+}
+
+// Init loads extenders from config file at path.  Empty path means no
+// extenders
+func Init(path string) error {
+	var es []core.Extender
+	if path != "" {
+		var err error
+		es, err = LoadConfig(path)
+		if err != nil {
+			return err
+		}
+		for _, e := range es {
+			log.Infof("scheduler extender %s loaded", e.Name())
+		}
+	}
+	SetExtenders(es)
+	return nil
+}
+
+func SetExtenders(es []core.Extender) {
+	extendersLock.Lock()
+	defer extendersLock.Unlock()
+
+	extenders = es
+}
+
+func GetExtenders() []core.Extender {
+	extendersLock.RLock()
+	defer extendersLock.RUnlock()
+
+	return extenders
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extender
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sched-extender")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name    string
+		content string
+		count   int
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			content: `extenders:
+- name: rack
+  url_prefix: http://127.0.0.1:8898/extender/
+  filter_verb: filter
+  ignorable: true
+- name: cost
+  url_prefix: http://127.0.0.1:8899
+  prioritize_verb: prioritize
+  weight: 3
+`,
+			count: 2,
+		},
+		{
+			name: "no verb",
+			content: `extenders:
+- name: rack
+  url_prefix: http://127.0.0.1:8898
+`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate",
+			content: `extenders:
+- name: rack
+  url_prefix: http://127.0.0.1:8898
+  filter_verb: filter
+- name: rack
+  url_prefix: http://127.0.0.1:8899
+  filter_verb: filter
+`,
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(dir, c.name+".yaml")
+			if err := ioutil.WriteFile(path, []byte(c.content), 0644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			es, err := LoadConfig(path)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %d extenders", len(es))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if len(es) != c.count {
+				t.Fatalf("want %d extenders, got %d", c.count, len(es))
+			}
+		})
+	}
+}
+
+func TestHTTPExtenderSend(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/extender/filter":
+			body, _ := ioutil.ReadAll(r.Body)
+			args := &api.ExtenderArgs{}
+			obj, err := jsonutils.Parse(body)
+			if err == nil {
+				err = obj.Unmarshal(args)
+			}
+			if err != nil || len(args.Candidates) != 2 {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(jsonutils.Marshal(&api.ExtenderFilterResult{
+				Candidates:       []string{args.Candidates[0].Id},
+				FailedCandidates: map[string]string{args.Candidates[1].Id: "rack full"},
+			}).String()))
+		case "/extender/slow":
+			time.Sleep(2 * time.Second)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	e, err := NewHTTPExtender(ExtenderConfig{
+		Name:           "rack",
+		UrlPrefix:      srv.URL + "/extender/",
+		FilterVerb:     "filter",
+		TimeoutSeconds: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPExtender: %v", err)
+	}
+	if e.Weight() != 1 || !e.IsFilter() || e.IsPrioritizer() {
+		t.Fatalf("unexpected defaults %#v", e.config)
+	}
+
+	args := &api.ExtenderArgs{
+		SchedInfo: &api.SchedInfo{},
+		Candidates: []api.ExtenderCandidate{
+			{Id: "host1", Name: "host1"},
+			{Id: "host2", Name: "host2"},
+		},
+	}
+	result := &api.ExtenderFilterResult{}
+	if err := e.send("filter", args, result); err != nil {
+		t.Fatalf("send filter: %v", err)
+	}
+	if len(result.Candidates) != 1 || result.Candidates[0] != "host1" || result.FailedCandidates["host2"] != "rack full" {
+		t.Fatalf("unexpected filter result %#v", result)
+	}
+
+	if err := e.send("slow", args, result); err == nil {
+		t.Fatalf("send should time out")
+	}
+	if err := e.send("missing", args, result); err == nil {
+		t.Fatalf("send to missing verb should fail")
+	}
+}
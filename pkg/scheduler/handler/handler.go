@@ -67,6 +67,8 @@ func schedulerActionHandler(c *gin.Context) {
 		doSchedulerTest(c)
 	case "forecast":
 		doSchedulerForecast(c)
+	case "simulate":
+		doSchedulerSimulate(c)
 	case "candidate-list":
 		doCandidateList(c)
 	case "cleanup":
@@ -145,6 +147,26 @@ func doSchedulerForecast(c *gin.Context) {
 	c.JSON(http.StatusOK, result.ForecastResult)
 }
 
+func doSchedulerSimulate(c *gin.Context) {
+	if !schedman.IsReady() {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("Global scheduler not init"))
+		return
+	}
+
+	args, err := api.FetchSimulateArgs(c.Request)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := schedman.Simulate(args)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 func doCandidateList(c *gin.Context) {
 	args, err := api.NewCandidateListArgs(c.Request.Body)
 	if err != nil {
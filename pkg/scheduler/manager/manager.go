@@ -133,6 +133,20 @@ func IsReady() bool {
 	return schedManager != nil
 }
 
+// FetchCandidates returns cached candidates of ids, those not cached are skipped
+func (sm *SchedulerManager) FetchCandidates(resType string, ids []string) []core.Candidater {
+	ret := []core.Candidater{}
+	for _, id := range ids {
+		c, err := sm.CandidateManager.GetCandidate(id, resType)
+		if err != nil {
+			log.Warningf("fetch %s candidate %s: %v", resType, id, err)
+			continue
+		}
+		ret = append(ret, c.(core.Candidater))
+	}
+	return ret
+}
+
 func GetCandidateManager() *data_manager.CandidateManager {
 	return schedManager.CandidateManager
 }
@@ -402,6 +416,7 @@ func GetHistoryDetail(historyDetailArgs *api.HistoryDetailArgs) (*api.HistoryDet
 		if err != nil {
 			historyTask.Error = fmt.Sprintf("%v", err)
 		}
+		historyTask.Extenders = taskExecutor.GetExtenderResults()
 
 		if historyDetailArgs.Log {
 			historyTask.Logs = taskExecutor.GetLogs()
@@ -21,6 +21,7 @@ import (
 	"yunion.io/x/onecloud/pkg/scheduler/api"
 	"yunion.io/x/onecloud/pkg/scheduler/core"
 	"yunion.io/x/onecloud/pkg/scheduler/data_manager"
+	"yunion.io/x/onecloud/pkg/scheduler/extender"
 	"yunion.io/x/onecloud/pkg/scheduler/factory"
 )
 
@@ -163,6 +164,10 @@ func (s *BaseScheduler) BeforePredicate() error {
 	return nil
 }
 
+func (s *BaseScheduler) Extenders() []core.Extender {
+	return extender.GetExtenders()
+}
+
 // GuestScheduler for guest type schedule
 type GuestScheduler struct {
 	*BaseScheduler
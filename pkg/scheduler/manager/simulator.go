@@ -0,0 +1,255 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"sort"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/sets"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/cache/candidate"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/data_manager"
+	schedmodels "yunion.io/x/onecloud/pkg/scheduler/models"
+)
+
+// Simulate places workloads on a snapshot of host candidates through the
+// predicates and priorities of the scheduler, nothing is really scheduled
+func Simulate(args *api.SimulateArgs) (*api.SimulateResult, error) {
+	return schedManager.simulate(args)
+}
+
+type simulateWorkload struct {
+	api.SimulateWorkload
+	result *api.SimulateWorkloadResult
+}
+
+func (sm *SchedulerManager) simulate(args *api.SimulateArgs) (*api.SimulateResult, error) {
+	hosts, removed, err := sm.simulateHosts(args)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]core.Candidater, len(hosts))
+	hostMap := make(map[string]*candidate.HostDesc, len(hosts))
+	for i, h := range hosts {
+		candidates[i] = h
+		hostMap[h.GetId()] = h
+	}
+	hostCounts := make(map[string]int64)
+
+	result := &api.SimulateResult{
+		RemovedHosts: removed,
+	}
+	workloads := make([]*simulateWorkload, len(args.Workloads))
+	for i := range args.Workloads {
+		workloads[i] = &simulateWorkload{
+			SimulateWorkload: args.Workloads[i],
+			result: &api.SimulateWorkloadResult{
+				Name:  args.Workloads[i].Name,
+				Hosts: make(map[string]int64),
+			},
+		}
+	}
+
+	active := workloads
+completed:
+	for len(active) > 0 {
+		fitted := []*simulateWorkload{}
+		for _, w := range active {
+			fits := true
+			for i := 0; i < w.Weight; i++ {
+				if result.Count >= args.MaxCount {
+					result.Truncated = true
+					break completed
+				}
+				forecast, err := sm.simulateSchedule(w.Data, candidates)
+				if err != nil {
+					return nil, errors.Wrapf(err, "simulate workload %s", w.Name)
+				}
+				if !forecast.CanCreate || len(forecast.Candidates) == 0 {
+					w.setBottleneck(forecast)
+					if len(result.Bottleneck) == 0 {
+						result.Bottleneck = w.result.Bottleneck
+					}
+					fits = false
+					break
+				}
+				host, ok := hostMap[forecast.Candidates[0].HostId]
+				if !ok {
+					return nil, errors.Errorf("simulate workload %s: unknown host %s", w.Name, forecast.Candidates[0].HostId)
+				}
+				host.AddSimulatedUsage(schedmodels.NewPendingUsageBySchedInfo(host.GetId(), w.Data))
+				hostCounts[host.GetId()]++
+				w.result.Hosts[host.GetName()]++
+				w.result.Count++
+				result.Count++
+			}
+			if fits {
+				fitted = append(fitted, w)
+			}
+		}
+		active = fitted
+	}
+
+	for _, w := range workloads {
+		result.Workloads = append(result.Workloads, *w.result)
+	}
+	for _, h := range hosts {
+		result.Hosts = append(result.Hosts, newSimulateHostResult(h, hostCounts[h.GetId()]))
+	}
+	sort.Slice(result.Hosts, func(i, j int) bool {
+		return result.Hosts[i].Name < result.Hosts[j].Name
+	})
+	return result, nil
+}
+
+// simulateHosts copies host candidates of region or zone, drops removed
+// hosts and adds new hosts copied from templates
+func (sm *SchedulerManager) simulateHosts(args *api.SimulateArgs) ([]*candidate.HostDesc, []string, error) {
+	cs, err := sm.CandidateManager.GetCandidates(data_manager.CandidateGetArgs{
+		ResType:  api.HostTypeHost,
+		RegionID: args.Region,
+		ZoneID:   args.Zone,
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "get host candidates")
+	}
+
+	findHost := func(ident string) *candidate.HostDesc {
+		for _, c := range cs {
+			h := c.(*candidate.HostDesc)
+			if h.GetId() == ident || h.GetName() == ident {
+				return h
+			}
+		}
+		return nil
+	}
+
+	removeIds := sets.NewString()
+	removed := []string{}
+	for _, ident := range args.RemoveHosts {
+		h := findHost(ident)
+		if h == nil {
+			return nil, nil, httperrors.NewResourceNotFoundError("host %s not found in candidates", ident)
+		}
+		removeIds.Insert(h.GetId())
+		removed = append(removed, h.GetName())
+	}
+
+	hosts := []*candidate.HostDesc{}
+	for _, c := range cs {
+		h := c.(*candidate.HostDesc)
+		if removeIds.Has(h.GetId()) {
+			continue
+		}
+		hosts = append(hosts, h.SimulateCopy())
+	}
+	for _, add := range args.AddHosts {
+		tmpl := findHost(add.TemplateHost)
+		if tmpl == nil {
+			return nil, nil, httperrors.NewResourceNotFoundError("template host %s not found in candidates", add.TemplateHost)
+		}
+		for i := 0; i < add.Count; i++ {
+			id := fmt.Sprintf("simulated-%s-%d", tmpl.GetId(), len(hosts))
+			name := fmt.Sprintf("%s-simulated-%d", tmpl.GetName(), len(hosts))
+			hosts = append(hosts, tmpl.SimulateNewHost(id, name))
+		}
+	}
+	return hosts, removed, nil
+}
+
+func (sm *SchedulerManager) simulateSchedule(info *api.SchedInfo, candidates []core.Candidater) (*api.SchedForecastResult, error) {
+	candidates = data_manager.FilterCandidates(candidates, data_manager.CandidateGetArgs{
+		RegionID:  info.PreferRegion,
+		ZoneID:    info.PreferZone,
+		ManagerID: info.PreferManager,
+		HostTypes: info.GetCandidateHostTypes(),
+	})
+	if len(candidates) == 0 {
+		return &api.SchedForecastResult{ReqCount: int64(info.Count)}, nil
+	}
+
+	s, err := newGuestScheduler(sm, info)
+	if err != nil {
+		return nil, err
+	}
+	g, err := core.NewGenericScheduler(s)
+	if err != nil {
+		return nil, err
+	}
+	unit := s.Unit()
+	result, err := g.Schedule(unit, candidates, core.SResultHelperFunc(core.ResultHelpForForcast))
+	if err != nil {
+		return nil, err
+	}
+	return result.ForecastResult, nil
+}
+
+// setBottleneck records the filter excluding most candidates as the
+// bottleneck of workload
+func (w *simulateWorkload) setBottleneck(forecast *api.SchedForecastResult) {
+	counts := make(map[string]int64)
+	for _, fc := range forecast.FilteredCandidates {
+		counts[fc.FilterName]++
+	}
+	w.result.FilteredCandidates = counts
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(w.result.Bottleneck) == 0 || counts[name] > counts[w.result.Bottleneck] {
+			w.result.Bottleneck = name
+		}
+	}
+}
+
+func newSimulateHostResult(h *candidate.HostDesc, count int64) api.SimulateHostResult {
+	ret := api.SimulateHostResult{
+		Id:        h.GetId(),
+		Name:      h.GetName(),
+		Simulated: h.IsSimulated(),
+		Count:     count,
+
+		FreeCPUCount:     h.GetFreeCPUCount(false),
+		FreeMemSize:      h.GetFreeMemSize(false),
+		TotalCPUCount:    h.GetTotalCPUCount(false),
+		TotalMemSize:     h.GetTotalMemSize(false),
+		TotalStorageSize: h.GetTotalLocalStorageSize(false),
+	}
+	if h.Zone != nil {
+		ret.Zone = h.Zone.GetName()
+	}
+	for _, tag := range h.HostSchedtags {
+		ret.Schedtags = append(ret.Schedtags, tag.GetName())
+	}
+	storageTypes := sets.NewString()
+	for _, s := range h.Storages {
+		if s.IsLocal() {
+			storageTypes.Insert(s.StorageType)
+		}
+	}
+	for _, storageType := range storageTypes.List() {
+		free, _ := h.GetFreeStorageSizeOfType(storageType, false)
+		ret.FreeStorageSize += free
+	}
+	return ret
+}
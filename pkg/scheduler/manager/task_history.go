@@ -16,15 +16,19 @@ package manager
 
 import (
 	"container/list"
+	"context"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/util/wait"
 	u "yunion.io/x/pkg/utils"
 
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	computemodels "yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/scheduler/api"
 	"yunion.io/x/onecloud/pkg/scheduler/models"
 	o "yunion.io/x/onecloud/pkg/scheduler/options"
@@ -244,3 +248,88 @@ func (m *HistoryManager) CancelCandidatesPendingUsage(hosts []*expireHost) {
 		}
 	}
 }
+
+// persistHistory records the decision of executor into sched_histories of
+// region database, the in memory history is lost when scheduler restarts
+func persistHistory(task *Task, te *TaskExecutor) {
+	schedInfo := task.SchedInfo
+	if schedInfo.IsSuggestion || te.unit == nil {
+		return
+	}
+
+	history := &computemodels.SSchedHistory{
+		SessionId:  schedInfo.SessionId,
+		Hypervisor: schedInfo.Hypervisor,
+		ProjectId:  schedInfo.Project,
+		Count:      schedInfo.Count,
+		Consuming:  te.Consuming.Milliseconds(),
+	}
+	guestIds := []string{}
+	for _, forGuest := range schedInfo.ForGuests {
+		guestIds = append(guestIds, forGuest.Id)
+	}
+	history.GuestIds = strings.Join(guestIds, ",")
+	request := jsonutils.Marshal(schedInfo.ScheduleInput).(*jsonutils.JSONDict)
+	request.Remove("pending_usages")
+	history.Request = request
+
+	selected := map[string]int{}
+	errs := []string{}
+	if te.resultError != nil {
+		errs = append(errs, te.resultError.Error())
+	} else if te.resultItems != nil && te.resultItems.Result != nil {
+		for _, candi := range te.resultItems.Result.Candidates {
+			if len(candi.Error) > 0 {
+				errs = append(errs, candi.Error)
+				continue
+			}
+			selected[candi.HostId]++
+			if candi.BackupCandidate != nil {
+				selected[candi.BackupCandidate.HostId]++
+			}
+		}
+	}
+	history.Success = len(errs) == 0
+	history.Error = strings.Join(u.Distinct(errs), "; ")
+
+	failed := map[string]*computeapi.SchedHistoryCandidate{}
+	for stage, fcs := range te.unit.FailedCandidateMap {
+		for _, fc := range fcs.Candidates {
+			id := fc.Candidate.IndexKey()
+			hc, ok := failed[id]
+			if !ok {
+				hc = &computeapi.SchedHistoryCandidate{Stage: stage}
+				failed[id] = hc
+			}
+			for _, reason := range fc.Reasons {
+				hc.Reasons = append(hc.Reasons, reason.GetReason())
+			}
+		}
+	}
+	candidates := computeapi.SchedHistoryCandidates{}
+	hostIds := []string{}
+	for _, c := range te.candidates {
+		getter := c.Getter()
+		hc := computeapi.SchedHistoryCandidate{
+			HostId: getter.Id(),
+			Host:   getter.Name(),
+		}
+		if fc, ok := failed[c.IndexKey()]; ok {
+			hc.Stage = fc.Stage
+			hc.Reasons = fc.Reasons
+		} else {
+			hc.Score = te.unit.GetScoreDetails(c.IndexKey())
+		}
+		if cnt, ok := selected[hc.HostId]; ok {
+			hc.Selected = cnt
+			hostIds = append(hostIds, hc.HostId)
+		}
+		candidates = append(candidates, hc)
+	}
+	history.HostIds = strings.Join(hostIds, ",")
+	history.Candidates = &candidates
+
+	if err := computemodels.SchedHistoryManager.Record(context.Background(), history); err != nil {
+		log.Errorf("persist history of session %s: %v", schedInfo.SessionId, err)
+	}
+}
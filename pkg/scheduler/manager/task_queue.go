@@ -28,6 +28,7 @@ import (
 	"yunion.io/x/onecloud/pkg/scheduler/api"
 	"yunion.io/x/onecloud/pkg/scheduler/core"
 	schedmodels "yunion.io/x/onecloud/pkg/scheduler/models"
+	o "yunion.io/x/onecloud/pkg/scheduler/options"
 )
 
 const (
@@ -49,10 +50,13 @@ type TaskExecutor struct {
 	callback  TaskExecuteCallback
 	unit      *core.Unit
 
+	candidates []core.Candidater
+
 	resultItems *core.ScheduleResult
 	resultError error
 	logs        []string
 	capacityMap interface{}
+	extenders   []api.ExtenderResult
 	completed   bool
 }
 
@@ -97,6 +101,7 @@ func (te *TaskExecutor) execute() (*core.ScheduleResult, error) {
 	}
 
 	te.unit = scheduler.Unit()
+	te.candidates = candidates
 	schedInfo := te.unit.SchedInfo
 	helper := GenerateResultHelper(schedInfo)
 	result, err := genericScheduler.Schedule(te.unit, candidates, helper)
@@ -139,6 +144,7 @@ func IsDriverSkipScheduleDirtyMark(driver computemodels.IGuestDriver) bool {
 
 func (te *TaskExecutor) cleanup() {
 	te.unit = nil
+	te.candidates = nil
 	te.scheduler = nil
 	te.callback = nil
 }
@@ -161,6 +167,10 @@ func (te *TaskExecutor) GetCapacityMap() interface{} {
 	return te.capacityMap
 }
 
+func (te *TaskExecutor) GetExtenderResults() []api.ExtenderResult {
+	return te.extenders
+}
+
 type TaskExecutorQueue struct {
 	schedType string
 	queue     chan *TaskExecutor
@@ -376,6 +386,9 @@ func (t *Task) onTaskCompleted(taskExecutor *TaskExecutor) {
 
 	go func() {
 		t.readLog(taskExecutor)
+		if o.GetOptions().SchedulerHistoryPersistent {
+			persistHistory(t, taskExecutor)
+		}
 		taskExecutor.cleanup()
 	}()
 }
@@ -386,6 +399,7 @@ func (t *Task) readLog(taskExecutor *TaskExecutor) {
 		logs := u.LogManager.Read()
 		taskExecutor.logs = logs
 		taskExecutor.capacityMap = u.CapacityMap
+		taskExecutor.extenders = u.GetExtenderResults()
 	}
 }
 
@@ -359,6 +359,27 @@ func (self *SPendingUsage) ToMap() map[string]interface{} {
 	}
 }
 
+// Copy returns a deep copy of usage which can be changed independently
+func (self *SPendingUsage) Copy() *SPendingUsage {
+	u := &SPendingUsage{
+		HostId:         self.HostId,
+		Cpu:            self.Cpu,
+		Memory:         self.Memory,
+		IsolatedDevice: self.IsolatedDevice,
+		DiskUsage:      NewResourcePendingUsage(self.DiskUsage.ToMap()),
+		NetUsage:       NewResourcePendingUsage(self.NetUsage.ToMap()),
+
+		InstanceGroupUsage: make(map[string]*api.CandidateGroup, len(self.InstanceGroupUsage)),
+	}
+	for id, cg := range self.InstanceGroupUsage {
+		u.InstanceGroupUsage[id] = &api.CandidateGroup{
+			SGroup:     cg.SGroup,
+			ReferCount: cg.ReferCount,
+		}
+	}
+	return u
+}
+
 func (self *SPendingUsage) Add(sUsage *SPendingUsage) {
 	self.Cpu = self.Cpu + sUsage.Cpu
 	self.Memory = self.Memory + sUsage.Memory
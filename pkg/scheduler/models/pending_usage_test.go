@@ -17,6 +17,8 @@ package models
 import (
 	"reflect"
 	"testing"
+
+	"yunion.io/x/onecloud/pkg/scheduler/api"
 )
 
 func TestNewResourcePendingUsage(t *testing.T) {
@@ -141,3 +143,19 @@ func TestSResourcePendingUsage_IsEmpty(t *testing.T) {
 		})
 	}
 }
+
+func TestSPendingUsage_Copy(t *testing.T) {
+	u := NewPendingUsageBySchedInfo("h1", nil)
+	u.Cpu = 2
+	u.DiskUsage.Set("local", 1024)
+	u.InstanceGroupUsage["g1"] = &api.CandidateGroup{ReferCount: 1}
+
+	c := u.Copy()
+	c.Add(u)
+	if u.Cpu != 2 || u.DiskUsage.Get("local") != 1024 || u.InstanceGroupUsage["g1"].ReferCount != 1 {
+		t.Errorf("changing copy should not change origin usage: %v", u.ToMap())
+	}
+	if c.Cpu != 4 || c.DiskUsage.Get("local") != 2048 || c.InstanceGroupUsage["g1"].ReferCount != 2 {
+		t.Errorf("copy should be added: %v", c.ToMap())
+	}
+}
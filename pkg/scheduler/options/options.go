@@ -40,6 +40,7 @@ type SchedOptions struct {
 	SchedulerTestLimit          int    `help:"Scheduler test items' limitations" default:"100"`
 	SchedulerHistoryLimit       int    `help:"Scheduler history items' limitations" default:"1000"`
 	SchedulerHistoryCleanPeriod string `help:"Scheduler history cleanup period" default:"60s"`
+	SchedulerHistoryPersistent  bool   `help:"Persist scheduling decisions to sched_histories of region database" default:"true"`
 
 	// parallelization options
 	HostBuildParallelizeSize int `help:"Number of host description build parallelization" default:"14"`
@@ -90,6 +91,12 @@ type SchedOptions struct {
 
 	SkuRefreshInterval string `help:"Server SKU refresh interval" default:"12h"`
 
+	SchedulerExtenderConfigFile string `help:"Path of yaml or json file configuring http scheduler extenders"`
+
+	EnableGuestPreemption bool `help:"Preempt preemptible guests when there is no enough resource for non-preemptible kvm guests" default:"true"`
+
+	EnableCapacityReservation bool `help:"Keep capacity reserved for projects by capacity reservations from guests of other projects" default:"true"`
+
 	OpenstackOptions
 }
 
@@ -38,6 +38,7 @@ import (
 	computemodels "yunion.io/x/onecloud/pkg/compute/models"
 	_ "yunion.io/x/onecloud/pkg/scheduler/algorithmprovider"
 	skuman "yunion.io/x/onecloud/pkg/scheduler/data_manager/sku"
+	"yunion.io/x/onecloud/pkg/scheduler/extender"
 	schedhandler "yunion.io/x/onecloud/pkg/scheduler/handler"
 	schedman "yunion.io/x/onecloud/pkg/scheduler/manager"
 	o "yunion.io/x/onecloud/pkg/scheduler/options"
@@ -52,6 +53,10 @@ func StartService() error {
 	// gin http framework mode configuration
 	gin.SetMode(opts.GinMode)
 
+	if err := extender.Init(opts.SchedulerExtenderConfigFile); err != nil {
+		log.Fatalf("init scheduler extenders: %v", err)
+	}
+
 	startSched := func() {
 		stopEverything := make(chan struct{})
 		go skuman.Start(utils.ToDuration(opts.SkuRefreshInterval))
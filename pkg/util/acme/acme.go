@@ -0,0 +1,579 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package acme implements the subset of ACME (RFC 8555) needed to get
+// certificates issued with http-01 and dns-01 challenges.  Account keys are
+// ECDSA P-256 and requests are signed with ES256
+package acme
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	StatusPending     = "pending"
+	StatusReady       = "ready"
+	StatusProcessing  = "processing"
+	StatusValid       = "valid"
+	StatusInvalid     = "invalid"
+	StatusDeactivated = "deactivated"
+	StatusExpired     = "expired"
+	StatusRevoked     = "revoked"
+
+	ChallengeHTTP01 = "http-01"
+	ChallengeDNS01  = "dns-01"
+
+	// HTTP01ChallengePrefix is the path prefix http-01 challenges are
+	// fetched from by the ACME server
+	HTTP01ChallengePrefix = "/.well-known/acme-challenge/"
+	// DNS01ChallengeLabel is prepended to the domain for TXT records of
+	// dns-01 challenges
+	DNS01ChallengeLabel = "_acme-challenge"
+
+	contentTypeJOSE  = "application/jose+json"
+	problemBadNonce  = "urn:ietf:params:acme:error:badNonce"
+	maxBadNonceRetry = 3
+)
+
+// Problem is the error document returned by ACME servers, RFC 7807
+type Problem struct {
+	Type       string `json:"type"`
+	Detail     string `json:"detail"`
+	Status     int    `json:"status"`
+	HTTPStatus int    `json:"-"`
+}
+
+func (p *Problem) Error() string {
+	return fmt.Sprintf("acme: %d %s: %s", p.HTTPStatus, p.Type, p.Detail)
+}
+
+type Directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+	RevokeCert string `json:"revokeCert"`
+	KeyChange  string `json:"keyChange"`
+}
+
+type Identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type Order struct {
+	URL string `json:"-"`
+
+	Status         string       `json:"status"`
+	Identifiers    []Identifier `json:"identifiers"`
+	Authorizations []string     `json:"authorizations"`
+	Finalize       string       `json:"finalize"`
+	Certificate    string       `json:"certificate"`
+	Error          *Problem     `json:"error"`
+}
+
+type Challenge struct {
+	Type   string   `json:"type"`
+	URL    string   `json:"url"`
+	Status string   `json:"status"`
+	Token  string   `json:"token"`
+	Error  *Problem `json:"error"`
+}
+
+type Authorization struct {
+	URL string `json:"-"`
+
+	Status     string      `json:"status"`
+	Identifier Identifier  `json:"identifier"`
+	Challenges []Challenge `json:"challenges"`
+	Wildcard   bool        `json:"wildcard"`
+}
+
+// Challenge returns the challenge of the type, or nil if the server does
+// not offer it
+func (authz *Authorization) Challenge(typ string) *Challenge {
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == typ {
+			return &authz.Challenges[i]
+		}
+	}
+	return nil
+}
+
+type Client struct {
+	DirectoryURL string
+	Key          *ecdsa.PrivateKey
+	// AccountURL is the key id of the account.  It is set by Register
+	AccountURL string
+
+	HTTPClient   *http.Client
+	PollInterval time.Duration
+
+	dir    *Directory
+	nonces []string
+	mu     sync.Mutex
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) pollInterval() time.Duration {
+	if c.PollInterval > 0 {
+		return c.PollInterval
+	}
+	return 2 * time.Second
+}
+
+func (c *Client) Discover(ctx context.Context) (*Directory, error) {
+	c.mu.Lock()
+	dir := c.dir
+	c.mu.Unlock()
+	if dir != nil {
+		return dir, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, c.DirectoryURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "new directory request")
+	}
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrapf(err, "get directory %s", c.DirectoryURL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	dir = &Directory{}
+	if err := json.NewDecoder(resp.Body).Decode(dir); err != nil {
+		return nil, errors.Wrap(err, "decode directory")
+	}
+	c.mu.Lock()
+	c.dir = dir
+	c.mu.Unlock()
+	return dir, nil
+}
+
+// Register creates the account of the key, or looks up the existing one.
+// The account url is returned and remembered as key id of later requests
+func (c *Client) Register(ctx context.Context, email string) (string, error) {
+	dir, err := c.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	req := map[string]interface{}{
+		"termsOfServiceAgreed": true,
+	}
+	if email != "" {
+		req["contact"] = []string{"mailto:" + email}
+	}
+	resp, err := c.post(ctx, dir.NewAccount, req, true)
+	if err != nil {
+		return "", errors.Wrap(err, "new account")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", responseError(resp)
+	}
+	c.AccountURL = resp.Header.Get("Location")
+	if c.AccountURL == "" {
+		return "", errors.Error("acme: new account response has no location")
+	}
+	return c.AccountURL, nil
+}
+
+func (c *Client) NewOrder(ctx context.Context, domains []string) (*Order, error) {
+	dir, err := c.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]Identifier, len(domains))
+	for i, domain := range domains {
+		ids[i] = Identifier{Type: "dns", Value: domain}
+	}
+	resp, err := c.post(ctx, dir.NewOrder, map[string]interface{}{"identifiers": ids}, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "new order")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return nil, responseError(resp)
+	}
+	order := &Order{URL: resp.Header.Get("Location")}
+	if err := json.NewDecoder(resp.Body).Decode(order); err != nil {
+		return nil, errors.Wrap(err, "decode order")
+	}
+	return order, nil
+}
+
+// postAsGet fetches the resource at url with POST-as-GET and decodes it
+// into v
+func (c *Client) postAsGet(ctx context.Context, url string, v interface{}) error {
+	resp, err := c.post(ctx, url, nil, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.Wrapf(err, "decode %s", url)
+	}
+	return nil
+}
+
+func (c *Client) GetOrder(ctx context.Context, url string) (*Order, error) {
+	order := &Order{URL: url}
+	if err := c.postAsGet(ctx, url, order); err != nil {
+		return nil, errors.Wrap(err, "get order")
+	}
+	return order, nil
+}
+
+func (c *Client) GetAuthorization(ctx context.Context, url string) (*Authorization, error) {
+	authz := &Authorization{URL: url}
+	if err := c.postAsGet(ctx, url, authz); err != nil {
+		return nil, errors.Wrap(err, "get authorization")
+	}
+	return authz, nil
+}
+
+// Accept tells the server the challenge is ready for validation
+func (c *Client) Accept(ctx context.Context, chal *Challenge) error {
+	resp, err := c.post(ctx, chal.URL, struct{}{}, false)
+	if err != nil {
+		return errors.Wrap(err, "accept challenge")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// WaitAuthorization polls the authorization until it is valid.  Invalid
+// authorizations yield the error of the failed challenge
+func (c *Client) WaitAuthorization(ctx context.Context, url string) (*Authorization, error) {
+	for {
+		authz, err := c.GetAuthorization(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		switch authz.Status {
+		case StatusValid:
+			return authz, nil
+		case StatusPending, StatusProcessing:
+		default:
+			for i := range authz.Challenges {
+				if authz.Challenges[i].Error != nil {
+					return nil, errors.Wrapf(authz.Challenges[i].Error, "authorization of %s %s", authz.Identifier.Value, authz.Status)
+				}
+			}
+			return nil, errors.Errorf("acme: authorization of %s %s", authz.Identifier.Value, authz.Status)
+		}
+		if err := c.sleep(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// WaitOrder polls the order until it is no longer pending or processing
+func (c *Client) WaitOrder(ctx context.Context, url string) (*Order, error) {
+	for {
+		order, err := c.GetOrder(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		switch order.Status {
+		case StatusPending, StatusProcessing:
+		case StatusInvalid:
+			if order.Error != nil {
+				return nil, errors.Wrap(order.Error, "order invalid")
+			}
+			return nil, errors.Error("acme: order invalid")
+		default:
+			return order, nil
+		}
+		if err := c.sleep(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Finalize submits the csr in DER form and waits for the certificate to
+// be issued
+func (c *Client) Finalize(ctx context.Context, order *Order, csr []byte) (*Order, error) {
+	req := map[string]string{
+		"csr": base64.RawURLEncoding.EncodeToString(csr),
+	}
+	resp, err := c.post(ctx, order.Finalize, req, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "finalize order")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	order, err = c.WaitOrder(ctx, order.URL)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != StatusValid {
+		return nil, errors.Errorf("acme: order %s after finalization", order.Status)
+	}
+	return order, nil
+}
+
+// FetchCert downloads the PEM encoded certificate chain
+func (c *Client) FetchCert(ctx context.Context, url string) ([]byte, error) {
+	resp, err := c.post(ctx, url, nil, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch certificate")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read certificate")
+	}
+	return data, nil
+}
+
+func (c *Client) sleep(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(c.pollInterval()):
+		return nil
+	}
+}
+
+// KeyAuthorization returns the key authorization of the challenge token
+func (c *Client) KeyAuthorization(token string) (string, error) {
+	thumb, err := JWKThumbprint(&c.Key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return token + "." + thumb, nil
+}
+
+// DNS01ChallengeRecord returns the TXT record value of the dns-01
+// challenge token
+func (c *Client) DNS01ChallengeRecord(token string) (string, error) {
+	ka, err := c.KeyAuthorization(token)
+	if err != nil {
+		return "", err
+	}
+	d := sha256.Sum256([]byte(ka))
+	return base64.RawURLEncoding.EncodeToString(d[:]), nil
+}
+
+func HTTP01ChallengePath(token string) string {
+	return HTTP01ChallengePrefix + token
+}
+
+func (c *Client) fetchNonce(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	if n := len(c.nonces); n > 0 {
+		nonce := c.nonces[n-1]
+		c.nonces = c.nonces[:n-1]
+		c.mu.Unlock()
+		return nonce, nil
+	}
+	c.mu.Unlock()
+
+	dir, err := c.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodHead, dir.NewNonce, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "new nonce request")
+	}
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "new nonce")
+	}
+	resp.Body.Close()
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.Error("acme: no nonce returned")
+	}
+	return nonce, nil
+}
+
+func (c *Client) saveNonce(resp *http.Response) {
+	if nonce := resp.Header.Get("Replay-Nonce"); nonce != "" {
+		c.mu.Lock()
+		c.nonces = append(c.nonces, nonce)
+		c.mu.Unlock()
+	}
+}
+
+// post sends JWS signed request.  Payload nil makes a POST-as-GET request.
+// Requests are signed with jwk instead of kid if useJWK is set, which is
+// required for new account requests.  Requests rejected for bad nonce are
+// retried
+func (c *Client) post(ctx context.Context, url string, payload interface{}, useJWK bool) (*http.Response, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal payload")
+		}
+	}
+	for retry := 0; ; retry++ {
+		nonce, err := c.fetchNonce(ctx)
+		if err != nil {
+			return nil, err
+		}
+		kid := c.AccountURL
+		if useJWK {
+			kid = ""
+		}
+		jws, err := signJWS(c.Key, kid, nonce, url, body)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(jws))
+		if err != nil {
+			return nil, errors.Wrap(err, "new request")
+		}
+		req.Header.Set("Content-Type", contentTypeJOSE)
+		resp, err := c.httpClient().Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, errors.Wrapf(err, "post %s", url)
+		}
+		c.saveNonce(resp)
+		if resp.StatusCode == http.StatusBadRequest && retry < maxBadNonceRetry {
+			err := responseError(resp)
+			if p, ok := err.(*Problem); ok && p.Type == problemBadNonce {
+				continue
+			}
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// responseError consumes the body of the failed response
+func responseError(resp *http.Response) error {
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	p := &Problem{}
+	if err := json.Unmarshal(data, p); err != nil || p.Type == "" {
+		p.Type = "unknown"
+		p.Detail = string(data)
+	}
+	p.HTTPStatus = resp.StatusCode
+	return p
+}
+
+type jwk struct {
+	Crv string `json:"crv"`
+	Kty string `json:"kty"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func ecJWK(pub *ecdsa.PublicKey) (*jwk, error) {
+	if pub.Curve.Params().Name != "P-256" {
+		return nil, errors.Errorf("acme: unsupported curve %s", pub.Curve.Params().Name)
+	}
+	return &jwk{
+		Crv: "P-256",
+		Kty: "EC",
+		X:   base64.RawURLEncoding.EncodeToString(padBytes(pub.X, 32)),
+		Y:   base64.RawURLEncoding.EncodeToString(padBytes(pub.Y, 32)),
+	}, nil
+}
+
+// JWKThumbprint computes the thumbprint of the key, RFC 7638
+func JWKThumbprint(pub *ecdsa.PublicKey) (string, error) {
+	k, err := ecJWK(pub)
+	if err != nil {
+		return "", err
+	}
+	// members in lexicographic order without white space
+	s := fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, k.Crv, k.Kty, k.X, k.Y)
+	d := sha256.Sum256([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(d[:]), nil
+}
+
+func padBytes(v *big.Int, size int) []byte {
+	b := v.Bytes()
+	if len(b) >= size {
+		return b
+	}
+	ret := make([]byte, size)
+	copy(ret[size-len(b):], b)
+	return ret
+}
+
+func signJWS(key *ecdsa.PrivateKey, kid, nonce, url string, payload []byte) ([]byte, error) {
+	protected := map[string]interface{}{
+		"alg":   "ES256",
+		"nonce": nonce,
+		"url":   url,
+	}
+	if kid != "" {
+		protected["kid"] = kid
+	} else {
+		k, err := ecJWK(&key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		protected["jwk"] = k
+	}
+	hdr, err := json.Marshal(protected)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal protected header")
+	}
+	var (
+		hdr64     = base64.RawURLEncoding.EncodeToString(hdr)
+		payload64 = base64.RawURLEncoding.EncodeToString(payload)
+		d         = sha256.Sum256([]byte(hdr64 + "." + payload64))
+	)
+	r, s, err := ecdsa.Sign(rand.Reader, key, d[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "sign")
+	}
+	sig := append(padBytes(r, 32), padBytes(s, 32)...)
+	return json.Marshal(map[string]string{
+		"protected": hdr64,
+		"payload":   payload64,
+		"signature": base64.RawURLEncoding.EncodeToString(sig),
+	})
+}
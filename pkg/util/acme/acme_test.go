@@ -0,0 +1,275 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a minimal acme server validating signatures, nonces and
+// key ids.  Challenges turn valid once accepted
+type fakeServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	nonce      int
+	nonces     map[string]bool
+	accountKey *ecdsa.PublicKey
+	badNonce   bool
+	authzValid bool
+	finalized  bool
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{nonces: map[string]bool{}}
+	mux := http.NewServeMux()
+	s.Server = httptest.NewServer(mux)
+	mux.HandleFunc("/dir", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Directory{
+			NewNonce:   s.URL + "/nonce",
+			NewAccount: s.URL + "/account",
+			NewOrder:   s.URL + "/order",
+		})
+	})
+	mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {
+		s.setNonce(w)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		payload, ok := s.verify(t, w, r)
+		if !ok {
+			return
+		}
+		s.setNonce(w)
+		switch r.URL.Path {
+		case "/account":
+			w.Header().Set("Location", s.URL+"/acct/1")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"status":"valid"}`))
+		case "/order":
+			w.Header().Set("Location", s.URL+"/order/1")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(s.order())
+		case "/order/1":
+			json.NewEncoder(w).Encode(s.order())
+		case "/authz/1":
+			status := StatusPending
+			if s.authzValid {
+				status = StatusValid
+			}
+			json.NewEncoder(w).Encode(Authorization{
+				Status:     status,
+				Identifier: Identifier{Type: "dns", Value: "www.example.com"},
+				Challenges: []Challenge{
+					{Type: ChallengeHTTP01, URL: s.URL + "/chal/1", Status: status, Token: "tok1"},
+					{Type: ChallengeDNS01, URL: s.URL + "/chal/2", Status: status, Token: "tok2"},
+				},
+			})
+		case "/chal/1":
+			s.authzValid = true
+			w.Write([]byte(`{"status":"processing"}`))
+		case "/finalize":
+			req := map[string]string{}
+			json.Unmarshal(payload, &req)
+			der, _ := base64.RawURLEncoding.DecodeString(req["csr"])
+			csr, err := x509.ParseCertificateRequest(der)
+			if err != nil || csr.CheckSignature() != nil || csr.Subject.CommonName != "www.example.com" {
+				t.Errorf("bad csr: %v", err)
+			}
+			s.finalized = true
+			json.NewEncoder(w).Encode(s.order())
+		case "/cert/1":
+			w.Write([]byte("-----BEGIN CERTIFICATE-----\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return s
+}
+
+func (s *fakeServer) order() Order {
+	status := StatusPending
+	if s.authzValid {
+		status = StatusReady
+	}
+	o := Order{
+		Status:         status,
+		Identifiers:    []Identifier{{Type: "dns", Value: "www.example.com"}},
+		Authorizations: []string{s.URL + "/authz/1"},
+		Finalize:       s.URL + "/finalize",
+	}
+	if s.finalized {
+		o.Status = StatusValid
+		o.Certificate = s.URL + "/cert/1"
+	}
+	return o
+}
+
+func (s *fakeServer) setNonce(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nonce++
+	nonce := fmt.Sprintf("nonce-%d", s.nonce)
+	s.nonces[nonce] = true
+	w.Header().Set("Replay-Nonce", nonce)
+}
+
+func (s *fakeServer) verify(t *testing.T, w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jws := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		t.Errorf("decode jws: %v", err)
+		return nil, false
+	}
+	hdrJSON, _ := base64.RawURLEncoding.DecodeString(jws["protected"])
+	hdr := struct {
+		Alg   string `json:"alg"`
+		Nonce string `json:"nonce"`
+		URL   string `json:"url"`
+		Kid   string `json:"kid"`
+		Jwk   *jwk   `json:"jwk"`
+	}{}
+	json.Unmarshal(hdrJSON, &hdr)
+	if hdr.URL != s.URL+r.URL.Path {
+		t.Errorf("url %s in header, request to %s", hdr.URL, r.URL.Path)
+	}
+	if s.badNonce || !s.nonces[hdr.Nonce] {
+		s.badNonce = false
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"type":%q}`, problemBadNonce)
+		return nil, false
+	}
+	delete(s.nonces, hdr.Nonce)
+
+	var pub *ecdsa.PublicKey
+	if r.URL.Path == "/account" {
+		x, _ := base64.RawURLEncoding.DecodeString(hdr.Jwk.X)
+		y, _ := base64.RawURLEncoding.DecodeString(hdr.Jwk.Y)
+		pub = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		s.accountKey = pub
+	} else {
+		if hdr.Kid != s.URL+"/acct/1" {
+			t.Errorf("request to %s with kid %q", r.URL.Path, hdr.Kid)
+		}
+		pub = s.accountKey
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(jws["signature"])
+	d := sha256.Sum256([]byte(jws["protected"] + "." + jws["payload"]))
+	if len(sig) != 64 || !ecdsa.Verify(pub, d[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		t.Errorf("bad signature of request to %s", r.URL.Path)
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(jws["payload"])
+	return payload, true
+}
+
+func TestClientIssue(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.Close()
+
+	key, err := GenerateAccountKey()
+	if err != nil {
+		t.Fatalf("GenerateAccountKey: %v", err)
+	}
+	c := &Client{
+		DirectoryURL: s.URL + "/dir",
+		Key:          key,
+		PollInterval: time.Millisecond,
+	}
+	ctx := context.Background()
+	acct, err := c.Register(ctx, "admin@example.com")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if acct != s.URL+"/acct/1" {
+		t.Errorf("account url %s", acct)
+	}
+
+	// a stale nonce should be retried transparently
+	s.badNonce = true
+	order, err := c.NewOrder(ctx, []string{"www.example.com"})
+	if err != nil {
+		t.Fatalf("NewOrder: %v", err)
+	}
+	authz, err := c.GetAuthorization(ctx, order.Authorizations[0])
+	if err != nil {
+		t.Fatalf("GetAuthorization: %v", err)
+	}
+	chal := authz.Challenge(ChallengeHTTP01)
+	if chal == nil {
+		t.Fatalf("no http-01 challenge")
+	}
+	if err := c.Accept(ctx, chal); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if _, err := c.WaitAuthorization(ctx, authz.URL); err != nil {
+		t.Fatalf("WaitAuthorization: %v", err)
+	}
+	csr, keyPEM, err := NewCertificateRequest([]string{"www.example.com"})
+	if err != nil || keyPEM == "" {
+		t.Fatalf("NewCertificateRequest: %v", err)
+	}
+	order, err = c.Finalize(ctx, order, csr)
+	if err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+	cert, err := c.FetchCert(ctx, order.Certificate)
+	if err != nil || len(cert) == 0 {
+		t.Fatalf("FetchCert: %v", err)
+	}
+}
+
+func TestChallengeValues(t *testing.T) {
+	key, _ := GenerateAccountKey()
+	c := &Client{Key: key}
+	thumb, err := JWKThumbprint(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("JWKThumbprint: %v", err)
+	}
+	ka, err := c.KeyAuthorization("tok")
+	if err != nil || ka != "tok."+thumb {
+		t.Errorf("KeyAuthorization = %s, %v", ka, err)
+	}
+	txt, _ := c.DNS01ChallengeRecord("tok")
+	d := sha256.Sum256([]byte(ka))
+	if txt != base64.RawURLEncoding.EncodeToString(d[:]) {
+		t.Errorf("DNS01ChallengeRecord = %s", txt)
+	}
+	if p := HTTP01ChallengePath("tok"); p != "/.well-known/acme-challenge/tok" {
+		t.Errorf("HTTP01ChallengePath = %s", p)
+	}
+
+	s, err := MarshalAccountKey(key)
+	if err != nil {
+		t.Fatalf("MarshalAccountKey: %v", err)
+	}
+	key2, err := ParseAccountKey(s)
+	if err != nil || key2.X.Cmp(key.X) != 0 {
+		t.Errorf("ParseAccountKey: %v", err)
+	}
+}
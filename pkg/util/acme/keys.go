@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const certKeyBits = 2048
+
+func GenerateAccountKey() (*ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate ecdsa key")
+	}
+	return key, nil
+}
+
+func MarshalAccountKey(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", errors.Wrap(err, "marshal ecdsa key")
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+}
+
+func ParseAccountKey(s string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.Error("acme: no pem block in account key")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse ecdsa key")
+	}
+	return key, nil
+}
+
+// NewCertificateRequest generates a RSA key for the certificate and the csr
+// of domains in DER form.  The first domain is used as the common name
+func NewCertificateRequest(domains []string) (csr []byte, keyPEM string, err error) {
+	if len(domains) == 0 {
+		return nil, "", errors.Error("acme: no domains for certificate")
+	}
+	key, err := rsa.GenerateKey(rand.Reader, certKeyBits)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "generate rsa key")
+	}
+	tmpl := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}
+	csr, err = x509.CreateCertificateRequest(rand.Reader, tmpl, key)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "create certificate request")
+	}
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+	return csr, keyPEM, nil
+}
@@ -17,6 +17,7 @@ package cephutils
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -466,3 +467,58 @@ func (self *SSnapshot) Clone(pool, name string) error {
 	opts = append(opts, []string{"clone", self.GetName(), fmt.Sprintf("%s/%s", pool, name)}...)
 	return self.image.client.run("rbd", opts)
 }
+
+// ImportImage creates the image from the raw data read from reader
+func (self *CephClient) ImportImage(name string, reader io.Reader) (*SImage, error) {
+	image := &SImage{name: name, client: self}
+	opts := self.options()
+	opts = append(opts, []string{"import", "-", image.GetName()}...)
+	cmd := procutils.NewRemoteCommandAsFarAsPossible("rbd", opts...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.Wrapf(err, "StdinPipe")
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "start rbd import")
+	}
+	_, copyErr := io.Copy(stdin, reader)
+	stdin.Close()
+	err = cmd.Wait()
+	if copyErr != nil {
+		return nil, errors.Wrapf(copyErr, "copy to rbd import")
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "rbd import %s", image.GetName())
+	}
+	return image, nil
+}
+
+type exportReader struct {
+	io.ReadCloser
+	cmd *procutils.Command
+}
+
+func (r *exportReader) Close() error {
+	r.ReadCloser.Close()
+	// the export is interrupted if the reader is closed before EOF
+	r.cmd.Kill()
+	r.cmd.Wait()
+	return nil
+}
+
+// Export streams the raw data of the image
+func (self *SImage) Export() (io.ReadCloser, error) {
+	opts := self.options()
+	opts = append(opts, []string{"export", "--no-progress", self.GetName(), "-"}...)
+	cmd := procutils.NewRemoteCommandAsFarAsPossible("rbd", opts...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrapf(err, "StdoutPipe")
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "start rbd export")
+	}
+	return &exportReader{ReadCloser: stdout, cmd: cmd}, nil
+}
@@ -594,6 +594,7 @@ func CgroupIoHardlimitSet(
 }
 
 func CgroupDestroy(pid string) bool {
+	UnpinProcess(pid)
 	tasks := []ICGroupTask{
 		&CGroupCPUTask{&CGroupTask{}},
 		&CGroupIOTask{&CGroupTask{}},
@@ -16,9 +16,11 @@ package cgrouputils
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 
 	"yunion.io/x/log"
@@ -35,6 +37,10 @@ var (
 	rebalanceProcessesLock    = sync.Mutex{}
 	rebalanceProcessesRunning = false
 
+	// processes pinned to numa nodes, skipped by rebalance
+	pinnedProcesses     = map[string]string{}
+	pinnedProcessesLock = sync.Mutex{}
+
 	utilHistory map[string][]float64
 )
 
@@ -193,6 +199,9 @@ func GetProcessesCpuinfo(pids []string) ([]*ProcessCPUinfo, error) {
 			log.Errorln(err)
 			return nil, err
 		}
+		if IsProcessPinned(pid) {
+			continue
+		}
 		if info.Share != nil && *info.Share < float64(coreCnt) {
 			ret = append(ret, info)
 		}
@@ -250,3 +259,37 @@ func SaveHistoryUtil() {
 		fileutils2.FilePutContents(utilHistoryFile, string(content), false)
 	}
 }
+
+// GetNumaNodeCpuset returns cpu list of numa node, e.g. 0-15,32-47
+func GetNumaNodeCpuset(node int) (string, error) {
+	content, err := fileutils2.FileGetContents(fmt.Sprintf("/sys/devices/system/node/node%d/cpulist", node))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(content), nil
+}
+
+// PinProcessCpuset binds process to cpuset, and keeps rebalance from moving it
+func PinProcessCpuset(pid string, cpuset string) bool {
+	task := NewCGroupCPUSetTask(pid, 0, cpuset)
+	if !task.SetTask() {
+		return false
+	}
+	pinnedProcessesLock.Lock()
+	defer pinnedProcessesLock.Unlock()
+	pinnedProcesses[pid] = cpuset
+	return true
+}
+
+func UnpinProcess(pid string) {
+	pinnedProcessesLock.Lock()
+	defer pinnedProcessesLock.Unlock()
+	delete(pinnedProcesses, pid)
+}
+
+func IsProcessPinned(pid string) bool {
+	pinnedProcessesLock.Lock()
+	defer pinnedProcessesLock.Unlock()
+	_, ok := pinnedProcesses[pid]
+	return ok
+}
@@ -0,0 +1,350 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// DHCPv6 message types, RFC 8415 section 7.3
+type DHCPv6MessageType uint8
+
+const (
+	DHCPv6Solicit            DHCPv6MessageType = 1
+	DHCPv6Advertise          DHCPv6MessageType = 2
+	DHCPv6Request            DHCPv6MessageType = 3
+	DHCPv6Confirm            DHCPv6MessageType = 4
+	DHCPv6Renew              DHCPv6MessageType = 5
+	DHCPv6Rebind             DHCPv6MessageType = 6
+	DHCPv6Reply              DHCPv6MessageType = 7
+	DHCPv6Release            DHCPv6MessageType = 8
+	DHCPv6Decline            DHCPv6MessageType = 9
+	DHCPv6InformationRequest DHCPv6MessageType = 11
+)
+
+// DHCPv6 option codes, RFC 8415 section 21 and RFC 3646
+type DHCPv6OptionCode uint16
+
+const (
+	DHCPv6OptClientID     DHCPv6OptionCode = 1
+	DHCPv6OptServerID     DHCPv6OptionCode = 2
+	DHCPv6OptIANA         DHCPv6OptionCode = 3
+	DHCPv6OptIAAddr       DHCPv6OptionCode = 5
+	DHCPv6OptORO          DHCPv6OptionCode = 6
+	DHCPv6OptPreference   DHCPv6OptionCode = 7
+	DHCPv6OptElapsedTime  DHCPv6OptionCode = 8
+	DHCPv6OptStatusCode   DHCPv6OptionCode = 13
+	DHCPv6OptRapidCommit  DHCPv6OptionCode = 14
+	DHCPv6OptDNSServers   DHCPv6OptionCode = 23
+	DHCPv6OptDomainSearch DHCPv6OptionCode = 24
+)
+
+const (
+	DHCPv6StatusSuccess   uint16 = 0
+	DHCPv6StatusNoAddrs   uint16 = 2
+	DHCPv6StatusNotOnLink uint16 = 4
+
+	DHCPv6ServerPort = 547
+	DHCPv6ClientPort = 546
+)
+
+var (
+	// All_DHCP_Relay_Agents_and_Servers
+	DHCPv6AllServers = net.ParseIP("ff02::1:2")
+
+	ErrDHCPv6Truncated = errors.Error("truncated dhcpv6 packet")
+)
+
+type DHCPv6Option struct {
+	Code DHCPv6OptionCode
+	Data []byte
+}
+
+type DHCPv6Packet struct {
+	Type          DHCPv6MessageType
+	TransactionId [3]byte
+	Options       []DHCPv6Option
+}
+
+func parseDHCPv6Options(b []byte) ([]DHCPv6Option, error) {
+	var opts []DHCPv6Option
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, ErrDHCPv6Truncated
+		}
+		code := binary.BigEndian.Uint16(b[0:2])
+		l := int(binary.BigEndian.Uint16(b[2:4]))
+		if len(b) < 4+l {
+			return nil, ErrDHCPv6Truncated
+		}
+		opts = append(opts, DHCPv6Option{
+			Code: DHCPv6OptionCode(code),
+			Data: b[4 : 4+l],
+		})
+		b = b[4+l:]
+	}
+	return opts, nil
+}
+
+func marshalDHCPv6Options(opts []DHCPv6Option) []byte {
+	var b []byte
+	for _, opt := range opts {
+		hdr := make([]byte, 4)
+		binary.BigEndian.PutUint16(hdr[0:2], uint16(opt.Code))
+		binary.BigEndian.PutUint16(hdr[2:4], uint16(len(opt.Data)))
+		b = append(b, hdr...)
+		b = append(b, opt.Data...)
+	}
+	return b
+}
+
+// ParseDHCPv6Packet decodes client/server messages.  Relay messages are not
+// supported
+func ParseDHCPv6Packet(b []byte) (*DHCPv6Packet, error) {
+	if len(b) < 4 {
+		return nil, ErrDHCPv6Truncated
+	}
+	pkt := &DHCPv6Packet{
+		Type: DHCPv6MessageType(b[0]),
+	}
+	copy(pkt.TransactionId[:], b[1:4])
+	opts, err := parseDHCPv6Options(b[4:])
+	if err != nil {
+		return nil, err
+	}
+	pkt.Options = opts
+	return pkt, nil
+}
+
+func (pkt *DHCPv6Packet) Marshal() []byte {
+	b := []byte{byte(pkt.Type)}
+	b = append(b, pkt.TransactionId[:]...)
+	return append(b, marshalDHCPv6Options(pkt.Options)...)
+}
+
+func (pkt *DHCPv6Packet) GetOption(code DHCPv6OptionCode) []byte {
+	for _, opt := range pkt.Options {
+		if opt.Code == code {
+			return opt.Data
+		}
+	}
+	return nil
+}
+
+func (pkt *DHCPv6Packet) HasOption(code DHCPv6OptionCode) bool {
+	for _, opt := range pkt.Options {
+		if opt.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (pkt *DHCPv6Packet) AddOption(code DHCPv6OptionCode, data []byte) {
+	pkt.Options = append(pkt.Options, DHCPv6Option{Code: code, Data: data})
+}
+
+func (pkt *DHCPv6Packet) String() string {
+	var codes []string
+	for _, opt := range pkt.Options {
+		codes = append(codes, fmt.Sprintf("%d", opt.Code))
+	}
+	return fmt.Sprintf("type=%d xid=%x options=[%s]", pkt.Type, pkt.TransactionId, strings.Join(codes, ","))
+}
+
+// MakeDUIDLL returns DUID based on link-layer address, RFC 8415 section 11.4
+func MakeDUIDLL(mac net.HardwareAddr) []byte {
+	b := []byte{0, 3, 0, 1}
+	return append(b, mac...)
+}
+
+// MacFromDUID extracts ethernet address from DUID-LLT and DUID-LL
+func MacFromDUID(duid []byte) (net.HardwareAddr, error) {
+	if len(duid) < 4 {
+		return nil, errors.Wrap(ErrDHCPv6Truncated, "duid")
+	}
+	var (
+		duidType = binary.BigEndian.Uint16(duid[0:2])
+		hwType   = binary.BigEndian.Uint16(duid[2:4])
+		off      int
+	)
+	switch duidType {
+	case 1:
+		off = 8
+	case 3:
+		off = 4
+	default:
+		return nil, errors.Errorf("duid type %d has no link-layer address", duidType)
+	}
+	if hwType != 1 || len(duid) != off+6 {
+		return nil, errors.Errorf("duid hardware type %d is not ethernet", hwType)
+	}
+	return net.HardwareAddr(duid[off : off+6]), nil
+}
+
+type DHCPv6Config struct {
+	ClientIP    net.IP
+	DNSServers  []net.IP
+	Domain      string
+	LeaseTime   time.Duration
+	RenewalTime time.Duration
+}
+
+func dhcpv6Seconds(d time.Duration) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(d/time.Second))
+	return b
+}
+
+func dhcpv6StatusCode(code uint16, msg string) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, code)
+	return append(b, []byte(msg)...)
+}
+
+// encodeDomainSearch encodes domain names in DNS wire format without
+// compression, RFC 1035 section 3.1
+func encodeDomainSearch(domain string) []byte {
+	var b []byte
+	for _, label := range strings.Split(strings.Trim(domain, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, []byte(label)...)
+	}
+	return append(b, 0)
+}
+
+// makeIANA returns IA_NA option of the request with the address assigned.
+// Requests without IA_NA yield nil
+func makeIANA(req *DHCPv6Packet, conf *DHCPv6Config) []byte {
+	reqIA := req.GetOption(DHCPv6OptIANA)
+	if len(reqIA) < 12 {
+		return nil
+	}
+	var (
+		valid = conf.LeaseTime
+		t1    = conf.RenewalTime
+		t2    = valid * 4 / 5
+	)
+	if t1 <= 0 || t1 > t2 {
+		t1 = valid / 2
+	}
+
+	addr := make([]byte, 0, 24)
+	addr = append(addr, conf.ClientIP.To16()...)
+	addr = append(addr, dhcpv6Seconds(valid)...)
+	addr = append(addr, dhcpv6Seconds(valid)...)
+
+	ia := make([]byte, 0, 12+4+len(addr))
+	ia = append(ia, reqIA[0:4]...) // IAID
+	ia = append(ia, dhcpv6Seconds(t1)...)
+	ia = append(ia, dhcpv6Seconds(t2)...)
+	ia = append(ia, marshalDHCPv6Options([]DHCPv6Option{
+		{Code: DHCPv6OptIAAddr, Data: addr},
+	})...)
+	return ia
+}
+
+// confirmOnLink reports whether addresses in IA_NA of the confirm message
+// match the assigned one
+func confirmOnLink(req *DHCPv6Packet, conf *DHCPv6Config) bool {
+	reqIA := req.GetOption(DHCPv6OptIANA)
+	if len(reqIA) < 12 {
+		return false
+	}
+	opts, err := parseDHCPv6Options(reqIA[12:])
+	if err != nil {
+		return false
+	}
+	for _, opt := range opts {
+		if opt.Code != DHCPv6OptIAAddr || len(opt.Data) < 16 {
+			continue
+		}
+		if !net.IP(opt.Data[:16]).Equal(conf.ClientIP) {
+			return false
+		}
+	}
+	return true
+}
+
+// MakeDHCPv6ReplyPacket makes advertise or reply message for the client
+// request.  Nil is returned if the request should be ignored
+func MakeDHCPv6ReplyPacket(req *DHCPv6Packet, serverId []byte, conf *DHCPv6Config) *DHCPv6Packet {
+	clientId := req.GetOption(DHCPv6OptClientID)
+	if clientId == nil {
+		return nil
+	}
+	if reqServerId := req.GetOption(DHCPv6OptServerID); reqServerId != nil {
+		if string(reqServerId) != string(serverId) {
+			return nil
+		}
+	}
+	resp := &DHCPv6Packet{
+		Type:          DHCPv6Reply,
+		TransactionId: req.TransactionId,
+	}
+	resp.AddOption(DHCPv6OptServerID, serverId)
+	resp.AddOption(DHCPv6OptClientID, clientId)
+
+	switch req.Type {
+	case DHCPv6Solicit:
+		if req.HasOption(DHCPv6OptRapidCommit) {
+			resp.AddOption(DHCPv6OptRapidCommit, []byte{})
+		} else {
+			resp.Type = DHCPv6Advertise
+			resp.AddOption(DHCPv6OptPreference, []byte{255})
+		}
+		fallthrough
+	case DHCPv6Request, DHCPv6Renew, DHCPv6Rebind:
+		if ia := makeIANA(req, conf); ia != nil {
+			resp.AddOption(DHCPv6OptIANA, ia)
+		} else {
+			resp.AddOption(DHCPv6OptStatusCode, dhcpv6StatusCode(DHCPv6StatusNoAddrs, "no IA_NA requested"))
+		}
+	case DHCPv6Confirm:
+		if confirmOnLink(req, conf) {
+			resp.AddOption(DHCPv6OptStatusCode, dhcpv6StatusCode(DHCPv6StatusSuccess, "all addresses on link"))
+		} else {
+			resp.AddOption(DHCPv6OptStatusCode, dhcpv6StatusCode(DHCPv6StatusNotOnLink, "address not on link"))
+		}
+		return resp
+	case DHCPv6Release, DHCPv6Decline:
+		resp.AddOption(DHCPv6OptStatusCode, dhcpv6StatusCode(DHCPv6StatusSuccess, ""))
+		return resp
+	case DHCPv6InformationRequest:
+	default:
+		return nil
+	}
+
+	if len(conf.DNSServers) > 0 {
+		var dns []byte
+		for _, ip := range conf.DNSServers {
+			dns = append(dns, ip.To16()...)
+		}
+		resp.AddOption(DHCPv6OptDNSServers, dns)
+	}
+	if conf.Domain != "" {
+		resp.AddOption(DHCPv6OptDomainSearch, encodeDomainSearch(conf.Domain))
+	}
+	return resp
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp
+
+import (
+	"net"
+	"runtime/debug"
+
+	"golang.org/x/net/ipv6"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+)
+
+type DHCPv6Handler interface {
+	// ServeDHCPv6 returns config of the client with the mac, or nil if the
+	// client is not served by us
+	ServeDHCPv6(mac net.HardwareAddr) (*DHCPv6Config, error)
+}
+
+// DHCPv6Server serves stateful DHCPv6 on one interface.  Servers of
+// different interfaces share the multicast address and drop packets not
+// received from their own interface
+type DHCPv6Server struct {
+	iface    *net.Interface
+	conn     *ipv6.PacketConn
+	serverId []byte
+}
+
+func NewDHCPv6Server(iface string) (*DHCPv6Server, error) {
+	intf, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, errors.Wrapf(err, "InterfaceByName %s", iface)
+	}
+	udpConn, err := net.ListenMulticastUDP("udp6", intf, &net.UDPAddr{
+		IP:   DHCPv6AllServers,
+		Port: DHCPv6ServerPort,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "listen dhcpv6 on %s", iface)
+	}
+	conn := ipv6.NewPacketConn(udpConn)
+	if err := conn.SetControlMessage(ipv6.FlagInterface, true); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "SetControlMessage")
+	}
+	return &DHCPv6Server{
+		iface:    intf,
+		conn:     conn,
+		serverId: MakeDUIDLL(intf.HardwareAddr),
+	}, nil
+}
+
+// clientMac identifies the client by EUI-64 link local source address first
+// as DUID may be generated from another interface of the client
+func dhcpv6ClientMac(pkt *DHCPv6Packet, src net.IP) (net.HardwareAddr, error) {
+	if macStr, err := netutils2.MacFromIPv6LinkLocal(src); err == nil {
+		return net.ParseMAC(macStr)
+	}
+	return MacFromDUID(pkt.GetOption(DHCPv6OptClientID))
+}
+
+func (s *DHCPv6Server) ListenAndServe(handler DHCPv6Handler) error {
+	defer s.conn.Close()
+	buf := make([]byte, 1500)
+	for {
+		n, cm, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return errors.Wrap(err, "read dhcpv6 packet")
+		}
+		if cm != nil && cm.IfIndex != s.iface.Index {
+			continue
+		}
+		src, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		pkt, err := ParseDHCPv6Packet(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			log.Debugf("[DHCPv6] bad packet from %s: %v", src, err)
+			continue
+		}
+		go s.serve(handler, pkt, src)
+	}
+}
+
+func (s *DHCPv6Server) serve(handler DHCPv6Handler, pkt *DHCPv6Packet, src *net.UDPAddr) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Serve panic error: %v", r)
+			debug.PrintStack()
+		}
+	}()
+
+	mac, err := dhcpv6ClientMac(pkt, src.IP)
+	if err != nil {
+		log.Debugf("[DHCPv6] unknown client %s: %v", src, err)
+		return
+	}
+	conf, err := handler.ServeDHCPv6(mac)
+	if err != nil {
+		log.Warningf("[DHCPv6] handler serve error: %v", err)
+		return
+	}
+	if conf == nil {
+		return
+	}
+	resp := MakeDHCPv6ReplyPacket(pkt, s.serverId, conf)
+	if resp == nil {
+		return
+	}
+	dst := &net.UDPAddr{
+		IP:   src.IP,
+		Port: DHCPv6ClientPort,
+		Zone: s.iface.Name,
+	}
+	cm := &ipv6.ControlMessage{IfIndex: s.iface.Index}
+	if _, err := s.conn.WriteTo(resp.Marshal(), cm, dst); err != nil {
+		log.Errorf("[DHCPv6] failed to response packet for %s: %v", mac, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDHCPv6Packet(t *testing.T) {
+	mac, _ := net.ParseMAC("00:22:0a:0b:0c:0d")
+	req := &DHCPv6Packet{
+		Type:          DHCPv6Solicit,
+		TransactionId: [3]byte{1, 2, 3},
+	}
+	req.AddOption(DHCPv6OptClientID, MakeDUIDLL(mac))
+	req.AddOption(DHCPv6OptElapsedTime, []byte{0, 0})
+	req.AddOption(DHCPv6OptIANA, []byte{0, 0, 0, 9, 0, 0, 0, 0, 0, 0, 0, 0})
+
+	pkt, err := ParseDHCPv6Packet(req.Marshal())
+	if err != nil {
+		t.Fatalf("ParseDHCPv6Packet: %v", err)
+	}
+	if pkt.Type != DHCPv6Solicit || pkt.TransactionId != req.TransactionId || len(pkt.Options) != 3 {
+		t.Fatalf("parsed packet mismatch: %s", pkt)
+	}
+	got, err := MacFromDUID(pkt.GetOption(DHCPv6OptClientID))
+	if err != nil || got.String() != mac.String() {
+		t.Errorf("MacFromDUID = %s, %v", got, err)
+	}
+	if _, err := ParseDHCPv6Packet([]byte{1, 2, 3, 4, 0, 1, 0, 8, 0}); err == nil {
+		t.Errorf("truncated packet should fail")
+	}
+}
+
+func TestMakeDHCPv6ReplyPacket(t *testing.T) {
+	var (
+		clientMac, _ = net.ParseMAC("00:22:0a:0b:0c:0d")
+		serverMac, _ = net.ParseMAC("00:22:0a:00:00:01")
+		serverId     = MakeDUIDLL(serverMac)
+		conf         = &DHCPv6Config{
+			ClientIP:    net.ParseIP("fd00:1::10"),
+			DNSServers:  []net.IP{net.ParseIP("fd00:1::53")},
+			Domain:      "cloud.local",
+			LeaseTime:   time.Hour,
+			RenewalTime: 30 * time.Minute,
+		}
+	)
+	req := &DHCPv6Packet{Type: DHCPv6Solicit}
+	req.AddOption(DHCPv6OptClientID, MakeDUIDLL(clientMac))
+	req.AddOption(DHCPv6OptIANA, []byte{0, 0, 0, 9, 0, 0, 0, 0, 0, 0, 0, 0})
+
+	resp := MakeDHCPv6ReplyPacket(req, serverId, conf)
+	if resp == nil || resp.Type != DHCPv6Advertise {
+		t.Fatalf("solicit should be advertised, got %v", resp)
+	}
+	ia := resp.GetOption(DHCPv6OptIANA)
+	if len(ia) != 12+4+24 {
+		t.Fatalf("bad IA_NA length %d", len(ia))
+	}
+	if iaid := ia[0:4]; iaid[3] != 9 {
+		t.Errorf("IAID not echoed: %v", iaid)
+	}
+	if ip := net.IP(ia[16:32]); !ip.Equal(conf.ClientIP) {
+		t.Errorf("assigned address %s, want %s", ip, conf.ClientIP)
+	}
+	if dns := resp.GetOption(DHCPv6OptDNSServers); !net.IP(dns).Equal(conf.DNSServers[0]) {
+		t.Errorf("dns servers %v", dns)
+	}
+	want := []byte{5, 'c', 'l', 'o', 'u', 'd', 5, 'l', 'o', 'c', 'a', 'l', 0}
+	if err := compareBytes(want, resp.GetOption(DHCPv6OptDomainSearch)); err != nil {
+		t.Errorf("domain search: %v", err)
+	}
+
+	req.AddOption(DHCPv6OptRapidCommit, []byte{})
+	if resp := MakeDHCPv6ReplyPacket(req, serverId, conf); resp == nil || resp.Type != DHCPv6Reply {
+		t.Errorf("rapid commit solicit should be replied")
+	}
+
+	req.Type = DHCPv6Request
+	req.AddOption(DHCPv6OptServerID, MakeDUIDLL(clientMac))
+	if resp := MakeDHCPv6ReplyPacket(req, serverId, conf); resp != nil {
+		t.Errorf("request to other server should be ignored")
+	}
+}
+
+func TestMakeRouterAdvert(t *testing.T) {
+	mac, _ := net.ParseMAC("00:22:0a:00:00:01")
+	b := MakeRouterAdvert(mac, &RouterAdvertConfig{
+		Prefix:     net.ParseIP("fd00:1::10"),
+		PrefixLen:  64,
+		Mtu:        1450,
+		DNSServers: []net.IP{net.ParseIP("fd00:1::53")},
+	})
+	if len(b) != 16+8+8+32+24 {
+		t.Fatalf("bad router advert length %d", len(b))
+	}
+	if b[5] != raFlagManaged|raFlagOther || b[6] != 0 || b[7] != 0 {
+		t.Errorf("bad flags or router lifetime: %v", b[4:8])
+	}
+	if got := ndSourceLinkAddr(b[16:]); got.String() != mac.String() {
+		t.Errorf("source link-layer address %s", got)
+	}
+	prefix := b[32:64]
+	if prefix[2] != 64 || prefix[3] != prefixFlagOnLink || !net.IP(prefix[16:32]).Equal(net.ParseIP("fd00:1::")) {
+		t.Errorf("bad prefix option %v", prefix)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp
+
+import (
+	"encoding/binary"
+	"net"
+	"runtime/debug"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+)
+
+const (
+	ndOptSourceLinkAddr = 1
+	ndOptPrefixInfo     = 3
+	ndOptMtu            = 5
+	ndOptRDNSS          = 25
+
+	raFlagManaged = 0x80
+	raFlagOther   = 0x40
+
+	prefixFlagOnLink = 0x80
+
+	ndInfiniteLifetime = 0xffffffff
+)
+
+var (
+	// All_Routers
+	IPv6AllRouters = net.ParseIP("ff02::2")
+)
+
+type RouterAdvertConfig struct {
+	Prefix     net.IP
+	PrefixLen  int
+	Mtu        int
+	DNSServers []net.IP
+}
+
+type RouterSolicitHandler interface {
+	// ServeRouterSolicit returns config of the soliciting host with the
+	// mac, or nil if the host is not served by us
+	ServeRouterSolicit(mac net.HardwareAddr) (*RouterAdvertConfig, error)
+}
+
+// MakeRouterAdvert builds router advertisement telling hosts to get addresses
+// and other config with DHCPv6.  Router lifetime is 0 as we are not the
+// router.  The prefix is on-link only, not for autonomous configuration.
+// Checksum is left to the kernel
+func MakeRouterAdvert(srcMac net.HardwareAddr, conf *RouterAdvertConfig) []byte {
+	b := []byte{
+		byte(ipv6.ICMPTypeRouterAdvertisement), 0, 0, 0,
+		64, raFlagManaged | raFlagOther, 0, 0, // hop limit, flags, router lifetime
+		0, 0, 0, 0, // reachable time
+		0, 0, 0, 0, // retrans timer
+	}
+	if len(srcMac) == 6 {
+		b = append(b, ndOptSourceLinkAddr, 1)
+		b = append(b, srcMac...)
+	}
+	if conf.Mtu > 0 {
+		opt := make([]byte, 8)
+		opt[0], opt[1] = ndOptMtu, 1
+		binary.BigEndian.PutUint32(opt[4:], uint32(conf.Mtu))
+		b = append(b, opt...)
+	}
+	if conf.Prefix != nil {
+		opt := make([]byte, 32)
+		opt[0], opt[1] = ndOptPrefixInfo, 4
+		opt[2] = byte(conf.PrefixLen)
+		opt[3] = prefixFlagOnLink
+		binary.BigEndian.PutUint32(opt[4:], ndInfiniteLifetime)
+		binary.BigEndian.PutUint32(opt[8:], ndInfiniteLifetime)
+		copy(opt[16:], conf.Prefix.Mask(net.CIDRMask(conf.PrefixLen, 128)).To16())
+		b = append(b, opt...)
+	}
+	if len(conf.DNSServers) > 0 {
+		opt := make([]byte, 8, 8+16*len(conf.DNSServers))
+		opt[0], opt[1] = ndOptRDNSS, byte(1+2*len(conf.DNSServers))
+		binary.BigEndian.PutUint32(opt[4:], ndInfiniteLifetime)
+		for _, ip := range conf.DNSServers {
+			opt = append(opt, ip.To16()...)
+		}
+		b = append(b, opt...)
+	}
+	return b
+}
+
+// ndSourceLinkAddr returns the source link-layer address in neighbor
+// discovery options
+func ndSourceLinkAddr(opts []byte) net.HardwareAddr {
+	for len(opts) >= 8 {
+		l := int(opts[1]) * 8
+		if l == 0 || l > len(opts) {
+			return nil
+		}
+		if opts[0] == ndOptSourceLinkAddr && l == 8 {
+			return net.HardwareAddr(opts[2:8])
+		}
+		opts = opts[l:]
+	}
+	return nil
+}
+
+// RouterAdvertServer answers router solicitations on one interface with
+// unicast router advertisements
+type RouterAdvertServer struct {
+	iface *net.Interface
+	conn  *ipv6.PacketConn
+}
+
+func NewRouterAdvertServer(iface string) (*RouterAdvertServer, error) {
+	intf, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, errors.Wrapf(err, "InterfaceByName %s", iface)
+	}
+	icmpConn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return nil, errors.Wrap(err, "listen icmpv6")
+	}
+	conn := icmpConn.IPv6PacketConn()
+	var filter ipv6.ICMPFilter
+	filter.SetAll(true)
+	filter.Accept(ipv6.ICMPTypeRouterSolicitation)
+	for _, fn := range []func() error{
+		func() error { return conn.SetICMPFilter(&filter) },
+		func() error { return conn.SetControlMessage(ipv6.FlagInterface, true) },
+		func() error { return conn.JoinGroup(intf, &net.IPAddr{IP: IPv6AllRouters}) },
+		// neighbor discovery packets must have hop limit 255
+		func() error { return conn.SetHopLimit(255) },
+		func() error { return conn.SetMulticastHopLimit(255) },
+	} {
+		if err := fn(); err != nil {
+			icmpConn.Close()
+			return nil, errors.Wrapf(err, "setup icmpv6 conn on %s", iface)
+		}
+	}
+	return &RouterAdvertServer{
+		iface: intf,
+		conn:  conn,
+	}, nil
+}
+
+func (s *RouterAdvertServer) ListenAndServe(handler RouterSolicitHandler) error {
+	defer s.conn.Close()
+	buf := make([]byte, 1500)
+	for {
+		n, cm, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return errors.Wrap(err, "read icmpv6 packet")
+		}
+		if cm != nil && cm.IfIndex != s.iface.Index {
+			continue
+		}
+		src, ok := addr.(*net.IPAddr)
+		if !ok || src.IP.IsUnspecified() {
+			continue
+		}
+		if n < 8 || buf[0] != byte(ipv6.ICMPTypeRouterSolicitation) {
+			continue
+		}
+		mac := ndSourceLinkAddr(buf[8:n])
+		if mac == nil {
+			macStr, err := netutils2.MacFromIPv6LinkLocal(src.IP)
+			if err != nil {
+				continue
+			}
+			mac, _ = net.ParseMAC(macStr)
+		}
+		go s.serve(handler, append(net.HardwareAddr(nil), mac...), src)
+	}
+}
+
+func (s *RouterAdvertServer) serve(handler RouterSolicitHandler, mac net.HardwareAddr, src *net.IPAddr) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Serve panic error: %v", r)
+			debug.PrintStack()
+		}
+	}()
+
+	conf, err := handler.ServeRouterSolicit(mac)
+	if err != nil {
+		log.Warningf("[RA] handler serve error: %v", err)
+		return
+	}
+	if conf == nil {
+		return
+	}
+	dst := &net.IPAddr{IP: src.IP, Zone: s.iface.Name}
+	cm := &ipv6.ControlMessage{IfIndex: s.iface.Index, HopLimit: 255}
+	if _, err := s.conn.WriteTo(MakeRouterAdvert(s.iface.HardwareAddr, conf), cm, dst); err != nil {
+		log.Errorf("[RA] failed to advertise to %s: %v", mac, err)
+	}
+}
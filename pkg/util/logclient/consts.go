@@ -74,6 +74,7 @@ const (
 	ACT_RENEW                        = "renew"
 	ACT_SET_AUTO_RENEW               = "set_auto_renew"
 	ACT_MIGRATE                      = "migrate"
+	ACT_PREEMPT                      = "preempt"
 	ACT_EIP_ASSOCIATE                = "eip_associate"
 	ACT_EIP_DISSOCIATE               = "eip_dissociate"
 	ACT_EIP_CONVERT                  = "eip_convert"
@@ -108,8 +109,10 @@ const (
 	ACT_OPEN_PUBLIC_CONNECTION  = "open_public_connection"
 	ACT_CLOSE_PUBLIC_CONNECTION = "close_public_connection"
 
-	ACT_IMAGE_SAVE  = "image_save"
-	ACT_IMAGE_PROBE = "image_probe"
+	ACT_IMAGE_SAVE             = "image_save"
+	ACT_IMAGE_PROBE            = "image_probe"
+	ACT_IMAGE_VERIFY_SIGNATURE = "image_verify_signature"
+	ACT_IMAGE_REPLICATE        = "image_replicate"
 
 	ACT_AUTHENTICATE = "authenticate"
 
@@ -127,6 +130,11 @@ const (
 	ACT_GUEST_PANICKED              = "guest_panicked"
 	ACT_HOST_MAINTAINING            = "host_maintaining"
 
+	ACT_FLOW_LOG = "flow_log"
+
+	ACT_REBALANCE  = "rebalance"
+	ACT_POWER_SAVE = "power_save"
+
 	ACT_MKDIR          = "mkdir"
 	ACT_DELETE_OBJECT  = "delete_object"
 	ACT_UPLOAD_OBJECT  = "upload_object"
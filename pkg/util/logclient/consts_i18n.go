@@ -260,6 +260,10 @@ func init() {
 		EN("Migrate").
 		CN("迁移"),
 	)
+	t.Set(ACT_PREEMPT, i18n.NewTableEntry().
+		EN("Preempt").
+		CN("抢占"),
+	)
 	t.Set(ACT_EIP_ASSOCIATE, i18n.NewTableEntry().
 		EN("Eip Associate").
 		CN("绑定弹性IP"),
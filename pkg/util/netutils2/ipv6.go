@@ -0,0 +1,104 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netutils2
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// ErrInvalidIPv6 is returned for malformed ipv6 addresses and masks
+const ErrInvalidIPv6 = errors.Error("InvalidIPv6")
+
+// ParseIPv6 parses a textual ipv6 address.  IPv4 and IPv4-mapped addresses
+// are rejected
+func ParseIPv6(addr string) (net.IP, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() != nil {
+		return nil, errors.Wrapf(ErrInvalidIPv6, "invalid ipv6 address %q", addr)
+	}
+	return ip.To16(), nil
+}
+
+// IsIPv6Addr reports whether addr is a valid ipv6 address
+func IsIPv6Addr(addr string) bool {
+	_, err := ParseIPv6(addr)
+	return err == nil
+}
+
+// IPv6Prefix returns the network address of addr with masklen bits
+func IPv6Prefix(addr string, masklen int) (string, error) {
+	ip, err := ParseIPv6(addr)
+	if err != nil {
+		return "", err
+	}
+	if masklen < 0 || masklen > 128 {
+		return "", errors.Wrapf(ErrInvalidIPv6, "invalid ipv6 mask length %d", masklen)
+	}
+	return ip.Mask(net.CIDRMask(masklen, 128)).String(), nil
+}
+
+// IPv6Compare compares two ipv6 addresses numerically
+func IPv6Compare(a, b net.IP) int {
+	return new(big.Int).SetBytes(a.To16()).Cmp(new(big.Int).SetBytes(b.To16()))
+}
+
+// IPv6Add returns the address n steps after ip
+func IPv6Add(ip net.IP, n int64) net.IP {
+	v := new(big.Int).SetBytes(ip.To16())
+	v.Add(v, big.NewInt(n))
+	b := v.Bytes()
+	if len(b) > net.IPv6len {
+		b = b[len(b)-net.IPv6len:]
+	}
+	ret := make(net.IP, net.IPv6len)
+	copy(ret[net.IPv6len-len(b):], b)
+	return ret
+}
+
+// IPv6LinkLocalFromMac returns the EUI-64 link local address of the mac
+func IPv6LinkLocalFromMac(mac string) (net.IP, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse mac %q", mac)
+	}
+	if len(hw) != 6 {
+		return nil, errors.Wrapf(ErrInvalidIPv6, "not an ethernet mac %q", mac)
+	}
+	ip := make(net.IP, net.IPv6len)
+	ip[0], ip[1] = 0xfe, 0x80
+	ip[8] = hw[0] ^ 0x02
+	ip[9], ip[10] = hw[1], hw[2]
+	ip[11], ip[12] = 0xff, 0xfe
+	ip[13], ip[14], ip[15] = hw[3], hw[4], hw[5]
+	return ip, nil
+}
+
+// MacFromIPv6LinkLocal recovers the mac from an EUI-64 link local address.
+// Addresses not generated from EUI-64 yield an error
+func MacFromIPv6LinkLocal(ip net.IP) (string, error) {
+	ip = ip.To16()
+	if ip == nil || !ip.IsLinkLocalUnicast() || ip.To4() != nil {
+		return "", errors.Wrapf(ErrInvalidIPv6, "not an ipv6 link local address %s", ip)
+	}
+	if ip[11] != 0xff || ip[12] != 0xfe {
+		return "", errors.Wrapf(ErrInvalidIPv6, "not an eui-64 address %s", ip)
+	}
+	return fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x",
+		ip[8]^0x02, ip[9], ip[10], ip[13], ip[14], ip[15]), nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netutils2
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIPv6Prefix(t *testing.T) {
+	cases := []struct {
+		addr    string
+		masklen int
+		want    string
+		wantErr bool
+	}{
+		{"2001:db8::1234", 64, "2001:db8::", false},
+		{"2001:db8:0:1:ffff::1", 48, "2001:db8::", false},
+		{"10.0.0.1", 24, "", true},
+		{"2001:db8::1", 129, "", true},
+	}
+	for _, c := range cases {
+		got, err := IPv6Prefix(c.addr, c.masklen)
+		if (err != nil) != c.wantErr {
+			t.Errorf("IPv6Prefix(%s, %d) err %v, wantErr %v", c.addr, c.masklen, err, c.wantErr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("IPv6Prefix(%s, %d) = %s, want %s", c.addr, c.masklen, got, c.want)
+		}
+	}
+}
+
+func TestIPv6Add(t *testing.T) {
+	ip := net.ParseIP("2001:db8::ffff")
+	got := IPv6Add(ip, 1)
+	if got.String() != "2001:db8::1:0" {
+		t.Errorf("IPv6Add = %s", got)
+	}
+	if IPv6Compare(got, ip) <= 0 {
+		t.Errorf("IPv6Compare(%s, %s) should be positive", got, ip)
+	}
+}
+
+func TestIPv6LinkLocalMac(t *testing.T) {
+	mac := "00:22:0a:0b:0c:0d"
+	ip, err := IPv6LinkLocalFromMac(mac)
+	if err != nil {
+		t.Fatalf("IPv6LinkLocalFromMac: %v", err)
+	}
+	if ip.String() != "fe80::222:aff:fe0b:c0d" {
+		t.Errorf("IPv6LinkLocalFromMac = %s", ip)
+	}
+	got, err := MacFromIPv6LinkLocal(ip)
+	if err != nil {
+		t.Fatalf("MacFromIPv6LinkLocal: %v", err)
+	}
+	if got != mac {
+		t.Errorf("MacFromIPv6LinkLocal = %s, want %s", got, mac)
+	}
+	if _, err := MacFromIPv6LinkLocal(net.ParseIP("fe80::1")); err == nil {
+		t.Errorf("MacFromIPv6LinkLocal(fe80::1) should fail")
+	}
+}
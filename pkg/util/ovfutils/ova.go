@@ -0,0 +1,114 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovfutils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+// SaveFile writes a file referenced by the descriptor into dir, decompressing it if necessary
+func SaveFile(reader io.Reader, file *SOvfFile, dir string) (string, error) {
+	if file.Compression == COMPRESSION_GZIP {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return "", errors.Wrapf(err, "gzip reader of %s", file.Href)
+		}
+		defer gz.Close()
+		reader = gz
+	} else if len(file.Compression) > 0 && file.Compression != "identity" {
+		return "", fmt.Errorf("unsupported compression %s of %s", file.Compression, file.Href)
+	}
+	localPath := filepath.Join(dir, file.LocalName())
+	fp, err := os.Create(localPath)
+	if err != nil {
+		return "", errors.Wrap(err, "create")
+	}
+	defer fp.Close()
+	_, err = io.Copy(fp, reader)
+	if err != nil {
+		return "", errors.Wrapf(err, "save %s", file.Href)
+	}
+	return localPath, nil
+}
+
+// ExtractOva unpacks an ova package read from reader into dir.
+// The descriptor must be the first entry of the package, so the package can be
+// extracted from a stream, only the files referenced by the descriptor are kept
+func ExtractOva(reader io.Reader, dir string) (*SOvfInfo, error) {
+	var info *SOvfInfo
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read ova")
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Base(hdr.Name)
+		if info == nil {
+			if !strings.HasSuffix(strings.ToLower(name), ".ovf") {
+				return nil, fmt.Errorf("the first entry %s of ova is not an ovf descriptor", hdr.Name)
+			}
+			content, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, errors.Wrap(err, "read ovf descriptor")
+			}
+			info, err = ParseOvf(content)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var file *SOvfFile
+		for i := range info.Files {
+			if info.Files[i].LocalName() == name {
+				file = &info.Files[i]
+				break
+			}
+		}
+		if file == nil {
+			log.Debugf("skip %s in ova", hdr.Name)
+			continue
+		}
+		_, err = SaveFile(tr, file, dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if info == nil {
+		return nil, fmt.Errorf("no ovf descriptor found in ova")
+	}
+	for _, file := range info.Files {
+		if _, err := os.Stat(filepath.Join(dir, file.LocalName())); err != nil {
+			return nil, errors.Wrapf(err, "file %s missing in ova", file.Href)
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,370 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovfutils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// CIM resource types used in VirtualHardwareSection items
+const (
+	RESOURCE_TYPE_CPU             = 3
+	RESOURCE_TYPE_MEMORY          = 4
+	RESOURCE_TYPE_IDE_CONTROLLER  = 5
+	RESOURCE_TYPE_SCSI_CONTROLLER = 6
+	RESOURCE_TYPE_ETHERNET        = 10
+	RESOURCE_TYPE_DISK            = 17
+	RESOURCE_TYPE_OTHER_STORAGE   = 20
+)
+
+const (
+	FIRMWARE_BIOS = "BIOS"
+	FIRMWARE_UEFI = "UEFI"
+
+	COMPRESSION_GZIP = "gzip"
+)
+
+type ovfFile struct {
+	Id          string `xml:"id,attr"`
+	Href        string `xml:"href,attr"`
+	Size        int64  `xml:"size,attr"`
+	Compression string `xml:"compression,attr"`
+}
+
+type ovfDisk struct {
+	DiskId                  string `xml:"diskId,attr"`
+	FileRef                 string `xml:"fileRef,attr"`
+	Capacity                string `xml:"capacity,attr"`
+	CapacityAllocationUnits string `xml:"capacityAllocationUnits,attr"`
+	Format                  string `xml:"format,attr"`
+}
+
+type ovfItem struct {
+	InstanceId      string   `xml:"InstanceID"`
+	ResourceType    int      `xml:"ResourceType"`
+	ResourceSubType string   `xml:"ResourceSubType"`
+	VirtualQuantity int64    `xml:"VirtualQuantity"`
+	AllocationUnits string   `xml:"AllocationUnits"`
+	HostResource    []string `xml:"HostResource"`
+	Parent          string   `xml:"Parent"`
+	AddressOnParent string   `xml:"AddressOnParent"`
+	Connection      []string `xml:"Connection"`
+}
+
+type ovfConfig struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type ovfEnvelope struct {
+	XMLName xml.Name  `xml:"Envelope"`
+	Files   []ovfFile `xml:"References>File"`
+	Disks   []ovfDisk `xml:"DiskSection>Disk"`
+
+	VirtualSystem struct {
+		Name string `xml:"Name"`
+
+		OperatingSystem struct {
+			OsType      string `xml:"osType,attr"`
+			Description string `xml:"Description"`
+		} `xml:"OperatingSystemSection"`
+
+		Hardware struct {
+			// ovf 1.x describes all devices as Item, ovf 2.x uses StorageItem and EthernetPortItem as well
+			Items             []ovfItem   `xml:"Item"`
+			StorageItems      []ovfItem   `xml:"StorageItem"`
+			EthernetPortItems []ovfItem   `xml:"EthernetPortItem"`
+			Configs           []ovfConfig `xml:"Config"`
+		} `xml:"VirtualHardwareSection"`
+	} `xml:"VirtualSystem"`
+}
+
+type SOvfFile struct {
+	Id          string
+	Href        string
+	Size        int64
+	Compression string
+}
+
+// LocalName is the name the file is saved as when the package is unpacked
+func (f SOvfFile) LocalName() string {
+	return path.Base(f.Href)
+}
+
+type SOvfDisk struct {
+	DiskId        string
+	FileRef       string
+	CapacityBytes int64
+	Format        string
+	// ide, scsi, pvscsi or sata
+	Driver string
+}
+
+type SOvfNic struct {
+	Connection string
+	Model      string
+}
+
+// NetDriver maps the virtual nic model to a nic driver a hypervisor understands
+func (nic SOvfNic) NetDriver() string {
+	model := strings.ToLower(nic.Model)
+	switch {
+	case strings.Contains(model, "vmxnet3"):
+		return "vmxnet3"
+	case strings.Contains(model, "virtio"):
+		return "virtio"
+	default:
+		return "e1000"
+	}
+}
+
+type SOvfInfo struct {
+	Name          string
+	OsType        string
+	OsDescription string
+	CpuCount      int
+	MemoryMB      int
+	Firmware      string
+
+	Files []SOvfFile
+	// disks in boot order, the first one is the system disk
+	Disks []SOvfDisk
+	Nics  []SOvfNic
+}
+
+func (info *SOvfInfo) GetFile(fileRef string) (*SOvfFile, error) {
+	for i := range info.Files {
+		if info.Files[i].Id == fileRef {
+			return &info.Files[i], nil
+		}
+	}
+	return nil, errors.Wrapf(errors.ErrNotFound, "file %s", fileRef)
+}
+
+// GetDiskFiles returns the files backing disks, in the same order as Disks
+func (info *SOvfInfo) GetDiskFiles() ([]*SOvfFile, error) {
+	files := make([]*SOvfFile, 0, len(info.Disks))
+	for _, disk := range info.Disks {
+		file, err := info.GetFile(disk.FileRef)
+		if err != nil {
+			return nil, errors.Wrapf(err, "disk %s", disk.DiskId)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// ParseAllocationUnits returns the number of bytes of a programmatic unit like "byte * 2^30"
+func ParseAllocationUnits(units string) (int64, error) {
+	units = strings.ToLower(strings.Replace(units, " ", "", -1))
+	switch units {
+	case "", "byte", "bytes":
+		return 1, nil
+	case "kilobytes", "kb":
+		return 1 << 10, nil
+	case "megabytes", "mb":
+		return 1 << 20, nil
+	case "gigabytes", "gb":
+		return 1 << 30, nil
+	}
+	if !strings.HasPrefix(units, "byte*") {
+		return 0, fmt.Errorf("unsupported allocation units %q", units)
+	}
+	factor := units[len("byte*"):]
+	if pos := strings.Index(factor, "^"); pos > 0 {
+		base, err := strconv.ParseInt(factor[:pos], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid allocation units %q", units)
+		}
+		exp, err := strconv.Atoi(factor[pos+1:])
+		if err != nil {
+			return 0, fmt.Errorf("invalid allocation units %q", units)
+		}
+		ret := int64(1)
+		for i := 0; i < exp; i++ {
+			ret *= base
+		}
+		return ret, nil
+	}
+	ret, err := strconv.ParseInt(factor, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid allocation units %q", units)
+	}
+	return ret, nil
+}
+
+func controllerDriver(ctrl ovfItem) string {
+	switch ctrl.ResourceType {
+	case RESOURCE_TYPE_IDE_CONTROLLER:
+		return "ide"
+	case RESOURCE_TYPE_SCSI_CONTROLLER:
+		if strings.Contains(strings.ToLower(ctrl.ResourceSubType), "virtualscsi") {
+			return "pvscsi"
+		}
+		return "scsi"
+	case RESOURCE_TYPE_OTHER_STORAGE:
+		return "sata"
+	}
+	return ""
+}
+
+type sDiskItem struct {
+	diskId  string
+	ctrlIdx int
+	address int
+	driver  string
+}
+
+func ParseOvf(content []byte) (*SOvfInfo, error) {
+	env := ovfEnvelope{}
+	err := xml.Unmarshal(content, &env)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal ovf descriptor")
+	}
+	vs := env.VirtualSystem
+	info := &SOvfInfo{
+		Name:          vs.Name,
+		OsType:        vs.OperatingSystem.OsType,
+		OsDescription: vs.OperatingSystem.Description,
+		Firmware:      FIRMWARE_BIOS,
+	}
+	for _, f := range env.Files {
+		info.Files = append(info.Files, SOvfFile{
+			Id:          f.Id,
+			Href:        f.Href,
+			Size:        f.Size,
+			Compression: f.Compression,
+		})
+	}
+
+	for _, conf := range vs.Hardware.Configs {
+		if conf.Key == "firmware" && strings.ToLower(conf.Value) == "efi" {
+			info.Firmware = FIRMWARE_UEFI
+		}
+	}
+
+	items := append([]ovfItem{}, vs.Hardware.Items...)
+	items = append(items, vs.Hardware.StorageItems...)
+	items = append(items, vs.Hardware.EthernetPortItems...)
+
+	controllers := map[string]int{}
+	for i, item := range items {
+		switch item.ResourceType {
+		case RESOURCE_TYPE_IDE_CONTROLLER, RESOURCE_TYPE_SCSI_CONTROLLER, RESOURCE_TYPE_OTHER_STORAGE:
+			controllers[item.InstanceId] = i
+		}
+	}
+
+	diskItems := []sDiskItem{}
+	for _, item := range items {
+		switch item.ResourceType {
+		case RESOURCE_TYPE_CPU:
+			info.CpuCount = int(item.VirtualQuantity)
+		case RESOURCE_TYPE_MEMORY:
+			units := item.AllocationUnits
+			if len(units) == 0 {
+				units = "byte * 2^20"
+			}
+			unit, err := ParseAllocationUnits(units)
+			if err != nil {
+				return nil, errors.Wrap(err, "memory")
+			}
+			info.MemoryMB = int(item.VirtualQuantity * unit / (1 << 20))
+		case RESOURCE_TYPE_ETHERNET:
+			nic := SOvfNic{Model: item.ResourceSubType}
+			if len(item.Connection) > 0 {
+				nic.Connection = item.Connection[0]
+			}
+			info.Nics = append(info.Nics, nic)
+		case RESOURCE_TYPE_DISK:
+			if len(item.HostResource) == 0 {
+				continue
+			}
+			diskItem := sDiskItem{diskId: path.Base(item.HostResource[0])}
+			if idx, ok := controllers[item.Parent]; ok {
+				diskItem.ctrlIdx = idx
+				diskItem.driver = controllerDriver(items[idx])
+			} else {
+				diskItem.ctrlIdx = len(items)
+			}
+			diskItem.address, _ = strconv.Atoi(item.AddressOnParent)
+			diskItems = append(diskItems, diskItem)
+		}
+	}
+	sort.SliceStable(diskItems, func(i, j int) bool {
+		if diskItems[i].ctrlIdx != diskItems[j].ctrlIdx {
+			return diskItems[i].ctrlIdx < diskItems[j].ctrlIdx
+		}
+		return diskItems[i].address < diskItems[j].address
+	})
+
+	disks := map[string]ovfDisk{}
+	for _, disk := range env.Disks {
+		disks[disk.DiskId] = disk
+	}
+	appendDisk := func(disk ovfDisk, driver string) error {
+		capacity, err := strconv.ParseInt(disk.Capacity, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid capacity %q of disk %s", disk.Capacity, disk.DiskId)
+		}
+		unit, err := ParseAllocationUnits(disk.CapacityAllocationUnits)
+		if err != nil {
+			return errors.Wrapf(err, "disk %s", disk.DiskId)
+		}
+		info.Disks = append(info.Disks, SOvfDisk{
+			DiskId:        disk.DiskId,
+			FileRef:       disk.FileRef,
+			CapacityBytes: capacity * unit,
+			Format:        disk.Format,
+			Driver:        driver,
+		})
+		delete(disks, disk.DiskId)
+		return nil
+	}
+	for _, item := range diskItems {
+		disk, ok := disks[item.diskId]
+		if !ok {
+			continue
+		}
+		if err := appendDisk(disk, item.driver); err != nil {
+			return nil, err
+		}
+	}
+	// disks not attached to any controller keep the order of DiskSection
+	for _, disk := range env.Disks {
+		if _, ok := disks[disk.DiskId]; !ok {
+			continue
+		}
+		if err := appendDisk(disk, ""); err != nil {
+			return nil, err
+		}
+	}
+	for _, disk := range info.Disks {
+		if len(disk.FileRef) == 0 {
+			return nil, fmt.Errorf("disk %s has no backing file", disk.DiskId)
+		}
+		if _, err := info.GetFile(disk.FileRef); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
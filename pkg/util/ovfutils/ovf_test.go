@@ -0,0 +1,188 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovfutils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testOvf = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope vmw:buildId="build-16321839" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vmw="http://www.vmware.com/schema/ovf">
+  <References>
+    <File ovf:href="centos-disk1.vmdk" ovf:id="file1" ovf:size="1014702080"/>
+    <File ovf:href="centos-disk2.vmdk.gz" ovf:id="file2" ovf:size="68096" ovf:compression="gzip"/>
+  </References>
+  <DiskSection>
+    <Info>Virtual disk information</Info>
+    <Disk ovf:capacity="100" ovf:capacityAllocationUnits="byte * 2^30" ovf:diskId="vmdisk2" ovf:fileRef="file2" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"/>
+    <Disk ovf:capacity="30" ovf:capacityAllocationUnits="byte * 2^30" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"/>
+  </DiskSection>
+  <VirtualSystem ovf:id="centos">
+    <Name>centos</Name>
+    <OperatingSystemSection ovf:id="107" vmw:osType="centos7_64Guest">
+      <Description>CentOS 7 (64-bit)</Description>
+    </OperatingSystemSection>
+    <VirtualHardwareSection>
+      <Item>
+        <rasd:AllocationUnits>hertz * 10^6</rasd:AllocationUnits>
+        <rasd:InstanceID>1</rasd:InstanceID>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>4</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>byte * 2^20</rasd:AllocationUnits>
+        <rasd:InstanceID>2</rasd:InstanceID>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>8192</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:InstanceID>3</rasd:InstanceID>
+        <rasd:ResourceSubType>VirtualSCSI</rasd:ResourceSubType>
+        <rasd:ResourceType>6</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>1</rasd:AddressOnParent>
+        <rasd:HostResource>ovf:/disk/vmdisk2</rasd:HostResource>
+        <rasd:InstanceID>5</rasd:InstanceID>
+        <rasd:Parent>3</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>0</rasd:AddressOnParent>
+        <rasd:HostResource>ovf:/disk/vmdisk1</rasd:HostResource>
+        <rasd:InstanceID>4</rasd:InstanceID>
+        <rasd:Parent>3</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
+        <rasd:Connection>VM Network</rasd:Connection>
+        <rasd:InstanceID>6</rasd:InstanceID>
+        <rasd:ResourceSubType>VmxNet3</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+      <vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>`
+
+func TestParseOvf(t *testing.T) {
+	info, err := ParseOvf([]byte(testOvf))
+	if err != nil {
+		t.Fatalf("ParseOvf: %s", err)
+	}
+	if info.Name != "centos" || info.OsType != "centos7_64Guest" {
+		t.Errorf("unexpected name %q os type %q", info.Name, info.OsType)
+	}
+	if info.CpuCount != 4 || info.MemoryMB != 8192 {
+		t.Errorf("want 4 cpu 8192MB memory, got %d cpu %dMB", info.CpuCount, info.MemoryMB)
+	}
+	if info.Firmware != FIRMWARE_UEFI {
+		t.Errorf("want firmware %s got %s", FIRMWARE_UEFI, info.Firmware)
+	}
+	if len(info.Nics) != 1 || info.Nics[0].NetDriver() != "vmxnet3" || info.Nics[0].Connection != "VM Network" {
+		t.Errorf("unexpected nics %#v", info.Nics)
+	}
+	if len(info.Disks) != 2 {
+		t.Fatalf("want 2 disks got %d", len(info.Disks))
+	}
+	root := info.Disks[0]
+	if root.DiskId != "vmdisk1" || root.CapacityBytes != 30<<30 || root.Driver != "pvscsi" {
+		t.Errorf("unexpected root disk %#v", root)
+	}
+	files, err := info.GetDiskFiles()
+	if err != nil {
+		t.Fatalf("GetDiskFiles: %s", err)
+	}
+	if files[0].Href != "centos-disk1.vmdk" || files[1].LocalName() != "centos-disk2.vmdk.gz" {
+		t.Errorf("unexpected disk files %#v %#v", files[0], files[1])
+	}
+}
+
+func TestParseAllocationUnits(t *testing.T) {
+	cases := []struct {
+		units string
+		want  int64
+	}{
+		{"", 1},
+		{"byte", 1},
+		{"byte * 2^20", 1 << 20},
+		{"byte*10^3", 1000},
+		{"byte * 512", 512},
+		{"GigaBytes", 1 << 30},
+	}
+	for _, c := range cases {
+		got, err := ParseAllocationUnits(c.units)
+		if err != nil {
+			t.Errorf("%q: %s", c.units, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q: want %d got %d", c.units, c.want, got)
+		}
+	}
+	if _, err := ParseAllocationUnits("hertz * 10^6"); err == nil {
+		t.Errorf("hertz should not be parsed as bytes")
+	}
+}
+
+func TestExtractOva(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	gzBuf := &bytes.Buffer{}
+	gw := gzip.NewWriter(gzBuf)
+	gw.Write([]byte("disk2"))
+	gw.Close()
+	for _, entry := range []struct {
+		name    string
+		content []byte
+	}{
+		{"centos.ovf", []byte(testOvf)},
+		{"centos.mf", []byte("SHA256(centos.ovf)= 00")},
+		{"centos-disk1.vmdk", []byte("disk1")},
+		{"centos-disk2.vmdk.gz", gzBuf.Bytes()},
+	} {
+		tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg})
+		tw.Write(entry.content)
+	}
+	tw.Close()
+
+	dir, err := ioutil.TempDir("", "ova")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	info, err := ExtractOva(buf, dir)
+	if err != nil {
+		t.Fatalf("ExtractOva: %s", err)
+	}
+	if len(info.Disks) != 2 {
+		t.Fatalf("want 2 disks got %d", len(info.Disks))
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, "centos-disk2.vmdk.gz"))
+	if err != nil || string(content) != "disk2" {
+		t.Errorf("compressed disk not extracted: %q %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "centos.mf")); err == nil {
+		t.Errorf("files not referenced by the descriptor should be skipped")
+	}
+}
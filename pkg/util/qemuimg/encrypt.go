@@ -0,0 +1,151 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemuimg
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemutils"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+)
+
+const (
+	encryptSecretId = "sec0"
+)
+
+// luks images are raw images wrapped by a LUKS header, used for encrypted rbd disks
+const LUKS = TImageFormat("luks")
+
+// encryptSecretObject passes the disk passphrase derived from the data key,
+// base64 encoded once more as qemu-img decodes secrets of format=base64
+func encryptSecretObject(info *api.SEncryptInfo) string {
+	data := base64.StdEncoding.EncodeToString(seclib2.QemuDiskPassphrase(info.Key))
+	return fmt.Sprintf("secret,id=%s,data=%s,format=base64", encryptSecretId, data)
+}
+
+// encryptOptions returns the creation options to encrypt an image of format with the disk secret
+func encryptOptions(format TImageFormat) []string {
+	if format == LUKS {
+		return []string{"key-secret=" + encryptSecretId}
+	}
+	return []string{"encrypt.format=luks", "encrypt.key-secret=" + encryptSecretId}
+}
+
+// imageOpts describes an encrypted image for commands invoked with --image-opts
+func (img *SQemuImage) imageOpts() string {
+	if img.Format == LUKS || img.Format == RAW {
+		return fmt.Sprintf("driver=luks,file.filename=%s,key-secret=%s", img.Path, encryptSecretId)
+	}
+	return fmt.Sprintf("driver=%s,file.filename=%s,encrypt.key-secret=%s", img.Format, img.Path, encryptSecretId)
+}
+
+func NewEncryptedQemuImageWithInfo(path string, info *api.SEncryptInfo) (*SQemuImage, error) {
+	img, err := NewQemuImage(path)
+	if err != nil {
+		return nil, err
+	}
+	img.EncryptInfo = info
+	return img, nil
+}
+
+func (img *SQemuImage) IsEncrypted() bool {
+	return img.EncryptInfo != nil
+}
+
+func (img *SQemuImage) runEncrypted(args ...string) error {
+	cmdline := []string{"-c", strconv.Itoa(int(img.IoLevel)), qemutils.GetQemuImg()}
+	cmdline = append(cmdline, args...)
+	output, err := procutils.NewRemoteCommandAsFarAsPossible("ionice", cmdline...).Output()
+	if err != nil {
+		log.Errorf("qemu-img %s fail %s: %s", args[0], err, output)
+		return errors.Wrapf(err, "qemu-img %s: %s", args[0], output)
+	}
+	return nil
+}
+
+// CreateEncrypted creates a LUKS encrypted image of format qcow2 or luks,
+// a qcow2 image may have a backing file encrypted with the same secret
+func (img *SQemuImage) CreateEncrypted(sizeMB int, format TImageFormat, info *api.SEncryptInfo, backPath string) error {
+	if img.IsValid() {
+		return fmt.Errorf("create: the image is valid??? %s", img.Format)
+	}
+	options := encryptOptions(format)
+	if format == QCOW2 {
+		if len(backPath) > 0 {
+			options = append(options, fmt.Sprintf("backing_file=%s", backPath), "cluster_size=2M")
+		} else {
+			options = append(options, qcow2SparseOptions()...)
+		}
+	}
+	args := []string{"create", "--object", encryptSecretObject(info),
+		"-f", format.String(), "-o", strings.Join(options, ","), img.Path}
+	if sizeMB > 0 {
+		args = append(args, fmt.Sprintf("%dM", sizeMB))
+	}
+	err := img.runEncrypted(args...)
+	if err != nil {
+		return err
+	}
+	img.EncryptInfo = info
+	if format == LUKS {
+		// qemu-img info can not open a luks image without its secret
+		img.Format = LUKS
+		img.SizeBytes = int64(sizeMB) * 1024 * 1024
+		return nil
+	}
+	return img.parse()
+}
+
+// ConvertToEncrypted writes an encrypted copy of a plain image to output
+func (img *SQemuImage) ConvertToEncrypted(output string, format TImageFormat, info *api.SEncryptInfo) error {
+	if !img.IsValid() {
+		return fmt.Errorf("self is not valid")
+	}
+	return img.runEncrypted("convert", "--object", encryptSecretObject(info),
+		"-f", img.Format.String(), "-O", format.String(),
+		"-o", strings.Join(encryptOptions(format), ","),
+		img.Path, output)
+}
+
+// ConvertToPlain writes a decrypted copy of an encrypted image to output
+func (img *SQemuImage) ConvertToPlain(output string, format TImageFormat) error {
+	if !img.IsEncrypted() {
+		return fmt.Errorf("image %s is not encrypted", img.Path)
+	}
+	return img.runEncrypted("convert", "--object", encryptSecretObject(img.EncryptInfo),
+		"--image-opts", img.imageOpts(), "-O", format.String(), output)
+}
+
+func (img *SQemuImage) resizeEncrypted(sizeMB int) error {
+	err := img.runEncrypted("resize", "--object", encryptSecretObject(img.EncryptInfo),
+		"--image-opts", img.imageOpts(), fmt.Sprintf("%dM", sizeMB))
+	if err != nil {
+		return err
+	}
+	return img.parse()
+}
+
+// NewLuksQemuImage describes an existing luks image, which can not be probed without its secret
+func NewLuksQemuImage(path string, info *api.SEncryptInfo) *SQemuImage {
+	return &SQemuImage{Path: path, Format: LUKS, EncryptInfo: info}
+}
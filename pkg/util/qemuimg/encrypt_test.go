@@ -0,0 +1,89 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemuimg
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+// a data key whose raw bytes are not valid UTF-8
+var invalidUtf8Key = base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe, 0xc3, 0x28, 0x80, 0x00, 0xa0, 0xa1})
+
+func TestEncryptSecretObject(t *testing.T) {
+	info := &api.SEncryptInfo{Key: invalidUtf8Key}
+	obj := encryptSecretObject(info)
+	var data string
+	for _, part := range strings.Split(obj, ",") {
+		if strings.HasPrefix(part, "data=") {
+			data = strings.TrimPrefix(part, "data=")
+		}
+	}
+	passphrase, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatalf("decode secret data of %s: %s", obj, err)
+	}
+	if !utf8.Valid(passphrase) {
+		t.Errorf("passphrase %q is not valid UTF-8", passphrase)
+	}
+}
+
+func TestEncryptedImage(t *testing.T) {
+	if _, err := exec.LookPath("qemu-img"); err != nil {
+		t.Skip("qemu-img not found")
+	}
+	if _, err := exec.LookPath("ionice"); err != nil {
+		t.Skip("ionice not found")
+	}
+	dir, err := ioutil.TempDir("", "qemuimg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	info := &api.SEncryptInfo{Key: invalidUtf8Key}
+	for _, format := range []TImageFormat{QCOW2, LUKS} {
+		path := filepath.Join(dir, "disk."+format.String())
+		img, err := NewQemuImage(path)
+		if err != nil {
+			t.Fatalf("new %s: %s", path, err)
+		}
+		if err := img.CreateEncrypted(16, format, info, ""); err != nil {
+			t.Fatalf("create %s: %s", format, err)
+		}
+		if img.Format != format {
+			t.Errorf("create %s: got format %s", format, img.Format)
+		}
+		plain := filepath.Join(dir, "plain."+format.String())
+		if err := img.ConvertToPlain(plain, QCOW2); err != nil {
+			t.Fatalf("convert %s to plain: %s", format, err)
+		}
+		out, err := NewQemuImage(plain)
+		if err != nil {
+			t.Fatalf("info %s: %s", plain, err)
+		}
+		if out.GetSizeMB() != 16 {
+			t.Errorf("convert %s: want 16M got %dM", format, out.GetSizeMB())
+		}
+	}
+}
@@ -59,6 +59,7 @@ type SQemuImage struct {
 	Encryption      bool
 	Subformat       string
 	IoLevel         TIONiceLevel
+	EncryptInfo     *api.SEncryptInfo
 }
 
 func NewQemuImage(path string) (*SQemuImage, error) {
@@ -492,6 +493,9 @@ func (img *SQemuImage) Resize(sizeMB int) error {
 	if !img.IsValid() {
 		return fmt.Errorf("self is not valid")
 	}
+	if img.IsEncrypted() {
+		return img.resizeEncrypted(sizeMB)
+	}
 	cmd := procutils.NewRemoteCommandAsFarAsPossible("ionice", "-c", strconv.Itoa(int(img.IoLevel)),
 		qemutils.GetQemuImg(), "resize", img.Path, fmt.Sprintf("%dM", sizeMB))
 	err := cmd.Run()
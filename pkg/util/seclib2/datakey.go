@@ -0,0 +1,144 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seclib2
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+const (
+	// DATA_KEY_SIZE is the length of a generated data encryption key, suitable for AES-256
+	DATA_KEY_SIZE = 32
+)
+
+// GenerateDataKey returns a fresh random key of DATA_KEY_SIZE bytes
+func GenerateDataKey() ([]byte, error) {
+	key := make([]byte, DATA_KEY_SIZE)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func newGCM(kek []byte) (cipher.AEAD, error) {
+	if len(kek) != DATA_KEY_SIZE {
+		return nil, fmt.Errorf("invalid key encryption key size %d", len(kek))
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// WrapKey encrypts dataKey with the key encryption key kek using AES-256-GCM,
+// the result is base64 encoded nonce followed by the sealed key
+func WrapKey(kek []byte, dataKey []byte) (string, error) {
+	gcm, err := newGCM(kek)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, dataKey, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// UnwrapKey reverses WrapKey
+func UnwrapKey(kek []byte, wrapped string) ([]byte, error) {
+	gcm, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("wrapped key too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+// EncryptQemuSecret encrypts secret with masterKey the way qemu expects for
+// "-object secret,keyid=...,iv=...": AES-256-CBC with PKCS#7 padding.
+// It returns the base64 encoded iv and ciphertext
+func EncryptQemuSecret(masterKey []byte, secret []byte) (string, string, error) {
+	if len(masterKey) != DATA_KEY_SIZE {
+		return "", "", fmt.Errorf("invalid master key size %d", len(masterKey))
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return "", "", err
+	}
+	padding := aes.BlockSize - len(secret)%aes.BlockSize
+	plain := append(append([]byte{}, secret...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", "", err
+	}
+	cipherText := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(cipherText, plain)
+	return base64.StdEncoding.EncodeToString(iv), base64.StdEncoding.EncodeToString(cipherText), nil
+}
+
+// QemuDiskPassphrase returns the LUKS passphrase of a disk encrypted with the
+// base64 encoded data key. qemu requires passphrases to be valid UTF-8, which
+// the raw key bytes are not, so the base64 text itself is the passphrase
+func QemuDiskPassphrase(dataKey string) []byte {
+	return []byte(dataKey)
+}
+
+// EncryptQemuDiskSecret encrypts the passphrase of an encrypted disk derived
+// from its base64 encoded data key with masterKey
+func EncryptQemuDiskSecret(masterKey []byte, dataKey string) (string, string, error) {
+	if _, err := base64.StdEncoding.DecodeString(dataKey); err != nil {
+		return "", "", fmt.Errorf("decode data key: %v", err)
+	}
+	return EncryptQemuSecret(masterKey, QemuDiskPassphrase(dataKey))
+}
+
+func decryptQemuSecret(masterKey []byte, iv string, data string) ([]byte, error) {
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(cipherText) == 0 || len(cipherText)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid ciphertext size %d", len(cipherText))
+	}
+	plain := make([]byte, len(cipherText))
+	cipher.NewCBCDecrypter(block, ivBytes).CryptBlocks(plain, cipherText)
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, fmt.Errorf("invalid padding %d", padding)
+	}
+	return plain[:len(plain)-padding], nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seclib2
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWrapKey(t *testing.T) {
+	kek, err := GenerateDataKey()
+	if err != nil {
+		t.Fatalf("generate kek: %s", err)
+	}
+	dek, err := GenerateDataKey()
+	if err != nil {
+		t.Fatalf("generate dek: %s", err)
+	}
+	wrapped, err := WrapKey(kek, dek)
+	if err != nil {
+		t.Fatalf("wrap: %s", err)
+	}
+	got, err := UnwrapKey(kek, wrapped)
+	if err != nil {
+		t.Fatalf("unwrap: %s", err)
+	}
+	if !bytes.Equal(got, dek) {
+		t.Errorf("unwrapped key mismatch")
+	}
+
+	other, _ := GenerateDataKey()
+	if _, err := UnwrapKey(other, wrapped); err == nil {
+		t.Errorf("unwrap with wrong kek should fail")
+	}
+	if _, err := WrapKey([]byte("short"), dek); err == nil {
+		t.Errorf("wrap with short kek should fail")
+	}
+}
+
+func TestEncryptQemuSecret(t *testing.T) {
+	master, _ := GenerateDataKey()
+	for _, secret := range []string{"a", "0123456789abcdef", "this is a longer base64 secret=="} {
+		iv, data, err := EncryptQemuSecret(master, []byte(secret))
+		if err != nil {
+			t.Fatalf("encrypt %q: %s", secret, err)
+		}
+		plain, err := decryptQemuSecret(master, iv, data)
+		if err != nil {
+			t.Fatalf("decrypt %q: %s", secret, err)
+		}
+		if string(plain) != secret {
+			t.Errorf("want %q got %q", secret, plain)
+		}
+	}
+}
+
+func TestEncryptQemuDiskSecret(t *testing.T) {
+	master, _ := GenerateDataKey()
+	dek, _ := GenerateDataKey()
+	// the data key as stored in encrypt_info.key
+	key := base64.StdEncoding.EncodeToString(dek)
+
+	// qemu-img gets "-object secret,data=<base64 passphrase>,format=base64"
+	imgSecret, err := base64.StdEncoding.DecodeString(base64.StdEncoding.EncodeToString(QemuDiskPassphrase(key)))
+	if err != nil {
+		t.Fatalf("decode qemu-img secret: %s", err)
+	}
+	if !utf8.Valid(imgSecret) {
+		t.Errorf("passphrase %q is not valid UTF-8", imgSecret)
+	}
+	// qemu gets "-object secret,data=<data>,keyid=<master>,iv=<iv>,format=base64"
+	iv, data, err := EncryptQemuDiskSecret(master, key)
+	if err != nil {
+		t.Fatalf("encrypt disk secret: %s", err)
+	}
+	qemuSecret, err := decryptQemuSecret(master, iv, data)
+	if err != nil {
+		t.Fatalf("decrypt disk secret: %s", err)
+	}
+	if !bytes.Equal(imgSecret, qemuSecret) {
+		t.Errorf("qemu passphrase %q differs from qemu-img passphrase %q", qemuSecret, imgSecret)
+	}
+
+	if _, _, err := EncryptQemuDiskSecret(master, "not base64!"); err == nil {
+		t.Errorf("encrypt invalid data key should fail")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seclib2
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	PUBLIC_KEY_TYPE_RSA     = "rsa"
+	PUBLIC_KEY_TYPE_ECDSA   = "ecdsa"
+	PUBLIC_KEY_TYPE_ED25519 = "ed25519"
+
+	ErrInvalidSignature = errors.Error("invalid signature")
+)
+
+// ParsePublicKeyPem parses a PEM encoded PKIX public key, e.g. cosign.pub or the output of openssl pkey -pubout
+func ParsePublicKeyPem(pemStr string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemStr))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "ParseCertificate")
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("unsupported PEM block %s", block.Type)
+}
+
+func PublicKeyType(pub crypto.PublicKey) (string, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return PUBLIC_KEY_TYPE_RSA, nil
+	case *ecdsa.PublicKey:
+		return PUBLIC_KEY_TYPE_ECDSA, nil
+	case ed25519.PublicKey:
+		return PUBLIC_KEY_TYPE_ED25519, nil
+	}
+	return "", fmt.Errorf("unsupported public key %T", pub)
+}
+
+// PublicKeyFingerprint is the hex encoded sha256 of the DER form of the public key
+func PublicKeyFingerprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", errors.Wrap(err, "MarshalPKIXPublicKey")
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// VerifyDigestSignature verifies a detached signature of a blob given its sha256 digest.
+// rsa signatures may use PKCS#1 v1.5 or PSS padding, ecdsa signatures are ASN.1 encoded
+// as produced by cosign sign-blob or openssl dgst -sha256 -sign, ed25519 signs the digest itself
+func VerifyDigestSignature(pub crypto.PublicKey, digest []byte, sig []byte) error {
+	if len(digest) != sha256.Size {
+		return fmt.Errorf("invalid sha256 digest length %d", len(digest))
+	}
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig) == nil {
+			return nil
+		}
+		if rsa.VerifyPSS(key, crypto.SHA256, digest, sig, nil) == nil {
+			return nil
+		}
+		return ErrInvalidSignature
+	case *ecdsa.PublicKey:
+		esig := ecdsaSignature{}
+		rest, err := asn1.Unmarshal(sig, &esig)
+		if err != nil || len(rest) > 0 {
+			return errors.Wrap(ErrInvalidSignature, "malformed ecdsa signature")
+		}
+		if !ecdsa.Verify(key, digest, esig.R, esig.S) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, digest, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported public key %T", pub)
+}
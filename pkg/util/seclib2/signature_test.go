@@ -0,0 +1,87 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seclib2
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func toPem(t *testing.T, pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %s", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestVerifyDigestSignature(t *testing.T) {
+	digest := sha256.Sum256([]byte("image content"))
+	tampered := sha256.Sum256([]byte("image content tampered"))
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	rsaSig, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	pssSig, _ := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], nil)
+	ecSig, _ := ecKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	edSig := ed25519.Sign(edKey, digest[:])
+
+	cases := []struct {
+		name    string
+		pub     crypto.PublicKey
+		keyType string
+		sig     []byte
+	}{
+		{"rsa-pkcs1v15", &rsaKey.PublicKey, PUBLIC_KEY_TYPE_RSA, rsaSig},
+		{"rsa-pss", &rsaKey.PublicKey, PUBLIC_KEY_TYPE_RSA, pssSig},
+		{"ecdsa", &ecKey.PublicKey, PUBLIC_KEY_TYPE_ECDSA, ecSig},
+		{"ed25519", edPub, PUBLIC_KEY_TYPE_ED25519, edSig},
+	}
+	for _, c := range cases {
+		pub, err := ParsePublicKeyPem(toPem(t, c.pub))
+		if err != nil {
+			t.Fatalf("%s: ParsePublicKeyPem: %s", c.name, err)
+		}
+		keyType, err := PublicKeyType(pub)
+		if err != nil || keyType != c.keyType {
+			t.Errorf("%s: want key type %s got %s %v", c.name, c.keyType, keyType, err)
+		}
+		if err := VerifyDigestSignature(pub, digest[:], c.sig); err != nil {
+			t.Errorf("%s: verify: %s", c.name, err)
+		}
+		if err := VerifyDigestSignature(pub, tampered[:], c.sig); err == nil {
+			t.Errorf("%s: signature of tampered content should not verify", c.name)
+		}
+	}
+
+	fp1, _ := PublicKeyFingerprint(&rsaKey.PublicKey)
+	fp2, _ := PublicKeyFingerprint(&ecKey.PublicKey)
+	if len(fp1) != 64 || fp1 == fp2 {
+		t.Errorf("unexpected fingerprints %s %s", fp1, fp2)
+	}
+	if _, err := ParsePublicKeyPem("not a key"); err == nil {
+		t.Errorf("parse invalid pem should fail")
+	}
+}
@@ -25,6 +25,7 @@ type Vpc struct {
 
 	Wire     *Wire    `json:"-"`
 	Networks Networks `json:"-"`
+	FlowLogs FlowLogs `json:"-"`
 }
 
 func (el *Vpc) Copy() *Vpc {
@@ -189,3 +190,118 @@ func (el *DnsRecord) Copy() *DnsRecord {
 		SDnsRecord: el.SDnsRecord,
 	}
 }
+
+type Loadbalancer struct {
+	compute_models.SLoadbalancer
+
+	Network   *Network              `json:"-"`
+	Listeners LoadbalancerListeners `json:"-"`
+}
+
+func (el *Loadbalancer) Copy() *Loadbalancer {
+	return &Loadbalancer{
+		SLoadbalancer: el.SLoadbalancer,
+	}
+}
+
+type LoadbalancerListener struct {
+	compute_models.SLoadbalancerListener
+
+	Loadbalancer *Loadbalancer             `json:"-"`
+	BackendGroup *LoadbalancerBackendGroup `json:"-"`
+}
+
+func (el *LoadbalancerListener) Copy() *LoadbalancerListener {
+	return &LoadbalancerListener{
+		SLoadbalancerListener: el.SLoadbalancerListener,
+	}
+}
+
+type LoadbalancerBackendGroup struct {
+	compute_models.SLoadbalancerBackendGroup
+
+	Loadbalancer *Loadbalancer        `json:"-"`
+	Backends     LoadbalancerBackends `json:"-"`
+}
+
+func (el *LoadbalancerBackendGroup) Copy() *LoadbalancerBackendGroup {
+	return &LoadbalancerBackendGroup{
+		SLoadbalancerBackendGroup: el.SLoadbalancerBackendGroup,
+	}
+}
+
+type LoadbalancerBackend struct {
+	compute_models.SLoadbalancerBackend
+
+	BackendGroup *LoadbalancerBackendGroup `json:"-"`
+}
+
+func (el *LoadbalancerBackend) Copy() *LoadbalancerBackend {
+	return &LoadbalancerBackend{
+		SLoadbalancerBackend: el.SLoadbalancerBackend,
+	}
+}
+
+type NatGateway struct {
+	compute_models.SNatGateway
+
+	Vpc        *Vpc        `json:"-"`
+	SEntries   NatSEntries `json:"-"`
+	DEntries   NatDEntries `json:"-"`
+	Elasticips Elasticips  `json:"-"`
+}
+
+func (el *NatGateway) Copy() *NatGateway {
+	return &NatGateway{
+		SNatGateway: el.SNatGateway,
+	}
+}
+
+type NatSEntry struct {
+	compute_models.SNatSEntry
+
+	NatGateway *NatGateway `json:"-"`
+}
+
+func (el *NatSEntry) Copy() *NatSEntry {
+	return &NatSEntry{
+		SNatSEntry: el.SNatSEntry,
+	}
+}
+
+type NatDEntry struct {
+	compute_models.SNatDEntry
+
+	NatGateway *NatGateway `json:"-"`
+}
+
+func (el *NatDEntry) Copy() *NatDEntry {
+	return &NatDEntry{
+		SNatDEntry: el.SNatDEntry,
+	}
+}
+
+type VpcPeeringConnection struct {
+	compute_models.SVpcPeeringConnection
+
+	Vpc     *Vpc `json:"-"`
+	PeerVpc *Vpc `json:"-"`
+}
+
+func (el *VpcPeeringConnection) Copy() *VpcPeeringConnection {
+	return &VpcPeeringConnection{
+		SVpcPeeringConnection: el.SVpcPeeringConnection,
+	}
+}
+
+type FlowLog struct {
+	compute_models.SFlowLog
+
+	Vpc *Vpc `json:"-"`
+}
+
+func (el *FlowLog) Copy() *FlowLog {
+	return &FlowLog{
+		SFlowLog: el.SFlowLog,
+	}
+}
@@ -43,6 +43,19 @@ type (
 	DnsRecords map[string]*DnsRecord
 
 	RouteTables map[string]*RouteTable
+
+	Loadbalancers             map[string]*Loadbalancer
+	LoadbalancerListeners     map[string]*LoadbalancerListener
+	LoadbalancerBackendGroups map[string]*LoadbalancerBackendGroup
+	LoadbalancerBackends      map[string]*LoadbalancerBackend
+
+	NatGateways map[string]*NatGateway
+	NatSEntries map[string]*NatSEntry
+	NatDEntries map[string]*NatDEntry
+
+	VpcPeeringConnections map[string]*VpcPeeringConnection
+
+	FlowLogs map[string]*FlowLog
 )
 
 func (set Vpcs) ModelManager() mcclient_modulebase.IBaseManager {
@@ -104,6 +117,25 @@ func (ms Vpcs) joinRouteTables(subEntries RouteTables) bool {
 	return correct
 }
 
+func (ms Vpcs) joinFlowLogs(subEntries FlowLogs) bool {
+	for _, m := range ms {
+		m.FlowLogs = FlowLogs{}
+	}
+	for subId, subEntry := range subEntries {
+		m, ok := ms[subEntry.VpcId]
+		if !ok {
+			// vpc may have been deleted with flow logs to be
+			// cleaned
+			log.Warningf("vpc_id %s of flow log %s(%s) is not present", subEntry.VpcId, subEntry.Name, subEntry.Id)
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.Vpc = m
+		m.FlowLogs[subId] = subEntry
+	}
+	return true
+}
+
 func (ms Vpcs) joinNetworks(subEntries Networks) bool {
 	for _, m := range ms {
 		m.Networks = Networks{}
@@ -681,3 +713,351 @@ func (set RouteTables) Copy() apihelper.IModelSet {
 	}
 	return setCopy
 }
+
+func (set Loadbalancers) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.Loadbalancers
+}
+
+func (set Loadbalancers) NewModel() db.IModel {
+	return &Loadbalancer{}
+}
+
+func (set Loadbalancers) AddModel(i db.IModel) {
+	m := i.(*Loadbalancer)
+	set[m.Id] = m
+}
+
+func (set Loadbalancers) Copy() apihelper.IModelSet {
+	setCopy := Loadbalancers{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+// ModelFilter leaves out loadbalancers in the default vpc.  They are
+// served by lbagents of lbcluster
+func (set Loadbalancers) ModelFilter() []string {
+	return []string{"vpc_id.notequals(" + computeapis.DEFAULT_VPC_ID + ")"}
+}
+
+func (ms Loadbalancers) joinListeners(subEntries LoadbalancerListeners) bool {
+	for _, m := range ms {
+		m.Listeners = LoadbalancerListeners{}
+	}
+	for _, subEntry := range subEntries {
+		lbId := subEntry.LoadbalancerId
+		m, ok := ms[lbId]
+		if !ok {
+			// listeners of loadbalancers in the default vpc
+			continue
+		}
+		subEntry.Loadbalancer = m
+		m.Listeners[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (ms Loadbalancers) joinBackendGroups(subEntries LoadbalancerBackendGroups) bool {
+	for _, subEntry := range subEntries {
+		lbId := subEntry.LoadbalancerId
+		m, ok := ms[lbId]
+		if !ok {
+			continue
+		}
+		subEntry.Loadbalancer = m
+	}
+	return true
+}
+
+func (ms Loadbalancers) joinNetworks(subEntries Networks) bool {
+	for id, m := range ms {
+		netId := m.NetworkId
+		network, ok := subEntries[netId]
+		if !ok || network.Vpc == nil {
+			// let it go.  The network could have been dropped
+			// for not being in an onecloud vpc
+			log.Warningf("loadbalancer %s(%s): network %s not found", m.Name, m.Id, netId)
+			delete(ms, id)
+			continue
+		}
+		m.Network = network
+	}
+	return true
+}
+
+func (set LoadbalancerListeners) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerListeners
+}
+
+func (set LoadbalancerListeners) NewModel() db.IModel {
+	return &LoadbalancerListener{}
+}
+
+func (set LoadbalancerListeners) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerListener)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerListeners) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerListeners{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms LoadbalancerListeners) joinBackendGroups(subEntries LoadbalancerBackendGroups) bool {
+	for _, m := range ms {
+		m.BackendGroup = nil
+		if m.Loadbalancer == nil || m.BackendGroupId == "" {
+			continue
+		}
+		lbbg, ok := subEntries[m.BackendGroupId]
+		if !ok {
+			log.Warningf("loadbalancer listener %s(%s): backend group %s not found",
+				m.Name, m.Id, m.BackendGroupId)
+			continue
+		}
+		m.BackendGroup = lbbg
+	}
+	return true
+}
+
+func (set LoadbalancerBackendGroups) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerBackendGroups
+}
+
+func (set LoadbalancerBackendGroups) NewModel() db.IModel {
+	return &LoadbalancerBackendGroup{}
+}
+
+func (set LoadbalancerBackendGroups) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerBackendGroup)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerBackendGroups) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerBackendGroups{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms LoadbalancerBackendGroups) joinBackends(subEntries LoadbalancerBackends) bool {
+	for _, m := range ms {
+		m.Backends = LoadbalancerBackends{}
+	}
+	for _, subEntry := range subEntries {
+		lbbgId := subEntry.BackendGroupId
+		m, ok := ms[lbbgId]
+		if !ok {
+			continue
+		}
+		subEntry.BackendGroup = m
+		m.Backends[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (set LoadbalancerBackends) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerBackends
+}
+
+func (set LoadbalancerBackends) NewModel() db.IModel {
+	return &LoadbalancerBackend{}
+}
+
+func (set LoadbalancerBackends) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerBackend)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerBackends) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerBackends{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set NatGateways) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatGateways
+}
+
+func (set NatGateways) NewModel() db.IModel {
+	return &NatGateway{}
+}
+
+func (set NatGateways) AddModel(i db.IModel) {
+	m := i.(*NatGateway)
+	set[m.Id] = m
+}
+
+func (set NatGateways) Copy() apihelper.IModelSet {
+	setCopy := NatGateways{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms NatGateways) joinVpcs(subEntries Vpcs) bool {
+	for id, m := range ms {
+		vpc, ok := subEntries[m.VpcId]
+		if !ok {
+			// nat gateways of public cloud vpcs
+			delete(ms, id)
+			continue
+		}
+		m.Vpc = vpc
+	}
+	return true
+}
+
+func (ms NatGateways) joinSEntries(subEntries NatSEntries) bool {
+	for _, m := range ms {
+		m.SEntries = NatSEntries{}
+	}
+	for _, subEntry := range subEntries {
+		m, ok := ms[subEntry.NatgatewayId]
+		if !ok {
+			continue
+		}
+		subEntry.NatGateway = m
+		m.SEntries[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (ms NatGateways) joinDEntries(subEntries NatDEntries) bool {
+	for _, m := range ms {
+		m.DEntries = NatDEntries{}
+	}
+	for _, subEntry := range subEntries {
+		m, ok := ms[subEntry.NatgatewayId]
+		if !ok {
+			continue
+		}
+		subEntry.NatGateway = m
+		m.DEntries[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (ms NatGateways) joinElasticips(subEntries Elasticips) bool {
+	for _, m := range ms {
+		m.Elasticips = Elasticips{}
+	}
+	for _, subEntry := range subEntries {
+		if subEntry.AssociateType != computeapis.EIP_ASSOCIATE_TYPE_NAT_GATEWAY {
+			continue
+		}
+		m, ok := ms[subEntry.AssociateId]
+		if !ok {
+			continue
+		}
+		m.Elasticips[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (set NatSEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatSTable
+}
+
+func (set NatSEntries) NewModel() db.IModel {
+	return &NatSEntry{}
+}
+
+func (set NatSEntries) AddModel(i db.IModel) {
+	m := i.(*NatSEntry)
+	set[m.Id] = m
+}
+
+func (set NatSEntries) Copy() apihelper.IModelSet {
+	setCopy := NatSEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set NatDEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatDTable
+}
+
+func (set NatDEntries) NewModel() db.IModel {
+	return &NatDEntry{}
+}
+
+func (set NatDEntries) AddModel(i db.IModel) {
+	m := i.(*NatDEntry)
+	set[m.Id] = m
+}
+
+func (set NatDEntries) Copy() apihelper.IModelSet {
+	setCopy := NatDEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set VpcPeeringConnections) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.VpcPeeringConnections
+}
+
+func (set VpcPeeringConnections) NewModel() db.IModel {
+	return &VpcPeeringConnection{}
+}
+
+func (set VpcPeeringConnections) AddModel(i db.IModel) {
+	m := i.(*VpcPeeringConnection)
+	set[m.Id] = m
+}
+
+func (set VpcPeeringConnections) Copy() apihelper.IModelSet {
+	setCopy := VpcPeeringConnections{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms VpcPeeringConnections) joinVpcs(subEntries Vpcs) bool {
+	for id, m := range ms {
+		vpc, ok1 := subEntries[m.VpcId]
+		peerVpc, ok2 := subEntries[m.PeerVpcId]
+		if !ok1 || !ok2 {
+			// peerings of public cloud vpcs
+			delete(ms, id)
+			continue
+		}
+		m.Vpc = vpc
+		m.PeerVpc = peerVpc
+	}
+	return true
+}
+
+func (set FlowLogs) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.FlowLogs
+}
+
+func (set FlowLogs) NewModel() db.IModel {
+	return &FlowLog{}
+}
+
+func (set FlowLogs) AddModel(i db.IModel) {
+	m := i.(*FlowLog)
+	set[m.Id] = m
+}
+
+func (set FlowLogs) Copy() apihelper.IModelSet {
+	setCopy := FlowLogs{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
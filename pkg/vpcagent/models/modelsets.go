@@ -36,6 +36,19 @@ type ModelSetsMaxUpdatedAt struct {
 	DnsRecords time.Time
 
 	RouteTables time.Time
+
+	Loadbalancers             time.Time
+	LoadbalancerListeners     time.Time
+	LoadbalancerBackendGroups time.Time
+	LoadbalancerBackends      time.Time
+
+	NatGateways time.Time
+	NatSEntries time.Time
+	NatDEntries time.Time
+
+	VpcPeeringConnections time.Time
+
+	FlowLogs time.Time
 }
 
 func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
@@ -55,6 +68,19 @@ func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
 		DnsRecords: apihelper.PseudoZeroTime,
 
 		RouteTables: apihelper.PseudoZeroTime,
+
+		Loadbalancers:             apihelper.PseudoZeroTime,
+		LoadbalancerListeners:     apihelper.PseudoZeroTime,
+		LoadbalancerBackendGroups: apihelper.PseudoZeroTime,
+		LoadbalancerBackends:      apihelper.PseudoZeroTime,
+
+		NatGateways: apihelper.PseudoZeroTime,
+		NatSEntries: apihelper.PseudoZeroTime,
+		NatDEntries: apihelper.PseudoZeroTime,
+
+		VpcPeeringConnections: apihelper.PseudoZeroTime,
+
+		FlowLogs: apihelper.PseudoZeroTime,
 	}
 }
 
@@ -74,6 +100,19 @@ type ModelSets struct {
 	DnsRecords DnsRecords
 
 	RouteTables RouteTables
+
+	Loadbalancers             Loadbalancers
+	LoadbalancerListeners     LoadbalancerListeners
+	LoadbalancerBackendGroups LoadbalancerBackendGroups
+	LoadbalancerBackends      LoadbalancerBackends
+
+	NatGateways NatGateways
+	NatSEntries NatSEntries
+	NatDEntries NatDEntries
+
+	VpcPeeringConnections VpcPeeringConnections
+
+	FlowLogs FlowLogs
 }
 
 func NewModelSets() *ModelSets {
@@ -93,6 +132,19 @@ func NewModelSets() *ModelSets {
 		DnsRecords: DnsRecords{},
 
 		RouteTables: RouteTables{},
+
+		Loadbalancers:             Loadbalancers{},
+		LoadbalancerListeners:     LoadbalancerListeners{},
+		LoadbalancerBackendGroups: LoadbalancerBackendGroups{},
+		LoadbalancerBackends:      LoadbalancerBackends{},
+
+		NatGateways: NatGateways{},
+		NatSEntries: NatSEntries{},
+		NatDEntries: NatDEntries{},
+
+		VpcPeeringConnections: VpcPeeringConnections{},
+
+		FlowLogs: FlowLogs{},
 	}
 }
 
@@ -114,6 +166,19 @@ func (mss *ModelSets) ModelSetList() []apihelper.IModelSet {
 		mss.DnsRecords,
 
 		mss.RouteTables,
+
+		mss.Loadbalancers,
+		mss.LoadbalancerListeners,
+		mss.LoadbalancerBackendGroups,
+		mss.LoadbalancerBackends,
+
+		mss.NatGateways,
+		mss.NatSEntries,
+		mss.NatDEntries,
+
+		mss.VpcPeeringConnections,
+
+		mss.FlowLogs,
 	}
 }
 
@@ -138,6 +203,19 @@ func (mss *ModelSets) copy_() *ModelSets {
 		DnsRecords: mss.DnsRecords.Copy().(DnsRecords),
 
 		RouteTables: mss.RouteTables.Copy().(RouteTables),
+
+		Loadbalancers:             mss.Loadbalancers.Copy().(Loadbalancers),
+		LoadbalancerListeners:     mss.LoadbalancerListeners.Copy().(LoadbalancerListeners),
+		LoadbalancerBackendGroups: mss.LoadbalancerBackendGroups.Copy().(LoadbalancerBackendGroups),
+		LoadbalancerBackends:      mss.LoadbalancerBackends.Copy().(LoadbalancerBackends),
+
+		NatGateways: mss.NatGateways.Copy().(NatGateways),
+		NatSEntries: mss.NatSEntries.Copy().(NatSEntries),
+		NatDEntries: mss.NatDEntries.Copy().(NatDEntries),
+
+		VpcPeeringConnections: mss.VpcPeeringConnections.Copy().(VpcPeeringConnections),
+
+		FlowLogs: mss.FlowLogs.Copy().(FlowLogs),
 	}
 	return mssCopy
 }
@@ -189,6 +267,17 @@ func (mss *ModelSets) join() bool {
 	p = append(p, mss.Guestnetworks.joinGuests(mss.Guests))
 	p = append(p, mss.Guestnetworks.joinElasticips(mss.Elasticips))
 	p = append(p, mss.Guestnetworks.joinNetworkAddresses(mss.NetworkAddresses))
+	p = append(p, mss.Loadbalancers.joinNetworks(mss.Networks))
+	p = append(p, mss.Loadbalancers.joinListeners(mss.LoadbalancerListeners))
+	p = append(p, mss.Loadbalancers.joinBackendGroups(mss.LoadbalancerBackendGroups))
+	p = append(p, mss.LoadbalancerBackendGroups.joinBackends(mss.LoadbalancerBackends))
+	p = append(p, mss.LoadbalancerListeners.joinBackendGroups(mss.LoadbalancerBackendGroups))
+	p = append(p, mss.NatGateways.joinVpcs(mss.Vpcs))
+	p = append(p, mss.NatGateways.joinSEntries(mss.NatSEntries))
+	p = append(p, mss.NatGateways.joinDEntries(mss.NatDEntries))
+	p = append(p, mss.NatGateways.joinElasticips(mss.Elasticips))
+	p = append(p, mss.VpcPeeringConnections.joinVpcs(mss.Vpcs))
+	p = append(p, mss.Vpcs.joinFlowLogs(mss.FlowLogs))
 	for _, b := range p {
 		if !b {
 			return false
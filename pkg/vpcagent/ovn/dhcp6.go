@@ -0,0 +1,84 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func dhcp6OptRef(networkId string) string {
+	return fmt.Sprintf("dhcp6/%s", networkId)
+}
+
+// networkIp6Cidr returns the ipv6 prefix of dual-stack network, or empty
+// string for ipv4 only network
+func networkIp6Cidr(network *agentmodels.Network) string {
+	if !network.IsSupportIPv6() || network.GuestGateway6 == "" {
+		return ""
+	}
+	prefix, err := netutils2.IPv6Prefix(network.GuestIp6Start, int(network.GuestIp6Mask))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%d", prefix, network.GuestIp6Mask)
+}
+
+// networkDhcp6Options returns DHCPv6 options for stateful address assignment
+// on the subnet switch.  Router advertisements come from the subnet router
+// port with managed flag set
+func networkDhcp6Options(network *agentmodels.Network, serverMac string) *ovn_nb.DHCPOptions {
+	cidr := networkIp6Cidr(network)
+	if cidr == "" {
+		return nil
+	}
+	dhcp6opts := &ovn_nb.DHCPOptions{
+		Cidr: cidr,
+		Options: map[string]string{
+			"server_id": serverMac,
+		},
+		ExternalIds: map[string]string{
+			externalKeyOcRef: dhcp6OptRef(network.Id),
+		},
+	}
+	if network.GuestDns6 != "" {
+		dhcp6opts.Options["dns_server"] = "{" + network.GuestDns6 + "}"
+	}
+	return dhcp6opts
+}
+
+// findDhcpOpt returns uuid of the DHCP_Options row with the ref
+func (keeper *OVNNorthboundKeeper) findDhcpOpt(ctx context.Context, ref string) string {
+	dhcpOptQuery := &ovn_nb.DHCPOptions{
+		ExternalIds: map[string]string{
+			externalKeyOcRef: ref,
+		},
+	}
+	if m := keeper.DB.DHCPOptions.FindOneMatchNonZeros(dhcpOptQuery); m != nil {
+		return m.OvsdbUuid()
+	}
+	args := []string{
+		"--bare", "--columns=_uuid", "find", "DHCP_Options",
+		fmt.Sprintf("external_ids:%s=%q", externalKeyOcRef, ref),
+	}
+	res := keeper.cli.Must(ctx, "find dhcpopt", args)
+	return strings.TrimSpace(res.Output)
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+const (
+	externalKeyOcFlowLog     = "oc-flow-log"
+	externalKeyOcFlowLogRate = "oc-flow-log-rate"
+)
+
+// guestnetworkFlowLog returns the enabled flow log closest to the guest
+// interface
+func guestnetworkFlowLog(guestnetwork *agentmodels.Guestnetwork) *agentmodels.FlowLog {
+	var (
+		vpc       = guestnetwork.Network.Vpc
+		byNetwork *agentmodels.FlowLog
+		byVpc     *agentmodels.FlowLog
+	)
+	for _, fl := range vpc.FlowLogs {
+		if !fl.Enabled.IsTrue() {
+			continue
+		}
+		switch fl.ResourceType {
+		case apis.FLOW_LOG_RESOURCE_TYPE_GUEST:
+			if fl.ResourceId == guestnetwork.GuestId {
+				return fl
+			}
+		case apis.FLOW_LOG_RESOURCE_TYPE_NETWORK:
+			if fl.ResourceId == guestnetwork.NetworkId {
+				byNetwork = fl
+			}
+		case apis.FLOW_LOG_RESOURCE_TYPE_VPC:
+			byVpc = fl
+		}
+	}
+	if byNetwork != nil {
+		return byNetwork
+	}
+	return byVpc
+}
+
+// aclSetFlowLog turns on logging of the acl if its verdict is of interest
+// to the flow log.  ACL name is set to flow log id for the collector on
+// hosts to find out where the records go.  Logging options are also
+// recorded in external_ids as false bool and nil pointers are not matched
+func (keeper *OVNNorthboundKeeper) aclSetFlowLog(acl *ovn_nb.ACL, fl *agentmodels.FlowLog) {
+	if fl == nil {
+		acl.ExternalIds[externalKeyOcFlowLog] = "off"
+		return
+	}
+	var (
+		isDrop = acl.Action == "drop"
+		doLog  bool
+	)
+	switch fl.TrafficType {
+	case apis.FLOW_LOG_TRAFFIC_TYPE_ACCEPT:
+		doLog = !isDrop
+	case apis.FLOW_LOG_TRAFFIC_TYPE_DROP:
+		doLog = isDrop
+	default:
+		doLog = true
+	}
+	if !doLog {
+		acl.ExternalIds[externalKeyOcFlowLog] = "off"
+		return
+	}
+	severity := "info"
+	if isDrop {
+		severity = "warning"
+	}
+	acl.Log = true
+	acl.Name = ptr(fl.Id)
+	acl.Severity = ptr(severity)
+	if keeper.meter {
+		acl.Meter = ptr(flowLogMeterName(fl.Id))
+	}
+	acl.ExternalIds[externalKeyOcFlowLog] = fmt.Sprintf("%s/%s", fl.Id, fl.TrafficType)
+}
+
+// ClaimVpcFlowLogs makes a meter for each enabled flow log of the vpc to
+// rate limit acl logs
+func (keeper *OVNNorthboundKeeper) ClaimVpcFlowLogs(ctx context.Context, vpc *agentmodels.Vpc) error {
+	if !keeper.meter {
+		return nil
+	}
+	var args []string
+	for _, fl := range vpc.FlowLogs {
+		if !fl.Enabled.IsTrue() {
+			continue
+		}
+		var (
+			ocVersion = fmt.Sprintf("%s.%d", fl.UpdatedAt, fl.UpdateVersion)
+			rate      = fmt.Sprintf("%d", fl.RateLimit)
+		)
+		meter := &ovn_nb.Meter{
+			Name: flowLogMeterName(fl.Id),
+			Unit: "pktps",
+			ExternalIds: map[string]string{
+				externalKeyOcRef:         fl.Id,
+				externalKeyOcFlowLogRate: rate,
+			},
+		}
+		allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, meter)
+		if allFound {
+			continue
+		}
+		args = append(args, cleanupArgs...)
+
+		ref := fmt.Sprintf("meter%d", len(args))
+		band := &ovn_nb.MeterBand{
+			Action: "drop",
+			Rate:   int64(fl.RateLimit),
+		}
+		args = append(args, ovnCreateArgs(band, ref+"b")...)
+		args = append(args, ovnCreateArgs(meter, ref)...)
+		args = append(args, "bands=@"+ref+"b")
+	}
+	if len(args) > 0 {
+		return keeper.cli.Must(ctx, "ClaimVpcFlowLogs", args)
+	}
+	return nil
+}
@@ -40,6 +40,13 @@ const (
 type OVNNorthboundKeeper struct {
 	DB  ovn_nb.OVNNorthbound
 	cli *ovnutil.OvnNbCtl
+
+	// lbHealthCheck tells whether the northbound db supports
+	// Load_Balancer_Health_Check
+	lbHealthCheck bool
+	// meter tells whether the northbound db supports Meter, which is
+	// used to rate limit acl logging
+	meter bool
 }
 
 func DumpOVNNorthbound(ctx context.Context, cli *ovnutil.OvnNbCtl) (*OVNNorthboundKeeper, error) {
@@ -54,11 +61,21 @@ func DumpOVNNorthbound(ctx context.Context, cli *ovnutil.OvnNbCtl) (*OVNNorthbou
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.LoadBalancer,
+		&db.NAT,
+	}
+	// newer ovn has more columns in Load_Balancer and NAT than those
+	// known to ovn_nb, e.g. health_check, ip_port_mappings, options
+	columns := map[string]string{
+		db.LoadBalancer.OvsdbTableName(): "_uuid,_version,external_ids,name,protocol,vips",
+		db.NAT.OvsdbTableName():          "_uuid,_version,external_ids,external_ip,external_mac,logical_ip,logical_port,type",
 	}
-	args := []string{"--format=json", "list", "<tbl>"}
 	for _, itbl := range itbls {
 		tbl := itbl.OvsdbTableName()
-		args[2] = tbl
+		args := []string{"--format=json", "list", tbl}
+		if cols, ok := columns[tbl]; ok {
+			args = []string{"--format=json", "--columns=" + cols, "list", tbl}
+		}
 		res := cli.Must(ctx, "List "+tbl, args)
 		if err := cli_util.UnmarshalJSON([]byte(res.Output), itbl); err != nil {
 			return nil, errors.Wrapf(err, "Unmarshal %s:\n%s",
@@ -69,6 +86,23 @@ func DumpOVNNorthbound(ctx context.Context, cli *ovnutil.OvnNbCtl) (*OVNNorthbou
 		DB:  db,
 		cli: cli,
 	}
+	{
+		args := []string{"--format=json", "--columns=_uuid", "list", "Load_Balancer_Health_Check"}
+		if _, err := cli.Try(ctx, "List Load_Balancer_Health_Check", args); err == nil {
+			keeper.lbHealthCheck = true
+		}
+	}
+	{
+		// newer ovn has column fair in Meter
+		tbl := db.Meter.OvsdbTableName()
+		args := []string{"--format=json", "--columns=_uuid,_version,bands,external_ids,name,unit", "list", tbl}
+		if res, err := cli.Try(ctx, "List "+tbl, args); err == nil {
+			if err := cli_util.UnmarshalJSON([]byte(res.Output), &keeper.DB.Meter); err != nil {
+				return nil, errors.Wrapf(err, "Unmarshal %s:\n%s", tbl, res.Output)
+			}
+			keeper.meter = true
+		}
+	}
 	return keeper, nil
 }
 
@@ -323,18 +357,29 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 	} else {
 		dhcpopts.Options["dns_server"] = "{223.5.5.5,223.6.6.6}"
 	}
-
-	var (
-		args      []string
-		ocVersion = fmt.Sprintf("%s.%d", network.UpdatedAt, network.UpdateVersion)
-	)
-	allFound, args := cmp(&keeper.DB, ocVersion,
+	irows := []types.IRow{
 		netLs,
 		netRnp,
 		netNrp,
 		netMdp,
 		dhcpopts,
+	}
+	dhcp6opts := networkDhcp6Options(network, dhcpMac)
+	if dhcp6opts != nil {
+		netRnp.Networks = append(netRnp.Networks, fmt.Sprintf("%s/%d", network.GuestGateway6, network.GuestIp6Mask))
+		netRnp.Ipv6RaConfigs = map[string]string{
+			"address_mode":  "dhcpv6_stateful",
+			"send_periodic": "true",
+			"mtu":           fmt.Sprintf("%d", mtu),
+		}
+		irows = append(irows, dhcp6opts)
+	}
+
+	var (
+		args      []string
+		ocVersion = fmt.Sprintf("%s.%d", network.UpdatedAt, network.UpdateVersion)
 	)
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
 	if allFound {
 		return nil
 	}
@@ -343,6 +388,9 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 	args = append(args, ovnCreateArgs(netNrp, netNrp.Name)...)
 	args = append(args, ovnCreateArgs(netMdp, netMdp.Name)...)
 	args = append(args, ovnCreateArgs(dhcpopts, "dhcpopts")...)
+	if dhcp6opts != nil {
+		args = append(args, ovnCreateArgs(dhcp6opts, "dhcp6opts")...)
+	}
 	args = append(args, "--", "add", "Logical_Switch", netLs.Name, "ports", "@"+netNrp.Name, "@"+netMdp.Name)
 	args = append(args, "--", "add", "Logical_Router", vpcLrName(network.Vpc.Id), "ports", "@"+netRnp.Name)
 	return keeper.cli.Must(ctx, "ClaimNetwork", args)
@@ -423,24 +471,15 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 		dhcpOpt         string
 	)
 
-	{
-		dhcpOptQuery := &ovn_nb.DHCPOptions{
-			ExternalIds: map[string]string{
-				externalKeyOcRef: guestnetwork.NetworkId,
-			},
-		}
-		if m := keeper.DB.DHCPOptions.FindOneMatchNonZeros(dhcpOptQuery); m != nil {
-			dhcpOpt = m.OvsdbUuid()
-		} else {
-			args := []string{
-				"--bare", "--columns=_uuid", "find", "DHCP_Options",
-				fmt.Sprintf("external_ids:%s=%q", externalKeyOcRef, guestnetwork.NetworkId),
-			}
-			res := keeper.cli.Must(ctx, "find dhcpopt", args)
-			dhcpOpt = strings.TrimSpace(res.Output)
-		}
-		if dhcpOpt == "" {
-			return fmt.Errorf("cannot find dhcpopt for subnet %s", guestnetwork.NetworkId)
+	dhcpOpt = keeper.findDhcpOpt(ctx, guestnetwork.NetworkId)
+	if dhcpOpt == "" {
+		return fmt.Errorf("cannot find dhcpopt for subnet %s", guestnetwork.NetworkId)
+	}
+	var dhcp6Opt string
+	if guestnetwork.Ip6Addr != "" && networkIp6Cidr(network) != "" {
+		dhcp6Opt = keeper.findDhcpOpt(ctx, dhcp6OptRef(guestnetwork.NetworkId))
+		if dhcp6Opt == "" {
+			return fmt.Errorf("cannot find dhcp6opt for subnet %s", guestnetwork.NetworkId)
 		}
 	}
 
@@ -454,12 +493,19 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 	}
 	sort.Strings(subIPs[1:])
 	sort.Strings(subIPms[1:])
+	if dhcp6Opt != "" {
+		subIPs = append(subIPs, guestnetwork.Ip6Addr)
+		subIPms = append(subIPms, fmt.Sprintf("%s/%d", guestnetwork.Ip6Addr, network.GuestIp6Mask))
+	}
 	gnp := &ovn_nb.LogicalSwitchPort{
 		Name:          lportName,
 		Addresses:     []string{fmt.Sprintf("%s %s", guestnetwork.MacAddr, strings.Join(subIPs, " "))},
 		Dhcpv4Options: &dhcpOpt,
 		Options:       map[string]string{},
 	}
+	if dhcp6Opt != "" {
+		gnp.Dhcpv6Options = &dhcp6Opt
+	}
 	if guest.SrcMacCheck.IsFalse() {
 		gnp.Addresses = append(gnp.Addresses, "unknown")
 		// empty, not nil, as match condition
@@ -477,45 +523,11 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 		}
 	}
 
-	var qosVif []*ovn_nb.QoS
-	if bwMbps := guestnetwork.BwLimit; bwMbps > 0 {
-		var (
-			kbps = int64(bwMbps * 1000)
-			kbur = int64(kbps * 2)
-		)
-		qosVif = []*ovn_nb.QoS{
-			&ovn_nb.QoS{
-				Priority:  2000,
-				Direction: "from-lport",
-				Match:     fmt.Sprintf("inport == %q", lportName),
-				Bandwidth: map[string]int64{
-					"rate":  kbps,
-					"burst": kbur,
-				},
-				ExternalIds: map[string]string{
-					externalKeyOcRef: ocQosRef,
-				},
-			},
-			&ovn_nb.QoS{
-				Priority:  1000,
-				Direction: "to-lport",
-				Match:     fmt.Sprintf("outport == %q", lportName),
-				Bandwidth: map[string]int64{
-					"rate":  kbps,
-					"burst": kbur,
-				},
-				ExternalIds: map[string]string{
-					externalKeyOcRef: ocQosRef,
-				},
-			},
-		}
-	}
+	qosVif := guestnetworkQoS(guestnetwork, lportName, ocQosRef)
 
 	var (
 		gnrDefault *ovn_nb.LogicalRouterStaticRoute
-		qosEipIn   *ovn_nb.QoS
-		qosEipOut  *ovn_nb.QoS
-		hasQoSEip  bool
+		qosEip     []*ovn_nb.QoS
 	)
 	{
 		gnrDefaultPolicy := "src-ip"
@@ -529,39 +541,7 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 					externalKeyOcRef: ocGnrDefaultRef,
 				},
 			}
-			if bwMbps := eip.Bandwidth; bwMbps > 0 {
-				var (
-					kbps     = int64(bwMbps * 1000)
-					kbur     = int64(kbps * 2)
-					eipgwVip = apis.VpcEipGatewayIP3().String()
-				)
-				hasQoSEip = true
-				qosEipIn = &ovn_nb.QoS{
-					Priority:  2000,
-					Direction: "from-lport",
-					Match:     fmt.Sprintf("inport == %q && ip4 && ip4.dst == %s", vpcEipLspName(vpc.Id, eipgwVip), guestnetwork.IpAddr),
-					Bandwidth: map[string]int64{
-						"rate":  kbps,
-						"burst": kbur,
-					},
-					ExternalIds: map[string]string{
-						externalKeyOcRef: ocQosEipRef,
-					},
-				}
-				qosEipOut = &ovn_nb.QoS{
-					Priority:  3000,
-					Direction: "from-lport",
-					Match:     fmt.Sprintf("inport == %q && ip4 && ip4.src == %s", vpcErpName(vpc.Id), guestnetwork.IpAddr),
-					Bandwidth: map[string]int64{
-						"rate":  kbps,
-						"burst": kbur,
-					},
-					ExternalIds: map[string]string{
-						externalKeyOcRef: ocQosEipRef,
-					},
-				}
-			}
-
+			qosEip = eipQoS(vpc, eip, guestnetwork.IpAddr, ocQosEipRef)
 		} else if vpcHasDistgw(vpc) {
 			gnrDefault = &ovn_nb.LogicalRouterStaticRoute{
 				Policy:     &gnrDefaultPolicy,
@@ -577,6 +557,7 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 
 	var acls []*ovn_nb.ACL
 	{
+		fl := guestnetworkFlowLog(guestnetwork)
 		sgrs := guest.OrderedSecurityGroupRules()
 		for _, sgr := range sgrs {
 			// kvm not support peer secgroup
@@ -591,6 +572,7 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 			acl.ExternalIds = map[string]string{
 				externalKeyOcRef: ocAclRef,
 			}
+			keeper.aclSetFlowLog(acl, fl)
 			acls = append(acls, acl)
 		}
 	}
@@ -607,8 +589,8 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 	for _, qos := range qosVif {
 		irows = append(irows, qos)
 	}
-	if hasQoSEip {
-		irows = append(irows, qosEipIn, qosEipOut)
+	for _, qos := range qosEip {
+		irows = append(irows, qos)
 	}
 	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
 	if allFound {
@@ -631,11 +613,10 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 		args = append(args, ovnCreateArgs(qos, ref)...)
 		args = append(args, "--", "add", "Logical_Switch", netLsName(guestnetwork.NetworkId), "qos_rules", "@"+ref)
 	}
-	if hasQoSEip {
-		args = append(args, ovnCreateArgs(qosEipIn, "qosEipIn")...)
-		args = append(args, "--", "add", "Logical_Switch", vpcEipLsName(vpc.Id), "qos_rules", "@qosEipIn")
-		args = append(args, ovnCreateArgs(qosEipOut, "qosEipOut")...)
-		args = append(args, "--", "add", "Logical_Switch", vpcEipLsName(vpc.Id), "qos_rules", "@qosEipOut")
+	for i, qos := range qosEip {
+		ref := fmt.Sprintf("qosEip%d", i)
+		args = append(args, ovnCreateArgs(qos, ref)...)
+		args = append(args, "--", "add", "Logical_Switch", vpcEipLsName(vpc.Id), "qos_rules", "@"+ref)
 	}
 	return keeper.cli.Must(ctx, "ClaimGuestnetwork", args)
 }
@@ -643,11 +624,15 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 func (keeper *OVNNorthboundKeeper) ClaimRoutes(ctx context.Context, vpc *agentmodels.Vpc, routes resolvedRoutes) error {
 	var irows []types.IRow
 	for _, route := range routes {
-		irows = append(irows, &ovn_nb.LogicalRouterStaticRoute{
+		irow := &ovn_nb.LogicalRouterStaticRoute{
 			Policy:   ptr("dst-ip"),
 			IpPrefix: route.Cidr,
 			Nexthop:  route.NextHop,
-		})
+		}
+		if route.OutputPort != "" {
+			irow.OutputPort = ptr(route.OutputPort)
+		}
+		irows = append(irows, irow)
 	}
 	ocVersion := fmt.Sprintf("%s.%d", vpc.UpdatedAt, vpc.UpdateVersion)
 	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
@@ -787,6 +772,9 @@ func (keeper *OVNNorthboundKeeper) Mark(ctx context.Context) {
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.LoadBalancer,
+		&db.NAT,
+		&db.Meter,
 	}
 	for _, itbl := range itbls {
 		for _, irow := range itbl.Rows() {
@@ -805,6 +793,7 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 		&db.LogicalRouter,
 		&db.DHCPOptions,
 		&db.DNS,
+		&db.Meter,
 	}
 	var irows []types.IRow
 	for _, itbl := range itbls {
@@ -862,5 +851,37 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 			keeper.cli.Must(ctx, "Sweep qos", args)
 		}
 	}
+	{ // load balancers are strongly referenced by switches and routers
+		var args []string
+		for _, irow := range db.LoadBalancer.Rows() {
+			_, ok := irow.GetExternalId(externalKeyOcVersion)
+			if !ok {
+				for _, ls := range db.LogicalSwitch.FindLoadBalancerReferrer_load_balancer(irow.OvsdbUuid()) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Switch", ls.Name, "load_balancer", irow.OvsdbUuid())
+				}
+				for _, lr := range db.LogicalRouter.FindLoadBalancerReferrer_load_balancer(irow.OvsdbUuid()) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Router", lr.Name, "load_balancer", irow.OvsdbUuid())
+				}
+				args = append(args, "--", "--if-exists", "destroy", irow.OvsdbTableName(), irow.OvsdbUuid())
+			}
+		}
+		if len(args) > 0 {
+			keeper.cli.Must(ctx, "Sweep load balancers", args)
+		}
+	}
+	{
+		var args []string
+		for _, irow := range db.NAT.Rows() {
+			_, ok := irow.GetExternalId(externalKeyOcVersion)
+			if !ok {
+				for _, lr := range db.LogicalRouter.FindNATReferrer_nat(irow.OvsdbUuid()) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Router", lr.Name, "nat", irow.OvsdbUuid())
+				}
+			}
+		}
+		if len(args) > 0 {
+			keeper.cli.Must(ctx, "Sweep nat", args)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,165 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/ovsdb/types"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+const (
+	externalKeyOcLbSwitches    = "oc-lb-switches"
+	externalKeyOcLbHealthCheck = "oc-lb-health-check"
+)
+
+// lbBackendLsp returns the logical switch port of the backend address if it's
+// a guest interface in the network
+func lbBackendLsp(network *agentmodels.Network, addr string) string {
+	for _, guestnetwork := range network.Guestnetworks {
+		if guestnetwork.IpAddr == addr {
+			return gnpName(guestnetwork.NetworkId, guestnetwork.Ifname)
+		}
+	}
+	return ""
+}
+
+// ClaimLoadbalancer realizes each tcp/udp listener of the vpc loadbalancer as
+// an ovn Load_Balancer attached to the vpc router and all switches of vpc
+// subnets
+func (keeper *OVNNorthboundKeeper) ClaimLoadbalancer(ctx context.Context, lb *agentmodels.Loadbalancer) error {
+	var (
+		network = lb.Network
+		vpc     = network.Vpc
+	)
+	if lb.Status != apis.LB_STATUS_ENABLED || lb.Address == "" {
+		return nil
+	}
+
+	var lsNames []string
+	for _, network := range vpc.Networks {
+		lsNames = append(lsNames, netLsName(network.Id))
+	}
+	sort.Strings(lsNames)
+
+	var args []string
+	for _, listener := range lb.Listeners {
+		if listener.Status != apis.LB_STATUS_ENABLED {
+			continue
+		}
+		proto := listener.ListenerType
+		if proto != apis.LB_LISTENER_TYPE_TCP && proto != apis.LB_LISTENER_TYPE_UDP {
+			continue
+		}
+		lbbg := listener.BackendGroup
+		if lbbg == nil {
+			continue
+		}
+
+		var (
+			backends   []string
+			ipPortMaps = map[string]string{}
+			vip        = fmt.Sprintf("%s:%d", lb.Address, listener.ListenerPort)
+			hasHc      = keeper.lbHealthCheck && listener.HealthCheck == apis.LB_BOOL_ON
+			ocVersion  = fmt.Sprintf("%s.%d", listener.UpdatedAt, listener.UpdateVersion)
+			hcOptions  map[string]string
+			hcSpec     = apis.LB_BOOL_OFF
+		)
+		for _, backend := range lbbg.Backends {
+			if backend.Address == "" {
+				continue
+			}
+			backends = append(backends, fmt.Sprintf("%s:%d", backend.Address, backend.Port))
+			if hasHc {
+				// health check probes are sent out from the vip on
+				// the backend lport.  Backends not in the same
+				// subnet as the vip are not monitored
+				if lsp := lbBackendLsp(network, backend.Address); lsp != "" {
+					ipPortMaps[backend.Address] = fmt.Sprintf("%s:%s", lsp, lb.Address)
+				}
+			}
+		}
+		if len(backends) == 0 {
+			continue
+		}
+		sort.Strings(backends)
+		if hasHc {
+			hcOptions = map[string]string{
+				"interval":      fmt.Sprintf("%d", listener.HealthCheckInterval),
+				"timeout":       fmt.Sprintf("%d", listener.HealthCheckTimeout),
+				"success_count": fmt.Sprintf("%d", listener.HealthCheckRise),
+				"failure_count": fmt.Sprintf("%d", listener.HealthCheckFall),
+			}
+			hcSpec = fmt.Sprintf("%s/%s/%s/%s",
+				hcOptions["interval"], hcOptions["timeout"],
+				hcOptions["success_count"], hcOptions["failure_count"])
+			var mapped []string
+			for addr := range ipPortMaps {
+				mapped = append(mapped, addr)
+			}
+			sort.Strings(mapped)
+			hcSpec += "/" + strings.Join(mapped, ",")
+		}
+
+		// health check and attachment are not in ovn_nb, they are
+		// tracked in external_ids to detect changes.  Keys must
+		// always be present as only non-zero values are matched
+		ovnLb := &ovn_nb.LoadBalancer{
+			Name:     lbName(listener.Id),
+			Protocol: ptr(proto),
+			Vips: map[string]string{
+				vip: strings.Join(backends, ","),
+			},
+			ExternalIds: map[string]string{
+				externalKeyOcRef:           listener.Id,
+				externalKeyOcLbSwitches:    strings.Join(lsNames, ","),
+				externalKeyOcLbHealthCheck: hcSpec,
+			},
+		}
+		allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, ovnLb)
+		if allFound {
+			continue
+		}
+		args = append(args, cleanupArgs...)
+
+		ref := fmt.Sprintf("lb%d", len(args))
+		var createArgs []string
+		if hasHc {
+			hcRef := ref + "hc"
+			args = append(args, "--", "--id=@"+hcRef, "create", "Load_Balancer_Health_Check",
+				fmt.Sprintf("vip=%q", vip))
+			args = append(args, types.OvsdbCmdArgsMapStringString("options", hcOptions)...)
+			createArgs = append(createArgs, "health_check=@"+hcRef)
+			createArgs = append(createArgs, types.OvsdbCmdArgsMapStringString("ip_port_mappings", ipPortMaps)...)
+		}
+		args = append(args, ovnCreateArgs(ovnLb, ref)...)
+		args = append(args, createArgs...)
+		args = append(args, "--", "add", "Logical_Router", vpcLrName(vpc.Id), "load_balancer", "@"+ref)
+		for _, lsName := range lsNames {
+			args = append(args, "--", "add", "Logical_Switch", lsName, "load_balancer", "@"+ref)
+		}
+	}
+	if len(args) > 0 {
+		return keeper.cli.Must(ctx, "ClaimLoadbalancer", args)
+	}
+	return nil
+}
@@ -81,6 +81,24 @@ func vpcEipLspName(vpcId string, eipgwId string) string {
 	return fmt.Sprintf("vpc-ep/%s/%s", vpcId, eipgwId)
 }
 
+// flow log
+func flowLogMeterName(flowLogId string) string {
+	return fmt.Sprintf("fl/%s", flowLogId)
+}
+
+// vpc peering
+func vpcPeerLsName(peeringId string) string {
+	return fmt.Sprintf("vpc-peer/%s", peeringId)
+}
+
+func vpcPeerRpName(peeringId, vpcId string) string {
+	return fmt.Sprintf("vpc-peer-r/%s/%s", peeringId, vpcId)
+}
+
+func vpcPeerPrName(peeringId, vpcId string) string {
+	return fmt.Sprintf("vpc-peer-p/%s/%s", peeringId, vpcId)
+}
+
 func netLsName(netId string) string {
 	return fmt.Sprintf("subnet/%s", netId)
 }
@@ -103,3 +121,13 @@ func netMdpName(netId string) string {
 func gnpName(netId string, ifname string) string {
 	return fmt.Sprintf("iface-%s-%s", netId, ifname)
 }
+
+// lbName returns Load_Balancer name for loadbalancer listener
+func lbName(listenerId string) string {
+	return fmt.Sprintf("lb/%s", listenerId)
+}
+
+// natDnatLbName returns Load_Balancer name for nat dnat entry
+func natDnatLbName(dentryId string) string {
+	return fmt.Sprintf("dnat/%s", dentryId)
+}
@@ -0,0 +1,175 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/ovsdb/types"
+	"yunion.io/x/pkg/util/netutils"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+// natSEntryCidr returns the normalized source cidr of the snat entry
+func natSEntryCidr(vpc *agentmodels.Vpc, sentry *agentmodels.NatSEntry) (string, error) {
+	cidr := sentry.SourceCIDR
+	if cidr == "" {
+		network, ok := vpc.Networks[sentry.NetworkId]
+		if !ok {
+			return "", fmt.Errorf("network %s not found in vpc %s", sentry.NetworkId, vpc.Id)
+		}
+		cidr = fmt.Sprintf("%s/%d", network.GuestIpStart, network.GuestIpMask)
+	}
+	prefix, err := netutils.NewIPV4Prefix(cidr)
+	if err != nil {
+		return "", err
+	}
+	return prefix.String(), nil
+}
+
+// ClaimNatGateway realizes nat entries on the vpc external router.
+//
+// SNAT entries become NAT rows of type snat.  Source addresses are steered to
+// the eipgw with src-ip static routes, the same way as guests with eip.  NAT
+// rows do not take ports, DNAT entries are realized as Load_Balancer rows
+// mapping eip:port to the internal address.  Bandwidth of nat gateway eips is
+// limited with QoS rows on the eip switch
+func (keeper *OVNNorthboundKeeper) ClaimNatGateway(ctx context.Context, nat *agentmodels.NatGateway) error {
+	var (
+		vpc      = nat.Vpc
+		eipgwVip = apis.VpcEipGatewayIP3().String()
+		args     []string
+	)
+	if !vpcHasEipgw(vpc) {
+		return nil
+	}
+
+	for _, sentry := range nat.SEntries {
+		if sentry.Status != apis.NAT_STAUTS_AVAILABLE || sentry.IP == "" {
+			continue
+		}
+		cidr, err := natSEntryCidr(vpc, sentry)
+		if err != nil {
+			log.Errorf("nat %s(%s): snat entry %s: %v", nat.Name, nat.Id, sentry.Id, err)
+			continue
+		}
+		var (
+			ocVersion = fmt.Sprintf("%s.%d", sentry.UpdatedAt, sentry.UpdateVersion)
+			ocRef     = fmt.Sprintf("snat/%s", sentry.Id)
+		)
+		snat := &ovn_nb.NAT{
+			Type:       "snat",
+			ExternalIp: sentry.IP,
+			LogicalIp:  cidr,
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocRef,
+			},
+		}
+		snatRoute := &ovn_nb.LogicalRouterStaticRoute{
+			Policy:     ptr("src-ip"),
+			IpPrefix:   cidr,
+			Nexthop:    eipgwVip,
+			OutputPort: ptr(vpcRepName(vpc.Id)),
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocRef,
+			},
+		}
+		allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, snat, snatRoute)
+		if allFound {
+			continue
+		}
+		args = append(args, cleanupArgs...)
+
+		ref := fmt.Sprintf("snat%d", len(args))
+		args = append(args, ovnCreateArgs(snat, ref)...)
+		args = append(args, "--", "add", "Logical_Router", vpcExtLrName(vpc.Id), "nat", "@"+ref)
+		args = append(args, ovnCreateArgs(snatRoute, ref+"r")...)
+		args = append(args, "--", "add", "Logical_Router", vpcExtLrName(vpc.Id), "static_routes", "@"+ref+"r")
+	}
+
+	for _, dentry := range nat.DEntries {
+		if dentry.Status != apis.NAT_STAUTS_AVAILABLE {
+			continue
+		}
+		proto := strings.ToLower(dentry.IpProtocol)
+		if proto != "tcp" && proto != "udp" {
+			log.Warningf("nat %s(%s): dnat entry %s: unsupported protocol %q", nat.Name, nat.Id, dentry.Id, dentry.IpProtocol)
+			continue
+		}
+		var (
+			ocVersion = fmt.Sprintf("%s.%d", dentry.UpdatedAt, dentry.UpdateVersion)
+			vip       = fmt.Sprintf("%s:%d", dentry.ExternalIP, dentry.ExternalPort)
+		)
+		dnatLb := &ovn_nb.LoadBalancer{
+			Name:     natDnatLbName(dentry.Id),
+			Protocol: ptr(proto),
+			Vips: map[string]string{
+				vip: fmt.Sprintf("%s:%d", dentry.InternalIP, dentry.InternalPort),
+			},
+			ExternalIds: map[string]string{
+				externalKeyOcRef: fmt.Sprintf("dnat/%s", dentry.Id),
+			},
+		}
+		allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, dnatLb)
+		if allFound {
+			continue
+		}
+		args = append(args, cleanupArgs...)
+
+		ref := fmt.Sprintf("dnat%d", len(args))
+		args = append(args, ovnCreateArgs(dnatLb, ref)...)
+		args = append(args, "--", "add", "Logical_Router", vpcExtLrName(vpc.Id), "load_balancer", "@"+ref)
+	}
+
+	for _, eip := range nat.Elasticips {
+		if eip.IpAddr == "" {
+			continue
+		}
+		var (
+			ocVersion = fmt.Sprintf("%s.%d", eip.UpdatedAt, eip.UpdateVersion)
+			ocRef     = fmt.Sprintf("qos-nat-eip/%s", eip.Id)
+			qosEip    = eipQoS(vpc, eip, eip.IpAddr, ocRef)
+		)
+		if len(qosEip) == 0 {
+			continue
+		}
+		irows := make([]types.IRow, len(qosEip))
+		for i, qos := range qosEip {
+			irows[i] = qos
+		}
+		allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, irows...)
+		if allFound {
+			continue
+		}
+		args = append(args, cleanupArgs...)
+
+		for _, qos := range qosEip {
+			ref := fmt.Sprintf("qosEip%d", len(args))
+			args = append(args, ovnCreateArgs(qos, ref)...)
+			args = append(args, "--", "add", "Logical_Switch", vpcEipLsName(vpc.Id), "qos_rules", "@"+ref)
+		}
+	}
+
+	if len(args) > 0 {
+		return keeper.cli.Must(ctx, "ClaimNatGateway", args)
+	}
+	return nil
+}
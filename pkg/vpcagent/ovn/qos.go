@@ -0,0 +1,115 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"fmt"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+// qosBandwidth returns the bandwidth column of QoS rows.  mbps is the rate
+// limit, burst is in kbits and defaults to twice the rate
+func qosBandwidth(mbps int, burst int) map[string]int64 {
+	var (
+		kbps = int64(mbps * 1000)
+		kbur = int64(burst)
+	)
+	if kbur <= 0 {
+		kbur = kbps * 2
+	}
+	return map[string]int64{
+		"rate":  kbps,
+		"burst": kbur,
+	}
+}
+
+// guestnetworkQoS returns QoS rows limiting traffic of the guest port.
+// Egress is traffic from the guest, matched as from-lport.  Ingress is traffic
+// to the guest, matched as to-lport.  Both default to BwLimit
+func guestnetworkQoS(guestnetwork *agentmodels.Guestnetwork, lportName, ocRef string) []*ovn_nb.QoS {
+	var (
+		ingress = guestnetwork.BwLimit
+		egress  = guestnetwork.BwLimit
+		burst   = guestnetwork.BwBurst
+		qoses   []*ovn_nb.QoS
+	)
+	if guestnetwork.IngressBwLimit > 0 {
+		ingress = guestnetwork.IngressBwLimit
+	}
+	if guestnetwork.EgressBwLimit > 0 {
+		egress = guestnetwork.EgressBwLimit
+	}
+	if egress > 0 {
+		qoses = append(qoses, &ovn_nb.QoS{
+			Priority:  2000,
+			Direction: "from-lport",
+			Match:     fmt.Sprintf("inport == %q", lportName),
+			Bandwidth: qosBandwidth(egress, burst),
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocRef,
+			},
+		})
+	}
+	if ingress > 0 {
+		qoses = append(qoses, &ovn_nb.QoS{
+			Priority:  1000,
+			Direction: "to-lport",
+			Match:     fmt.Sprintf("outport == %q", lportName),
+			Bandwidth: qosBandwidth(ingress, burst),
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocRef,
+			},
+		})
+	}
+	return qoses
+}
+
+// eipQoS returns QoS rows on the eip switch limiting traffic of the eip
+// address.  addr is what the address looks like on the eip switch, the guest
+// address for guest eips as the translation is done by eipgw, the eip itself
+// for nat gateway eips as the translation is done by the vpc external router
+func eipQoS(vpc *agentmodels.Vpc, eip *agentmodels.Elasticip, addr, ocRef string) []*ovn_nb.QoS {
+	if eip.Bandwidth <= 0 {
+		return nil
+	}
+	var (
+		bandwidth = qosBandwidth(eip.Bandwidth, 0)
+		eipgwVip  = apis.VpcEipGatewayIP3().String()
+	)
+	return []*ovn_nb.QoS{
+		&ovn_nb.QoS{
+			Priority:  2000,
+			Direction: "from-lport",
+			Match:     fmt.Sprintf("inport == %q && ip4 && ip4.dst == %s", vpcEipLspName(vpc.Id, eipgwVip), addr),
+			Bandwidth: bandwidth,
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocRef,
+			},
+		},
+		&ovn_nb.QoS{
+			Priority:  3000,
+			Direction: "from-lport",
+			Match:     fmt.Sprintf("inport == %q && ip4 && ip4.src == %s", vpcErpName(vpc.Id), addr),
+			Bandwidth: bandwidth,
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocRef,
+			},
+		},
+	}
+}
@@ -24,6 +24,7 @@ type resolvedRoutes []resolvedRoute
 type resolvedRoute struct {
 	Cidr         string
 	NextHop      string
+	OutputPort   string
 	Network      *agentmodels.Network
 	Guestnetwork *agentmodels.Guestnetwork
 }
@@ -61,6 +62,20 @@ func resolveRoutes(vpc *agentmodels.Vpc, mss *agentmodels.ModelSets) resolvedRou
 					Guestnetwork: gn,
 				})
 			}
+		case computeapis.Next_HOP_TYPE_VPCPEERING:
+			peering, ok := mss.VpcPeeringConnections[routeModel.NextHopId]
+			if !ok || !vpcPeeringReady(peering) {
+				break
+			}
+			_, nexthop, err := vpcPeeringTransitIPs(peering, vpc.Id)
+			if err != nil {
+				break
+			}
+			r = append(r, resolvedRoute{
+				Cidr:       routeModel.Cidr,
+				NextHop:    nexthop,
+				OutputPort: vpcPeerRpName(peering.Id, vpc.Id),
+			})
 		default:
 			return nil
 		}
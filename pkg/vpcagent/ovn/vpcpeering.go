@@ -0,0 +1,152 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/log"
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/ovsdb/types"
+	"yunion.io/x/pkg/util/netutils"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+	"yunion.io/x/onecloud/pkg/vpcagent/ovn/mac"
+)
+
+// vpcPeeringTransitIPs returns transit addresses of the peering on the vpc
+// side and the peer side.  The vpc side of the peering takes the first host
+// address of the transit /30, the peer vpc side takes the second
+func vpcPeeringTransitIPs(peering *agentmodels.VpcPeeringConnection, vpcId string) (string, string, error) {
+	prefix, err := netutils.NewIPV4Prefix(peering.OvnTransitCidr)
+	if err != nil {
+		return "", "", err
+	}
+	var (
+		ip1 = prefix.Address.StepUp().String()
+		ip2 = prefix.Address.StepUp().StepUp().String()
+	)
+	switch vpcId {
+	case peering.VpcId:
+		return ip1, ip2, nil
+	case peering.PeerVpcId:
+		return ip2, ip1, nil
+	default:
+		return "", "", fmt.Errorf("vpc %s is not connected by peering %s", vpcId, peering.Id)
+	}
+}
+
+func vpcPeeringReady(peering *agentmodels.VpcPeeringConnection) bool {
+	return peering.Status == apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE && peering.OvnTransitCidr != ""
+}
+
+// ClaimVpcPeering connects routers of the two vpcs with a transit switch.
+// Each vpc router routes subnets of the other vpc to the other end of the
+// transit link
+func (keeper *OVNNorthboundKeeper) ClaimVpcPeering(ctx context.Context, peering *agentmodels.VpcPeeringConnection) error {
+	if !vpcPeeringReady(peering) {
+		return nil
+	}
+	var (
+		ocVersion = fmt.Sprintf("%s.%d", peering.UpdatedAt, peering.UpdateVersion)
+		ocRef     = fmt.Sprintf("peer/%s", peering.Id)
+		args      []string
+	)
+
+	peerLs := &ovn_nb.LogicalSwitch{
+		Name: vpcPeerLsName(peering.Id),
+	}
+	irows := []types.IRow{peerLs}
+	for _, vpc := range []*agentmodels.Vpc{peering.Vpc, peering.PeerVpc} {
+		localIp, _, err := vpcPeeringTransitIPs(peering, vpc.Id)
+		if err != nil {
+			return err
+		}
+		irows = append(irows,
+			&ovn_nb.LogicalRouterPort{
+				Name:     vpcPeerRpName(peering.Id, vpc.Id),
+				Mac:      mac.HashVpcPeeringRouterPortMac(peering.Id, vpc.Id),
+				Networks: []string{fmt.Sprintf("%s/%d", localIp, apis.VpcPeeringTransitMask)},
+			},
+			&ovn_nb.LogicalSwitchPort{
+				Name:      vpcPeerPrName(peering.Id, vpc.Id),
+				Type:      "router",
+				Addresses: []string{"router"},
+				Options: map[string]string{
+					"router-port": vpcPeerRpName(peering.Id, vpc.Id),
+				},
+			},
+		)
+	}
+	if allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, irows...); !allFound {
+		args = append(args, cleanupArgs...)
+		args = append(args, ovnCreateArgs(peerLs, peerLs.Name)...)
+		for _, irow := range irows[1:] {
+			switch row := irow.(type) {
+			case *ovn_nb.LogicalRouterPort:
+				args = append(args, ovnCreateArgs(row, row.Name)...)
+			case *ovn_nb.LogicalSwitchPort:
+				args = append(args, ovnCreateArgs(row, row.Name)...)
+				args = append(args, "--", "add", "Logical_Switch", peerLs.Name, "ports", "@"+row.Name)
+			}
+		}
+		for _, vpc := range []*agentmodels.Vpc{peering.Vpc, peering.PeerVpc} {
+			rpName := vpcPeerRpName(peering.Id, vpc.Id)
+			args = append(args, "--", "add", "Logical_Router", vpcLrName(vpc.Id), "ports", "@"+rpName)
+		}
+	}
+
+	// routes are claimed one by one as networks of both vpcs come and go
+	// independent of the peering itself
+	for _, pair := range [][2]*agentmodels.Vpc{
+		{peering.Vpc, peering.PeerVpc},
+		{peering.PeerVpc, peering.Vpc},
+	} {
+		vpc, peerVpc := pair[0], pair[1]
+		_, nexthop, err := vpcPeeringTransitIPs(peering, vpc.Id)
+		if err != nil {
+			return err
+		}
+		for _, network := range peerVpc.Networks {
+			prefix, err := netutils.NewIPV4Prefix(fmt.Sprintf("%s/%d", network.GuestIpStart, network.GuestIpMask))
+			if err != nil {
+				log.Errorf("vpc peering %s(%s): network %s: %v", peering.Name, peering.Id, network.Id, err)
+				continue
+			}
+			route := &ovn_nb.LogicalRouterStaticRoute{
+				Policy:     ptr("dst-ip"),
+				IpPrefix:   prefix.String(),
+				Nexthop:    nexthop,
+				OutputPort: ptr(vpcPeerRpName(peering.Id, vpc.Id)),
+				ExternalIds: map[string]string{
+					externalKeyOcRef: ocRef,
+				},
+			}
+			if allFound, _ := cmp(&keeper.DB, ocVersion, route); allFound {
+				continue
+			}
+			ref := fmt.Sprintf("peerr%d", len(args))
+			args = append(args, ovnCreateArgs(route, ref)...)
+			args = append(args, "--", "add", "Logical_Router", vpcLrName(vpc.Id), "static_routes", "@"+ref)
+		}
+	}
+
+	if len(args) > 0 {
+		return keeper.cli.Must(ctx, "ClaimVpcPeering", args)
+	}
+	return nil
+}
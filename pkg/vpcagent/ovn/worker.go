@@ -123,6 +123,7 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 		if vpcHasEipgw(vpc) {
 			ovndb.ClaimVpcEipgw(ctx, vpc)
 		}
+		ovndb.ClaimVpcFlowLogs(ctx, vpc)
 		for _, network := range vpc.Networks {
 			ovndb.ClaimNetwork(ctx, network, w.opts.OvnUnderlayMtu)
 			for _, guestnetwork := range network.Guestnetworks {
@@ -173,6 +174,15 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 		}
 		ovndb.ClaimVpcGuestDnsRecords(ctx, vpc)
 	}
+	for _, lb := range mss.Loadbalancers {
+		ovndb.ClaimLoadbalancer(ctx, lb)
+	}
+	for _, nat := range mss.NatGateways {
+		ovndb.ClaimNatGateway(ctx, nat)
+	}
+	for _, peering := range mss.VpcPeeringConnections {
+		ovndb.ClaimVpcPeering(ctx, peering)
+	}
 	ovndb.ClaimDnsRecords(ctx, mss.Vpcs, mss.DnsRecords)
 	ovndb.Sweep(ctx)
 	return nil
@@ -87,6 +87,15 @@ func (cli *OvnNbCtl) Must(ctx context.Context, msg string, args []string) *CmdRe
 	return res
 }
 
+// Try runs ovn-nbctl like Must but returns the error instead of panicking
+func (cli *OvnNbCtl) Try(ctx context.Context, msg string, args []string) (*CmdResult, error) {
+	res := cli.run(ctx, args)
+	if res.Err != nil {
+		return res, cli.errWrap(res, msg, args)
+	}
+	return res, nil
+}
+
 func (cli *OvnNbCtl) errWrap(err error, msg string, args []string) error {
 	s := cli.argsString(args)
 	return errors.Wrapf(err, "%s:\n%s\n", msg, s)
@@ -162,6 +171,7 @@ func OvnNbctlArgsDestroy(irows []types.IRow) []string {
 		case *ovn_nb.LogicalRouterStaticRoute:
 		case *ovn_nb.ACL:
 		case *ovn_nb.QoS:
+		case *ovn_nb.NAT:
 		default:
 			if !irow.OvsdbIsRoot() {
 				panic(irow.OvsdbTableName())